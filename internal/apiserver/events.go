@@ -0,0 +1,136 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// Kinds of the events streamed by the events endpoint.
+const (
+	EventSession           = "session"
+	EventMessage           = "message"
+	EventPermissionRequest = "permission_request"
+	EventPermissionAnswer  = "permission_answer"
+	EventAgent             = "agent"
+)
+
+// Event is the data of an event of the stream.
+type Event struct {
+	// Type is created, updated or deleted.
+	Type      pubsub.EventType `json:"type"`
+	SessionID string           `json:"session_id,omitempty"`
+	Payload   any              `json:"payload"`
+}
+
+// AgentEvent reports that the agent finished processing a prompt.
+type AgentEvent struct {
+	Type      string   `json:"type"`
+	SessionID string   `json:"session_id"`
+	Message   *Message `json:"message,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// handleEvents streams the events of the sessions, messages, permissions
+// and agent as server-sent events, only the ones of a session when the
+// session_id parameter is set.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	sessionID := r.URL.Query().Get("session_id")
+	sessions := s.app.Sessions.Subscribe(ctx)
+	messages := s.app.Messages.Subscribe(ctx)
+	requests := s.app.Permissions.Subscribe(ctx)
+	answers := s.app.Permissions.SubscribeNotifications(ctx)
+	agentEvents := s.app.CoderAgent.Subscribe(ctx)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(kind string, event Event) error {
+		if sessionID != "" && event.SessionID != sessionID {
+			return nil
+		}
+		if err := writeEvent(w, kind, event); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-sessions:
+			err = send(EventSession, Event{Type: e.Type, SessionID: e.Payload.ID, Payload: s.toSession(e.Payload)})
+		case e := <-messages:
+			err = send(EventMessage, Event{Type: e.Type, SessionID: e.Payload.SessionID, Payload: toMessage(e.Payload)})
+		case e := <-requests:
+			err = send(EventPermissionRequest, Event{Type: e.Type, SessionID: e.Payload.SessionID, Payload: e.Payload})
+		case e := <-answers:
+			if !e.Payload.Granted && !e.Payload.Denied {
+				continue
+			}
+			err = send(EventPermissionAnswer, Event{Type: e.Type, Payload: permissionAnswer(e.Payload)})
+		case e := <-agentEvents:
+			payload := toAgentEvent(e.Payload)
+			err = send(EventAgent, Event{Type: e.Type, SessionID: payload.SessionID, Payload: payload})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+type permissionAnswerPayload struct {
+	ToolCallID string `json:"tool_call_id"`
+	Granted    bool   `json:"granted"`
+}
+
+func permissionAnswer(n permission.PermissionNotification) permissionAnswerPayload {
+	return permissionAnswerPayload{ToolCallID: n.ToolCallID, Granted: n.Granted}
+}
+
+func toAgentEvent(e agent.AgentEvent) AgentEvent {
+	event := AgentEvent{
+		Type:      string(e.Type),
+		SessionID: e.SessionID,
+	}
+	if e.Message.ID != "" {
+		msg := toMessage(e.Message)
+		event.Message = &msg
+		event.SessionID = e.Message.SessionID
+	}
+	if e.Error != nil {
+		event.Error = e.Error.Error()
+	}
+	return event
+}
+
+// writeEvent writes a server-sent event, its data is the JSON of the event
+// on a single line.
+func writeEvent(w io.Writer, kind string, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", kind, data)
+	return err
+}
@@ -0,0 +1,422 @@
+// Package apiserver exposes a local HTTP API to drive crush from other
+// programs, like editors, bots and dashboards.
+package apiserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// maxBodySize is the size limit of the request bodies.
+const maxBodySize = 1 << 20
+
+// Server serves the API of an app. Every request must carry the token of
+// the server as a bearer token.
+type Server struct {
+	app   *app.App
+	token string
+	// ctx outlives the requests, the prompts run in it.
+	ctx     context.Context
+	pending *csync.Map[string, permission.PermissionRequest]
+}
+
+// New creates a server for the app. A random token is generated when token
+// is empty.
+func New(a *app.App, token string) (*Server, error) {
+	if token == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		token = hex.EncodeToString(key)
+	}
+	return &Server{
+		app:     a,
+		token:   token,
+		ctx:     context.Background(),
+		pending: csync.NewMap[string, permission.PermissionRequest](),
+	}, nil
+}
+
+// Token returns the token the clients must send.
+func (s *Server) Token() string {
+	return s.token
+}
+
+// Serve listens on the address and serves the API until the context is
+// done.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	s.ctx = ctx
+	s.trackPermissions(ctx)
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sessions", s.handleListSessions)
+	mux.HandleFunc("POST /sessions", s.handleCreateSession)
+	mux.HandleFunc("GET /sessions/{id}", s.handleGetSession)
+	mux.HandleFunc("GET /sessions/{id}/messages", s.handleListMessages)
+	mux.HandleFunc("POST /sessions/{id}/prompts", s.handlePrompt)
+	mux.HandleFunc("POST /sessions/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("GET /sessions/{id}/diffs", s.handleDiffs)
+	mux.HandleFunc("GET /permissions", s.handleListPermissions)
+	mux.HandleFunc("POST /permissions/{id}", s.handleAnswerPermission)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	return s.authorize(mux)
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// trackPermissions keeps the pending permission requests, for the clients
+// to list and answer them.
+func (s *Server) trackPermissions(ctx context.Context) {
+	requests := s.app.Permissions.Subscribe(ctx)
+	notifications := s.app.Permissions.SubscribeNotifications(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-requests:
+				if !ok {
+					return
+				}
+				s.pending.Set(event.Payload.ID, event.Payload)
+			case event, ok := <-notifications:
+				if !ok {
+					return
+				}
+				n := event.Payload
+				if !n.Granted && !n.Denied {
+					continue
+				}
+				// The request may have been answered elsewhere, e.g. by
+				// remote approval.
+				for id, req := range s.pending.Seq2() {
+					if req.ToolCallID == n.ToolCallID {
+						s.pending.Del(id)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Session is a session as returned by the API.
+type Session struct {
+	ID               string  `json:"id"`
+	ParentSessionID  string  `json:"parent_session_id,omitempty"`
+	Title            string  `json:"title"`
+	MessageCount     int64   `json:"message_count"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	Busy             bool    `json:"busy"`
+	CreatedAt        int64   `json:"created_at"`
+	UpdatedAt        int64   `json:"updated_at"`
+}
+
+// Message is a message as returned by the API.
+type Message struct {
+	ID           string               `json:"id"`
+	SessionID    string               `json:"session_id"`
+	Role         string               `json:"role"`
+	Content      string               `json:"content,omitempty"`
+	Reasoning    string               `json:"reasoning,omitempty"`
+	ToolCalls    []message.ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults  []message.ToolResult `json:"tool_results,omitempty"`
+	FinishReason string               `json:"finish_reason,omitempty"`
+	Model        string               `json:"model,omitempty"`
+	CreatedAt    int64                `json:"created_at"`
+	UpdatedAt    int64                `json:"updated_at"`
+}
+
+func (s *Server) toSession(sess session.Session) Session {
+	return Session{
+		ID:               sess.ID,
+		ParentSessionID:  sess.ParentSessionID,
+		Title:            sess.Title,
+		MessageCount:     sess.MessageCount,
+		PromptTokens:     sess.PromptTokens,
+		CompletionTokens: sess.CompletionTokens,
+		Cost:             sess.Cost,
+		Busy:             s.app.CoderAgent.IsSessionBusy(sess.ID),
+		CreatedAt:        sess.CreatedAt,
+		UpdatedAt:        sess.UpdatedAt,
+	}
+}
+
+func toMessage(msg message.Message) Message {
+	m := Message{
+		ID:          msg.ID,
+		SessionID:   msg.SessionID,
+		Role:        string(msg.Role),
+		Content:     msg.Content().String(),
+		Reasoning:   msg.ReasoningContent().Thinking,
+		ToolCalls:   msg.ToolCalls(),
+		ToolResults: msg.ToolResults(),
+		Model:       msg.Model,
+		CreatedAt:   msg.CreatedAt,
+		UpdatedAt:   msg.UpdatedAt,
+	}
+	if msg.IsFinished() {
+		m.FinishReason = string(msg.FinishReason())
+	}
+	return m
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.app.Sessions.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	result := make([]Session, 0, len(sessions))
+	for _, sess := range sessions {
+		result = append(result, s.toSession(sess))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+type createSessionRequest struct {
+	Title string `json:"title"`
+}
+
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	var req createSessionRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Title == "" {
+		req.Title = "API session"
+	}
+	sess, err := s.app.Sessions.Create(r.Context(), req.Title)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.toSession(sess))
+}
+
+// getSession writes an error and returns false when the session of the
+// path doesn't exist.
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request) (session.Session, bool) {
+	sess, err := s.app.Sessions.Get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "session not found")
+		return sess, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return sess, false
+	}
+	return sess, true
+}
+
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	if sess, ok := s.getSession(w, r); ok {
+		writeJSON(w, http.StatusOK, s.toSession(sess))
+	}
+}
+
+func (s *Server) handleListMessages(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	msgs, err := s.app.Messages.List(r.Context(), sess.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	result := make([]Message, 0, len(msgs))
+	for _, msg := range msgs {
+		result = append(result, toMessage(msg))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+type promptRequest struct {
+	Prompt string `json:"prompt"`
+}
+
+type promptResponse struct {
+	SessionID string `json:"session_id"`
+	// Queued is true when the session was busy, the prompt runs once the
+	// current one is done.
+	Queued bool `json:"queued"`
+}
+
+// handlePrompt starts the agent in the background, its progress is
+// streamed by the events endpoint.
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	var req promptRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		writeError(w, http.StatusBadRequest, "prompt is required")
+		return
+	}
+	done, err := s.app.CoderAgent.Run(s.ctx, sess.ID, req.Prompt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// The agent queues the prompts of busy sessions, without a channel to
+	// wait on.
+	if done == nil {
+		writeJSON(w, http.StatusAccepted, promptResponse{SessionID: sess.ID, Queued: true})
+		return
+	}
+	go func() {
+		result := <-done
+		if result.Error != nil && !errors.Is(result.Error, context.Canceled) && !errors.Is(result.Error, agent.ErrRequestCancelled) {
+			slog.Error("API prompt failed", "session_id", sess.ID, "error", result.Error)
+		}
+	}()
+	writeJSON(w, http.StatusAccepted, promptResponse{SessionID: sess.ID})
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	s.app.CoderAgent.Cancel(sess.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDiffs(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.getSession(w, r)
+	if !ok {
+		return
+	}
+	diffs, err := s.app.SessionDiffs(r.Context(), sess.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, diffs)
+}
+
+func (s *Server) handleListPermissions(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("session_id")
+	result := []permission.PermissionRequest{}
+	for _, req := range s.pending.Seq2() {
+		if sessionID == "" || req.SessionID == sessionID {
+			result = append(result, req)
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Decisions answering a permission request.
+const (
+	DecisionAllow        = "allow"
+	DecisionAllowSession = "allow_session"
+	DecisionDeny         = "deny"
+)
+
+type answerRequest struct {
+	Decision string `json:"decision"`
+}
+
+func (s *Server) handleAnswerPermission(w http.ResponseWriter, r *http.Request) {
+	var req answerRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Decision != DecisionAllow && req.Decision != DecisionAllowSession && req.Decision != DecisionDeny {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid decision %q, expected allow, allow_session or deny", req.Decision))
+		return
+	}
+	perm, ok := s.pending.Take(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "permission request not found or already answered")
+		return
+	}
+	switch req.Decision {
+	case DecisionAllow:
+		s.app.Permissions.Grant(perm)
+	case DecisionAllowSession:
+		s.app.Permissions.GrantPersistent(perm)
+	case DecisionDeny:
+		s.app.Permissions.Deny(perm)
+	}
+	slog.Info("Permission answered through the API", "tool", perm.ToolName, "action", perm.Action, "decision", req.Decision)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	// An empty body is valid, the fields keep their default.
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
@@ -0,0 +1,142 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorize(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(nil, "secret")
+	require.NoError(t, err)
+	handler := s.authorize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for header, status := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"Bearer secret": http.StatusNoContent,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/sessions", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, status, rec.Code, header)
+	}
+}
+
+func TestNew_RandomToken(t *testing.T) {
+	t.Parallel()
+
+	a, err := New(nil, "")
+	require.NoError(t, err)
+	b, err := New(nil, "")
+	require.NoError(t, err)
+	require.Len(t, a.Token(), 64)
+	require.NotEqual(t, a.Token(), b.Token())
+}
+
+func TestReadJSON(t *testing.T) {
+	t.Parallel()
+
+	var req promptRequest
+	rec := httptest.NewRecorder()
+	require.True(t, readJSON(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"prompt": "hi"}`)), &req))
+	require.Equal(t, "hi", req.Prompt)
+
+	rec = httptest.NewRecorder()
+	require.True(t, readJSON(rec, httptest.NewRequest(http.MethodPost, "/", http.NoBody), &req))
+
+	rec = httptest.NewRecorder()
+	require.False(t, readJSON(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"promt": "hi"}`)), &req))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp errorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Contains(t, resp.Error, "promt")
+}
+
+func TestWriteEvent(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := writeEvent(&buf, EventPermissionAnswer, Event{
+		Type:    pubsub.CreatedEvent,
+		Payload: permissionAnswerPayload{ToolCallID: "call_1", Granted: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "event: permission_answer\ndata: {\"type\":\"created\",\"payload\":{\"tool_call_id\":\"call_1\",\"granted\":true}}\n\n", buf.String())
+}
+
+type testSessions struct {
+	session.Service
+}
+
+func (testSessions) Get(_ context.Context, id string) (session.Session, error) {
+	return session.Session{ID: id}, nil
+}
+
+// testAgent queues the prompts of the busy sessions like the agent does.
+type testAgent struct {
+	agent.Service
+	runs chan chan agent.AgentEvent
+	busy bool
+}
+
+func (a *testAgent) Run(context.Context, string, string, ...message.Attachment) (<-chan agent.AgentEvent, error) {
+	if a.busy {
+		return nil, nil
+	}
+	a.busy = true
+	done := make(chan agent.AgentEvent)
+	a.runs <- done
+	return done, nil
+}
+
+func TestHandlePrompt_Queued(t *testing.T) {
+	t.Parallel()
+
+	coder := &testAgent{runs: make(chan chan agent.AgentEvent, 1)}
+	s, err := New(&app.App{Sessions: testSessions{}, CoderAgent: coder}, "secret")
+	require.NoError(t, err)
+
+	prompt := func() promptResponse {
+		req := httptest.NewRequest(http.MethodPost, "/sessions/session/prompts", strings.NewReader(`{"prompt": "hi"}`))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		var resp promptResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return resp
+	}
+
+	require.Equal(t, promptResponse{SessionID: "session"}, prompt())
+	done := <-coder.runs
+	require.Equal(t, promptResponse{SessionID: "session", Queued: true}, prompt())
+	require.Empty(t, coder.runs)
+
+	// The running prompt is waited on.
+	select {
+	case done <- agent.AgentEvent{}:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the running prompt isn't waited on")
+	}
+}
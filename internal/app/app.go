@@ -3,35 +3,44 @@ package app
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/artifact"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/db"
-	"github.com/charmbracelet/crush/internal/format"
 	"github.com/charmbracelet/crush/internal/history"
 	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/llm/tools"
 	"github.com/charmbracelet/crush/internal/log"
 	"github.com/charmbracelet/crush/internal/pubsub"
 
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/notify"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pin"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/trash"
 )
 
 type App struct {
 	Sessions    session.Service
 	Messages    message.Service
 	History     history.Service
+	Artifacts   artifact.Service
+	Trash       trash.Service
+	Pins        pin.Service
 	Permissions permission.Service
 
+	Notifications *notify.Service
+
 	CoderAgent agent.Service
 
 	LSPClients map[string]*lsp.Client
@@ -41,7 +50,8 @@ type App struct {
 	watcherCancelFuncs *csync.Slice[context.CancelFunc]
 	lspWatcherWG       sync.WaitGroup
 
-	config *config.Config
+	// config is replaced when the configuration is reloaded.
+	config atomic.Pointer[config.Config]
 
 	serviceEventsWG *sync.WaitGroup
 	eventsCtx       context.Context
@@ -56,10 +66,24 @@ type App struct {
 // New initializes a new applcation instance.
 func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 	q := db.New(conn)
-	sessions := session.NewService(q)
+	artifacts := artifact.NewService(cfg.Options.DataDirectory)
+	sessions := session.NewService(q, artifacts)
 	messages := message.NewService(q)
 	files := history.NewService(q, conn)
 	skipPermissionsRequests := cfg.Permissions != nil && cfg.Permissions.SkipRequests
+	profile, err := permission.LookupProfile(cfg.Permissions.ActiveProfile())
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil && profile.Audit && cfg.AuditLog() == nil {
+		// The profiles approving everything keep a record of it.
+		auditOptions := config.AuditOptions{}
+		if cfg.Options.Audit != nil {
+			auditOptions = *cfg.Options.Audit
+		}
+		auditOptions.Enabled = true
+		cfg.Options.Audit = &auditOptions
+	}
 	allowedTools := []string{}
 	if cfg.Permissions != nil && cfg.Permissions.AllowedTools != nil {
 		allowedTools = cfg.Permissions.AllowedTools
@@ -69,21 +93,32 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 		Sessions:    sessions,
 		Messages:    messages,
 		History:     files,
+		Artifacts:   artifacts,
+		Trash:       trash.NewService(cfg.Options.DataDirectory),
+		Pins:        pin.NewService(cfg.Options.DataDirectory),
 		Permissions: permission.NewPermissionService(cfg.WorkingDir(), skipPermissionsRequests, allowedTools),
 		LSPClients:  make(map[string]*lsp.Client),
 
 		globalCtx: ctx,
 
-		config: cfg,
-
 		watcherCancelFuncs: csync.NewSlice[context.CancelFunc](),
 
 		events:          make(chan tea.Msg, 100),
 		serviceEventsWG: &sync.WaitGroup{},
 		tuiWG:           &sync.WaitGroup{},
 	}
+	app.config.Store(cfg)
+	app.Permissions.SetProfile(profile)
+
+	app.Notifications = notify.NewService(cfg, sessions, app.Permissions)
 
 	app.setupEvents()
+	app.Notifications.WatchPermissions(app.eventsCtx, app.Permissions)
+
+	if auditLog := cfg.AuditLog(); auditLog != nil {
+		app.Permissions.SetAuditLog(auditLog)
+		app.auditFileChanges(app.eventsCtx, auditLog)
+	}
 
 	// Initialize LSP clients in the background.
 	app.initLSPClients(ctx)
@@ -96,102 +131,21 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 	} else {
 		slog.Warn("No agent configuration found")
 	}
+
+	app.watchConfig(app.eventsCtx)
 	return app, nil
 }
 
 // Config returns the application configuration.
 func (app *App) Config() *config.Config {
-	return app.config
+	return app.config.Load()
 }
 
-// RunNonInteractive handles the execution flow when a prompt is provided via
-// CLI flag.
-func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool) error {
-	slog.Info("Running in non-interactive mode")
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	// Start spinner if not in quiet mode.
-	var spinner *format.Spinner
-	if !quiet {
-		spinner = format.NewSpinner(ctx, cancel, "Generating")
-		spinner.Start()
-	}
-
-	// Helper function to stop spinner once.
-	stopSpinner := func() {
-		if !quiet && spinner != nil {
-			spinner.Stop()
-			spinner = nil
-		}
-	}
-	defer stopSpinner()
-
-	const maxPromptLengthForTitle = 100
-	titlePrefix := "Non-interactive: "
-	var titleSuffix string
-
-	if len(prompt) > maxPromptLengthForTitle {
-		titleSuffix = prompt[:maxPromptLengthForTitle] + "..."
-	} else {
-		titleSuffix = prompt
-	}
-	title := titlePrefix + titleSuffix
-
-	sess, err := app.Sessions.Create(ctx, title)
-	if err != nil {
-		return fmt.Errorf("failed to create session for non-interactive mode: %w", err)
-	}
-	slog.Info("Created session for non-interactive run", "session_id", sess.ID)
-
-	// Automatically approve all permission requests for this non-interactive session
-	app.Permissions.AutoApproveSession(sess.ID)
-
-	done, err := app.CoderAgent.Run(ctx, sess.ID, prompt)
-	if err != nil {
-		return fmt.Errorf("failed to start agent processing stream: %w", err)
-	}
-
-	messageEvents := app.Messages.Subscribe(ctx)
-	readBts := 0
-
-	for {
-		select {
-		case result := <-done:
-			stopSpinner()
-
-			if result.Error != nil {
-				if errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled) {
-					slog.Info("Non-interactive: agent processing cancelled", "session_id", sess.ID)
-					return nil
-				}
-				return fmt.Errorf("agent processing failed: %w", result.Error)
-			}
-
-			msgContent := result.Message.Content().String()
-			if len(msgContent) < readBts {
-				slog.Error("Non-interactive: message content is shorter than read bytes", "message_length", len(msgContent), "read_bytes", readBts)
-				return fmt.Errorf("message content is shorter than read bytes: %d < %d", len(msgContent), readBts)
-			}
-			fmt.Println(msgContent[readBts:])
-
-			slog.Info("Non-interactive: run completed", "session_id", sess.ID)
-			return nil
-
-		case event := <-messageEvents:
-			msg := event.Payload
-			if msg.SessionID == sess.ID && msg.Role == message.Assistant && len(msg.Parts) > 0 {
-				stopSpinner()
-				part := msg.Content().String()[readBts:]
-				fmt.Print(part)
-				readBts += len(part)
-			}
-
-		case <-ctx.Done():
-			stopSpinner()
-			return ctx.Err()
-		}
+// PrewarmAgent warms up the provider of the coder agent in the background,
+// when enabled in the options.
+func (app *App) PrewarmAgent() {
+	if app.CoderAgent != nil {
+		app.CoderAgent.Prewarm(app.globalCtx)
 	}
 }
 
@@ -207,8 +161,10 @@ func (app *App) setupEvents() {
 	setupSubscriber(ctx, app.serviceEventsWG, "permissions", app.Permissions.Subscribe, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "permissions-notifications", app.Permissions.SubscribeNotifications, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "history", app.History.Subscribe, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "pins", app.Pins.Subscribe, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "mcp", agent.SubscribeMCPEvents, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "lsp", SubscribeLSPEvents, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "tool-output", tools.SubscribeToolOutput, app.events)
 	cleanupFunc := func() {
 		cancel()
 		app.serviceEventsWG.Wait()
@@ -252,7 +208,7 @@ func setupSubscriber[T any](
 }
 
 func (app *App) InitCoderAgent() error {
-	coderAgentCfg := app.config.Agents["coder"]
+	coderAgentCfg := app.Config().Agents["coder"]
 	if coderAgentCfg.ID == "" {
 		return fmt.Errorf("coder agent configuration is missing")
 	}
@@ -264,6 +220,9 @@ func (app *App) InitCoderAgent() error {
 		app.Sessions,
 		app.Messages,
 		app.History,
+		app.Artifacts,
+		app.Trash,
+		app.Pins,
 		app.LSPClients,
 	)
 	if err != nil {
@@ -275,11 +234,13 @@ func (app *App) InitCoderAgent() error {
 	app.cleanupFuncs = append(app.cleanupFuncs, agent.CloseMCPClients)
 
 	setupSubscriber(app.eventsCtx, app.serviceEventsWG, "coderAgent", app.CoderAgent.Subscribe, app.events)
+	app.Notifications.WatchAgent(app.eventsCtx, app.CoderAgent.Subscribe)
 	return nil
 }
 
-// Subscribe sends events to the TUI as tea.Msgs.
-func (app *App) Subscribe(program *tea.Program) {
+// Subscribe sends events to the TUI as tea.Msgs, until the context is done
+// or the app shuts down.
+func (app *App) Subscribe(ctx context.Context, program *tea.Program) {
 	defer log.RecoverPanic("app.Subscribe", func() {
 		slog.Info("TUI subscription panic: attempting graceful shutdown")
 		program.Quit()
@@ -299,6 +260,8 @@ func (app *App) Subscribe(program *tea.Program) {
 		case <-tuiCtx.Done():
 			slog.Debug("TUI message handler shutting down")
 			return
+		case <-ctx.Done():
+			return
 		case msg, ok := <-app.events:
 			if !ok {
 				slog.Debug("TUI message channel closed")
@@ -313,7 +276,9 @@ func (app *App) Subscribe(program *tea.Program) {
 func (app *App) Shutdown() {
 	if app.CoderAgent != nil {
 		app.CoderAgent.CancelAll()
+		app.CoderAgent.EndSessions(context.Background())
 	}
+	app.Artifacts.RemovePlainCopies()
 
 	for cancel := range app.watcherCancelFuncs.Seq() {
 		cancel()
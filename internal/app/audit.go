@@ -0,0 +1,33 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/charmbracelet/crush/internal/audit"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// auditFileChanges records the new versions of the files in the audit log.
+// The initial versions hold the content of the files before they were
+// changed, so they aren't recorded.
+func (app *App) auditFileChanges(ctx context.Context, log *audit.Log) {
+	app.serviceEventsWG.Add(1)
+	go func() {
+		defer app.serviceEventsWG.Done()
+		for event := range app.History.Subscribe(ctx) {
+			if event.Type != pubsub.CreatedEvent || event.Payload.Version == history.InitialVersion {
+				continue
+			}
+			file := event.Payload
+			if err := log.Record(audit.TypeFileChange, file.SessionID, audit.FileChange{
+				Path:        file.Path,
+				Version:     file.Version,
+				ContentHash: audit.ContentHash(file.Content),
+			}); err != nil {
+				slog.Error("Failed to record the file change", "error", err)
+			}
+		}
+	}()
+}
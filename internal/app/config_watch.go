@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/fsnotify/fsnotify"
+)
+
+// configReloadDelay waits for editors to finish writing the configuration.
+const configReloadDelay = 500 * time.Millisecond
+
+// ConfigReloadedMsg is sent to the TUI once the configuration files changed
+// and were loaded again.
+type ConfigReloadedMsg struct {
+	Changes []config.Change
+	Err     error
+}
+
+// Changed tells whether the section of the configuration changed.
+func (m ConfigReloadedMsg) Changed(section string) bool {
+	return slices.ContainsFunc(m.Changes, func(c config.Change) bool {
+		return c.Section == section
+	})
+}
+
+// watchConfig reloads the configuration when one of its files changes. The
+// directories of the files are watched, as editors often replace the files
+// instead of writing them.
+func (app *App) watchConfig(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Error("error watching the configuration", "error", err)
+		return
+	}
+
+	workingDir := app.Config().WorkingDir()
+	paths := config.ConfigPaths(workingDir)
+	for i, path := range paths {
+		paths[i] = filepath.Clean(path)
+	}
+	var dirs []string
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if slices.Contains(dirs, dir) {
+			continue
+		}
+		dirs = append(dirs, dir)
+		if err := watcher.Add(dir); err != nil {
+			slog.Debug("error watching configuration directory", "error", err, "dir", dir)
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		reload := time.NewTimer(configReloadDelay)
+		reload.Stop()
+		defer reload.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Chmod) || !slices.Contains(paths, filepath.Clean(event.Name)) {
+					continue
+				}
+				reload.Reset(configReloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("error watching the configuration", "error", err)
+			case <-reload.C:
+				msg := app.reloadConfig(workingDir)
+				if msg.Err == nil && len(msg.Changes) == 0 {
+					continue
+				}
+				select {
+				case app.events <- msg:
+				case <-time.After(2 * time.Second):
+					slog.Warn("message dropped due to slow consumer", "name", "config")
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+}
+
+// reloadConfig loads the configuration again and applies the changes which
+// don't need a restart to the services.
+func (app *App) reloadConfig(workingDir string) ConfigReloadedMsg {
+	changes, previous, err := config.Reload(workingDir)
+	if err != nil {
+		// The file may be half written, or invalid until the user is done
+		// editing it.
+		slog.Warn("error reloading the configuration", "error", err)
+		return ConfigReloadedMsg{Err: err}
+	}
+	if len(changes) == 0 {
+		return ConfigReloadedMsg{}
+	}
+	cfg := config.Get()
+	app.config.Store(cfg)
+
+	for _, change := range changes {
+		slog.Info("configuration changed", "section", change.Section, "restart", change.Restart)
+		switch change.Section {
+		case "models":
+			if app.CoderAgent == nil {
+				continue
+			}
+			if err := app.UpdateAgentModel(); err != nil {
+				slog.Error("error updating the model", "error", err)
+				return ConfigReloadedMsg{Changes: changes, Err: err}
+			}
+		case "permissions":
+			var allowedTools []string
+			if cfg.Permissions != nil {
+				allowedTools = cfg.Permissions.AllowedTools
+			}
+			app.Permissions.SetAllowedTools(allowedTools)
+			profile, err := permission.LookupProfile(cfg.Permissions.ActiveProfile())
+			if err != nil {
+				slog.Error("error updating the permission profile", "error", err)
+				return ConfigReloadedMsg{Changes: changes, Err: err}
+			}
+			app.Permissions.SetProfile(profile)
+		case "mcp":
+			if app.CoderAgent != nil {
+				agent.UpdateMCP(previous.MCP, cfg.MCP)
+			}
+		}
+	}
+	return ConfigReloadedMsg{Changes: changes}
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"context"
+	"maps"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/doctor"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+)
+
+// Doctor checks the providers, and the language servers and MCP servers as
+// they run.
+func (app *App) Doctor(ctx context.Context) []doctor.Check {
+	app.clientsMutex.RLock()
+	clients := maps.Clone(app.LSPClients)
+	app.clientsMutex.RUnlock()
+
+	return doctor.Run(ctx, config.Get(), doctor.Options{
+		LSPClients: clients,
+		MCPStates:  agent.GetMCPStates(),
+	})
+}
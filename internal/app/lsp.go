@@ -3,22 +3,56 @@ package app
 import (
 	"context"
 	"log/slog"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/log"
 	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
 	"github.com/charmbracelet/crush/internal/lsp/watcher"
 )
 
-// initLSPClients initializes LSP clients.
+// initLSPClients initializes LSP clients. When lazy LSP startup is enabled the
+// servers are only started once the first message is created.
 func (app *App) initLSPClients(ctx context.Context) {
-	for name, clientConfig := range app.config.LSP {
+	if app.Config().Options.LazyLSP {
+		go app.deferLSPClients(ctx)
+		slog.Info("LSP clients initialization deferred until first message")
+		return
+	}
+	app.startLSPClients(ctx)
+}
+
+// startLSPClients starts all enabled LSP clients in parallel.
+func (app *App) startLSPClients(ctx context.Context) {
+	for name, clientConfig := range app.Config().LSP {
+		if clientConfig.Disabled {
+			continue
+		}
 		go app.createAndStartLSPClient(ctx, name, clientConfig)
 	}
 	slog.Info("LSP clients initialization started in background")
 }
 
+// deferLSPClients waits for the first message event and then starts the LSP
+// clients.
+func (app *App) deferLSPClients(ctx context.Context) {
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	select {
+	case _, ok := <-app.Messages.Subscribe(subCtx):
+		if !ok {
+			return
+		}
+	case <-ctx.Done():
+		return
+	}
+	app.startLSPClients(ctx)
+}
+
 // createAndStartLSPClient creates a new LSP client, initializes it, and starts its workspace watcher
 func (app *App) createAndStartLSPClient(ctx context.Context, name string, config config.LSPConfig) {
 	slog.Info("Creating LSP client", "name", name, "command", config.Command, "fileTypes", config.FileTypes, "args", config.Args)
@@ -42,7 +76,7 @@ func (app *App) createAndStartLSPClient(ctx context.Context, name string, config
 	defer cancel()
 
 	// Initialize LSP client.
-	_, err = lspClient.InitializeLSPClient(initCtx, app.config.WorkingDir())
+	_, err = lspClient.InitializeLSPClient(initCtx, app.Config().WorkingDir())
 	if err != nil {
 		slog.Error("Initialize failed", "name", name, "error", err)
 		updateLSPState(name, lsp.StateError, err, lspClient, 0)
@@ -94,14 +128,14 @@ func (app *App) runWorkspaceWatcher(ctx context.Context, name string, workspaceW
 		app.restartLSPClient(ctx, name)
 	})
 
-	workspaceWatcher.WatchWorkspace(ctx, app.config.WorkingDir())
+	workspaceWatcher.WatchWorkspace(ctx, app.Config().WorkingDir())
 	slog.Info("Workspace watcher stopped", "client", name)
 }
 
 // restartLSPClient attempts to restart a crashed or failed LSP client.
 func (app *App) restartLSPClient(ctx context.Context, name string) {
 	// Get the original configuration.
-	clientConfig, exists := app.config.LSP[name]
+	clientConfig, exists := app.Config().LSP[name]
 	if !exists {
 		slog.Error("Cannot restart client, configuration not found", "client", name)
 		return
@@ -127,3 +161,48 @@ func (app *App) restartLSPClient(ctx context.Context, name string) {
 	app.createAndStartLSPClient(ctx, name, clientConfig)
 	slog.Info("Successfully restarted LSP client", "client", name)
 }
+
+// Symbol is a symbol of the workspace found by a language server.
+type Symbol struct {
+	Name string
+	Path string
+	Line int
+}
+
+// WorkspaceSymbols asks the ready language servers for the symbols of the
+// workspace matching the query.
+func (app *App) WorkspaceSymbols(ctx context.Context, query string) []Symbol {
+	app.clientsMutex.RLock()
+	clients := slices.Collect(maps.Values(app.LSPClients))
+	app.clientsMutex.RUnlock()
+
+	var result []Symbol
+	for _, client := range clients {
+		if client.GetServerState() != lsp.StateReady {
+			continue
+		}
+		response, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: query})
+		if err != nil {
+			slog.Debug("Failed to get workspace symbols", "error", err)
+			continue
+		}
+		symbols, err := response.Results()
+		if err != nil {
+			slog.Debug("Failed to parse workspace symbols", "error", err)
+			continue
+		}
+		for _, s := range symbols {
+			location := s.GetLocation()
+			path, err := location.URI.Path()
+			if err != nil {
+				continue
+			}
+			result = append(result, Symbol{
+				Name: s.GetName(),
+				Path: path,
+				Line: int(location.Range.Start.Line) + 1,
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pin"
+)
+
+// maxPinTitleLength keeps the titles of the pinned messages on one line.
+const maxPinTitleLength = 80
+
+// PinCandidates returns the files touched in the session and its messages
+// which can be pinned, the ones pinned already left out. The messages come
+// latest first, then the files most recently touched first.
+func (app *App) PinCandidates(ctx context.Context, sessionID string) ([]pin.Pin, error) {
+	pinned, err := app.Pins.List(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	isPinned := func(c pin.Pin) bool {
+		return slices.ContainsFunc(pinned, func(p pin.Pin) bool {
+			return p.Kind == c.Kind && p.Path == c.Path && p.MessageID == c.MessageID
+		})
+	}
+
+	msgs, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []pin.Pin
+	for _, msg := range slices.Backward(msgs) {
+		text := strings.TrimSpace(msg.Content().Text)
+		if text == "" || (msg.Role != message.User && msg.Role != message.Assistant) {
+			continue
+		}
+		c := pin.Pin{
+			SessionID: sessionID,
+			Kind:      pin.KindMessage,
+			MessageID: msg.ID,
+			Title:     pinTitle(msg),
+			Tokens:    pin.EstimateTokens(len(text)),
+		}
+		if !isPinned(c) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	touched, err := app.History.ListTouched(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range touched {
+		info, err := os.Stat(f.Path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		c := pin.Pin{
+			SessionID: sessionID,
+			Kind:      pin.KindFile,
+			Path:      f.Path,
+			Title:     f.Path,
+			Tokens:    pin.EstimateTokens(int(info.Size())),
+		}
+		if !isPinned(c) {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates, nil
+}
+
+// Pin pins the candidate returned by PinCandidates to the context of its
+// session.
+func (app *App) Pin(ctx context.Context, candidate pin.Pin) (pin.Pin, error) {
+	if candidate.Kind == pin.KindFile {
+		return app.Pins.PinFile(candidate.SessionID, candidate.Path)
+	}
+	msg, err := app.Messages.Get(ctx, candidate.MessageID)
+	if err != nil {
+		return pin.Pin{}, err
+	}
+	return app.Pins.PinMessage(candidate.SessionID, msg.ID, pinTitle(msg), msg.Content().Text)
+}
+
+// pinTitle is the role and the first line of the message.
+func pinTitle(msg message.Message) string {
+	text := strings.TrimSpace(msg.Content().Text)
+	line, _, cut := strings.Cut(text, "\n")
+	if runes := []rune(line); len(runes) > maxPinTitleLength {
+		line, cut = string(runes[:maxPinTitleLength]), true
+	}
+	if cut {
+		line += " …"
+	}
+	role := "you"
+	if msg.Role == message.Assistant {
+		role = "assistant"
+	}
+	return role + ": " + line
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// rewindToolName is the tool name of the files put in the trash by a rewind.
+const rewindToolName = "rewind"
+
+// Rewind is the outcome of rewinding a session.
+type Rewind struct {
+	// Branch is the session archiving the messages discarded by the rewind,
+	// its trash keeping the files as they were before the rewind.
+	Branch session.Session
+	// Prompt is the text of the prompt the session was rewound to, for it to
+	// be edited and sent again.
+	Prompt string
+	// Files are the files restored to their content at the time of the
+	// prompt.
+	Files []string
+}
+
+// Rewind truncates the session back to the prompt of the message, moving it
+// and the messages after it to an archived branch, and restores the files
+// changed since to their content at the time of the prompt.
+func (app *App) Rewind(ctx context.Context, sessionID, messageID string) (Rewind, error) {
+	if app.CoderAgent != nil && app.CoderAgent.IsSessionBusy(sessionID) {
+		return Rewind{}, errors.New("the agent is working, cancel it first to rewind the session")
+	}
+	sess, err := app.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		return Rewind{}, err
+	}
+	msgs, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return Rewind{}, err
+	}
+	i := slices.IndexFunc(msgs, func(msg message.Message) bool {
+		return msg.ID == messageID
+	})
+	if i < 0 {
+		return Rewind{}, fmt.Errorf("message %s not found in the session", messageID)
+	}
+	if msgs[i].Role != message.User {
+		return Rewind{}, errors.New("a session can only be rewound to a prompt")
+	}
+	tail := msgs[i:]
+
+	branch, err := app.Sessions.CreateBranchSession(ctx, sessionID, "Rewound: "+sess.Title)
+	if err != nil {
+		return Rewind{}, fmt.Errorf("failed to create the branch of the session: %w", err)
+	}
+	result := Rewind{Branch: branch, Prompt: tail[0].Content().Text}
+
+	versions, err := app.History.ListBySession(ctx, sessionID)
+	if err != nil {
+		return Rewind{}, err
+	}
+	for _, checkpoint := range history.Checkpoint(versions, tail[0].CreatedAt) {
+		restored, err := app.restoreCheckpoint(ctx, sessionID, branch.ID, checkpoint)
+		if err != nil {
+			return Rewind{}, fmt.Errorf("failed to restore %s: %w", checkpoint.Path, err)
+		}
+		if restored {
+			result.Files = append(result.Files, checkpoint.Path)
+		}
+	}
+
+	for _, msg := range tail {
+		if err := app.Messages.Move(ctx, msg, branch.ID); err != nil {
+			return Rewind{}, fmt.Errorf("failed to archive the messages: %w", err)
+		}
+		if msg.ID == sess.SummaryMessageID {
+			sess.SummaryMessageID = ""
+			if _, err := app.Sessions.Save(ctx, sess); err != nil {
+				return Rewind{}, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// restoreCheckpoint puts the file back to its content at the checkpoint,
+// keeping its current content in the trash of the branch. It reports whether
+// the file changed.
+func (app *App) restoreCheckpoint(ctx context.Context, sessionID, branchID string, checkpoint history.FileCheckpoint) (bool, error) {
+	current, err := os.ReadFile(checkpoint.Path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if checkpoint.Missing {
+		if !exists {
+			return false, nil
+		}
+		_, err := app.Trash.Delete(branchID, checkpoint.Path, rewindToolName)
+		return err == nil, err
+	}
+	if exists && bytes.Equal(current, []byte(checkpoint.Content)) {
+		return false, nil
+	}
+	if exists {
+		if _, err := app.Trash.Keep(branchID, checkpoint.Path, rewindToolName); err != nil {
+			return false, err
+		}
+	}
+	if err := os.WriteFile(checkpoint.Path, []byte(checkpoint.Content), 0o644); err != nil {
+		return false, err
+	}
+	// The new version keeps the changes of the session shown in the sidebar
+	// in line with the files.
+	if _, err := app.History.CreateVersion(ctx, sessionID, checkpoint.Path, checkpoint.Content); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// EditPrompt replaces the prompt of the message with the text and regenerates
+// the conversation from it, the previous continuation being archived in the
+// branch of the rewind.
+func (app *App) EditPrompt(ctx context.Context, sessionID, messageID, text string, attachments ...message.Attachment) (Rewind, error) {
+	if app.CoderAgent == nil {
+		return Rewind{}, errors.New("coder agent is not initialized")
+	}
+	result, err := app.Rewind(ctx, sessionID, messageID)
+	if err != nil {
+		return Rewind{}, err
+	}
+	if _, err := app.CoderAgent.Run(ctx, sessionID, text, attachments...); err != nil {
+		return result, fmt.Errorf("failed to regenerate the session: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,370 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/format"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// Statuses of a non-interactive run.
+const (
+	RunStatusCompleted = "completed"
+	RunStatusFailed    = "failed"
+	RunStatusCancelled = "cancelled"
+)
+
+// ErrRunFailed is returned once the result of a run that didn't complete
+// was printed as JSON, for the process to exit with an error.
+var ErrRunFailed = errors.New("run failed")
+
+// RunOptions configure a non-interactive run.
+type RunOptions struct {
+	// Quiet hides the spinner.
+	Quiet bool
+	// JSON prints a RunResult once the run ends instead of streaming the
+	// answer.
+	JSON bool
+	// JSONL streams the events of the run as RunEvent JSON lines, ending
+	// with its RunResult.
+	JSONL bool
+	// AllowedToolsOnly denies the permission requests not approved by the
+	// allowed tools of the configuration, instead of approving them all.
+	AllowedToolsOnly bool
+	Attachments      []message.Attachment
+}
+
+// RunResult is the outcome of a non-interactive run, printed with --output
+// json or as the last event with --output jsonl.
+type RunResult struct {
+	SessionID         string          `json:"session_id"`
+	Status            string          `json:"status"`
+	Error             string          `json:"error,omitempty"`
+	Answer            string          `json:"answer"`
+	Messages          []RunMessage    `json:"messages"`
+	Diffs             []RunDiff       `json:"diffs"`
+	DeniedPermissions []RunPermission `json:"denied_permissions,omitempty"`
+	Usage             RunUsage        `json:"usage"`
+	ExitCode          int             `json:"exit_code"`
+}
+
+type RunMessage struct {
+	Role         string               `json:"role"`
+	Content      string               `json:"content,omitempty"`
+	ToolCalls    []message.ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults  []message.ToolResult `json:"tool_results,omitempty"`
+	FinishReason string               `json:"finish_reason,omitempty"`
+	Model        string               `json:"model,omitempty"`
+}
+
+type RunDiff struct {
+	Path      string `json:"path"`
+	Diff      string `json:"diff"`
+	Additions int    `json:"additions"`
+	Removals  int    `json:"removals"`
+}
+
+type RunPermission struct {
+	Tool        string `json:"tool"`
+	Action      string `json:"action"`
+	Path        string `json:"path"`
+	Description string `json:"description"`
+}
+
+type RunUsage struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// RunNonInteractive handles the execution flow when a prompt is provided via
+// CLI flag.
+func (app *App) RunNonInteractive(ctx context.Context, prompt string, opts RunOptions) error {
+	slog.Info("Running in non-interactive mode")
+
+	if !app.CoderAgent.Model().SupportsImages {
+		for _, attachment := range opts.Attachments {
+			if !message.IsTextMIMEType(attachment.MimeType) {
+				return fmt.Errorf("the model %s doesn't support images, can't attach %s", app.CoderAgent.Model().Name, attachment.FileName)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start spinner if not in quiet mode.
+	quiet := opts.Quiet || opts.JSON || opts.JSONL
+	var spinner *format.Spinner
+	if !quiet {
+		spinner = format.NewSpinner(ctx, cancel, "Generating")
+		spinner.Start()
+	}
+
+	// Helper function to stop spinner once.
+	stopSpinner := func() {
+		if !quiet && spinner != nil {
+			spinner.Stop()
+			spinner = nil
+		}
+	}
+	defer stopSpinner()
+
+	const maxPromptLengthForTitle = 100
+	titlePrefix := "Non-interactive: "
+	var titleSuffix string
+
+	if len(prompt) > maxPromptLengthForTitle {
+		titleSuffix = prompt[:maxPromptLengthForTitle] + "..."
+	} else {
+		titleSuffix = prompt
+	}
+	title := titlePrefix + titleSuffix
+
+	sess, err := app.Sessions.Create(ctx, title)
+	if err != nil {
+		return fmt.Errorf("failed to create session for non-interactive mode: %w", err)
+	}
+	slog.Info("Created session for non-interactive run", "session_id", sess.ID)
+
+	// Nobody can answer the permission requests, they are either all
+	// approved or denied unless the configuration allows the tool.
+	var permissionRequests <-chan pubsub.Event[permission.PermissionRequest]
+	if opts.AllowedToolsOnly {
+		permissionRequests = app.Permissions.Subscribe(ctx)
+	} else {
+		app.Permissions.AutoApproveSession(sess.ID)
+	}
+	var denied []RunPermission
+
+	done, err := app.CoderAgent.Run(ctx, sess.ID, prompt, opts.Attachments...)
+	if err != nil {
+		return fmt.Errorf("failed to start agent processing stream: %w", err)
+	}
+
+	messageEvents := app.Messages.Subscribe(ctx)
+	readBts := 0
+	var events *runEvents
+	if opts.JSONL {
+		events = newRunEvents(os.Stdout, sess.ID)
+		if err := events.write(RunEvent{Type: RunEventSession}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case result := <-done:
+			stopSpinner()
+			if opts.JSON || opts.JSONL {
+				return app.printRunResult(ctx, sess.ID, result, denied, events)
+			}
+
+			if result.Error != nil {
+				if errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled) {
+					slog.Info("Non-interactive: agent processing cancelled", "session_id", sess.ID)
+					return nil
+				}
+				return fmt.Errorf("agent processing failed: %w", result.Error)
+			}
+
+			msgContent := result.Message.Content().String()
+			if len(msgContent) < readBts {
+				slog.Error("Non-interactive: message content is shorter than read bytes", "message_length", len(msgContent), "read_bytes", readBts)
+				return fmt.Errorf("message content is shorter than read bytes: %d < %d", len(msgContent), readBts)
+			}
+			fmt.Println(msgContent[readBts:])
+
+			slog.Info("Non-interactive: run completed", "session_id", sess.ID)
+			return nil
+
+		case event := <-messageEvents:
+			msg := event.Payload
+			if events != nil && msg.SessionID == sess.ID {
+				if err := events.message(msg); err != nil {
+					return err
+				}
+				continue
+			}
+			if !opts.JSON && msg.SessionID == sess.ID && msg.Role == message.Assistant && len(msg.Parts) > 0 {
+				stopSpinner()
+				part := msg.Content().String()[readBts:]
+				fmt.Print(part)
+				readBts += len(part)
+			}
+
+		case event := <-permissionRequests:
+			req := event.Payload
+			if req.SessionID != sess.ID {
+				continue
+			}
+			slog.Warn("Non-interactive: denied a permission not allowed by the configuration", "tool", req.ToolName, "action", req.Action)
+			permission := RunPermission{
+				Tool:        req.ToolName,
+				Action:      req.Action,
+				Path:        req.Path,
+				Description: req.Description,
+			}
+			denied = append(denied, permission)
+			app.Permissions.Deny(req)
+			if events != nil {
+				if err := events.write(RunEvent{Type: RunEventPermissionDenied, Permission: &permission}); err != nil {
+					return err
+				}
+			}
+
+		case <-ctx.Done():
+			stopSpinner()
+			return ctx.Err()
+		}
+	}
+}
+
+// RunPrompt runs the prompt in a new session, approving all the permission
+// requests, and returns its result once it ends. Unlike RunNonInteractive it
+// prints nothing, for the callers reporting the runs themselves.
+func (app *App) RunPrompt(ctx context.Context, title, prompt string) (RunResult, error) {
+	sess, err := app.Sessions.Create(ctx, title)
+	if err != nil {
+		return RunResult{}, fmt.Errorf("failed to create session: %w", err)
+	}
+	app.Permissions.AutoApproveSession(sess.ID)
+	done, err := app.CoderAgent.Run(ctx, sess.ID, prompt)
+	if err != nil {
+		return RunResult{}, fmt.Errorf("failed to start agent processing stream: %w", err)
+	}
+	// The run ends once cancelled along with ctx.
+	return app.runResult(ctx, sess.ID, <-done, nil, nil)
+}
+
+// printRunResult prints the result of the run as JSON, or as the last of
+// the events when they are streamed, returning ErrRunFailed when the run
+// didn't complete.
+func (app *App) printRunResult(ctx context.Context, sessionID string, event agent.AgentEvent, denied []RunPermission, events *runEvents) error {
+	result, err := app.runResult(ctx, sessionID, event, denied, events)
+	if err != nil {
+		return err
+	}
+	if events != nil {
+		if err := events.write(RunEvent{Type: RunEventResult, Result: &result}); err != nil {
+			return err
+		}
+	} else {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	}
+	if result.ExitCode != 0 {
+		return ErrRunFailed
+	}
+	return nil
+}
+
+// runResult returns the result of the run, writing the messages left to
+// the events when they are streamed.
+func (app *App) runResult(ctx context.Context, sessionID string, event agent.AgentEvent, denied []RunPermission, events *runEvents) (RunResult, error) {
+	result := RunResult{
+		SessionID:         sessionID,
+		Status:            RunStatusCompleted,
+		Answer:            event.Message.Content().String(),
+		Messages:          []RunMessage{},
+		Diffs:             []RunDiff{},
+		DeniedPermissions: denied,
+	}
+	switch {
+	case event.Error == nil:
+	case errors.Is(event.Error, context.Canceled) || errors.Is(event.Error, agent.ErrRequestCancelled):
+		result.Status = RunStatusCancelled
+		result.Error = event.Error.Error()
+		result.ExitCode = 1
+	default:
+		result.Status = RunStatusFailed
+		result.Error = event.Error.Error()
+		result.ExitCode = 1
+	}
+
+	// The run is over, read what it left even when ctx is cancelled.
+	ctx = context.WithoutCancel(ctx)
+	if msgs, err := app.Messages.List(ctx, sessionID); err == nil {
+		for _, msg := range msgs {
+			if events != nil {
+				// Write what was left of the run before its result.
+				if err := events.message(msg); err != nil {
+					return result, err
+				}
+			}
+			m := RunMessage{
+				Role:        string(msg.Role),
+				Content:     msg.Content().String(),
+				ToolCalls:   msg.ToolCalls(),
+				ToolResults: msg.ToolResults(),
+				Model:       msg.Model,
+			}
+			if msg.IsFinished() {
+				m.FinishReason = string(msg.FinishReason())
+			}
+			result.Messages = append(result.Messages, m)
+		}
+	}
+	if diffs, err := app.SessionDiffs(ctx, sessionID); err == nil {
+		result.Diffs = diffs
+	}
+	if s, err := app.Sessions.Get(ctx, sessionID); err == nil {
+		result.Usage = RunUsage{
+			PromptTokens:     s.PromptTokens,
+			CompletionTokens: s.CompletionTokens,
+			Cost:             s.Cost,
+		}
+	}
+	return result, nil
+}
+
+// SessionDiffs returns the changes of the files edited in the session, from
+// their first to their last version.
+func (app *App) SessionDiffs(ctx context.Context, sessionID string) ([]RunDiff, error) {
+	files, err := app.History.ListBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	type versions struct{ first, last string }
+	var paths []string
+	byPath := make(map[string]*versions)
+	for _, f := range files {
+		if v, ok := byPath[f.Path]; ok {
+			v.last = f.Content
+			continue
+		}
+		paths = append(paths, f.Path)
+		byPath[f.Path] = &versions{first: f.Content, last: f.Content}
+	}
+
+	diffs := make([]RunDiff, 0, len(paths))
+	for _, path := range paths {
+		v := byPath[path]
+		if v.first == v.last {
+			continue
+		}
+		rel := path
+		if r, err := filepath.Rel(app.Config().WorkingDir(), path); err == nil {
+			rel = r
+		}
+		before, _ := fsext.ToUnixLineEndings(v.first)
+		after, _ := fsext.ToUnixLineEndings(v.last)
+		d, additions, removals := diff.GenerateDiff(before, after, rel)
+		diffs = append(diffs, RunDiff{Path: rel, Diff: d, Additions: additions, Removals: removals})
+	}
+	return diffs, nil
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// Types of the events of a run streamed with --output jsonl.
+const (
+	RunEventSession          = "session"
+	RunEventText             = "text"
+	RunEventToolCall         = "tool_call"
+	RunEventToolResult       = "tool_result"
+	RunEventPermissionDenied = "permission_denied"
+	RunEventResult           = "result"
+)
+
+// RunEvent is a line of the output of a run with --output jsonl. The session
+// event starts the run and the result event ends it.
+type RunEvent struct {
+	Type      string `json:"type"`
+	SessionID string `json:"session_id"`
+	// Text is the text the assistant streamed since the last text event.
+	Text       string              `json:"text,omitempty"`
+	ToolCall   *message.ToolCall   `json:"tool_call,omitempty"`
+	ToolResult *message.ToolResult `json:"tool_result,omitempty"`
+	Permission *RunPermission      `json:"permission,omitempty"`
+	Result     *RunResult          `json:"result,omitempty"`
+}
+
+// runEvents writes the events of a run as JSON lines, as its messages are
+// updated.
+type runEvents struct {
+	enc       *json.Encoder
+	sessionID string
+	// written is the length of the text of each message already written.
+	written map[string]int
+	// calls and results are the IDs of the tool calls and of the results
+	// already written.
+	calls   map[string]bool
+	results map[string]bool
+}
+
+func newRunEvents(w io.Writer, sessionID string) *runEvents {
+	return &runEvents{
+		enc:       json.NewEncoder(w),
+		sessionID: sessionID,
+		written:   make(map[string]int),
+		calls:     make(map[string]bool),
+		results:   make(map[string]bool),
+	}
+}
+
+func (e *runEvents) write(event RunEvent) error {
+	event.SessionID = e.sessionID
+	return e.enc.Encode(event)
+}
+
+// message writes the text the message gained since it was last written, and
+// its tool calls and results once they are finished.
+func (e *runEvents) message(msg message.Message) error {
+	switch msg.Role {
+	case message.Assistant:
+		text := msg.Content().String()
+		if written := e.written[msg.ID]; len(text) > written {
+			e.written[msg.ID] = len(text)
+			if err := e.write(RunEvent{Type: RunEventText, Text: text[written:]}); err != nil {
+				return err
+			}
+		}
+		for _, call := range msg.ToolCalls() {
+			if !call.Finished || e.calls[call.ID] {
+				continue
+			}
+			e.calls[call.ID] = true
+			if err := e.write(RunEvent{Type: RunEventToolCall, ToolCall: &call}); err != nil {
+				return err
+			}
+		}
+	case message.Tool:
+		for _, result := range msg.ToolResults() {
+			if e.results[result.ToolCallID] {
+				continue
+			}
+			e.results[result.ToolCallID] = true
+			if err := e.write(RunEvent{Type: RunEventToolResult, ToolResult: &result}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
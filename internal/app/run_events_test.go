@@ -0,0 +1,49 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+func TestRunEvents(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	events := newRunEvents(&buf, "s")
+
+	msg := message.Message{ID: "m", Role: message.Assistant}
+	msg.AppendContent("Let me ")
+	require.NoError(t, events.message(msg))
+	msg.AppendContent("look.")
+	msg.AddToolCall(message.ToolCall{ID: "c", Name: "ls"})
+	require.NoError(t, events.message(msg))
+	msg.FinishToolCall("c")
+	require.NoError(t, events.message(msg))
+	require.NoError(t, events.message(msg))
+
+	tool := message.Message{ID: "t", Role: message.Tool}
+	tool.AddToolResult(message.ToolResult{ToolCallID: "c", Content: "main.go"})
+	require.NoError(t, events.message(tool))
+	require.NoError(t, events.message(tool))
+
+	var got []RunEvent
+	for line := range strings.Lines(buf.String()) {
+		var event RunEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		require.Equal(t, "s", event.SessionID)
+		got = append(got, event)
+	}
+	require.Len(t, got, 4)
+	require.Equal(t, RunEvent{Type: RunEventText, SessionID: "s", Text: "Let me "}, got[0])
+	require.Equal(t, RunEvent{Type: RunEventText, SessionID: "s", Text: "look."}, got[1])
+	require.Equal(t, RunEventToolCall, got[2].Type)
+	require.Equal(t, "ls", got[2].ToolCall.Name)
+	require.Equal(t, RunEventToolResult, got[3].Type)
+	require.Equal(t, "main.go", got[3].ToolResult.Content)
+}
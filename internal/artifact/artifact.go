@@ -0,0 +1,335 @@
+// Package artifact stores large tool outputs, files and images per session
+// so they can be referenced from the conversation instead of inlined.
+package artifact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/encryption"
+	"github.com/google/uuid"
+)
+
+const (
+	artifactsDirName = "artifacts"
+	metadataSuffix   = ".json"
+)
+
+type Artifact struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	Name      string `json:"name"`
+	MimeType  string `json:"mime_type"`
+	Size      int64  `json:"size"`
+	// ToolName is the tool that produced the artifact.
+	ToolName  string `json:"tool_name,omitempty"`
+	Path      string `json:"path"`
+	CreatedAt int64  `json:"created_at"`
+	// Encrypted is set when the file at Path is encrypted with the key of
+	// the data directory.
+	Encrypted bool `json:"encrypted,omitempty"`
+}
+
+// IsText reports whether the artifact holds text that can be read in
+// chunks by the model or opened in an editor.
+func (a Artifact) IsText() bool {
+	return strings.HasPrefix(a.MimeType, "text/") ||
+		a.MimeType == "application/json" ||
+		a.MimeType == "application/xml"
+}
+
+type CreateParams struct {
+	Name     string
+	MimeType string
+	ToolName string
+	Data     []byte
+}
+
+type Service interface {
+	Create(sessionID string, params CreateParams) (Artifact, error)
+	Get(sessionID, id string) (Artifact, error)
+	List(sessionID string) ([]Artifact, error)
+	Read(sessionID, id string) ([]byte, error)
+	// PlainPath returns the path of a file holding the contents of the
+	// artifact, a decrypted copy in the temporary directory when it is
+	// encrypted. remove deletes the copy once it was read, it does nothing
+	// for the artifacts which aren't encrypted.
+	PlainPath(a Artifact) (path string, remove func(), err error)
+	// RemovePlainCopies deletes the decrypted copies not removed yet.
+	RemovePlainCopies()
+	DeleteSessionArtifacts(sessionID string) error
+	// Sessions returns the IDs of the sessions with artifacts.
+	Sessions() ([]string, error)
+}
+
+type service struct {
+	dir     string
+	dataDir string
+	mu      sync.Mutex
+	// copies holds the directories of the decrypted copies not removed yet.
+	copies map[string]struct{}
+}
+
+// NewService returns a store keeping the artifacts in the artifacts
+// directory of the given data directory.
+func NewService(dataDir string) Service {
+	return &service{
+		dir:     filepath.Join(dataDir, artifactsDirName),
+		dataDir: dataDir,
+		copies:  make(map[string]struct{}),
+	}
+}
+
+func (s *service) sessionDir(sessionID string) string {
+	return filepath.Join(s.dir, filepath.Base(sessionID))
+}
+
+func (s *service) Create(sessionID string, params CreateParams) (Artifact, error) {
+	if sessionID == "" {
+		return Artifact{}, errors.New("session ID is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.sessionDir(sessionID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return Artifact{}, fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+
+	id := uuid.New().String()[:8]
+	name := filepath.Base(params.Name)
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "artifact"
+	}
+	mimeType := params.MimeType
+	if mimeType == "" {
+		mimeType = "text/plain"
+	}
+
+	a := Artifact{
+		ID:        id,
+		SessionID: sessionID,
+		Name:      name,
+		MimeType:  mimeType,
+		Size:      int64(len(params.Data)),
+		ToolName:  params.ToolName,
+		Path:      filepath.Join(dir, id+"-"+name),
+		CreatedAt: time.Now().Unix(),
+	}
+	key, err := encryption.Key(s.dataDir)
+	if err != nil {
+		return Artifact{}, err
+	}
+	return writeArtifact(a, params.Data, key)
+}
+
+// writeArtifact writes the contents and the metadata of the artifact,
+// encrypted with the key when there is one.
+func writeArtifact(a Artifact, data, key []byte) (Artifact, error) {
+	a.Encrypted = key != nil
+	if a.Encrypted {
+		sealed, err := encryption.Seal(key, data)
+		if err != nil {
+			return Artifact{}, fmt.Errorf("failed to encrypt artifact: %w", err)
+		}
+		data = sealed
+	}
+	if err := os.WriteFile(a.Path, data, 0o600); err != nil {
+		return Artifact{}, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	meta, err := json.Marshal(a)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to marshal artifact: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(a.Path), a.ID+metadataSuffix), meta, 0o600); err != nil {
+		return Artifact{}, fmt.Errorf("failed to write artifact metadata: %w", err)
+	}
+	return a, nil
+}
+
+func (s *service) Get(sessionID, id string) (Artifact, error) {
+	data, err := os.ReadFile(filepath.Join(s.sessionDir(sessionID), filepath.Base(id)+metadataSuffix))
+	if errors.Is(err, os.ErrNotExist) {
+		return Artifact{}, fmt.Errorf("artifact not found: %s", id)
+	}
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	var a Artifact
+	if err := json.Unmarshal(data, &a); err != nil {
+		return Artifact{}, fmt.Errorf("failed to parse artifact metadata: %w", err)
+	}
+	return a, nil
+}
+
+// List returns the artifacts of the session, most recent first.
+func (s *service) List(sessionID string) ([]Artifact, error) {
+	entries, err := os.ReadDir(s.sessionDir(sessionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	var artifacts []Artifact
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), metadataSuffix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		a, err := s.Get(sessionID, id)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, a)
+	}
+	slices.SortFunc(artifacts, func(a, b Artifact) int {
+		if a.CreatedAt != b.CreatedAt {
+			return int(b.CreatedAt - a.CreatedAt)
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return artifacts, nil
+}
+
+func (s *service) Read(sessionID, id string) ([]byte, error) {
+	a, err := s.Get(sessionID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.read(a)
+}
+
+func (s *service) read(a Artifact) ([]byte, error) {
+	data, err := os.ReadFile(a.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	if !a.Encrypted {
+		return data, nil
+	}
+	key, err := encryption.Key(s.dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("artifact %s is encrypted but the data directory isn't", a.ID)
+	}
+	data, err = encryption.Open(key, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt artifact: %w", err)
+	}
+	return data, nil
+}
+
+func (s *service) PlainPath(a Artifact) (string, func(), error) {
+	if !a.Encrypted {
+		return a.Path, func() {}, nil
+	}
+	data, err := s.read(a)
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp("", "crush-artifact-")
+	if err != nil {
+		return "", nil, err
+	}
+	s.mu.Lock()
+	s.copies[dir] = struct{}{}
+	s.mu.Unlock()
+	remove := func() {
+		s.mu.Lock()
+		delete(s.copies, dir)
+		s.mu.Unlock()
+		removePlainCopy(dir)
+	}
+	path := filepath.Join(dir, a.Name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		remove()
+		return "", nil, err
+	}
+	return path, remove, nil
+}
+
+func (s *service) RemovePlainCopies() {
+	s.mu.Lock()
+	dirs := slices.Collect(maps.Keys(s.copies))
+	clear(s.copies)
+	s.mu.Unlock()
+	for _, dir := range dirs {
+		removePlainCopy(dir)
+	}
+}
+
+func removePlainCopy(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		slog.Error("Failed to remove the decrypted copy of an artifact", "path", dir, "error", err)
+	}
+}
+
+func (s *service) DeleteSessionArtifacts(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.RemoveAll(s.sessionDir(sessionID)); err != nil {
+		return fmt.Errorf("failed to delete artifacts: %w", err)
+	}
+	return nil
+}
+
+func (s *service) Sessions() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() {
+			ids = append(ids, e.Name())
+		}
+	}
+	return ids, nil
+}
+
+// Encrypt encrypts with the key the artifacts of the data directory that
+// aren't encrypted yet.
+func Encrypt(dataDir string, key []byte) error {
+	s := &service{dir: filepath.Join(dataDir, artifactsDirName), dataDir: dataDir}
+	sessions, err := s.Sessions()
+	if err != nil {
+		return err
+	}
+	for _, sessionID := range sessions {
+		artifacts, err := s.List(sessionID)
+		if err != nil {
+			return err
+		}
+		for _, a := range artifacts {
+			if a.Encrypted {
+				continue
+			}
+			data, err := os.ReadFile(a.Path)
+			if err != nil {
+				return fmt.Errorf("failed to read artifact: %w", err)
+			}
+			if _, err := writeArtifact(a, data, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
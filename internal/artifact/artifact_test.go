@@ -0,0 +1,104 @@
+package artifact
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/encryption"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	t.Parallel()
+
+	s := NewService(t.TempDir())
+
+	list, err := s.List("session")
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	_, err = s.Create("", CreateParams{Name: "out.txt", Data: []byte("x")})
+	require.Error(t, err)
+
+	a, err := s.Create("session", CreateParams{
+		Name:     "../../stdout.txt",
+		ToolName: "bash",
+		Data:     []byte("line 1\nline 2\n"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "stdout.txt", a.Name)
+	require.Equal(t, "text/plain", a.MimeType)
+	require.Equal(t, int64(14), a.Size)
+	require.True(t, a.IsText())
+
+	img, err := s.Create("session", CreateParams{Name: "shot.png", MimeType: "image/png", Data: []byte{0x89}})
+	require.NoError(t, err)
+	require.False(t, img.IsText())
+
+	got, err := s.Get("session", a.ID)
+	require.NoError(t, err)
+	require.Equal(t, a, got)
+
+	data, err := s.Read("session", a.ID)
+	require.NoError(t, err)
+	require.Equal(t, "line 1\nline 2\n", string(data))
+
+	_, err = s.Get("other", a.ID)
+	require.Error(t, err)
+
+	list, err = s.List("session")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	require.NoError(t, s.DeleteSessionArtifacts("session"))
+	list, err = s.List("session")
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func TestEncryptedService(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	s := NewService(dataDir)
+	plain, err := s.Create("session", CreateParams{Name: "before.txt", Data: []byte("written before")})
+	require.NoError(t, err)
+
+	key, err := encryption.Setup(dataDir, encryption.SourcePassphrase, "correct horse")
+	require.NoError(t, err)
+	require.NoError(t, Encrypt(dataDir, key))
+	a, err := s.Create("session", CreateParams{Name: "after.txt", Data: []byte("written after")})
+	require.NoError(t, err)
+	require.True(t, a.Encrypted)
+
+	for _, want := range []struct {
+		id, content string
+	}{{plain.ID, "written before"}, {a.ID, "written after"}} {
+		got, err := s.Get("session", want.id)
+		require.NoError(t, err)
+		require.True(t, got.Encrypted)
+		onDisk, err := os.ReadFile(got.Path)
+		require.NoError(t, err)
+		require.NotContains(t, string(onDisk), want.content)
+
+		data, err := s.Read("session", want.id)
+		require.NoError(t, err)
+		require.Equal(t, want.content, string(data))
+
+		path, remove, err := s.PlainPath(got)
+		require.NoError(t, err)
+		require.NotEqual(t, got.Path, path)
+		decrypted, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, want.content, string(decrypted))
+		remove()
+		require.NoDirExists(t, filepath.Dir(path))
+	}
+
+	// The copies still open are removed on shutdown.
+	path, _, err := s.PlainPath(a)
+	require.NoError(t, err)
+	s.RemovePlainCopies()
+	require.NoDirExists(t, filepath.Dir(path))
+}
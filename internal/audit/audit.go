@@ -0,0 +1,299 @@
+// Package audit keeps an append-only, tamper-evident log of the actions of
+// the agent: tool calls, permission decisions, file changes and the requests
+// sent to the providers.
+//
+// Each entry holds the hash of the previous one, so removing or changing an
+// entry breaks the chain from that entry on, which Verify detects.
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// FileName is the name of the log in the data directory.
+	FileName = "audit.jsonl"
+
+	// maxInputLength keeps the contents written by the tools from bloating
+	// the log, the hash of the whole input is kept instead.
+	maxInputLength = 4096
+	// truncatedMarker ends the inputs cut at maxInputLength.
+	truncatedMarker = "...(truncated)"
+	// tailChunkSize is read backwards from the end of the log to find its
+	// last entry.
+	tailChunkSize = 64 * 1024
+)
+
+// Types of the entries.
+const (
+	TypeToolCall   = "tool_call"
+	TypePermission = "permission"
+	TypeFileChange = "file_change"
+	TypeLLMRequest = "llm_request"
+)
+
+// Entry is a line of the log. Data holds one of ToolCall, Permission,
+// FileChange or LLMRequest, depending on the type.
+type Entry struct {
+	Seq       int64           `json:"seq"`
+	Time      int64           `json:"time"`
+	Type      string          `json:"type"`
+	SessionID string          `json:"session_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
+type ToolCall struct {
+	Tool       string `json:"tool"`
+	ToolCallID string `json:"tool_call_id"`
+	Input      string `json:"input"`
+	// InputHash is the hash of the whole input, set when Input is truncated.
+	InputHash  string `json:"input_sha256,omitempty"`
+	IsError    bool   `json:"is_error"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type Permission struct {
+	Tool       string `json:"tool"`
+	Action     string `json:"action"`
+	Path       string `json:"path,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Granted    bool   `json:"granted"`
+	// Reason tells who decided: the user, or the rule that approved the
+	// request without asking.
+	Reason string `json:"reason"`
+}
+
+type FileChange struct {
+	Path    string `json:"path"`
+	Version int64  `json:"version"`
+	// ContentHash is the hash of the new content of the file.
+	ContentHash string `json:"content_sha256"`
+}
+
+type LLMRequest struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// Purpose is what the request is for: the prompt, a title or a summary.
+	Purpose  string `json:"purpose"`
+	Messages int    `json:"messages"`
+	Tools    int    `json:"tools"`
+}
+
+// writeMu serializes the writes of all the logs of the process, so entries
+// written from different services chain one after the other. The file lock
+// taken by Record does the same across processes.
+var writeMu sync.Mutex
+
+// Log appends entries to an audit log file.
+type Log struct {
+	path string
+}
+
+// New returns the log at the path, or at audit.jsonl in the data directory
+// when the path is empty.
+func New(dataDir, path string) *Log {
+	if path == "" {
+		path = filepath.Join(dataDir, FileName)
+	}
+	return &Log{path: path}
+}
+
+// Path returns the path of the log file.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record appends an entry of the given type, chained to the last entry of
+// the log.
+func (l *Log) Record(typ, sessionID string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The last entry is read from the file rather than remembered, and the
+	// file is locked until the new entry is appended, so the chain stays
+	// valid when several processes share the log.
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("failed to lock the audit log: %w", err)
+	}
+	defer unlockFile(f) //nolint:errcheck
+	last, err := lastEntry(f)
+	if err != nil {
+		return fmt.Errorf("failed to read the last audit entry: %w", err)
+	}
+	entry := Entry{
+		Time:      time.Now().UnixMilli(),
+		Type:      typ,
+		SessionID: sessionID,
+		Data:      raw,
+	}
+	if last != nil {
+		entry.Seq = last.Seq + 1
+		entry.PrevHash = last.Hash
+	}
+	if entry.Hash, err = hashEntry(entry); err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// hashEntry returns the hash of the entry without its own hash, which
+// includes the hash of the previous entry.
+func hashEntry(e Entry) (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lastEntry reads the entries backwards from the end of the file until it
+// finds the last one. It returns nil when the file is empty.
+func lastEntry(f *os.File) (*Entry, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+	var tail []byte
+	for end > 0 {
+		start := max(end-tailChunkSize, 0)
+		chunk := make([]byte, end-start)
+		if _, err := f.ReadAt(chunk, start); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		tail = append(chunk, tail...)
+		end = start
+		trimmed := bytes.TrimRight(tail, "\n")
+		if len(trimmed) == 0 && end == 0 {
+			return nil, nil
+		}
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 || end == 0 {
+			var e Entry
+			if err := json.Unmarshal(trimmed[i+1:], &e); err != nil {
+				return nil, err
+			}
+			return &e, nil
+		}
+	}
+	return nil, nil
+}
+
+// ToolInput returns the input of a tool call as recorded, truncated with the
+// hash of the whole input when it is too long.
+func ToolInput(input string) (string, string) {
+	if len(input) <= maxInputLength {
+		return input, ""
+	}
+	// Cut at the start of a character, not in the middle of its bytes.
+	cut := maxInputLength
+	for cut > 0 && !utf8.RuneStart(input[cut]) {
+		cut--
+	}
+	return input[:cut] + truncatedMarker, ContentHash(input)
+}
+
+// ContentHash returns the hex encoded SHA-256 of the content.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Read returns the entries of the log file for which keep returns true, in
+// order. A missing log has no entries.
+func Read(path string, keep func(Entry) bool) ([]Entry, error) {
+	var entries []Entry
+	err := scan(path, func(e Entry) error {
+		if keep == nil || keep(e) {
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Verify checks the chain of hashes of the log file. It returns the number
+// of entries, and an error telling the first entry that was changed,
+// removed or inserted.
+func Verify(path string) (int, error) {
+	var n int
+	var prev *Entry
+	err := scan(path, func(e Entry) error {
+		n++
+		hash, err := hashEntry(e)
+		if err != nil {
+			return err
+		}
+		if hash != e.Hash {
+			return fmt.Errorf("entry %d was modified: its hash does not match its content", e.Seq)
+		}
+		switch {
+		case prev == nil && (e.Seq != 0 || e.PrevHash != ""):
+			return fmt.Errorf("entry %d is the first one: the entries before it were removed", e.Seq)
+		case prev != nil && e.Seq != prev.Seq+1:
+			return fmt.Errorf("entry %d follows entry %d: the entries between them were removed", e.Seq, prev.Seq)
+		case prev != nil && e.PrevHash != prev.Hash:
+			return fmt.Errorf("entry %d does not chain to entry %d", e.Seq, prev.Seq)
+		}
+		prev = &e
+		return nil
+	})
+	return n, err
+}
+
+func scan(path string, fn func(Entry) error) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("line %d of %s is not a valid entry: %w", i+1, path, err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordChainsEntries(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	l := New(dir, "")
+	require.NoError(t, l.Record(TypeLLMRequest, "s1", LLMRequest{Provider: "anthropic", Model: "claude", Purpose: "prompt"}))
+	require.NoError(t, l.Record(TypeToolCall, "s1", ToolCall{Tool: "bash", Input: `{"command":"ls <dir>"}`}))
+	// A new log of the same file continues the chain.
+	require.NoError(t, New(dir, "").Record(TypePermission, "s1", Permission{Tool: "bash", Granted: true, Reason: "user"}))
+
+	entries, err := Read(l.Path(), nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, int64(2), entries[2].Seq)
+	require.Equal(t, entries[1].Hash, entries[2].PrevHash)
+
+	n, err := Verify(l.Path())
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+}
+
+func TestRecordWaitsForTheFileLock(t *testing.T) {
+	t.Parallel()
+
+	l := New(t.TempDir(), "")
+	require.NoError(t, l.Record(TypeToolCall, "s1", ToolCall{Tool: "ls"}))
+
+	// Another process holding the lock is simulated with another handle.
+	f, err := os.OpenFile(l.Path(), os.O_RDWR, 0o600)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, lockFile(f))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Record(TypeToolCall, "s1", ToolCall{Tool: "view"})
+	}()
+	select {
+	case <-done:
+		t.Fatal("the entry was appended while the log was locked")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(t, unlockFile(f))
+	require.NoError(t, <-done)
+
+	n, err := Verify(l.Path())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	t.Parallel()
+
+	record := func(t *testing.T) string {
+		l := New(t.TempDir(), "")
+		for _, tool := range []string{"view", "bash", "edit"} {
+			require.NoError(t, l.Record(TypeToolCall, "s1", ToolCall{Tool: tool}))
+		}
+		return l.Path()
+	}
+	rewrite := func(t *testing.T, path string, fn func([]string) []string) {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.NoError(t, os.WriteFile(path, []byte(strings.Join(fn(lines), "\n")+"\n"), 0o600))
+	}
+
+	t.Run("modified", func(t *testing.T) {
+		path := record(t)
+		rewrite(t, path, func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], "bash", "view", 1)
+			return lines
+		})
+		_, err := Verify(path)
+		require.ErrorContains(t, err, "entry 1 was modified")
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		path := record(t)
+		rewrite(t, path, func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		})
+		_, err := Verify(path)
+		require.ErrorContains(t, err, "entries between them were removed")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		n, err := Verify(filepath.Join(t.TempDir(), FileName))
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+}
+
+func TestToolInput(t *testing.T) {
+	t.Parallel()
+
+	input, hash := ToolInput(`{"command":"ls"}`)
+	require.Equal(t, `{"command":"ls"}`, input)
+	require.Empty(t, hash)
+
+	// The multi-byte character across the limit isn't split.
+	long := strings.Repeat("a", maxInputLength-1) + "한글"
+	input, hash = ToolInput(long)
+	require.Equal(t, strings.Repeat("a", maxInputLength-1)+truncatedMarker, input)
+	require.True(t, utf8.ValidString(input))
+	require.Equal(t, ContentHash(long), hash)
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package audit
+
+import "os"
+
+// lockFile does nothing on the platforms without file locks, where only the
+// writes of the process are serialized.
+func lockFile(*os.File) error {
+	return nil
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/audit"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Query the audit log of the agent actions",
+	Long: `List the entries of the audit log: the tool calls, permission decisions, file
+changes and provider requests of the agent. The log is written when
+options.audit.enabled is set, and each entry is chained to the previous one by
+its hash, see crush audit verify.`,
+	Example: `
+# Show the actions of the last day
+crush audit --since 24h
+
+# Show the permission decisions of a session
+crush audit --session 3f2a --type permission
+
+# Print the bash commands run as JSON lines
+crush audit --tool bash --json
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := auditLogPath(cmd)
+		if err != nil {
+			return err
+		}
+		sessionID, _ := cmd.Flags().GetString("session")
+		typ, _ := cmd.Flags().GetString("type")
+		tool, _ := cmd.Flags().GetString("tool")
+		since, _ := cmd.Flags().GetDuration("since")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		var after int64
+		if since > 0 {
+			after = time.Now().Add(-since).UnixMilli()
+		}
+		entries, err := audit.Read(path, func(e audit.Entry) bool {
+			if sessionID != "" && !strings.HasPrefix(e.SessionID, sessionID) {
+				return false
+			}
+			if typ != "" && e.Type != typ {
+				return false
+			}
+			if tool != "" && auditTool(e) != tool {
+				return false
+			}
+			return e.Time >= after
+		})
+		if err != nil {
+			return err
+		}
+		if limit > 0 && len(entries) > limit {
+			entries = entries[len(entries)-limit:]
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			for _, e := range entries {
+				if err := enc.Encode(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No audit entries found")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SEQ\tWHEN\tTYPE\tSESSION\tDETAILS")
+		for _, e := range entries {
+			fmt.Fprintf(
+				w,
+				"%d\t%s\t%s\t%s\t%s\n",
+				e.Seq,
+				time.UnixMilli(e.Time).Format(time.DateTime),
+				e.Type,
+				e.SessionID,
+				auditDetails(e),
+			)
+		}
+		return w.Flush()
+	},
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that the audit log wasn't tampered with",
+	Long: `Check the chain of hashes of the audit log, reporting the first entry that was
+modified, removed or inserted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := auditLogPath(cmd)
+		if err != nil {
+			return err
+		}
+		n, err := audit.Verify(path)
+		if err != nil {
+			return fmt.Errorf("audit log %s is corrupted: %w", path, err)
+		}
+		fmt.Printf("Audit log %s is intact (%d entries)\n", path, n)
+		return nil
+	},
+}
+
+// auditLogPath returns the path of the audit log, even when the log isn't
+// enabled anymore.
+func auditLogPath(cmd *cobra.Command) (string, error) {
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := config.Load(cwd, dataDir, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %v", err)
+	}
+	var path string
+	if cfg.Options.Audit != nil {
+		path = cfg.Options.Audit.Path
+	}
+	return audit.New(cfg.Options.DataDirectory, path).Path(), nil
+}
+
+// auditTool returns the tool of the tool calls and permission decisions.
+func auditTool(e audit.Entry) string {
+	var data struct {
+		Tool string `json:"tool"`
+	}
+	_ = json.Unmarshal(e.Data, &data)
+	return data.Tool
+}
+
+// auditDetails summarizes the data of an entry on one line.
+func auditDetails(e audit.Entry) string {
+	switch e.Type {
+	case audit.TypeToolCall:
+		var d audit.ToolCall
+		if json.Unmarshal(e.Data, &d) == nil {
+			status := "ok"
+			if d.IsError {
+				status = "error"
+			}
+			return fmt.Sprintf("%s %s (%s, %dms)", d.Tool, truncateDetails(d.Input), status, d.DurationMs)
+		}
+	case audit.TypePermission:
+		var d audit.Permission
+		if json.Unmarshal(e.Data, &d) == nil {
+			decision := "denied"
+			if d.Granted {
+				decision = "granted"
+			}
+			return fmt.Sprintf("%s %s %s %s by %s", decision, d.Tool, d.Action, d.Path, d.Reason)
+		}
+	case audit.TypeFileChange:
+		var d audit.FileChange
+		if json.Unmarshal(e.Data, &d) == nil {
+			return fmt.Sprintf("%s v%d", d.Path, d.Version)
+		}
+	case audit.TypeLLMRequest:
+		var d audit.LLMRequest
+		if json.Unmarshal(e.Data, &d) == nil {
+			return fmt.Sprintf("%s %s/%s (%d messages, %d tools)", d.Purpose, d.Provider, d.Model, d.Messages, d.Tools)
+		}
+	}
+	return string(e.Data)
+}
+
+// maxDetailsLength keeps each entry on one line of the terminal.
+const maxDetailsLength = 80
+
+func truncateDetails(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > maxDetailsLength {
+		return s[:maxDetailsLength] + "..."
+	}
+	return s
+}
+
+func init() {
+	auditCmd.Flags().String("session", "", "Only show the entries of the session (or of the sessions with this ID prefix)")
+	auditCmd.Flags().String("type", "", "Only show the entries of this type: tool_call, permission, file_change or llm_request")
+	auditCmd.Flags().String("tool", "", "Only show the tool calls and permission decisions of this tool")
+	auditCmd.Flags().Duration("since", 0, "Only show the entries of this last duration, e.g. 24h")
+	auditCmd.Flags().Int("limit", 0, "Only show the last entries")
+	auditCmd.Flags().Bool("json", false, "Print the entries as JSON lines")
+	auditCmd.AddCommand(auditVerifyCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/ledger"
+	"github.com/spf13/cobra"
+)
+
+var blameCmd = &cobra.Command{
+	Use:   "blame <file>",
+	Short: "Show which sessions changed a file",
+	Long: `List the sessions that changed a file, most recent first, with the prompt that
+led to each change. The changes are recorded in the history.jsonl file of the
+data directory, unless options.disable_ledger is set.`,
+	Example: `
+# Show the sessions that changed a file
+crush blame internal/cmd/root.go
+
+# Print the entries as JSON lines
+crush blame main.go --json
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The file is resolved before --cwd changes the directory.
+		path, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(cwd, dataDir, false)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %v", err)
+		}
+
+		entries, err := ledger.New(cfg.Options.DataDirectory, cfg.WorkingDir()).ForFile(path)
+		if err != nil {
+			return err
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			for _, e := range entries {
+				if err := enc.Encode(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if len(entries) == 0 {
+			fmt.Printf("No session changed %s\n", args[0])
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "WHEN\tSESSION\tTITLE\tSUMMARY")
+		for _, e := range entries {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\n",
+				time.Unix(e.Time, 0).Format(time.DateTime),
+				e.SessionID,
+				e.SessionTitle,
+				e.Summary,
+			)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	blameCmd.Flags().Bool("json", false, "Print the entries as JSON lines")
+	rootCmd.AddCommand(blameCmd)
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare --model <a> --model <b> [prompt...]",
+	Short: "Send a prompt to two models side by side",
+	Long: `Send the same prompt to two or more models at the same time and compare
+their answers, streamed in split panes when stdout is a terminal. The time
+to the first token, the total duration, the tokens and the cost of each
+answer are shown below it.
+
+With --session the prompt follows the conversation of a session, the text of
+its prompts and answers being sent without the tool calls. Each comparison
+is appended to comparisons.jsonl in the data directory, to back the choice
+of a model with the numbers of real prompts.`,
+	Example: `
+# Compare two models on a prompt
+crush compare -m anthropic/claude-sonnet-4 -m openai/gpt-4.1 "Explain the use of context in Go"
+
+# Continue the conversation of a session with both models
+crush compare -m claude-sonnet-4 -m gpt-4.1 --session 5f1c2a "Now add the tests"
+
+# Print the answers and their numbers as JSON
+crush compare -m claude-sonnet-4 -m gpt-4.1 --json "Write a haiku about Go"
+  `,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		models, _ := cmd.Flags().GetStringArray("model")
+		sessionID, _ := cmd.Flags().GetString("session")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		debug, _ := cmd.Flags().GetBool("debug")
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		if len(models) < 2 {
+			return errors.New("at least two models are needed, given with --model")
+		}
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Init(cwd, dataDir, debug)
+		if err != nil {
+			return err
+		}
+		if !cfg.IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+		sides := make([]agent.CompareSide, len(models))
+		for i, model := range models {
+			selected, err := evalModel(cfg, model)
+			if err != nil {
+				return err
+			}
+			sides[i], err = agent.NewCompareSide(cfg, selected.Provider, *cfg.GetModel(selected.Provider, selected.Model))
+			if err != nil {
+				return err
+			}
+		}
+
+		prompt := strings.Join(args, " ")
+		var history []message.Message
+		if sessionID != "" {
+			history, sessionID, err = compareHistory(ctx, cfg, sessionID)
+			if err != nil {
+				return err
+			}
+		}
+		messages := agent.CompareMessages(append(history, message.Message{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+		}))
+
+		var results []agent.CompareResult
+		if !asJSON && term.IsTerminal(os.Stdout.Fd()) && term.IsTerminal(os.Stdin.Fd()) {
+			results, err = runCompareView(ctx, cancel, sides, messages)
+			if err != nil {
+				return err
+			}
+		} else {
+			results = agent.Compare(ctx, sides, messages, func(agent.CompareEvent) {})
+		}
+
+		comparison := agent.Comparison{
+			Time:      time.Now().Unix(),
+			SessionID: sessionID,
+			Prompt:    prompt,
+			Results:   results,
+		}
+		if err := createDotCrushDir(cfg.Options.DataDirectory); err == nil {
+			err = agent.RecordComparison(cfg.Options.DataDirectory, comparison)
+			if err != nil {
+				slog.Warn("Failed to record the comparison", "error", err)
+			}
+		}
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(comparison)
+		}
+		return writeComparison(os.Stdout, results, !term.IsTerminal(os.Stdout.Fd()))
+	},
+}
+
+// compareHistory returns the messages of the session given by its ID or a
+// unique prefix of it, and its full ID.
+func compareHistory(ctx context.Context, cfg *config.Config, id string) ([]message.Message, string, error) {
+	if err := unlockDataDir(cfg); err != nil {
+		return nil, "", err
+	}
+	conn, err := db.Connect(ctx, cfg.Options.DataDirectory)
+	if err != nil {
+		return nil, "", err
+	}
+	defer conn.Close()
+	q := db.New(conn)
+	all, err := session.NewService(q, nil).List(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	sess, err := findSession(all, id)
+	if err != nil {
+		return nil, "", err
+	}
+	msgs, err := message.NewService(q).List(ctx, sess.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return msgs, sess.ID, nil
+}
+
+// writeComparison writes the answers, when they weren't shown in the split
+// panes, followed by a table of the numbers of each model.
+func writeComparison(w io.Writer, results []agent.CompareResult, answers bool) error {
+	if answers {
+		for _, res := range results {
+			fmt.Fprintf(w, "== %s ==\n\n", res.Model)
+			if res.Error != "" {
+				fmt.Fprintf(w, "Error: %s\n\n", res.Error)
+				continue
+			}
+			fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(res.Answer))
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODEL\tFIRST TOKEN\tDURATION\tPROMPT\tCOMPLETION\tCOST")
+	for _, res := range results {
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%d\t%d\t$%.4f\n",
+			res.Model,
+			formatMs(res.FirstTokenMs),
+			formatMs(res.DurationMs),
+			res.PromptTokens,
+			res.CompletionTokens,
+			res.Cost,
+		)
+	}
+	return tw.Flush()
+}
+
+func formatMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
+
+// runCompareView streams the answers in split panes until the user quits,
+// returning the results of the models.
+func runCompareView(ctx context.Context, cancel context.CancelFunc, sides []agent.CompareSide, messages []message.Message) ([]agent.CompareResult, error) {
+	view := newCompareView(sides, cancel)
+	prog := tea.NewProgram(view, tea.WithAltScreen(), tea.WithContext(ctx))
+	resultsc := make(chan []agent.CompareResult, 1)
+	go func() {
+		resultsc <- agent.Compare(ctx, sides, messages, func(event agent.CompareEvent) {
+			prog.Send(event)
+		})
+	}()
+	if _, err := prog.Run(); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, tea.ErrInterrupted) {
+		cancel()
+		return nil, err
+	}
+	// Quitting before the models are done cancels them.
+	cancel()
+	return <-resultsc, nil
+}
+
+func init() {
+	compareCmd.Flags().StringArrayP("model", "m", nil, "Model to compare, given as provider/model, at least two")
+	compareCmd.Flags().StringP("session", "s", "", "Send the prompt after the conversation of this session")
+	compareCmd.Flags().Bool("json", false, "Print the answers and their numbers as JSON")
+	rootCmd.AddCommand(compareCmd)
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// comparePane is the answer of a model in the compare view.
+type comparePane struct {
+	label  string
+	answer strings.Builder
+	result *agent.CompareResult
+}
+
+// compareView shows the answers of the models being compared side by side,
+// as they stream.
+type compareView struct {
+	panes  []*comparePane
+	cancel context.CancelFunc
+	width  int
+	height int
+}
+
+func newCompareView(sides []agent.CompareSide, cancel context.CancelFunc) *compareView {
+	v := &compareView{cancel: cancel}
+	for _, side := range sides {
+		v.panes = append(v.panes, &comparePane{label: side.Label})
+	}
+	return v
+}
+
+func (v *compareView) Init() tea.Cmd { return nil }
+
+func (v *compareView) done() bool {
+	for _, pane := range v.panes {
+		if pane.result == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Update implements tea.Model.
+func (v *compareView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		v.width, v.height = msg.Width, msg.Height
+	case tea.KeyPressMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc", "q":
+			v.cancel()
+			return v, tea.Quit
+		}
+	case agent.CompareEvent:
+		pane := v.panes[msg.Side]
+		if msg.Result != nil {
+			pane.result = msg.Result
+		} else {
+			pane.answer.WriteString(msg.Content)
+		}
+	}
+	return v, nil
+}
+
+func (v *compareView) View() string {
+	if v.width == 0 {
+		return ""
+	}
+	t := styles.CurrentTheme()
+	// The borders and padding of the panes take 4 columns and 2 lines, the
+	// title and numbers 4 lines and the help 1 line.
+	paneWidth := v.width / len(v.panes)
+	innerWidth := max(1, paneWidth-4)
+	innerHeight := max(1, v.height-2-4-1)
+
+	panes := make([]string, len(v.panes))
+	for i, pane := range v.panes {
+		title := t.S().Base.Bold(true).Foreground(t.Primary).Render(pane.label)
+		numbers := t.S().Subtle.Render("Waiting for the answer...")
+		answer := pane.answer.String()
+		if pane.result != nil {
+			numbers = t.S().Subtle.Render(compareNumbers(*pane.result))
+			if pane.result.Error != "" {
+				answer += "\n\n" + t.S().Base.Foreground(t.Error).Render("Error: "+pane.result.Error)
+			}
+		}
+		// Only the end of the answer fits in the pane as it streams.
+		lines := strings.Split(lipgloss.NewStyle().Width(innerWidth).Render(answer), "\n")
+		if len(lines) > innerHeight {
+			lines = lines[len(lines)-innerHeight:]
+		}
+		body := lipgloss.NewStyle().Width(innerWidth).Height(innerHeight).Render(strings.Join(lines, "\n"))
+		panes[i] = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(t.Border).
+			Padding(0, 1).
+			Width(paneWidth).
+			Render(lipgloss.JoinVertical(
+				lipgloss.Left,
+				ansi.Truncate(title, innerWidth, "…"),
+				"",
+				body,
+				"",
+				ansi.Truncate(numbers, innerWidth, "…"),
+			))
+	}
+
+	help := "esc cancel"
+	if v.done() {
+		help = "q quit"
+	}
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, panes...),
+		t.S().Muted.Render(help),
+	)
+}
+
+func compareNumbers(res agent.CompareResult) string {
+	return fmt.Sprintf(
+		"first token %s · %s · %d → %d tokens · $%.4f",
+		formatMs(res.FirstTokenMs),
+		formatMs(res.DurationMs),
+		res.PromptTokens,
+		res.CompletionTokens,
+		res.Cost,
+	)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long: `Print the effective configuration of the working directory, its secrets masked.
+
+The configuration is merged from layers, each one overriding the ones before it:
+
+  1. the built-in defaults
+  2. the user configuration: crush.json in the config directory, then in the
+     data directory
+  3. the project configuration: crush.json, then .crush.json in the working
+     directory
+  4. the environment variables: CRUSH_CONFIG_ followed by the key, its segments
+     separated by a double underscore, like CRUSH_CONFIG_OPTIONS__DEBUG=true
+  5. the flags: --set key.path=value, --debug and --data-dir
+
+Objects are merged key by key, arrays are concatenated and other values are
+replaced. The ${VAR} and $(command) references of the string values are
+expanded, except the commands of the project configuration.
+
+With --resolved every key is printed on its own line with the layer which set it.`,
+	Example: `
+# Print the effective configuration as JSON
+crush config show
+
+# Print every key with the file, variable or flag which set it
+crush config show --resolved
+
+# See what an override changes
+crush config show --resolved --set options.tui.compact_mode=true
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		debug, _ := cmd.Flags().GetBool("debug")
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(cwd, dataDir, debug)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %v", err)
+		}
+
+		if resolved, _ := cmd.Flags().GetBool("resolved"); !resolved {
+			effective, err := cfg.Effective()
+			if err != nil {
+				return err
+			}
+			bts, err := json.MarshalIndent(effective, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(bts))
+			return nil
+		}
+
+		values, err := cfg.Resolved()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		for _, v := range values {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, v.Source)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	configShowCmd.Flags().Bool("resolved", false, "Print every key with the layer which set it")
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
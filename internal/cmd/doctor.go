@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/doctor"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the providers, language servers and MCP servers",
+	Long: `Check that the configuration works before a task runs into a dead credential
+or a missing server. The large and small models are sent a tiny request each,
+checking the API key, the model and how long the provider takes to answer,
+and so is the cheapest model of the other enabled providers. The commands of
+the language servers and stdio MCP servers are looked up, and the HTTP and
+SSE MCP servers requested.
+
+Each failure comes with how to fix it, and the command fails when any check
+did. Rate limits, outages and slow answers are only reported as warnings.`,
+	Example: `
+# Check the configuration of the current project
+crush doctor
+
+# Print the checks as JSON
+crush doctor --json
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		debug, _ := cmd.Flags().GetBool("debug")
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		if err := confirmProjectTrust(cwd); err != nil {
+			return err
+		}
+		cfg, err := config.Init(cwd, dataDir, debug)
+		if err != nil {
+			return err
+		}
+		if !cfg.IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
+		checks := doctor.Run(cmd.Context(), cfg, doctor.Options{})
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(checks); err != nil {
+				return err
+			}
+		} else {
+			writeChecks(os.Stdout, checks)
+		}
+		if failed := doctor.Count(checks, doctor.StatusFailed); failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+		}
+		return nil
+	},
+}
+
+func writeChecks(w io.Writer, checks []doctor.Check) {
+	for _, c := range checks {
+		mark := styles.CheckIcon
+		switch c.Status {
+		case doctor.StatusWarning:
+			mark = styles.WarningIcon
+		case doctor.StatusFailed:
+			mark = styles.ErrorIcon
+		}
+		fmt.Fprintf(w, "%s %-8s %s: %s", mark, c.Kind, c.Name, c.Detail)
+		if c.LatencyMs > 0 && c.Kind != doctor.KindProvider {
+			fmt.Fprintf(w, " (%s)", formatMs(c.LatencyMs))
+		}
+		fmt.Fprintln(w)
+		if c.Fix != "" {
+			fmt.Fprintf(w, "  → %s\n", c.Fix)
+		}
+	}
+}
+
+func init() {
+	doctorCmd.Flags().Bool("json", false, "Print the checks as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/encryption"
+	"github.com/charmbracelet/x/term"
+)
+
+// unlockDataDir unlocks the session data when it is encrypted, asking the
+// passphrase in the terminal when needed. When encryption is enabled in the
+// configuration and the data isn't encrypted yet, the key is created and the
+// artifacts are encrypted; the database is encrypted when it is opened.
+func unlockDataDir(cfg *config.Config) error {
+	dataDir := cfg.Options.DataDirectory
+	if encryption.Enabled(dataDir) {
+		_, err := encryption.Unlock(dataDir, passphrasePrompt("Passphrase of the session data: "))
+		return err
+	}
+	opts := cfg.Options.Encryption
+	if opts == nil || !opts.Enabled {
+		return nil
+	}
+
+	source := cmp.Or(opts.Key, encryption.SourceKeychain)
+	var passphrase string
+	if source == encryption.SourcePassphrase {
+		passphrase = os.Getenv(encryption.PassphraseEnv)
+		if passphrase == "" {
+			prompt := passphrasePrompt("New passphrase of the session data: ")
+			if prompt == nil {
+				return encryption.ErrLocked
+			}
+			var err error
+			if passphrase, err = prompt(); err != nil {
+				return err
+			}
+			confirm, err := passphrasePrompt("Repeat the passphrase: ")()
+			if err != nil {
+				return err
+			}
+			if confirm != passphrase {
+				return errors.New("the passphrases don't match")
+			}
+		}
+	}
+	key, err := encryption.Setup(dataDir, source, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to set up encryption: %w", err)
+	}
+	if err := artifact.Encrypt(dataDir, key); err != nil {
+		return fmt.Errorf("failed to encrypt the artifacts: %w", err)
+	}
+	return nil
+}
+
+// passphrasePrompt returns a function reading a passphrase from the
+// terminal without echoing it, or nil when crush doesn't run in a terminal.
+func passphrasePrompt(label string) func() (string, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) || !term.IsTerminal(os.Stderr.Fd()) {
+		return nil
+	}
+	return func() (string, error) {
+		fmt.Fprint(os.Stderr, label)
+		passphrase, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(passphrase), "\r\n"), nil
+	}
+}
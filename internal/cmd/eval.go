@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/eval"
+	"github.com/spf13/cobra"
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval [dir]",
+	Short: "Run the eval cases of a directory against the configured models",
+	Long: `Run the YAML test cases of a directory, evals by default, and report which
+pass. Each case runs in a new temporary workspace holding its files, with all
+the permission requests approved, and checks the tools called, the final
+answer and the files left in the workspace.
+
+The cases run against the large model of the configuration, or against each
+model given with --model, to catch the regressions of a model swap or of a
+change of the system prompt.`,
+	Example: `
+# Run the cases of the evals directory
+crush eval
+
+# Compare two models on the same cases
+crush eval --model anthropic/claude-sonnet-4 --model openai/gpt-4.1
+
+# Only run the cases whose name matches, keeping their workspaces
+crush eval --run '^rename-' --keep testdata/evals
+
+# Print the report as JSON for the CI
+crush eval --json > eval-report.json
+  `,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "evals"
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		models, _ := cmd.Flags().GetStringArray("model")
+		filter, _ := cmd.Flags().GetString("run")
+		keep, _ := cmd.Flags().GetBool("keep")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		debug, _ := cmd.Flags().GetBool("debug")
+
+		cases, err := eval.Load(dir)
+		if err != nil {
+			return fmt.Errorf("failed to load the eval cases: %w", err)
+		}
+		if filter != "" {
+			re, err := regexp.Compile(filter)
+			if err != nil {
+				return fmt.Errorf("invalid --run pattern: %w", err)
+			}
+			var matching []eval.Case
+			for _, c := range cases {
+				if re.MatchString(c.Name) {
+					matching = append(matching, c)
+				}
+			}
+			cases = matching
+		}
+		if len(cases) == 0 {
+			return fmt.Errorf("no eval cases found in %s", dir)
+		}
+		if len(models) == 0 {
+			// An empty model runs the large model of the configuration.
+			models = []string{""}
+		}
+
+		var report eval.Report
+		for _, model := range models {
+			for _, c := range cases {
+				if !asJSON && model != "" {
+					fmt.Fprintf(os.Stderr, "Running %s with %s...\n", c.Name, model)
+				} else if !asJSON {
+					fmt.Fprintf(os.Stderr, "Running %s...\n", c.Name)
+				}
+				report.Add(runEvalCase(cmd.Context(), c, model, keep, debug))
+			}
+		}
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else if err := report.WriteText(os.Stdout); err != nil {
+			return err
+		}
+		if report.Failed > 0 {
+			return fmt.Errorf("%d of %d eval runs failed", report.Failed, len(report.Results))
+		}
+		return nil
+	},
+}
+
+// runEvalCase runs the case in a new workspace with the model, given as
+// provider/model or as the ID of a model of any provider.
+func runEvalCase(ctx context.Context, c eval.Case, model string, keep, debug bool) eval.Result {
+	result := eval.Result{Case: c.Name, Model: model}
+	workspace, err := os.MkdirTemp("", "crush-eval-")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if keep {
+		defer fmt.Fprintf(os.Stderr, "Kept the workspace of %s in %s\n", c.Name, workspace)
+	} else {
+		defer os.RemoveAll(workspace)
+	}
+	if err := c.Setup(workspace); err != nil {
+		result.Error = fmt.Sprintf("failed to write the files of the case: %v", err)
+		return result
+	}
+
+	evalApp, label, err := setupEvalApp(ctx, workspace, model, debug)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer evalApp.Shutdown()
+	result.Model = label
+
+	ctx, cancel := context.WithTimeout(ctx, c.RunTimeout())
+	defer cancel()
+	started := time.Now()
+	run, err := evalApp.RunPrompt(ctx, "Eval: "+c.Name, c.Prompt)
+	result.DurationMs = time.Since(started).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.PromptTokens = run.Usage.PromptTokens
+	result.CompletionTokens = run.Usage.CompletionTokens
+	result.Cost = run.Usage.Cost
+	if run.Status != app.RunStatusCompleted {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			run.Error = fmt.Sprintf("timed out after %s", c.RunTimeout())
+		}
+		result.Error = fmt.Sprintf("the run %s: %s", run.Status, run.Error)
+		return result
+	}
+
+	outcome := eval.Outcome{Answer: run.Answer, Workspace: workspace}
+	for _, msg := range run.Messages {
+		for _, call := range msg.ToolCalls {
+			outcome.Tools = append(outcome.Tools, call.Name)
+		}
+	}
+	result.Failures = c.Check(outcome)
+	return result
+}
+
+// setupEvalApp sets up the app in the workspace with the model as its large
+// model, returning the provider/model label of the model.
+func setupEvalApp(ctx context.Context, workspace, model string, debug bool) (*app.App, string, error) {
+	cfg, err := config.Init(workspace, "", debug)
+	if err != nil {
+		return nil, model, err
+	}
+	if !cfg.IsConfigured() {
+		return nil, model, fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+	}
+	if model != "" {
+		selected, err := evalModel(cfg, model)
+		if err != nil {
+			return nil, model, err
+		}
+		cfg.Models[config.SelectedModelTypeLarge] = selected
+	}
+	large := cfg.Models[config.SelectedModelTypeLarge]
+	label := large.Provider + "/" + large.Model
+
+	if err := createDotCrushDir(cfg.Options.DataDirectory); err != nil {
+		return nil, label, err
+	}
+	if err := unlockDataDir(cfg); err != nil {
+		return nil, label, err
+	}
+	conn, err := db.Connect(ctx, cfg.Options.DataDirectory)
+	if err != nil {
+		return nil, label, err
+	}
+	evalApp, err := app.New(ctx, conn, cfg)
+	if err != nil {
+		return nil, label, err
+	}
+	return evalApp, label, nil
+}
+
+// evalModel returns the selection of the model given as provider/model, or
+// as the ID of a model of any provider.
+func evalModel(cfg *config.Config, model string) (config.SelectedModel, error) {
+	// The IDs of the models of the aggregators hold a slash too.
+	providerID, modelID, _ := strings.Cut(model, "/")
+	for id, p := range cfg.Providers.Seq2() {
+		if p.Disable {
+			continue
+		}
+		for _, m := range p.Models {
+			if (id == providerID && m.ID == modelID) || m.ID == model {
+				return config.SelectedModel{Provider: id, Model: m.ID, MaxTokens: m.DefaultMaxTokens}, nil
+			}
+		}
+	}
+	return config.SelectedModel{}, fmt.Errorf("model %s not found in the configured providers", model)
+}
+
+func init() {
+	evalCmd.Flags().StringArrayP("model", "m", nil, "Run the cases against this model, given as provider/model, can be repeated")
+	evalCmd.Flags().String("run", "", "Only run the cases whose name matches this regular expression")
+	evalCmd.Flags().Bool("keep", false, "Keep the workspaces of the cases once they ran")
+	evalCmd.Flags().Bool("json", false, "Print the report as JSON")
+	rootCmd.AddCommand(evalCmd)
+}
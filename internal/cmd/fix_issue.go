@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/issues"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var fixIssueCmd = &cobra.Command{
+	Use:   "fix-issue <url>",
+	Short: "Fix a GitHub or GitLab issue and open a pull request",
+	Long: `Fetch a GitHub or GitLab issue, let the agent fix it in a new git worktree on
+its own branch, commit the changes and open a pull request with a generated
+description. The gh or glab CLI must be installed and authenticated.
+
+Fetching the issue, pushing the branch and opening the pull request are
+permission requests of the fix-issue tool, like the tool calls of the agent
+they are asked in the terminal unless allowed in permissions.allowed_tools,
+e.g. "fix-issue:push", or with --yolo. They are denied when crush doesn't run
+in a terminal.
+
+With --dry-run the changes are committed to the branch of the worktree, which
+is kept for review, but nothing is pushed.`,
+	Example: `
+# Fix a GitHub issue
+crush fix-issue https://github.com/owner/repo/issues/42
+
+# Fix a GitLab issue without pushing anything
+crush fix-issue --dry-run https://gitlab.com/group/project/-/issues/7
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		issue, err := issues.ParseURL(args[0])
+		if err != nil {
+			return err
+		}
+
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		cfg, err := config.Load(cwd, dataDir, false)
+		if err != nil {
+			return err
+		}
+		dataDir = cfg.Options.DataDirectory
+		if err := createDotCrushDir(dataDir); err != nil {
+			return err
+		}
+		wt, err := issues.AddWorktree(cmd.Context(), cwd, dataDir, issue)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Working on %s in %s\n", issue.Ref(), wt.Dir)
+
+		// The agent works in the worktree, with the sessions stored in the
+		// data directory of the repository.
+		_ = cmd.Flags().Set("cwd", wt.Dir)
+		_ = cmd.Flags().Set("data-dir", dataDir)
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		if !app.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+		go answerPermissionsInTerminal(ctx, app)
+
+		result, err := issues.Fix(ctx, app, issue, wt, issues.FixOptions{DryRun: dryRun})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The worktree %s is kept, remove it with git worktree remove\n", wt.Dir)
+			return err
+		}
+		switch {
+		case !result.Changed:
+			fmt.Println("The agent didn't change any file, no pull request was opened")
+			return wt.Remove(cmd.Context())
+		case dryRun:
+			fmt.Printf("Dry run: the changes are committed to the branch %s in %s\n\n", wt.Branch, wt.Dir)
+			fmt.Printf("Pull request title: %s\n\n%s", result.Title, result.Body)
+			return nil
+		}
+		fmt.Printf("Pull request: %s\n", result.PRURL)
+		return wt.Remove(cmd.Context())
+	},
+}
+
+// answerPermissionsInTerminal asks the user to answer the permission
+// requests in the terminal, they are denied when crush doesn't run in one.
+func answerPermissionsInTerminal(ctx context.Context, app *app.App) {
+	interactive := term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stderr.Fd())
+	stdin := bufio.NewReader(os.Stdin)
+	requests := app.Permissions.Subscribe(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-requests:
+			req := event.Payload
+			if !interactive {
+				fmt.Fprintf(os.Stderr, "Denied %s %s, allow it in permissions.allowed_tools to run without a terminal\n", req.ToolName, req.Action)
+				app.Permissions.Deny(req)
+				continue
+			}
+			answerPermission(app.Permissions, stdin, req)
+		}
+	}
+}
+
+func answerPermission(permissions permission.Service, stdin *bufio.Reader, req permission.PermissionRequest) {
+	fmt.Fprintf(os.Stderr, "\n%s wants to %s", req.ToolName, req.Action)
+	if req.Path != "" {
+		fmt.Fprintf(os.Stderr, " in %s", req.Path)
+	}
+	fmt.Fprintf(os.Stderr, "\n%s\n", req.Description)
+	fmt.Fprint(os.Stderr, "Allow? [y]es, [a]llow for the session, [N]o ")
+	answer, _ := stdin.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		permissions.Grant(req)
+	case "a":
+		permissions.GrantPersistent(req)
+	default:
+		permissions.Deny(req)
+	}
+}
+
+func init() {
+	fixIssueCmd.Flags().Bool("dry-run", false, "Commit the changes locally without pushing them nor opening a pull request")
+	fixIssueCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+
+	rootCmd.AddCommand(fixIssueCmd)
+}
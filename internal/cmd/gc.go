@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/retention"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Prune old sessions and compact the database",
+	Long: `Delete the sessions past the retention limits with their artifacts and trashed
+files, then compact the database to give the freed space back to the disk.
+
+The limits come from options.retention in the configuration, the flags
+override them. Crush also prunes on startup when limits are configured, but
+only gc compacts the database.`,
+	Example: `
+# Apply the configured retention limits and compact the database
+crush gc
+
+# Keep the 100 most recent sessions of the last 30 days
+crush gc --max-sessions 100 --max-age-days 30
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := shareConfig(cmd)
+		if err != nil {
+			return err
+		}
+		var policy retention.Policy
+		if p := cfg.RetentionPolicy(); p != nil {
+			policy = *p
+		}
+		if cmd.Flags().Changed("max-age-days") {
+			days, _ := cmd.Flags().GetInt("max-age-days")
+			policy.MaxAge = time.Duration(days) * 24 * time.Hour
+		}
+		if cmd.Flags().Changed("max-sessions") {
+			policy.MaxSessions, _ = cmd.Flags().GetInt("max-sessions")
+		}
+		if cmd.Flags().Changed("max-db-size-mb") {
+			mb, _ := cmd.Flags().GetInt("max-db-size-mb")
+			policy.MaxDBSize = int64(mb) << 20
+		}
+
+		if err := unlockDataDir(cfg); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		conn, err := db.Connect(ctx, cfg.Options.DataDirectory)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		report, err := retention.GC(ctx, conn, cfg.Options.DataDirectory, policy)
+		if err != nil {
+			return err
+		}
+		fmt.Printf(
+			"Deleted %d sessions, database %s -> %s\n",
+			report.Sessions,
+			humanize.Bytes(uint64(report.SizeBefore)),
+			humanize.Bytes(uint64(report.SizeAfter)),
+		)
+		return nil
+	},
+}
+
+func init() {
+	gcCmd.Flags().Int("max-age-days", 0, "Delete the sessions not updated for this many days")
+	gcCmd.Flags().Int("max-sessions", 0, "Keep at most this many sessions")
+	gcCmd.Flags().Int("max-db-size-mb", 0, "Delete the oldest sessions until the database holds less than this many megabytes")
+
+	rootCmd.AddCommand(gcCmd)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/importer"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>...",
+	Short: "Import conversations of other agents as sessions",
+	Long: `Import conversations of other agents as crush sessions, to resume them from
+the session list with their full history.
+
+Supported formats:
+  claude-code  Claude Code transcripts, the .jsonl files of ~/.claude/projects
+  openai       ChatGPT data exports (conversations.json) and conversations in
+               the chat completions format, with a messages list
+
+The format is detected unless --format is set. Reasoning, system messages and
+the conversations of sub-agents are left out. Use - to read from stdin.`,
+	Example: `
+# Import a Claude Code transcript
+crush import ~/.claude/projects/-home-me-project/5f1c2a.jsonl
+
+# Import all the conversations of a ChatGPT export
+crush import --format openai conversations.json
+  `,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		cfg, err := shareConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := createDotCrushDir(cfg.Options.DataDirectory); err != nil {
+			return err
+		}
+		if err := unlockDataDir(cfg); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		conn, err := db.Connect(ctx, cfg.Options.DataDirectory)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		q := db.New(conn)
+		sessions := session.NewService(q, nil)
+		messages := message.NewService(q)
+
+		for _, path := range args {
+			convs, err := readConversations(path, format)
+			if err != nil {
+				return fmt.Errorf("failed to import %s: %w", path, err)
+			}
+			for _, conv := range convs {
+				if len(conv.Messages) == 0 {
+					continue
+				}
+				sess, err := importer.Save(ctx, sessions, messages, conv)
+				if err != nil {
+					return fmt.Errorf("failed to import %s: %w", path, err)
+				}
+				fmt.Printf("Imported %s (%d messages) as session %s\n", sess.Title, len(conv.Messages), sess.ID)
+			}
+		}
+		return nil
+	},
+}
+
+func readConversations(path, format string) ([]importer.Conversation, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return importer.Parse(r, format)
+}
+
+func init() {
+	importCmd.Flags().String("format", "", "Format of the files: claude-code or openai, detected by default")
+
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/jobs"
+	"github.com/spf13/cobra"
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Manage scheduled jobs",
+	Long: `Manage jobs that run a prompt in non-interactive mode on a cron schedule.
+Jobs are stored per project and run by "crush jobs serve".`,
+	Example: `
+# Add a job that runs every Monday at 9:00
+crush jobs add "update deps weekly" --cron "0 9 * * 1" --max-cost 1.5
+
+# Add a job that opens a pull request with its changes
+crush jobs add "fix lint warnings" --name lint --cron @daily --open-pr
+
+# Add a job whose permission requests are answered from Slack
+crush jobs add "triage new issues" --cron "0 * * * *" --remote-approval
+
+# List the jobs
+crush jobs list
+
+# Run a job now
+crush jobs run lint
+
+# Run the jobs on schedule
+crush jobs serve
+  `,
+}
+
+var jobsAddCmd = &cobra.Command{
+	Use:   "add [prompt...]",
+	Short: "Add a scheduled job",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := jobsStore(cmd)
+		if err != nil {
+			return err
+		}
+
+		prompt := strings.Join(args, " ")
+		prompt, err = MaybePrependStdin(prompt)
+		if err != nil {
+			return err
+		}
+
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			name = jobs.Slug(prompt)
+		}
+		cron, _ := cmd.Flags().GetString("cron")
+		maxCost, _ := cmd.Flags().GetFloat64("max-cost")
+		maxTokens, _ := cmd.Flags().GetInt64("max-tokens")
+		timeout, _ := cmd.Flags().GetInt("timeout")
+		reportsDir, _ := cmd.Flags().GetString("reports-dir")
+		openPR, _ := cmd.Flags().GetBool("open-pr")
+		remoteApproval, _ := cmd.Flags().GetBool("remote-approval")
+
+		job := jobs.Job{
+			Name:           name,
+			Prompt:         prompt,
+			Cron:           cron,
+			MaxCost:        maxCost,
+			MaxTokens:      maxTokens,
+			Timeout:        timeout,
+			ReportsDir:     reportsDir,
+			OpenPR:         openPR,
+			RemoteApproval: remoteApproval,
+			CreatedAt:      time.Now(),
+		}
+		if err := store.Add(job); err != nil {
+			return err
+		}
+		next, _ := job.Next()
+		fmt.Printf("Added job %s, next run at %s\n", job.Name, next.Format(time.RFC1123))
+		return nil
+	},
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled jobs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := jobsStore(cmd)
+		if err != nil {
+			return err
+		}
+		list, err := store.List()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("No jobs")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSCHEDULE\tLAST RUN\tNEXT RUN\tPROMPT")
+		for _, job := range list {
+			lastRun := "never"
+			if !job.LastRun.IsZero() {
+				lastRun = job.LastRun.Format(time.DateTime)
+			}
+			nextRun := "-"
+			if next, err := job.Next(); err == nil && !next.IsZero() {
+				nextRun = next.Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", job.Name, job.Cron, lastRun, nextRun, job.Prompt)
+		}
+		return w.Flush()
+	},
+}
+
+var jobsRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a scheduled job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := jobsStore(cmd)
+		if err != nil {
+			return err
+		}
+		return store.Remove(args[0])
+	},
+}
+
+var jobsRunCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Run a job now",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := jobsStore(cmd)
+		if err != nil {
+			return err
+		}
+		job, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+		if !app.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
+		if job.RemoteApproval {
+			if err := startRemoteApproval(cmd, app); err != nil {
+				return err
+			}
+		}
+
+		if err := store.SetLastRun(job.Name, time.Now()); err != nil {
+			return err
+		}
+		report, err := jobs.Run(cmd.Context(), app, job)
+		if err != nil {
+			return err
+		}
+		printJobReport(report)
+		return nil
+	},
+}
+
+var jobsServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the scheduled jobs when they are due",
+	Long:  `Run the scheduled jobs of the project when they are due, one at a time, until interrupted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+		if !app.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
+		if app.Notifications.Approvals() != nil {
+			if err := startRemoteApproval(cmd, app); err != nil {
+				return err
+			}
+		}
+
+		store := jobs.NewStore(app.Config().Options.DataDirectory)
+		fmt.Println("Waiting for jobs to be due, press Ctrl+C to stop")
+		return jobs.Serve(cmd.Context(), app, store, printJobReport)
+	},
+}
+
+func init() {
+	jobsAddCmd.Flags().String("name", "", "Job name, defaults to a slug of the prompt")
+	jobsAddCmd.Flags().String("cron", "", "Cron schedule, e.g. \"0 9 * * 1\" or @daily")
+	jobsAddCmd.Flags().Float64("max-cost", 0, "Maximum cost of a run in USD")
+	jobsAddCmd.Flags().Int64("max-tokens", 0, "Maximum number of tokens of a run")
+	jobsAddCmd.Flags().Int("timeout", 0, "Maximum duration of a run in minutes")
+	jobsAddCmd.Flags().String("reports-dir", "", "Directory to write the run reports to")
+	jobsAddCmd.Flags().Bool("open-pr", false, "Open a pull request with the changes of each run")
+	jobsAddCmd.Flags().Bool("remote-approval", false, "Answer the permission requests of each run from the notifiers instead of approving them")
+	_ = jobsAddCmd.MarkFlagRequired("cron")
+
+	jobsCmd.AddCommand(jobsAddCmd, jobsListCmd, jobsRemoveCmd, jobsRunCmd, jobsServeCmd)
+	rootCmd.AddCommand(jobsCmd)
+}
+
+// jobsStore returns the job store of the project without setting up the
+// whole app.
+func jobsStore(cmd *cobra.Command) (*jobs.Store, error) {
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load(cwd, dataDir, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %v", err)
+	}
+	return jobs.NewStore(cfg.Options.DataDirectory), nil
+}
+
+// startRemoteApproval serves the remote approval pages until the command is
+// done.
+func startRemoteApproval(cmd *cobra.Command, app *app.App) error {
+	approvals := app.Notifications.Approvals()
+	if approvals == nil {
+		return fmt.Errorf("remote approval is not configured - please set options.remote_approval in the configuration")
+	}
+	return approvals.Start(cmd.Context())
+}
+
+func printJobReport(r jobs.Report) {
+	fmt.Printf("Job %s: %s", r.Job.Name, r.Status)
+	if r.Error != "" {
+		fmt.Printf(" (%s)", r.Error)
+	}
+	fmt.Printf(", cost $%.4f, %d tokens\n", r.Cost, r.Tokens)
+	if r.PRURL != "" {
+		fmt.Printf("Pull request: %s\n", r.PRURL)
+	}
+	if r.Path != "" {
+		fmt.Printf("Report: %s\n", r.Path)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/lsp/install"
+	"github.com/charmbracelet/crush/internal/share"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+	"github.com/tidwall/sjson"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Manage the language servers",
+}
+
+var lspSetupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Detect the languages of the project and set up their language servers",
+	Long: `Detect the languages of the project from the files at its root and set up
+their language servers: gopls, rust-analyzer, typescript-language-server and
+pyright. The servers that aren't installed are installed in the lsp directory
+of the crush data directory, then the lsp configuration is written to the
+crush.json file of the project, or to the global data config with --global.
+Servers already in the configuration are left as they are.`,
+	Example: `
+# Set up the language servers of the project, asking before each install
+crush lsp setup
+
+# Install and configure them without asking
+crush lsp setup --yes
+
+# Only show what would be set up
+crush lsp setup --dry-run
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yes, _ := cmd.Flags().GetBool("yes")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		global, _ := cmd.Flags().GetBool("global")
+
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(cwd, dataDir, false)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %v", err)
+		}
+
+		detected := install.Detect(cfg.WorkingDir())
+		if len(detected) == 0 {
+			fmt.Println("No language with a known language server was detected")
+			return nil
+		}
+
+		interactive := !yes && term.IsTerminal(os.Stdin.Fd())
+		if !yes && !dryRun && !interactive {
+			return fmt.Errorf("installing language servers needs a confirmation, use --yes to install them")
+		}
+		prompter := share.NewPrompter(os.Stdin, os.Stderr)
+
+		configPath := config.GlobalConfigData()
+		if !global {
+			configPath = projectConfigPath(cfg.WorkingDir())
+		}
+		dir := install.Dir()
+		for _, s := range detected {
+			if _, ok := cfg.LSP[s.Name]; ok {
+				fmt.Printf("%s: already configured\n", s.Name)
+				continue
+			}
+			path, installed := install.Find(s, dir)
+			if dryRun {
+				if installed {
+					fmt.Printf("%s: would configure %s for %s\n", s.Name, path, s.Language)
+				} else {
+					fmt.Printf("%s: would install and configure it for %s\n", s.Name, s.Language)
+				}
+				continue
+			}
+			if !installed {
+				if interactive && !prompter.Confirm(fmt.Sprintf("Install %s for %s?", s.Name, s.Language)) {
+					continue
+				}
+				fmt.Printf("%s: installing in %s\n", s.Name, fsext.PrettyPath(dir))
+				path, err = install.Install(cmd.Context(), s, dir)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", s.Name, err)
+					continue
+				}
+			}
+			if err := writeLSPConfig(configPath, s.Name, install.Config(s, path)); err != nil {
+				return err
+			}
+			fmt.Printf("%s: configured in %s\n", s.Name, fsext.PrettyPath(configPath))
+		}
+		return nil
+	},
+}
+
+// projectConfigPath returns the configuration file of the project, the
+// hidden one when it is the only one.
+func projectConfigPath(workingDir string) string {
+	path := filepath.Join(workingDir, "crush.json")
+	hidden := filepath.Join(workingDir, ".crush.json")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(hidden); err == nil {
+			return hidden
+		}
+	}
+	return path
+}
+
+func writeLSPConfig(path, name string, lsp config.LSPConfig) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		data = []byte("{}")
+	} else if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	updated, err := sjson.SetBytesOptions(data, "lsp."+name, lsp, &sjson.Options{Optimistic: true})
+	if err != nil {
+		return fmt.Errorf("failed to set the lsp configuration of %s: %w", name, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, updated, 0o600)
+}
+
+func init() {
+	lspSetupCmd.Flags().BoolP("yes", "y", false, "Install the missing servers without asking")
+	lspSetupCmd.Flags().Bool("dry-run", false, "Only show what would be installed and configured")
+	lspSetupCmd.Flags().Bool("global", false, "Write the configuration to the global data config instead of the project")
+	lspCmd.AddCommand(lspSetupCmd)
+	rootCmd.AddCommand(lspCmd)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/mcpserver"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Use crush with other MCP clients",
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the built-in tools over MCP",
+	Long: `Serve the built-in tools of crush, like edit, bash, grep and the LSP
+diagnostics, as an MCP server so other MCP clients can use them.
+Tools that need a permission are denied unless they are allowed in
+permissions.allowed_tools, the server runs with --yolo or remote approval
+is configured.`,
+	Example: `
+# Serve the tools over stdio, e.g. from the MCP configuration of an IDE
+crush mcp serve --cwd /path/to/project
+
+# Serve only some tools over SSE
+crush mcp serve --transport sse --address localhost:8765 --tools view,grep,diagnostics
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		transport, _ := cmd.Flags().GetString("transport")
+		address, _ := cmd.Flags().GetString("address")
+		baseURL, _ := cmd.Flags().GetString("base-url")
+		allowed, _ := cmd.Flags().GetStringSlice("tools")
+		if transport != "stdio" && transport != "sse" {
+			return fmt.Errorf("unknown transport %q, use stdio or sse", transport)
+		}
+
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		if app.Notifications.Approvals() != nil {
+			if err := startRemoteApproval(cmd, app); err != nil {
+				return err
+			}
+		}
+
+		srv, err := mcpserver.New(cmd.Context(), app, allowed)
+		if err != nil {
+			return err
+		}
+
+		if transport == "sse" {
+			if baseURL == "" {
+				baseURL = "http://" + address
+			}
+			fmt.Fprintf(os.Stderr, "Serving MCP over SSE at %s/sse\n", baseURL)
+			return srv.ServeSSE(cmd.Context(), address, baseURL)
+		}
+		// The standard output is the transport, nothing else may be
+		// written to it.
+		return srv.ServeStdio(cmd.Context(), os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	mcpServeCmd.Flags().String("transport", "stdio", "Transport to serve on: stdio or sse")
+	mcpServeCmd.Flags().String("address", "localhost:8765", "Address to listen on for the sse transport")
+	mcpServeCmd.Flags().String("base-url", "", "URL the sse server is reachable at, defaults to the address")
+	mcpServeCmd.Flags().StringSlice("tools", nil, "Only serve these tools")
+	mcpServeCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+
+	mcpCmd.AddCommand(mcpServeCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
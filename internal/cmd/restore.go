@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/trash"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [id...]",
+	Short: "Restore files deleted or overwritten by the agent",
+	Long: `Restore the files the agent deleted or overwrote, from the trash of their session.
+Without arguments, the files in the trash are listed.`,
+	Example: `
+# List the files in the trash
+crush restore
+
+# List the files in the trash of a session
+crush restore --session 0f1c2d3e-...
+
+# Restore a file where it was
+crush restore 1a2b3c4d
+
+# Restore a file over the one that replaced it
+crush restore 1a2b3c4d --force
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		trashBin, err := trashService(cmd)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			sessionID, _ := cmd.Flags().GetString("session")
+			return listTrash(trashBin, sessionID)
+		}
+
+		force, _ := cmd.Flags().GetBool("force")
+		var errs []error
+		for _, id := range args {
+			e, err := trashBin.Restore(id, force)
+			if errors.Is(err, trash.ErrExists) {
+				err = fmt.Errorf("%w, use --force to replace it", err)
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			fmt.Printf("Restored %s\n", fsext.PrettyPath(e.Path))
+		}
+		return errors.Join(errs...)
+	},
+}
+
+func listTrash(trashBin trash.Service, sessionID string) error {
+	entries, err := trashBin.List(sessionID)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("The trash is empty")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tWHEN\tREASON\tTOOL\tSIZE\tPATH")
+	for _, e := range entries {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.ID,
+			time.Unix(e.TrashedAt, 0).Format(time.DateTime),
+			e.Reason,
+			e.ToolName,
+			humanize.Bytes(uint64(e.Size)),
+			fsext.PrettyPath(e.Path),
+		)
+	}
+	return w.Flush()
+}
+
+func trashService(cmd *cobra.Command) (trash.Service, error) {
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load(cwd, dataDir, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %v", err)
+	}
+	return trash.NewService(cfg.Options.DataDirectory), nil
+}
+
+func init() {
+	restoreCmd.Flags().String("session", "", "Only list the files of this session")
+	restoreCmd.Flags().Bool("force", false, "Replace the files that exist where the restored files were")
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -2,17 +2,24 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/projects"
+	"github.com/charmbracelet/crush/internal/retention"
 	"github.com/charmbracelet/crush/internal/tui"
+	"github.com/charmbracelet/crush/internal/tui/components/chat"
+	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/crush/internal/version"
 	"github.com/charmbracelet/fang"
 	"github.com/charmbracelet/x/term"
@@ -23,9 +30,13 @@ func init() {
 	rootCmd.PersistentFlags().StringP("cwd", "c", "", "Current working directory")
 	rootCmd.PersistentFlags().StringP("data-dir", "D", "", "Custom crush data directory")
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Debug")
+	rootCmd.PersistentFlags().StringArray("set", nil, "Override a configuration key as key.path=value, the value parsed as JSON")
+	rootCmd.PersistentFlags().String("profile", "", "Permission profile deciding on the permissions first: "+strings.Join(permission.ProfileNames(), ", "))
 
 	rootCmd.Flags().BoolP("help", "h", false, "Help")
 	rootCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+	rootCmd.Flags().StringP("session", "s", "", "Open the session with this ID")
+	rootCmd.Flags().StringArrayP("workspace", "w", nil, "Also open the project in this directory as a workspace to switch to")
 
 	rootCmd.AddCommand(runCmd)
 }
@@ -55,33 +66,140 @@ crush -v
 # Run a single non-interactive prompt
 crush run "Explain the use of context in Go"
 
+# Run the prompt piped to stdin, printing the answer as plain text
+echo "Summarize the changes of this branch" | crush > summary.md
+
 # Run in dangerous mode (auto-accept all permissions)
 crush -y
+
+# Approve reads and edits in the repository but deny network access and
+# package installs, denying the other permissions without asking
+crush run --profile ci "Fix the failing tests"
+
+# Override configuration keys for this run
+crush --set options.tui.compact_mode=true --set options.debug=true
+
+# Open a session
+crush -s 5f1c2a3b-...
+
+# Also open other projects as workspaces, switched to with ctrl+x
+crush -w ../api -w ../web
   `,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		overrides, _ := cmd.Flags().GetStringArray("set")
+		return config.SetOverrides(overrides)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		app, err := setupApp(cmd)
+		// The TUI can't be drawn when the output is piped, the prompt piped
+		// to stdin is run like with crush run instead.
+		if !term.IsTerminal(os.Stdout.Fd()) {
+			err := runPrompt(cmd, "", app.RunOptions{Quiet: !term.IsTerminal(os.Stderr.Fd())})
+			if errors.Is(err, errNoPrompt) {
+				return fmt.Errorf("stdout is not a terminal, pipe a prompt to crush or use crush run: %w", err)
+			}
+			return err
+		}
+		sessionID, _ := cmd.Flags().GetString("session")
+		return runTUI(cmd, sessionID)
+	},
+}
+
+// runTUI runs the interactive mode, opening the session when its ID is
+// set. Switching to the workspace of another project restarts the TUI on the
+// app of that project, the workspaces left stay open until crush exits.
+func runTUI(cmd *cobra.Command, sessionID string) error {
+	app, err := setupApp(cmd)
+	if err != nil {
+		return err
+	}
+	open := newWorkspaces(cmd, app)
+	defer open.shutdown()
+	dirs, _ := cmd.Flags().GetStringArray("workspace")
+	if err := open.openAll(dirs, app); err != nil {
+		return err
+	}
+
+	ws := open.open[app.Config().Options.DataDirectory]
+	ws.sessionID = sessionID
+	var notice error
+	for {
+		model, err := runProgram(cmd, ws.app, ws.sessionID, notice)
 		if err != nil {
 			return err
 		}
-		defer app.Shutdown()
+		ws.sessionID = model.SessionID()
+		project, ok := model.SwitchWorkspace()
+		if !ok {
+			return nil
+		}
+		next, err := open.activate(project)
+		if err != nil {
+			// Stay in the current workspace and show why.
+			slog.Error("Failed to switch workspace", "error", err)
+			notice = err
+			if _, err := open.activate(projects.Project{
+				Path:    ws.app.Config().WorkingDir(),
+				DataDir: ws.app.Config().Options.DataDirectory,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if next.sessionID != "" {
+			if _, err := next.app.Sessions.Get(cmd.Context(), next.sessionID); err != nil {
+				next.sessionID = ""
+			}
+		}
+		ws, notice = next, nil
+	}
+}
+
+// switchableModel is the TUI model, telling which workspace to switch to
+// once it quits.
+type switchableModel interface {
+	SessionID() string
+	SwitchWorkspace() (projects.Project, bool)
+}
+
+// runProgram runs the TUI on the app until it quits, reporting the notice
+// when it isn't nil.
+func runProgram(cmd *cobra.Command, app *app.App, sessionID string, notice error) (switchableModel, error) {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
 
-		// Set up the TUI.
-		program := tea.NewProgram(
-			tui.New(app),
-			tea.WithAltScreen(),
-			tea.WithContext(cmd.Context()),
+	// Set up the TUI.
+	opts := []tea.ProgramOption{
+		tea.WithAltScreen(),
+		tea.WithContext(ctx),
+	}
+	if app.Config().Mouse() {
+		opts = append(opts,
 			tea.WithMouseCellMotion(),            // Use cell motion instead of all motion to reduce event flooding
 			tea.WithFilter(tui.MouseEventFilter), // Filter mouse events based on focus state
 		)
+	}
+	program := tea.NewProgram(tui.New(app), opts...)
 
-		go app.Subscribe(program)
-
-		if _, err := program.Run(); err != nil {
-			slog.Error("TUI run error", "error", err)
-			return fmt.Errorf("TUI error: %v", err)
+	if sessionID != "" {
+		sess, err := app.Sessions.Get(ctx, sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("session %s not found in %s", sessionID, app.Config().Options.DataDirectory)
 		}
-		return nil
-	},
+		// Send blocks until the program runs.
+		go program.Send(chat.SessionSelectedMsg(sess))
+	}
+	if notice != nil {
+		go program.Send(util.InfoMsg{Type: util.InfoTypeError, Msg: notice.Error()})
+	}
+
+	go app.Subscribe(ctx, program)
+
+	model, err := program.Run()
+	if err != nil {
+		slog.Error("TUI run error", "error", err)
+		return nil, fmt.Errorf("TUI error: %v", err)
+	}
+	return model.(switchableModel), nil
 }
 
 func Execute() {
@@ -100,6 +218,7 @@ func Execute() {
 func setupApp(cmd *cobra.Command) (*app.App, error) {
 	debug, _ := cmd.Flags().GetBool("debug")
 	yolo, _ := cmd.Flags().GetBool("yolo")
+	profile, _ := cmd.Flags().GetString("profile")
 	dataDir, _ := cmd.Flags().GetString("data-dir")
 	ctx := cmd.Context()
 
@@ -108,6 +227,10 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 		return nil, err
 	}
 
+	if err := confirmProjectTrust(cwd); err != nil {
+		return nil, err
+	}
+
 	cfg, err := config.Init(cwd, dataDir, debug)
 	if err != nil {
 		return nil, err
@@ -117,10 +240,18 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 		cfg.Permissions = &config.Permissions{}
 	}
 	cfg.Permissions.SkipRequests = yolo
+	cfg.Permissions.ProfileFlag = profile
 
 	if err := createDotCrushDir(cfg.Options.DataDirectory); err != nil {
 		return nil, err
 	}
+	if err := projects.Register(cfg.WorkingDir(), cfg.Options.DataDirectory); err != nil {
+		slog.Warn("Failed to record the project in the global index", "error", err)
+	}
+
+	if err := unlockDataDir(cfg); err != nil {
+		return nil, err
+	}
 
 	// Connect to DB; this will also run migrations.
 	conn, err := db.Connect(ctx, cfg.Options.DataDirectory)
@@ -128,6 +259,15 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 		return nil, err
 	}
 
+	if policy := cfg.RetentionPolicy(); policy != nil {
+		report, err := retention.Prune(ctx, conn, cfg.Options.DataDirectory, *policy)
+		if err != nil {
+			slog.Warn("Failed to prune old sessions", "error", err)
+		} else if report.Sessions > 0 {
+			slog.Info("Pruned old sessions", "count", report.Sessions)
+		}
+	}
+
 	appInstance, err := app.New(ctx, conn, cfg)
 	if err != nil {
 		slog.Error("Failed to create app instance", "error", err)
@@ -138,28 +278,37 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 }
 
 func MaybePrependStdin(prompt string) (string, error) {
+	bts, err := readPipedStdin()
+	if err != nil || len(bts) == 0 {
+		return prompt, err
+	}
+	return string(bts) + "\n\n" + prompt, nil
+}
+
+// readPipedStdin returns the data piped or redirected to stdin, nil when
+// stdin is a terminal.
+func readPipedStdin() ([]byte, error) {
 	if term.IsTerminal(os.Stdin.Fd()) {
-		return prompt, nil
+		return nil, nil
 	}
 	fi, err := os.Stdin.Stat()
 	if err != nil {
-		return prompt, err
+		return nil, err
 	}
-	if fi.Mode()&os.ModeNamedPipe == 0 {
-		return prompt, nil
+	if fi.Mode()&os.ModeNamedPipe == 0 && !fi.Mode().IsRegular() {
+		return nil, nil
 	}
-	bts, err := io.ReadAll(os.Stdin)
-	if err != nil {
-		return prompt, err
-	}
-	return string(bts) + "\n\n" + prompt, nil
+	return io.ReadAll(os.Stdin)
 }
 
 func ResolveCwd(cmd *cobra.Command) (string, error) {
 	cwd, _ := cmd.Flags().GetString("cwd")
 	if cwd != "" {
-		err := os.Chdir(cwd)
+		cwd, err := filepath.Abs(cwd)
 		if err != nil {
+			return "", fmt.Errorf("failed to resolve directory: %v", err)
+		}
+		if err := os.Chdir(cwd); err != nil {
 			return "", fmt.Errorf("failed to change directory: %v", err)
 		}
 		return cwd, nil
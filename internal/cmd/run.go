@@ -1,58 +1,192 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/document"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/x/term"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// errNoPrompt is returned when a run has no prompt.
+var errNoPrompt = errors.New("no prompt provided")
+
 var runCmd = &cobra.Command{
 	Use:   "run [prompt...]",
 	Short: "Run a single non-interactive prompt",
 	Long: `Run a single prompt in non-interactive mode and exit.
-The prompt can be provided as arguments or piped from stdin.`,
+The prompt can be provided as arguments or piped from stdin. Files can be
+attached with --file, and images piped from stdin are attached too.
+
+The answer is streamed as plain text, the spinner is only shown when stderr
+is a terminal. With --output jsonl the events of the run are streamed as JSON
+lines instead, for scripts and build logs.`,
 	Example: `
 # Run a simple prompt
 crush run Explain the use of context in Go
 
 # Pipe input from stdin
 echo "What is this code doing?" | crush run
+cat build.log | crush run "Why did this fail?"
+
+# Attach files, text files are sent as text and images as images
+crush run -f design.png -f spec.md "Implement this"
+
+# Attach a PDF, DOCX or XLSX document, converted to Markdown locally
+crush run -f report.pdf "Summarize the findings, citing the pages"
 
 # Run with quiet mode (no spinner)
 crush run -q "Generate a README for this project"
+
+# Print the messages, tool calls, diffs and token usage as JSON
+crush run --output json "Fix the failing tests"
+
+# Stream the text, tool calls and results of the run as JSON lines
+crush run --format jsonl "Fix the failing tests" | jq -r 'select(.type == "text") | .text'
+
+# Only approve the tools allowed by the configuration, denying the others
+crush run --allowed-tools-only "Review the changes of this branch"
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		quiet, _ := cmd.Flags().GetBool("quiet")
-
-		app, err := setupApp(cmd)
-		if err != nil {
-			return err
+		output, _ := cmd.Flags().GetString("output")
+		allowedToolsOnly, _ := cmd.Flags().GetBool("allowed-tools-only")
+		files, _ := cmd.Flags().GetStringArray("file")
+		if output != "text" && output != "json" && output != "jsonl" {
+			return fmt.Errorf("invalid output format %q, expected text, json or jsonl", output)
 		}
-		defer app.Shutdown()
-
-		if !app.Config().IsConfigured() {
-			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		opts := app.RunOptions{
+			// The frames of the spinner would garble the logs.
+			Quiet:            quiet || !term.IsTerminal(os.Stderr.Fd()),
+			JSON:             output == "json",
+			JSONL:            output == "jsonl",
+			AllowedToolsOnly: allowedToolsOnly,
+		}
+		for _, path := range files {
+			attachment, err := readAttachment(path)
+			if err != nil {
+				return err
+			}
+			opts.Attachments = append(opts.Attachments, attachment)
 		}
+		return runPrompt(cmd, strings.Join(args, " "), opts)
+	},
+}
+
+// runPrompt runs the prompt non-interactively, with the text or data piped
+// to stdin.
+func runPrompt(cmd *cobra.Command, prompt string, opts app.RunOptions) error {
+	app, err := setupApp(cmd)
+	if err != nil {
+		return err
+	}
+	defer app.Shutdown()
 
-		prompt := strings.Join(args, " ")
+	if !app.Config().IsConfigured() {
+		return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+	}
 
-		prompt, err = MaybePrependStdin(prompt)
+	// The documents are converted once the context window of the model is
+	// known.
+	for i, attachment := range opts.Attachments {
+		if !document.Is(attachment.FilePath) {
+			continue
+		}
+		var contextWindow int64
+		if model := app.Config().LargeModel(); model != nil {
+			contextWindow = model.ContextWindow
+		}
+		converted, err := document.Attachment(attachment.FilePath, attachment.Content, document.MaxSize(contextWindow))
 		if err != nil {
-			slog.Error("Failed to read from stdin", "error", err)
 			return err
 		}
+		opts.Attachments[i] = converted
+	}
 
-		if prompt == "" {
-			return fmt.Errorf("no prompt provided")
+	// Piped text is prepended to the prompt, other data like images is
+	// attached.
+	piped, err := readPipedStdin()
+	if err != nil {
+		slog.Error("Failed to read from stdin", "error", err)
+		return err
+	}
+	if len(piped) > 0 {
+		mimeType := detectMIMEType(piped)
+		if message.IsTextMIMEType(mimeType) {
+			prompt = string(piped) + "\n\n" + prompt
+		} else {
+			opts.Attachments = append(opts.Attachments, message.Attachment{
+				FilePath: "stdin",
+				FileName: "stdin",
+				MimeType: mimeType,
+				Content:  piped,
+			})
 		}
+	}
 
-		// Run non-interactive flow using the App method
-		return app.RunNonInteractive(cmd.Context(), prompt, quiet)
-	},
+	if strings.TrimSpace(prompt) == "" {
+		return errNoPrompt
+	}
+
+	// Run non-interactive flow using the App method
+	return app.RunNonInteractive(cmd.Context(), prompt, opts)
 }
 
 func init() {
 	runCmd.Flags().BoolP("quiet", "q", false, "Hide spinner")
+	runCmd.Flags().StringP("output", "o", "text", "Output format: text streams the answer, json prints the whole run once it ends, jsonl streams its events as JSON lines")
+	runCmd.Flags().StringArrayP("file", "f", nil, "Attach a file to the prompt, can be repeated")
+	runCmd.Flags().Bool("allowed-tools-only", false, "Deny the permission requests of the tools not allowed by the configuration instead of approving them")
+	// --format is accepted for --output.
+	runCmd.Flags().SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "format" {
+			name = "output"
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
+// maxAttachmentSize is the size limit of the attachments, like in the TUI.
+const maxAttachmentSize = 5 * 1024 * 1024
+
+// readAttachment reads a file attached with --file. Text files, images and
+// documents are supported, the documents being converted by runPrompt.
+func readAttachment(path string) (message.Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return message.Attachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if info.IsDir() {
+		return message.Attachment{}, fmt.Errorf("attachment %s is a directory", path)
+	}
+	if info.Size() > maxAttachmentSize {
+		return message.Attachment{}, fmt.Errorf("attachment %s is larger than 5MB", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return message.Attachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	mimeType := detectMIMEType(content)
+	if !document.Is(path) && !message.IsTextMIMEType(mimeType) && !strings.HasPrefix(mimeType, "image/") {
+		return message.Attachment{}, fmt.Errorf("attachment %s is neither text, an image nor a PDF, DOCX or XLSX document (%s)", path, mimeType)
+	}
+	return message.Attachment{
+		FilePath: path,
+		FileName: filepath.Base(path),
+		MimeType: mimeType,
+		Content:  content,
+	}, nil
+}
+
+func detectMIMEType(content []byte) string {
+	return http.DetectContentType(content[:min(512, len(content))])
 }
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/apiserver"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a local HTTP API to drive crush",
+	Long: `Serve a local HTTP API for editors, bots and dashboards to drive crush: create
+sessions, send prompts, stream the events of the sessions as server-sent
+events, answer the permission requests and fetch the diffs of the sessions.
+
+Every request must send the token as a bearer token. A random token is
+generated unless --token or CRUSH_API_TOKEN is set.
+
+Endpoints:
+  GET  /sessions                  List the sessions
+  POST /sessions                  Create a session: {"title": "..."}
+  GET  /sessions/{id}             Get a session
+  GET  /sessions/{id}/messages    List the messages of a session
+  POST /sessions/{id}/prompts     Send a prompt: {"prompt": "..."}
+  POST /sessions/{id}/cancel      Cancel the prompt being processed
+  GET  /sessions/{id}/diffs       Get the changes made in a session
+  GET  /permissions               List the pending permission requests
+  POST /permissions/{id}          Answer a permission request: {"decision": "allow|allow_session|deny"}
+  GET  /events                    Stream the events, ?session_id= filters them`,
+	Example: `
+# Serve the API on the default address
+crush serve
+
+# Create a session, send a prompt and follow its events
+curl -H "Authorization: Bearer $TOKEN" -X POST localhost:7777/sessions
+curl -H "Authorization: Bearer $TOKEN" -d '{"prompt": "Fix the tests"}' localhost:7777/sessions/$ID/prompts
+curl -N -H "Authorization: Bearer $TOKEN" "localhost:7777/events?session_id=$ID"
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		address, _ := cmd.Flags().GetString("address")
+		token, _ := cmd.Flags().GetString("token")
+		if token == "" {
+			token = os.Getenv("CRUSH_API_TOKEN")
+		}
+
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		if !app.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
+		srv, err := apiserver.New(app, token)
+		if err != nil {
+			return err
+		}
+		ln, err := net.Listen("tcp", address)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Serving the API at http://%s\n", ln.Addr())
+		if token == "" {
+			fmt.Fprintf(os.Stderr, "Token: %s\n", srv.Token())
+		}
+		return srv.Serve(cmd.Context(), ln)
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("address", "localhost:7777", "Address to listen on")
+	serveCmd.Flags().String("token", "", "Token the clients must send, defaults to CRUSH_API_TOKEN or a random token")
+	serveCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+
+	rootCmd.AddCommand(serveCmd)
+}
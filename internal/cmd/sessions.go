@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/projects"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List the sessions of the project or of all the projects",
+	Long: `List the sessions of the project, or with --all the sessions of every project
+crush ran in, with their project, model, cost and last activity. The projects
+are recorded in a global index when crush starts in them.`,
+	Example: `
+# List the sessions of the project
+crush sessions
+
+# List the sessions of all the projects as JSON
+crush sessions --all --json
+
+# Open a session of any project
+crush sessions open 5f1c2a
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		var sessions []projects.Session
+		var err error
+		if all {
+			sessions, err = projects.AllSessions(cmd.Context())
+		} else {
+			var cwd string
+			if cwd, err = ResolveCwd(cmd); err != nil {
+				return err
+			}
+			cfg, err := shareConfig(cmd)
+			if err != nil {
+				return err
+			}
+			sessions, err = projects.Sessions(cmd.Context(), projects.Project{Path: cwd, DataDir: cfg.Options.DataDirectory})
+		}
+		if err != nil {
+			return err
+		}
+		if limit > 0 && len(sessions) > limit {
+			sessions = sessions[:limit]
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			for _, s := range sessions {
+				if err := enc.Encode(sessionJSON(s)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if len(sessions) == 0 {
+			fmt.Println("No sessions found")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tLAST ACTIVITY\tPROJECT\tMODEL\tCOST\tTITLE")
+		for _, s := range sessions {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\t$%.4f\t%s\n",
+				s.ID[:min(8, len(s.ID))],
+				time.Unix(s.UpdatedAt, 0).Format(time.DateTime),
+				s.Project.Path,
+				s.Model,
+				s.Cost,
+				s.Title,
+			)
+		}
+		return w.Flush()
+	},
+}
+
+var sessionsOpenCmd = &cobra.Command{
+	Use:   "open <id>",
+	Short: "Open a session of any project",
+	Long:  `Open a session of any project recorded in the global index, by its ID or a unique prefix of it, in its project directory.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, err := projects.AllSessions(cmd.Context())
+		if err != nil {
+			return err
+		}
+		byID := make(map[string]projects.Session, len(all))
+		list := make([]session.Session, 0, len(all))
+		for _, s := range all {
+			byID[s.ID] = s
+			list = append(list, s.Session)
+		}
+		found, err := findSession(list, args[0])
+		if err != nil {
+			return err
+		}
+		s := byID[found.ID]
+
+		_ = cmd.Flags().Set("cwd", s.Project.Path)
+		_ = cmd.Flags().Set("data-dir", s.Project.DataDir)
+		return runTUI(cmd, s.ID)
+	},
+}
+
+type sessionInfo struct {
+	ID           string  `json:"id"`
+	Title        string  `json:"title"`
+	Project      string  `json:"project"`
+	DataDir      string  `json:"data_dir"`
+	Model        string  `json:"model,omitempty"`
+	Cost         float64 `json:"cost"`
+	MessageCount int64   `json:"message_count"`
+	UpdatedAt    string  `json:"updated_at"`
+}
+
+func sessionJSON(s projects.Session) sessionInfo {
+	return sessionInfo{
+		ID:           s.ID,
+		Title:        strings.TrimSpace(s.Title),
+		Project:      s.Project.Path,
+		DataDir:      s.Project.DataDir,
+		Model:        s.Model,
+		Cost:         s.Cost,
+		MessageCount: s.MessageCount,
+		UpdatedAt:    time.Unix(s.UpdatedAt, 0).UTC().Format(time.RFC3339),
+	}
+}
+
+func init() {
+	sessionsCmd.Flags().Bool("all", false, "List the sessions of all the projects")
+	sessionsCmd.Flags().Bool("json", false, "Print the sessions as JSON lines")
+	sessionsCmd.Flags().Int("limit", 0, "Only list the most recent sessions")
+	sessionsOpenCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+
+	sessionsCmd.AddCommand(sessionsOpenCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
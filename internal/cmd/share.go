@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/share"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share",
+	Short: "Share a session, custom command or agent",
+	Long: `Upload a session transcript, a custom command or an agent definition as a
+secret GitHub gist, with the GitHub CLI, or to the paste service set in
+options.share, and print the link.
+The content is scanned for secrets like API keys and tokens first and each
+one is reviewed before anything is uploaded. Paths in the home directory
+are always shortened to ~.`,
+	Example: `
+# Share the latest session
+crush share session
+
+# Share a session by ID, redacting every secret found without asking
+crush share session 5f1c2a --yes
+
+# Share the latest session with the reasoning of the model
+crush share session --thinking
+
+# Share a custom command
+crush share command user:review
+
+# Print what would be shared without uploading it
+crush share agent coder --dry-run
+  `,
+}
+
+var shareSessionCmd = &cobra.Command{
+	Use:   "session [id]",
+	Short: "Share a session transcript, the latest one by default",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := shareConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if err := unlockDataDir(cfg); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		conn, err := db.Connect(ctx, cfg.Options.DataDirectory)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		q := db.New(conn)
+		sessions := session.NewService(q, nil)
+		messages := message.NewService(q)
+
+		all, err := sessions.List(ctx)
+		if err != nil {
+			return err
+		}
+		if len(all) == 0 {
+			return fmt.Errorf("no sessions found in %s", cfg.Options.DataDirectory)
+		}
+		sess := all[0]
+		if len(args) == 1 {
+			sess, err = findSession(all, args[0])
+			if err != nil {
+				return err
+			}
+		}
+		msgs, err := messages.List(ctx, sess.ID)
+		if err != nil {
+			return err
+		}
+
+		thinking, _ := cmd.Flags().GetBool("thinking")
+		return shareDocument(cmd, cfg, share.Document{
+			Filename:    "crush-session.md",
+			Description: "Crush session: " + sess.Title,
+			Content:     share.Transcript(sess, msgs, share.TranscriptOptions{Thinking: thinking}),
+		})
+	},
+}
+
+var shareCommandCmd = &cobra.Command{
+	Use:   "command <id>",
+	Short: "Share a custom command",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := shareConfig(cmd)
+		if err != nil {
+			return err
+		}
+		path, err := commands.CustomCommandPath(cfg, args[0])
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		name := args[0][strings.LastIndex(args[0], ":")+1:]
+		return shareDocument(cmd, cfg, share.Document{
+			Filename:    name + ".md",
+			Description: "Crush custom command " + args[0],
+			Content:     string(content),
+		})
+	},
+}
+
+var shareAgentCmd = &cobra.Command{
+	Use:   "agent <id>",
+	Short: "Share an agent definition",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := shareConfig(cmd)
+		if err != nil {
+			return err
+		}
+		agent, ok := cfg.Agents[args[0]]
+		if !ok {
+			return fmt.Errorf("agent %q not found", args[0])
+		}
+		content, err := json.MarshalIndent(agent, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		return shareDocument(cmd, cfg, share.Document{
+			Filename:    args[0] + ".json",
+			Description: "Crush agent " + agent.Name,
+			Content:     string(content) + "\n",
+		})
+	},
+}
+
+func init() {
+	shareCmd.PersistentFlags().BoolP("yes", "y", false, "Redact every secret found and upload without asking")
+	shareCmd.PersistentFlags().Bool("dry-run", false, "Print the sanitized content instead of uploading it")
+	shareCmd.PersistentFlags().Bool("public", false, "Create a public gist")
+
+	shareSessionCmd.Flags().Bool("thinking", false, "Include the reasoning of the model in the transcript")
+
+	shareCmd.AddCommand(shareSessionCmd, shareCommandCmd, shareAgentCmd)
+	rootCmd.AddCommand(shareCmd)
+}
+
+func shareConfig(cmd *cobra.Command) (*config.Config, error) {
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load(cwd, dataDir, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %v", err)
+	}
+	return cfg, nil
+}
+
+// findSession finds a session by its ID or a unique prefix of it.
+func findSession(sessions []session.Session, id string) (session.Session, error) {
+	var found []session.Session
+	for _, s := range sessions {
+		if s.ID == id {
+			return s, nil
+		}
+		if strings.HasPrefix(s.ID, id) {
+			found = append(found, s)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return session.Session{}, fmt.Errorf("session %q not found", id)
+	case 1:
+		return found[0], nil
+	default:
+		return session.Session{}, fmt.Errorf("session prefix %q is ambiguous, %d sessions match", id, len(found))
+	}
+}
+
+// shareDocument sanitizes the document, interactively unless --yes is set,
+// and uploads it.
+func shareDocument(cmd *cobra.Command, cfg *config.Config, doc share.Document) error {
+	yes, _ := cmd.Flags().GetBool("yes")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	public, _ := cmd.Flags().GetBool("public")
+
+	if home, err := os.UserHomeDir(); err == nil {
+		doc.Content = share.ReplaceHome(doc.Content, home)
+	}
+
+	interactive := !yes && term.IsTerminal(os.Stdin.Fd())
+	if !yes && !dryRun && !interactive {
+		return fmt.Errorf("the redaction review needs a terminal, use --yes to redact every secret found")
+	}
+	prompter := share.NewPrompter(os.Stdin, os.Stderr)
+
+	findings := share.Scan(doc.Content)
+	redact := findings
+	if interactive && len(findings) > 0 {
+		fmt.Fprintf(os.Stderr, "Found %d possible secrets.\n", len(findings))
+		var err error
+		redact, err = prompter.Review(doc.Content, findings)
+		if err != nil {
+			return err
+		}
+	}
+	doc.Content = share.Redact(doc.Content, redact)
+
+	if dryRun {
+		fmt.Print(doc.Content)
+		return nil
+	}
+
+	opts := cfg.Options.Share
+	if public {
+		if opts == nil {
+			opts = &config.ShareOptions{}
+		}
+		opts.Public = true
+	}
+	uploader, err := share.NewUploader(opts)
+	if err != nil {
+		return err
+	}
+	if interactive {
+		fmt.Fprintf(os.Stderr, "%d of %d secrets redacted.\n", len(redact), len(findings))
+		if !prompter.Confirm(fmt.Sprintf("Upload %s (%d bytes)?", doc.Filename, len(doc.Content))) {
+			return fmt.Errorf("share aborted")
+		}
+	}
+
+	url, err := uploader.Upload(cmd.Context(), doc)
+	if err != nil {
+		return err
+	}
+	fmt.Println(url)
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var trustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Trust the project configuration of the working directory",
+	Long: `Trust the security-relevant fields of the crush.json and .crush.json files of the
+working directory: the MCP and LSP servers, the databases, the permissions, the
+notifiers, the editor command, the context paths, the egress policy, the share,
+remote approval, audit, redaction and dataset options and the provider URLs,
+API keys, API key commands, headers and system prompt prefixes.
+
+Until they are trusted these fields are ignored. Crush asks to trust them when
+it starts in a terminal, this command trusts them in scripts and CI.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		untrusted, err := config.UntrustedProjectConfigs(cwd)
+		if err != nil {
+			return err
+		}
+		if len(untrusted) == 0 {
+			fmt.Println("The project configuration is already trusted")
+			return nil
+		}
+		printUntrustedConfigs(os.Stdout, untrusted)
+		if err := config.TrustProjectConfigs(untrusted); err != nil {
+			return fmt.Errorf("failed to trust the project configuration: %w", err)
+		}
+		fmt.Println("The project configuration is now trusted")
+		return nil
+	},
+}
+
+// confirmProjectTrust asks the user to trust the project configurations
+// which are new or changed. Their security-relevant fields are ignored when
+// the user declines, or when crush doesn't run in a terminal.
+func confirmProjectTrust(cwd string) error {
+	untrusted, err := config.UntrustedProjectConfigs(cwd)
+	if err != nil || len(untrusted) == 0 {
+		return err
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) || !term.IsTerminal(os.Stderr.Fd()) {
+		fmt.Fprintln(os.Stderr, "Ignoring the untrusted fields of the project configuration, run crush trust to apply them.")
+		return nil
+	}
+
+	printUntrustedConfigs(os.Stderr, untrusted)
+	fmt.Fprint(os.Stderr, "Trust this configuration? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return config.TrustProjectConfigs(untrusted)
+	}
+	fmt.Fprintln(os.Stderr, "Ignoring the untrusted fields of the project configuration.")
+	return nil
+}
+
+func printUntrustedConfigs(w io.Writer, untrusted []config.UntrustedConfig) {
+	for _, u := range untrusted {
+		if u.Trusted == "" {
+			fmt.Fprintf(w, "%s wasn't trusted yet. It sets:\n\n", u.Path)
+		} else {
+			fmt.Fprintf(w, "%s changed since it was trusted:\n\n", u.Path)
+		}
+		d, _, _ := diff.GenerateDiff(u.Trusted, u.Current, u.Path)
+		fmt.Fprintln(w, d)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(trustCmd)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/projects"
+	"github.com/spf13/cobra"
+)
+
+// workspace is a project open in the process, with its own sessions, LSP
+// servers and configuration.
+type workspace struct {
+	app *app.App
+	// sessionID is the session selected when the workspace was left.
+	sessionID string
+}
+
+// workspaces holds the workspaces open in the process. One is active at a
+// time: the configuration and the working directory of the process are
+// global, so the others stay idle until they are switched to.
+type workspaces struct {
+	cmd  *cobra.Command
+	open map[string]*workspace // by data directory
+}
+
+func newWorkspaces(cmd *cobra.Command, first *app.App) *workspaces {
+	w := &workspaces{cmd: cmd, open: make(map[string]*workspace)}
+	w.open[first.Config().Options.DataDirectory] = &workspace{app: first}
+	return w
+}
+
+// activate makes the workspace of the project active, opening it when it
+// isn't open yet. An empty data directory stands for the default one of the
+// project.
+func (w *workspaces) activate(project projects.Project) (*workspace, error) {
+	if ws, ok := w.open[project.DataDir]; ok && project.DataDir != "" {
+		if err := os.Chdir(project.Path); err != nil {
+			return nil, fmt.Errorf("failed to change directory: %v", err)
+		}
+		config.Set(ws.app.Config())
+		return ws, nil
+	}
+
+	_ = w.cmd.Flags().Set("cwd", project.Path)
+	_ = w.cmd.Flags().Set("data-dir", project.DataDir)
+	a, err := setupApp(w.cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the workspace of %s: %w", project.Path, err)
+	}
+	ws := &workspace{app: a}
+	w.open[a.Config().Options.DataDirectory] = ws
+	return ws, nil
+}
+
+// openAll opens the workspaces of the directories, then activates the
+// workspace of the app again.
+func (w *workspaces) openAll(dirs []string, current *app.App) error {
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		if _, err := w.activate(projects.Project{Path: abs}); err != nil {
+			return err
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	_, err := w.activate(projects.Project{
+		Path:    current.Config().WorkingDir(),
+		DataDir: current.Config().Options.DataDirectory,
+	})
+	return err
+}
+
+func (w *workspaces) shutdown() {
+	for _, ws := range w.open {
+		ws.app.Shutdown()
+	}
+}
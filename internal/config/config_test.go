@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowTool(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "crush.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"permissions":{"allowed_tools":["view"]}}`), 0o600))
+	// The rules of the project aren't saved in the global configuration.
+	c := &Config{Permissions: &Permissions{AllowedTools: []string{"view", "ls"}}, dataConfigDir: path}
+
+	require.NoError(t, c.AllowTool("bash:execute"))
+	require.NoError(t, c.AllowTool("bash:execute"))
+	require.Equal(t, []string{"view", "ls", "bash:execute"}, c.Permissions.AllowedTools)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"permissions":{"allowed_tools":["view","bash:execute"]}}`, string(data))
+}
+
+func TestShellOptionsResolvedEnv(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env.local"), []byte("TOKEN=from-file\nREGION=eu\n"), 0o600))
+	opts := &ShellOptions{
+		Env:     map[string]string{"TOKEN": "$(echo secret)", "STAGE": "dev"},
+		EnvFile: ".env.local",
+	}
+	env, err := opts.ResolvedEnv(dir)
+	require.NoError(t, err)
+	// Env takes precedence over the env file.
+	require.Equal(t, []string{"REGION=eu", "STAGE=dev", "TOKEN=secret"}, env)
+
+	opts.EnvFile = ".env.missing"
+	_, err = opts.ResolvedEnv(dir)
+	require.ErrorContains(t, err, "reading env file")
+}
+
+func TestLSPsHasEnabled(t *testing.T) {
+	t.Parallel()
+
+	require.False(t, LSPs{}.HasEnabled())
+	require.False(t, LSPs{"gopls": {Command: "gopls", Disabled: true}}.HasEnabled())
+	require.True(t, LSPs{"gopls": {Command: "gopls", Disabled: true}, "pyright": {Command: "pyright"}}.HasEnabled())
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+// apiKeyCommandTimeout bounds the credential helpers, which may wait for
+// the user to log in.
+const apiKeyCommandTimeout = 2 * time.Minute
+
+// apiKeys caches the keys printed by the api_key_command of the providers,
+// shared by the agents using the same provider. apiKeysMu also makes sure a
+// helper doesn't run several times at once.
+var (
+	apiKeysMu sync.Mutex
+	apiKeys   = map[string]cachedAPIKey{}
+)
+
+type cachedAPIKey struct {
+	command string
+	key     string
+	// expires is zero when the key is kept until the provider rejects it.
+	expires time.Time
+}
+
+// runAPIKeyCommand runs the credential helper, swapped in tests.
+var runAPIKeyCommand = defaultRunAPIKeyCommand
+
+func defaultRunAPIKeyCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), apiKeyCommandTimeout)
+	defer cancel()
+	sh := shell.NewShell(&shell.Options{Env: env.New().Env()})
+	stdout, stderr, err := sh.Exec(ctx, command)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// ProviderAPIKey returns the API key of the provider: the output of its
+// api_key_command, cached, or else its api_key resolved.
+func (c *Config) ProviderAPIKey(p ProviderConfig) (string, error) {
+	if p.APIKeyCommand == "" {
+		return c.Resolve(p.APIKey)
+	}
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+	if cached, ok := apiKeys[p.ID]; ok && cached.command == p.APIKeyCommand &&
+		(cached.expires.IsZero() || time.Now().Before(cached.expires)) {
+		return cached.key, nil
+	}
+	key, err := runAPIKeyCommand(p.APIKeyCommand)
+	if err != nil {
+		return "", fmt.Errorf("api_key_command of %s failed: %w", p.ID, err)
+	}
+	if key == "" {
+		return "", fmt.Errorf("api_key_command of %s printed no key", p.ID)
+	}
+	cached := cachedAPIKey{command: p.APIKeyCommand, key: key}
+	if p.APIKeyTTL > 0 {
+		cached.expires = time.Now().Add(time.Duration(p.APIKeyTTL) * time.Second)
+	}
+	apiKeys[p.ID] = cached
+	return key, nil
+}
+
+// RefreshProviderAPIKey returns the API key of the provider after it
+// rejected the key, running its api_key_command again, unless another agent
+// already did, or resolving its api_key again for the variables and commands
+// it references.
+func (c *Config) RefreshProviderAPIKey(p ProviderConfig, rejected string) (string, error) {
+	apiKeysMu.Lock()
+	if cached, ok := apiKeys[p.ID]; ok && cached.key == rejected {
+		delete(apiKeys, p.ID)
+	}
+	apiKeysMu.Unlock()
+	return c.ProviderAPIKey(p)
+}
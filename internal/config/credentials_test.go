@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderAPIKeyCommand(t *testing.T) {
+	runs := 0
+	runAPIKeyCommand = func(command string) (string, error) {
+		runs++
+		if command == "print-nothing" {
+			return "", nil
+		}
+		return fmt.Sprintf("token-%d", runs), nil
+	}
+	t.Cleanup(func() {
+		runAPIKeyCommand = defaultRunAPIKeyCommand
+		clear(apiKeys)
+	})
+
+	cfg := &Config{resolver: NewEnvironmentVariableResolver(env.NewFromMap(map[string]string{"KEY": "static"}))}
+
+	key, err := cfg.ProviderAPIKey(ProviderConfig{ID: "plain", APIKey: "$KEY"})
+	require.NoError(t, err)
+	require.Equal(t, "static", key)
+	require.Zero(t, runs)
+
+	helper := ProviderConfig{ID: "sso", APIKeyCommand: "sso-cli token"}
+	key, err = cfg.ProviderAPIKey(helper)
+	require.NoError(t, err)
+	require.Equal(t, "token-1", key)
+
+	// The key is cached until the provider rejects it.
+	key, err = cfg.ProviderAPIKey(helper)
+	require.NoError(t, err)
+	require.Equal(t, "token-1", key)
+	require.Equal(t, 1, runs)
+
+	key, err = cfg.RefreshProviderAPIKey(helper, "token-1")
+	require.NoError(t, err)
+	require.Equal(t, "token-2", key)
+
+	// Another agent rejecting the previous key reuses the refreshed one.
+	key, err = cfg.RefreshProviderAPIKey(helper, "token-1")
+	require.NoError(t, err)
+	require.Equal(t, "token-2", key)
+	require.Equal(t, 2, runs)
+
+	// The key is run again once its TTL expires.
+	expiring := ProviderConfig{ID: "expiring", APIKeyCommand: "sso-cli token", APIKeyTTL: 1}
+	_, err = cfg.ProviderAPIKey(expiring)
+	require.NoError(t, err)
+	cached := apiKeys["expiring"]
+	cached.expires = cached.expires.Add(-time.Hour)
+	apiKeys["expiring"] = cached
+	key, err = cfg.ProviderAPIKey(expiring)
+	require.NoError(t, err)
+	require.Equal(t, "token-4", key)
+
+	_, err = cfg.ProviderAPIKey(ProviderConfig{ID: "empty", APIKeyCommand: "print-nothing"})
+	require.ErrorContains(t, err, "printed no key")
+}
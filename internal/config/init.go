@@ -33,6 +33,12 @@ func Get() *Config {
 	return cfg
 }
 
+// Set makes the configuration the current one, when switching between the
+// workspaces of several projects.
+func Set(cfg *Config) {
+	instance.Store(cfg)
+}
+
 func ProjectNeedsInitialization() (bool, error) {
 	cfg := Get()
 	if cfg == nil {
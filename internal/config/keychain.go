@@ -0,0 +1,81 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/keychain"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// isPlaintextKey tells whether the API key is written in the configuration
+// itself, rather than referencing a variable, a command or the keychain.
+func isPlaintextKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "$") && !strings.HasPrefix(key, KeychainPrefix)
+}
+
+// storeAPIKey stores the plaintext API key of the provider in the keychain,
+// returning the reference to write in the configuration instead, or the key
+// itself when the keychain can't be used.
+func storeAPIKey(providerID, apiKey string, store func(account, secret string) error) string {
+	if !isPlaintextKey(apiKey) {
+		return apiKey
+	}
+	if err := store(providerID, apiKey); err != nil {
+		slog.Debug("Keeping the API key in the configuration file", "provider", providerID, "error", err)
+		return apiKey
+	}
+	return KeychainPrefix + providerID
+}
+
+// migrateAPIKeys moves the plaintext API keys of the user configuration files
+// to the keychain, replacing them with keychain: references. The project
+// configurations are left alone, they belong to the repository.
+func migrateAPIKeys(paths []string, store func(account, secret string) error) error {
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		content := string(data)
+		migrated := 0
+		for id, provider := range gjson.Get(content, "providers").Map() {
+			apiKey := provider.Get("api_key").String()
+			if !isPlaintextKey(apiKey) {
+				continue
+			}
+			if err := store(id, apiKey); errors.Is(err, keychain.ErrUnavailable) {
+				return nil
+			} else if err != nil {
+				slog.Warn("Failed to move the API key to the keychain", "provider", id, "path", path, "error", err)
+				continue
+			}
+			key := "providers." + escapePathSegment(id) + ".api_key"
+			if content, err = sjson.Set(content, key, KeychainPrefix+id); err != nil {
+				return fmt.Errorf("failed to update %s: %w", path, err)
+			}
+			migrated++
+		}
+		if migrated == 0 {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			return fmt.Errorf("failed to update %s: %w", path, err)
+		}
+		slog.Info("Moved the API keys to the keychain", "path", path, "count", migrated)
+	}
+	return nil
+}
+
+// escapePathSegment escapes the characters of a key which are special in
+// gjson and sjson paths.
+func escapePathSegment(key string) string {
+	return strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`).Replace(key)
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/keychain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "crush.json")
+	content := `{
+  "providers": {
+    "openai": {"api_key": "sk-123"},
+    "anthropic": {"api_key": "$ANTHROPIC_API_KEY"},
+    "my.gateway": {"api_key": "gw-456", "base_url": "https://gateway.example.com"},
+    "local": {"api_key": "keychain:local"}
+  }
+}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	stored := make(map[string]string)
+	store := func(account, secret string) error {
+		stored[account] = secret
+		return nil
+	}
+	require.NoError(t, migrateAPIKeys([]string{path, filepath.Join(t.TempDir(), "missing.json")}, store))
+	require.Equal(t, map[string]string{"openai": "sk-123", "my.gateway": "gw-456"}, stored)
+
+	cfg, err := loadFromConfigPaths(t.TempDir(), []string{path})
+	require.NoError(t, err)
+	openai, _ := cfg.Providers.Get("openai")
+	require.Equal(t, "keychain:openai", openai.APIKey)
+	gateway, _ := cfg.Providers.Get("my.gateway")
+	require.Equal(t, "keychain:my.gateway", gateway.APIKey)
+	require.Equal(t, "https://gateway.example.com", gateway.BaseURL)
+	anthropic, _ := cfg.Providers.Get("anthropic")
+	require.Equal(t, "$ANTHROPIC_API_KEY", anthropic.APIKey)
+
+	// Once migrated there is nothing left to store.
+	clear(stored)
+	require.NoError(t, migrateAPIKeys([]string{path}, store))
+	require.Empty(t, stored)
+}
+
+func TestMigrateAPIKeysWithoutKeychain(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "crush.json")
+	content := `{"providers": {"openai": {"api_key": "sk-123"}}}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	require.NoError(t, migrateAPIKeys([]string{path}, func(string, string) error {
+		return keychain.ErrUnavailable
+	}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+
+	require.Equal(t, "sk-123", storeAPIKey("openai", "sk-123", func(string, string) error {
+		return errors.New("locked")
+	}))
+	require.Equal(t, "keychain:openai", storeAPIKey("openai", "sk-123", func(string, string) error {
+		return nil
+	}))
+}
+
+func TestResolveKeychain(t *testing.T) {
+	t.Parallel()
+
+	resolver := &shellVariableResolver{
+		shell: &mockShell{},
+		keychain: func(account string) (string, error) {
+			if account == "openai" {
+				return "sk-123", nil
+			}
+			return "", errors.New("not found")
+		},
+	}
+	value, err := resolver.ResolveValue("keychain:openai")
+	require.NoError(t, err)
+	require.Equal(t, "sk-123", value)
+	_, err = resolver.ResolveValue("keychain:other")
+	require.Error(t, err)
+}
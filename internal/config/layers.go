@@ -0,0 +1,368 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/charmbracelet/crush/internal/env"
+)
+
+// The configuration is merged from layers, each one overriding the values of
+// the layers before it:
+//
+//  1. the built-in defaults,
+//  2. the user configurations, crush.json in the config directory and then in
+//     the data directory,
+//  3. the project configurations, crush.json and then .crush.json in the
+//     working directory,
+//  4. the CRUSH_CONFIG_ environment variables,
+//  5. the --set flags, and the --debug and --data-dir flags.
+//
+// Objects are merged key by key, arrays are concatenated and other values are
+// replaced. The ${VAR} and $(command) references of the string values are
+// expanded, except the commands of the project configurations which anyone
+// can commit to a repository.
+
+const (
+	// envConfigPrefix prefixes the environment variables setting a key of the
+	// configuration, the segments of the key separated by a double
+	// underscore: CRUSH_CONFIG_OPTIONS__TUI__COMPACT_MODE=true.
+	envConfigPrefix = "CRUSH_CONFIG_"
+
+	// SourceDefault is the source of the values no layer sets.
+	SourceDefault = "default"
+)
+
+// configLayer is a source of configuration values.
+type configLayer struct {
+	source string
+	data   map[string]any
+	// commands tells whether the $(command) references of the values are run.
+	commands bool
+}
+
+// flagOverrides holds the layers of the --set flags.
+var flagOverrides atomic.Pointer[[]configLayer]
+
+// SetOverrides sets the keys of the configuration given as key.path=value
+// with the --set flags, which override every other layer. The value is
+// parsed as JSON, or taken as a string when it isn't valid JSON.
+func SetOverrides(values []string) error {
+	layers := make([]configLayer, 0, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid override %q, expected key.path=value", v)
+		}
+		layers = append(layers, configLayer{
+			source:   "flag --set " + key,
+			data:     layerData(strings.Split(strings.TrimSpace(key), "."), value),
+			commands: true,
+		})
+	}
+	flagOverrides.Store(&layers)
+	return nil
+}
+
+// configLayers reads the layers of the configuration files, the environment
+// and the flags, in the order they are merged.
+func configLayers(workingDir string, configPaths []string, environ []string) ([]configLayer, error) {
+	projectPaths := projectConfigPaths(workingDir)
+	var layers []configLayer
+	for _, path := range configPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to open config file %s: %w", path, err)
+		}
+		data, err = trustedConfigData(workingDir, path, data)
+		if err != nil {
+			return nil, err
+		}
+		var raw map[string]any
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		layers = append(layers, configLayer{
+			source:   path,
+			data:     raw,
+			commands: !slices.Contains(projectPaths, path),
+		})
+	}
+	layers = append(layers, envLayers(environ)...)
+	if overrides := flagOverrides.Load(); overrides != nil {
+		layers = append(layers, *overrides...)
+	}
+	return layers, nil
+}
+
+// envLayers returns a layer for each CRUSH_CONFIG_ environment variable.
+func envLayers(environ []string) []configLayer {
+	var layers []configLayer
+	for _, ev := range environ {
+		name, value, ok := strings.Cut(ev, "=")
+		if !ok || !strings.HasPrefix(name, envConfigPrefix) || name == envConfigPrefix {
+			continue
+		}
+		key := strings.ToLower(strings.TrimPrefix(name, envConfigPrefix))
+		layers = append(layers, configLayer{
+			source:   "env " + name,
+			data:     layerData(strings.Split(key, "__"), value),
+			commands: true,
+		})
+	}
+	slices.SortFunc(layers, func(a, b configLayer) int {
+		return strings.Compare(a.source, b.source)
+	})
+	return layers
+}
+
+// layerData nests the value under the segments of its key.
+func layerData(key []string, value string) map[string]any {
+	var v any
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		v = value
+	}
+	for i := len(key) - 1; i > 0; i-- {
+		v = map[string]any{key[i]: v}
+	}
+	return map[string]any{key[0]: v}
+}
+
+// mergeLayers expands the references of the layers and merges them,
+// recording which layer set each key.
+func mergeLayers(layers []configLayer, resolver VariableResolver) (*Config, error) {
+	readers := make([]io.Reader, 0, len(layers))
+	sources := make(map[string]string)
+	for _, layer := range layers {
+		data := expandReferences(layer.data, resolver, layer.commands).(map[string]any)
+		bts, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode the configuration of %s: %w", layer.source, err)
+		}
+		readers = append(readers, bytes.NewReader(bts))
+		recordSources(sources, "", data, layer.source)
+	}
+	cfg, err := loadFromReaders(readers)
+	if err != nil {
+		return nil, err
+	}
+	cfg.sources = sources
+	return cfg, nil
+}
+
+// recordSources records the source of each value of the layer. Arrays are
+// concatenated, so all the layers setting one are its sources.
+func recordSources(sources map[string]string, prefix string, data map[string]any, source string) {
+	for k, v := range data {
+		key := joinKey(prefix, k)
+		switch v := v.(type) {
+		case map[string]any:
+			if len(v) > 0 {
+				recordSources(sources, key, v, source)
+				continue
+			}
+		case []any:
+			if previous, ok := sources[key]; ok && previous != source {
+				sources[key] = previous + ", " + source
+				continue
+			}
+		}
+		sources[key] = source
+	}
+}
+
+// expandReferences expands the ${VAR} and $(command) references of the
+// string values, leaving the other forms to the fields which resolve them
+// when they are used. A reference which can't be resolved is left as is.
+func expandReferences(v any, resolver VariableResolver, commands bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		expanded := make(map[string]any, len(v))
+		for k, value := range v {
+			expanded[k] = expandReferences(value, resolver, commands)
+		}
+		return expanded
+	case []any:
+		expanded := make([]any, len(v))
+		for i, value := range v {
+			expanded[i] = expandReferences(value, resolver, commands)
+		}
+		return expanded
+	case string:
+		return expandString(v, resolver, commands)
+	}
+	return v
+}
+
+func expandString(s string, resolver VariableResolver, commands bool) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "$")
+		if start == -1 || start+1 == len(s) {
+			break
+		}
+		end := referenceEnd(s[start:])
+		if end == -1 {
+			b.WriteString(s[:start+1])
+			s = s[start+1:]
+			continue
+		}
+		if s[start+1] == '(' && !commands {
+			b.WriteString(s[:start+end])
+			s = s[start+end:]
+			continue
+		}
+		ref := s[start : start+end]
+		value, err := resolver.ResolveValue(ref)
+		if err != nil {
+			slog.Warn("Failed to expand a configuration value", "reference", ref, "error", err)
+			value = ref
+		}
+		b.WriteString(s[:start])
+		b.WriteString(value)
+		s = s[start+end:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// referenceEnd returns the length of the ${VAR} or $(command) reference the
+// string starts with, or -1 when it starts with neither.
+func referenceEnd(s string) int {
+	switch s[1] {
+	case '{':
+		if end := strings.Index(s, "}"); end > 2 {
+			return end + 1
+		}
+	case '(':
+		depth := 0
+		for i := 2; i < len(s); i++ {
+			switch s[i] {
+			case '(':
+				depth++
+			case ')':
+				if depth == 0 {
+					return i + 1
+				}
+				depth--
+			}
+		}
+	}
+	return -1
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// Source returns where the key of the configuration was set: a
+// configuration file, an environment variable, a flag or SourceDefault.
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// ResolvedValue is a key of the effective configuration.
+type ResolvedValue struct {
+	Key string `json:"key"`
+	// Value is the JSON encoded value, secrets masked.
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Effective returns the effective configuration, its secrets masked.
+func (c *Config) Effective() (map[string]any, error) {
+	bts, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	if err := json.Unmarshal(bts, &data); err != nil {
+		return nil, err
+	}
+	maskSecrets(nil, data)
+	return data, nil
+}
+
+// Resolved lists the keys of the effective configuration, sorted, with the
+// layer which set them.
+func (c *Config) Resolved() ([]ResolvedValue, error) {
+	data, err := c.Effective()
+	if err != nil {
+		return nil, err
+	}
+	var values []ResolvedValue
+	flattenResolved(&values, "", data, c)
+	slices.SortFunc(values, func(a, b ResolvedValue) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return values, nil
+}
+
+func flattenResolved(values *[]ResolvedValue, prefix string, data map[string]any, c *Config) {
+	for k, v := range data {
+		key := joinKey(prefix, k)
+		if m, ok := v.(map[string]any); ok && len(m) > 0 {
+			flattenResolved(values, key, m, c)
+			continue
+		}
+		bts, _ := json.Marshal(v)
+		*values = append(*values, ResolvedValue{
+			Key:    key,
+			Value:  string(bts),
+			Source: c.Source(key),
+		})
+	}
+}
+
+func maskSecrets(path []string, data map[string]any) {
+	for k, v := range data {
+		keyPath := append(slices.Clone(path), k)
+		if m, ok := v.(map[string]any); ok {
+			maskSecrets(keyPath, m)
+		} else if isSecret(keyPath, v) {
+			data[k] = "********"
+		}
+	}
+}
+
+// secretKeys are the keys holding a credential, and secretMaps the maps
+// whose values hold one.
+var (
+	secretKeys = []string{"api_key", "client_secret", "dsn", "slack_signing_secret"}
+	secretMaps = []string{"headers", "extra_headers", "env"}
+)
+
+// isSecret tells whether the value is a credential, unless it references a
+// variable or the keychain.
+func isSecret(path []string, v any) bool {
+	s, ok := v.(string)
+	if !ok || s == "" || strings.HasPrefix(s, "$") || strings.HasPrefix(s, KeychainPrefix) {
+		return false
+	}
+	if slices.Contains(secretKeys, path[len(path)-1]) {
+		return true
+	}
+	return len(path) > 1 && slices.Contains(secretMaps, path[len(path)-2])
+}
+
+// newLayerResolver returns the resolver expanding the references of the
+// layers.
+func newLayerResolver() VariableResolver {
+	return NewShellVariableResolver(env.New())
+}
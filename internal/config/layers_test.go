@@ -0,0 +1,139 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/stretchr/testify/require"
+)
+
+func testLayerResolver() VariableResolver {
+	return &shellVariableResolver{
+		env: env.NewFromMap(map[string]string{"TOKEN": "abc", "HOST": "example.com"}),
+		shell: &mockShell{execFunc: func(ctx context.Context, command string) (string, string, error) {
+			if command == "echo secret" {
+				return "secret\n", "", nil
+			}
+			return "", "", errors.New("unexpected command")
+		}},
+	}
+}
+
+func TestExpandString(t *testing.T) {
+	t.Parallel()
+
+	resolver := testLayerResolver()
+	for value, expected := range map[string]string{
+		"plain":                          "plain",
+		"https://${HOST}/v1":             "https://example.com/v1",
+		"Bearer ${TOKEN} $(echo secret)": "Bearer abc secret",
+		"$OPENAI_API_KEY":                "$OPENAI_API_KEY",
+		"${MISSING} and $":               "${MISSING} and $",
+		"$(false)":                       "$(false)",
+		"cost $5 ${TOKEN":                "cost $5 ${TOKEN",
+	} {
+		require.Equal(t, expected, expandString(value, resolver, true), value)
+	}
+	require.Equal(t, "$(echo secret) abc", expandString("$(echo secret) ${TOKEN}", resolver, false))
+}
+
+func TestEnvLayers(t *testing.T) {
+	t.Parallel()
+
+	layers := envLayers([]string{
+		"CRUSH_CONFIG_OPTIONS__TUI__COMPACT_MODE=true",
+		"CRUSH_CONFIG_OPTIONS__CONTEXT_PATHS=[\"NOTES.md\"]",
+		"CRUSH_CONFIG_=ignored",
+		"CRUSH_OPENAI_API_KEY=ignored",
+		"CRUSH_CONFIG_MODELS__LARGE__MODEL=gpt-4o",
+	})
+	require.Len(t, layers, 3)
+	require.Equal(t, "env CRUSH_CONFIG_MODELS__LARGE__MODEL", layers[0].source)
+	require.Equal(t, map[string]any{"models": map[string]any{"large": map[string]any{"model": "gpt-4o"}}}, layers[0].data)
+	require.Equal(t, map[string]any{"options": map[string]any{"context_paths": []any{"NOTES.md"}}}, layers[1].data)
+	require.Equal(t, map[string]any{"options": map[string]any{"tui": map[string]any{"compact_mode": true}}}, layers[2].data)
+}
+
+func TestMergeLayers(t *testing.T) {
+	t.Parallel()
+
+	layers := []configLayer{
+		{
+			source:   "user",
+			data:     map[string]any{"options": map[string]any{"debug": true, "context_paths": []any{"A.md"}, "data_directory": "$(echo secret)"}},
+			commands: true,
+		},
+		{
+			source: "project",
+			data:   map[string]any{"options": map[string]any{"context_paths": []any{"B.md"}, "tui": map[string]any{"diff_mode": "$(echo secret)"}}},
+		},
+		{
+			source:   "env CRUSH_CONFIG_OPTIONS__DEBUG",
+			data:     map[string]any{"options": map[string]any{"debug": false}},
+			commands: true,
+		},
+	}
+	cfg, err := mergeLayers(layers, testLayerResolver())
+	require.NoError(t, err)
+	require.False(t, cfg.Options.Debug)
+	require.Equal(t, []string{"A.md", "B.md"}, cfg.Options.ContextPaths)
+	require.Equal(t, "secret", cfg.Options.DataDirectory)
+	require.Equal(t, "$(echo secret)", cfg.Options.TUI.DiffMode, "project commands aren't run")
+
+	require.Equal(t, "env CRUSH_CONFIG_OPTIONS__DEBUG", cfg.Source("options.debug"))
+	require.Equal(t, "user, project", cfg.Source("options.context_paths"))
+	require.Equal(t, "project", cfg.Source("options.tui.diff_mode"))
+	require.Equal(t, SourceDefault, cfg.Source("options.tui.compact_mode"))
+}
+
+func TestSetOverrides(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("CRUSH_CONFIG_OPTIONS__DEBUG", "true")
+	t.Cleanup(func() { flagOverrides.Store(nil) })
+	workingDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "crush.json"), []byte(`{"options": {"debug": false, "tui": {"compact_mode": true}}}`), 0o600))
+
+	require.Error(t, SetOverrides([]string{"options.debug"}))
+	require.NoError(t, SetOverrides([]string{"options.tui.compact_mode=false", "options.tui.diff_mode=split"}))
+
+	cfg, err := loadFromConfigPaths(workingDir, ConfigPaths(workingDir))
+	require.NoError(t, err)
+	require.True(t, cfg.Options.Debug)
+	require.False(t, cfg.Options.TUI.CompactMode)
+	require.Equal(t, "split", cfg.Options.TUI.DiffMode)
+	require.Equal(t, "env CRUSH_CONFIG_OPTIONS__DEBUG", cfg.Source("options.debug"))
+	require.Equal(t, "flag --set options.tui.compact_mode", cfg.Source("options.tui.compact_mode"))
+}
+
+func TestResolved(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := mergeLayers([]configLayer{{
+		source: "user",
+		data: map[string]any{
+			"providers": map[string]any{
+				"openai": map[string]any{"api_key": "sk-123", "extra_headers": map[string]any{"X-Key": "$KEY"}},
+				"local":  map[string]any{"api_key": "$LOCAL_KEY"},
+			},
+			"mcp": map[string]any{"github": map[string]any{"env": map[string]any{"GITHUB_TOKEN": "ghp_123"}}},
+		},
+	}}, testLayerResolver())
+	require.NoError(t, err)
+
+	values, err := cfg.Resolved()
+	require.NoError(t, err)
+	resolved := make(map[string]ResolvedValue)
+	for _, v := range values {
+		resolved[v.Key] = v
+	}
+	require.Equal(t, ResolvedValue{Key: "providers.openai.api_key", Value: `"********"`, Source: "user"}, resolved["providers.openai.api_key"])
+	require.Equal(t, `"$KEY"`, resolved["providers.openai.extra_headers.X-Key"].Value)
+	require.Equal(t, `"$LOCAL_KEY"`, resolved["providers.local.api_key"].Value)
+	require.Equal(t, `"********"`, resolved["mcp.github.env.GITHUB_TOKEN"].Value)
+	require.Equal(t, SourceDefault, resolved["mcp.github.type"].Source)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,12 +12,15 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/env"
 	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/keychain"
 	"github.com/charmbracelet/crush/internal/log"
+	"github.com/charmbracelet/crush/internal/network"
 )
 
 const defaultCatwalkURL = "https://catwalk.charm.sh"
@@ -36,16 +40,13 @@ func LoadReader(fd io.Reader) (*Config, error) {
 	return &config, err
 }
 
+// networkOnce configures the network with the first configuration loaded.
+var networkOnce sync.Once
+
 // Load loads the configuration from the default paths.
 func Load(workingDir, dataDir string, debug bool) (*Config, error) {
-	// uses default config paths
-	configPaths := []string{
-		globalConfig(),
-		GlobalConfigData(),
-		filepath.Join(workingDir, fmt.Sprintf("%s.json", appName)),
-		filepath.Join(workingDir, fmt.Sprintf(".%s.json", appName)),
-	}
-	cfg, err := loadFromConfigPaths(configPaths)
+	configPaths := ConfigPaths(workingDir)
+	cfg, err := loadFromConfigPaths(workingDir, configPaths)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config from paths %v: %w", configPaths, err)
 	}
@@ -54,8 +55,12 @@ func Load(workingDir, dataDir string, debug bool) (*Config, error) {
 
 	cfg.setDefaults(workingDir, dataDir)
 
+	if dataDir != "" {
+		cfg.sources["options.data_directory"] = "flag --data-dir"
+	}
 	if debug {
 		cfg.Options.Debug = true
+		cfg.sources["options.debug"] = "flag --debug"
 	}
 
 	// Setup logs
@@ -64,10 +69,30 @@ func Load(workingDir, dataDir string, debug bool) (*Config, error) {
 		cfg.Options.Debug,
 	)
 
+	// The transport is shared by the whole process, the settings of the
+	// configurations loaded afterwards need a restart.
+	networkOnce.Do(func() {
+		err = network.Configure(cfg.Network())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the network: %w", err)
+	}
+
+	if cfg.Keychain() {
+		if err := migrateAPIKeys([]string{globalConfig(), GlobalConfigData()}, keychain.Set); err != nil {
+			slog.Warn("Failed to move the API keys to the keychain", "error", err)
+		}
+	}
+
 	// Load known providers, this loads the config from catwalk
-	providers, err := Providers()
+	providers, err := knownProviders(!cfg.Options.DisableProviderAutoUpdate)
 	if err != nil || len(providers) == 0 {
-		return nil, fmt.Errorf("failed to load providers: %w", err)
+		if cfg.Providers.Len() == 0 {
+			return nil, fmt.Errorf("failed to load providers: %w", err)
+		}
+		// The configured providers and their models are enough to work
+		// offline.
+		slog.Warn("Failed to load the catalog of providers, using the configured providers only", "error", err)
 	}
 	cfg.knownProviders = providers
 
@@ -142,12 +167,19 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			if len(config.Models) > 0 {
 				models := []catwalk.Model{}
 				seen := make(map[string]bool)
+				catalog := make(map[string]catwalk.Model, len(p.Models))
+				for _, model := range p.Models {
+					catalog[model.ID] = model
+				}
 
 				for _, model := range config.Models {
 					if seen[model.ID] {
 						continue
 					}
 					seen[model.ID] = true
+					if base, ok := catalog[model.ID]; ok {
+						model = overrideModel(base, model)
+					}
 					if model.Name == "" {
 						model.Name = model.ID
 					}
@@ -180,9 +212,15 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			Name:               p.Name,
 			BaseURL:            p.APIEndpoint,
 			APIKey:             p.APIKey,
+			APIKeyCommand:      config.APIKeyCommand,
+			APIKeyTTL:          config.APIKeyTTL,
 			Type:               p.Type,
 			Disable:            config.Disable,
 			SystemPromptPrefix: config.SystemPromptPrefix,
+			CompatMode:         config.CompatMode,
+			Paths:              config.Paths,
+			Timeouts:           config.Timeouts,
+			Normalize:          config.Normalize,
 			ExtraHeaders:       headers,
 			ExtraBody:          config.ExtraBody,
 			ExtraParams:        make(map[string]string),
@@ -197,6 +235,9 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 				prepared.BaseURL = customBaseURL
 				slog.Info("Using custom Anthropic base URL from CRUSH_ANTHROPIC_BASE_URL", "url", customBaseURL)
 			}
+			if compatMode := env.Get("CRUSH_ANTHROPIC_COMPAT_MODE"); compatMode != "" {
+				prepared.CompatMode = CompatMode(compatMode)
+			}
 		case catwalk.InferenceProviderVertexAI:
 			if !hasVertexCredentials(env) {
 				if configExists {
@@ -238,7 +279,7 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 		default:
 			// if the provider api or endpoint are missing we skip them
 			v, err := resolver.ResolveValue(p.APIKey)
-			if v == "" || err != nil {
+			if (v == "" || err != nil) && prepared.APIKeyCommand == "" {
 				if configExists {
 					slog.Warn("Skipping provider due to missing API key", "provider", p.ID)
 					c.Providers.Del(string(p.ID))
@@ -270,7 +311,7 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			c.Providers.Del(id)
 			continue
 		}
-		if providerConfig.APIKey == "" {
+		if providerConfig.APIKey == "" && providerConfig.APIKeyCommand == "" {
 			slog.Warn("Provider is missing API key, this might be OK for local providers", "provider", id)
 		}
 		if providerConfig.BaseURL == "" {
@@ -283,14 +324,19 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			c.Providers.Del(id)
 			continue
 		}
-		if providerConfig.Type != catwalk.TypeOpenAI && providerConfig.Type != catwalk.TypeAnthropic {
+		if providerConfig.Type != catwalk.TypeOpenAI && providerConfig.Type != catwalk.TypeAnthropic && providerConfig.Type != TypeAnthropicCompat {
 			slog.Warn("Skipping custom provider because the provider type is not supported", "provider", id, "type", providerConfig.Type)
 			c.Providers.Del(id)
 			continue
 		}
 
+		if providerConfig.CompatMode != "" && (providerConfig.CompatMode != CompatModeProxy || (providerConfig.Type != catwalk.TypeAnthropic && providerConfig.Type != TypeAnthropicCompat)) {
+			slog.Warn("Ignoring the compat mode of the provider, only proxy is supported for the anthropic types", "provider", id, "compat_mode", providerConfig.CompatMode)
+			providerConfig.CompatMode = ""
+		}
+
 		apiKey, err := resolver.ResolveValue(providerConfig.APIKey)
-		if apiKey == "" || err != nil {
+		if (apiKey == "" || err != nil) && providerConfig.APIKeyCommand == "" {
 			slog.Warn("Provider is missing API key, this might be OK for local providers", "provider", id)
 		}
 		baseURL, err := resolver.ResolveValue(providerConfig.BaseURL)
@@ -515,23 +561,26 @@ func (c *Config) configureSelectedModels(knownProviders []catwalk.Provider) erro
 	return nil
 }
 
-func loadFromConfigPaths(configPaths []string) (*Config, error) {
-	var configs []io.Reader
-
-	for _, path := range configPaths {
-		fd, err := os.Open(path)
-		if err != nil {
-			if os.IsNotExist(err) {
-				continue
-			}
-			return nil, fmt.Errorf("failed to open config file %s: %w", path, err)
-		}
-		defer fd.Close()
-
-		configs = append(configs, fd)
+// ConfigPaths returns the configuration files of the working directory, in
+// the order they are merged.
+func ConfigPaths(workingDir string) []string {
+	return []string{
+		globalConfig(),
+		GlobalConfigData(),
+		filepath.Join(workingDir, fmt.Sprintf("%s.json", appName)),
+		filepath.Join(workingDir, fmt.Sprintf(".%s.json", appName)),
 	}
+}
 
-	return loadFromReaders(configs)
+// loadFromConfigPaths merges the configuration files, leaving out the
+// untrusted fields of the project configurations of the working directory,
+// and then the environment variables and the flags overriding them.
+func loadFromConfigPaths(workingDir string, configPaths []string) (*Config, error) {
+	layers, err := configLayers(workingDir, configPaths, os.Environ())
+	if err != nil {
+		return nil, err
+	}
+	return mergeLayers(layers, newLayerResolver())
 }
 
 func loadFromReaders(readers []io.Reader) (*Config, error) {
@@ -547,6 +596,26 @@ func loadFromReaders(readers []io.Reader) (*Config, error) {
 	return LoadReader(merged)
 }
 
+// overrideModel returns the model of the catalog with the fields set by the
+// configuration. The capabilities can only be turned on, as a false value
+// can't be told from a missing one.
+func overrideModel(base, override catwalk.Model) catwalk.Model {
+	return catwalk.Model{
+		ID:                     base.ID,
+		Name:                   cmp.Or(override.Name, base.Name),
+		CostPer1MIn:            cmp.Or(override.CostPer1MIn, base.CostPer1MIn),
+		CostPer1MOut:           cmp.Or(override.CostPer1MOut, base.CostPer1MOut),
+		CostPer1MInCached:      cmp.Or(override.CostPer1MInCached, base.CostPer1MInCached),
+		CostPer1MOutCached:     cmp.Or(override.CostPer1MOutCached, base.CostPer1MOutCached),
+		ContextWindow:          cmp.Or(override.ContextWindow, base.ContextWindow),
+		DefaultMaxTokens:       cmp.Or(override.DefaultMaxTokens, base.DefaultMaxTokens),
+		CanReason:              override.CanReason || base.CanReason,
+		HasReasoningEffort:     override.HasReasoningEffort || base.HasReasoningEffort,
+		DefaultReasoningEffort: cmp.Or(override.DefaultReasoningEffort, base.DefaultReasoningEffort),
+		SupportsImages:         override.SupportsImages || base.SupportsImages,
+	}
+}
+
 func hasVertexCredentials(env env.Env) bool {
 	hasProject := env.Get("VERTEXAI_PROJECT") != ""
 	hasLocation := env.Get("VERTEXAI_LOCATION") != ""
@@ -593,6 +662,12 @@ func globalConfig() string {
 	return filepath.Join(os.Getenv("HOME"), ".config", appName, fmt.Sprintf("%s.json", appName))
 }
 
+// GlobalContextFile returns the CRUSH.md file next to the global
+// configuration, added to the context of every project.
+func GlobalContextFile() string {
+	return filepath.Join(filepath.Dir(globalConfig()), "CRUSH.md")
+}
+
 // GlobalConfigData returns the path to the main data directory for the application.
 // this config is used when the app overrides configurations instead of updating the global config.
 func GlobalConfigData() string {
@@ -128,6 +128,70 @@ func TestConfig_configureProvidersWithOverride(t *testing.T) {
 	require.Equal(t, "Updated", pc.Models[0].Name)
 }
 
+func TestConfig_configureProvidersWithModelOverride(t *testing.T) {
+	knownProviders := []catwalk.Provider{
+		{
+			ID:          "openai",
+			APIKey:      "$OPENAI_API_KEY",
+			APIEndpoint: "https://api.openai.com/v1",
+			Models: []catwalk.Model{{
+				ID:               "test-model",
+				Name:             "Test Model",
+				CostPer1MIn:      2.5,
+				CostPer1MOut:     10,
+				ContextWindow:    128_000,
+				DefaultMaxTokens: 4096,
+				SupportsImages:   true,
+			}},
+		},
+	}
+
+	cfg := &Config{
+		Providers: csync.NewMap[string, ProviderConfig](),
+	}
+	cfg.Providers.Set("openai", ProviderConfig{
+		Models: []catwalk.Model{
+			{
+				ID:               "test-model",
+				ContextWindow:    32_000,
+				DefaultMaxTokens: 8192,
+				CanReason:        true,
+			},
+			{
+				ID:            "fine-tuned",
+				ContextWindow: 16_000,
+			},
+		},
+	})
+	cfg.setDefaults("/tmp", "")
+
+	env := env.NewFromMap(map[string]string{
+		"OPENAI_API_KEY": "test-key",
+	})
+	resolver := NewEnvironmentVariableResolver(env)
+	err := cfg.configureProviders(env, resolver, knownProviders)
+	require.NoError(t, err)
+
+	pc, _ := cfg.Providers.Get("openai")
+	require.Equal(t, []catwalk.Model{
+		{
+			ID:               "test-model",
+			Name:             "Test Model",
+			CostPer1MIn:      2.5,
+			CostPer1MOut:     10,
+			ContextWindow:    32_000,
+			DefaultMaxTokens: 8192,
+			CanReason:        true,
+			SupportsImages:   true,
+		},
+		{
+			ID:            "fine-tuned",
+			Name:          "fine-tuned",
+			ContextWindow: 16_000,
+		},
+	}, pc.Models)
+}
+
 func TestConfig_configureProvidersWithNewProvider(t *testing.T) {
 	knownProviders := []catwalk.Provider{
 		{
@@ -1184,3 +1248,50 @@ func TestConfig_configureSelectedModels(t *testing.T) {
 		require.Equal(t, int64(100), large.MaxTokens)
 	})
 }
+
+func TestToolLimitsOptions_For(t *testing.T) {
+	t.Parallel()
+
+	var none *ToolLimitsOptions
+	require.Equal(t, ToolLimits{}, none.For("bash", "shell"))
+
+	opts := &ToolLimitsOptions{
+		Default: ToolLimits{Timeout: 60, MaxOutputBytes: 1000},
+		Categories: map[string]ToolLimits{
+			"shell": {Timeout: 120, MaxMemoryMB: 512},
+		},
+		Tools: map[string]ToolLimits{
+			"bash": {Timeout: 300},
+		},
+	}
+	require.Equal(t, ToolLimits{Timeout: 300, MaxOutputBytes: 1000, MaxMemoryMB: 512}, opts.For("bash", "shell"))
+	require.Equal(t, ToolLimits{Timeout: 120, MaxOutputBytes: 1000, MaxMemoryMB: 512}, opts.For("run_tests", "shell"))
+	require.Equal(t, ToolLimits{Timeout: 60, MaxOutputBytes: 1000}, opts.For("view", "file"))
+}
+
+func TestMCPConfig_ToolEnabled(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, MCPConfig{}.ToolEnabled("create_issue"))
+
+	m := MCPConfig{
+		Tools:         []string{"get_*", "list_*", "create_issue"},
+		DisabledTools: []string{"list_secrets"},
+	}
+	require.True(t, m.ToolEnabled("get_issue"))
+	require.True(t, m.ToolEnabled("create_issue"))
+	require.True(t, m.ToolEnabled("list_repos"))
+	require.False(t, m.ToolEnabled("list_secrets"))
+	require.False(t, m.ToolEnabled("delete_repo"))
+
+	m = MCPConfig{DisabledTools: []string{"delete_*"}}
+	require.True(t, m.ToolEnabled("create_issue"))
+	require.False(t, m.ToolEnabled("delete_repo"))
+}
+
+func TestMCPConfig_ToolName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "mcp_github_create_issue", MCPConfig{}.ToolName("github", "create_issue"))
+	require.Equal(t, "gh_create_issue", MCPConfig{Prefix: "gh_"}.ToolName("github", "create_issue"))
+}
@@ -18,11 +18,40 @@ type ProviderClient interface {
 	GetProviders() ([]catwalk.Provider, error)
 }
 
+// catwalkTimeout bounds the requests for the catalog, so that crush starts
+// from its cache behind a firewall dropping them.
+const catwalkTimeout = 10 * time.Second
+
 var (
 	providerOnce sync.Once
 	providerList []catwalk.Provider
 )
 
+// timeoutProviderClient gives up on the providers of the client after the
+// timeout.
+type timeoutProviderClient struct {
+	client  ProviderClient
+	timeout time.Duration
+}
+
+func (c timeoutProviderClient) GetProviders() ([]catwalk.Provider, error) {
+	type result struct {
+		providers []catwalk.Provider
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		providers, err := c.client.GetProviders()
+		done <- result{providers, err}
+	}()
+	select {
+	case r := <-done:
+		return r.providers, r.err
+	case <-time.After(c.timeout):
+		return nil, fmt.Errorf("no answer from the catalog after %s", c.timeout)
+	}
+}
+
 // file to cache provider data
 func providerCacheFileData() string {
 	xdgDataHome := os.Getenv("XDG_DATA_HOME")
@@ -74,17 +103,28 @@ func loadProvidersFromCache(path string) ([]catwalk.Provider, error) {
 	return providers, nil
 }
 
+// Providers returns the catalog of the known providers and their models,
+// from catwalk or from its cache. It is loaded once, with the configuration.
 func Providers() ([]catwalk.Provider, error) {
+	return knownProviders(true)
+}
+
+// knownProviders loads the catalog. Without autoUpdate only the cache is
+// read, for the machines which can't reach catwalk.
+func knownProviders(autoUpdate bool) ([]catwalk.Provider, error) {
 	catwalkURL := cmp.Or(os.Getenv("CATWALK_URL"), defaultCatwalkURL)
-	client := catwalk.NewWithURL(catwalkURL)
+	client := timeoutProviderClient{
+		client:  catwalk.NewWithURL(catwalkURL),
+		timeout: catwalkTimeout,
+	}
 	path := providerCacheFileData()
-	return loadProvidersOnce(client, path)
+	return loadProvidersOnce(client, path, autoUpdate)
 }
 
-func loadProvidersOnce(client ProviderClient, path string) ([]catwalk.Provider, error) {
+func loadProvidersOnce(client ProviderClient, path string, autoUpdate bool) ([]catwalk.Provider, error) {
 	var err error
 	providerOnce.Do(func() {
-		providerList, err = loadProviders(client, path)
+		providerList, err = loadProviders(client, path, autoUpdate)
 	})
 	if err != nil {
 		return nil, err
@@ -92,9 +132,18 @@ func loadProvidersOnce(client ProviderClient, path string) ([]catwalk.Provider,
 	return providerList, nil
 }
 
-func loadProviders(client ProviderClient, path string) (providerList []catwalk.Provider, err error) {
+func loadProviders(client ProviderClient, path string, autoUpdate bool) (providerList []catwalk.Provider, err error) {
 	// if cache is not stale, load from it
 	stale, exists := isCacheStale(path)
+	if !autoUpdate {
+		if !exists {
+			err = fmt.Errorf("provider auto update is disabled and there is no cached catalog at %s", path)
+			return
+		}
+		slog.Info("Using cached provider data, auto update disabled", "path", path)
+		providerList, err = loadProvidersFromCache(path)
+		return
+	}
 	if !stale {
 		slog.Info("Using cached provider data", "path", path)
 		providerList, err = loadProvidersFromCache(path)
@@ -19,7 +19,7 @@ func TestProvider_loadProvidersEmptyResult(t *testing.T) {
 	client := &emptyProviderClient{}
 	tmpPath := t.TempDir() + "/providers.json"
 
-	providers, err := loadProviders(client, tmpPath)
+	providers, err := loadProviders(client, tmpPath, true)
 	require.EqualError(t, err, "failed to load providers")
 	require.Empty(t, providers)
 	require.Len(t, providers, 0)
@@ -39,7 +39,7 @@ func TestProvider_loadProvidersEmptyCache(t *testing.T) {
 	require.NoError(t, os.WriteFile(tmpPath, data, 0o644))
 
 	// Should refresh and get real providers instead of using empty cache
-	providers, err := loadProviders(client, tmpPath)
+	providers, err := loadProviders(client, tmpPath, true)
 	require.NoError(t, err)
 	require.NotNil(t, providers)
 	require.Len(t, providers, 1)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/stretchr/testify/require"
@@ -12,9 +13,11 @@ import (
 
 type mockProviderClient struct {
 	shouldFail bool
+	calls      int
 }
 
 func (m *mockProviderClient) GetProviders() ([]catwalk.Provider, error) {
+	m.calls++
 	if m.shouldFail {
 		return nil, errors.New("failed to load providers")
 	}
@@ -28,7 +31,7 @@ func (m *mockProviderClient) GetProviders() ([]catwalk.Provider, error) {
 func TestProvider_loadProvidersNoIssues(t *testing.T) {
 	client := &mockProviderClient{shouldFail: false}
 	tmpPath := t.TempDir() + "/providers.json"
-	providers, err := loadProviders(client, tmpPath)
+	providers, err := loadProviders(client, tmpPath, true)
 	require.NoError(t, err)
 	require.NotNil(t, providers)
 	require.Len(t, providers, 1)
@@ -57,7 +60,7 @@ func TestProvider_loadProvidersWithIssues(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to write old providers to file: %v", err)
 	}
-	providers, err := loadProviders(client, tmpPath)
+	providers, err := loadProviders(client, tmpPath, true)
 	require.NoError(t, err)
 	require.NotNil(t, providers)
 	require.Len(t, providers, 1)
@@ -67,7 +70,47 @@ func TestProvider_loadProvidersWithIssues(t *testing.T) {
 func TestProvider_loadProvidersWithIssuesAndNoCache(t *testing.T) {
 	client := &mockProviderClient{shouldFail: true}
 	tmpPath := t.TempDir() + "/providers.json"
-	providers, err := loadProviders(client, tmpPath)
+	providers, err := loadProviders(client, tmpPath, true)
 	require.Error(t, err)
 	require.Nil(t, providers, "Expected nil providers when loading fails and no cache exists")
 }
+
+func TestProvider_loadProvidersWithoutAutoUpdate(t *testing.T) {
+	client := &mockProviderClient{shouldFail: false}
+	tmpPath := t.TempDir() + "/providers.json"
+
+	providers, err := loadProviders(client, tmpPath, false)
+	require.Error(t, err)
+	require.Nil(t, providers)
+
+	data, err := json.Marshal([]catwalk.Provider{{Name: "CachedProvider"}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(tmpPath, data, 0o644))
+	stale := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(tmpPath, stale, stale))
+
+	providers, err = loadProviders(client, tmpPath, false)
+	require.NoError(t, err)
+	require.Len(t, providers, 1)
+	require.Equal(t, "CachedProvider", providers[0].Name, "Expected the stale cache to be used")
+	require.Zero(t, client.calls, "Expected catwalk not to be called")
+}
+
+type blockingProviderClient struct {
+	release chan struct{}
+}
+
+func (m *blockingProviderClient) GetProviders() ([]catwalk.Provider, error) {
+	<-m.release
+	return nil, nil
+}
+
+func TestProvider_timeoutProviderClient(t *testing.T) {
+	blocking := &blockingProviderClient{release: make(chan struct{})}
+	defer close(blocking.release)
+
+	client := timeoutProviderClient{client: blocking, timeout: 10 * time.Millisecond}
+	providers, err := client.GetProviders()
+	require.Error(t, err)
+	require.Nil(t, providers)
+}
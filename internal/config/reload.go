@@ -0,0 +1,129 @@
+package config
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// liveSections are the sections of the configuration applied while crush
+// runs, the others only apply once it restarts.
+var liveSections = []string{"models", "permissions", "mcp", "options.tui"}
+
+// Change is a section of the configuration which changed: a top level key,
+// or a key of the options.
+type Change struct {
+	Section string
+	// Restart tells the change only applies once crush restarts.
+	Restart bool
+}
+
+// Reload loads the configuration of the working directory again and applies
+// the changes which are safe while crush runs: the selected models, the
+// permissions, the MCP servers and the TUI options. It returns the sections
+// which changed, and the configuration they changed from. Nothing is
+// applied while the configuration of another workspace is the current one.
+func Reload(workingDir string) ([]Change, *Config, error) {
+	current := Get()
+	if current == nil || current.WorkingDir() != workingDir {
+		return nil, current, nil
+	}
+	next, err := Load(workingDir, current.Options.DataDirectory, current.Options.Debug)
+	if err != nil {
+		return nil, current, err
+	}
+	changes, err := diffConfigs(current, next)
+	if err != nil || len(changes) == 0 {
+		return nil, current, err
+	}
+
+	updated := *current
+	options := *current.Options
+	updated.Options = &options
+	for _, change := range changes {
+		switch change.Section {
+		case "models":
+			updated.Models = next.Models
+		case "permissions":
+			permissions := Permissions{}
+			if next.Permissions != nil {
+				permissions = *next.Permissions
+			}
+			// --yolo and --profile aren't part of the files.
+			if current.Permissions != nil {
+				permissions.SkipRequests = current.Permissions.SkipRequests
+				permissions.ProfileFlag = current.Permissions.ProfileFlag
+			}
+			updated.Permissions = &permissions
+		case "mcp":
+			updated.MCP = next.MCP
+		case "options.tui":
+			options.TUI = next.Options.TUI
+		}
+	}
+	Set(&updated)
+	return changes, current, nil
+}
+
+// diffConfigs returns the sections whose values differ between the
+// configurations, sorted.
+func diffConfigs(previous, next *Config) ([]Change, error) {
+	before, err := previous.values()
+	if err != nil {
+		return nil, err
+	}
+	after, err := next.values()
+	if err != nil {
+		return nil, err
+	}
+	sections := make(map[string]bool)
+	for key, value := range before {
+		if after[key] != value {
+			sections[section(key)] = true
+		}
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			sections[section(key)] = true
+		}
+	}
+	changes := make([]Change, 0, len(sections))
+	for _, s := range slices.Sorted(maps.Keys(sections)) {
+		changes = append(changes, Change{
+			Section: s,
+			Restart: !slices.Contains(liveSections, s),
+		})
+	}
+	return changes, nil
+}
+
+// values returns the JSON encoded values of the keys of the configuration,
+// its secrets included.
+func (c *Config) values() (map[string]string, error) {
+	bts, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	if err := json.Unmarshal(bts, &data); err != nil {
+		return nil, err
+	}
+	var resolved []ResolvedValue
+	flattenResolved(&resolved, "", data, c)
+	values := make(map[string]string, len(resolved))
+	for _, v := range resolved {
+		values[v.Key] = v.Value
+	}
+	return values, nil
+}
+
+// section returns the top level key of the configuration key, or the key of
+// the options.
+func section(key string) string {
+	parts := strings.SplitN(key, ".", 3)
+	if parts[0] == "options" && len(parts) > 1 {
+		return parts[0] + "." + parts[1]
+	}
+	return parts[0]
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	t.Parallel()
+
+	previous := &Config{
+		Models:      map[SelectedModelType]SelectedModel{SelectedModelTypeLarge: {Model: "gpt-4o", Provider: "openai"}},
+		Permissions: &Permissions{AllowedTools: []string{"view"}},
+		Options:     &Options{DataDirectory: ".crush", TUI: &TUIOptions{CompactMode: true}},
+	}
+	next := &Config{
+		Models:      map[SelectedModelType]SelectedModel{SelectedModelTypeLarge: {Model: "gpt-4o", Provider: "openai"}},
+		Permissions: &Permissions{AllowedTools: []string{"view", "ls"}},
+		Options:     &Options{DataDirectory: ".data", TUI: &TUIOptions{DiffMode: "split"}},
+		LSP:         map[string]LSPConfig{"gopls": {Command: "gopls"}},
+	}
+
+	changes, err := diffConfigs(previous, next)
+	require.NoError(t, err)
+	require.Equal(t, []Change{
+		{Section: "lsp", Restart: true},
+		{Section: "options.data_directory", Restart: true},
+		{Section: "options.tui"},
+		{Section: "permissions"},
+	}, changes)
+
+	changes, err = diffConfigs(previous, previous)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestSection(t *testing.T) {
+	t.Parallel()
+
+	for key, expected := range map[string]string{
+		"models.large.model":       "models",
+		"mcp":                      "mcp",
+		"options.tui.compact_mode": "options.tui",
+		"options.debug":            "options.debug",
+		"options":                  "options",
+	} {
+		require.Equal(t, expected, section(key), key)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/charmbracelet/crush/internal/env"
+	"github.com/charmbracelet/crush/internal/keychain"
 	"github.com/charmbracelet/crush/internal/shell"
 )
 
@@ -18,9 +19,15 @@ type Shell interface {
 	Exec(ctx context.Context, command string) (stdout, stderr string, err error)
 }
 
+// KeychainPrefix prefixes the values read from the keychain of the OS,
+// keychain:NAME being the secret NAME of crush in the keychain.
+const KeychainPrefix = "keychain:"
+
 type shellVariableResolver struct {
 	shell Shell
 	env   env.Env
+	// keychain reads a secret from the keychain, keychain.Get when nil.
+	keychain func(account string) (string, error)
 }
 
 func NewShellVariableResolver(env env.Env) VariableResolver {
@@ -38,7 +45,20 @@ func NewShellVariableResolver(env env.Env) VariableResolver {
 // it will resolve shell-like variable substitution anywhere in the string, including:
 // - $(command) for command substitution
 // - $VAR or ${VAR} for environment variables
+// - keychain:NAME for the secrets of the keychain of the OS
 func (r *shellVariableResolver) ResolveValue(value string) (string, error) {
+	if account, ok := strings.CutPrefix(value, KeychainPrefix); ok {
+		get := r.keychain
+		if get == nil {
+			get = keychain.Get
+		}
+		secret, err := get(account)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s from the keychain: %w", account, err)
+		}
+		return secret, nil
+	}
+
 	// Special case: lone $ is an error (backward compatibility)
 	if value == "$" {
 		return "", fmt.Errorf("invalid value format: %s", value)
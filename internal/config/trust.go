@@ -0,0 +1,259 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// TrustFileName is the name of the file of the user data directory holding
+// the trusted project configurations.
+const TrustFileName = "trusted_projects.json"
+
+// securityFields are the fields of a project configuration which run
+// commands, send data to other hosts or change what the model is told. They
+// are only applied once the user trusts their values.
+var securityFields = map[string][]string{
+	"":          {"mcp", "lsp", "permissions", "notifiers", "hooks", "databases"},
+	"options":   {"editor", "context_paths", "egress", "network", "prompts", "post_edit", "shell", "share", "remote_approval", "audit", "redaction", "dataset"},
+	"providers": {"base_url", "api_key", "api_key_command", "extra_headers", "system_prompt_prefix", "normalize"},
+}
+
+// UntrustedConfig is a project configuration whose security-relevant fields
+// are new or changed since the user last trusted it.
+type UntrustedConfig struct {
+	Path string
+	// Trusted holds the fields the user last trusted, empty the first time
+	// the configuration is loaded.
+	Trusted string
+	Current string
+	hash    string
+	fields  map[string]any
+}
+
+type trustedConfig struct {
+	Hash   string         `json:"hash"`
+	Fields map[string]any `json:"fields"`
+}
+
+// trustMu guards the trust file, the MCP servers may be reloaded while the
+// user trusts a configuration.
+var trustMu sync.Mutex
+
+func trustFile() string {
+	return filepath.Join(filepath.Dir(GlobalConfigData()), TrustFileName)
+}
+
+func readTrusted() (map[string]trustedConfig, error) {
+	trusted := make(map[string]trustedConfig)
+	data, err := os.ReadFile(trustFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &trusted); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", trustFile(), err)
+	}
+	return trusted, nil
+}
+
+// projectConfigPaths returns the configuration files of the working
+// directory, which need to be trusted.
+func projectConfigPaths(workingDir string) []string {
+	paths := ConfigPaths(workingDir)
+	return paths[len(paths)-2:]
+}
+
+// UntrustedProjectConfigs returns the project configurations of the working
+// directory whose security-relevant fields the user didn't trust yet.
+func UntrustedProjectConfigs(workingDir string) ([]UntrustedConfig, error) {
+	trustMu.Lock()
+	trusted, err := readTrusted()
+	trustMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var untrusted []UntrustedConfig
+	for _, path := range projectConfigPaths(workingDir) {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		u, err := checkTrust(trusted, path, data)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			untrusted = append(untrusted, *u)
+		}
+	}
+	return untrusted, nil
+}
+
+// TrustProjectConfigs records the current fields of the configurations as
+// trusted.
+func TrustProjectConfigs(configs []UntrustedConfig) error {
+	trustMu.Lock()
+	defer trustMu.Unlock()
+
+	trusted, err := readTrusted()
+	if err != nil {
+		return err
+	}
+	for _, c := range configs {
+		trusted[c.Path] = trustedConfig{Hash: c.hash, Fields: c.fields}
+	}
+	data, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(trustFile()), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(trustFile(), data, 0o600)
+}
+
+// checkTrust returns the configuration when its security-relevant fields
+// aren't trusted, nil when they are or when it has none.
+func checkTrust(trusted map[string]trustedConfig, path string, data []byte) (*UntrustedConfig, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	fields := extractSecurityFields(raw)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	// The keys of the maps are sorted when marshaled, so the hash doesn't
+	// depend on the formatting of the file.
+	canonical, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(canonical)
+	hash := hex.EncodeToString(sum[:])
+
+	previous, ok := trusted[path]
+	if ok && previous.Hash == hash {
+		return nil, nil
+	}
+	u := &UntrustedConfig{
+		Path:    path,
+		Current: formatSecurityFields(fields),
+		hash:    hash,
+		fields:  fields,
+	}
+	if ok {
+		u.Trusted = formatSecurityFields(previous.Fields)
+	}
+	return u, nil
+}
+
+func formatSecurityFields(fields map[string]any) string {
+	data, _ := json.MarshalIndent(fields, "", "  ")
+	return string(data) + "\n"
+}
+
+// extractSecurityFields returns the security-relevant fields of the raw
+// configuration, with the same nesting.
+func extractSecurityFields(raw map[string]any) map[string]any {
+	fields := make(map[string]any)
+	for _, key := range securityFields[""] {
+		if v, ok := raw[key]; ok {
+			fields[key] = v
+		}
+	}
+	if options, ok := raw["options"].(map[string]any); ok {
+		picked := pickFields(options, securityFields["options"])
+		if len(picked) > 0 {
+			fields["options"] = picked
+		}
+	}
+	if providers, ok := raw["providers"].(map[string]any); ok {
+		picked := make(map[string]any)
+		for id, p := range providers {
+			if provider, ok := p.(map[string]any); ok {
+				if f := pickFields(provider, securityFields["providers"]); len(f) > 0 {
+					picked[id] = f
+				}
+			}
+		}
+		if len(picked) > 0 {
+			fields["providers"] = picked
+		}
+	}
+	return fields
+}
+
+func pickFields(m map[string]any, keys []string) map[string]any {
+	picked := make(map[string]any)
+	for _, key := range keys {
+		if v, ok := m[key]; ok {
+			picked[key] = v
+		}
+	}
+	return picked
+}
+
+// withoutSecurityFields removes the security-relevant fields from the raw
+// configuration.
+func withoutSecurityFields(raw map[string]any) {
+	for _, key := range securityFields[""] {
+		delete(raw, key)
+	}
+	if options, ok := raw["options"].(map[string]any); ok {
+		for _, key := range securityFields["options"] {
+			delete(options, key)
+		}
+	}
+	if providers, ok := raw["providers"].(map[string]any); ok {
+		for _, p := range providers {
+			if provider, ok := p.(map[string]any); ok {
+				for _, key := range securityFields["providers"] {
+					delete(provider, key)
+				}
+			}
+		}
+	}
+}
+
+// trustedConfigData returns the content of the configuration file, without
+// its security-relevant fields when it is an untrusted project
+// configuration.
+func trustedConfigData(workingDir, path string, data []byte) ([]byte, error) {
+	if workingDir == "" || !slices.Contains(projectConfigPaths(workingDir), path) {
+		return data, nil
+	}
+	trustMu.Lock()
+	trusted, err := readTrusted()
+	trustMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	u, err := checkTrust(trusted, path, data)
+	if err != nil || u == nil {
+		return data, err
+	}
+
+	slog.Warn("Ignoring the untrusted fields of the project configuration, run crush trust to apply them", "path", path)
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	withoutSecurityFields(raw)
+	return json.Marshal(raw)
+}
@@ -77,6 +77,20 @@ func (m *Map[K, V]) Take(key K) (V, bool) {
 	return v, ok
 }
 
+// Update sets the value for the specified key to the value returned by fn,
+// called with the current value and whether it exists, atomically. The key is
+// deleted when fn returns false.
+func (m *Map[K, V]) Update(key K, fn func(V, bool) (V, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.inner[key]
+	if v, ok = fn(v, ok); ok {
+		m.inner[key] = v
+	} else {
+		delete(m.inner, key)
+	}
+}
+
 // Seq2 returns an iter.Seq2 that yields key-value pairs from the map.
 func (m *Map[K, V]) Seq2() iter.Seq2[K, V] {
 	dst := make(map[K]V)
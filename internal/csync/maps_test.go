@@ -169,6 +169,31 @@ func TestMap_Take_EmptyMap(t *testing.T) {
 	require.Equal(t, 0, m.Len())
 }
 
+func TestMap_Update(t *testing.T) {
+	t.Parallel()
+
+	m := NewMap[string, int]()
+	m.Update("key1", func(v int, ok bool) (int, bool) {
+		require.False(t, ok)
+		return v + 1, true
+	})
+	m.Update("key1", func(v int, ok bool) (int, bool) {
+		require.True(t, ok)
+		return v + 1, true
+	})
+
+	value, ok := m.Get("key1")
+	require.True(t, ok)
+	require.Equal(t, 2, value)
+
+	m.Update("key1", func(int, bool) (int, bool) {
+		return 0, false
+	})
+	_, ok = m.Get("key1")
+	require.False(t, ok)
+	require.Equal(t, 0, m.Len())
+}
+
 func TestMap_Take_SameKeyTwice(t *testing.T) {
 	t.Parallel()
 
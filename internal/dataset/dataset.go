@@ -0,0 +1,199 @@
+// Package dataset appends the finished turns of the agent to a JSONL file in
+// the chat formats of the fine-tuning APIs of OpenAI and Anthropic, so the
+// real usage of a team can become evaluation and fine-tuning sets.
+//
+// Each record holds the system prompt, the messages of the conversation up
+// to the last answer of the turn with its tool calls and results, and the
+// tools offered to the model. The texts are sanitized before they are
+// written: the secrets are masked and the redaction hooks applied.
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/redact"
+)
+
+// FileName is the name of the dataset in the data directory.
+const FileName = "dataset.jsonl"
+
+// Formats of the records.
+const (
+	FormatOpenAI    = "openai"
+	FormatAnthropic = "anthropic"
+)
+
+// patternMask replaces the values matching the redaction patterns.
+const patternMask = "[REDACTED:pattern]"
+
+// Hook sanitizes a text of a record before it is written, e.g. to mask the
+// names of customers. The hooks run after the secrets are masked.
+type Hook func(text string) string
+
+// Options configure a Writer.
+type Options struct {
+	// Format is FormatOpenAI or FormatAnthropic, FormatOpenAI when empty.
+	Format string
+	// Redactor masks the secrets of the texts, none are masked when nil.
+	Redactor *redact.Redactor
+	// Patterns holds regular expressions of the values masked on top of
+	// the secrets.
+	Patterns []string
+	// Hooks run on each text of the records, in order.
+	Hooks []Hook
+}
+
+// Turn is a finished turn of the agent.
+type Turn struct {
+	System string
+	// Messages are the messages of the conversation, the last one being the
+	// final answer of the turn.
+	Messages []message.Message
+	Tools    []tools.ToolInfo
+}
+
+// writeMu serializes the writes of all the writers of the process, so the
+// records of concurrent sessions don't interleave.
+var writeMu sync.Mutex
+
+// Writer appends records to a dataset file.
+type Writer struct {
+	path   string
+	format string
+	hooks  []Hook
+}
+
+// New returns the writer of the dataset at the path, or at dataset.jsonl in
+// the data directory when the path is empty.
+func New(dataDir, path string, opts Options) (*Writer, error) {
+	if path == "" {
+		path = filepath.Join(dataDir, FileName)
+	}
+	format := opts.Format
+	switch format {
+	case "":
+		format = FormatOpenAI
+	case FormatOpenAI, FormatAnthropic:
+	default:
+		return nil, fmt.Errorf("unknown dataset format %q", opts.Format)
+	}
+	w := &Writer{path: path, format: format}
+	if opts.Redactor != nil {
+		w.hooks = append(w.hooks, func(text string) string {
+			text, _ = opts.Redactor.Redact(text, false)
+			return text
+		})
+	}
+	for _, pattern := range opts.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		w.hooks = append(w.hooks, func(text string) string {
+			return re.ReplaceAllString(text, patternMask)
+		})
+	}
+	w.hooks = append(w.hooks, opts.Hooks...)
+	return w, nil
+}
+
+// Path returns the path of the dataset file.
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// Record appends the turn to the dataset.
+func (w *Writer) Record(turn Turn) error {
+	var record any
+	if w.format == FormatAnthropic {
+		record = w.anthropicRecord(turn)
+	} else {
+		record = w.openAIRecord(turn)
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+func (w *Writer) sanitize(text string) string {
+	for _, hook := range w.hooks {
+		text = hook(text)
+	}
+	return text
+}
+
+// sanitizeInput sanitizes the strings of the JSON input of a tool call,
+// keeping it valid JSON.
+func (w *Writer) sanitizeInput(input string) json.RawMessage {
+	var value any
+	if err := json.Unmarshal([]byte(input), &value); err != nil {
+		value = map[string]any{}
+	}
+	raw, err := json.Marshal(w.sanitizeValue(value))
+	if err != nil {
+		return json.RawMessage("{}")
+	}
+	return raw
+}
+
+func (w *Writer) sanitizeValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return w.sanitize(v)
+	case []any:
+		for i := range v {
+			v[i] = w.sanitizeValue(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = w.sanitizeValue(v[k])
+		}
+	}
+	return value
+}
+
+// userText returns the text of a user message with its text attachments.
+// The images are left out of the records.
+func userText(msg message.Message) string {
+	texts := []string{msg.Content().Text}
+	for _, part := range msg.BinaryContent() {
+		if part.IsText() {
+			texts = append(texts, part.Text())
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+func toolSchema(info tools.ToolInfo) map[string]any {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": info.Parameters,
+	}
+	if len(info.Required) > 0 {
+		schema["required"] = info.Required
+	}
+	return schema
+}
@@ -0,0 +1,174 @@
+package dataset
+
+import (
+	"encoding/json"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// openAIRecord is a line of the chat format of the fine-tuning API of
+// OpenAI.
+type openAIRecord struct {
+	Messages []openAIMessage `json:"messages"`
+	Tools    []openAITool    `json:"tools,omitempty"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name string `json:"name"`
+	// Arguments is the JSON input of the call as a string.
+	Arguments string `json:"arguments"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+func (w *Writer) openAIRecord(turn Turn) openAIRecord {
+	var record openAIRecord
+	if turn.System != "" {
+		record.Messages = append(record.Messages, openAIMessage{Role: "system", Content: w.sanitize(turn.System)})
+	}
+	for _, msg := range turn.Messages {
+		switch msg.Role {
+		case message.User:
+			record.Messages = append(record.Messages, openAIMessage{Role: "user", Content: w.sanitize(userText(msg))})
+		case message.Assistant:
+			out := openAIMessage{Role: "assistant", Content: w.sanitize(msg.Content().Text)}
+			for _, call := range msg.ToolCalls() {
+				out.ToolCalls = append(out.ToolCalls, openAIToolCall{
+					ID:   call.ID,
+					Type: "function",
+					Function: openAIFunctionCall{
+						Name:      call.Name,
+						Arguments: string(w.sanitizeInput(call.Input)),
+					},
+				})
+			}
+			record.Messages = append(record.Messages, out)
+		case message.Tool:
+			for _, result := range msg.ToolResults() {
+				record.Messages = append(record.Messages, openAIMessage{
+					Role:       "tool",
+					Content:    w.sanitize(result.Content),
+					ToolCallID: result.ToolCallID,
+				})
+			}
+		}
+	}
+	for _, info := range turn.Tools {
+		record.Tools = append(record.Tools, openAITool{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        info.Name,
+				Description: info.Description,
+				Parameters:  toolSchema(info),
+			},
+		})
+	}
+	return record
+}
+
+// anthropicRecord is a line of the messages format of the fine-tuning of
+// the Claude models, the tool results being content blocks of the user
+// messages.
+type anthropicRecord struct {
+	System   string             `json:"system,omitempty"`
+	Messages []anthropicMessage `json:"messages"`
+	Tools    []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+func (w *Writer) anthropicRecord(turn Turn) anthropicRecord {
+	record := anthropicRecord{System: w.sanitize(turn.System)}
+	add := func(role string, blocks []anthropicBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		// The roles alternate, the tool results and the prompts queued
+		// after them make a single user message.
+		if n := len(record.Messages); n > 0 && record.Messages[n-1].Role == role {
+			record.Messages[n-1].Content = append(record.Messages[n-1].Content, blocks...)
+			return
+		}
+		record.Messages = append(record.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	for _, msg := range turn.Messages {
+		var blocks []anthropicBlock
+		switch msg.Role {
+		case message.User:
+			add("user", []anthropicBlock{{Type: "text", Text: w.sanitize(userText(msg))}})
+		case message.Assistant:
+			if text := msg.Content().Text; text != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: w.sanitize(text)})
+			}
+			for _, call := range msg.ToolCalls() {
+				blocks = append(blocks, anthropicBlock{
+					Type:  "tool_use",
+					ID:    call.ID,
+					Name:  call.Name,
+					Input: w.sanitizeInput(call.Input),
+				})
+			}
+			add("assistant", blocks)
+		case message.Tool:
+			for _, result := range msg.ToolResults() {
+				blocks = append(blocks, anthropicBlock{
+					Type:      "tool_result",
+					ToolUseID: result.ToolCallID,
+					Content:   w.sanitize(result.Content),
+					IsError:   result.IsError,
+				})
+			}
+			add("user", blocks)
+		}
+	}
+	for _, info := range turn.Tools {
+		record.Tools = append(record.Tools, anthropicTool{
+			Name:        info.Name,
+			Description: info.Description,
+			InputSchema: toolSchema(info),
+		})
+	}
+	return record
+}
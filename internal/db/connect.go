@@ -3,21 +3,41 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
+	"os"
 	"path/filepath"
 
+	"github.com/charmbracelet/crush/internal/encryption"
 	_ "github.com/ncruces/go-sqlite3/driver"
 	_ "github.com/ncruces/go-sqlite3/embed"
+	_ "github.com/ncruces/go-sqlite3/vfs/xts"
 
 	"github.com/pressly/goose/v3"
 )
 
+// sqliteHeader starts the unencrypted databases.
+const sqliteHeader = "SQLite format 3\x00"
+
 func Connect(ctx context.Context, dataDir string) (*sql.DB, error) {
 	if dataDir == "" {
 		return nil, fmt.Errorf("data.dir is not set")
 	}
 	dbPath := filepath.Join(dataDir, "crush.db")
+	key, err := encryption.Key(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		if err := encrypt(ctx, dbPath, key); err != nil {
+			return nil, err
+		}
+		dbPath = encryptedDSN(dbPath, key)
+	}
 	// Open the SQLite database
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
@@ -38,6 +58,10 @@ func Connect(ctx context.Context, dataDir string) (*sql.DB, error) {
 		"PRAGMA cache_size = -8000;",
 		"PRAGMA synchronous = NORMAL;",
 	}
+	if key != nil {
+		// Keep the temporary files, which hold data too, out of the disk.
+		pragmas = append(pragmas, "PRAGMA temp_store = memory;")
+	}
 
 	for _, pragma := range pragmas {
 		if _, err = db.ExecContext(ctx, pragma); err != nil {
@@ -60,3 +84,57 @@ func Connect(ctx context.Context, dataDir string) (*sql.DB, error) {
 	}
 	return db, nil
 }
+
+// encryptedDSN opens the database through the AES-XTS VFS, which encrypts
+// the database and its journals.
+func encryptedDSN(path string, key []byte) string {
+	return "file:" + filepath.ToSlash(path) + "?vfs=xts&hexkey=" + hex.EncodeToString(key)
+}
+
+// encrypt replaces the database, when it isn't encrypted yet, by a copy
+// encrypted with the key. This happens once, after the data directory is
+// marked as encrypted.
+func encrypt(ctx context.Context, dbPath string, key []byte) error {
+	f, err := os.Open(dbPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	header := make([]byte, len(sqliteHeader))
+	_, err = io.ReadFull(f, header)
+	f.Close()
+	if err != nil || string(header) != sqliteHeader {
+		// Empty or already encrypted.
+		return nil
+	}
+
+	plain, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer plain.Close()
+	if _, err := plain.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+
+	tmp := dbPath + ".encrypted"
+	_ = os.Remove(tmp)
+	if _, err := plain.ExecContext(ctx, "VACUUM INTO ?", encryptedDSN(tmp, key)); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to encrypt database: %w", err)
+	}
+	if err := plain.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/encryption"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectEncryptsDatabase(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	conn, err := Connect(t.Context(), dataDir)
+	require.NoError(t, err)
+	_, err = conn.ExecContext(t.Context(), "INSERT INTO sessions (id, title, updated_at, created_at) VALUES ('s1', 'Secret title', 0, 0)")
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	_, err = encryption.Setup(dataDir, encryption.SourcePassphrase, "correct horse")
+	require.NoError(t, err)
+	conn, err = Connect(t.Context(), dataDir)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var title string
+	require.NoError(t, conn.QueryRowContext(t.Context(), "SELECT title FROM sessions WHERE id = 's1'").Scan(&title))
+	require.Equal(t, "Secret title", title)
+
+	data, err := os.ReadFile(filepath.Join(dataDir, "crush.db"))
+	require.NoError(t, err)
+	require.NotContains(t, string(data), sqliteHeader)
+	require.NotContains(t, string(data), "Secret title")
+}
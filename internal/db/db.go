@@ -39,6 +39,9 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.deleteMessageStmt, err = db.PrepareContext(ctx, deleteMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteMessage: %w", err)
 	}
+	if q.deleteOrphanSessionsStmt, err = db.PrepareContext(ctx, deleteOrphanSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanSessions: %w", err)
+	}
 	if q.deleteSessionStmt, err = db.PrepareContext(ctx, deleteSession); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteSession: %w", err)
 	}
@@ -54,6 +57,9 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getFileByPathAndSessionStmt, err = db.PrepareContext(ctx, getFileByPathAndSession); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFileByPathAndSession: %w", err)
 	}
+	if q.getLastSessionModelStmt, err = db.PrepareContext(ctx, getLastSessionModel); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLastSessionModel: %w", err)
+	}
 	if q.getMessageStmt, err = db.PrepareContext(ctx, getMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query GetMessage: %w", err)
 	}
@@ -72,12 +78,27 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.listMessagesBySessionStmt, err = db.PrepareContext(ctx, listMessagesBySession); err != nil {
 		return nil, fmt.Errorf("error preparing query ListMessagesBySession: %w", err)
 	}
+	if q.listMessagesBySessionBeforeStmt, err = db.PrepareContext(ctx, listMessagesBySessionBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMessagesBySessionBefore: %w", err)
+	}
 	if q.listNewFilesStmt, err = db.PrepareContext(ctx, listNewFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListNewFiles: %w", err)
 	}
+	if q.listSessionFilesStmt, err = db.PrepareContext(ctx, listSessionFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSessionFiles: %w", err)
+	}
 	if q.listSessionsStmt, err = db.PrepareContext(ctx, listSessions); err != nil {
 		return nil, fmt.Errorf("error preparing query ListSessions: %w", err)
 	}
+	if q.moveMessageStmt, err = db.PrepareContext(ctx, moveMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query MoveMessage: %w", err)
+	}
+	if q.recordSessionFileReadStmt, err = db.PrepareContext(ctx, recordSessionFileRead); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordSessionFileRead: %w", err)
+	}
+	if q.recordSessionFileWriteStmt, err = db.PrepareContext(ctx, recordSessionFileWrite); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordSessionFileWrite: %w", err)
+	}
 	if q.updateMessageStmt, err = db.PrepareContext(ctx, updateMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateMessage: %w", err)
 	}
@@ -114,6 +135,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteMessageStmt: %w", cerr)
 		}
 	}
+	if q.deleteOrphanSessionsStmt != nil {
+		if cerr := q.deleteOrphanSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanSessionsStmt: %w", cerr)
+		}
+	}
 	if q.deleteSessionStmt != nil {
 		if cerr := q.deleteSessionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteSessionStmt: %w", cerr)
@@ -139,6 +165,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getFileByPathAndSessionStmt: %w", cerr)
 		}
 	}
+	if q.getLastSessionModelStmt != nil {
+		if cerr := q.getLastSessionModelStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLastSessionModelStmt: %w", cerr)
+		}
+	}
 	if q.getMessageStmt != nil {
 		if cerr := q.getMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getMessageStmt: %w", cerr)
@@ -169,16 +200,41 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listMessagesBySessionStmt: %w", cerr)
 		}
 	}
+	if q.listMessagesBySessionBeforeStmt != nil {
+		if cerr := q.listMessagesBySessionBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMessagesBySessionBeforeStmt: %w", cerr)
+		}
+	}
 	if q.listNewFilesStmt != nil {
 		if cerr := q.listNewFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listNewFilesStmt: %w", cerr)
 		}
 	}
+	if q.listSessionFilesStmt != nil {
+		if cerr := q.listSessionFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSessionFilesStmt: %w", cerr)
+		}
+	}
 	if q.listSessionsStmt != nil {
 		if cerr := q.listSessionsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listSessionsStmt: %w", cerr)
 		}
 	}
+	if q.moveMessageStmt != nil {
+		if cerr := q.moveMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing moveMessageStmt: %w", cerr)
+		}
+	}
+	if q.recordSessionFileReadStmt != nil {
+		if cerr := q.recordSessionFileReadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordSessionFileReadStmt: %w", cerr)
+		}
+	}
+	if q.recordSessionFileWriteStmt != nil {
+		if cerr := q.recordSessionFileWriteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordSessionFileWriteStmt: %w", cerr)
+		}
+	}
 	if q.updateMessageStmt != nil {
 		if cerr := q.updateMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateMessageStmt: %w", cerr)
@@ -226,53 +282,67 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                          DBTX
-	tx                          *sql.Tx
-	createFileStmt              *sql.Stmt
-	createMessageStmt           *sql.Stmt
-	createSessionStmt           *sql.Stmt
-	deleteFileStmt              *sql.Stmt
-	deleteMessageStmt           *sql.Stmt
-	deleteSessionStmt           *sql.Stmt
-	deleteSessionFilesStmt      *sql.Stmt
-	deleteSessionMessagesStmt   *sql.Stmt
-	getFileStmt                 *sql.Stmt
-	getFileByPathAndSessionStmt *sql.Stmt
-	getMessageStmt              *sql.Stmt
-	getSessionByIDStmt          *sql.Stmt
-	listFilesByPathStmt         *sql.Stmt
-	listFilesBySessionStmt      *sql.Stmt
-	listLatestSessionFilesStmt  *sql.Stmt
-	listMessagesBySessionStmt   *sql.Stmt
-	listNewFilesStmt            *sql.Stmt
-	listSessionsStmt            *sql.Stmt
-	updateMessageStmt           *sql.Stmt
-	updateSessionStmt           *sql.Stmt
+	db                              DBTX
+	tx                              *sql.Tx
+	createFileStmt                  *sql.Stmt
+	createMessageStmt               *sql.Stmt
+	createSessionStmt               *sql.Stmt
+	deleteFileStmt                  *sql.Stmt
+	deleteMessageStmt               *sql.Stmt
+	deleteOrphanSessionsStmt        *sql.Stmt
+	deleteSessionStmt               *sql.Stmt
+	deleteSessionFilesStmt          *sql.Stmt
+	deleteSessionMessagesStmt       *sql.Stmt
+	getFileStmt                     *sql.Stmt
+	getFileByPathAndSessionStmt     *sql.Stmt
+	getLastSessionModelStmt         *sql.Stmt
+	getMessageStmt                  *sql.Stmt
+	getSessionByIDStmt              *sql.Stmt
+	listFilesByPathStmt             *sql.Stmt
+	listFilesBySessionStmt          *sql.Stmt
+	listLatestSessionFilesStmt      *sql.Stmt
+	listMessagesBySessionStmt       *sql.Stmt
+	listMessagesBySessionBeforeStmt *sql.Stmt
+	listNewFilesStmt                *sql.Stmt
+	listSessionFilesStmt            *sql.Stmt
+	listSessionsStmt                *sql.Stmt
+	moveMessageStmt                 *sql.Stmt
+	recordSessionFileReadStmt       *sql.Stmt
+	recordSessionFileWriteStmt      *sql.Stmt
+	updateMessageStmt               *sql.Stmt
+	updateSessionStmt               *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                          tx,
-		tx:                          tx,
-		createFileStmt:              q.createFileStmt,
-		createMessageStmt:           q.createMessageStmt,
-		createSessionStmt:           q.createSessionStmt,
-		deleteFileStmt:              q.deleteFileStmt,
-		deleteMessageStmt:           q.deleteMessageStmt,
-		deleteSessionStmt:           q.deleteSessionStmt,
-		deleteSessionFilesStmt:      q.deleteSessionFilesStmt,
-		deleteSessionMessagesStmt:   q.deleteSessionMessagesStmt,
-		getFileStmt:                 q.getFileStmt,
-		getFileByPathAndSessionStmt: q.getFileByPathAndSessionStmt,
-		getMessageStmt:              q.getMessageStmt,
-		getSessionByIDStmt:          q.getSessionByIDStmt,
-		listFilesByPathStmt:         q.listFilesByPathStmt,
-		listFilesBySessionStmt:      q.listFilesBySessionStmt,
-		listLatestSessionFilesStmt:  q.listLatestSessionFilesStmt,
-		listMessagesBySessionStmt:   q.listMessagesBySessionStmt,
-		listNewFilesStmt:            q.listNewFilesStmt,
-		listSessionsStmt:            q.listSessionsStmt,
-		updateMessageStmt:           q.updateMessageStmt,
-		updateSessionStmt:           q.updateSessionStmt,
+		db:                              tx,
+		tx:                              tx,
+		createFileStmt:                  q.createFileStmt,
+		createMessageStmt:               q.createMessageStmt,
+		createSessionStmt:               q.createSessionStmt,
+		deleteFileStmt:                  q.deleteFileStmt,
+		deleteMessageStmt:               q.deleteMessageStmt,
+		deleteOrphanSessionsStmt:        q.deleteOrphanSessionsStmt,
+		deleteSessionStmt:               q.deleteSessionStmt,
+		deleteSessionFilesStmt:          q.deleteSessionFilesStmt,
+		deleteSessionMessagesStmt:       q.deleteSessionMessagesStmt,
+		getFileStmt:                     q.getFileStmt,
+		getFileByPathAndSessionStmt:     q.getFileByPathAndSessionStmt,
+		getLastSessionModelStmt:         q.getLastSessionModelStmt,
+		getMessageStmt:                  q.getMessageStmt,
+		getSessionByIDStmt:              q.getSessionByIDStmt,
+		listFilesByPathStmt:             q.listFilesByPathStmt,
+		listFilesBySessionStmt:          q.listFilesBySessionStmt,
+		listLatestSessionFilesStmt:      q.listLatestSessionFilesStmt,
+		listMessagesBySessionStmt:       q.listMessagesBySessionStmt,
+		listMessagesBySessionBeforeStmt: q.listMessagesBySessionBeforeStmt,
+		listNewFilesStmt:                q.listNewFilesStmt,
+		listSessionFilesStmt:            q.listSessionFilesStmt,
+		listSessionsStmt:                q.listSessionsStmt,
+		moveMessageStmt:                 q.moveMessageStmt,
+		recordSessionFileReadStmt:       q.recordSessionFileReadStmt,
+		recordSessionFileWriteStmt:      q.recordSessionFileWriteStmt,
+		updateMessageStmt:               q.updateMessageStmt,
+		updateSessionStmt:               q.updateSessionStmt,
 	}
 }
@@ -79,6 +79,21 @@ func (q *Queries) DeleteSessionMessages(ctx context.Context, sessionID string) e
 	return err
 }
 
+const getLastSessionModel = `-- name: GetLastSessionModel :one
+SELECT model
+FROM messages
+WHERE session_id = ? AND role = 'assistant' AND model IS NOT NULL AND model != ''
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastSessionModel(ctx context.Context, sessionID string) (sql.NullString, error) {
+	row := q.queryRow(ctx, q.getLastSessionModelStmt, getLastSessionModel, sessionID)
+	var model sql.NullString
+	err := row.Scan(&model)
+	return model, err
+}
+
 const getMessage = `-- name: GetMessage :one
 SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider
 FROM messages
@@ -142,6 +157,70 @@ func (q *Queries) ListMessagesBySession(ctx context.Context, sessionID string) (
 	return items, nil
 }
 
+const listMessagesBySessionBefore = `-- name: ListMessagesBySessionBefore :many
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider
+FROM messages
+WHERE session_id = ?
+  AND rowid < COALESCE((SELECT rowid FROM messages WHERE id = ?), 9223372036854775807)
+ORDER BY rowid DESC
+LIMIT ?
+`
+
+type ListMessagesBySessionBeforeParams struct {
+	SessionID string `json:"session_id"`
+	ID        string `json:"id"`
+	Limit     int64  `json:"limit"`
+}
+
+func (q *Queries) ListMessagesBySessionBefore(ctx context.Context, arg ListMessagesBySessionBeforeParams) ([]Message, error) {
+	rows, err := q.query(ctx, q.listMessagesBySessionBeforeStmt, listMessagesBySessionBefore, arg.SessionID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Message{}
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.Role,
+			&i.Parts,
+			&i.Model,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FinishedAt,
+			&i.Provider,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveMessage = `-- name: MoveMessage :exec
+UPDATE messages
+SET session_id = ?
+WHERE id = ?
+`
+
+type MoveMessageParams struct {
+	SessionID string `json:"session_id"`
+	ID        string `json:"id"`
+}
+
+func (q *Queries) MoveMessage(ctx context.Context, arg MoveMessageParams) error {
+	_, err := q.exec(ctx, q.moveMessageStmt, moveMessage, arg.SessionID, arg.ID)
+	return err
+}
+
 const updateMessage = `-- name: UpdateMessage :exec
 UPDATE messages
 SET
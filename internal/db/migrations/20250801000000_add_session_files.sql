@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Files read or modified in each session
+CREATE TABLE IF NOT EXISTS session_files (
+    session_id TEXT NOT NULL,
+    path TEXT NOT NULL,
+    read_count INTEGER NOT NULL DEFAULT 0 CHECK (read_count >= 0),
+    write_count INTEGER NOT NULL DEFAULT 0 CHECK (write_count >= 0),
+    last_line INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    updated_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    PRIMARY KEY (session_id, path),
+    FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS session_files;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Messages moved to another session, like the branch archiving the messages
+-- discarded by a rewind, are counted in their new session
+CREATE TRIGGER IF NOT EXISTS update_session_message_count_on_move
+AFTER UPDATE OF session_id ON messages
+WHEN old.session_id != new.session_id
+BEGIN
+UPDATE sessions SET
+    message_count = message_count - 1
+WHERE id = old.session_id;
+UPDATE sessions SET
+    message_count = message_count + 1
+WHERE id = new.session_id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_session_message_count_on_move;
+-- +goose StatementEnd
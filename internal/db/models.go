@@ -42,3 +42,13 @@ type Session struct {
 	CreatedAt        int64          `json:"created_at"`
 	SummaryMessageID sql.NullString `json:"summary_message_id"`
 }
+
+type SessionFile struct {
+	SessionID  string `json:"session_id"`
+	Path       string `json:"path"`
+	ReadCount  int64  `json:"read_count"`
+	WriteCount int64  `json:"write_count"`
+	LastLine   int64  `json:"last_line"`
+	CreatedAt  int64  `json:"created_at"`
+	UpdatedAt  int64  `json:"updated_at"`
+}
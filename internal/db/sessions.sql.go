@@ -72,6 +72,20 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 	return i, err
 }
 
+const deleteOrphanSessions = `-- name: DeleteOrphanSessions :execrows
+DELETE FROM sessions
+WHERE parent_session_id IS NOT NULL
+  AND parent_session_id NOT IN (SELECT id FROM sessions)
+`
+
+func (q *Queries) DeleteOrphanSessions(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteOrphanSessionsStmt, deleteOrphanSessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteSession = `-- name: DeleteSession :exec
 DELETE FROM sessions
 WHERE id = ?
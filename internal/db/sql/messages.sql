@@ -3,12 +3,27 @@ SELECT *
 FROM messages
 WHERE id = ? LIMIT 1;
 
+-- name: GetLastSessionModel :one
+SELECT model
+FROM messages
+WHERE session_id = ? AND role = 'assistant' AND model IS NOT NULL AND model != ''
+ORDER BY created_at DESC
+LIMIT 1;
+
 -- name: ListMessagesBySession :many
 SELECT *
 FROM messages
 WHERE session_id = ?
 ORDER BY created_at ASC;
 
+-- name: ListMessagesBySessionBefore :many
+SELECT *
+FROM messages
+WHERE session_id = ?
+  AND rowid < COALESCE((SELECT rowid FROM messages WHERE id = ?), 9223372036854775807)
+ORDER BY rowid DESC
+LIMIT ?;
+
 -- name: CreateMessage :one
 INSERT INTO messages (
     id,
@@ -32,6 +47,10 @@ SET
     updated_at = strftime('%s', 'now')
 WHERE id = ?;
 
+-- name: MoveMessage :exec
+UPDATE messages
+SET session_id = ?
+WHERE id = ?;
 
 -- name: DeleteMessage :exec
 DELETE FROM messages
@@ -0,0 +1,37 @@
+-- name: RecordSessionFileRead :exec
+INSERT INTO session_files (
+    session_id,
+    path,
+    read_count,
+    last_line,
+    created_at,
+    updated_at
+) VALUES (
+    ?, ?, 1, ?, strftime('%s', 'now'), strftime('%s', 'now')
+)
+ON CONFLICT (session_id, path) DO UPDATE SET
+    read_count = read_count + 1,
+    last_line = excluded.last_line,
+    updated_at = strftime('%s', 'now');
+
+-- name: RecordSessionFileWrite :exec
+INSERT INTO session_files (
+    session_id,
+    path,
+    write_count,
+    last_line,
+    created_at,
+    updated_at
+) VALUES (
+    ?, ?, 1, ?, strftime('%s', 'now'), strftime('%s', 'now')
+)
+ON CONFLICT (session_id, path) DO UPDATE SET
+    write_count = write_count + 1,
+    last_line = excluded.last_line,
+    updated_at = strftime('%s', 'now');
+
+-- name: ListSessionFiles :many
+SELECT *
+FROM session_files
+WHERE session_id = ?
+ORDER BY updated_at DESC, path ASC;
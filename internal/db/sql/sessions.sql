@@ -49,3 +49,8 @@ RETURNING *;
 -- name: DeleteSession :exec
 DELETE FROM sessions
 WHERE id = ?;
+
+-- name: DeleteOrphanSessions :execrows
+DELETE FROM sessions
+WHERE parent_session_id IS NOT NULL
+  AND parent_session_id NOT IN (SELECT id FROM sessions);
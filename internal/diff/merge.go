@@ -0,0 +1,158 @@
+package diff
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aymanbagabas/go-udiff"
+)
+
+// hunk replaces the lines start to end of the base.
+type hunk struct {
+	start, end int
+	lines      []string
+}
+
+// Merge merges the changes made to base in ours and in theirs, line by line.
+// The lines changed differently on both sides are kept from both between
+// conflict markers with the labels, and their number is returned.
+func Merge(base, ours, theirs, oursLabel, theirsLabel string) (string, int) {
+	baseLines := splitLines(base)
+	oursHunks := lineHunks(baseLines, splitLines(ours))
+	theirsHunks := lineHunks(baseLines, splitLines(theirs))
+
+	var sb strings.Builder
+	conflicts := 0
+	pos := 0
+	for len(oursHunks) > 0 || len(theirsHunks) > 0 {
+		// The hunks of both sides touching the first one are merged
+		// together.
+		var first hunk
+		if len(theirsHunks) == 0 || len(oursHunks) > 0 && oursHunks[0].start <= theirsHunks[0].start {
+			first = oursHunks[0]
+		} else {
+			first = theirsHunks[0]
+		}
+		start, end := first.start, first.end
+		var o, t int
+		for grown := true; grown; {
+			grown = false
+			if o < len(oursHunks) && overlaps(oursHunks[o], start, end) {
+				end = max(end, oursHunks[o].end)
+				o, grown = o+1, true
+			}
+			if t < len(theirsHunks) && overlaps(theirsHunks[t], start, end) {
+				end = max(end, theirsHunks[t].end)
+				t, grown = t+1, true
+			}
+		}
+		writeLines(&sb, baseLines[pos:start])
+		oursText := applyHunks(baseLines, start, end, oursHunks[:o])
+		theirsText := applyHunks(baseLines, start, end, theirsHunks[:t])
+		switch {
+		case t == 0 || oursText == theirsText:
+			sb.WriteString(oursText)
+		case o == 0:
+			sb.WriteString(theirsText)
+		default:
+			conflicts++
+			sb.WriteString("<<<<<<< " + oursLabel + "\n")
+			sb.WriteString(withNewline(oursText))
+			sb.WriteString("=======\n")
+			sb.WriteString(withNewline(theirsText))
+			sb.WriteString(">>>>>>> " + theirsLabel + "\n")
+		}
+		pos = end
+		oursHunks, theirsHunks = oursHunks[o:], theirsHunks[t:]
+	}
+	writeLines(&sb, baseLines[pos:])
+	return sb.String(), conflicts
+}
+
+// overlaps reports whether the hunk changes lines of the region, or inserts
+// lines where the region starts.
+func overlaps(h hunk, start, end int) bool {
+	return h.start < end || h.start == start
+}
+
+// applyHunks returns the lines start to end of the base with the changes of
+// the hunks.
+func applyHunks(base []string, start, end int, hunks []hunk) string {
+	var sb strings.Builder
+	pos := start
+	for _, h := range hunks {
+		writeLines(&sb, base[pos:h.start])
+		writeLines(&sb, h.lines)
+		pos = h.end
+	}
+	writeLines(&sb, base[pos:end])
+	return sb.String()
+}
+
+// lineHunks diffs the lines, each distinct line being mapped to a rune so
+// the diff of the strings is a diff of the lines.
+func lineHunks(before, after []string) []hunk {
+	ids := make(map[string]rune)
+	encode := func(lines []string) string {
+		var sb strings.Builder
+		for _, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = lineRune(len(ids))
+				ids[line] = id
+			}
+			sb.WriteRune(id)
+		}
+		return sb.String()
+	}
+	b, a := encode(before), encode(after)
+
+	var hunks []hunk
+	// shift is how many bytes the edits before moved the rest of after.
+	shift := 0
+	for _, edit := range udiff.Strings(b, a) {
+		start := utf8.RuneCountInString(b[:edit.Start])
+		h := hunk{
+			start: start,
+			end:   start + utf8.RuneCountInString(b[edit.Start:edit.End]),
+		}
+		if edit.New != "" {
+			offset := utf8.RuneCountInString(a[:edit.Start+shift])
+			h.lines = after[offset : offset+utf8.RuneCountInString(edit.New)]
+		}
+		shift += len(edit.New) - (edit.End - edit.Start)
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
+
+// lineRune returns a distinct valid rune for each line number.
+func lineRune(n int) rune {
+	r := rune(n + 1)
+	if r >= 0xD800 {
+		// Skip the surrogates, which aren't valid runes.
+		r += 0x800
+	}
+	return r
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func writeLines(sb *strings.Builder, lines []string) {
+	for _, line := range lines {
+		sb.WriteString(line)
+	}
+}
+
+func withNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
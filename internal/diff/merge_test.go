@@ -0,0 +1,93 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	base := "one\ntwo\nthree\nfour\nfive\n"
+	tests := []struct {
+		name      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts int
+	}{
+		{
+			name:   "unchanged",
+			ours:   base,
+			theirs: base,
+			want:   base,
+		},
+		{
+			name:   "separate changes",
+			ours:   "one\nTWO\nthree\nfour\nfive\n",
+			theirs: "one\ntwo\nthree\nfour\nFIVE\nsix\n",
+			want:   "one\nTWO\nthree\nfour\nFIVE\nsix\n",
+		},
+		{
+			name:   "same change",
+			ours:   "one\ntwo\n3\nfour\nfive\n",
+			theirs: "one\ntwo\n3\nfour\nfive\n",
+			want:   "one\ntwo\n3\nfour\nfive\n",
+		},
+		{
+			name:      "conflict",
+			ours:      "one\ntwo\nthree (ours)\nfour\nfive\n",
+			theirs:    "zero\none\ntwo\nthree (theirs)\nfour\n",
+			want:      "zero\none\ntwo\n<<<<<<< ours\nthree (ours)\n=======\nthree (theirs)\n>>>>>>> theirs\nfour\n",
+			conflicts: 1,
+		},
+		{
+			name:      "insertions at the same line",
+			ours:      "one\ntwo\nours\nthree\nfour\nfive\n",
+			theirs:    "one\ntwo\ntheirs\nthree\nfour\nfive\n",
+			want:      "one\ntwo\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\nthree\nfour\nfive\n",
+			conflicts: 1,
+		},
+		{
+			name:   "no final newline",
+			ours:   "one\ntwo\nthree\nfour\nfive",
+			theirs: "ONE\ntwo\nthree\nfour\nfive\n",
+			want:   "ONE\ntwo\nthree\nfour\nfive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			merged, conflicts := Merge(base, tt.ours, tt.theirs, "ours", "theirs")
+			require.Equal(t, tt.want, merged)
+			require.Equal(t, tt.conflicts, conflicts)
+		})
+	}
+}
+
+func TestMergeManyLines(t *testing.T) {
+	t.Parallel()
+
+	// More distinct lines than ASCII runes.
+	var base, ours, theirs string
+	for i := range 300 {
+		line := string(rune('a'+i%26)) + string(rune('0'+i/26)) + "\n"
+		base += line
+		switch i {
+		case 10:
+			ours += "ours\n"
+		case 250:
+			theirs += line + "theirs\n"
+			ours += line
+			continue
+		default:
+			ours += line
+		}
+		theirs += line
+	}
+	merged, conflicts := Merge(base, ours, theirs, "ours", "theirs")
+	require.Zero(t, conflicts)
+	require.Contains(t, merged, "j0\nours\nl0\n")
+	require.Contains(t, merged, "q9\ntheirs\nr9\n")
+}
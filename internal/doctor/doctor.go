@@ -0,0 +1,409 @@
+// Package doctor checks the providers, language servers and MCP servers of
+// the configuration, reporting what fails with how to fix it before a task
+// runs into a dead credential or a missing server.
+package doctor
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/network"
+	"github.com/charmbracelet/crush/internal/symbols"
+)
+
+const (
+	// pingTimeout bounds the check of a provider, its retries included.
+	pingTimeout = 30 * time.Second
+	// slowLatency is the time past which a provider is reported as slow.
+	slowLatency = 10 * time.Second
+	// mcpTimeout bounds the request checking an HTTP or SSE MCP server.
+	mcpTimeout = 10 * time.Second
+)
+
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusWarning Status = "warning"
+	StatusFailed  Status = "failed"
+)
+
+// Kinds of the checked components.
+const (
+	KindProvider = "provider"
+	KindLSP      = "lsp"
+	KindMCP      = "mcp"
+	KindSymbols  = "symbols"
+)
+
+// Check is the result of the check of a provider and model, a language
+// server or an MCP server.
+type Check struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Fix suggests how to fix what failed.
+	Fix       string `json:"fix,omitempty"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+}
+
+// Options gives the state of the running servers, which are checked
+// instead of their configuration. Without them the commands of the servers
+// are looked up and the HTTP ones requested.
+type Options struct {
+	LSPClients map[string]*lsp.Client
+	MCPStates  map[string]agent.MCPClientInfo
+}
+
+// Count returns how many of the checks have the status.
+func Count(checks []Check, status Status) int {
+	n := 0
+	for _, c := range checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Run checks the selected models and the other enabled providers with a
+// request each, then the language servers and MCP servers, all at once.
+// The checks are returned in that order.
+func Run(ctx context.Context, cfg *config.Config, opts Options) []Check {
+	checks := providerChecks(cfg)
+	checks = append(checks, lspChecks(cfg, opts.LSPClients)...)
+	checks = append(checks, mcpChecks(cfg, opts.MCPStates)...)
+	checks = append(checks, done(symbolsCheck()))
+
+	results := make([]Check, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check(ctx)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+type checkFunc func(ctx context.Context) Check
+
+// symbolsCheck tells which parser the symbol tools and the repository map
+// use, the heuristic of the builds without cgo being less reliable.
+func symbolsCheck() Check {
+	c := Check{Kind: KindSymbols, Name: symbols.Backend, Status: StatusOK, Detail: "declarations parsed"}
+	if !symbols.Precise() {
+		c.Status = StatusWarning
+		c.Detail = "declarations recognized line by line"
+		c.Fix = "Build crush with CGO_ENABLED=1 to parse the files with tree-sitter"
+	}
+	return c
+}
+
+// done returns the check already done.
+func done(c Check) checkFunc {
+	return func(context.Context) Check { return c }
+}
+
+// providerChecks checks the large and small models, then the enabled
+// providers which neither uses with their cheapest model.
+func providerChecks(cfg *config.Config) []checkFunc {
+	var checks []checkFunc
+	type target struct {
+		provider config.ProviderConfig
+		model    catwalk.Model
+		uses     []string
+	}
+	var targets []*target
+	used := make(map[string]bool)
+	for _, tp := range []config.SelectedModelType{config.SelectedModelTypeLarge, config.SelectedModelTypeSmall} {
+		selected, ok := cfg.Models[tp]
+		if !ok {
+			continue
+		}
+		name := selected.Provider + "/" + selected.Model
+		p, ok := cfg.Providers.Get(selected.Provider)
+		if !ok {
+			checks = append(checks, done(Check{
+				Kind:   KindProvider,
+				Name:   name,
+				Status: StatusFailed,
+				Detail: fmt.Sprintf("the %s model uses the unknown provider %s", tp, selected.Provider),
+				Fix:    "Configure the provider, or pick another model with the Switch Model command",
+			}))
+			continue
+		}
+		used[p.ID] = true
+		if p.Disable {
+			checks = append(checks, done(Check{
+				Kind:   KindProvider,
+				Name:   name,
+				Status: StatusFailed,
+				Detail: fmt.Sprintf("the %s model uses the disabled provider %s", tp, p.ID),
+				Fix:    fmt.Sprintf("Set providers.%s.disable to false, or pick another model with the Switch Model command", p.ID),
+			}))
+			continue
+		}
+		model := cfg.GetModel(p.ID, selected.Model)
+		if model == nil {
+			checks = append(checks, done(Check{
+				Kind:   KindProvider,
+				Name:   name,
+				Status: StatusFailed,
+				Detail: fmt.Sprintf("%s doesn't offer the %s model", p.ID, tp),
+				Fix:    fmt.Sprintf("Pick another model with the Switch Model command, or add it to providers.%s.models", p.ID),
+			}))
+			continue
+		}
+		if i := slices.IndexFunc(targets, func(t *target) bool { return t.provider.ID == p.ID && t.model.ID == model.ID }); i >= 0 {
+			targets[i].uses = append(targets[i].uses, string(tp))
+			continue
+		}
+		targets = append(targets, &target{provider: p, model: *model, uses: []string{string(tp)}})
+	}
+
+	providers := cfg.EnabledProviders()
+	slices.SortFunc(providers, func(a, b config.ProviderConfig) int { return strings.Compare(a.ID, b.ID) })
+	for _, p := range providers {
+		if used[p.ID] {
+			continue
+		}
+		if len(p.Models) == 0 {
+			checks = append(checks, done(Check{
+				Kind:   KindProvider,
+				Name:   p.ID,
+				Status: StatusWarning,
+				Detail: "the provider has no models",
+				Fix:    fmt.Sprintf("Add the models of the provider to providers.%s.models", p.ID),
+			}))
+			continue
+		}
+		cheapest := slices.MinFunc(p.Models, func(a, b catwalk.Model) int {
+			return cmp.Compare(a.CostPer1MIn+a.CostPer1MOut, b.CostPer1MIn+b.CostPer1MOut)
+		})
+		targets = append(targets, &target{provider: p, model: cheapest})
+	}
+
+	for _, t := range targets {
+		checks = append(checks, func(ctx context.Context) Check {
+			ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+			defer cancel()
+			latency, err := provider.Ping(ctx, t.provider, t.model)
+			c := checkPing(t.provider, latency, err)
+			c.Name = t.provider.ID + "/" + t.model.ID
+			if len(t.uses) > 0 && c.Status == StatusOK {
+				c.Detail += fmt.Sprintf(" (%s model)", strings.Join(t.uses, " and "))
+			}
+			return c
+		})
+	}
+	return checks
+}
+
+// checkPing tells what the answer to the ping of the provider means.
+func checkPing(p config.ProviderConfig, latency time.Duration, err error) Check {
+	c := Check{Kind: KindProvider, Status: StatusOK, LatencyMs: latency.Milliseconds()}
+	if err == nil {
+		c.Detail = "answered in " + latency.Round(10*time.Millisecond).String()
+		if latency > slowLatency {
+			c.Status = StatusWarning
+			c.Fix = "The provider is slow to answer, check the network or pick a model or region closer to you"
+		}
+		return c
+	}
+	c.Status = StatusFailed
+	c.Detail = err.Error()
+	var overloaded *provider.OverloadedError
+	code := provider.StatusCode(err)
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden || strings.Contains(err.Error(), "API key"):
+		c.Fix = apiKeyFix(p)
+	case code == http.StatusNotFound:
+		c.Fix = fmt.Sprintf("Check the base_url of providers.%s, and that the model is still offered", p.ID)
+	case code == http.StatusTooManyRequests:
+		c.Status = StatusWarning
+		c.Fix = "The rate limit or the quota of the account is reached, check its plan and billing"
+	case code >= http.StatusInternalServerError || errors.As(err, &overloaded):
+		c.Status = StatusWarning
+		c.Fix = "The provider is having trouble, check its status page and retry later"
+	case code == http.StatusBadRequest:
+		c.Fix = fmt.Sprintf("The request was rejected, check the model and the extra_body and extra_headers of providers.%s", p.ID)
+	case errors.Is(err, context.DeadlineExceeded):
+		c.Detail = fmt.Sprintf("no answer in %s", pingTimeout)
+		c.Fix = "Check the network, options.network.proxy and the base_url of the provider"
+	case code == 0:
+		c.Fix = "Check the network, options.network.proxy and the egress allow list"
+	}
+	return c
+}
+
+// apiKeyFix tells where the key the provider rejected comes from.
+func apiKeyFix(p config.ProviderConfig) string {
+	switch {
+	case p.APIKeyCommand != "":
+		return fmt.Sprintf("Check that the api_key_command of providers.%s prints a valid key", p.ID)
+	case strings.HasPrefix(p.APIKey, "$"):
+		return fmt.Sprintf("Check that %s holds a valid API key for %s", strings.Trim(p.APIKey, "${}"), p.ID)
+	case strings.HasPrefix(p.APIKey, "keychain:"):
+		return fmt.Sprintf("Check the API key of %s in the keychain, or set it again when crush asks", p.ID)
+	}
+	return fmt.Sprintf("Check the api_key of providers.%s", p.ID)
+}
+
+// lspChecks checks the state of the running language servers, and that the
+// commands of the others can be found.
+func lspChecks(cfg *config.Config, clients map[string]*lsp.Client) []checkFunc {
+	var checks []checkFunc
+	for _, l := range cfg.LSP.Sorted() {
+		if l.LSP.Disabled {
+			continue
+		}
+		c := Check{Kind: KindLSP, Name: l.Name, Status: StatusOK}
+		if client, ok := clients[l.Name]; ok {
+			switch client.GetServerState() {
+			case lsp.StateReady:
+				c.Detail = "ready"
+			case lsp.StateStarting:
+				c.Status = StatusWarning
+				c.Detail = "still starting"
+				c.Fix = "Large projects take a while to load, check again in a moment"
+			case lsp.StateError:
+				c.Status = StatusFailed
+				c.Detail = "the server failed"
+				c.Fix = fmt.Sprintf("Check the command and args of lsp.%s, and the logs with crush logs", l.Name)
+			}
+		} else if path, err := exec.LookPath(l.LSP.Command); err != nil {
+			c.Status = StatusFailed
+			c.Detail = fmt.Sprintf("%s isn't installed", l.LSP.Command)
+			c.Fix = fmt.Sprintf("Install it or fix lsp.%s.command, crush lsp setup installs the common servers", l.Name)
+		} else {
+			c.Detail = path
+		}
+		checks = append(checks, done(c))
+	}
+	return checks
+}
+
+// mcpChecks checks the state of the running MCP servers, that the commands
+// of the other stdio servers can be found and that the other HTTP and SSE
+// servers answer.
+func mcpChecks(cfg *config.Config, states map[string]agent.MCPClientInfo) []checkFunc {
+	var checks []checkFunc
+	for _, m := range cfg.MCP.Sorted() {
+		if m.MCP.Disabled {
+			continue
+		}
+		if info, ok := states[m.Name]; ok {
+			checks = append(checks, done(mcpStateCheck(m.Name, info)))
+			continue
+		}
+		if m.MCP.Type == config.MCPSse || m.MCP.Type == config.MCPHttp {
+			checks = append(checks, func(ctx context.Context) Check {
+				return checkMCPURL(ctx, m.Name, m.MCP)
+			})
+			continue
+		}
+		c := Check{Kind: KindMCP, Name: m.Name, Status: StatusOK}
+		if path, err := exec.LookPath(m.MCP.Command); err != nil {
+			c.Status = StatusFailed
+			c.Detail = fmt.Sprintf("%s isn't installed", m.MCP.Command)
+			c.Fix = fmt.Sprintf("Install it or fix mcp.%s.command", m.Name)
+		} else {
+			c.Detail = path
+		}
+		checks = append(checks, done(c))
+	}
+	return checks
+}
+
+func mcpStateCheck(name string, info agent.MCPClientInfo) Check {
+	c := Check{Kind: KindMCP, Name: name, Status: StatusOK, LatencyMs: info.Latency.Milliseconds()}
+	switch info.State {
+	case agent.MCPStateConnected:
+		c.Detail = fmt.Sprintf("connected, %d tools", info.ToolCount)
+	case agent.MCPStateStarting:
+		c.Status = StatusWarning
+		c.Detail = "still starting"
+		c.Fix = "Check again in a moment"
+	case agent.MCPStateAuthorizing:
+		c.Status = StatusWarning
+		c.Detail = "waiting for the authorization"
+		c.Fix = "Finish the authorization in the browser"
+	case agent.MCPStateError:
+		c.Status = StatusFailed
+		c.Detail = "failed"
+		if info.Error != nil {
+			c.Detail = info.Error.Error()
+		}
+		c.Fix = fmt.Sprintf("Check the configuration of mcp.%s, then restart it from the MCP Servers dialog", name)
+	default:
+		c.Detail = info.State.String()
+	}
+	return c
+}
+
+// checkMCPURL requests the URL of the server. Servers answer requests which
+// aren't a session of their own with all sorts of errors, only the ones
+// telling the URL or the credentials are wrong are reported.
+func checkMCPURL(ctx context.Context, name string, m config.MCPConfig) Check {
+	c := Check{Kind: KindMCP, Name: name, Status: StatusOK}
+	ctx, cancel := context.WithTimeout(ctx, mcpTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.URL, nil)
+	if err != nil {
+		c.Status = StatusFailed
+		c.Detail = err.Error()
+		c.Fix = fmt.Sprintf("Fix mcp.%s.url", name)
+		return c
+	}
+	for k, v := range m.ResolvedHeaders() {
+		req.Header.Set(k, v)
+	}
+	client := &http.Client{Transport: network.Transport()}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		c.Status = StatusFailed
+		c.Detail = err.Error()
+		c.Fix = fmt.Sprintf("Check that the server is running, the network and mcp.%s.url", name)
+		return c
+	}
+	resp.Body.Close()
+	c.LatencyMs = time.Since(start).Milliseconds()
+	c.Detail = resp.Status
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		if m.OAuth != nil {
+			c.Status = StatusWarning
+			c.Fix = "The server is authorized in the browser when crush starts"
+		} else {
+			c.Status = StatusFailed
+			c.Fix = fmt.Sprintf("Check the credentials in mcp.%s.headers, or set mcp.%s.oauth", name, name)
+		}
+	case resp.StatusCode == http.StatusNotFound:
+		c.Status = StatusFailed
+		c.Fix = fmt.Sprintf("Check the path of mcp.%s.url", name)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		c.Status = StatusWarning
+		c.Fix = "The server is having trouble, retry later"
+	default:
+		c.Detail = "reachable"
+	}
+	return c
+}
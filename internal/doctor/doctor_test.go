@@ -0,0 +1,141 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/symbols"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPing(t *testing.T) {
+	t.Parallel()
+
+	p := config.ProviderConfig{ID: "openai", APIKey: "$OPENAI_API_KEY"}
+	apiErr := func(code int) error {
+		return fmt.Errorf("request failed: %w", &openai.Error{StatusCode: code})
+	}
+	tests := []struct {
+		name    string
+		latency time.Duration
+		err     error
+		status  Status
+		fix     string
+	}{
+		{"answered", time.Second, nil, StatusOK, ""},
+		{"slow", 12 * time.Second, nil, StatusWarning, "The provider is slow to answer, check the network or pick a model or region closer to you"},
+		{"unauthorized", 0, apiErr(http.StatusUnauthorized), StatusFailed, "Check that OPENAI_API_KEY holds a valid API key for openai"},
+		{"not found", 0, apiErr(http.StatusNotFound), StatusFailed, "Check the base_url of providers.openai, and that the model is still offered"},
+		{"rate limited", 0, apiErr(http.StatusTooManyRequests), StatusWarning, "The rate limit or the quota of the account is reached, check its plan and billing"},
+		{"server error", 0, apiErr(http.StatusBadGateway), StatusWarning, "The provider is having trouble, check its status page and retry later"},
+		{"timeout", 0, context.DeadlineExceeded, StatusFailed, "Check the network, options.network.proxy and the base_url of the provider"},
+		{"network", 0, errors.New("dial tcp: connection refused"), StatusFailed, "Check the network, options.network.proxy and the egress allow list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := checkPing(p, tt.latency, tt.err)
+			require.Equal(t, tt.status, c.Status)
+			require.Equal(t, tt.fix, c.Fix)
+		})
+	}
+}
+
+func TestAPIKeyFix(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Check that the api_key_command of providers.work prints a valid key",
+		apiKeyFix(config.ProviderConfig{ID: "work", APIKeyCommand: "vault read key"}))
+	require.Equal(t, "Check that ANTHROPIC_API_KEY holds a valid API key for anthropic",
+		apiKeyFix(config.ProviderConfig{ID: "anthropic", APIKey: "${ANTHROPIC_API_KEY}"}))
+	require.Equal(t, "Check the api_key of providers.local",
+		apiKeyFix(config.ProviderConfig{ID: "local", APIKey: "sk-123"}))
+}
+
+func TestLSPChecks(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{LSP: config.LSPs{
+		"missing":  {Command: "crush-doctor-missing-server"},
+		"disabled": {Command: "crush-doctor-missing-server", Disabled: true},
+	}}
+	var checks []Check
+	for _, check := range lspChecks(cfg, nil) {
+		checks = append(checks, check(t.Context()))
+	}
+	require.Len(t, checks, 1)
+	require.Equal(t, "missing", checks[0].Name)
+	require.Equal(t, StatusFailed, checks[0].Status)
+	require.Equal(t, "crush-doctor-missing-server isn't installed", checks[0].Detail)
+}
+
+func TestMCPChecks(t *testing.T) {
+	t.Parallel()
+
+	servers := map[int]*httptest.Server{}
+	for _, code := range []int{http.StatusMethodNotAllowed, http.StatusUnauthorized, http.StatusNotFound} {
+		servers[code] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}))
+		t.Cleanup(servers[code].Close)
+	}
+	cfg := &config.Config{MCP: config.MCPs{
+		"a-running":  {Type: config.MCPStdio, Command: "crush-doctor-missing-server"},
+		"b-missing":  {Type: config.MCPStdio, Command: "crush-doctor-missing-server"},
+		"c-http":     {Type: config.MCPHttp, URL: servers[http.StatusMethodNotAllowed].URL},
+		"d-secret":   {Type: config.MCPHttp, URL: servers[http.StatusUnauthorized].URL},
+		"e-oauth":    {Type: config.MCPSse, URL: servers[http.StatusUnauthorized].URL, OAuth: &config.MCPOAuthConfig{}},
+		"f-wrongurl": {Type: config.MCPHttp, URL: servers[http.StatusNotFound].URL},
+	}}
+	states := map[string]agent.MCPClientInfo{
+		"a-running": {State: agent.MCPStateConnected, ToolCount: 3},
+	}
+
+	var checks []Check
+	for _, check := range mcpChecks(cfg, states) {
+		checks = append(checks, check(t.Context()))
+	}
+	require.Len(t, checks, 6)
+	statuses := make(map[string]Status)
+	for _, c := range checks {
+		statuses[c.Name] = c.Status
+	}
+	require.Equal(t, map[string]Status{
+		"a-running":  StatusOK,
+		"b-missing":  StatusFailed,
+		"c-http":     StatusOK,
+		"d-secret":   StatusFailed,
+		"e-oauth":    StatusWarning,
+		"f-wrongurl": StatusFailed,
+	}, statuses)
+	require.Equal(t, "connected, 3 tools", checks[0].Detail)
+}
+
+func TestCount(t *testing.T) {
+	t.Parallel()
+
+	checks := []Check{{Status: StatusOK}, {Status: StatusFailed}, {Status: StatusWarning}, {Status: StatusFailed}}
+	require.Equal(t, 2, Count(checks, StatusFailed))
+	require.Equal(t, 1, Count(checks, StatusOK))
+}
+
+func TestSymbolsCheck(t *testing.T) {
+	t.Parallel()
+
+	c := symbolsCheck()
+	require.Equal(t, symbols.Backend, c.Name)
+	if symbols.Precise() {
+		require.Equal(t, StatusOK, c.Status)
+	} else {
+		require.Equal(t, StatusWarning, c.Status)
+		require.NotEmpty(t, c.Fix)
+	}
+}
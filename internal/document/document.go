@@ -0,0 +1,168 @@
+// Package document converts PDF, DOCX and XLSX documents to Markdown
+// locally, split in chunks citing where they come from in the document, so
+// they can be attached to the prompts of any model as text.
+package document
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+const (
+	// MIMEType is the MIME type of the converted documents.
+	MIMEType = "text/markdown"
+	// ChunkSize is the size, in bytes, of the chunks of a document.
+	ChunkSize = 4000
+	// DefaultMaxSize bounds the converted text of a document attached when
+	// the context window of the model is unknown.
+	DefaultMaxSize = 100_000
+)
+
+// Extensions are the extensions of the documents that can be converted.
+var Extensions = []string{".pdf", ".docx", ".xlsx"}
+
+// ErrNoText is returned for the documents without text to extract, like
+// scanned PDFs.
+var ErrNoText = errors.New("no text found in the document")
+
+// Section is a part of a document, like a page or a sheet.
+type Section struct {
+	// Ref locates the section in the document, like "page 3".
+	Ref  string
+	Text string
+}
+
+// Chunk is a part of a section small enough to be attached.
+type Chunk struct {
+	// Ref locates the chunk in the document, like "page 3, part 2".
+	Ref  string
+	Text string
+}
+
+// Is reports whether the file is a document that can be converted, from its
+// extension.
+func Is(path string) bool {
+	return slices.Contains(Extensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// Convert extracts the text of the document as Markdown sections.
+func Convert(path string, data []byte) ([]Section, error) {
+	var sections []Section
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		sections, err = convertPDF(data)
+	case ".docx":
+		sections, err = convertDOCX(data)
+	case ".xlsx":
+		sections, err = convertXLSX(data)
+	default:
+		return nil, fmt.Errorf("unsupported document %s, only PDF, DOCX and XLSX files are", filepath.Base(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s: %w", filepath.Base(path), err)
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("failed to convert %s: %w", filepath.Base(path), ErrNoText)
+	}
+	return sections, nil
+}
+
+// Split splits the sections in chunks of at most size bytes, at paragraph
+// or line boundaries when possible.
+func Split(sections []Section, size int) []Chunk {
+	var chunks []Chunk
+	for _, section := range sections {
+		parts := splitText(section.Text, size)
+		for i, part := range parts {
+			ref := section.Ref
+			if len(parts) > 1 {
+				ref = fmt.Sprintf("%s, part %d", ref, i+1)
+			}
+			chunks = append(chunks, Chunk{Ref: ref, Text: part})
+		}
+	}
+	return chunks
+}
+
+func splitText(text string, size int) []string {
+	var parts []string
+	for len(text) > size {
+		cut := strings.LastIndex(text[:size], "\n\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(text[:size], "\n")
+		}
+		if cut <= 0 {
+			cut = strings.LastIndex(text[:size], " ")
+		}
+		if cut <= 0 {
+			cut = size
+			// Don't cut a UTF-8 sequence.
+			for cut > 0 && text[cut]&0xC0 == 0x80 {
+				cut--
+			}
+		}
+		parts = append(parts, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
+
+// MaxSize returns the size of the converted text to attach for a model with
+// the context window, in tokens: a quarter of it, at about 4 bytes a token.
+func MaxSize(contextWindow int64) int {
+	if contextWindow <= 0 {
+		return DefaultMaxSize
+	}
+	return int(contextWindow)
+}
+
+// Render formats the chunks of the document as Markdown, each under a
+// heading citing its origin. The chunks past maxSize bytes are left out.
+func Render(name string, chunks []Chunk, maxSize int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Document %s, converted to Markdown in %d chunks. Cite the chunks the answer relies on by their origin, like [%s].\n", name, len(chunks), origin(name, chunks[0]))
+	size := 0
+	for i, chunk := range chunks {
+		if size+len(chunk.Text) > maxSize && i > 0 {
+			if i == len(chunks)-1 {
+				fmt.Fprintf(&sb, "\nChunk %d (%s) was left out to fit the context window.\n", i+1, chunk.Ref)
+				break
+			}
+			last := chunks[len(chunks)-1]
+			fmt.Fprintf(&sb, "\nChunks %d to %d (%s to %s) were left out to fit the context window.\n", i+1, len(chunks), chunk.Ref, last.Ref)
+			break
+		}
+		size += len(chunk.Text)
+		fmt.Fprintf(&sb, "\n## [%d] %s\n\n%s\n", i+1, origin(name, chunk), chunk.Text)
+	}
+	return sb.String()
+}
+
+func origin(name string, chunk Chunk) string {
+	return name + ", " + chunk.Ref
+}
+
+// Attachment converts the document to a Markdown attachment of at most about
+// maxSize bytes.
+func Attachment(path string, data []byte, maxSize int) (message.Attachment, error) {
+	sections, err := Convert(path, data)
+	if err != nil {
+		return message.Attachment{}, err
+	}
+	name := filepath.Base(path)
+	return message.Attachment{
+		FilePath: path,
+		FileName: name,
+		MimeType: MIMEType,
+		Content:  []byte(Render(name, Split(sections, ChunkSize), maxSize)),
+	}, nil
+}
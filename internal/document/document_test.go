@@ -0,0 +1,62 @@
+package document
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIs(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, Is("report.PDF"))
+	require.True(t, Is("dir/plan.docx"))
+	require.True(t, Is("sales.xlsx"))
+	require.False(t, Is("notes.md"))
+	require.False(t, Is("old.doc"))
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	chunks := Split([]Section{
+		{Ref: "page 1", Text: "Short page."},
+		{Ref: "page 2", Text: "First paragraph.\n\nSecond paragraph is longer."},
+	}, 30)
+	require.Equal(t, []Chunk{
+		{Ref: "page 1", Text: "Short page."},
+		{Ref: "page 2, part 1", Text: "First paragraph."},
+		{Ref: "page 2, part 2", Text: "Second paragraph is longer."},
+	}, chunks)
+
+	// Text without spaces is cut between characters.
+	chunks = Split([]Section{{Ref: "page 1", Text: strings.Repeat("é", 5)}}, 5)
+	require.Equal(t, []Chunk{
+		{Ref: "page 1, part 1", Text: "éé"},
+		{Ref: "page 1, part 2", Text: "éé"},
+		{Ref: "page 1, part 3", Text: "é"},
+	}, chunks)
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	chunks := []Chunk{
+		{Ref: "page 1", Text: "Intro."},
+		{Ref: "page 2", Text: "Results."},
+		{Ref: "page 3", Text: "Appendix."},
+	}
+	require.Equal(t, `Document report.pdf, converted to Markdown in 3 chunks. Cite the chunks the answer relies on by their origin, like [report.pdf, page 1].
+
+## [1] report.pdf, page 1
+
+Intro.
+
+## [2] report.pdf, page 2
+
+Results.
+
+Chunk 3 (page 3) was left out to fit the context window.
+`, Render("report.pdf", chunks, 15))
+}
@@ -0,0 +1,187 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// convertDOCX extracts the paragraphs and tables of a Word document, in a
+// section per heading.
+func convertDOCX(data []byte) ([]Section, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a DOCX file: %w", err)
+	}
+	body, err := readZipFile(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []Section
+	current := Section{Ref: "beginning"}
+	var text strings.Builder
+	flush := func() {
+		current.Text = strings.TrimSpace(text.String())
+		if current.Text != "" {
+			sections = append(sections, current)
+		}
+		text.Reset()
+	}
+
+	var (
+		para       strings.Builder
+		heading    int
+		listItem   bool
+		inText     bool
+		tableDepth int
+		rows       [][]string
+		cell       []string
+	)
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid DOCX document: %w", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "p":
+				para.Reset()
+				heading, listItem = 0, false
+			case "pStyle":
+				heading = headingLevel(attr(tok, "val"))
+			case "numPr":
+				listItem = true
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteByte('\t')
+			case "br", "cr":
+				para.WriteByte(' ')
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					rows = nil
+				}
+			case "tr":
+				if tableDepth == 1 {
+					rows = append(rows, nil)
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell = nil
+				}
+			}
+		case xml.EndElement:
+			switch tok.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				line := strings.TrimSpace(para.String())
+				if line == "" {
+					continue
+				}
+				if tableDepth > 0 {
+					cell = append(cell, line)
+					continue
+				}
+				switch {
+				case heading > 0:
+					flush()
+					current = Section{Ref: strconv.Quote(line) + " section"}
+					line = strings.Repeat("#", heading) + " " + line
+				case listItem:
+					line = "- " + line
+				}
+				text.WriteString(line + "\n\n")
+			case "tc":
+				if tableDepth == 1 && len(rows) > 0 {
+					rows[len(rows)-1] = append(rows[len(rows)-1], strings.Join(cell, " "))
+				}
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 {
+					text.WriteString(markdownTable(rows) + "\n")
+				}
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(tok)
+			}
+		}
+	}
+	flush()
+	return sections, nil
+}
+
+// headingLevel returns the Markdown heading level of a paragraph style, 0
+// when it isn't a heading.
+func headingLevel(style string) int {
+	style = strings.ToLower(style)
+	if style == "title" {
+		return 1
+	}
+	if n, ok := strings.CutPrefix(style, "heading"); ok {
+		if level, err := strconv.Atoi(n); err == nil && level > 0 {
+			return min(level, 6)
+		}
+	}
+	return 0
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func readZipFile(zr *zip.Reader, name string) ([]byte, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("missing %s: %w", name, err)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// markdownTable renders the rows as a Markdown table, the first row being
+// its header.
+func markdownTable(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if width == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	writeRow := func(row []string) {
+		sb.WriteString("|")
+		for i := range width {
+			var cell string
+			if i < len(row) {
+				cell = strings.ReplaceAll(strings.ReplaceAll(row[i], "|", `\|`), "\n", " ")
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+	}
+	writeRow(rows[0])
+	sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return sb.String()
+}
@@ -0,0 +1,49 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// buildZip writes the files in a zip archive, like the Office documents.
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestConvertDOCX(t *testing.T) {
+	t.Parallel()
+
+	data := buildZip(t, map[string]string{
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Draft, do not share.</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Budget</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">The budget </w:t></w:r><w:r><w:t>grows.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>Hire two</w:t></w:r></w:p>
+<w:tbl>
+<w:tr><w:tc><w:p><w:r><w:t>Team</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Cost</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>R|D</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>10</w:t></w:r></w:p></w:tc></w:tr>
+</w:tbl>
+</w:body></w:document>`,
+	})
+
+	sections, err := Convert("plan.docx", data)
+	require.NoError(t, err)
+	require.Equal(t, []Section{
+		{Ref: "beginning", Text: "Draft, do not share."},
+		{Ref: `"Budget" section`, Text: "# Budget\n\nThe budget grows.\n\n- Hire two\n\n| Team | Cost |\n| --- | --- |\n| R\\|D | 10 |"},
+	}, sections)
+}
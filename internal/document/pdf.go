@@ -0,0 +1,841 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// The PDF reader below only extracts text: it parses the objects of the file,
+// walks its page tree and interprets the text operators of the content
+// streams, mapping the character codes to Unicode with the ToUnicode maps of
+// the fonts. It supports the Flate and ASCII85 filters and object streams,
+// not encrypted files nor text in images.
+
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfString  []byte
+	pdfDict    map[string]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		data []byte
+	}
+)
+
+var pdfObjectRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// errPDFEncrypted is returned for the encrypted PDFs, which text can't be
+// read without decrypting it.
+var errPDFEncrypted = errors.New("encrypted PDFs are not supported")
+
+type pdfReader struct {
+	objects map[int]any
+	fonts   map[any]*pdfFont
+}
+
+// convertPDF extracts the text of a PDF, in a section per page.
+func convertPDF(data []byte) ([]Section, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+	r := &pdfReader{objects: make(map[int]any), fonts: make(map[any]*pdfFont)}
+	r.parseObjects(data)
+	if r.encrypted(data) {
+		return nil, errPDFEncrypted
+	}
+
+	var sections []Section
+	for i, page := range r.pages() {
+		text := r.pageText(page)
+		if text == "" {
+			continue
+		}
+		sections = append(sections, Section{Ref: fmt.Sprintf("page %d", i+1), Text: text})
+	}
+	return sections, nil
+}
+
+// parseObjects reads the objects of the file, the later definitions of
+// incremental updates replacing the earlier ones, then those of the object
+// streams.
+func (r *pdfReader) parseObjects(data []byte) {
+	for _, m := range pdfObjectRe.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		l := &pdfLexer{data: data, pos: m[1]}
+		v, err := l.value()
+		if err != nil {
+			continue
+		}
+		if dict, ok := v.(pdfDict); ok {
+			if stream, ok := l.stream(dict); ok {
+				v = stream
+			}
+		}
+		r.objects[num] = v
+	}
+
+	for _, obj := range r.objects {
+		stream, ok := obj.(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, err := r.decode(stream)
+		if err != nil {
+			continue
+		}
+		n, _ := r.resolve(stream.dict["N"]).(float64)
+		first, _ := r.resolve(stream.dict["First"]).(float64)
+		header := &pdfLexer{data: data}
+		for range int(n) {
+			num, err1 := header.value()
+			offset, err2 := header.value()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			objNum, ok1 := num.(float64)
+			objOffset, ok2 := offset.(float64)
+			pos := int(first) + int(objOffset)
+			if !ok1 || !ok2 || pos < 0 || pos >= len(data) {
+				continue
+			}
+			if _, ok := r.objects[int(objNum)]; ok {
+				continue
+			}
+			l := &pdfLexer{data: data, pos: pos}
+			if v, err := l.value(); err == nil {
+				r.objects[int(objNum)] = v
+			}
+		}
+	}
+}
+
+func (r *pdfReader) encrypted(data []byte) bool {
+	for _, obj := range r.objects {
+		if stream, ok := obj.(*pdfStream); ok && stream.dict["Type"] == pdfName("XRef") && stream.dict["Encrypt"] != nil {
+			return true
+		}
+	}
+	i := bytes.LastIndex(data, []byte("trailer"))
+	if i < 0 {
+		return false
+	}
+	l := &pdfLexer{data: data, pos: i + len("trailer")}
+	trailer, _ := l.value()
+	dict, ok := trailer.(pdfDict)
+	return ok && dict["Encrypt"] != nil
+}
+
+func (r *pdfReader) resolve(v any) any {
+	for range 32 {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = r.objects[ref.num]
+	}
+	return nil
+}
+
+func (r *pdfReader) dict(v any) pdfDict {
+	switch v := r.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decode returns the data of the stream once its filters are applied.
+func (r *pdfReader) decode(stream *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := r.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+	data := stream.data
+	for _, f := range filters {
+		switch r.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			// Truncated streams are common, keep what could be read.
+			decoded, err := io.ReadAll(zr)
+			if err != nil && len(decoded) == 0 {
+				return nil, err
+			}
+			data = decoded
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data = bytes.TrimSuffix(bytes.TrimSpace(data), []byte("~>"))
+			decoded := make([]byte, len(data))
+			n, _, err := ascii85.Decode(decoded, data, true)
+			if err != nil {
+				return nil, err
+			}
+			data = decoded[:n]
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data = hexDecode(bytes.TrimSuffix(bytes.TrimSpace(data), []byte(">")))
+		default:
+			return nil, fmt.Errorf("unsupported PDF filter %v", f)
+		}
+	}
+	return data, nil
+}
+
+// pdfPage is a page with the resources it inherits.
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages returns the pages of the document in order, walking its page tree,
+// or all the page objects when it has none.
+func (r *pdfReader) pages() []pdfPage {
+	var root pdfDict
+	nums := make([]int, 0, len(r.objects))
+	for num := range r.objects {
+		nums = append(nums, num)
+	}
+	slices.Sort(nums)
+	for _, num := range nums {
+		if dict := r.dict(r.objects[num]); dict["Type"] == pdfName("Catalog") {
+			root = dict
+		}
+	}
+
+	var pages []pdfPage
+	visited := make(map[any]bool)
+	var walk func(node any, resources pdfDict)
+	walk = func(node any, resources pdfDict) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		dict := r.dict(node)
+		if dict == nil {
+			return
+		}
+		if res := r.dict(dict["Resources"]); res != nil {
+			resources = res
+		}
+		if kids, ok := r.resolve(dict["Kids"]).([]any); ok {
+			for _, kid := range kids {
+				walk(kid, resources)
+			}
+			return
+		}
+		pages = append(pages, pdfPage{dict: dict, resources: resources})
+	}
+	if root != nil {
+		walk(root["Pages"], nil)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+	for _, num := range nums {
+		if dict := r.dict(r.objects[num]); dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: r.dict(dict["Resources"])})
+		}
+	}
+	return pages
+}
+
+// pageText extracts the text of the content streams of the page.
+func (r *pdfReader) pageText(page pdfPage) string {
+	var contents []any
+	switch c := r.resolve(page.dict["Contents"]).(type) {
+	case []any:
+		contents = c
+	case *pdfStream:
+		contents = []any{c}
+	}
+	var data []byte
+	for _, c := range contents {
+		stream, ok := r.resolve(c).(*pdfStream)
+		if !ok {
+			continue
+		}
+		decoded, err := r.decode(stream)
+		if err != nil {
+			continue
+		}
+		data = append(data, decoded...)
+		data = append(data, '\n')
+	}
+
+	fonts := r.dict(page.resources["Font"])
+	var out strings.Builder
+	newline := func() {
+		if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+			out.WriteByte('\n')
+		}
+	}
+	var font *pdfFont
+	var operands []any
+	lastY := 0.0
+	l := &pdfLexer{data: data}
+	for {
+		v, err := l.value()
+		if err != nil {
+			break
+		}
+		op, ok := v.(pdfKeyword)
+		if !ok {
+			operands = append(operands, v)
+			continue
+		}
+		switch op {
+		case "BT":
+			lastY = 0
+		case "Tf":
+			if len(operands) >= 2 {
+				name, _ := operands[0].(pdfName)
+				font = r.font(fonts[string(name)])
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				out.WriteString(font.text(operands[len(operands)-1]))
+			}
+		case "'", "\"":
+			newline()
+			if len(operands) >= 1 {
+				out.WriteString(font.text(operands[len(operands)-1]))
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				items, _ := operands[len(operands)-1].([]any)
+				for _, item := range items {
+					// A large negative offset separates words.
+					if n, ok := item.(float64); ok && n < -200 {
+						out.WriteByte(' ')
+						continue
+					}
+					out.WriteString(font.text(item))
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, _ := operands[1].(float64); ty != 0 {
+					newline()
+				} else if tx, _ := operands[0].(float64); tx > 0 {
+					out.WriteByte(' ')
+				}
+			}
+		case "T*":
+			newline()
+		case "Tm":
+			if len(operands) >= 6 {
+				if y, _ := operands[5].(float64); y != lastY {
+					newline()
+					lastY = y
+				}
+			}
+		case "BI":
+			l.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+	return cleanText(out.String())
+}
+
+// cleanText trims the lines of the text and collapses its blank lines.
+func cleanText(text string) string {
+	lines := strings.Split(text, "\n")
+	var kept []string
+	blank := false
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			blank = len(kept) > 0
+			continue
+		}
+		if blank {
+			kept = append(kept, "")
+			blank = false
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// pdfFont maps the character codes of a font to text.
+type pdfFont struct {
+	// codeLen is the length, in bytes, of the character codes.
+	codeLen int
+	// toUnicode maps the codes to text, from the ToUnicode map of the font.
+	toUnicode map[uint32]string
+}
+
+func (r *pdfReader) font(v any) *pdfFont {
+	key := v
+	if _, ok := v.(pdfRef); !ok {
+		key = fmt.Sprintf("%p", r.dict(v))
+	}
+	if f, ok := r.fonts[key]; ok {
+		return f
+	}
+	f := &pdfFont{codeLen: 1}
+	dict := r.dict(v)
+	if dict["Subtype"] == pdfName("Type0") {
+		f.codeLen = 2
+	}
+	if stream, ok := r.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := r.decode(stream); err == nil {
+			f.toUnicode = parseCMap(data)
+		}
+	}
+	r.fonts[key] = f
+	return f
+}
+
+// text decodes a string shown with the font.
+func (f *pdfFont) text(v any) string {
+	s, ok := v.(pdfString)
+	if !ok {
+		return ""
+	}
+	if f == nil {
+		return latin1(s)
+	}
+	if f.toUnicode == nil {
+		if f.codeLen == 2 {
+			// Without a map, the codes of composite fonts are glyph IDs.
+			return ""
+		}
+		return latin1(s)
+	}
+	var sb strings.Builder
+	for i := 0; i+f.codeLen <= len(s); i += f.codeLen {
+		var code uint32
+		for _, b := range s[i : i+f.codeLen] {
+			code = code<<8 | uint32(b)
+		}
+		if text, ok := f.toUnicode[code]; ok {
+			sb.WriteString(text)
+		} else if f.codeLen == 1 {
+			sb.WriteString(latin1(s[i : i+1]))
+		}
+	}
+	return sb.String()
+}
+
+// winAnsi maps the codes of the WinAnsiEncoding which differ from Latin-1.
+var winAnsi = map[byte]rune{
+	0x80: '€', 0x85: '…', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”',
+	0x95: '•', 0x96: '–', 0x97: '—', 0x99: '™',
+}
+
+func latin1(s []byte) string {
+	runes := make([]rune, 0, len(s))
+	for _, b := range s {
+		if r, ok := winAnsi[b]; ok {
+			runes = append(runes, r)
+			continue
+		}
+		runes = append(runes, rune(b))
+	}
+	return string(runes)
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode map.
+func parseCMap(data []byte) map[uint32]string {
+	m := make(map[uint32]string)
+	l := &pdfLexer{data: data}
+	var operands []any
+	mode := ""
+	for {
+		v, err := l.value()
+		if err != nil {
+			return m
+		}
+		kw, ok := v.(pdfKeyword)
+		if !ok {
+			if mode != "" {
+				operands = append(operands, v)
+			}
+			continue
+		}
+		switch kw {
+		case "beginbfchar", "beginbfrange":
+			mode = string(kw)
+			operands = operands[:0]
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					m[cmapCode(src)] = utf16BE(dst)
+				}
+			}
+			mode = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, end := cmapCode(lo), cmapCode(hi)
+				if end < start || end-start > 0xFFFF {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					base := []rune(utf16BE(dst))
+					if len(base) == 0 {
+						continue
+					}
+					for code := start; code <= end; code++ {
+						runes := slices.Clone(base)
+						runes[len(runes)-1] += rune(code - start)
+						m[code] = string(runes)
+					}
+				case []any:
+					for j, d := range dst {
+						if s, ok := d.(pdfString); ok && start+uint32(j) <= end {
+							m[start+uint32(j)] = utf16BE(s)
+						}
+					}
+				}
+			}
+			mode = ""
+		}
+	}
+}
+
+func cmapCode(s []byte) uint32 {
+	var code uint32
+	for _, b := range s {
+		code = code<<8 | uint32(b)
+	}
+	return code
+}
+
+func utf16BE(s []byte) string {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// pdfLexer reads the values of PDF objects and content streams.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+var errPDFEnd = errors.New("end of PDF data")
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *pdfLexer) value() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errPDFEnd
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		dict := make(pdfDict)
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				return nil, errPDFEnd
+			}
+			if bytes.HasPrefix(l.data[l.pos:], []byte(">>")) {
+				l.pos += 2
+				return dict, nil
+			}
+			key, err := l.value()
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(pdfName)
+			if !ok {
+				return nil, fmt.Errorf("invalid PDF dictionary key %v", key)
+			}
+			val, err := l.value()
+			if err != nil {
+				return nil, err
+			}
+			dict[string(name)] = val
+		}
+	case c == '<':
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			return nil, errPDFEnd
+		}
+		s := hexDecode(l.data[l.pos+1 : l.pos+end])
+		l.pos += end + 1
+		return pdfString(s), nil
+	case c == '[':
+		l.pos++
+		var arr []any
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				return nil, errPDFEnd
+			}
+			if l.data[l.pos] == ']' {
+				l.pos++
+				return arr, nil
+			}
+			v, err := l.value()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+	case c == '(':
+		return l.literal(), nil
+	case c == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfName(unescapeName(l.data[start:l.pos])), nil
+	case c == '>' || c == ']' || c == ')' || c == '{' || c == '}':
+		// Stray delimiters of damaged content are skipped.
+		l.pos++
+		return pdfKeyword([]byte{c}), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	token := string(l.data[start:l.pos])
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return pdfKeyword(token), nil
+	}
+	// An integer can start a reference, "12 0 R".
+	if isInteger(token) {
+		save := l.pos
+		if gen, ok := l.integer(); ok {
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == 'R' && (l.pos+1 == len(l.data) || isPDFSpace(l.data[l.pos+1]) || isPDFDelimiter(l.data[l.pos+1])) {
+				l.pos++
+				return pdfRef{num: int(n), gen: gen}, nil
+			}
+		}
+		l.pos = save
+	}
+	return n, nil
+}
+
+func isInteger(token string) bool {
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return token != ""
+}
+
+func (l *pdfLexer) integer() (int, bool) {
+	l.skipSpace()
+	start := l.pos
+	for l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		l.pos++
+	}
+	if start == l.pos || (l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos])) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(l.data[start:l.pos]))
+	return n, err == nil
+}
+
+// literal reads a string in parentheses, with its escapes.
+func (l *pdfLexer) literal() pdfString {
+	l.pos++
+	var s []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return s
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for range 2 {
+						if l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7' {
+							n = n*8 + int(l.data[l.pos]-'0')
+							l.pos++
+						}
+					}
+					c = byte(n)
+				} else {
+					c = e
+				}
+			}
+		}
+		s = append(s, c)
+	}
+	return s
+}
+
+// stream reads the data of the stream following its dictionary, if any.
+func (l *pdfLexer) stream(dict pdfDict) (*pdfStream, bool) {
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		return nil, false
+	}
+	start := l.pos + len("stream")
+	if bytes.HasPrefix(l.data[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(l.data) && (l.data[start] == '\n' || l.data[start] == '\r') {
+		start++
+	}
+	// The length may be an indirect object, not known yet: use it when it is
+	// direct and ends the stream, or look for its end.
+	if n, ok := dict["Length"].(float64); ok {
+		end := start + int(n)
+		if end <= len(l.data) && bytes.HasPrefix(bytes.TrimLeft(l.data[end:], "\r\n "), []byte("endstream")) {
+			l.pos = end
+			return &pdfStream{dict: dict, data: l.data[start:end]}, true
+		}
+	}
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		return nil, false
+	}
+	data := bytes.TrimRight(l.data[start:start+end], "\r\n")
+	l.pos = start + end
+	return &pdfStream{dict: dict, data: data}, true
+}
+
+// skipInlineImage skips the data of an inline image, up to its EI operator.
+func (l *pdfLexer) skipInlineImage() {
+	i := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if i < 0 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += i + 2
+	for {
+		j := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if j < 0 {
+			l.pos = len(l.data)
+			return
+		}
+		l.pos += j + 2
+		if isPDFSpace(l.data[l.pos-3]) && (l.pos == len(l.data) || isPDFSpace(l.data[l.pos])) {
+			return
+		}
+	}
+}
+
+func unescapeName(name []byte) string {
+	if !bytes.ContainsRune(name, '#') {
+		return string(name)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) {
+			if b, err := hex.DecodeString(string(name[i+1 : i+3])); err == nil {
+				sb.WriteByte(b[0])
+				i += 2
+				continue
+			}
+		}
+		sb.WriteByte(name[i])
+	}
+	return sb.String()
+}
+
+func hexDecode(data []byte) []byte {
+	digits := make([]byte, 0, len(data)+1)
+	for _, c := range data {
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s := make([]byte, len(digits)/2)
+	n, _ := hex.Decode(s, digits)
+	return s[:n]
+}
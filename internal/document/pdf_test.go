@@ -0,0 +1,76 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// buildPDF writes the objects, numbered from 1, in a PDF file.
+func buildPDF(t *testing.T, objects ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func flateStream(t *testing.T, dict, content string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, err := zw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return fmt.Sprintf("<< %s /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", dict, buf.Len(), buf.Bytes())
+}
+
+func TestConvertPDF(t *testing.T) {
+	t.Parallel()
+
+	page1 := "BT /F1 12 Tf 72 720 Td (Quarterly \\(Q3\\) report) Tj 0 -14 Td [(Reve) 20 (nue) -300 (grew)] TJ ET"
+	page2 := "BT /F2 12 Tf 1 0 0 1 72 720 Tm <00010002> Tj 1 0 0 1 72 700 Tm <0003> Tj ET"
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"1 beginbfchar <0003> <00E9> endbfchar\n" +
+		"1 beginbfrange <0001> <0002> <0048> endbfrange\n" +
+		"endcmap CMapName currentdict /CMap defineresource pop end end"
+	data := buildPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [8 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 9 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(page1), page1),
+		flateStream(t, "", page2),
+		flateStream(t, "", cmap),
+	)
+
+	sections, err := Convert("report.pdf", data)
+	require.NoError(t, err)
+	require.Equal(t, []Section{
+		{Ref: "page 1", Text: "Quarterly (Q3) report\nRevenue grew"},
+		{Ref: "page 2", Text: "HI\né"},
+	}, sections)
+}
+
+func TestConvertPDFErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Convert("scan.pdf", buildPDF(t,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+	))
+	require.ErrorIs(t, err, ErrNoText)
+
+	_, err = Convert("image.pdf", []byte("\x89PNG"))
+	require.Error(t, err)
+}
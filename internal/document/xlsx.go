@@ -0,0 +1,221 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// xlsxRowsPerSection is the number of rows of a sheet rendered in a
+// section, each section repeating the header row of the sheet.
+const xlsxRowsPerSection = 100
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		// The relationship ID is in the r namespace.
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			T      string `xml:"t,attr"`
+			V      string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// convertXLSX renders the sheets of an Excel workbook as Markdown tables, in
+// sections of xlsxRowsPerSection rows.
+func convertXLSX(data []byte) ([]Section, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a XLSX file: %w", err)
+	}
+	var workbook xlsxWorkbook
+	if err := unmarshalZipFile(zr, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := unmarshalZipFile(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+	shared, err := xlsxSharedStrings(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []Section
+	for _, s := range workbook.Sheets {
+		target, ok := targets[s.RID]
+		if !ok {
+			continue
+		}
+		var sheet xlsxSheet
+		if err := unmarshalZipFile(zr, target, &sheet); err != nil {
+			return nil, err
+		}
+		var rows [][]string
+		var numbers []int
+		for i, row := range sheet.Rows {
+			var values []string
+			for j, c := range row.Cells {
+				col := j
+				if c.R != "" {
+					col = xlsxColumn(c.R)
+				}
+				for len(values) <= col {
+					values = append(values, "")
+				}
+				values[col] = xlsxValue(c.T, c.V, c.Inline, shared)
+			}
+			if strings.TrimSpace(strings.Join(values, "")) == "" {
+				continue
+			}
+			n := row.R
+			if n == 0 {
+				n = i + 1
+			}
+			rows = append(rows, values)
+			numbers = append(numbers, n)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		header := rows[0]
+		if len(rows) == 1 {
+			sections = append(sections, Section{
+				Ref:  fmt.Sprintf("sheet %q, row %d", s.Name, numbers[0]),
+				Text: markdownTable(rows),
+			})
+			continue
+		}
+		for start := 1; start < len(rows); start += xlsxRowsPerSection {
+			end := min(start+xlsxRowsPerSection, len(rows))
+			table := append([][]string{header}, rows[start:end]...)
+			sections = append(sections, Section{
+				Ref:  fmt.Sprintf("sheet %q, rows %d-%d", s.Name, numbers[start], numbers[end-1]),
+				Text: markdownTable(table),
+			})
+		}
+	}
+	return sections, nil
+}
+
+func unmarshalZipFile(zr *zip.Reader, name string, v any) error {
+	data, err := readZipFile(zr, name)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return nil
+}
+
+// xlsxSharedStrings reads the strings the cells refer to by index, the rich
+// text runs of a string being joined.
+func xlsxSharedStrings(zr *zip.Reader) ([]string, error) {
+	f, err := zr.Open("xl/sharedStrings.xml")
+	if err != nil {
+		// Workbooks without text cells have none.
+		return nil, nil
+	}
+	defer f.Close()
+
+	var shared []string
+	var sb strings.Builder
+	inText := false
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return shared, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid xl/sharedStrings.xml: %w", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "si":
+				sb.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				// Phonetic hints aren't part of the text.
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			switch tok.Name.Local {
+			case "si":
+				shared = append(shared, sb.String())
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(tok)
+			}
+		}
+	}
+}
+
+// xlsxColumn returns the index of the column of a cell reference, like 2
+// for C7.
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return max(col-1, 0)
+}
+
+func xlsxValue(typ, v, inline string, shared []string) string {
+	switch typ {
+	case "s":
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 || i >= len(shared) {
+			return ""
+		}
+		return shared[i]
+	case "inlineStr":
+		return inline
+	case "b":
+		if v == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return v
+}
@@ -0,0 +1,34 @@
+package document
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertXLSX(t *testing.T) {
+	t.Parallel()
+
+	data := buildZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Sales" sheetId="1" r:id="rId1"/><sheet name="Empty" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="worksheet" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Region</t></si><si><r><t>Tot</t></r><r><t>al</t></r></si><si><t>North</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>Done</t></is></c></row>
+<row r="3"><c r="A3" t="s"><v>2</v></c><c r="B3"><v>1250.5</v></c><c r="C3" t="b"><v>1</v></c></row>
+<row r="4"><c r="B4"><v>7</v></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`,
+	})
+
+	sections, err := Convert("sales.xlsx", data)
+	require.NoError(t, err)
+	require.Equal(t, []Section{{
+		Ref:  `sheet "Sales", rows 3-4`,
+		Text: "| Region | Total | Done |\n| --- | --- | --- |\n| North | 1250.5 | TRUE |\n|  | 7 |  |\n",
+	}}, sections)
+}
@@ -0,0 +1,188 @@
+// Package editor builds the commands that open a file at a line in the
+// editor of the user.
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"mvdan.cc/sh/v3/shell"
+)
+
+// Command opens a file in an editor.
+type Command struct {
+	// Name is the editor the command runs.
+	Name string
+	Args []string
+	// Terminal editors take over the terminal until they exit, GUI editors
+	// and editor servers return right away and are started in the
+	// background.
+	Terminal bool
+}
+
+// template is how an editor opens a file at a line.
+type template struct {
+	args     string
+	terminal bool
+}
+
+var (
+	vscodeTemplate    = template{args: "--goto {file}:{line}:{column}"}
+	jetbrainsTemplate = template{args: "--line {line} --column {column} {file}"}
+	plusLineTemplate  = template{args: "+{line} {file}", terminal: true}
+	suffixTemplate    = template{args: "{file}:{line}:{column}"}
+)
+
+// templates are the arguments known editors take, by executable name.
+var templates = map[string]template{
+	"code":          vscodeTemplate,
+	"code-insiders": vscodeTemplate,
+	"codium":        vscodeTemplate,
+	"cursor":        vscodeTemplate,
+	"windsurf":      vscodeTemplate,
+
+	"idea":      jetbrainsTemplate,
+	"goland":    jetbrainsTemplate,
+	"pycharm":   jetbrainsTemplate,
+	"webstorm":  jetbrainsTemplate,
+	"clion":     jetbrainsTemplate,
+	"rubymine":  jetbrainsTemplate,
+	"phpstorm":  jetbrainsTemplate,
+	"rider":     jetbrainsTemplate,
+	"rustrover": jetbrainsTemplate,
+	"studio":    jetbrainsTemplate,
+
+	"vi":    plusLineTemplate,
+	"vim":   plusLineTemplate,
+	"nvim":  plusLineTemplate,
+	"nano":  {args: "+{line},{column} {file}", terminal: true},
+	"emacs": {args: "+{line}:{column} {file}", terminal: true},
+	"kak":   plusLineTemplate,
+	"micro": {args: "+{line}:{column} {file}", terminal: true},
+	"hx":    {args: "{file}:{line}:{column}", terminal: true},
+	"helix": {args: "{file}:{line}:{column}", terminal: true},
+
+	"subl": suffixTemplate,
+	"zed":  suffixTemplate,
+
+	"notepad": {args: "{file}"},
+}
+
+// isVSCode reports whether the editor understands the VS Code command line,
+// including --remote.
+func isVSCode(name string) bool {
+	t, ok := templates[name]
+	return ok && t == vscodeTemplate
+}
+
+// Open returns the command that opens the file at the line and column, which
+// start at 1, with the configured editor or the one detected from the
+// environment.
+func Open(opts *config.EditorOptions, path string, line, column int) (Command, error) {
+	return resolve(opts, os.Getenv, path, line, column)
+}
+
+// resolve picks, in order, the configured command, the Neovim instance or
+// the IDE whose terminal crush runs in, $VISUAL, $EDITOR and a default
+// editor of the platform.
+func resolve(opts *config.EditorOptions, getenv func(string) string, path string, line, column int) (Command, error) {
+	if opts == nil {
+		opts = &config.EditorOptions{}
+	}
+	line, column = max(line, 1), max(column, 1)
+
+	if opts.Command != "" {
+		return expand(opts, getenv, opts.Command, path, line, column)
+	}
+
+	// Neovim exports the address of its server to the programs of its
+	// terminal, open the file in that instance.
+	if server := getenv("NVIM"); server != "" {
+		return Command{
+			Name: "nvim",
+			Args: []string{
+				"nvim", "--server", server, "--remote-send",
+				fmt.Sprintf(`<C-\><C-N>:edit +%d %s<CR>`, line, vimEscape(path)),
+			},
+		}, nil
+	}
+
+	switch {
+	case getenv("TERM_PROGRAM") == "vscode":
+		// The code CLI of the integrated terminal also reaches the window of
+		// remote workspaces.
+		return expand(opts, getenv, "code", path, line, column)
+	case getenv("TERMINAL_EMULATOR") == "JetBrains-JediTerm":
+		return expand(opts, getenv, "idea", path, line, column)
+	}
+
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := getenv(env); editor != "" {
+			return expand(opts, getenv, editor, path, line, column)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return expand(opts, getenv, "notepad", path, line, column)
+	}
+	return expand(opts, getenv, "nvim", path, line, column)
+}
+
+// expand builds the command of an editor command line. Command lines with
+// placeholders are used as is, otherwise the arguments of the known editor
+// are appended to it.
+func expand(opts *config.EditorOptions, getenv func(string) string, cmdline, path string, line, column int) (Command, error) {
+	fields, err := shell.Fields(cmdline, getenv)
+	if err != nil {
+		return Command{}, fmt.Errorf("invalid editor command %q: %w", cmdline, err)
+	}
+	if len(fields) == 0 {
+		return Command{}, errors.New("empty editor command")
+	}
+
+	name := strings.TrimSuffix(filepath.Base(fields[0]), ".exe")
+	t, known := templates[name]
+	if !known {
+		t = template{args: "{file}", terminal: true}
+	}
+	if !strings.Contains(cmdline, "{file}") {
+		extra := strings.Fields(t.args)
+		if isVSCode(name) && opts.Remote != "" {
+			extra = append([]string{"--remote", opts.Remote}, extra...)
+		}
+		fields = append(fields, extra...)
+	}
+
+	replacer := strings.NewReplacer(
+		"{file}", path,
+		"{line}", strconv.Itoa(line),
+		"{column}", strconv.Itoa(column),
+	)
+	for i, f := range fields[1:] {
+		fields[i+1] = replacer.Replace(f)
+	}
+	return Command{Name: name, Args: fields, Terminal: t.terminal}, nil
+}
+
+// vimEscape escapes a path for Vim's :edit command.
+func vimEscape(path string) string {
+	var sb strings.Builder
+	for _, r := range path {
+		switch {
+		case r == '<':
+			// Keys sent to the server use the <Key> notation.
+			sb.WriteString("<lt>")
+			continue
+		case strings.ContainsRune(" \t\\%#|\"", r):
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
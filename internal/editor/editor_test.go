@@ -0,0 +1,89 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     *config.EditorOptions
+		env      map[string]string
+		want     []string
+		terminal bool
+	}{
+		{
+			name:     "editor variable",
+			env:      map[string]string{"EDITOR": "vim"},
+			want:     []string{"vim", "+12", "main.go"},
+			terminal: true,
+		},
+		{
+			name:     "visual before editor",
+			env:      map[string]string{"VISUAL": "hx", "EDITOR": "vim"},
+			want:     []string{"hx", "main.go:12:1"},
+			terminal: true,
+		},
+		{
+			name: "editor with arguments",
+			env:  map[string]string{"EDITOR": "code --wait"},
+			want: []string{"code", "--wait", "--goto", "main.go:12:1"},
+		},
+		{
+			name:     "unknown editor",
+			env:      map[string]string{"EDITOR": "/opt/bin/ed"},
+			want:     []string{"/opt/bin/ed", "main.go"},
+			terminal: true,
+		},
+		{
+			name: "vscode terminal",
+			env:  map[string]string{"TERM_PROGRAM": "vscode", "EDITOR": "vim"},
+			want: []string{"code", "--goto", "main.go:12:1"},
+		},
+		{
+			name: "vscode remote",
+			opts: &config.EditorOptions{Remote: "ssh-remote+devbox"},
+			env:  map[string]string{"TERM_PROGRAM": "vscode"},
+			want: []string{"code", "--remote", "ssh-remote+devbox", "--goto", "main.go:12:1"},
+		},
+		{
+			name: "jetbrains terminal",
+			env:  map[string]string{"TERMINAL_EMULATOR": "JetBrains-JediTerm"},
+			want: []string{"idea", "--line", "12", "--column", "1", "main.go"},
+		},
+		{
+			name: "neovim server",
+			env:  map[string]string{"NVIM": "/tmp/nvim.sock", "EDITOR": "vim"},
+			want: []string{"nvim", "--server", "/tmp/nvim.sock", "--remote-send", `<C-\><C-N>:edit +12 main.go<CR>`},
+		},
+		{
+			name:     "configured template",
+			opts:     &config.EditorOptions{Command: "$HOME/bin/edit --at {line} '{file}'"},
+			env:      map[string]string{"HOME": "/home/me", "NVIM": "/tmp/nvim.sock"},
+			want:     []string{"/home/me/bin/edit", "--at", "12", "main.go"},
+			terminal: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			getenv := func(key string) string { return tt.env[key] }
+			cmd, err := resolve(tt.opts, getenv, "main.go", 12, 0)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cmd.Args)
+			require.Equal(t, tt.terminal, cmd.Terminal)
+		})
+	}
+}
+
+func TestVimEscape(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, `my\ dir/<lt>x>\#1.go`, vimEscape("my dir/<x>#1.go"))
+}
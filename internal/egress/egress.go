@@ -0,0 +1,264 @@
+// Package egress restricts the hosts the tools can connect to, so the agent
+// can't send data to arbitrary hosts.
+//
+// The policy applies to the HTTP clients of the tools and of the MCP
+// servers. The commands run by the bash tool are pointed to a local proxy
+// enforcing it, which only covers the programs honoring the proxy
+// environment variables.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrBlocked is returned for the connections the policy doesn't allow.
+var ErrBlocked = errors.New("blocked by the egress policy")
+
+// Options configure a Policy.
+type Options struct {
+	// Allow and Deny hold host names, "*.example.com" patterns matching the
+	// subdomains of a domain, IP addresses and CIDRs. Deny wins over Allow.
+	Allow []string
+	Deny  []string
+	// DenyByDefault blocks the hosts not matching Allow, otherwise only
+	// those matching Deny are blocked.
+	DenyByDefault bool
+}
+
+// Policy decides which hosts can be connected to. A nil policy allows every
+// host.
+type Policy struct {
+	allow         rules
+	deny          rules
+	denyByDefault bool
+	lookup        func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+type rules struct {
+	hosts []string
+	nets  []*net.IPNet
+}
+
+func New(opts Options) *Policy {
+	return &Policy{
+		allow:         parseRules(opts.Allow),
+		deny:          parseRules(opts.Deny),
+		denyByDefault: opts.DenyByDefault,
+		lookup:        net.DefaultResolver.LookupIPAddr,
+	}
+}
+
+func parseRules(entries []string) rules {
+	var r rules
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			r.nets = append(r.nets, n)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			r.nets = append(r.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		r.hosts = append(r.hosts, strings.TrimSuffix(entry, "."))
+	}
+	return r
+}
+
+func (r rules) matchHost(host string) bool {
+	for _, pattern := range r.hosts {
+		switch {
+		case pattern == "*", pattern == host:
+			return true
+		case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+func (r rules) matchIP(ip net.IP) bool {
+	for _, n := range r.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error wrapping ErrBlocked when the policy doesn't allow
+// connecting to the host. Host names are resolved when the policy has CIDRs,
+// a host is allowed by them when all its addresses are.
+func (p *Policy) Check(ctx context.Context, host string) error {
+	if p == nil {
+		return nil
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	blocked := fmt.Errorf("%w: %s", ErrBlocked, host)
+
+	if ip := net.ParseIP(host); ip != nil {
+		switch {
+		case p.deny.matchIP(ip):
+			return blocked
+		case p.allow.matchIP(ip):
+			return nil
+		}
+		return p.fallback(blocked)
+	}
+
+	switch {
+	case p.deny.matchHost(host):
+		return blocked
+	case p.allow.matchHost(host):
+		return nil
+	}
+	if len(p.deny.nets) == 0 && len(p.allow.nets) == 0 {
+		return p.fallback(blocked)
+	}
+
+	addrs, err := p.lookup(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		// The connection fails anyway, unless the policy allows it.
+		return p.fallback(blocked)
+	}
+	return p.checkAddrs(addrs, false, blocked)
+}
+
+// checkAddrs checks the addresses a host name resolved to. The deny CIDRs
+// apply even to the host names allowed by name.
+func (p *Policy) checkAddrs(addrs []net.IPAddr, allowedName bool, blocked error) error {
+	allowed := len(addrs) > 0
+	for _, addr := range addrs {
+		if p.deny.matchIP(addr.IP) {
+			return blocked
+		}
+		allowed = allowed && p.allow.matchIP(addr.IP)
+	}
+	if allowed || allowedName {
+		return nil
+	}
+	return p.fallback(blocked)
+}
+
+// dialer is the dialer of the connections checked by the policy, with the
+// settings of the default transport.
+var dialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dial resolves the host of the address once, checks the policy against
+// the addresses it resolved to and connects to those very addresses, so a
+// host name can't resolve to an allowed address for the check and to a
+// denied one for the connection.
+func (p *Policy) dial(ctx context.Context, dial dialFunc, network, addr string) (net.Conn, error) {
+	if p == nil {
+		return dial(ctx, network, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		if err := p.Check(ctx, host); err != nil {
+			return nil, err
+		}
+		return dial(ctx, network, addr)
+	}
+
+	blocked := fmt.Errorf("%w: %s", ErrBlocked, host)
+	if p.deny.matchHost(host) {
+		return nil, blocked
+	}
+	addrs, err := p.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkAddrs(addrs, p.allow.matchHost(host), blocked); err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		var conn net.Conn
+		conn, err = dial(ctx, network, net.JoinHostPort(a.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+func (p *Policy) fallback(blocked error) error {
+	if p.denyByDefault {
+		return blocked
+	}
+	return nil
+}
+
+// Transport returns the round tripper checking the host of each request,
+// including those of the redirects, before sending it with base. When base
+// is an *http.Transport, the policy is checked again against the addresses
+// it connects to.
+func (p *Policy) Transport(base http.RoundTripper) http.RoundTripper {
+	if p == nil {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	tr := &transport{policy: p, base: base}
+	if t, ok := base.(*http.Transport); ok {
+		t = t.Clone()
+		dial := t.DialContext
+		if dial == nil {
+			dial = dialer.DialContext
+		}
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			// The connections to the proxy of the user are left alone, the
+			// proxy resolves the host of the request itself.
+			if proxyHost, _ := ctx.Value(proxyHostKey{}).(string); proxyHost != "" {
+				if host, _, _ := net.SplitHostPort(addr); strings.EqualFold(host, proxyHost) {
+					return dial(ctx, network, addr)
+				}
+			}
+			return p.dial(ctx, dial, network, addr)
+		}
+		tr.base, tr.proxy = t, t.Proxy
+	}
+	return tr
+}
+
+// proxyHostKey holds the host of the proxy of the request in the context of
+// its connections.
+type proxyHostKey struct{}
+
+type transport struct {
+	policy *Policy
+	base   http.RoundTripper
+	proxy  func(*http.Request) (*url.URL, error)
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.policy.Check(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	if t.proxy != nil {
+		if u, err := t.proxy(req); err == nil && u != nil {
+			req = req.WithContext(context.WithValue(req.Context(), proxyHostKey{}, u.Hostname()))
+		}
+	}
+	return t.base.RoundTrip(req)
+}
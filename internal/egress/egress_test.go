@@ -0,0 +1,166 @@
+package egress
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	t.Parallel()
+
+	p := New(Options{
+		Allow:         []string{"github.com", "*.golang.org", "10.0.0.0/8"},
+		Deny:          []string{"evil.golang.org", "169.254.169.254"},
+		DenyByDefault: true,
+	})
+	p.lookup = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "internal.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("10.1.2.3")}}, nil
+		case "mixed.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("10.1.2.3")}, {IP: net.ParseIP("8.8.8.8")}}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host}
+	}
+
+	for host, allowed := range map[string]bool{
+		"github.com":           true,
+		"GitHub.com.":          true,
+		"api.github.com":       false,
+		"pkg.golang.org":       true,
+		"golang.org":           false,
+		"evil.golang.org":      false,
+		"10.20.30.40":          true,
+		"169.254.169.254":      false,
+		"internal.example.com": true,
+		"mixed.example.com":    false,
+		"example.com":          false,
+	} {
+		err := p.Check(t.Context(), host)
+		if allowed {
+			require.NoError(t, err, host)
+		} else {
+			require.ErrorIs(t, err, ErrBlocked, host)
+		}
+	}
+}
+
+func TestPolicyCheck_AllowByDefault(t *testing.T) {
+	t.Parallel()
+
+	p := New(Options{Deny: []string{"*.example.com"}})
+	require.NoError(t, p.Check(t.Context(), "github.com"))
+	require.ErrorIs(t, p.Check(t.Context(), "api.example.com"), ErrBlocked)
+
+	var nilPolicy *Policy
+	require.NoError(t, nilPolicy.Check(t.Context(), "api.example.com"))
+}
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: New(Options{DenyByDefault: true}).Transport(nil)}
+	_, err := client.Get(server.URL)
+	require.ErrorIs(t, err, ErrBlocked)
+
+	client = &http.Client{Transport: New(Options{Allow: []string{"127.0.0.1"}, DenyByDefault: true}).Transport(nil)}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestProxy(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	proxyURL, err := New(Options{Deny: []string{"127.0.0.1"}}).ProxyURL()
+	require.NoError(t, err)
+	u, err := url.Parse(proxyURL)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(u)}}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// The proxy enforces the last policy.
+	_, err = New(Options{}).ProxyURL()
+	require.NoError(t, err)
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+// rebindingLookup answers a public address first, then a loopback one, like
+// a host name rebound between the check of the policy and the connection.
+func rebindingLookup() func(context.Context, string) ([]net.IPAddr, error) {
+	var lookups atomic.Int32
+	return func(context.Context, string) ([]net.IPAddr, error) {
+		if lookups.Add(1) == 1 {
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+		}
+		return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+	}
+}
+
+func TestTransport_DNSRebinding(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	t.Cleanup(server.Close)
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	p := New(Options{Deny: []string{"127.0.0.0/8"}})
+	p.lookup = rebindingLookup()
+	client := &http.Client{Transport: p.Transport(&http.Transport{})}
+	_, err = client.Get("http://rebind.example.com:" + port)
+	require.ErrorIs(t, err, ErrBlocked)
+	require.Zero(t, hits.Load())
+}
+
+// TestProxy_DNSRebinding isn't parallel, the proxy of the process enforcing
+// the last policy.
+func TestProxy_DNSRebinding(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	t.Cleanup(server.Close)
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	p := New(Options{Deny: []string{"127.0.0.0/8"}})
+	p.lookup = rebindingLookup()
+	proxyURL, err := p.ProxyURL()
+	require.NoError(t, err)
+	u, err := url.Parse(proxyURL)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(u), TLSClientConfig: server.Client().Transport.(*http.Transport).TLSClientConfig}}
+
+	_, err = client.Get("https://rebind.example.com:" + port)
+	require.ErrorContains(t, err, "Forbidden")
+	require.Zero(t, hits.Load())
+}
@@ -0,0 +1,152 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// proxy is the local HTTP proxy of the process. It is started once and
+// enforces the last policy given to ProxyURL.
+var proxy struct {
+	mu     sync.Mutex
+	url    string
+	policy atomic.Pointer[Policy]
+}
+
+// ProxyURL returns the URL of the local proxy enforcing the policy, starting
+// it the first time.
+func (p *Policy) ProxyURL() (string, error) {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+
+	proxy.policy.Store(p)
+	if proxy.url != "" {
+		return proxy.url, nil
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	server := &http.Server{
+		Handler:           http.HandlerFunc(serveProxy),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Egress proxy stopped", "error", err)
+		}
+	}()
+	proxy.url = "http://" + ln.Addr().String()
+	return proxy.url, nil
+}
+
+// ProxyEnv returns the environment variables pointing the commands to the
+// proxy.
+func ProxyEnv(proxyURL string) map[string]string {
+	return map[string]string{
+		"HTTP_PROXY":  proxyURL,
+		"HTTPS_PROXY": proxyURL,
+		"http_proxy":  proxyURL,
+		"https_proxy": proxyURL,
+		"NO_PROXY":    "",
+		"no_proxy":    "",
+	}
+}
+
+var proxyTransport = &http.Transport{
+	// The requests go straight to the hosts, not to the proxy of the user.
+	Proxy: nil,
+	DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return proxy.policy.Load().dial(ctx, dialer.DialContext, network, addr)
+	},
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 10,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+func serveProxy(w http.ResponseWriter, r *http.Request) {
+	policy := proxy.policy.Load()
+	host := r.URL.Hostname()
+	if r.Method == http.MethodConnect {
+		host, _, _ = net.SplitHostPort(r.Host)
+	}
+	if err := policy.Check(r.Context(), host); err != nil {
+		slog.Warn("Blocked connection", "host", host)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodConnect {
+		tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "this is a proxy, requests must use absolute URLs", http.StatusBadRequest)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.Header.Del("Proxy-Connection")
+	out.Header.Del("Proxy-Authorization")
+	resp, err := proxyTransport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for k, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// tunnel relays the bytes of a CONNECT request, used for HTTPS.
+func tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := proxy.policy.Load().dial(r.Context(), dialer.DialContext, "tcp", r.Host)
+	if errors.Is(err, ErrBlocked) {
+		slog.Warn("Blocked connection", "host", r.Host)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling is not supported", http.StatusInternalServerError)
+		return
+	}
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		upstream.Close()
+		client.Close()
+		return
+	}
+	go func() {
+		defer upstream.Close()
+		defer client.Close()
+		_, _ = io.Copy(upstream, client)
+	}()
+	go func() {
+		defer upstream.Close()
+		defer client.Close()
+		_, _ = io.Copy(client, upstream)
+	}()
+}
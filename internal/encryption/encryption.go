@@ -0,0 +1,240 @@
+// Package encryption keeps the session data of a data directory encrypted at
+// rest: the database and the artifacts.
+//
+// The key is either a random key kept in the keychain of the OS or derived
+// from a passphrase. The data directory records which one in FileName, with
+// a value encrypted with the key to tell a wrong passphrase from corrupted
+// data. Once a data directory is encrypted it stays so: the key is needed to
+// open it whatever the configuration says.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/keychain"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// FileName is the name of the description of the key in the data
+	// directory.
+	FileName = "encryption.json"
+
+	// PassphraseEnv holds the passphrase when the key is derived from one.
+	PassphraseEnv = "CRUSH_PASSPHRASE"
+
+	keySize  = 32
+	saltSize = 16
+)
+
+// Sources of the key.
+const (
+	SourceKeychain   = "keychain"
+	SourcePassphrase = "passphrase"
+)
+
+var (
+	// ErrLocked is returned when the key of an encrypted data directory
+	// isn't available.
+	ErrLocked = errors.New("the session data is encrypted, set " + PassphraseEnv + " or run crush in a terminal to unlock it")
+	// ErrWrongKey is returned when the key doesn't decrypt the data.
+	ErrWrongKey = errors.New("wrong passphrase or key")
+)
+
+// checkValue is encrypted with the key in FileName.
+var checkValue = []byte("crush")
+
+type descriptor struct {
+	Source string `json:"source"`
+	Salt   string `json:"salt,omitempty"`
+	Check  string `json:"check"`
+}
+
+// unlocked holds the keys of the data directories unlocked by the process.
+var unlocked = csync.NewMap[string, []byte]()
+
+func descriptorFile(dataDir string) string {
+	return filepath.Join(dataDir, FileName)
+}
+
+// Enabled reports whether the data directory is encrypted.
+func Enabled(dataDir string) bool {
+	_, err := os.Stat(descriptorFile(dataDir))
+	return err == nil
+}
+
+// Setup creates the key of the data directory and marks it as encrypted. The
+// data written before stays unencrypted until it is encrypted with the key.
+func Setup(dataDir, source, passphrase string) ([]byte, error) {
+	if Enabled(dataDir) {
+		return nil, fmt.Errorf("%s is already encrypted", dataDir)
+	}
+	d := descriptor{Source: source}
+	var key []byte
+	switch source {
+	case SourceKeychain:
+		key = make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := keychain.Set(account(dataDir), hex.EncodeToString(key)); err != nil {
+			return nil, fmt.Errorf("failed to store the key in the keychain: %w", err)
+		}
+	case SourcePassphrase:
+		if passphrase == "" {
+			return nil, errors.New("the passphrase is empty")
+		}
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		d.Salt = hex.EncodeToString(salt)
+		key = deriveKey(passphrase, salt)
+	default:
+		return nil, fmt.Errorf("unknown key source %q, use %s or %s", source, SourceKeychain, SourcePassphrase)
+	}
+
+	check, err := Seal(key, checkValue)
+	if err != nil {
+		return nil, err
+	}
+	d.Check = hex.EncodeToString(check)
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(descriptorFile(dataDir), data, 0o600); err != nil {
+		return nil, err
+	}
+	unlocked.Set(account(dataDir), key)
+	return key, nil
+}
+
+// Unlock returns the key of the encrypted data directory. The passphrase is
+// read from PassphraseEnv, or asked with prompt when it is unset; a nil
+// prompt makes Unlock return ErrLocked instead.
+func Unlock(dataDir string, prompt func() (string, error)) ([]byte, error) {
+	if key, ok := unlocked.Get(account(dataDir)); ok {
+		return key, nil
+	}
+	data, err := os.ReadFile(descriptorFile(dataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s is not encrypted", dataDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var d descriptor
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", descriptorFile(dataDir), err)
+	}
+
+	var key []byte
+	switch d.Source {
+	case SourceKeychain:
+		secret, err := keychain.Get(account(dataDir))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the key from the keychain: %w", err)
+		}
+		if key, err = hex.DecodeString(secret); err != nil {
+			return nil, fmt.Errorf("failed to read the key from the keychain: %w", err)
+		}
+	case SourcePassphrase:
+		passphrase := os.Getenv(PassphraseEnv)
+		if passphrase == "" {
+			if prompt == nil {
+				return nil, ErrLocked
+			}
+			if passphrase, err = prompt(); err != nil {
+				return nil, err
+			}
+		}
+		salt, err := hex.DecodeString(d.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", descriptorFile(dataDir), err)
+		}
+		key = deriveKey(passphrase, salt)
+	default:
+		return nil, fmt.Errorf("unknown key source %q in %s", d.Source, descriptorFile(dataDir))
+	}
+
+	check, err := hex.DecodeString(d.Check)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", descriptorFile(dataDir), err)
+	}
+	if plain, err := Open(key, check); err != nil || !bytes.Equal(plain, checkValue) {
+		return nil, ErrWrongKey
+	}
+	unlocked.Set(account(dataDir), key)
+	return key, nil
+}
+
+// Key returns the key of the data directory, or nil when it isn't
+// encrypted. Only the keys that don't need a prompt are read.
+func Key(dataDir string) ([]byte, error) {
+	if !Enabled(dataDir) {
+		return nil, nil
+	}
+	return Unlock(dataDir, nil)
+}
+
+// Seal encrypts and authenticates the data with AES-GCM.
+func Seal(key, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts data encrypted with Seal.
+func Open(key, ciphertext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrWrongKey
+	}
+	nonce, data := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plain, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func deriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, 1, 64*1024, 4, keySize)
+}
+
+// account identifies the key of the data directory in the keychain.
+func account(dataDir string) string {
+	if abs, err := filepath.Abs(dataDir); err == nil {
+		return abs
+	}
+	return dataDir
+}
@@ -0,0 +1,51 @@
+package encryption
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupAndUnlock(t *testing.T) {
+	dataDir := t.TempDir()
+	require.False(t, Enabled(dataDir))
+	key, err := Key(dataDir)
+	require.NoError(t, err)
+	require.Nil(t, key)
+
+	key, err = Setup(dataDir, SourcePassphrase, "correct horse")
+	require.NoError(t, err)
+	require.True(t, Enabled(dataDir))
+	_, err = Setup(dataDir, SourcePassphrase, "correct horse")
+	require.Error(t, err)
+
+	unlocked.Del(account(dataDir))
+	t.Setenv(PassphraseEnv, "")
+	_, err = Key(dataDir)
+	require.ErrorIs(t, err, ErrLocked)
+
+	_, err = Unlock(dataDir, func() (string, error) { return "wrong", nil })
+	require.ErrorIs(t, err, ErrWrongKey)
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	got, err := Key(dataDir)
+	require.NoError(t, err)
+	require.Equal(t, key, got)
+}
+
+func TestSealAndOpen(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, keySize)
+	sealed, err := Seal(key, []byte("secret"))
+	require.NoError(t, err)
+	require.NotContains(t, string(sealed), "secret")
+
+	plain, err := Open(key, sealed)
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(plain))
+
+	key[0] = 1
+	_, err = Open(key, sealed)
+	require.ErrorIs(t, err, ErrWrongKey)
+}
@@ -0,0 +1,254 @@
+// Package eval runs test cases against the agent, to catch the regressions
+// of a model swap or of a change of the system prompt.
+//
+// A case is a YAML file: the prompt, the files of the workspace it runs in,
+// and the expectations on the tools called, the final answer and the files
+// left in the workspace:
+//
+//	name: rename-function
+//	prompt: Rename the function foo to bar in main.go
+//	files:
+//	  main.go: |
+//	    package main
+//
+//	    func foo() {}
+//	expect:
+//	  tools: [edit]
+//	  no_tools: [bash]
+//	  answer:
+//	    contains: [bar]
+//	  files:
+//	    - path: main.go
+//	      contains: ["func bar()"]
+//	      not_contains: ["func foo()"]
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultTimeout bounds the run of the cases without a timeout.
+const defaultTimeout = 10 * time.Minute
+
+// Case is a test case, read from a YAML file.
+type Case struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Prompt      string `yaml:"prompt"`
+	// Files are written to the empty workspace the case runs in, by their
+	// path relative to it.
+	Files map[string]string `yaml:"files"`
+	// Timeout is a duration like 5m, the run failing past it.
+	Timeout string `yaml:"timeout"`
+	Expect  Expect `yaml:"expect"`
+
+	// Path is the file the case was read from.
+	Path    string `yaml:"-"`
+	timeout time.Duration
+}
+
+// Expect holds the assertions of a case.
+type Expect struct {
+	// Tools must all be called during the run, in any order.
+	Tools []string `yaml:"tools"`
+	// NoTools must not be called.
+	NoTools []string     `yaml:"no_tools"`
+	Answer  TextAssert   `yaml:"answer"`
+	Files   []FileAssert `yaml:"files"`
+}
+
+// TextAssert holds the assertions on a text. Matches are regular
+// expressions.
+type TextAssert struct {
+	Contains    []string `yaml:"contains"`
+	NotContains []string `yaml:"not_contains"`
+	Matches     []string `yaml:"matches"`
+}
+
+// FileAssert holds the assertions on a file of the workspace once the case
+// ran. The file must exist unless Exists is false.
+type FileAssert struct {
+	Path       string `yaml:"path"`
+	Exists     *bool  `yaml:"exists"`
+	TextAssert `yaml:",inline"`
+}
+
+// Load reads the cases of the YAML files of the directory and its
+// subdirectories, sorted by path.
+func Load(dir string) ([]Case, error) {
+	var cases []Case
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml") {
+			return nil
+		}
+		c, err := LoadCase(path)
+		if err != nil {
+			return err
+		}
+		cases = append(cases, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string)
+	for _, c := range cases {
+		if other, ok := names[c.Name]; ok {
+			return nil, fmt.Errorf("cases %s and %s are both named %q", other, c.Path, c.Name)
+		}
+		names[c.Name] = c.Path
+	}
+	return cases, nil
+}
+
+// LoadCase reads the case of a YAML file, named after the file unless it
+// has a name.
+func LoadCase(path string) (Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Case{}, err
+	}
+	var c Case
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return Case{}, fmt.Errorf("invalid case %s: %w", path, err)
+	}
+	c.Path = path
+	if c.Name == "" {
+		c.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := c.validate(); err != nil {
+		return Case{}, fmt.Errorf("invalid case %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *Case) validate() error {
+	if strings.TrimSpace(c.Prompt) == "" {
+		return errors.New("the prompt is empty")
+	}
+	c.timeout = defaultTimeout
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout %q", c.Timeout)
+		}
+		c.timeout = timeout
+	}
+	for path := range c.Files {
+		if !filepath.IsLocal(path) {
+			return fmt.Errorf("the file %s is out of the workspace", path)
+		}
+	}
+	texts := []TextAssert{c.Expect.Answer}
+	for _, f := range c.Expect.Files {
+		if !filepath.IsLocal(f.Path) {
+			return fmt.Errorf("the file %s is out of the workspace", f.Path)
+		}
+		texts = append(texts, f.TextAssert)
+	}
+	for _, text := range texts {
+		for _, pattern := range text.Matches {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RunTimeout returns the duration past which the run of the case fails.
+func (c Case) RunTimeout() time.Duration {
+	return c.timeout
+}
+
+// Setup writes the files of the case to the workspace.
+func (c Case) Setup(workspace string) error {
+	for path, content := range c.Files {
+		path = filepath.Join(workspace, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Outcome is what a run of a case did.
+type Outcome struct {
+	Answer string
+	// Tools are the names of the tools called, in order.
+	Tools []string
+	// Workspace is the directory the case ran in.
+	Workspace string
+}
+
+// Check returns the assertions of the case the outcome fails, none when it
+// passes.
+func (c Case) Check(o Outcome) []string {
+	var failures []string
+	for _, tool := range c.Expect.Tools {
+		if !slices.Contains(o.Tools, tool) {
+			failures = append(failures, fmt.Sprintf("the %s tool wasn't called", tool))
+		}
+	}
+	for _, tool := range c.Expect.NoTools {
+		if slices.Contains(o.Tools, tool) {
+			failures = append(failures, fmt.Sprintf("the %s tool was called", tool))
+		}
+	}
+	failures = append(failures, c.Expect.Answer.check("the answer", o.Answer)...)
+	for _, f := range c.Expect.Files {
+		data, err := os.ReadFile(filepath.Join(o.Workspace, f.Path))
+		exists := err == nil
+		switch {
+		case err != nil && !errors.Is(err, fs.ErrNotExist):
+			failures = append(failures, fmt.Sprintf("failed to read %s: %v", f.Path, err))
+			continue
+		case f.Exists != nil && !*f.Exists:
+			if exists {
+				failures = append(failures, fmt.Sprintf("%s exists", f.Path))
+			}
+			continue
+		case !exists:
+			failures = append(failures, fmt.Sprintf("%s doesn't exist", f.Path))
+			continue
+		}
+		failures = append(failures, f.check(f.Path, string(data))...)
+	}
+	return failures
+}
+
+func (a TextAssert) check(what, text string) []string {
+	var failures []string
+	for _, s := range a.Contains {
+		if !strings.Contains(text, s) {
+			failures = append(failures, fmt.Sprintf("%s doesn't contain %q", what, s))
+		}
+	}
+	for _, s := range a.NotContains {
+		if strings.Contains(text, s) {
+			failures = append(failures, fmt.Sprintf("%s contains %q", what, s))
+		}
+	}
+	for _, pattern := range a.Matches {
+		if !regexp.MustCompile(pattern).MatchString(text) {
+			failures = append(failures, fmt.Sprintf("%s doesn't match %q", what, pattern))
+		}
+	}
+	return failures
+}
@@ -0,0 +1,126 @@
+package eval
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const renameCase = `prompt: Rename the function foo to bar in main.go
+timeout: 2m
+files:
+  main.go: |
+    package main
+
+    func foo() {}
+expect:
+  tools: [edit]
+  no_tools: [bash]
+  answer:
+    contains: [bar]
+    matches: ["(?i)renamed"]
+  files:
+    - path: main.go
+      contains: ["func bar()"]
+      not_contains: ["func foo()"]
+    - path: foo.go
+      exists: false
+`
+
+func writeCase(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeCase(t, dir, "rename.yaml", renameCase)
+	writeCase(t, dir, "go/explain.yml", "name: explain-slices\nprompt: What is a slice?\n")
+	writeCase(t, dir, "README.md", "# Evals")
+
+	cases, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, cases, 2)
+	require.Equal(t, "explain-slices", cases[0].Name)
+	require.Equal(t, defaultTimeout, cases[0].RunTimeout())
+	require.Equal(t, "rename", cases[1].Name)
+	require.Equal(t, 2*time.Minute, cases[1].RunTimeout())
+	require.Equal(t, []string{"edit"}, cases[1].Expect.Tools)
+	require.Len(t, cases[1].Expect.Files, 2)
+	require.Equal(t, []string{"func bar()"}, cases[1].Expect.Files[0].Contains)
+	require.False(t, *cases[1].Expect.Files[1].Exists)
+
+	writeCase(t, dir, "other.yaml", "name: rename\nprompt: Rename it\n")
+	_, err = Load(dir)
+	require.ErrorContains(t, err, `are both named "rename"`)
+}
+
+func TestLoadCaseInvalid(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for content, want := range map[string]string{
+		"files:\n  a.go: x\n":                                 "the prompt is empty",
+		"prompt: x\ntimeout: soon\n":                          `invalid timeout "soon"`,
+		"prompt: x\nfiles:\n  ../a.go: x\n":                   "the file ../a.go is out of the workspace",
+		"prompt: x\nexpect:\n  answer:\n    matches: ['(']\n": `invalid pattern "("`,
+	} {
+		_, err := LoadCase(writeCase(t, dir, "case.yaml", content))
+		require.ErrorContains(t, err, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c, err := LoadCase(writeCase(t, dir, "rename.yaml", renameCase))
+	require.NoError(t, err)
+
+	workspace := t.TempDir()
+	require.NoError(t, c.Setup(workspace))
+	require.Equal(t, []string{
+		"the edit tool wasn't called",
+		"the bash tool was called",
+		`the answer doesn't contain "bar"`,
+		`the answer doesn't match "(?i)renamed"`,
+		`main.go doesn't contain "func bar()"`,
+		`main.go contains "func foo()"`,
+	}, c.Check(Outcome{Answer: "Done", Tools: []string{"view", "bash"}, Workspace: workspace}))
+
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main\n\nfunc bar() {}\n"), 0o644))
+	require.Empty(t, c.Check(Outcome{Answer: "Renamed foo to bar.", Tools: []string{"view", "edit"}, Workspace: workspace}))
+
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "foo.go"), nil, 0o644))
+	require.NoError(t, os.Remove(filepath.Join(workspace, "main.go")))
+	require.Equal(t, []string{"main.go doesn't exist", "foo.go exists"}, c.Check(Outcome{Answer: "Renamed foo to bar.", Tools: []string{"edit"}, Workspace: workspace}))
+}
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	var report Report
+	report.Add(Result{Case: "rename", Model: "anthropic/claude-sonnet-4", DurationMs: 1500, PromptTokens: 100, CompletionTokens: 20, Cost: 0.01})
+	report.Add(Result{Case: "rename", Model: "openai/gpt-4.1", Failures: []string{"the edit tool wasn't called"}})
+	report.Add(Result{Case: "explain", Model: "openai/gpt-4.1", Error: "the run failed: overloaded"})
+	require.Equal(t, 1, report.Passed)
+	require.Equal(t, 2, report.Failed)
+	require.True(t, report.Results[0].Passed)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteText(&buf))
+	out := buf.String()
+	require.Contains(t, out, "PASS    anthropic/claude-sonnet-4  rename   1.5s")
+	require.Contains(t, out, "rename (openai/gpt-4.1):\n  - the edit tool wasn't called\n")
+	require.Contains(t, out, "explain (openai/gpt-4.1):\n  - the run failed: overloaded\n")
+	require.Contains(t, out, "anthropic/claude-sonnet-4: 1/1 passed\nopenai/gpt-4.1: 0/2 passed\n")
+}
@@ -0,0 +1,107 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// Result is the result of a case run against a model.
+type Result struct {
+	Case  string `json:"case"`
+	Model string `json:"model"`
+	// Passed is set when the run completed and all the assertions passed.
+	Passed   bool     `json:"passed"`
+	Failures []string `json:"failures,omitempty"`
+	// Error tells why the run didn't complete.
+	Error            string  `json:"error,omitempty"`
+	DurationMs       int64   `json:"duration_ms"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Report holds the results of the cases for each model, in the order they
+// ran.
+type Report struct {
+	Results []Result `json:"results"`
+	Passed  int      `json:"passed"`
+	Failed  int      `json:"failed"`
+}
+
+func (r *Report) Add(result Result) {
+	result.Passed = result.Error == "" && len(result.Failures) == 0
+	if result.Passed {
+		r.Passed++
+	} else {
+		r.Failed++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// WriteText writes the report as a table of the results followed by their
+// failures and the totals of each model.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tMODEL\tCASE\tDURATION\tTOKENS\tCOST")
+	var models []string
+	for _, res := range r.Results {
+		if !slices.Contains(models, res.Model) {
+			models = append(models, res.Model)
+		}
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%d\t$%.4f\n",
+			resultLabel(res),
+			res.Model,
+			res.Case,
+			(time.Duration(res.DurationMs) * time.Millisecond).Round(100*time.Millisecond),
+			res.PromptTokens+res.CompletionTokens,
+			res.Cost,
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, res := range r.Results {
+		if res.Passed {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (%s):\n", res.Case, res.Model)
+		if res.Error != "" {
+			fmt.Fprintf(w, "  - %s\n", res.Error)
+		}
+		for _, failure := range res.Failures {
+			fmt.Fprintf(w, "  - %s\n", failure)
+		}
+	}
+
+	fmt.Fprintln(w)
+	for _, model := range models {
+		var passed, total int
+		for _, res := range r.Results {
+			if res.Model != model {
+				continue
+			}
+			total++
+			if res.Passed {
+				passed++
+			}
+		}
+		fmt.Fprintf(w, "%s: %d/%d passed\n", model, passed, total)
+	}
+	return nil
+}
+
+func resultLabel(res Result) string {
+	switch {
+	case res.Passed:
+		return "PASS"
+	case res.Error != "":
+		return "ERROR"
+	}
+	return "FAIL"
+}
@@ -0,0 +1,265 @@
+// Package filewatch tells the agent about the files it read or edited which
+// changed on disk since, like when the user edits them in an IDE or pulls
+// commits, so it doesn't work on stale content and overwrite the changes.
+package filewatch
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// MaxFiles is how many of the files last read or edited are watched for
+	// each session.
+	MaxFiles = 100
+	// MaxFileSize is the size above which the files are not watched.
+	MaxFileSize = 256 * 1024
+	// MaxDiffSize bounds the diff of a file in the changes.
+	MaxDiffSize = 4000
+)
+
+// Change is a file which changed since the agent last read or edited it.
+type Change struct {
+	Path string
+	// Diff is the unified diff from the content the agent knew, empty when
+	// the file was removed.
+	Diff    string
+	Removed bool
+}
+
+// snapshot is the content of a file the agent knows.
+type snapshot struct {
+	content string
+	at      time.Time
+}
+
+type session struct {
+	files map[string]*snapshot
+	// dirty are the files with events since they were last checked.
+	dirty map[string]bool
+}
+
+// Watcher watches the directories of the files known to the agent in each
+// session. The directories are watched, as editors often replace the files
+// instead of writing them.
+type Watcher struct {
+	mu       sync.Mutex
+	fsw      *fsnotify.Watcher
+	dirs     map[string]int
+	sessions map[string]*session
+}
+
+// New starts a watcher. Without file system notifications every known file
+// is checked for changes.
+func New() *Watcher {
+	w := &Watcher{
+		dirs:     make(map[string]int),
+		sessions: make(map[string]*session),
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("Error watching the files of the sessions", "error", err)
+		return w
+	}
+	w.fsw = fsw
+	go w.watch()
+	return w
+}
+
+func (w *Watcher) watch() {
+	for {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			path := filepath.Clean(event.Name)
+			w.mu.Lock()
+			for _, s := range w.sessions {
+				if _, ok := s.files[path]; ok {
+					s.dirty[path] = true
+				}
+			}
+			w.mu.Unlock()
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			slog.Debug("Error watching the files of the sessions", "error", err)
+		}
+	}
+}
+
+// Track records the content of the file on disk as the one known to the
+// agent of the session, after it read or edited it.
+func (w *Watcher) Track(sessionID, path string) {
+	path = filepath.Clean(path)
+	content, ok := readText(path)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.sessions[sessionID]
+	if s == nil {
+		s = &session{
+			files: make(map[string]*snapshot),
+			dirty: make(map[string]bool),
+		}
+		w.sessions[sessionID] = s
+	}
+	if !ok {
+		w.forget(s, path)
+		return
+	}
+	if _, known := s.files[path]; !known {
+		if len(s.files) == MaxFiles {
+			w.forget(s, oldest(s.files))
+		}
+		w.addDir(filepath.Dir(path))
+	}
+	s.files[path] = &snapshot{content: content, at: time.Now()}
+	delete(s.dirty, path)
+}
+
+// Changes returns the files of the session which changed since the agent
+// last read or edited them, their current content becoming the known one.
+func (w *Watcher) Changes(sessionID string) []Change {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.sessions[sessionID]
+	if s == nil {
+		return nil
+	}
+	var paths []string
+	for path := range s.files {
+		if w.fsw == nil || s.dirty[path] {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	var changes []Change
+	for _, path := range paths {
+		delete(s.dirty, path)
+		known := s.files[path]
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			changes = append(changes, Change{Path: path, Removed: true})
+			w.forget(s, path)
+			continue
+		}
+		content, ok := readText(path)
+		if !ok {
+			// It became too large or binary, it can't be compared anymore.
+			w.forget(s, path)
+			continue
+		}
+		if content == known.content {
+			continue
+		}
+		unified, _, _ := diff.GenerateDiff(known.content, content, path)
+		changes = append(changes, Change{Path: path, Diff: truncate(unified)})
+		known.content = content
+	}
+	return changes
+}
+
+// Close stops watching the files.
+func (w *Watcher) Close() error {
+	if w.fsw == nil {
+		return nil
+	}
+	return w.fsw.Close()
+}
+
+func (w *Watcher) forget(s *session, path string) {
+	if _, ok := s.files[path]; !ok {
+		return
+	}
+	delete(s.files, path)
+	delete(s.dirty, path)
+	w.removeDir(filepath.Dir(path))
+}
+
+func (w *Watcher) addDir(dir string) {
+	w.dirs[dir]++
+	if w.dirs[dir] > 1 || w.fsw == nil {
+		return
+	}
+	if err := w.fsw.Add(dir); err != nil {
+		slog.Debug("Error watching directory", "dir", dir, "error", err)
+	}
+}
+
+func (w *Watcher) removeDir(dir string) {
+	w.dirs[dir]--
+	if w.dirs[dir] > 0 {
+		return
+	}
+	delete(w.dirs, dir)
+	if w.fsw != nil {
+		_ = w.fsw.Remove(dir)
+	}
+}
+
+func oldest(files map[string]*snapshot) string {
+	var path string
+	var at time.Time
+	for p, s := range files {
+		if path == "" || s.at.Before(at) {
+			path, at = p, s.at
+		}
+	}
+	return path
+}
+
+// readText reads the file, when it is small enough and text.
+func readText(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Size() > MaxFileSize {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !utf8.Valid(data) {
+		return "", false
+	}
+	return string(data), true
+}
+
+func truncate(unified string) string {
+	if len(unified) <= MaxDiffSize {
+		return unified
+	}
+	cut := strings.LastIndex(unified[:MaxDiffSize], "\n")
+	if cut < 0 {
+		cut = MaxDiffSize - 1
+	}
+	return unified[:cut+1] + "... (diff truncated, view the file for the rest)\n"
+}
+
+var defaultWatcher = sync.OnceValue(New)
+
+// Track records the content of the file as the one known to the agent of
+// the session, with the watcher of the process.
+func Track(sessionID, path string) {
+	if sessionID == "" {
+		return
+	}
+	defaultWatcher().Track(sessionID, path)
+}
+
+// Changes returns the files of the session which changed since the agent
+// last read or edited them, with the watcher of the process.
+func Changes(sessionID string) []Change {
+	return defaultWatcher().Changes(sessionID)
+}
@@ -0,0 +1,95 @@
+package filewatch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func waitChanges(t *testing.T, w *Watcher, sessionID string) []Change {
+	t.Helper()
+	var changes []Change
+	require.Eventually(t, func() bool {
+		changes = w.Changes(sessionID)
+		return len(changes) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	return changes
+}
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+
+	w := New()
+	t.Cleanup(func() { w.Close() })
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0o644))
+	w.Track("session", path)
+	require.Empty(t, w.Changes("session"))
+
+	// A write of the agent is tracked with its content, it isn't a change.
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc main() {\n}\n"), 0o644))
+	w.Track("session", path)
+	time.Sleep(50 * time.Millisecond)
+	require.Empty(t, w.Changes("session"))
+
+	// The user replaces the file, like editors do.
+	tmp := filepath.Join(dir, "main.go.tmp")
+	require.NoError(t, os.WriteFile(tmp, []byte("package main\n\nfunc main() {\n\tprintln(1)\n}\n"), 0o644))
+	require.NoError(t, os.Rename(tmp, path))
+	changes := waitChanges(t, w, "session")
+	require.Len(t, changes, 1)
+	require.Equal(t, path, changes[0].Path)
+	require.False(t, changes[0].Removed)
+	require.Contains(t, changes[0].Diff, "+\tprintln(1)\n")
+	// The change is only reported once.
+	require.Empty(t, w.Changes("session"))
+	require.Empty(t, w.Changes("other"))
+
+	require.NoError(t, os.Remove(path))
+	changes = waitChanges(t, w, "session")
+	require.Equal(t, []Change{{Path: path, Removed: true}}, changes)
+}
+
+func TestWatcherWithoutNotifications(t *testing.T) {
+	t.Parallel()
+
+	w := &Watcher{
+		dirs:     make(map[string]int),
+		sessions: make(map[string]*session),
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("one\n"), 0o644))
+	w.Track("session", path)
+	require.Empty(t, w.Changes("session"))
+
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("two\n", MaxDiffSize)), 0o644))
+	changes := w.Changes("session")
+	require.Len(t, changes, 1)
+	require.LessOrEqual(t, len(changes[0].Diff), MaxDiffSize+100)
+	require.True(t, strings.HasSuffix(changes[0].Diff, "(diff truncated, view the file for the rest)\n"))
+}
+
+func TestWatcherMaxFiles(t *testing.T) {
+	t.Parallel()
+
+	w := &Watcher{
+		dirs:     make(map[string]int),
+		sessions: make(map[string]*session),
+	}
+	dir := t.TempDir()
+	for i := range MaxFiles + 1 {
+		path := filepath.Join(dir, strings.Repeat("a", i+1))
+		require.NoError(t, os.WriteFile(path, []byte("text\n"), 0o644))
+		w.Track("session", path)
+	}
+	files := w.sessions["session"].files
+	require.Len(t, files, MaxFiles)
+	require.NotContains(t, files, filepath.Join(dir, "a"))
+	require.Equal(t, map[string]int{dir: MaxFiles}, w.dirs)
+}
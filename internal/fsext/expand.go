@@ -21,9 +21,19 @@ func Expand(s string) (string, error) {
 		return "", err
 	}
 	cfg := &expand.Config{
-		Env:      expand.FuncEnviron(os.Getenv),
+		Env:      expand.FuncEnviron(getenv),
 		ReadDir2: os.ReadDir,
 		GlobStar: true,
 	}
 	return expand.Literal(cfg, word)
 }
+
+// getenv returns the environment variable, HOME being the home directory of
+// the user when it isn't set, like on Windows, for '~' to expand.
+func getenv(name string) string {
+	value := os.Getenv(name)
+	if value == "" && name == "HOME" {
+		return HomeDir()
+	}
+	return value
+}
@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/charlievieth/fastwalk"
+	"github.com/charmbracelet/crush/internal/csync"
 
 	ignore "github.com/sabhiram/go-gitignore"
 )
@@ -17,6 +21,7 @@ import (
 type FileInfo struct {
 	Path    string
 	ModTime time.Time
+	Size    int64
 }
 
 func SkipHidden(path string) bool {
@@ -56,35 +61,19 @@ func SkipHidden(path string) bool {
 	return false
 }
 
-// FastGlobWalker provides gitignore-aware file walking with fastwalk
+// FastGlobWalker provides gitignore-aware file walking with fastwalk. The
+// .gitignore and .crushignore files of the directories under the root apply
+// to their paths, like in git.
 type FastGlobWalker struct {
-	gitignore   *ignore.GitIgnore
-	crushignore *ignore.GitIgnore
-	rootPath    string
+	rootPath string
+	ignores  *csync.Map[string, *ignore.GitIgnore]
 }
 
 func NewFastGlobWalker(searchPath string) *FastGlobWalker {
-	walker := &FastGlobWalker{
-		rootPath: searchPath,
+	return &FastGlobWalker{
+		rootPath: filepath.Clean(searchPath),
+		ignores:  csync.NewMap[string, *ignore.GitIgnore](),
 	}
-
-	// Load gitignore if it exists
-	gitignorePath := filepath.Join(searchPath, ".gitignore")
-	if _, err := os.Stat(gitignorePath); err == nil {
-		if gi, err := ignore.CompileIgnoreFile(gitignorePath); err == nil {
-			walker.gitignore = gi
-		}
-	}
-
-	// Load crushignore if it exists
-	crushignorePath := filepath.Join(searchPath, ".crushignore")
-	if _, err := os.Stat(crushignorePath); err == nil {
-		if ci, err := ignore.CompileIgnoreFile(crushignorePath); err == nil {
-			walker.crushignore = ci
-		}
-	}
-
-	return walker
 }
 
 // ShouldSkip checks if a path should be skipped based on gitignore, crushignore, and hidden file rules
@@ -93,28 +82,65 @@ func (w *FastGlobWalker) ShouldSkip(path string) bool {
 		return true
 	}
 
+	path = filepath.Clean(path)
 	relPath, err := filepath.Rel(w.rootPath, path)
-	if err != nil {
+	if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
 		return false
 	}
 
-	if w.gitignore != nil {
-		if w.gitignore.MatchesPath(relPath) {
-			return true
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if ign := w.ignoreOf(dir); ign != nil {
+			if rel, err := filepath.Rel(dir, path); err == nil && ign.MatchesPath(rel) {
+				return true
+			}
 		}
-	}
-
-	if w.crushignore != nil {
-		if w.crushignore.MatchesPath(relPath) {
-			return true
+		if dir == w.rootPath || dir == filepath.Dir(dir) {
+			return false
 		}
 	}
+}
 
-	return false
+// ignoreOf returns the rules of the .gitignore and .crushignore files of the
+// directory, nil without them.
+func (w *FastGlobWalker) ignoreOf(dir string) *ignore.GitIgnore {
+	return w.ignores.GetOrSet(dir, func() *ignore.GitIgnore {
+		var lines []string
+		for _, name := range []string{".gitignore", ".crushignore"} {
+			if content, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+				lines = append(lines, strings.Split(string(content), "\n")...)
+			}
+		}
+		if len(lines) == 0 {
+			return nil
+		}
+		return ignore.CompileIgnoreLines(lines...)
+	})
 }
 
 func GlobWithDoubleStar(pattern, searchPath string, limit int) ([]string, bool, error) {
+	matches, truncated, err := GlobFiles([]string{pattern}, searchPath, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	results := make([]string, len(matches))
+	for i, m := range matches {
+		results[i] = m.Path
+	}
+	return results, truncated, nil
+}
+
+// GlobFiles returns the files under the search path matching any of the
+// doublestar patterns, which support brace expansion, skipping the ignored
+// ones. The most recently modified files come first, at most limit of them
+// when it is positive.
+func GlobFiles(patterns []string, searchPath string, limit int) ([]FileInfo, bool, error) {
+	for _, pattern := range patterns {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, false, fmt.Errorf("invalid glob pattern: %s", pattern)
+		}
+	}
 	walker := NewFastGlobWalker(searchPath)
+	var mu sync.Mutex
 	var matches []FileInfo
 	conf := fastwalk.Config{
 		Follow: true,
@@ -143,9 +169,12 @@ func GlobWithDoubleStar(pattern, searchPath string, limit int) ([]string, bool,
 		if err != nil {
 			relPath = path
 		}
+		relPath = filepath.ToSlash(relPath)
 
-		matched, err := doublestar.Match(pattern, relPath)
-		if err != nil || !matched {
+		if !slices.ContainsFunc(patterns, func(pattern string) bool {
+			matched, err := doublestar.Match(pattern, relPath)
+			return err == nil && matched
+		}) {
 			return nil
 		}
 
@@ -154,40 +183,52 @@ func GlobWithDoubleStar(pattern, searchPath string, limit int) ([]string, bool,
 			return nil
 		}
 
-		matches = append(matches, FileInfo{Path: path, ModTime: info.ModTime()})
-		if limit > 0 && len(matches) >= limit*2 {
-			return filepath.SkipAll
-		}
+		mu.Lock()
+		matches = append(matches, FileInfo{Path: path, ModTime: info.ModTime(), Size: info.Size()})
+		mu.Unlock()
 		return nil
 	})
 	if err != nil {
 		return nil, false, fmt.Errorf("fastwalk error: %w", err)
 	}
 
-	sort.Slice(matches, func(i, j int) bool {
-		return matches[i].ModTime.After(matches[j].ModTime)
-	})
+	SortByModTime(matches)
 
 	truncated := false
 	if limit > 0 && len(matches) > limit {
 		matches = matches[:limit]
 		truncated = true
 	}
+	return matches, truncated, nil
+}
 
-	results := make([]string, len(matches))
-	for i, m := range matches {
-		results[i] = m.Path
-	}
-	return results, truncated, nil
+// SortByModTime sorts the files with the most recently modified first, then
+// by path.
+func SortByModTime(files []FileInfo) {
+	sort.SliceStable(files, func(i, j int) bool {
+		if !files[i].ModTime.Equal(files[j].ModTime) {
+			return files[i].ModTime.After(files[j].ModTime)
+		}
+		return files[i].Path < files[j].Path
+	})
 }
 
+// PrettyPath replaces the home directory the path is in with ~.
 func PrettyPath(path string) string {
-	// replace home directory with ~
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		path = strings.ReplaceAll(path, homeDir, "~")
+	homeDir := HomeDir()
+	if homeDir == "" || len(path) < len(homeDir) {
+		return path
 	}
-	return path
+	prefix := path[:len(homeDir)]
+	// The paths of Windows aren't case sensitive.
+	if prefix != homeDir && (runtime.GOOS != "windows" || !strings.EqualFold(prefix, homeDir)) {
+		return path
+	}
+	rest := path[len(homeDir):]
+	if rest != "" && rest[0] != '/' && rest[0] != filepath.Separator {
+		return path
+	}
+	return "~" + rest
 }
 
 func DirTrim(pwd string, lim int) string {
@@ -242,10 +283,10 @@ func ToUnixLineEndings(content string) (string, bool) {
 	return content, false
 }
 
-// ToWindowsLineEndings converts Unix line endings (LF) to Windows line endings (CRLF).
+// ToWindowsLineEndings converts Unix line endings (LF) to Windows line endings (CRLF),
+// including the ones of content mixing both.
 func ToWindowsLineEndings(content string) (string, bool) {
-	if !strings.Contains(content, "\r\n") {
-		return strings.ReplaceAll(content, "\n", "\r\n"), true
-	}
-	return content, false
+	unix, _ := ToUnixLineEndings(content)
+	windows := strings.ReplaceAll(unix, "\n", "\r\n")
+	return windows, windows != content
 }
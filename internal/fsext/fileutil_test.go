@@ -0,0 +1,47 @@
+package fsext
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobFiles(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"src/app.ts", "src/view.tsx", "test/app_test.go", "main.go", "README.md", "src/gen.ts"} {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(name), 0o644))
+		modTime := now.Add(-time.Duration(i) * time.Hour)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "src", ".crushignore"), []byte("gen.ts\n"), 0o644))
+
+	files, truncated, err := GlobFiles([]string{"src/**/*.{ts,tsx}", "**/*.go"}, tmpDir, 0)
+	require.NoError(t, err)
+	require.False(t, truncated)
+	var paths []string
+	for _, file := range files {
+		rel, err := filepath.Rel(tmpDir, file.Path)
+		require.NoError(t, err)
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	// The most recently modified files come first.
+	require.Equal(t, []string{"src/app.ts", "src/view.tsx", "test/app_test.go", "main.go"}, paths)
+	require.Equal(t, int64(len("src/app.ts")), files[0].Size)
+
+	files, truncated, err = GlobFiles([]string{"**/*.go"}, tmpDir, 1)
+	require.NoError(t, err)
+	require.True(t, truncated)
+	require.Len(t, files, 1)
+	require.Equal(t, filepath.Join(tmpDir, "test", "app_test.go"), files[0].Path)
+
+	_, _, err = GlobFiles([]string{"src/[a"}, tmpDir, 0)
+	require.Error(t, err)
+}
@@ -4,6 +4,8 @@ import (
 	"cmp"
 	"os"
 	"os/user"
+	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -18,3 +20,26 @@ var HomeDir = sync.OnceValue(func() string {
 		os.Getenv("HOMEPATH"),
 	)
 })
+
+// ExpandHome replaces the leading ~ of the path with the home directory,
+// with either separator on Windows.
+func ExpandHome(path string) string {
+	if path == "~" {
+		return HomeDir()
+	}
+	if strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return filepath.Join(HomeDir(), path[2:])
+	}
+	return path
+}
+
+// AbsPath returns the path given to a tool as an absolute path, relative
+// paths being in the working directory. Absolute paths let Go handle the
+// paths longer than MAX_PATH on Windows.
+func AbsPath(path, workingDir string) string {
+	path = ExpandHome(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	return filepath.Clean(path)
+}
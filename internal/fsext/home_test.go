@@ -0,0 +1,43 @@
+package fsext
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbsPath(t *testing.T) {
+	t.Parallel()
+
+	workingDir := filepath.FromSlash("/repo")
+	require.Equal(t, filepath.FromSlash("/repo/internal/app.go"), AbsPath("internal/../internal/app.go", workingDir))
+	require.Equal(t, filepath.FromSlash("/etc/hosts"), AbsPath(filepath.FromSlash("/etc/hosts"), workingDir))
+	require.Equal(t, workingDir, AbsPath("", workingDir))
+	require.Equal(t, filepath.Join(HomeDir(), ".config"), AbsPath("~/.config", workingDir))
+	// Variables aren't expanded in the paths.
+	require.Equal(t, filepath.FromSlash("/repo/$HOME"), AbsPath("$HOME", workingDir))
+}
+
+func TestPrettyPath(t *testing.T) {
+	t.Parallel()
+
+	home := HomeDir()
+	require.Equal(t, "~", PrettyPath(home))
+	require.Equal(t, "~"+string(filepath.Separator)+"src", PrettyPath(filepath.Join(home, "src")))
+	// Only the home directory the path is in is replaced.
+	require.Equal(t, home+"-backup", PrettyPath(home+"-backup"))
+	require.Equal(t, filepath.Join("/tmp", home), PrettyPath(filepath.Join("/tmp", home)))
+}
+
+func TestLineEndings(t *testing.T) {
+	t.Parallel()
+
+	content, changed := ToWindowsLineEndings("a\nb\r\nc\n")
+	require.True(t, changed)
+	require.Equal(t, "a\r\nb\r\nc\r\n", content)
+
+	content, changed = ToWindowsLineEndings("a\r\nb\r\n")
+	require.False(t, changed)
+	require.Equal(t, "a\r\nb\r\n", content)
+}
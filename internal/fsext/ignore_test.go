@@ -2,6 +2,7 @@ package fsext
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -30,3 +31,20 @@ func TestCrushIgnore(t *testing.T) {
 	require.False(t, dl.shouldIgnore("test1.txt", nil), ".txt files should not be ignored")
 	require.True(t, dl.shouldIgnore("test3.tmp", nil), ".tmp files should be ignored by common patterns")
 }
+
+func TestFastGlobWalkerNestedIgnores(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "sub", "deep"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("*.log\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "sub", ".crushignore"), []byte("/gen.go\n"), 0o644))
+
+	w := NewFastGlobWalker(tempDir)
+	require.True(t, w.ShouldSkip(filepath.Join(tempDir, "sub", "deep", "app.log")))
+	require.True(t, w.ShouldSkip(filepath.Join(tempDir, "sub", "gen.go")))
+	// The patterns are relative to the directory of their ignore file.
+	require.False(t, w.ShouldSkip(filepath.Join(tempDir, "sub", "deep", "gen.go")))
+	require.False(t, w.ShouldSkip(filepath.Join(tempDir, "gen.go")))
+	require.False(t, w.ShouldSkip(filepath.Join(tempDir, "sub", "main.go")))
+}
@@ -35,6 +35,10 @@ type Service interface {
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
 	Delete(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
+
+	RecordRead(ctx context.Context, sessionID, path string, line int) error
+	RecordWrite(ctx context.Context, sessionID, path string, line int) error
+	ListTouched(ctx context.Context, sessionID string) ([]TouchedFile, error)
 }
 
 type service struct {
@@ -208,3 +212,47 @@ func (s *service) fromDBItem(item db.File) File {
 		UpdatedAt: item.UpdatedAt,
 	}
 }
+
+// FileCheckpoint is the content of a file at a checkpoint.
+type FileCheckpoint struct {
+	Path    string
+	Content string
+	// Missing is set when the file was created after the checkpoint.
+	Missing bool
+}
+
+// Checkpoint returns the content the files of the session had at the time,
+// a Unix timestamp, for the files changed since. The files are the versions
+// of the session, sorted by version as ListBySession returns them.
+func Checkpoint(files []File, at int64) []FileCheckpoint {
+	var paths []string
+	before := make(map[string]*File)
+	after := make(map[string]*File)
+	for i := range files {
+		f := &files[i]
+		if f.CreatedAt < at {
+			before[f.Path] = f
+			continue
+		}
+		if _, ok := after[f.Path]; !ok {
+			after[f.Path] = f
+			paths = append(paths, f.Path)
+		}
+	}
+	checkpoints := make([]FileCheckpoint, 0, len(paths))
+	for _, path := range paths {
+		if f, ok := before[path]; ok {
+			checkpoints = append(checkpoints, FileCheckpoint{Path: path, Content: f.Content})
+			continue
+		}
+		// The first version of a file changed after the checkpoint is its
+		// content before the change, empty when the change created it.
+		first := after[path]
+		checkpoints = append(checkpoints, FileCheckpoint{
+			Path:    path,
+			Content: first.Content,
+			Missing: first.Version == InitialVersion && first.Content == "",
+		})
+	}
+	return checkpoints
+}
@@ -0,0 +1,29 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	files := []File{
+		{Path: "main.go", Content: "v0", Version: InitialVersion, CreatedAt: 10},
+		{Path: "main.go", Content: "v1", Version: 1, CreatedAt: 20},
+		{Path: "util.go", Content: "u0", Version: InitialVersion, CreatedAt: 30},
+		{Path: "new.go", Content: "", Version: InitialVersion, CreatedAt: 30},
+		{Path: "main.go", Content: "v2", Version: 2, CreatedAt: 40},
+		{Path: "new.go", Content: "n1", Version: 1, CreatedAt: 40},
+		{Path: "util.go", Content: "u1", Version: 1, CreatedAt: 40},
+		{Path: "old.go", Content: "o0", Version: InitialVersion, CreatedAt: 5},
+	}
+
+	require.Equal(t, []FileCheckpoint{
+		{Path: "util.go", Content: "u0"},
+		{Path: "new.go", Missing: true},
+		{Path: "main.go", Content: "v1"},
+	}, Checkpoint(files, 30))
+	require.Empty(t, Checkpoint(files, 50))
+}
@@ -0,0 +1,58 @@
+package history
+
+import (
+	"context"
+
+	"github.com/charmbracelet/crush/internal/db"
+)
+
+// TouchedFile is an entry of the index of files read or modified in a
+// session.
+type TouchedFile struct {
+	SessionID  string
+	Path       string
+	ReadCount  int64
+	WriteCount int64
+	// LastLine is the line of the last read or modification, starting at 1.
+	LastLine  int64
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+func (s *service) RecordRead(ctx context.Context, sessionID, path string, line int) error {
+	return s.q.RecordSessionFileRead(ctx, db.RecordSessionFileReadParams{
+		SessionID: sessionID,
+		Path:      path,
+		LastLine:  int64(line),
+	})
+}
+
+func (s *service) RecordWrite(ctx context.Context, sessionID, path string, line int) error {
+	return s.q.RecordSessionFileWrite(ctx, db.RecordSessionFileWriteParams{
+		SessionID: sessionID,
+		Path:      path,
+		LastLine:  int64(line),
+	})
+}
+
+// ListTouched returns the files read or modified in the session, most
+// recently touched first.
+func (s *service) ListTouched(ctx context.Context, sessionID string) ([]TouchedFile, error) {
+	dbFiles, err := s.q.ListSessionFiles(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]TouchedFile, len(dbFiles))
+	for i, f := range dbFiles {
+		files[i] = TouchedFile{
+			SessionID:  f.SessionID,
+			Path:       f.Path,
+			ReadCount:  f.ReadCount,
+			WriteCount: f.WriteCount,
+			LastLine:   f.LastLine,
+			CreatedAt:  f.CreatedAt,
+			UpdatedAt:  f.UpdatedAt,
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,188 @@
+// Package hooks runs the commands configured for the events of the agent,
+// like formatting the files it edits, gating its tool calls or notifying
+// when it ends its turn. The commands read the event as JSON on their
+// standard input and can block or change the action.
+package hooks
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+const (
+	defaultTimeout = 60 * time.Second
+	// BlockExitCode is the exit code of the commands blocking the action,
+	// their standard error being the reason.
+	BlockExitCode = 2
+	// maxReasonSize bounds what the commands tell the agent.
+	maxReasonSize = 4000
+)
+
+// Input is the event written as JSON to the standard input of the commands.
+type Input struct {
+	Event      config.HookEvent `json:"event"`
+	SessionID  string           `json:"session_id"`
+	WorkingDir string           `json:"cwd"`
+	// ToolName and ToolInput are the tool call, for pre_tool_call and
+	// post_edit.
+	ToolName  string          `json:"tool_name,omitempty"`
+	ToolInput json.RawMessage `json:"tool_input,omitempty"`
+	// FilePath is the edited file, for post_edit.
+	FilePath string `json:"file_path,omitempty"`
+	// Response is the last answer of the agent, for turn_complete.
+	Response string `json:"response,omitempty"`
+	// EditedFiles are the files edited during the turn, for turn_complete.
+	EditedFiles []string `json:"edited_files,omitempty"`
+}
+
+// Output is what the commands can write as JSON to their standard output
+// when they succeed.
+type Output struct {
+	// Decision is "block" to block the action.
+	Decision string `json:"decision,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	// ToolInput replaces the input of the tool call, for pre_tool_call.
+	ToolInput json.RawMessage `json:"tool_input,omitempty"`
+	// Message is told to the agent without blocking the action.
+	Message string `json:"message,omitempty"`
+}
+
+// Result is the outcome of the commands of an event.
+type Result struct {
+	Blocked bool
+	// Reason tells the agent why the action was blocked.
+	Reason string
+	// ToolInput is the input the tool call runs with, set when a command
+	// changed it.
+	ToolInput json.RawMessage
+	// Messages are what the commands told the agent.
+	Messages []string
+}
+
+// Has reports whether hooks are enabled for the event.
+func Has(hooks config.Hooks, event config.HookEvent) bool {
+	for _, h := range hooks {
+		if h.Event == event && !h.Disabled {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs the commands of the event of the input matching it, in the order
+// of their names, until one blocks the action. The commands failing
+// otherwise are logged and don't block it.
+func Run(ctx context.Context, hooks config.Hooks, in Input) Result {
+	var result Result
+	for _, h := range hooks.Sorted() {
+		if h.Hook.Event != in.Event || h.Hook.Disabled || !matches(h.Hook.Matcher, in) {
+			continue
+		}
+		out, blocked, err := run(ctx, h.Hook, in)
+		if err != nil {
+			slog.Warn("Hook failed", "hook", h.Name, "event", in.Event, "error", err)
+			continue
+		}
+		if out.Message != "" {
+			result.Messages = append(result.Messages, truncate(out.Message))
+		}
+		if blocked || out.Decision == "block" {
+			result.Blocked = true
+			result.Reason = fmt.Sprintf("Blocked by the %s hook: %s", h.Name, truncate(cmp.Or(out.Reason, "no reason given")))
+			return result
+		}
+		if len(out.ToolInput) > 0 {
+			in.ToolInput = out.ToolInput
+			result.ToolInput = out.ToolInput
+		}
+	}
+	return result
+}
+
+// matches reports whether the glob matches the tool name of pre_tool_call
+// or the file of post_edit. The globs without a slash match the base name
+// of the file.
+func matches(glob string, in Input) bool {
+	var target string
+	switch {
+	case glob == "":
+		return true
+	case in.Event == config.HookPreToolCall:
+		target = in.ToolName
+	case in.Event == config.HookPostEdit:
+		target = in.FilePath
+		if rel, err := filepath.Rel(in.WorkingDir, target); err == nil && !strings.HasPrefix(rel, "..") {
+			target = rel
+		}
+		target = filepath.ToSlash(target)
+		if !strings.Contains(glob, "/") {
+			target = path.Base(target)
+		}
+	default:
+		return true
+	}
+	matched, err := doublestar.Match(glob, target)
+	return err == nil && matched
+}
+
+// run runs the command of the hook, returning its output and whether it
+// exited with BlockExitCode.
+func run(ctx context.Context, hook config.HookConfig, in Input) (Output, bool, error) {
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return Output{}, false, err
+	}
+	timeout := defaultTimeout
+	if hook.Timeout > 0 {
+		timeout = time.Duration(hook.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	env := append(os.Environ(),
+		"CRUSH_HOOK_EVENT="+string(in.Event),
+		"CRUSH_SESSION_ID="+in.SessionID,
+		"CRUSH_TOOL_NAME="+in.ToolName,
+		"CRUSH_FILE="+in.FilePath,
+	)
+	sh := shell.NewShell(&shell.Options{WorkingDir: in.WorkingDir, Env: env})
+	stdout, stderr, err := sh.ExecWithInput(ctx, hook.Command, bytes.NewReader(payload))
+	stdout, stderr = strings.TrimSpace(stdout), strings.TrimSpace(stderr)
+	switch code := shell.ExitCode(err); {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return Output{}, false, fmt.Errorf("timed out after %s", timeout)
+	case code == BlockExitCode:
+		return Output{Reason: cmp.Or(stderr, stdout)}, true, nil
+	case err != nil:
+		return Output{}, false, fmt.Errorf("%w: %s", err, cmp.Or(stderr, stdout))
+	}
+
+	var out Output
+	if strings.HasPrefix(stdout, "{") {
+		if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+			return Output{}, false, fmt.Errorf("invalid output: %w", err)
+		}
+	}
+	return out, false, nil
+}
+
+func truncate(s string) string {
+	if len(s) <= maxReasonSize {
+		return s
+	}
+	return s[:maxReasonSize] + "... (truncated)"
+}
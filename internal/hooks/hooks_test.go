@@ -0,0 +1,87 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	in := Input{
+		Event:      config.HookPreToolCall,
+		SessionID:  "session",
+		WorkingDir: dir,
+		ToolName:   "bash",
+		ToolInput:  json.RawMessage(`{"command":"rm -rf build"}`),
+	}
+
+	t.Run("block", func(t *testing.T) {
+		t.Parallel()
+		result := Run(t.Context(), config.Hooks{
+			"gate": {Event: config.HookPreToolCall, Matcher: "bash", Command: `grep -q 'rm -rf' && echo "no recursive removals" >&2 && exit 2 || true`},
+		}, in)
+		require.True(t, result.Blocked)
+		require.Equal(t, "Blocked by the gate hook: no recursive removals", result.Reason)
+	})
+
+	t.Run("modify", func(t *testing.T) {
+		t.Parallel()
+		result := Run(t.Context(), config.Hooks{
+			"a-rewrite": {Event: config.HookPreToolCall, Command: `echo '{"tool_input":{"command":"rm -r build"},"message":"rewrote the command"}'`},
+			// The hooks after see the new input.
+			"b-check": {Event: config.HookPreToolCall, Command: `grep -q 'rm -rf' && exit 2 || true`},
+		}, in)
+		require.False(t, result.Blocked)
+		require.JSONEq(t, `{"command":"rm -r build"}`, string(result.ToolInput))
+		require.Equal(t, []string{"rewrote the command"}, result.Messages)
+	})
+
+	t.Run("skipped", func(t *testing.T) {
+		t.Parallel()
+		result := Run(t.Context(), config.Hooks{
+			"other tool":  {Event: config.HookPreToolCall, Matcher: "edit", Command: "exit 2"},
+			"other event": {Event: config.HookPostEdit, Command: "exit 2"},
+			"disabled":    {Event: config.HookPreToolCall, Command: "exit 2", Disabled: true},
+			// Failing commands don't block the action.
+			"failing": {Event: config.HookPreToolCall, Command: "exit 1"},
+		}, in)
+		require.Equal(t, Result{}, result)
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(dir, "main.go")
+		require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+		result := Run(t.Context(), config.Hooks{
+			"format": {Event: config.HookPostEdit, Matcher: "*.go", Command: `echo "$CRUSH_HOOK_EVENT" >> "$CRUSH_FILE"`},
+		}, Input{Event: config.HookPostEdit, WorkingDir: dir, FilePath: path})
+		require.False(t, result.Blocked)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "package main\npost_edit\n", string(content))
+	})
+}
+
+func TestMatches(t *testing.T) {
+	t.Parallel()
+
+	edit := Input{Event: config.HookPostEdit, WorkingDir: "/project", FilePath: "/project/internal/app/app.go"}
+	require.True(t, matches("", edit))
+	require.True(t, matches("*.go", edit))
+	require.True(t, matches("internal/**/*.go", edit))
+	require.False(t, matches("cmd/**", edit))
+	require.False(t, matches("*.ts", edit))
+
+	call := Input{Event: config.HookPreToolCall, ToolName: "mcp_github_create_issue"}
+	require.True(t, matches("mcp_*", call))
+	require.False(t, matches("bash", call))
+	// The matcher only applies to the tool and edit events.
+	require.True(t, matches("bash", Input{Event: config.HookTurnComplete}))
+}
@@ -0,0 +1,144 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+const defaultPostEditTimeout = 30 * time.Second
+
+// builtinFormatters and builtinLinters run on the edited files when their
+// program is installed.
+var (
+	builtinFormatters = map[string]string{
+		"*.go": "gofmt -w {file}",
+		"*.{js,jsx,mjs,cjs,ts,tsx,css,scss,json}": "prettier --write --log-level warn {file}",
+		"*.py": "black --quiet {file}",
+	}
+	builtinLinters = map[string]string{
+		"*.py":                      "ruff check --quiet {file}",
+		"*.{js,jsx,mjs,cjs,ts,tsx}": "eslint {file}",
+	}
+)
+
+// postEditCommand is a formatter or linter of a file.
+type postEditCommand struct {
+	glob    string
+	command string
+	// builtin commands only run when their program is installed.
+	builtin bool
+}
+
+// PostEdit runs the formatters then the linters matching the file edited
+// by the agent, and returns their failures for the agent to fix them.
+func PostEdit(ctx context.Context, opts *config.PostEditOptions, workingDir, filePath string) []string {
+	if opts == nil || !opts.Enabled {
+		return nil
+	}
+	timeout := defaultPostEditTimeout
+	if opts.Timeout > 0 {
+		timeout = time.Duration(opts.Timeout) * time.Second
+	}
+
+	var failures []string
+	for _, kind := range []struct {
+		name     string
+		builtin  map[string]string
+		commands map[string]string
+	}{
+		{"formatter", builtinFormatters, opts.Formatters},
+		{"linter", builtinLinters, opts.Linters},
+	} {
+		for _, c := range postEditCommands(kind.builtin, kind.commands) {
+			if !matchesFile(c.glob, workingDir, filePath) {
+				continue
+			}
+			command := c.command
+			if c.builtin {
+				var ok bool
+				if command, ok = resolveProgram(workingDir, command); !ok {
+					continue
+				}
+			}
+			if out, err := runPostEdit(ctx, timeout, command, workingDir, filePath); err != nil {
+				failures = append(failures, truncate(fmt.Sprintf("The %s `%s` failed on %s: %s\n%s", kind.name, c.command, filePath, err, out)))
+			}
+		}
+	}
+	return failures
+}
+
+// postEditCommands returns the built-in commands with the configured ones,
+// which replace the built-in ones of the same glob, in the order of their
+// globs.
+func postEditCommands(builtin, configured map[string]string) []postEditCommand {
+	var commands []postEditCommand
+	for _, glob := range slices.Sorted(maps.Keys(builtin)) {
+		if _, ok := configured[glob]; !ok {
+			commands = append(commands, postEditCommand{glob: glob, command: builtin[glob], builtin: true})
+		}
+	}
+	for _, glob := range slices.Sorted(maps.Keys(configured)) {
+		if configured[glob] != "" {
+			commands = append(commands, postEditCommand{glob: glob, command: configured[glob]})
+		}
+	}
+	return commands
+}
+
+// matchesFile reports whether the glob matches the path of the file relative
+// to the working directory, or its base name for the globs without a slash.
+func matchesFile(glob, workingDir, filePath string) bool {
+	return matches(glob, Input{Event: config.HookPostEdit, WorkingDir: workingDir, FilePath: filePath})
+}
+
+// resolveProgram returns the command with its program found in the
+// node_modules of the project or in $PATH, false when it isn't installed.
+func resolveProgram(workingDir, command string) (string, bool) {
+	program, args, _ := strings.Cut(command, " ")
+	local := filepath.Join(workingDir, "node_modules", ".bin", program)
+	if _, err := os.Stat(local); err == nil {
+		return shellQuote(local) + " " + args, true
+	}
+	if _, err := exec.LookPath(program); err != nil {
+		return "", false
+	}
+	return command, true
+}
+
+// runPostEdit runs the command on the file, the placeholders being replaced
+// by the file and its directory, and returns its output.
+func runPostEdit(ctx context.Context, timeout time.Duration, command, workingDir, filePath string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	command = strings.NewReplacer("{file}", `"$CRUSH_FILE"`, "{dir}", `"$CRUSH_DIR"`).Replace(command)
+	env := append(os.Environ(),
+		"CRUSH_FILE="+filePath,
+		"CRUSH_DIR="+filepath.Dir(filePath),
+	)
+	sh := shell.NewShell(&shell.Options{WorkingDir: workingDir, Env: env})
+	stdout, stderr, err := sh.Exec(ctx, command)
+	out := strings.TrimSpace(strings.TrimSpace(stdout) + "\n" + strings.TrimSpace(stderr))
+	if ctx.Err() != nil {
+		return out, fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		return out, fmt.Errorf("exit code %d", shell.ExitCode(err))
+	}
+	return out, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
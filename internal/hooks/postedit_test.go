@@ -0,0 +1,83 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostEdit(t *testing.T) {
+	t.Parallel()
+
+	write := func(t *testing.T, name, content string) (string, string) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return dir, path
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		dir, path := write(t, "notes.txt", "text\n")
+		opts := &config.PostEditOptions{Linters: map[string]string{"*.txt": "false"}}
+		require.Empty(t, PostEdit(t.Context(), opts, dir, path))
+		require.Empty(t, PostEdit(t.Context(), nil, dir, path))
+	})
+
+	t.Run("builtin formatter", func(t *testing.T) {
+		t.Parallel()
+		dir, path := write(t, "main.go", "package main\nfunc main() {\n}\n")
+		require.Empty(t, PostEdit(t.Context(), &config.PostEditOptions{Enabled: true}, dir, path))
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "package main\n\nfunc main() {\n}\n", string(content))
+	})
+
+	t.Run("overridden formatter", func(t *testing.T) {
+		t.Parallel()
+		dir, path := write(t, "main.go", "package main\nfunc main() {\n}\n")
+		opts := &config.PostEditOptions{Enabled: true, Formatters: map[string]string{"*.go": ""}}
+		require.Empty(t, PostEdit(t.Context(), opts, dir, path))
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "package main\nfunc main() {\n}\n", string(content))
+	})
+
+	t.Run("failing linter", func(t *testing.T) {
+		t.Parallel()
+		dir, path := write(t, "notes.txt", "TODO\n")
+		opts := &config.PostEditOptions{Enabled: true, Linters: map[string]string{
+			"*.txt": `! grep -n TODO {file}`,
+			"*.md":  "false",
+		}}
+		failures := PostEdit(t.Context(), opts, dir, path)
+		require.Len(t, failures, 1)
+		require.Contains(t, failures[0], "The linter `! grep -n TODO {file}` failed on "+path+": exit code 1")
+		require.Contains(t, failures[0], "1:TODO")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+		dir, path := write(t, "notes.txt", "text\n")
+		opts := &config.PostEditOptions{Enabled: true, Timeout: 1, Linters: map[string]string{"*.txt": "sleep 5"}}
+		failures := PostEdit(t.Context(), opts, dir, path)
+		require.Len(t, failures, 1)
+		require.Contains(t, failures[0], "timed out after 1s")
+	})
+}
+
+func TestPostEditCommands(t *testing.T) {
+	t.Parallel()
+
+	commands := postEditCommands(
+		map[string]string{"*.go": "gofmt -w {file}", "*.py": "black {file}"},
+		map[string]string{"*.go": "goimports -w {file}", "*.py": "", "*.rs": "rustfmt {file}"},
+	)
+	require.Equal(t, []postEditCommand{
+		{glob: "*.go", command: "goimports -w {file}"},
+		{glob: "*.rs", command: "rustfmt {file}"},
+	}, commands)
+}
@@ -0,0 +1,210 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// claudeCodeLine is a line of a Claude Code transcript.
+type claudeCodeLine struct {
+	Type        string `json:"type"`
+	Summary     string `json:"summary"`
+	IsSidechain bool   `json:"isSidechain"`
+	IsMeta      bool   `json:"isMeta"`
+	Message     struct {
+		ID      string          `json:"id"`
+		Role    string          `json:"role"`
+		Model   string          `json:"model"`
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+type claudeCodeBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+	Source    struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+		Data      string `json:"data"`
+	} `json:"source"`
+}
+
+// ParseClaudeCode reads a Claude Code transcript, the .jsonl files of
+// ~/.claude/projects. The reasoning and the conversations of the sub-agents
+// are left out.
+func ParseClaudeCode(r io.Reader) (Conversation, error) {
+	var conv Conversation
+	names := make(toolNames)
+	// The blocks of an assistant message are written on separate lines.
+	var lastAssistantID string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var l claudeCodeLine
+		if err := json.Unmarshal([]byte(line), &l); err != nil {
+			return conv, fmt.Errorf("line %d: %w", n, err)
+		}
+		if l.Type == "summary" {
+			if conv.Title == "" {
+				conv.Title = l.Summary
+			}
+			continue
+		}
+		if (l.Type != "user" && l.Type != "assistant") || l.IsSidechain || l.IsMeta {
+			continue
+		}
+		blocks, err := claudeCodeBlocks(l.Message.Content)
+		if err != nil {
+			return conv, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		if l.Type == "assistant" {
+			parts := assistantParts(blocks)
+			names.add(parts)
+			if len(parts) == 0 {
+				continue
+			}
+			if l.Message.ID != "" && l.Message.ID == lastAssistantID {
+				last := &conv.Messages[len(conv.Messages)-1]
+				last.Parts = append(last.Parts, parts...)
+				continue
+			}
+			lastAssistantID = l.Message.ID
+			conv.Messages = append(conv.Messages, Message{Role: message.Assistant, Parts: parts, Model: l.Message.Model})
+			continue
+		}
+
+		lastAssistantID = ""
+		var userParts, results []message.ContentPart
+		for _, b := range blocks {
+			switch b.Type {
+			case "text":
+				userParts = append(userParts, message.TextContent{Text: b.Text})
+			case "image":
+				if data, err := base64.StdEncoding.DecodeString(b.Source.Data); err == nil && b.Source.Type == "base64" {
+					userParts = append(userParts, message.BinaryContent{MIMEType: b.Source.MediaType, Data: data})
+				}
+			case "tool_result":
+				content, err := claudeCodeText(b.Content)
+				if err != nil {
+					return conv, fmt.Errorf("line %d: %w", n, err)
+				}
+				results = append(results, message.ToolResult{
+					ToolCallID: b.ToolUseID,
+					Name:       names[b.ToolUseID],
+					Content:    content,
+					IsError:    b.IsError,
+				})
+			}
+		}
+		if len(results) > 0 {
+			conv.Messages = append(conv.Messages, Message{Role: message.Tool, Parts: results})
+		}
+		if len(userParts) > 0 {
+			conv.Messages = append(conv.Messages, Message{Role: message.User, Parts: userParts})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return conv, err
+	}
+	if conv.Title == "" {
+		conv.Title = firstPrompt(conv.Messages)
+	}
+	return conv, nil
+}
+
+func assistantParts(blocks []claudeCodeBlock) []message.ContentPart {
+	var parts []message.ContentPart
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			if strings.TrimSpace(b.Text) != "" {
+				parts = append(parts, message.TextContent{Text: b.Text})
+			}
+		case "tool_use":
+			input := string(b.Input)
+			if input == "" {
+				input = "{}"
+			}
+			parts = append(parts, message.ToolCall{
+				ID:       b.ID,
+				Name:     b.Name,
+				Input:    input,
+				Type:     "function",
+				Finished: true,
+			})
+		}
+	}
+	return parts
+}
+
+// claudeCodeBlocks decodes content which is either a string or a list of
+// blocks.
+func claudeCodeBlocks(raw json.RawMessage) ([]claudeCodeBlock, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []claudeCodeBlock{{Type: "text", Text: text}}, nil
+	}
+	var blocks []claudeCodeBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// claudeCodeText returns the text of content which is either a string or a
+// list of blocks.
+func claudeCodeText(raw json.RawMessage) (string, error) {
+	blocks, err := claudeCodeBlocks(raw)
+	if err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, b := range blocks {
+		if b.Type == "text" {
+			texts = append(texts, b.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// firstPrompt returns the start of the first prompt, used as title when
+// the conversation has none.
+func firstPrompt(msgs []Message) string {
+	const maxTitleLength = 100
+	for _, msg := range msgs {
+		if msg.Role != message.User {
+			continue
+		}
+		for _, part := range msg.Parts {
+			if t, ok := part.(message.TextContent); ok {
+				title := strings.Join(strings.Fields(t.Text), " ")
+				if len(title) > maxTitleLength {
+					title = title[:maxTitleLength] + "..."
+				}
+				return title
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+// Package importer converts the conversations of other agents into crush
+// sessions, so they can be resumed.
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// Formats of the conversations that can be imported.
+const (
+	FormatClaudeCode = "claude-code"
+	FormatOpenAI     = "openai"
+)
+
+// interruptedToolResult is the result of the tool calls which have none in
+// the imported conversation, the providers require one.
+const interruptedToolResult = "The tool call was interrupted, it has no result."
+
+// Conversation is an imported conversation.
+type Conversation struct {
+	Title    string
+	Messages []Message
+}
+
+// Message is a message of an imported conversation.
+type Message struct {
+	Role  message.MessageRole
+	Parts []message.ContentPart
+	Model string
+}
+
+// Parse reads the conversations of the format, detecting it when empty.
+func Parse(r io.Reader, format string) ([]Conversation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = Detect(data)
+	}
+	switch format {
+	case FormatClaudeCode:
+		conv, err := ParseClaudeCode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return []Conversation{conv}, nil
+	case FormatOpenAI:
+		return ParseOpenAI(data)
+	}
+	return nil, fmt.Errorf("unknown format %q, expected %s or %s", format, FormatClaudeCode, FormatOpenAI)
+}
+
+// Detect guesses the format of the data: a JSON document is an OpenAI
+// export, JSON lines are a Claude Code transcript.
+func Detect(data []byte) string {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		return FormatOpenAI
+	}
+	// A JSON lines transcript has one object per line.
+	if first, _, _ := bytes.Cut(data, []byte("\n")); bytes.HasSuffix(bytes.TrimSpace(first), []byte("}")) && bytes.Contains(data, []byte("\n{")) {
+		return FormatClaudeCode
+	}
+	return FormatOpenAI
+}
+
+// Save creates a session with the messages of the conversation.
+func Save(ctx context.Context, sessions session.Service, messages message.Service, conv Conversation) (session.Session, error) {
+	if len(conv.Messages) == 0 {
+		return session.Session{}, errors.New("the conversation has no messages")
+	}
+	title := conv.Title
+	if title == "" {
+		title = "Imported conversation"
+	}
+	sess, err := sessions.Create(ctx, title)
+	if err != nil {
+		return sess, fmt.Errorf("failed to create session: %w", err)
+	}
+	for _, msg := range closeToolCalls(conv.Messages) {
+		if _, err := messages.Create(ctx, sess.ID, message.CreateMessageParams{
+			Role:     msg.Role,
+			Parts:    msg.Parts,
+			Model:    msg.Model,
+			Provider: "import",
+		}); err != nil {
+			return sess, fmt.Errorf("failed to create message: %w", err)
+		}
+	}
+	return sess, nil
+}
+
+// closeToolCalls adds a result to the tool calls without one, right after
+// their message, and finishes the assistant messages.
+func closeToolCalls(msgs []Message) []Message {
+	msgs = slices.Clone(msgs)
+	results := make(map[string]bool)
+	for _, msg := range msgs {
+		for _, part := range msg.Parts {
+			if r, ok := part.(message.ToolResult); ok {
+				results[r.ToolCallID] = true
+			}
+		}
+	}
+
+	var closed []Message
+	for i, msg := range msgs {
+		if msg.Role != message.Assistant {
+			closed = append(closed, msg)
+			continue
+		}
+		reason := message.FinishReasonEndTurn
+		var missing []message.ContentPart
+		for _, part := range msg.Parts {
+			call, ok := part.(message.ToolCall)
+			if !ok {
+				continue
+			}
+			reason = message.FinishReasonToolUse
+			if !results[call.ID] {
+				missing = append(missing, message.ToolResult{
+					ToolCallID: call.ID,
+					Name:       call.Name,
+					Content:    interruptedToolResult,
+					IsError:    true,
+				})
+			}
+		}
+		msg.Parts = append(msg.Parts, message.Finish{Reason: reason, Time: time.Now().Unix()})
+		closed = append(closed, msg)
+		if len(missing) == 0 {
+			continue
+		}
+		// Merge the missing results with the ones of the next message.
+		if i+1 < len(msgs) && msgs[i+1].Role == message.Tool {
+			msgs[i+1].Parts = append(missing, msgs[i+1].Parts...)
+			continue
+		}
+		closed = append(closed, Message{Role: message.Tool, Parts: missing})
+	}
+	return closed
+}
+
+// toolNames maps the IDs of the tool calls to their name, for the results.
+type toolNames map[string]string
+
+func (n toolNames) add(parts []message.ContentPart) {
+	for _, part := range parts {
+		if call, ok := part.(message.ToolCall); ok {
+			n[call.ID] = call.Name
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+const claudeCodeTranscript = `{"type":"summary","summary":"Fix the parser"}
+{"type":"user","message":{"role":"user","content":"The parser fails on empty files"}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"thinking","thinking":"Let me look"}]}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"text","text":"Let me read it."}]}}
+{"type":"assistant","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"parser.go"}}]}}
+{"type":"user","isSidechain":true,"message":{"role":"user","content":"sub-agent prompt"}}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"package parser"}]}]}}
+{"type":"assistant","message":{"id":"msg_2","role":"assistant","model":"claude-sonnet-4","content":[{"type":"text","text":"Fixed."},{"type":"tool_use","id":"toolu_2","name":"Bash","input":{"command":"go test"}}]}}
+`
+
+func TestParseClaudeCode(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, FormatClaudeCode, Detect([]byte(claudeCodeTranscript)))
+	convs, err := Parse(strings.NewReader(claudeCodeTranscript), "")
+	require.NoError(t, err)
+	require.Len(t, convs, 1)
+	conv := convs[0]
+	require.Equal(t, "Fix the parser", conv.Title)
+	require.Equal(t, []Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "The parser fails on empty files"}}},
+		{Role: message.Assistant, Model: "claude-sonnet-4", Parts: []message.ContentPart{
+			message.TextContent{Text: "Let me read it."},
+			message.ToolCall{ID: "toolu_1", Name: "Read", Input: `{"file_path":"parser.go"}`, Type: "function", Finished: true},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "toolu_1", Name: "Read", Content: "package parser"},
+		}},
+		{Role: message.Assistant, Model: "claude-sonnet-4", Parts: []message.ContentPart{
+			message.TextContent{Text: "Fixed."},
+			message.ToolCall{ID: "toolu_2", Name: "Bash", Input: `{"command":"go test"}`, Type: "function", Finished: true},
+		}},
+	}, conv.Messages)
+}
+
+func TestParseOpenAI_ChatGPTExport(t *testing.T) {
+	t.Parallel()
+
+	export := `[{
+		"title": "Regex help",
+		"current_node": "c",
+		"mapping": {
+			"root": {"parent": null, "message": null},
+			"s": {"parent": "root", "message": {"author": {"role": "system"}, "content": {"content_type": "text", "parts": [""]}, "metadata": {"is_visually_hidden_from_conversation": true}}},
+			"a": {"parent": "s", "message": {"author": {"role": "user"}, "content": {"content_type": "text", "parts": ["Match digits"]}, "metadata": {}}},
+			"old": {"parent": "a", "message": {"author": {"role": "assistant"}, "content": {"content_type": "text", "parts": ["Discarded answer"]}, "metadata": {}}},
+			"c": {"parent": "a", "message": {"author": {"role": "assistant"}, "content": {"content_type": "text", "parts": ["Use \\d+"]}, "metadata": {"model_slug": "gpt-4o"}}}
+		}
+	}]`
+	require.Equal(t, FormatOpenAI, Detect([]byte(export)))
+	convs, err := Parse(strings.NewReader(export), "")
+	require.NoError(t, err)
+	require.Equal(t, []Conversation{{
+		Title: "Regex help",
+		Messages: []Message{
+			{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Match digits"}}},
+			{Role: message.Assistant, Model: "gpt-4o", Parts: []message.ContentPart{message.TextContent{Text: `Use \d+`}}},
+		},
+	}}, convs)
+}
+
+func TestParseOpenAI_Chat(t *testing.T) {
+	t.Parallel()
+
+	chat := `{"model": "gpt-4.1", "messages": [
+		{"role": "system", "content": "You are helpful"},
+		{"role": "user", "content": [{"type": "text", "text": "What time is it?"}]},
+		{"role": "assistant", "content": null, "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "clock", "arguments": "{}"}}]},
+		{"role": "tool", "tool_call_id": "call_1", "content": "12:00"},
+		{"role": "assistant", "content": "It is noon."}
+	]}`
+	convs, err := Parse(strings.NewReader(chat), FormatOpenAI)
+	require.NoError(t, err)
+	require.Equal(t, []Conversation{{
+		Title: "What time is it?",
+		Messages: []Message{
+			{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "What time is it?"}}},
+			{Role: message.Assistant, Model: "gpt-4.1", Parts: []message.ContentPart{
+				message.ToolCall{ID: "call_1", Name: "clock", Input: "{}", Type: "function", Finished: true},
+			}},
+			{Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{ToolCallID: "call_1", Name: "clock", Content: "12:00"}}},
+			{Role: message.Assistant, Model: "gpt-4.1", Parts: []message.ContentPart{message.TextContent{Text: "It is noon."}}},
+		},
+	}}, convs)
+}
+
+func TestCloseToolCalls(t *testing.T) {
+	t.Parallel()
+
+	call := message.ToolCall{ID: "call_1", Name: "bash", Input: "{}", Finished: true}
+	msgs := closeToolCalls([]Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Run the tests"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{call}},
+	})
+	require.Len(t, msgs, 3)
+	finish, ok := msgs[1].Parts[1].(message.Finish)
+	require.True(t, ok)
+	require.Equal(t, message.FinishReasonToolUse, finish.Reason)
+	require.Equal(t, message.Tool, msgs[2].Role)
+	require.Equal(t, []message.ContentPart{message.ToolResult{
+		ToolCallID: "call_1",
+		Name:       "bash",
+		Content:    interruptedToolResult,
+		IsError:    true,
+	}}, msgs[2].Parts)
+}
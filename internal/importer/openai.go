@@ -0,0 +1,236 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// chatGPTConversation is a conversation of a ChatGPT data export, the
+// conversations.json file. The messages form a tree, the branch shown to
+// the user ends at the current node.
+type chatGPTConversation struct {
+	Title       string                 `json:"title"`
+	CurrentNode string                 `json:"current_node"`
+	Mapping     map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	Parent  string `json:"parent"`
+	Message *struct {
+		Author struct {
+			Role string `json:"role"`
+		} `json:"author"`
+		Content struct {
+			ContentType string `json:"content_type"`
+			Parts       []any  `json:"parts"`
+		} `json:"content"`
+		Metadata struct {
+			ModelSlug        string `json:"model_slug"`
+			IsVisuallyHidden bool   `json:"is_visually_hidden_from_conversation"`
+		} `json:"metadata"`
+	} `json:"message"`
+}
+
+// chatConversation is a conversation in the format of the OpenAI chat
+// completions API.
+type chatConversation struct {
+	Title    string        `json:"title"`
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	ToolCallID string          `json:"tool_call_id"`
+	ToolCalls  []struct {
+		ID       string `json:"id"`
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+}
+
+// ParseOpenAI reads OpenAI conversations: a ChatGPT data export, a
+// conversation in the chat completions format, i.e. an object with the
+// messages, or a list of them. The system messages are left out, crush
+// uses its own.
+func ParseOpenAI(data []byte) ([]Conversation, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		var item json.RawMessage
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, err
+		}
+		items = []json.RawMessage{item}
+	}
+
+	// A list of messages is a single conversation.
+	var probe struct {
+		Role    string `json:"role"`
+		Mapping any    `json:"mapping"`
+	}
+	if len(items) > 0 && json.Unmarshal(items[0], &probe) == nil && probe.Role != "" {
+		var msgs []chatMessage
+		if err := json.Unmarshal(data, &msgs); err != nil {
+			return nil, err
+		}
+		conv, err := parseChat(chatConversation{Messages: msgs})
+		if err != nil {
+			return nil, err
+		}
+		return []Conversation{conv}, nil
+	}
+
+	var convs []Conversation
+	for i, item := range items {
+		probe.Mapping = nil
+		if err := json.Unmarshal(item, &probe); err != nil {
+			return nil, fmt.Errorf("conversation %d: %w", i+1, err)
+		}
+		var conv Conversation
+		var err error
+		if probe.Mapping != nil {
+			var c chatGPTConversation
+			if err := json.Unmarshal(item, &c); err != nil {
+				return nil, fmt.Errorf("conversation %d: %w", i+1, err)
+			}
+			conv, err = parseChatGPT(c)
+		} else {
+			var c chatConversation
+			if err := json.Unmarshal(item, &c); err != nil {
+				return nil, fmt.Errorf("conversation %d: %w", i+1, err)
+			}
+			conv, err = parseChat(c)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("conversation %d: %w", i+1, err)
+		}
+		convs = append(convs, conv)
+	}
+	if len(convs) == 0 {
+		return nil, errors.New("no conversation found")
+	}
+	return convs, nil
+}
+
+func parseChatGPT(c chatGPTConversation) (Conversation, error) {
+	conv := Conversation{Title: c.Title}
+	var branch []chatGPTNode
+	seen := make(map[string]bool)
+	for id := c.CurrentNode; id != "" && !seen[id]; {
+		seen[id] = true
+		node, ok := c.Mapping[id]
+		if !ok {
+			return conv, fmt.Errorf("message %s not found", id)
+		}
+		branch = append(branch, node)
+		id = node.Parent
+	}
+	slices.Reverse(branch)
+
+	for _, node := range branch {
+		msg := node.Message
+		if msg == nil || msg.Metadata.IsVisuallyHidden || msg.Content.ContentType != "text" {
+			continue
+		}
+		var texts []string
+		for _, p := range msg.Content.Parts {
+			if s, ok := p.(string); ok && strings.TrimSpace(s) != "" {
+				texts = append(texts, s)
+			}
+		}
+		if len(texts) == 0 {
+			continue
+		}
+		parts := []message.ContentPart{message.TextContent{Text: strings.Join(texts, "\n")}}
+		switch msg.Author.Role {
+		case "user":
+			conv.Messages = append(conv.Messages, Message{Role: message.User, Parts: parts})
+		case "assistant":
+			conv.Messages = append(conv.Messages, Message{Role: message.Assistant, Parts: parts, Model: msg.Metadata.ModelSlug})
+		}
+	}
+	if conv.Title == "" {
+		conv.Title = firstPrompt(conv.Messages)
+	}
+	return conv, nil
+}
+
+func parseChat(c chatConversation) (Conversation, error) {
+	conv := Conversation{Title: c.Title}
+	names := make(toolNames)
+	for i, m := range c.Messages {
+		text, err := chatText(m.Content)
+		if err != nil {
+			return conv, fmt.Errorf("message %d: %w", i+1, err)
+		}
+		switch m.Role {
+		case "user":
+			conv.Messages = append(conv.Messages, Message{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: text}}})
+		case "assistant":
+			var parts []message.ContentPart
+			if strings.TrimSpace(text) != "" {
+				parts = append(parts, message.TextContent{Text: text})
+			}
+			for _, call := range m.ToolCalls {
+				parts = append(parts, message.ToolCall{
+					ID:       call.ID,
+					Name:     call.Function.Name,
+					Input:    call.Function.Arguments,
+					Type:     "function",
+					Finished: true,
+				})
+			}
+			names.add(parts)
+			if len(parts) > 0 {
+				conv.Messages = append(conv.Messages, Message{Role: message.Assistant, Parts: parts, Model: c.Model})
+			}
+		case "tool":
+			result := message.ToolResult{ToolCallID: m.ToolCallID, Name: names[m.ToolCallID], Content: text}
+			// The results of the calls of a message are in a single crush
+			// message.
+			if n := len(conv.Messages); n > 0 && conv.Messages[n-1].Role == message.Tool {
+				conv.Messages[n-1].Parts = append(conv.Messages[n-1].Parts, result)
+				continue
+			}
+			conv.Messages = append(conv.Messages, Message{Role: message.Tool, Parts: []message.ContentPart{result}})
+		}
+	}
+	if conv.Title == "" {
+		conv.Title = firstPrompt(conv.Messages)
+	}
+	return conv, nil
+}
+
+// chatText returns the text of content which is either a string or a list
+// of parts.
+func chatText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
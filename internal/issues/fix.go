@@ -0,0 +1,202 @@
+package issues
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+// ToolName is the tool name of the permission requests of the remote
+// operations, e.g. fix-issue:push can be allowed in permissions.allowed_tools.
+const ToolName = "fix-issue"
+
+// Actions of the remote operations, they are requested as permissions.
+const (
+	ActionFetch  = "fetch"
+	ActionPush   = "push"
+	ActionOpenPR = "open_pr"
+)
+
+// ErrDenied is returned when the user denies a remote operation.
+var ErrDenied = errors.New("permission denied")
+
+// Worktree is the git worktree the agent fixes an issue in, on its own
+// branch.
+type Worktree struct {
+	Dir    string
+	Branch string
+	// Base is the branch the repository was on, the pull request targets
+	// it.
+	Base    string
+	repoDir string
+}
+
+// AddWorktree creates a worktree of the repository at its current commit,
+// in the data directory of the repository, on a new branch for the issue.
+func AddWorktree(ctx context.Context, repoDir, dataDir string, issue Issue) (Worktree, error) {
+	base, err := runCommand(ctx, repoDir, "git", "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return Worktree{}, err
+	}
+	wt := Worktree{
+		Dir:     filepath.Join(dataDir, "worktrees", fmt.Sprintf("issue-%d", issue.Number)),
+		Branch:  fmt.Sprintf("crush/issue-%d", issue.Number),
+		Base:    base,
+		repoDir: repoDir,
+	}
+	if _, err := os.Stat(wt.Dir); err == nil {
+		return Worktree{}, fmt.Errorf("worktree %s already exists, remove it with git worktree remove", wt.Dir)
+	}
+	if err := os.MkdirAll(filepath.Dir(wt.Dir), 0o700); err != nil {
+		return Worktree{}, err
+	}
+	if _, err := runCommand(ctx, repoDir, "git", "worktree", "add", "-b", wt.Branch, wt.Dir, "HEAD"); err != nil {
+		return Worktree{}, err
+	}
+	return wt, nil
+}
+
+// Remove removes the worktree, its branch is kept.
+func (w Worktree) Remove(ctx context.Context) error {
+	_, err := runCommand(ctx, w.repoDir, "git", "worktree", "remove", "--force", w.Dir)
+	return err
+}
+
+// FixOptions configure a fix.
+type FixOptions struct {
+	// DryRun commits the changes to the branch of the worktree but doesn't
+	// push it nor open the pull request.
+	DryRun bool
+}
+
+// FixResult is the outcome of a fix.
+type FixResult struct {
+	Issue     Issue
+	SessionID string
+	// Changed is false when the agent didn't change any file.
+	Changed bool
+	Title   string
+	Body    string
+	PRURL   string
+}
+
+// Fix fetches the issue, runs the coder agent of the app, which must work
+// in the worktree, and opens a pull request with its changes. The remote
+// operations are permission requests of the session, answered like the
+// ones of the tools.
+func Fix(ctx context.Context, a *app.App, issue Issue, wt Worktree, opts FixOptions) (FixResult, error) {
+	result := FixResult{Issue: issue}
+	sess, err := a.Sessions.Create(ctx, "Fix issue "+issue.Ref())
+	if err != nil {
+		return result, fmt.Errorf("failed to create session: %w", err)
+	}
+	result.SessionID = sess.ID
+
+	if err := request(a, sess.ID, wt, ActionFetch, fmt.Sprintf("Fetch the issue %s", issue.URL)); err != nil {
+		return result, err
+	}
+	issue, err = Fetch(ctx, issue)
+	if err != nil {
+		return result, err
+	}
+	result.Issue = issue
+
+	slog.Info("Fixing issue", "issue", issue.Ref(), "worktree", wt.Dir, "session_id", sess.ID)
+	done, err := a.CoderAgent.Run(ctx, sess.ID, issue.Prompt())
+	if err != nil {
+		return result, fmt.Errorf("failed to start the agent: %w", err)
+	}
+	var summary string
+	select {
+	case event := <-done:
+		if event.Error != nil {
+			return result, fmt.Errorf("the agent failed: %w", event.Error)
+		}
+		summary = event.Message.Content().String()
+	case <-ctx.Done():
+		return result, ctx.Err()
+	}
+
+	status, err := runCommand(ctx, wt.Dir, "git", "status", "--porcelain")
+	if err != nil {
+		return result, err
+	}
+	if status == "" {
+		return result, nil
+	}
+	result.Changed = true
+	result.Title = PRTitle(issue)
+	if _, err := runCommand(ctx, wt.Dir, "git", "add", "-A"); err != nil {
+		return result, err
+	}
+	if _, err := runCommand(ctx, wt.Dir, "git", "commit", "-m", result.Title); err != nil {
+		return result, err
+	}
+	stat, _ := runCommand(ctx, wt.Dir, "git", "diff", "--stat", wt.Base+"..."+wt.Branch)
+	result.Body = PRBody(issue, summary, stat)
+	if opts.DryRun {
+		return result, nil
+	}
+
+	if err := request(a, sess.ID, wt, ActionPush, fmt.Sprintf("Push the branch %s to origin", wt.Branch)); err != nil {
+		return result, err
+	}
+	if _, err := runCommand(ctx, wt.Dir, "git", "push", "-u", "origin", wt.Branch); err != nil {
+		return result, err
+	}
+	if err := request(a, sess.ID, wt, ActionOpenPR, fmt.Sprintf("Open a pull request from %s to %s: %s", wt.Branch, wt.Base, result.Title)); err != nil {
+		return result, err
+	}
+	result.PRURL, err = openPullRequest(ctx, issue, wt, result.Title, result.Body)
+	return result, err
+}
+
+func request(a *app.App, sessionID string, wt Worktree, action, description string) error {
+	granted := a.Permissions.Request(permission.CreatePermissionRequest{
+		SessionID:   sessionID,
+		ToolName:    ToolName,
+		Action:      action,
+		Description: description,
+		Path:        wt.Dir,
+	})
+	if !granted {
+		return fmt.Errorf("%s: %w", description, ErrDenied)
+	}
+	return nil
+}
+
+func openPullRequest(ctx context.Context, issue Issue, wt Worktree, title, body string) (string, error) {
+	if issue.Platform == GitLab {
+		return runCommand(ctx, wt.Dir, "glab", "mr", "create", "--repo", issue.repoArg(), "--source-branch", wt.Branch, "--target-branch", wt.Base, "--title", title, "--description", body, "--yes")
+	}
+	return runCommand(ctx, wt.Dir, "gh", "pr", "create", "--repo", issue.repoArg(), "--base", wt.Base, "--head", wt.Branch, "--title", title, "--body", body)
+}
+
+// PRTitle returns the title of the pull request fixing the issue, also used
+// as commit message.
+func PRTitle(issue Issue) string {
+	return fmt.Sprintf("Fix #%d: %s", issue.Number, issue.Title)
+}
+
+// PRBody returns the description of the pull request, the summary of the
+// agent followed by the changed files. It closes the issue once merged.
+func PRBody(issue Issue, summary, stat string) string {
+	var sb strings.Builder
+	sb.WriteString("Closes #" + strconv.Itoa(issue.Number) + "\n\n")
+	if summary = strings.TrimSpace(summary); summary != "" {
+		sb.WriteString(summary + "\n\n")
+	}
+	if stat != "" {
+		sb.WriteString("```\n" + stat + "\n```\n\n")
+	}
+	sb.WriteString("Generated by crush from " + issue.URL + ".\n")
+	return sb.String()
+}
@@ -0,0 +1,39 @@
+package issues
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorktree(t *testing.T) {
+	t.Parallel()
+
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		_, err := runCommand(t.Context(), repo, "git", args...)
+		require.NoError(t, err)
+	}
+	dataDir := filepath.Join(repo, ".crush")
+
+	wt, err := AddWorktree(t.Context(), repo, dataDir, Issue{Number: 42})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dataDir, "worktrees", "issue-42"), wt.Dir)
+	require.Equal(t, "crush/issue-42", wt.Branch)
+	require.Equal(t, "main", wt.Base)
+	branch, err := runCommand(t.Context(), wt.Dir, "git", "rev-parse", "--abbrev-ref", "HEAD")
+	require.NoError(t, err)
+	require.Equal(t, wt.Branch, branch)
+
+	_, err = AddWorktree(t.Context(), repo, dataDir, Issue{Number: 42})
+	require.ErrorContains(t, err, "already exists")
+
+	require.NoError(t, wt.Remove(t.Context()))
+	_, err = os.Stat(wt.Dir)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
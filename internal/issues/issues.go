@@ -0,0 +1,166 @@
+// Package issues turns GitHub and GitLab issues into pull requests with the
+// coder agent.
+package issues
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Platforms hosting the issues.
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+)
+
+// Issue is an issue of a GitHub or GitLab repository. The title, body and
+// comments are only set once it is fetched.
+type Issue struct {
+	Platform string
+	Host     string
+	// Repo is the path of the repository, e.g. owner/repo on GitHub or
+	// group/subgroup/project on GitLab.
+	Repo     string
+	Number   int
+	URL      string
+	Title    string
+	Body     string
+	Comments []Comment
+}
+
+type Comment struct {
+	Author string
+	Body   string
+}
+
+// ParseURL parses the URL of an issue, e.g.
+// https://github.com/owner/repo/issues/1 or
+// https://gitlab.com/group/project/-/issues/1.
+func ParseURL(raw string) (Issue, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return Issue{}, fmt.Errorf("invalid issue URL %q", raw)
+	}
+	path := strings.Trim(u.Path, "/")
+	issue := Issue{Host: u.Host, URL: raw}
+
+	var repo, number string
+	if before, after, ok := strings.Cut(path, "/-/issues/"); ok {
+		issue.Platform = GitLab
+		repo, number = before, after
+	} else {
+		parts := strings.Split(path, "/")
+		if len(parts) < 4 || parts[len(parts)-2] != "issues" {
+			return Issue{}, fmt.Errorf("%q is not the URL of an issue", raw)
+		}
+		repo, number = strings.Join(parts[:len(parts)-2], "/"), parts[len(parts)-1]
+		issue.Platform = GitHub
+		if strings.Contains(u.Host, "gitlab") {
+			issue.Platform = GitLab
+		} else if len(parts) != 4 {
+			return Issue{}, fmt.Errorf("%q is not the URL of an issue", raw)
+		}
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 || repo == "" {
+		return Issue{}, fmt.Errorf("%q is not the URL of an issue", raw)
+	}
+	issue.Repo = repo
+	issue.Number = n
+	return issue, nil
+}
+
+// Ref returns the short reference of the issue, e.g. owner/repo#1.
+func (i Issue) Ref() string {
+	return fmt.Sprintf("%s#%d", i.Repo, i.Number)
+}
+
+// repoArg is the repository as given to the gh and glab CLIs.
+func (i Issue) repoArg() string {
+	if i.Platform == GitLab {
+		return "https://" + i.Host + "/" + i.Repo
+	}
+	if i.Host == "github.com" {
+		return i.Repo
+	}
+	return i.Host + "/" + i.Repo
+}
+
+// Fetch reads the title, body and comments of the issue with the GitHub or
+// GitLab CLI.
+func Fetch(ctx context.Context, issue Issue) (Issue, error) {
+	number := strconv.Itoa(issue.Number)
+	if issue.Platform == GitLab {
+		out, err := runCommand(ctx, "", "glab", "issue", "view", number, "--repo", issue.repoArg(), "--output", "json")
+		if err != nil {
+			return issue, err
+		}
+		var data struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		}
+		if err := json.Unmarshal([]byte(out), &data); err != nil {
+			return issue, fmt.Errorf("failed to parse the issue: %w", err)
+		}
+		issue.Title = data.Title
+		issue.Body = data.Description
+		return issue, nil
+	}
+
+	out, err := runCommand(ctx, "", "gh", "issue", "view", number, "--repo", issue.repoArg(), "--json", "title,body,comments")
+	if err != nil {
+		return issue, err
+	}
+	var data struct {
+		Title    string `json:"title"`
+		Body     string `json:"body"`
+		Comments []struct {
+			Author struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			Body string `json:"body"`
+		} `json:"comments"`
+	}
+	if err := json.Unmarshal([]byte(out), &data); err != nil {
+		return issue, fmt.Errorf("failed to parse the issue: %w", err)
+	}
+	issue.Title = data.Title
+	issue.Body = data.Body
+	for _, c := range data.Comments {
+		issue.Comments = append(issue.Comments, Comment{Author: c.Author.Login, Body: c.Body})
+	}
+	return issue, nil
+}
+
+// Prompt returns the prompt asking the agent to fix the issue.
+func (i Issue) Prompt() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Fix the issue %s of this repository.\n\n", i.Ref())
+	fmt.Fprintf(&sb, "<issue url=%q>\n# %s\n\n%s\n", i.URL, i.Title, strings.TrimSpace(i.Body))
+	for _, c := range i.Comments {
+		fmt.Fprintf(&sb, "\n## Comment by %s\n\n%s\n", c.Author, strings.TrimSpace(c.Body))
+	}
+	sb.WriteString("</issue>\n\n")
+	sb.WriteString("Make the changes needed to fix it and run the relevant tests. Don't commit, the changes are committed for you. ")
+	sb.WriteString("End with a short summary of the changes, it is used as the description of the pull request.")
+	return sb.String()
+}
+
+func runCommand(ctx context.Context, dir, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	// The output is parsed, keep the warnings of the commands out of it.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,75 @@
+package issues
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURL(t *testing.T) {
+	t.Parallel()
+
+	for raw, want := range map[string]Issue{
+		"https://github.com/owner/repo/issues/42": {
+			Platform: GitHub, Host: "github.com", Repo: "owner/repo", Number: 42,
+		},
+		"https://github.example.com/owner/repo/issues/1/": {
+			Platform: GitHub, Host: "github.example.com", Repo: "owner/repo", Number: 1,
+		},
+		"https://gitlab.com/group/sub/project/-/issues/7": {
+			Platform: GitLab, Host: "gitlab.com", Repo: "group/sub/project", Number: 7,
+		},
+		"https://gitlab.example.com/group/project/issues/3": {
+			Platform: GitLab, Host: "gitlab.example.com", Repo: "group/project", Number: 3,
+		},
+	} {
+		issue, err := ParseURL(raw)
+		require.NoError(t, err, raw)
+		want.URL = raw
+		require.Equal(t, want, issue, raw)
+	}
+
+	for _, raw := range []string{
+		"github.com/owner/repo/issues/42",
+		"https://github.com/owner/repo/pull/42",
+		"https://github.com/owner/repo/issues/abc",
+		"https://github.com/owner/repo/issues/0",
+		"https://github.com/owner/sub/repo/issues/1",
+	} {
+		_, err := ParseURL(raw)
+		require.Error(t, err, raw)
+	}
+}
+
+func TestRepoArg(t *testing.T) {
+	t.Parallel()
+
+	for raw, want := range map[string]string{
+		"https://github.com/owner/repo/issues/1":         "owner/repo",
+		"https://github.example.com/owner/repo/issues/1": "github.example.com/owner/repo",
+		"https://gitlab.com/group/project/-/issues/1":    "https://gitlab.com/group/project",
+	} {
+		issue, err := ParseURL(raw)
+		require.NoError(t, err)
+		require.Equal(t, want, issue.repoArg())
+	}
+}
+
+func TestPromptAndPRBody(t *testing.T) {
+	t.Parallel()
+
+	issue, err := ParseURL("https://github.com/owner/repo/issues/42")
+	require.NoError(t, err)
+	issue.Title = "Crash on empty input"
+	issue.Body = "It panics.\n"
+	issue.Comments = []Comment{{Author: "alice", Body: "Same here"}}
+
+	prompt := issue.Prompt()
+	require.Contains(t, prompt, "owner/repo#42")
+	require.Contains(t, prompt, "# Crash on empty input\n\nIt panics.\n")
+	require.Contains(t, prompt, "## Comment by alice\n\nSame here\n")
+
+	require.Equal(t, "Fix #42: Crash on empty input", PRTitle(issue))
+	body := PRBody(issue, "Handle the empty input.\n", " main.go | 2 +-")
+	require.Equal(t, "Closes #42\n\nHandle the empty input.\n\n```\n main.go | 2 +-\n```\n\nGenerated by crush from https://github.com/owner/repo/issues/42.\n", body)
+}
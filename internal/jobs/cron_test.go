@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		_, err := ParseSchedule(expr)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	t.Parallel()
+
+	// A Wednesday.
+	from := time.Date(2025, 8, 6, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 8, 6, 10, 31, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 8, 6, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 8, 7, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2025, 8, 10, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 8, 6, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * 1", time.Date(2025, 8, 11, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2025, 8, 7, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 8, 10, 0, 0, 0, 0, time.UTC)},
+		{"30 10 6 8 *", time.Date(2026, 8, 6, 10, 30, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2025, 8, 15, 12, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted.
+		{"0 0 20 * 5", time.Date(2025, 8, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			s, err := ParseSchedule(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, s.Next(from))
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Package jobs implements scheduled headless agent runs.
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const jobsFileName = "jobs.json"
+
+// Job is a prompt run by the coder agent on a cron schedule.
+type Job struct {
+	Name   string `json:"name"`
+	Prompt string `json:"prompt"`
+	Cron   string `json:"cron"`
+	// MaxCost is the maximum cost of a run in USD, 0 means no limit.
+	MaxCost float64 `json:"max_cost,omitempty"`
+	// MaxTokens is the maximum number of tokens of a run, 0 means no limit.
+	MaxTokens int64 `json:"max_tokens,omitempty"`
+	// Timeout is the maximum duration of a run in minutes, 0 means no limit.
+	Timeout int `json:"timeout,omitempty"`
+	// ReportsDir overrides the directory the run reports are written to.
+	ReportsDir string `json:"reports_dir,omitempty"`
+	// OpenPR commits the changes of a run to a new branch and opens a pull
+	// request with the GitHub CLI.
+	OpenPR bool `json:"open_pr,omitempty"`
+	// RemoteApproval sends the permission requests of a run to the
+	// notifiers instead of approving them automatically.
+	RemoteApproval bool      `json:"remote_approval,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastRun        time.Time `json:"last_run,omitzero"`
+}
+
+// Next returns the next time the job is due after its last run.
+func (j Job) Next() (time.Time, error) {
+	schedule, err := ParseSchedule(j.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	from := j.LastRun
+	if from.IsZero() {
+		from = j.CreatedAt
+	}
+	return schedule.Next(from), nil
+}
+
+// Due reports whether the job should run at now.
+func (j Job) Due(now time.Time) bool {
+	next, err := j.Next()
+	return err == nil && !next.IsZero() && !next.After(now)
+}
+
+// Store keeps the jobs of a project in a JSON file in its data directory.
+type Store struct {
+	path string
+}
+
+func NewStore(dataDir string) *Store {
+	return &Store{path: filepath.Join(dataDir, jobsFileName)}
+}
+
+// List returns the jobs sorted by name.
+func (s *Store) List() ([]Job, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+	var jobs []Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse jobs file %s: %w", s.path, err)
+	}
+	slices.SortFunc(jobs, func(a, b Job) int { return strings.Compare(a.Name, b.Name) })
+	return jobs, nil
+}
+
+// Get returns the job with the given name.
+func (s *Store) Get(name string) (Job, error) {
+	jobs, err := s.List()
+	if err != nil {
+		return Job{}, err
+	}
+	for _, j := range jobs {
+		if j.Name == name {
+			return j, nil
+		}
+	}
+	return Job{}, fmt.Errorf("job not found: %s", name)
+}
+
+// Add stores a new job, failing if one with the same name exists.
+func (s *Store) Add(job Job) error {
+	if job.Name == "" {
+		return fmt.Errorf("job name is required")
+	}
+	// The name ends up in the file name of the reports and in the branch of
+	// the pull requests.
+	if Slug(job.Name) != job.Name {
+		return fmt.Errorf("invalid job name %q, use up to 40 lowercase letters, digits and dashes", job.Name)
+	}
+	if strings.TrimSpace(job.Prompt) == "" {
+		return fmt.Errorf("job prompt is required")
+	}
+	if _, err := ParseSchedule(job.Cron); err != nil {
+		return err
+	}
+	jobs, err := s.List()
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(jobs, func(j Job) bool { return j.Name == job.Name }) {
+		return fmt.Errorf("job already exists: %s", job.Name)
+	}
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	return s.save(append(jobs, job))
+}
+
+// Remove deletes the job with the given name.
+func (s *Store) Remove(name string) error {
+	jobs, err := s.List()
+	if err != nil {
+		return err
+	}
+	idx := slices.IndexFunc(jobs, func(j Job) bool { return j.Name == name })
+	if idx == -1 {
+		return fmt.Errorf("job not found: %s", name)
+	}
+	return s.save(slices.Delete(jobs, idx, idx+1))
+}
+
+// SetLastRun records the time the job last ran.
+func (s *Store) SetLastRun(name string, t time.Time) error {
+	jobs, err := s.List()
+	if err != nil {
+		return err
+	}
+	idx := slices.IndexFunc(jobs, func(j Job) bool { return j.Name == name })
+	if idx == -1 {
+		return fmt.Errorf("job not found: %s", name)
+	}
+	jobs[idx].LastRun = t
+	return s.save(jobs)
+}
+
+func (s *Store) save(jobs []Job) error {
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal jobs: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	// Write to a temporary file first so a crash never leaves a truncated
+	// jobs file behind.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write jobs: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write jobs: %w", err)
+	}
+	return nil
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slug turns a free form job description into a job name.
+func Slug(s string) string {
+	s = nonSlugChars.ReplaceAllString(strings.ToLower(s), "-")
+	s = strings.Trim(s, "-")
+	if len(s) > 40 {
+		s = strings.TrimRight(s[:40], "-")
+	}
+	return s
+}
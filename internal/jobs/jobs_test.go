@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(t.TempDir())
+	list, err := store.List()
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	created := time.Date(2025, 8, 6, 10, 30, 0, 0, time.UTC)
+	require.NoError(t, store.Add(Job{Name: "deps", Prompt: "update deps", Cron: "@weekly", CreatedAt: created}))
+	require.NoError(t, store.Add(Job{Name: "lint", Prompt: "fix lint", Cron: "@daily", CreatedAt: created}))
+	require.Error(t, store.Add(Job{Name: "lint", Prompt: "fix lint", Cron: "@daily"}))
+	require.Error(t, store.Add(Job{Name: "bad", Prompt: "bad", Cron: "every day"}))
+	require.Error(t, store.Add(Job{Name: "empty", Cron: "@daily"}))
+	require.Error(t, store.Add(Job{Name: "../../etc/x", Prompt: "escape", Cron: "@daily"}))
+	require.Error(t, store.Add(Job{Name: "a b", Prompt: "spaces", Cron: "@daily"}))
+
+	list, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "deps", list[0].Name)
+	require.Equal(t, "lint", list[1].Name)
+
+	lastRun := created.Add(48 * time.Hour)
+	require.NoError(t, store.SetLastRun("lint", lastRun))
+	job, err := store.Get("lint")
+	require.NoError(t, err)
+	require.True(t, job.LastRun.Equal(lastRun))
+
+	require.NoError(t, store.Remove("deps"))
+	require.Error(t, store.Remove("deps"))
+	_, err = store.Get("deps")
+	require.Error(t, err)
+}
+
+func TestJobDue(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2025, 8, 6, 10, 30, 0, 0, time.UTC)
+	job := Job{Name: "lint", Prompt: "fix lint", Cron: "@daily", CreatedAt: created}
+	require.False(t, job.Due(created.Add(time.Hour)))
+	require.True(t, job.Due(time.Date(2025, 8, 7, 0, 0, 0, 0, time.UTC)))
+
+	job.LastRun = time.Date(2025, 8, 7, 0, 0, 5, 0, time.UTC)
+	require.False(t, job.Due(time.Date(2025, 8, 7, 12, 0, 0, 0, time.UTC)))
+	require.True(t, job.Due(time.Date(2025, 8, 8, 0, 1, 0, 0, time.UTC)))
+}
+
+func TestSlug(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "update-deps-weekly", Slug("Update deps weekly!"))
+	require.Equal(t, "a", Slug("  -a- "))
+	require.LessOrEqual(t, len(Slug("a very long job description that goes on and on and on")), 40)
+}
+
+func TestReportRunID(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2025, 8, 6, 10, 30, 0, 0, time.UTC)
+	require.Equal(t, "deps-20250806-103000", Report{Job: Job{Name: "deps"}, StartedAt: started}.runID())
+	require.Equal(t, "etc-passwd-20250806-103000", Report{Job: Job{Name: "../etc/passwd"}, StartedAt: started}.runID())
+	require.Equal(t, "job-20250806-103000", Report{Job: Job{Name: "~^:"}, StartedAt: started}.runID())
+}
@@ -0,0 +1,298 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+const (
+	StatusCompleted      = "completed"
+	StatusFailed         = "failed"
+	StatusBudgetExceeded = "budget exceeded"
+	StatusTimedOut       = "timed out"
+
+	reportsDirName = "reports"
+)
+
+// Report describes a job run.
+type Report struct {
+	Job        Job
+	SessionID  string
+	Status     string
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Cost       float64
+	Tokens     int64
+	Output     string
+	PRURL      string
+	// Path is the file the report was written to.
+	Path string
+}
+
+// Run runs the job with the coder agent of the app, enforcing its budget,
+// writes its report and, when configured, opens a pull request with the
+// changes.
+func Run(ctx context.Context, a *app.App, job Job) (Report, error) {
+	cwd := a.Config().WorkingDir()
+	report := Report{Job: job, StartedAt: time.Now()}
+
+	if job.RemoteApproval && (!a.Notifications.Enabled() || !a.Notifications.Approvals().Serving()) {
+		return report, fmt.Errorf("job %s uses remote approval but no notifier or remote approval server is configured", job.Name)
+	}
+
+	if job.OpenPR {
+		clean, err := gitIsClean(ctx, cwd)
+		if err != nil {
+			return report, err
+		}
+		if !clean {
+			return report, fmt.Errorf("job %s opens pull requests but the working tree has uncommitted changes", job.Name)
+		}
+	}
+
+	runCtx := ctx
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, time.Duration(job.Timeout)*time.Minute)
+		defer cancel()
+	}
+
+	sess, err := a.Sessions.Create(runCtx, "Job: "+job.Name)
+	if err != nil {
+		return report, fmt.Errorf("failed to create session for job: %w", err)
+	}
+	report.SessionID = sess.ID
+	if !job.RemoteApproval {
+		a.Permissions.AutoApproveSession(sess.ID)
+	}
+
+	slog.Info("Running job", "job", job.Name, "session_id", sess.ID)
+	done, err := a.CoderAgent.Run(runCtx, sess.ID, job.Prompt)
+	if err != nil {
+		return report, fmt.Errorf("failed to start job: %w", err)
+	}
+
+	sessionEvents := a.Sessions.Subscribe(runCtx)
+	var budgetExceeded string
+	var result agent.AgentEvent
+loop:
+	for {
+		select {
+		case result = <-done:
+			break loop
+		case event := <-sessionEvents:
+			if event.Type != pubsub.UpdatedEvent || event.Payload.ID != sess.ID {
+				continue
+			}
+			s := event.Payload
+			report.Cost = s.Cost
+			report.Tokens = s.PromptTokens + s.CompletionTokens
+			if budgetExceeded != "" {
+				continue
+			}
+			if job.MaxCost > 0 && s.Cost > job.MaxCost {
+				budgetExceeded = fmt.Sprintf("cost $%.4f exceeded the budget of $%.4f", s.Cost, job.MaxCost)
+			} else if job.MaxTokens > 0 && report.Tokens > job.MaxTokens {
+				budgetExceeded = fmt.Sprintf("%d tokens exceeded the budget of %d tokens", report.Tokens, job.MaxTokens)
+			}
+			if budgetExceeded != "" {
+				slog.Warn("Job budget exceeded, cancelling", "job", job.Name, "reason", budgetExceeded)
+				a.CoderAgent.Cancel(sess.ID)
+			}
+		}
+	}
+
+	// The session events may lag behind, read the final usage.
+	if s, err := a.Sessions.Get(ctx, sess.ID); err == nil {
+		report.Cost = s.Cost
+		report.Tokens = s.PromptTokens + s.CompletionTokens
+	}
+	report.FinishedAt = time.Now()
+	report.Output = result.Message.Content().String()
+
+	switch {
+	case budgetExceeded != "":
+		report.Status = StatusBudgetExceeded
+		report.Error = budgetExceeded
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		report.Status = StatusTimedOut
+		report.Error = fmt.Sprintf("the job did not finish within %d minutes", job.Timeout)
+	case result.Error != nil:
+		report.Status = StatusFailed
+		report.Error = result.Error.Error()
+	default:
+		report.Status = StatusCompleted
+	}
+
+	if job.OpenPR && report.Status == StatusCompleted {
+		url, err := openPullRequest(ctx, cwd, report)
+		if err != nil {
+			slog.Error("Failed to open pull request", "job", job.Name, "error", err)
+			report.Error = fmt.Sprintf("failed to open pull request: %v", err)
+		}
+		report.PRURL = url
+	}
+
+	dir := job.ReportsDir
+	if dir == "" {
+		dir = filepath.Join(a.Config().Options.DataDirectory, reportsDirName)
+	}
+	path, err := writeReport(dir, report)
+	if err != nil {
+		return report, err
+	}
+	report.Path = path
+	return report, nil
+}
+
+// runID names the report and the branch of the run. The name is slugged
+// again since the jobs file may have been edited by hand.
+func (r Report) runID() string {
+	name := Slug(r.Job.Name)
+	if name == "" {
+		name = "job"
+	}
+	return name + "-" + r.StartedAt.Format("20060102-150405")
+}
+
+func writeReport(dir string, r Report) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	path := filepath.Join(dir, r.runID()+".md")
+	if err := os.WriteFile(path, []byte(r.Markdown()), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, nil
+}
+
+// Markdown formats the report.
+func (r Report) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Job %s\n\n", r.Job.Name)
+	fmt.Fprintf(&sb, "- Status: %s\n", r.Status)
+	if r.Error != "" {
+		fmt.Fprintf(&sb, "- Error: %s\n", r.Error)
+	}
+	fmt.Fprintf(&sb, "- Started: %s\n", r.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "- Duration: %s\n", r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+	fmt.Fprintf(&sb, "- Cost: $%.4f\n", r.Cost)
+	fmt.Fprintf(&sb, "- Tokens: %d\n", r.Tokens)
+	fmt.Fprintf(&sb, "- Session: %s\n", r.SessionID)
+	if r.PRURL != "" {
+		fmt.Fprintf(&sb, "- Pull request: %s\n", r.PRURL)
+	}
+	fmt.Fprintf(&sb, "\n## Prompt\n\n%s\n", r.Job.Prompt)
+	if r.Output != "" {
+		fmt.Fprintf(&sb, "\n## Output\n\n%s\n", r.Output)
+	}
+	return sb.String()
+}
+
+// openPullRequest commits the changes left by a run to a new branch, pushes
+// it and opens a pull request with the GitHub CLI. It returns an empty URL
+// when the run did not change anything.
+func openPullRequest(ctx context.Context, cwd string, r Report) (string, error) {
+	clean, err := gitIsClean(ctx, cwd)
+	if err != nil || clean {
+		return "", err
+	}
+
+	base, err := runCommand(ctx, cwd, "git", "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	branch := "crush/" + r.runID()
+	title := "crush job: " + r.Job.Name
+
+	steps := [][]string{
+		{"git", "checkout", "-b", branch},
+		{"git", "add", "-A"},
+		{"git", "commit", "-m", title},
+		{"git", "push", "-u", "origin", branch},
+	}
+	for _, step := range steps {
+		if _, err := runCommand(ctx, cwd, step[0], step[1:]...); err != nil {
+			return "", err
+		}
+	}
+	// Leave the working tree on the branch it was on before the run.
+	defer func() {
+		if _, err := runCommand(ctx, cwd, "git", "checkout", base); err != nil {
+			slog.Error("Failed to switch back to base branch", "branch", base, "error", err)
+		}
+	}()
+
+	return runCommand(ctx, cwd, "gh", "pr", "create", "--base", base, "--head", branch, "--title", title, "--body", r.Markdown())
+}
+
+func gitIsClean(ctx context.Context, cwd string) (bool, error) {
+	out, err := runCommand(ctx, cwd, "git", "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out == "", nil
+}
+
+func runCommand(ctx context.Context, cwd, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = cwd
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Serve runs the jobs of the store whenever they are due until the context
+// is done. Jobs run one at a time.
+func Serve(ctx context.Context, a *app.App, store *Store, onReport func(Report)) error {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		jobs, err := store.List()
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if !job.Due(time.Now()) {
+				continue
+			}
+			// Record the run first so a failing job does not retry in a
+			// loop until its next scheduled time.
+			if err := store.SetLastRun(job.Name, time.Now()); err != nil {
+				return err
+			}
+			report, err := Run(ctx, a, job)
+			if err != nil {
+				slog.Error("Job failed", "job", job.Name, "error", err)
+				report.Status = StatusFailed
+				report.Error = err.Error()
+			}
+			if onReport != nil {
+				onReport(report)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package keychain stores the secrets of crush in the keychain of the OS: the
+// macOS Keychain, the Windows Credential Manager or libsecret elsewhere.
+package keychain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// service names the entries of crush in the keychain.
+const service = "crush"
+
+// ErrUnavailable is returned when the keychain of the OS can't be used.
+var ErrUnavailable = errors.New("no supported keychain")
+
+// The Windows Credential Manager is reached through the password vault of
+// PowerShell, the account and the secret are passed in the environment and
+// on stdin so that they are never interpreted as code.
+const (
+	windowsVault = `[void][Windows.Security.Credentials.PasswordVault,Windows.Security.Credentials,ContentType=WindowsRuntime]; $vault = New-Object Windows.Security.Credentials.PasswordVault; `
+	windowsGet   = windowsVault + `$c = $vault.Retrieve($env:CRUSH_KEYCHAIN_SERVICE, $env:CRUSH_KEYCHAIN_ACCOUNT); $c.RetrievePassword(); $c.Password`
+	windowsSet   = windowsVault + `$vault.Add((New-Object Windows.Security.Credentials.PasswordCredential($env:CRUSH_KEYCHAIN_SERVICE, $env:CRUSH_KEYCHAIN_ACCOUNT, [Console]::In.ReadToEnd())))`
+	windowsDel   = windowsVault + `$vault.Remove($vault.Retrieve($env:CRUSH_KEYCHAIN_SERVICE, $env:CRUSH_KEYCHAIN_ACCOUNT))`
+)
+
+// Get reads the secret of the account, with the security tool on macOS,
+// PowerShell on Windows or secret-tool, from libsecret, elsewhere.
+func Get(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "windows":
+		cmd = powershell(windowsGet, account)
+	default:
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	}
+	out, err := run(cmd, "")
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSpace(out)
+	if secret == "" {
+		return "", fmt.Errorf("no secret for %s in the keychain", account)
+	}
+	return secret, nil
+}
+
+// Set stores the secret of the account, replacing the previous one.
+func Set(account, secret string) error {
+	var cmd *exec.Cmd
+	stdin := ""
+	switch runtime.GOOS {
+	case "darwin":
+		// The arguments are seen by the other users in the process list,
+		// the command is given on stdin to the interactive mode instead.
+		if strings.ContainsAny(secret, "\r\n") {
+			return errors.New("the secret can't span several lines")
+		}
+		cmd = exec.Command("security", "-i")
+		stdin = fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", securityQuote(service), securityQuote(account), securityQuote(secret))
+	case "windows":
+		// The vault keeps several credentials for an account, the previous
+		// one is removed first.
+		_ = Delete(account)
+		cmd = powershell(windowsSet, account)
+		stdin = secret
+	default:
+		cmd = exec.Command("secret-tool", "store", "--label", "crush "+account, "service", service, "account", account)
+		stdin = secret
+	}
+	_, err := run(cmd, stdin)
+	return err
+}
+
+// Delete removes the secret of the account.
+func Delete(account string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "delete-generic-password", "-s", service, "-a", account)
+	case "windows":
+		cmd = powershell(windowsDel, account)
+	default:
+		cmd = exec.Command("secret-tool", "clear", "service", service, "account", account)
+	}
+	_, err := run(cmd, "")
+	return err
+}
+
+// securityQuote quotes the argument of a command of the interactive mode of
+// the security tool.
+func securityQuote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+func powershell(script, account string) *exec.Cmd {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.Env = append(os.Environ(),
+		"CRUSH_KEYCHAIN_SERVICE="+service,
+		"CRUSH_KEYCHAIN_ACCOUNT="+account,
+	)
+	return cmd
+}
+
+func run(cmd *exec.Cmd, stdin string) (string, error) {
+	if _, err := exec.LookPath(cmd.Path); err != nil {
+		return "", ErrUnavailable
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", cmd.Args[0], msg)
+		}
+		return "", fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,143 @@
+// Package ledger keeps a per-repository record of the files changed by
+// crush sessions, so a change can be traced back to the session that made
+// it.
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const fileName = "history.jsonl"
+
+// maxSummaryLength keeps each entry on one short line.
+const maxSummaryLength = 120
+
+// Entry records that a session changed a file.
+type Entry struct {
+	Time         int64  `json:"time"`
+	SessionID    string `json:"session_id"`
+	SessionTitle string `json:"session_title,omitempty"`
+	// Path is relative to the working directory, with forward slashes, so
+	// the ledger stays valid when the repository is moved.
+	Path    string `json:"path"`
+	Summary string `json:"summary"`
+	Model   string `json:"model,omitempty"`
+}
+
+// Ledger appends entries to the history.jsonl file of the data directory.
+type Ledger struct {
+	path       string
+	workingDir string
+	mu         sync.Mutex
+}
+
+// New returns the ledger of the given data directory, with paths relative
+// to the working directory.
+func New(dataDir, workingDir string) *Ledger {
+	return &Ledger{
+		path:       filepath.Join(dataDir, fileName),
+		workingDir: workingDir,
+	}
+}
+
+// Append records the entries, with their paths made relative to the
+// working directory and their summaries reduced to one line.
+func (l *Ledger) Append(entries ...Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		e.Path = l.rel(e.Path)
+		e.Summary = Summarize(e.Summary)
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForFile returns the entries of the file, most recent first.
+func (l *Ledger) ForFile(path string) ([]Entry, error) {
+	path = l.rel(path)
+	entries, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	var matches []Entry
+	for _, e := range slices.Backward(entries) {
+		if e.Path == path {
+			matches = append(matches, e)
+		}
+	}
+	return matches, nil
+}
+
+func (l *Ledger) read() ([]Entry, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		// Lines that don't parse, like a line cut by a crash, are skipped.
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func (l *Ledger) rel(path string) string {
+	if filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(l.workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// Summarize returns the first non-empty line of the text, shortened to fit
+// a ledger entry.
+func Summarize(text string) string {
+	for line := range strings.Lines(text) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if len([]rune(line)) > maxSummaryLength {
+			line = string([]rune(line)[:maxSummaryLength-3]) + "..."
+		}
+		return line
+	}
+	return ""
+}
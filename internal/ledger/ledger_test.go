@@ -0,0 +1,52 @@
+package ledger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedger(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	workingDir := t.TempDir()
+	l := New(dataDir, workingDir)
+
+	require.NoError(t, l.Append(
+		Entry{Time: 1, SessionID: "a", Path: filepath.Join(workingDir, "main.go"), Summary: "\nAdd a flag\nwith details"},
+		Entry{Time: 2, SessionID: "a", Path: "other.go", Summary: "Add a flag"},
+	))
+	require.NoError(t, l.Append(Entry{Time: 3, SessionID: "b", Path: "./main.go", Summary: strings.Repeat("x", 200)}))
+
+	entries, err := l.ForFile(filepath.Join(workingDir, "main.go"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "b", entries[0].SessionID)
+	require.Equal(t, maxSummaryLength, len(entries[0].Summary))
+	require.Equal(t, "a", entries[1].SessionID)
+	require.Equal(t, "main.go", entries[1].Path)
+	require.Equal(t, "Add a flag", entries[1].Summary)
+
+	// A line cut by a crash doesn't hide the others.
+	f, err := os.OpenFile(filepath.Join(dataDir, fileName), os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":4,"sess`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	entries, err = l.ForFile("other.go")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestLedgerMissingFile(t *testing.T) {
+	t.Parallel()
+
+	entries, err := New(t.TempDir(), t.TempDir()).ForFile("main.go")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
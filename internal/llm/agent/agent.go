@@ -7,12 +7,20 @@ import (
 	"log/slog"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/audit"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/dataset"
+	"github.com/charmbracelet/crush/internal/egress"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/ledger"
 	"github.com/charmbracelet/crush/internal/llm/prompt"
 	"github.com/charmbracelet/crush/internal/llm/provider"
 	"github.com/charmbracelet/crush/internal/llm/tools"
@@ -20,9 +28,13 @@ import (
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pin"
 	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/redact"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/shell"
+	"github.com/charmbracelet/crush/internal/symbols"
+	"github.com/charmbracelet/crush/internal/trash"
 )
 
 // Common errors
@@ -37,12 +49,18 @@ const (
 	AgentEventTypeError     AgentEventType = "error"
 	AgentEventTypeResponse  AgentEventType = "response"
 	AgentEventTypeSummarize AgentEventType = "summarize"
+	AgentEventTypeUsage     AgentEventType = "usage"
 )
 
 type AgentEvent struct {
 	Type    AgentEventType
 	Message message.Message
 	Error   error
+	// Duration is how long the run took, set on the event ending a run.
+	Duration time.Duration
+
+	// Usage is the token usage of the session, set on usage events.
+	Usage Usage
 
 	// When summarizing
 	SessionID string
@@ -61,15 +79,57 @@ type Service interface {
 	Summarize(ctx context.Context, sessionID string) error
 	UpdateModel() error
 	QueuedPrompts(sessionID string) int
+	// QueuedPromptList returns the prompts queued behind the running one, in
+	// the order they are sent.
+	QueuedPromptList(sessionID string) []string
+	// UpdateQueuedPrompt replaces the queued prompt at index, reporting
+	// whether it was still queued.
+	UpdateQueuedPrompt(sessionID string, index int, content string) bool
+	// RemoveQueuedPrompt takes the queued prompt at index out of the queue,
+	// reporting whether it was still queued.
+	RemoveQueuedPrompt(sessionID string, index int) (string, bool)
 	ClearQueue(sessionID string)
+	Prewarm(ctx context.Context)
+	// SetPlanMode turns plan mode on or off: the tools changing files or
+	// running commands propose their action instead of running it.
+	SetPlanMode(enabled bool)
+	PlanMode() bool
+	Plan(sessionID string) []ProposedAction
+	ExecutePlan(ctx context.Context, sessionID string) ([]PlanResult, error)
+	DiscardPlan(sessionID string)
+	// EndSessions runs the session_end hooks of the sessions which ran
+	// prompts, when crush exits.
+	EndSessions(ctx context.Context)
 }
 
 type agent struct {
 	*pubsub.Broker[AgentEvent]
-	agentCfg config.Agent
-	sessions session.Service
-	messages message.Service
-	mcpTools []McpTool
+	agentCfg  config.Agent
+	sessions  session.Service
+	messages  message.Service
+	history   history.Service
+	artifacts artifact.Service
+	pins      pin.Service
+	ledger    *ledger.Ledger
+	mcpTools  []McpTool
+
+	// redactor masks the secrets of the messages sent to the provider, it
+	// is nil when the redaction is disabled.
+	redactor   *redact.Redactor
+	redactions *redact.Log
+	// audit records the tool calls and the requests sent to the provider,
+	// it is nil when the audit log is disabled.
+	audit *audit.Log
+	// dataset records the finished turns for fine-tuning, it is nil when
+	// the dataset is disabled.
+	dataset *dataset.Writer
+
+	permissions   permission.Service
+	planMode      atomic.Bool
+	plans         *csync.Map[string, *sessionPlan]
+	executedPlans *csync.Map[string, []PlanResult]
+
+	lspClients map[string]*lsp.Client
 
 	tools *csync.LazySlice[tools.BaseTool]
 
@@ -81,8 +141,30 @@ type agent struct {
 	summarizeProviderID string
 
 	activeRequests *csync.Map[string, context.CancelFunc]
+	// generations are closed once the running generation of each session
+	// is done, for the next one to wait for a canceled one to save its
+	// partial results.
+	generations *csync.Map[string, chan struct{}]
+	// turnUsage is the usage of the finished requests of the running turn of
+	// each session.
+	turnUsage *csync.Map[string, provider.TokenUsage]
+	// routes are how the model answering the running turn of each session
+	// was picked.
+	routes *csync.Map[string, route]
 
 	promptQueue *csync.Map[string, []string]
+
+	// seededSessions holds the sessions that already got the files touched
+	// earlier in the session added to their first prompt in this run.
+	seededSessions *csync.Map[string, bool]
+	// promptedSessions holds the sessions which ran prompts in this run,
+	// for the session_end hooks.
+	promptedSessions *csync.Map[string, bool]
+
+	// lastRequest is the time, in nanoseconds, the provider was last sent a
+	// request, the warm-up requests are skipped when it is recent.
+	lastRequest   atomic.Int64
+	keepAliveOnce sync.Once
 }
 
 var agentPromptMap = map[string]prompt.PromptID{
@@ -98,6 +180,9 @@ func NewAgent(
 	sessions session.Service,
 	messages message.Service,
 	history history.Service,
+	artifacts artifact.Service,
+	trashBin trash.Service,
+	pins pin.Service,
 	lspClients map[string]*lsp.Client,
 ) (Service, error) {
 	cfg := config.Get()
@@ -108,7 +193,7 @@ func NewAgent(
 		if taskAgentCfg.ID == "" {
 			return nil, fmt.Errorf("task agent not found in config")
 		}
-		taskAgent, err := NewAgent(ctx, taskAgentCfg, permissions, sessions, messages, history, lspClients)
+		taskAgent, err := NewAgent(ctx, taskAgentCfg, permissions, sessions, messages, history, artifacts, trashBin, pins, lspClients)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create task agent: %w", err)
 		}
@@ -133,6 +218,12 @@ func NewAgent(
 	opts := []provider.ProviderClientOption{
 		provider.WithModel(agentCfg.Model),
 		provider.WithSystemMessage(prompt.GetPrompt(promptID, providerCfg.ID, config.Get().Options.ContextPaths...)),
+		provider.WithSystemContext(prompt.SystemContext(promptID)),
+	}
+	if systemContext := prompt.SystemContext(promptID); systemContext != nil {
+		// Built ahead for the first prompt not to wait for the files to be
+		// parsed.
+		go systemContext()
 	}
 	agentProvider, err := provider.NewProvider(*providerCfg, opts...)
 	if err != nil {
@@ -179,45 +270,33 @@ func NewAgent(
 			slog.Info("Initialized agent tools", "agent", agentCfg.ID)
 		}()
 
-		cwd := cfg.WorkingDir()
-		allTools := []tools.BaseTool{
-			tools.NewBashTool(permissions, cwd),
-			tools.NewDownloadTool(permissions, cwd),
-			tools.NewEditTool(lspClients, permissions, history, cwd),
-			tools.NewMultiEditTool(lspClients, permissions, history, cwd),
-			tools.NewFetchTool(permissions, cwd),
-			tools.NewGlobTool(cwd),
-			tools.NewGrepTool(cwd),
-			tools.NewLsTool(permissions, cwd),
-			tools.NewSourcegraphTool(),
-			tools.NewViewTool(lspClients, permissions, cwd),
-			tools.NewWriteTool(lspClients, permissions, history, cwd),
-		}
-
-		mcpToolsOnce.Do(func() {
-			mcpTools = doGetMCPTools(ctx, permissions, cfg)
-		})
-		allTools = append(allTools, mcpTools...)
-
-		if len(lspClients) > 0 {
-			allTools = append(allTools, tools.NewDiagnosticsTool(lspClients))
-		}
+		allTools := BuiltinTools(cfg, permissions, history, artifacts, trashBin, lspClients)
 
 		if agentTool != nil {
 			allTools = append(allTools, agentTool)
 		}
 
-		if agentCfg.AllowedTools == nil {
-			return allTools
-		}
+		return allTools
+	}
 
-		var filteredTools []tools.BaseTool
-		for _, tool := range allTools {
-			if slices.Contains(agentCfg.AllowedTools, tool.Name()) {
-				filteredTools = append(filteredTools, tool)
-			}
+	// MCP servers connect in the background; their tools become available
+	// as each one finishes initializing.
+	initMCPClients(ctx, permissions, cfg)
+
+	var changes *ledger.Ledger
+	if !cfg.Options.DisableLedger {
+		changes = ledger.New(cfg.Options.DataDirectory, cfg.WorkingDir())
+	}
+
+	var redactor *redact.Redactor
+	var redactions *redact.Log
+	if opts := cfg.Options.Redaction; opts == nil || !opts.Disabled {
+		var redactOpts redact.Options
+		if opts != nil {
+			redactOpts = redact.Options{Allowlist: opts.Allowlist, Entropy: opts.Entropy}
 		}
-		return filteredTools
+		redactor = redact.New(redactOpts)
+		redactions = redact.NewLog(cfg.Options.DataDirectory)
 	}
 
 	return &agent{
@@ -227,19 +306,224 @@ func NewAgent(
 		providerID:          string(providerCfg.ID),
 		messages:            messages,
 		sessions:            sessions,
+		history:             history,
+		artifacts:           artifacts,
+		pins:                pins,
+		ledger:              changes,
+		redactor:            redactor,
+		redactions:          redactions,
+		audit:               cfg.AuditLog(),
+		dataset:             newDataset(cfg),
+		permissions:         permissions,
+		plans:               csync.NewMap[string, *sessionPlan](),
+		executedPlans:       csync.NewMap[string, []PlanResult](),
 		titleProvider:       titleProvider,
 		summarizeProvider:   summarizeProvider,
 		summarizeProviderID: string(providerCfg.ID),
 		activeRequests:      csync.NewMap[string, context.CancelFunc](),
+		generations:         csync.NewMap[string, chan struct{}](),
+		turnUsage:           csync.NewMap[string, provider.TokenUsage](),
+		routes:              csync.NewMap[string, route](),
 		tools:               csync.NewLazySlice(toolFn),
 		promptQueue:         csync.NewMap[string, []string](),
+		seededSessions:      csync.NewMap[string, bool](),
+		promptedSessions:    csync.NewMap[string, bool](),
+		lspClients:          lspClients,
 	}, nil
 }
 
+// BuiltinTools returns the tools crush implements itself, without the
+// agent and MCP tools.
+func BuiltinTools(
+	cfg *config.Config,
+	permissions permission.Service,
+	history history.Service,
+	artifacts artifact.Service,
+	trashBin trash.Service,
+	lspClients map[string]*lsp.Client,
+) []tools.BaseTool {
+	cwd := cfg.WorkingDir()
+	if cfg.Options.DisableTrash {
+		trashBin = nil
+	}
+	egressPolicy := cfg.EgressPolicy()
+	var bashEgress *egress.Policy
+	if egressPolicy != nil && cfg.Options.Egress.Bash {
+		bashEgress = egressPolicy
+	}
+	var bashShell tools.BashShell
+	if opts := cfg.Options.Shell; opts != nil {
+		env, err := opts.ResolvedEnv(cwd)
+		if err != nil {
+			slog.Error("Failed to resolve the environment of the bash commands", "error", err)
+		}
+		bashShell = tools.BashShell{Program: opts.Program, SourceRC: opts.SourceRC, Env: env}
+	}
+	allTools := []tools.BaseTool{
+		tools.NewBashTool(permissions, trashBin, cwd, bashEgress, bashShell),
+		tools.NewResetShellTool(cwd),
+		tools.NewDownloadTool(permissions, cwd, egressPolicy),
+		tools.NewEditTool(lspClients, permissions, history, trashBin, cwd),
+		tools.NewMultiEditTool(lspClients, permissions, history, trashBin, cwd),
+		tools.NewFetchTool(permissions, cwd, egressPolicy),
+		tools.NewGlobTool(cwd),
+		tools.NewGrepTool(cwd),
+		tools.NewLsTool(permissions, cwd),
+		tools.NewOutlineFileTool(cwd),
+		tools.NewPlanUpdateTool(),
+		tools.NewReadArtifactTool(artifacts),
+		tools.NewExpandResultTool(artifacts),
+		tools.NewRunTestsTool(permissions, cwd),
+		tools.NewSourcegraphTool(egressPolicy),
+		tools.NewViewTool(lspClients, permissions, history, cwd),
+		tools.NewWriteTool(lspClients, permissions, history, trashBin, cwd),
+	}
+
+	// Without tree-sitter the symbols are only found line by line, the
+	// language servers find them reliably when there are some.
+	if symbols.Precise() || !cfg.LSP.HasEnabled() {
+		allTools = append(allTools,
+			tools.NewFindDefinitionTool(cwd),
+			tools.NewListSymbolsTool(cwd),
+		)
+	}
+
+	// LSP clients may still be starting (or not started at all when
+	// startup is deferred), so rely on the configuration instead.
+	if cfg.LSP.HasEnabled() {
+		allTools = append(allTools,
+			tools.NewDiagnosticsTool(lspClients),
+			tools.NewFindReferencesTool(lspClients, cwd),
+			tools.NewCallHierarchyTool(lspClients, cwd),
+			tools.NewWorkspaceSymbolsTool(lspClients, cwd),
+			tools.NewRenameSymbolTool(lspClients, permissions, history, trashBin, cwd),
+			tools.NewCodeActionTool(lspClients, permissions, history, trashBin, cwd),
+			tools.NewFormatFileTool(lspClients, permissions, history, trashBin, cwd),
+		)
+	}
+
+	if len(cfg.Databases) > 0 {
+		allTools = append(allTools, tools.NewDBQueryTool(permissions, cfg.Databases))
+	}
+	return allTools
+}
+
+const (
+	// maxInlineToolResult is the size above which a tool result is moved to
+	// the artifact store and replaced by a preview.
+	maxInlineToolResult = 64 * 1024
+	// artifactPreviewSize is the size of the preview of an offloaded result.
+	artifactPreviewSize = 4 * 1024
+)
+
+// storeArtifacts saves the artifacts returned by the tool, and the result
+// itself when too large, in the session artifact store so the conversation
+// only holds a short handle and a preview.
+func (a *agent) storeArtifacts(sessionID, toolName string, response tools.ToolResponse) tools.ToolResponse {
+	if a.artifacts == nil {
+		return response
+	}
+
+	if len(response.Artifacts) == 0 && response.Type == tools.ToolResponseTypeText && len(response.Content) > maxInlineToolResult {
+		full := response.Content
+		response.Artifacts = []tools.ToolArtifact{{Name: toolName + "-output.txt", MimeType: "text/plain", Data: []byte(full)}}
+		preview := truncateToValidUTF8(full, artifactPreviewSize)
+		response.Content = preview + fmt.Sprintf("\n\n... [%d bytes omitted] ...", len(full)-len(preview))
+	}
+
+	var handles []string
+	for _, ta := range response.Artifacts {
+		stored, err := a.artifacts.Create(sessionID, artifact.CreateParams{
+			Name:     ta.Name,
+			MimeType: ta.MimeType,
+			ToolName: toolName,
+			Data:     ta.Data,
+		})
+		if err != nil {
+			slog.Error("Failed to store tool artifact", "tool", toolName, "error", err)
+			continue
+		}
+		handles = append(handles, fmt.Sprintf("<artifact id=%q name=%q mime_type=%q size=%d />", stored.ID, stored.Name, stored.MimeType, stored.Size))
+	}
+	response.Artifacts = nil
+	if len(handles) > 0 {
+		response.Content += "\n\nThe full content is stored in the following artifacts, use the read_artifact tool to read them:\n" + strings.Join(handles, "\n")
+	}
+	return response
+}
+
+// truncateToValidUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateToValidUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// maxSeededTouchedFiles is the maximum number of touched files listed when a
+// session is resumed.
+const maxSeededTouchedFiles = 20
+
+// withTouchedFiles adds the files read and modified earlier in a resumed
+// session to the first prompt sent in this run, so the model knows where
+// the work left off. The stored message is left untouched.
+func (a *agent) withTouchedFiles(ctx context.Context, sessionID string, resumed bool, msg message.Message) message.Message {
+	if _, seeded := a.seededSessions.Get(sessionID); seeded {
+		return msg
+	}
+	a.seededSessions.Set(sessionID, true)
+	if !resumed || a.history == nil {
+		return msg
+	}
+
+	touched, err := a.history.ListTouched(ctx, sessionID)
+	if err != nil {
+		slog.Error("Failed to list touched files", "error", err)
+		return msg
+	}
+	if len(touched) == 0 {
+		return msg
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<touched_files>\nFiles read or modified earlier in this session, most recent first:\n")
+	for i, f := range touched {
+		if i == maxSeededTouchedFiles {
+			fmt.Fprintf(&sb, "- ... and %d more\n", len(touched)-maxSeededTouchedFiles)
+			break
+		}
+		fmt.Fprintf(&sb, "- %s (read %d, modified %d, last line %d)\n", f.Path, f.ReadCount, f.WriteCount, f.LastLine)
+	}
+	sb.WriteString("</touched_files>")
+	return withNote(msg, sb.String())
+}
+
 func (a *agent) Model() catwalk.Model {
 	return *config.Get().GetModelByType(a.agentCfg.Model)
 }
 
+// availableTools returns the built-in tools together with the tools of the
+// MCP servers that are connected right now, filtered by the agent's allowed
+// tools.
+func (a *agent) availableTools() []tools.BaseTool {
+	allTools := slices.Collect(a.tools.Seq())
+	allTools = append(allTools, connectedMCPTools()...)
+	if a.agentCfg.AllowedTools == nil {
+		return allTools
+	}
+
+	var filteredTools []tools.BaseTool
+	for _, tool := range allTools {
+		if slices.Contains(a.agentCfg.AllowedTools, tool.Name()) {
+			filteredTools = append(filteredTools, tool)
+		}
+	}
+	return filteredTools
+}
+
 func (a *agent) Cancel(sessionID string) {
 	// Cancel regular requests
 	if cancel, ok := a.activeRequests.Take(sessionID); ok && cancel != nil {
@@ -299,6 +583,7 @@ func (a *agent) generateTitle(ctx context.Context, sessionID string, content str
 	}}
 
 	// Use streaming approach like summarization
+	a.auditRequest(sessionID, "title", config.Get().Models[config.SelectedModelTypeSmall].Provider, a.titleProvider.Model().ID, 1, 0)
 	response := a.titleProvider.StreamResponse(
 		ctx,
 		[]message.Message{
@@ -341,24 +626,36 @@ func (a *agent) err(err error) AgentEvent {
 
 func (a *agent) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error) {
 	if !a.Model().SupportsImages && attachments != nil {
-		attachments = nil
+		// Text attachments, like MCP resources, are sent as text.
+		attachments = slices.DeleteFunc(slices.Clone(attachments), func(attachment message.Attachment) bool {
+			return !message.IsTextMIMEType(attachment.MimeType)
+		})
 	}
 	events := make(chan AgentEvent)
 	if a.IsSessionBusy(sessionID) {
-		existing, ok := a.promptQueue.Get(sessionID)
-		if !ok {
-			existing = []string{}
-		}
-		existing = append(existing, content)
-		a.promptQueue.Set(sessionID, existing)
+		a.promptQueue.Update(sessionID, func(existing []string, _ bool) ([]string, bool) {
+			return append(existing, content), true
+		})
 		return nil, nil
 	}
 
-	genCtx, cancel := context.WithCancel(ctx)
+	genCtx, cancel := context.WithCancelCause(ctx)
 
-	a.activeRequests.Set(sessionID, cancel)
+	a.promptedSessions.Set(sessionID, true)
+	a.activeRequests.Set(sessionID, func() { cancel(ErrRequestCancelled) })
+	a.turnUsage.Del(sessionID)
+	previous, _ := a.generations.Get(sessionID)
+	done := make(chan struct{})
+	a.generations.Set(sessionID, done)
 	go func() {
+		defer close(done)
+		if previous != nil {
+			// The canceled generation may still be saving its partial
+			// results, the new one must see them.
+			<-previous
+		}
 		slog.Debug("Request started", "sessionID", sessionID)
+		started := time.Now()
 		defer log.RecoverPanic("agent.Run", func() {
 			events <- a.err(fmt.Errorf("panic while running the agent"))
 		})
@@ -367,12 +664,20 @@ func (a *agent) Run(ctx context.Context, sessionID string, content string, attac
 			attachmentParts = append(attachmentParts, message.BinaryContent{Path: attachment.FilePath, MIMEType: attachment.MimeType, Data: attachment.Content})
 		}
 		result := a.processGeneration(genCtx, sessionID, content, attachmentParts)
+		result.Duration = time.Since(started)
 		if result.Error != nil && !errors.Is(result.Error, ErrRequestCancelled) && !errors.Is(result.Error, context.Canceled) {
 			slog.Error(result.Error.Error())
 		}
 		slog.Debug("Request completed", "sessionID", sessionID)
-		a.activeRequests.Del(sessionID)
-		cancel()
+		if !errors.Is(context.Cause(genCtx), ErrRequestCancelled) {
+			// A canceled request was removed already, and the session may
+			// be running the next one.
+			a.activeRequests.Del(sessionID)
+		}
+		a.generations.Update(sessionID, func(current chan struct{}, ok bool) (chan struct{}, bool) {
+			return current, ok && current != done
+		})
+		cancel(nil)
 		a.Publish(pubsub.CreatedEvent, result)
 		events <- result
 		close(events)
@@ -415,14 +720,30 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 			msgs[0].Role = message.User
 		}
 	}
+	msgs = repairHistory(msgs)
 
 	userMsg, err := a.createUserMessage(ctx, sessionID, content, attachmentParts)
 	if err != nil {
 		return a.err(fmt.Errorf("failed to create user message: %w", err))
 	}
 	// Append the new user message to the conversation history.
-	msgHistory := append(msgs, userMsg)
+	msgHistory := append(msgs, withInterruption(msgs, a.withPins(ctx, sessionID, msgs, a.withExternalChanges(sessionID, a.withPlanNotes(sessionID, a.withTouchedFiles(ctx, sessionID, len(msgs) > 0, userMsg))))))
 
+	opts := runOptionsFrom(ctx)
+	var r route
+	if opts.Model == "" {
+		opts.Model = routeModel(cfg, a.agentCfg.Model, newPromptTraits(content, len(attachmentParts), msgs))
+		r = newRoute(cfg, a.agentCfg.Model, opts.Model)
+	}
+	a.routes.Set(sessionID, r)
+	tp, err := a.providerFor(opts)
+	if err != nil {
+		return a.err(err)
+	}
+
+	var fix autoFix
+	var changed changedFiles
+	hookContinuations := 0
 	for {
 		// Check for cancellation before each iteration
 		select {
@@ -431,7 +752,7 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		default:
 			// Continue processing
 		}
-		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, sessionID, msgHistory)
+		agentMessage, toolResults, err := a.streamAndHandleEvents(ctx, tp, opts, sessionID, msgHistory)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				agentMessage.AddFinish(message.FinishReasonCanceled, "Request cancelled", "")
@@ -443,6 +764,8 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		if cfg.Options.Debug {
 			slog.Info("Result", "message", agentMessage.FinishReason(), "toolResults", toolResults)
 		}
+		fix.track(agentMessage, cfg.WorkingDir())
+		changed.track(agentMessage, toolResults)
 		if (agentMessage.FinishReason() == message.FinishReasonToolUse) && toolResults != nil {
 			// We are not done, we need to respond with the tool response
 			msgHistory = append(msgHistory, agentMessage, *toolResults)
@@ -476,6 +799,43 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 				}
 				continue
 			}
+			// The errors left in the edited files are sent back until they
+			// are fixed or the attempts are exhausted.
+			if prompt, ok := a.autoFixPrompt(&fix); ok {
+				userMsg, err := a.createUserMessage(ctx, sessionID, prompt, nil)
+				if err != nil {
+					return a.err(fmt.Errorf("failed to create user message for auto-fix: %w", err))
+				}
+				msgHistory = append(msgHistory, agentMessage, userMsg)
+				continue
+			}
+			// The turn_complete hooks, like lint gates, can keep the agent
+			// working.
+			if prompt, ok := a.turnCompletePrompt(ctx, sessionID, agentMessage, fix.files, &hookContinuations); ok {
+				userMsg, err := a.createUserMessage(ctx, sessionID, prompt, nil)
+				if err != nil {
+					return a.err(fmt.Errorf("failed to create user message for hook feedback: %w", err))
+				}
+				msgHistory = append(msgHistory, agentMessage, userMsg)
+				continue
+			}
+			// The answer of the small model is a draft the large model
+			// checks, answering instead when it isn't good enough.
+			if r.drafting() {
+				large, err := a.providerFor(RunOptions{Model: config.SelectedModelTypeLarge, AllowedTools: opts.AllowedTools})
+				if err != nil {
+					return a.err(err)
+				}
+				if reason, ok := a.verifyDraft(ctx, sessionID, &r, large, append(msgHistory, agentMessage)); !ok {
+					userMsg, err := a.createUserMessage(ctx, sessionID, escalationPrompt(reason), nil)
+					if err != nil {
+						return a.err(fmt.Errorf("failed to create user message for escalation: %w", err))
+					}
+					msgHistory = append(msgHistory, agentMessage, userMsg)
+					tp, opts.Model = large, config.SelectedModelTypeLarge
+					continue
+				}
+			}
 		}
 		if agentMessage.FinishReason() == "" {
 			// Kujtim: could not track down where this is happening but this means its cancelled
@@ -483,6 +843,10 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 			_ = a.messages.Update(context.Background(), agentMessage)
 			return a.err(ErrRequestCancelled)
 		}
+		a.recordLedger(ctx, sessionID, content, changed)
+		if agentMessage.FinishReason() == message.FinishReasonEndTurn {
+			a.recordDataset(tp, opts, append(msgHistory, agentMessage))
+		}
 		return AgentEvent{
 			Type:    AgentEventTypeResponse,
 			Message: agentMessage,
@@ -500,39 +864,43 @@ func (a *agent) createUserMessage(ctx context.Context, sessionID, content string
 	})
 }
 
-func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
+func (a *agent) streamAndHandleEvents(ctx context.Context, tp turnProvider, opts RunOptions, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
 
 	// Create the assistant message first so the spinner shows immediately
 	assistantMsg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:     message.Assistant,
 		Parts:    []message.ContentPart{},
-		Model:    a.Model().ID,
-		Provider: a.providerID,
+		Model:    tp.model.ID,
+		Provider: tp.id,
 	})
 	if err != nil {
 		return assistantMsg, nil, fmt.Errorf("failed to create assistant message: %w", err)
 	}
 
-	// Now collect tools (which may block on MCP initialization)
-	eventChan := a.provider.StreamResponse(ctx, msgHistory, slices.Collect(a.tools.Seq()))
+	// Collect the tools available for this turn; MCP servers that are still
+	// starting are picked up on a later turn.
+	availableTools := a.runTools(opts)
+	a.markRequest()
+	a.auditRequest(sessionID, "prompt", tp.id, tp.model.ID, len(msgHistory), len(availableTools))
+	eventChan := tp.provider.StreamResponse(ctx, a.redactMessages(sessionID, msgHistory), availableTools)
 
 	// Add the session and message ID into the context if needed by tools.
 	ctx = context.WithValue(ctx, tools.MessageIDContextKey, assistantMsg.ID)
 
 	// Process each event in the stream.
 	for event := range eventChan {
-		if processErr := a.processEvent(ctx, sessionID, &assistantMsg, event); processErr != nil {
+		if processErr := a.processEvent(ctx, tp.model, sessionID, &assistantMsg, event); processErr != nil {
 			if errors.Is(processErr, context.Canceled) {
-				a.finishMessage(context.Background(), &assistantMsg, message.FinishReasonCanceled, "Request cancelled", "")
-			} else {
-				a.finishMessage(ctx, &assistantMsg, message.FinishReasonError, "API Error", processErr.Error())
+				toolResults := a.cancelStream(tp, &assistantMsg)
+				return assistantMsg, toolResults, processErr
 			}
+			a.finishMessage(ctx, &assistantMsg, message.FinishReasonError, "API Error", processErr.Error())
 			return assistantMsg, nil, processErr
 		}
 		if ctx.Err() != nil {
-			a.finishMessage(context.Background(), &assistantMsg, message.FinishReasonCanceled, "Request cancelled", "")
-			return assistantMsg, nil, ctx.Err()
+			toolResults := a.cancelStream(tp, &assistantMsg)
+			return assistantMsg, toolResults, ctx.Err()
 		}
 	}
 
@@ -554,7 +922,7 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		default:
 			// Continue processing
 			var tool tools.BaseTool
-			for availableTool := range a.tools.Seq() {
+			for _, availableTool := range availableTools {
 				if availableTool.Info().Name == toolCall.Name {
 					tool = availableTool
 					break
@@ -571,6 +939,16 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 				continue
 			}
 
+			if a.isPlanned(tool, toolCall) {
+				proposed := a.propose(sessionID, assistantMsg.ID, toolCall)
+				toolResults[i] = message.ToolResult{
+					ToolCallID: toolCall.ID,
+					Content:    proposed.Content,
+					IsError:    proposed.IsError,
+				}
+				continue
+			}
+
 			// Run tool in goroutine to allow cancellation
 			type toolExecResult struct {
 				response tools.ToolResponse
@@ -578,8 +956,9 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 			}
 			resultChan := make(chan toolExecResult, 1)
 
+			started := time.Now()
 			go func() {
-				response, err := tool.Run(ctx, tools.ToolCall{
+				response, err := a.runTool(ctx, sessionID, tool, tools.ToolCall{
 					ID:    toolCall.ID,
 					Name:  toolCall.Name,
 					Input: toolCall.Input,
@@ -597,10 +976,21 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 				for j := i; j < len(toolCalls); j++ {
 					toolResults[j] = message.ToolResult{
 						ToolCallID: toolCalls[j].ID,
-						Content:    "Tool execution canceled by user",
+						Content:    toolCanceledContent,
 						IsError:    true,
 					}
 				}
+				// The tool gets a moment to return what it did so far, like
+				// the output of the aborted command.
+				select {
+				case result := <-resultChan:
+					if result.err == nil && result.response.Content != "" {
+						toolResults[i].Content = result.response.Content + "\n\n" + toolCanceledContent
+						toolResults[i].Metadata = result.response.Metadata
+					}
+				case <-time.After(toolCancelGrace):
+				}
+				a.auditToolCall(sessionID, toolCall, true, time.Since(started))
 				goto out
 			case result := <-resultChan:
 				toolResponse = result.response
@@ -610,6 +1000,17 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 			if toolErr != nil {
 				slog.Error("Tool execution error", "toolCall", toolCall.ID, "error", toolErr)
 				if errors.Is(toolErr, permission.ErrorPermissionDenied) {
+					// With feedback, the agent goes on with what the user
+					// told it to do instead.
+					if feedback, ok := a.permissions.Feedback(toolCall.ID); ok {
+						toolResults[i] = message.ToolResult{
+							ToolCallID: toolCall.ID,
+							Content:    fmt.Sprintf("The user denied the permission to run this tool call and said:\n%s", feedback),
+							IsError:    true,
+						}
+						a.auditToolCall(sessionID, toolCall, true, time.Since(started))
+						continue
+					}
 					toolResults[i] = message.ToolResult{
 						ToolCallID: toolCall.ID,
 						Content:    "Permission denied",
@@ -626,6 +1027,9 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 					break
 				}
 			}
+			a.auditToolCall(sessionID, toolCall, toolResponse.IsError || toolErr != nil, time.Since(started))
+			toolResponse = a.compressResult(ctx, assistantMsg.SessionID, toolCall.Name, toolResponse)
+			toolResponse = a.storeArtifacts(assistantMsg.SessionID, toolCall.Name, toolResponse)
 			toolResults[i] = message.ToolResult{
 				ToolCallID: toolCall.ID,
 				Content:    toolResponse.Content,
@@ -645,7 +1049,7 @@ out:
 	msg, err := a.messages.Create(context.Background(), assistantMsg.SessionID, message.CreateMessageParams{
 		Role:     message.Tool,
 		Parts:    parts,
-		Provider: a.providerID,
+		Provider: tp.id,
 	})
 	if err != nil {
 		return assistantMsg, nil, fmt.Errorf("failed to create cancelled tool message: %w", err)
@@ -659,7 +1063,7 @@ func (a *agent) finishMessage(ctx context.Context, msg *message.Message, finishR
 	_ = a.messages.Update(ctx, *msg)
 }
 
-func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg *message.Message, event provider.ProviderEvent) error {
+func (a *agent) processEvent(ctx context.Context, model catwalk.Model, sessionID string, assistantMsg *message.Message, event provider.ProviderEvent) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -670,9 +1074,11 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 	switch event.Type {
 	case provider.EventThinkingDelta:
 		assistantMsg.AppendReasoningContent(event.Thinking)
+		assistantMsg.SetReasoningFormat(event.ReasoningFormat)
 		return a.messages.Update(ctx, *assistantMsg)
 	case provider.EventSignatureDelta:
 		assistantMsg.AppendReasoningSignature(event.Signature)
+		assistantMsg.SetReasoningFormat(event.ReasoningFormat)
 		return a.messages.Update(ctx, *assistantMsg)
 	case provider.EventContentDelta:
 		assistantMsg.FinishThinking()
@@ -690,6 +1096,8 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		slog.Info("Finished tool call", "toolCall", event.ToolCall)
 		assistantMsg.FinishToolCall(event.ToolCall.ID)
 		return a.messages.Update(ctx, *assistantMsg)
+	case provider.EventUsage:
+		return a.streamUsage(ctx, sessionID, model, event.Response.Usage)
 	case provider.EventError:
 		return event.Error
 	case provider.EventComplete:
@@ -699,7 +1107,7 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		if err := a.messages.Update(ctx, *assistantMsg); err != nil {
 			return fmt.Errorf("failed to update message: %w", err)
 		}
-		return a.TrackUsage(ctx, sessionID, a.Model(), event.Response.Usage)
+		return a.TrackUsage(ctx, sessionID, model, event.Response.Usage)
 	}
 
 	return nil
@@ -711,12 +1119,7 @@ func (a *agent) TrackUsage(ctx context.Context, sessionID string, model catwalk.
 		return fmt.Errorf("failed to get session: %w", err)
 	}
 
-	cost := model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
-		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
-		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
-		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
-
-	sess.Cost += cost
+	sess.Cost += usageCost(model, usage)
 	sess.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
 	sess.PromptTokens = usage.InputTokens + usage.CacheCreationTokens
 
@@ -724,9 +1127,40 @@ func (a *agent) TrackUsage(ctx context.Context, sessionID string, model catwalk.
 	if err != nil {
 		return fmt.Errorf("failed to save session: %w", err)
 	}
+
+	var turn provider.TokenUsage
+	a.turnUsage.Update(sessionID, func(existing provider.TokenUsage, _ bool) (provider.TokenUsage, bool) {
+		turn = addUsage(existing, usage)
+		return turn, true
+	})
+	a.publishUsage(sessionID, model, sess.Cost, turn, usage)
 	return nil
 }
 
+// streamUsage publishes the usage of the request being streamed, before it is
+// tracked once the request completes.
+func (a *agent) streamUsage(ctx context.Context, sessionID string, model catwalk.Model, usage provider.TokenUsage) error {
+	sess, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	turn, _ := a.turnUsage.Get(sessionID)
+	a.publishUsage(sessionID, model, sess.Cost+usageCost(model, usage), addUsage(turn, usage), usage)
+	return nil
+}
+
+func (a *agent) publishUsage(sessionID string, model catwalk.Model, cost float64, turn, last provider.TokenUsage) {
+	usage := newUsage(model, cost, turn, last)
+	if r, ok := a.routes.Get(sessionID); ok {
+		usage.Route = r.String()
+	}
+	a.Publish(pubsub.UpdatedEvent, AgentEvent{
+		Type:      AgentEventTypeUsage,
+		SessionID: sessionID,
+		Usage:     usage,
+	})
+}
+
 func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	if a.summarizeProvider == nil {
 		return fmt.Errorf("summarize provider not available")
@@ -801,9 +1235,10 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 		a.Publish(pubsub.CreatedEvent, event)
 
 		// Send the messages to the summarize provider
+		a.auditRequest(sessionID, "summary", a.summarizeProviderID, a.summarizeProvider.Model().ID, len(msgsWithPrompt), 0)
 		response := a.summarizeProvider.StreamResponse(
 			summarizeCtx,
-			msgsWithPrompt,
+			a.redactMessages(sessionID, msgsWithPrompt),
 			nil,
 		)
 		var finalResponse *provider.ProviderResponse
@@ -830,7 +1265,7 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 			a.Publish(pubsub.CreatedEvent, event)
 			return
 		}
-		shell := shell.GetPersistentShell(config.Get().WorkingDir())
+		shell := shell.GetSessionShell(sessionID, config.Get().WorkingDir())
 		summary += "\n\n**Current working directory of the persistent shell**\n\n" + shell.GetWorkingDir()
 		event = AgentEvent{
 			Type:     AgentEventTypeSummarize,
@@ -905,6 +1340,38 @@ func (a *agent) Summarize(ctx context.Context, sessionID string) error {
 	return nil
 }
 
+func (a *agent) QueuedPromptList(sessionID string) []string {
+	l, _ := a.promptQueue.Get(sessionID)
+	return slices.Clone(l)
+}
+
+func (a *agent) UpdateQueuedPrompt(sessionID string, index int, content string) bool {
+	var updated bool
+	a.promptQueue.Update(sessionID, func(l []string, ok bool) ([]string, bool) {
+		if index < 0 || index >= len(l) {
+			return l, ok
+		}
+		l[index] = content
+		updated = true
+		return l, true
+	})
+	return updated
+}
+
+func (a *agent) RemoveQueuedPrompt(sessionID string, index int) (string, bool) {
+	var removed string
+	var found bool
+	a.promptQueue.Update(sessionID, func(l []string, ok bool) ([]string, bool) {
+		if index < 0 || index >= len(l) {
+			return l, ok
+		}
+		removed, found = l[index], true
+		l = slices.Delete(l, index, index+1)
+		return l, len(l) > 0
+	})
+	return removed, found
+}
+
 func (a *agent) ClearQueue(sessionID string) {
 	if a.QueuedPrompts(sessionID) > 0 {
 		slog.Info("Clearing queued prompts", "session_id", sessionID)
@@ -929,6 +1396,14 @@ func (a *agent) CancelAll() {
 			time.Sleep(200 * time.Millisecond)
 		}
 	}
+	// The canceled generations save their partial results.
+	for done := range a.generations.Seq() {
+		select {
+		case <-done:
+		case <-timeout:
+			return
+		}
+	}
 }
 
 func (a *agent) UpdateModel() error {
@@ -956,6 +1431,7 @@ func (a *agent) UpdateModel() error {
 		opts := []provider.ProviderClientOption{
 			provider.WithModel(a.agentCfg.Model),
 			provider.WithSystemMessage(prompt.GetPrompt(promptID, currentProviderCfg.ID, cfg.Options.ContextPaths...)),
+			provider.WithSystemContext(prompt.SystemContext(promptID)),
 		}
 
 		newProvider, err := provider.NewProvider(*currentProviderCfg, opts...)
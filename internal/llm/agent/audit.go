@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/audit"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// auditRequest records the metadata of a request sent to a provider, the
+// content of the messages stays in the sessions.
+func (a *agent) auditRequest(sessionID, purpose, providerID, model string, messages, tools int) {
+	if a.audit == nil {
+		return
+	}
+	if err := a.audit.Record(audit.TypeLLMRequest, sessionID, audit.LLMRequest{
+		Provider: providerID,
+		Model:    model,
+		Purpose:  purpose,
+		Messages: messages,
+		Tools:    tools,
+	}); err != nil {
+		slog.Error("Failed to record the provider request", "error", err)
+	}
+}
+
+func (a *agent) auditToolCall(sessionID string, call message.ToolCall, isError bool, duration time.Duration) {
+	if a.audit == nil {
+		return
+	}
+	input, inputHash := audit.ToolInput(call.Input)
+	if err := a.audit.Record(audit.TypeToolCall, sessionID, audit.ToolCall{
+		Tool:       call.Name,
+		ToolCallID: call.ID,
+		Input:      input,
+		InputHash:  inputHash,
+		IsError:    isError,
+		DurationMs: duration.Milliseconds(),
+	}); err != nil {
+		slog.Error("Failed to record the tool call", "error", err)
+	}
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+const (
+	defaultAutoFixAttempts = 3
+	// maxAutoFixErrors keeps the message short when an edit broke a file.
+	maxAutoFixErrors = 20
+)
+
+// fileEditTools are the tools changing the file of their file_path parameter.
+var fileEditTools = []string{tools.EditToolName, tools.MultiEditToolName, tools.WriteToolName}
+
+// autoFix holds the files edited while answering a prompt, and how many
+// times their errors were sent back to the agent.
+type autoFix struct {
+	files    []string
+	attempts int
+}
+
+// track records the files edited by the tool calls of a message.
+func (f *autoFix) track(msg message.Message, workingDir string) {
+	for _, call := range msg.ToolCalls() {
+		path, ok := editedFile(call.Name, call.Input, workingDir)
+		if ok && !slices.Contains(f.files, path) {
+			f.files = append(f.files, path)
+		}
+	}
+}
+
+// editedFile returns the absolute path of the file a call of a file editing
+// tool changes.
+func editedFile(toolName, input, workingDir string) (string, bool) {
+	if !slices.Contains(fileEditTools, toolName) {
+		return "", false
+	}
+	var params struct {
+		FilePath string `json:"file_path"`
+	}
+	if err := json.Unmarshal([]byte(input), &params); err != nil || params.FilePath == "" {
+		return "", false
+	}
+	path := params.FilePath
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	return path, true
+}
+
+// autoFixPrompt returns the message sending the errors left in the edited
+// files back to the agent. It returns false when auto-fix is disabled, when
+// the files are clean or when the attempts are exhausted.
+func (a *agent) autoFixPrompt(f *autoFix) (string, bool) {
+	opts := config.Get().Options.AutoFix
+	if opts == nil || !opts.Enabled || len(f.files) == 0 || len(a.lspClients) == 0 {
+		return "", false
+	}
+	maxAttempts := cmp.Or(opts.MaxAttempts, defaultAutoFixAttempts)
+	if f.attempts >= maxAttempts {
+		return "", false
+	}
+
+	var errs []string
+	for _, file := range f.files {
+		errs = append(errs, tools.FileErrors(file, a.lspClients)...)
+	}
+	if len(errs) == 0 {
+		return "", false
+	}
+	f.attempts++
+
+	var sb strings.Builder
+	sb.WriteString("<file_diagnostics>\n")
+	sb.WriteString(strings.Join(errs[:min(len(errs), maxAutoFixErrors)], "\n"))
+	if len(errs) > maxAutoFixErrors {
+		fmt.Fprintf(&sb, "\n... and %d more errors", len(errs)-maxAutoFixErrors)
+	}
+	sb.WriteString("\n</file_diagnostics>\n\n")
+	fmt.Fprintf(&sb, "The language servers report these errors in the files you edited (auto-fix attempt %d of %d). ", f.attempts, maxAttempts)
+	sb.WriteString("Fix them, or explain why they should be left as they are.")
+	return sb.String(), true
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoFixTrack(t *testing.T) {
+	t.Parallel()
+
+	msg := message.Message{
+		Parts: []message.ContentPart{
+			message.ToolCall{Name: tools.EditToolName, Input: `{"file_path":"main.go"}`},
+			message.ToolCall{Name: tools.WriteToolName, Input: `{"file_path":"/tmp/other.go"}`},
+			message.ToolCall{Name: tools.MultiEditToolName, Input: `{"file_path":"/work/main.go"}`},
+			message.ToolCall{Name: tools.ViewToolName, Input: `{"file_path":"view.go"}`},
+			message.ToolCall{Name: tools.EditToolName, Input: `{`},
+		},
+	}
+
+	var fix autoFix
+	fix.track(msg, "/work")
+	require.Equal(t, []string{"/work/main.go", "/tmp/other.go"}, fix.files)
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// toolCancelGrace is how long a canceled tool gets to return what it did so
+// far, like the output of an aborted command.
+const toolCancelGrace = 2 * time.Second
+
+const toolCanceledContent = "Tool execution canceled by user"
+
+// closeToolCalls marks the tool calls of a message whose stream was canceled
+// as finished, replacing the inputs which were cut mid-way, so the providers
+// accept the message when it is sent back.
+func closeToolCalls(msg message.Message) message.Message {
+	parts := slices.Clone(msg.Parts)
+	for i, part := range parts {
+		call, ok := part.(message.ToolCall)
+		if !ok {
+			continue
+		}
+		call.Finished = true
+		if !json.Valid([]byte(call.Input)) {
+			call.Input = "{}"
+		}
+		parts[i] = call
+	}
+	msg.Parts = parts
+	return msg
+}
+
+// canceledResults returns the results of the tool calls which didn't run
+// because the request was canceled.
+func canceledResults(calls []message.ToolCall) []message.ToolResult {
+	results := make([]message.ToolResult, 0, len(calls))
+	for _, call := range calls {
+		results = append(results, message.ToolResult{
+			ToolCallID: call.ID,
+			Name:       call.Name,
+			Content:    toolCanceledContent,
+			IsError:    true,
+		})
+	}
+	return results
+}
+
+// repairHistory makes the history of a session valid for the providers after
+// a canceled request: every tool call gets a result and a closed input, and
+// the responses canceled before anything was streamed are dropped. The stored
+// messages are left untouched.
+func repairHistory(msgs []message.Message) []message.Message {
+	repaired := make([]message.Message, 0, len(msgs))
+	for i := 0; i < len(msgs); i++ {
+		msg := msgs[i]
+		if msg.Role != message.Assistant {
+			repaired = append(repaired, msg)
+			continue
+		}
+		calls := msg.ToolCalls()
+		if len(calls) == 0 {
+			if msg.FinishReason() == message.FinishReasonCanceled && strings.TrimSpace(msg.Content().Text) == "" {
+				continue
+			}
+			repaired = append(repaired, msg)
+			continue
+		}
+		repaired = append(repaired, closeToolCalls(msg))
+
+		results := message.Message{Role: message.Tool, SessionID: msg.SessionID}
+		if i+1 < len(msgs) && msgs[i+1].Role == message.Tool {
+			i++
+			results = msgs[i]
+		}
+		var missing []message.ToolCall
+		for _, call := range calls {
+			if !slices.ContainsFunc(results.ToolResults(), func(r message.ToolResult) bool {
+				return r.ToolCallID == call.ID
+			}) {
+				missing = append(missing, call)
+			}
+		}
+		if len(missing) > 0 {
+			results.Parts = slices.Clone(results.Parts)
+			for _, result := range canceledResults(missing) {
+				results.Parts = append(results.Parts, result)
+			}
+		}
+		repaired = append(repaired, results)
+	}
+	return repaired
+}
+
+// withInterruption tells the model that the user canceled its previous
+// response, so it follows the new instructions instead of resuming the
+// interrupted work. The stored message is left untouched.
+func withInterruption(history []message.Message, msg message.Message) message.Message {
+	for _, previous := range slices.Backward(history) {
+		if previous.Role != message.Assistant {
+			continue
+		}
+		if previous.FinishReason() != message.FinishReasonCanceled {
+			return msg
+		}
+		return withNote(msg, "<interrupted>\nThe user canceled your previous response before it was complete. "+
+			"What it wrote and the results of the tools which ran are kept above, the other tool calls didn't run. "+
+			"Don't resume the interrupted work unless asked to, follow the message above instead.\n</interrupted>")
+	}
+	return msg
+}
+
+// cancelStream saves the response whose stream was canceled with what was
+// streamed so far, and returns the results of its tool calls, which won't
+// run.
+func (a *agent) cancelStream(tp turnProvider, assistantMsg *message.Message) *message.Message {
+	*assistantMsg = closeToolCalls(*assistantMsg)
+	a.finishMessage(context.Background(), assistantMsg, message.FinishReasonCanceled, "Request cancelled", "")
+	calls := assistantMsg.ToolCalls()
+	if len(calls) == 0 {
+		return nil
+	}
+	parts := make([]message.ContentPart, 0, len(calls))
+	for _, result := range canceledResults(calls) {
+		parts = append(parts, result)
+	}
+	msg, err := a.messages.Create(context.Background(), assistantMsg.SessionID, message.CreateMessageParams{
+		Role:     message.Tool,
+		Parts:    parts,
+		Provider: tp.id,
+	})
+	if err != nil {
+		slog.Error("Failed to save the canceled tool calls", "error", err)
+		return nil
+	}
+	return &msg
+}
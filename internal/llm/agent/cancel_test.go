@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloseToolCalls(t *testing.T) {
+	t.Parallel()
+
+	msg := message.Message{Role: message.Assistant, Parts: []message.ContentPart{
+		message.TextContent{Text: "Let me look."},
+		message.ToolCall{ID: "1", Name: "view", Input: `{"file_path":"main.go"}`, Finished: true},
+		message.ToolCall{ID: "2", Name: "bash", Input: `{"command":"go te`},
+	}}
+	closed := closeToolCalls(msg)
+	require.Equal(t, []message.ToolCall{
+		{ID: "1", Name: "view", Input: `{"file_path":"main.go"}`, Finished: true},
+		{ID: "2", Name: "bash", Input: "{}", Finished: true},
+	}, closed.ToolCalls())
+	// The message is left untouched.
+	require.False(t, msg.ToolCalls()[1].Finished)
+}
+
+func TestRepairHistory(t *testing.T) {
+	t.Parallel()
+
+	user := message.Message{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "fix it"}}}
+	canceled := message.Message{Role: message.Assistant, Parts: []message.ContentPart{
+		message.ToolCall{ID: "1", Name: "view", Input: "{}", Finished: true},
+		message.ToolCall{ID: "2", Name: "bash", Input: `{"comm`},
+		message.Finish{Reason: message.FinishReasonCanceled},
+	}}
+	results := message.Message{Role: message.Tool, Parts: []message.ContentPart{
+		message.ToolResult{ToolCallID: "1", Content: "package main"},
+	}}
+	empty := message.Message{Role: message.Assistant, Parts: []message.ContentPart{
+		message.Finish{Reason: message.FinishReasonCanceled},
+	}}
+	unanswered := message.Message{Role: message.Assistant, Parts: []message.ContentPart{
+		message.ToolCall{ID: "3", Name: "ls", Input: "{}", Finished: true},
+		message.Finish{Reason: message.FinishReasonCanceled},
+	}}
+
+	repaired := repairHistory([]message.Message{user, canceled, results, user, empty, user, unanswered})
+	require.Len(t, repaired, 7)
+	require.Equal(t, message.User, repaired[0].Role)
+	require.Equal(t, "{}", repaired[1].ToolCalls()[1].Input)
+	require.Equal(t, []message.ToolResult{
+		{ToolCallID: "1", Content: "package main"},
+		{ToolCallID: "2", Name: "bash", Content: toolCanceledContent, IsError: true},
+	}, repaired[2].ToolResults())
+	// The response canceled before anything was streamed is dropped.
+	require.Equal(t, message.User, repaired[3].Role)
+	require.Equal(t, message.User, repaired[4].Role)
+	require.Equal(t, message.Tool, repaired[6].Role)
+	require.Equal(t, []message.ToolResult{
+		{ToolCallID: "3", Name: "ls", Content: toolCanceledContent, IsError: true},
+	}, repaired[6].ToolResults())
+	// The stored messages are left untouched.
+	require.Len(t, results.ToolResults(), 1)
+}
+
+func TestWithInterruption(t *testing.T) {
+	t.Parallel()
+
+	user := message.Message{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "use the other file"}}}
+	finished := message.Message{Role: message.Assistant, Parts: []message.ContentPart{
+		message.TextContent{Text: "Done."},
+		message.Finish{Reason: message.FinishReasonEndTurn},
+	}}
+	canceled := message.Message{Role: message.Assistant, Parts: []message.ContentPart{
+		message.TextContent{Text: "I'll edit"},
+		message.Finish{Reason: message.FinishReasonCanceled},
+	}}
+	results := message.Message{Role: message.Tool}
+
+	require.Equal(t, user, withInterruption(nil, user))
+	require.Equal(t, user, withInterruption([]message.Message{canceled, user, finished}, user))
+
+	interrupted := withInterruption([]message.Message{user, canceled, results}, user)
+	require.Contains(t, interrupted.Content().Text, "use the other file\n\n<interrupted>\nThe user canceled your previous response")
+	require.Equal(t, "use the other file", user.Content().Text)
+}
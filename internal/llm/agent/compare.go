@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/prompt"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// ComparisonsFile is the file of the data directory the comparisons are
+// appended to, one JSON object per line.
+const ComparisonsFile = "comparisons.jsonl"
+
+// CompareSide is a model answering the conversation of a comparison.
+type CompareSide struct {
+	// Label names the model, as provider/model.
+	Label    string
+	Provider provider.Provider
+}
+
+// NewCompareSide returns the side answering with the model of the provider,
+// with the system prompt of the coder agent and the settings of the large
+// model.
+func NewCompareSide(cfg *config.Config, providerID string, model catwalk.Model) (CompareSide, error) {
+	providerCfg, ok := cfg.Providers.Get(providerID)
+	if !ok {
+		return CompareSide{}, fmt.Errorf("provider %s not found", providerID)
+	}
+	p, err := provider.NewProvider(
+		providerCfg,
+		provider.WithModel(config.SelectedModelTypeLarge),
+		provider.WithCatalogModel(model),
+		provider.WithMaxTokens(model.DefaultMaxTokens),
+		provider.WithSystemMessage(prompt.GetPrompt(prompt.PromptCoder, providerID, cfg.Options.ContextPaths...)),
+		provider.WithSystemContext(prompt.SystemContext(prompt.PromptCoder)),
+	)
+	if err != nil {
+		return CompareSide{}, fmt.Errorf("failed to create the provider of %s/%s: %w", providerID, model.ID, err)
+	}
+	return CompareSide{Label: providerID + "/" + model.ID, Provider: p}, nil
+}
+
+// CompareResult is the answer of a model to a comparison, with its latency,
+// tokens and cost.
+type CompareResult struct {
+	Model  string `json:"model"`
+	Answer string `json:"answer"`
+	// Error tells why the model didn't answer.
+	Error string `json:"error,omitempty"`
+	// FirstTokenMs is the time until the first token of the answer or of
+	// its reasoning.
+	FirstTokenMs     int64   `json:"first_token_ms"`
+	DurationMs       int64   `json:"duration_ms"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// CompareEvent is a part of the answer streamed by a side, or the result of
+// the side once it's done.
+type CompareEvent struct {
+	// Side is the index of the side.
+	Side    int
+	Content string
+	Result  *CompareResult
+}
+
+// Compare sends the messages to the models of the sides at the same time,
+// without tools, and returns their results in the order of the sides.
+// onEvent is called from the goroutine of each side as they stream.
+func Compare(ctx context.Context, sides []CompareSide, messages []message.Message, onEvent func(CompareEvent)) []CompareResult {
+	results := make([]CompareResult, len(sides))
+	var wg sync.WaitGroup
+	for i, side := range sides {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = compareSide(ctx, i, side, messages, onEvent)
+			onEvent(CompareEvent{Side: i, Result: &results[i]})
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func compareSide(ctx context.Context, i int, side CompareSide, messages []message.Message, onEvent func(CompareEvent)) CompareResult {
+	result := CompareResult{Model: side.Label}
+	started := time.Now()
+	for event := range side.Provider.StreamResponse(ctx, messages, nil) {
+		switch event.Type {
+		case provider.EventContentDelta, provider.EventThinkingDelta:
+			if result.FirstTokenMs == 0 {
+				result.FirstTokenMs = time.Since(started).Milliseconds()
+			}
+			if event.Type == provider.EventContentDelta {
+				result.Answer += event.Content
+				onEvent(CompareEvent{Side: i, Content: event.Content})
+			}
+		case provider.EventError:
+			result.Error = event.Error.Error()
+		case provider.EventComplete:
+			usage := event.Response.Usage
+			result.Answer = cmp.Or(event.Response.Content, result.Answer)
+			result.PromptTokens = usage.InputTokens + usage.CacheCreationTokens + usage.CacheReadTokens
+			result.CompletionTokens = usage.OutputTokens
+			result.Cost = usageCost(side.Provider.Model(), usage)
+		}
+	}
+	if result.Error == "" && ctx.Err() != nil {
+		result.Error = ctx.Err().Error()
+	}
+	result.DurationMs = time.Since(started).Milliseconds()
+	return result
+}
+
+// CompareMessages returns the text of the prompts and answers of a
+// conversation, the tool calls and their results being left out as the
+// models of a comparison answer without tools. The consecutive texts of a
+// role are joined in a single message.
+func CompareMessages(msgs []message.Message) []message.Message {
+	var text []message.Message
+	for _, msg := range msgs {
+		if msg.Role != message.User && msg.Role != message.Assistant {
+			continue
+		}
+		content := msg.Content().Text
+		if content == "" {
+			continue
+		}
+		if n := len(text); n > 0 && text[n-1].Role == msg.Role {
+			last := text[n-1].Content().Text
+			text[n-1].Parts = []message.ContentPart{message.TextContent{Text: last + "\n\n" + content}}
+			continue
+		}
+		text = append(text, message.Message{
+			Role:  msg.Role,
+			Parts: []message.ContentPart{message.TextContent{Text: content}},
+		})
+	}
+	return text
+}
+
+// Comparison is a conversation answered by several models, as recorded in
+// the comparisons file.
+type Comparison struct {
+	Time      int64           `json:"time"`
+	SessionID string          `json:"session_id,omitempty"`
+	Prompt    string          `json:"prompt"`
+	Results   []CompareResult `json:"results"`
+}
+
+// RecordComparison appends the comparison to the comparisons file of the
+// data directory.
+func RecordComparison(dataDir string, c Comparison) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dataDir, ComparisonsFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
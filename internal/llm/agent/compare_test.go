@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// streamProvider streams the events to every request.
+type streamProvider struct {
+	model  catwalk.Model
+	events []provider.ProviderEvent
+}
+
+func (p streamProvider) SendMessages(context.Context, []message.Message, []tools.BaseTool) (*provider.ProviderResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p streamProvider) StreamResponse(context.Context, []message.Message, []tools.BaseTool) <-chan provider.ProviderEvent {
+	ch := make(chan provider.ProviderEvent, len(p.events))
+	for _, event := range p.events {
+		ch <- event
+	}
+	close(ch)
+	return ch
+}
+
+func (p streamProvider) Model() catwalk.Model { return p.model }
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	sides := []CompareSide{
+		{Label: "anthropic/claude-sonnet-4", Provider: streamProvider{
+			model: catwalk.Model{CostPer1MIn: 3, CostPer1MOut: 15},
+			events: []provider.ProviderEvent{
+				{Type: provider.EventThinkingDelta, Thinking: "Hmm"},
+				{Type: provider.EventContentDelta, Content: "Hello"},
+				{Type: provider.EventContentDelta, Content: " world"},
+				{Type: provider.EventComplete, Response: &provider.ProviderResponse{
+					Content: "Hello world",
+					Usage:   provider.TokenUsage{InputTokens: 1_000_000, OutputTokens: 100_000},
+				}},
+			},
+		}},
+		{Label: "openai/gpt-4.1", Provider: streamProvider{
+			events: []provider.ProviderEvent{
+				{Type: provider.EventError, Error: errors.New("overloaded")},
+			},
+		}},
+	}
+
+	var mu sync.Mutex
+	var deltas []string
+	var done []int
+	results := Compare(t.Context(), sides, nil, func(event CompareEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if event.Result != nil {
+			done = append(done, event.Side)
+			return
+		}
+		require.Equal(t, 0, event.Side)
+		deltas = append(deltas, event.Content)
+	})
+	require.Equal(t, []string{"Hello", " world"}, deltas)
+	require.ElementsMatch(t, []int{0, 1}, done)
+
+	require.Len(t, results, 2)
+	require.Equal(t, "anthropic/claude-sonnet-4", results[0].Model)
+	require.Equal(t, "Hello world", results[0].Answer)
+	require.Empty(t, results[0].Error)
+	require.Equal(t, int64(1_000_000), results[0].PromptTokens)
+	require.Equal(t, int64(100_000), results[0].CompletionTokens)
+	require.InDelta(t, 4.5, results[0].Cost, 1e-9)
+	require.Equal(t, "openai/gpt-4.1", results[1].Model)
+	require.Equal(t, "overloaded", results[1].Error)
+}
+
+func TestCompareMessages(t *testing.T) {
+	t.Parallel()
+
+	msgs := CompareMessages([]message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Fix the tests"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.TextContent{Text: "Let me run them."},
+			message.ToolCall{ID: "call_1", Name: "bash", Input: `{"command":"go test"}`, Finished: true},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{ToolCallID: "call_1", Content: "ok"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "They pass."}}},
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Add one"}}},
+	})
+	require.Len(t, msgs, 3)
+	require.Equal(t, message.User, msgs[0].Role)
+	require.Equal(t, message.Assistant, msgs[1].Role)
+	require.Equal(t, "Let me run them.\n\nThey pass.", msgs[1].Content().Text)
+	require.Empty(t, msgs[1].ToolCalls())
+	require.Equal(t, "Add one", msgs[2].Content().Text)
+}
+
+func TestRecordComparison(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c := Comparison{Time: 1, Prompt: "Hi", Results: []CompareResult{{Model: "openai/gpt-4.1", Answer: "Hello"}}}
+	require.NoError(t, RecordComparison(dir, c))
+	require.NoError(t, RecordComparison(dir, c))
+
+	data, err := os.ReadFile(filepath.Join(dir, ComparisonsFile))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var got Comparison
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &got))
+	require.Equal(t, c, got)
+}
@@ -0,0 +1,262 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// defaultCompressionMinSize is the size in bytes from which the tool
+	// outputs are compressed when the configuration doesn't set it.
+	defaultCompressionMinSize = 4096
+	// errorContextLines are the lines kept around each error of a log.
+	errorContextLines = 2
+	// logHeadLines and logTailLines are the first and last lines of a log
+	// always kept, the tail usually summing it up.
+	logHeadLines = 5
+	logTailLines = 20
+)
+
+// defaultCompressedTools are the tools whose outputs are compressed when the
+// configuration doesn't list them.
+var defaultCompressedTools = []string{tools.BashToolName, tools.RunTestsToolName}
+
+const compressSystemPrompt = `You compress the outputs of the commands a coding agent runs, like build logs, test runs and stack traces, so the agent reads less.
+Keep every error and warning with its file path, line number and message verbatim, the names of the failing tests, the first frames of the stack traces and the final summary lines.
+Drop progress, the passing steps and repeated lines. Reply with the compressed output only, without commentary.`
+
+var (
+	// errorLinePattern matches the lines of the logs worth keeping.
+	errorLinePattern = regexp.MustCompile(`(?i)\b(error|errors|warning|fail|failed|failure|fatal|panic|exception|traceback|undefined|cannot|denied|not found)\b|^\S+:\d+(:\d+)?:`)
+	// goTestNoisePattern matches the lines of go test outputs about the
+	// passing tests.
+	goTestNoisePattern = regexp.MustCompile(`^\s*(=== (RUN|PAUSE|CONT|NAME)\s|--- (PASS|SKIP): |PASS$|ok\s+\S+\s|\?\s+\S+\s+\[no test files\])`)
+	goroutinePattern   = regexp.MustCompile(`^goroutine \d+ \[`)
+)
+
+// compressResult replaces the verbose text output of the tool with what
+// matters in it, keeping the full text in the artifact store for the
+// expand_result tool.
+func (a *agent) compressResult(ctx context.Context, sessionID, toolName string, response tools.ToolResponse) tools.ToolResponse {
+	opts := config.Get().Options.Compression
+	if opts == nil || !opts.Enabled || a.artifacts == nil || response.Type != tools.ToolResponseTypeText {
+		return response
+	}
+	compressedTools := opts.Tools
+	if len(compressedTools) == 0 {
+		compressedTools = defaultCompressedTools
+	}
+	minSize := opts.MinSize
+	if minSize <= 0 {
+		minSize = defaultCompressionMinSize
+	}
+	if !slices.Contains(compressedTools, toolName) || len(response.Content) < minSize {
+		return response
+	}
+
+	full := response.Content
+	compressed := compressOutput(full)
+	if len(compressed) >= minSize && opts.SmallModel {
+		if summary, err := a.summarizeOutput(ctx, sessionID, full); err != nil {
+			slog.Warn("Failed to compress the tool output with the small model", "tool", toolName, "error", err)
+		} else if summary != "" {
+			compressed = summary
+		}
+	}
+	if len(compressed) >= len(full)*9/10 {
+		// Not worth the round trip to expand it.
+		return response
+	}
+
+	stored, err := a.artifacts.Create(sessionID, artifact.CreateParams{
+		Name:     toolName + "-result.txt",
+		MimeType: "text/plain",
+		ToolName: toolName,
+		Data:     []byte(full),
+	})
+	if err != nil {
+		slog.Error("Failed to store the full tool output", "tool", toolName, "error", err)
+		return response
+	}
+	lines := strings.Count(strings.TrimRight(full, "\n"), "\n") + 1
+	response.Content = fmt.Sprintf("<compressed_result id=%q lines=%d bytes=%d>\n%s\n</compressed_result>\n"+
+		"The output was compressed, call %s with the id to read the lines left out or search them.",
+		stored.ID, lines, len(full), compressed, tools.ExpandResultToolName)
+	return response
+}
+
+// compressOutput shrinks the output with the rules for the common verbose
+// outputs: progress and colors are stripped, repeated lines collapsed, the
+// passing tests and the other goroutines of Go dumps dropped, and only the
+// errors kept of the logs which remain too long.
+func compressOutput(output string) string {
+	lines := normalizeLines(output)
+	lines = collapseRepeats(lines)
+	lines = dropGoTestNoise(lines)
+	lines = dropOtherGoroutines(lines)
+	lines = extractErrors(lines)
+	return strings.Join(lines, "\n")
+}
+
+// normalizeLines strips the colors, and keeps what follows the last carriage
+// return of each line, like progress bars do.
+func normalizeLines(output string) []string {
+	output = ansi.Strip(strings.ReplaceAll(output, "\r\n", "\n"))
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	for i, line := range lines {
+		if j := strings.LastIndex(line, "\r"); j >= 0 {
+			lines[i] = line[j+1:]
+		}
+	}
+	return lines
+}
+
+func collapseRepeats(lines []string) []string {
+	var out []string
+	for i := 0; i < len(lines); {
+		j := i + 1
+		for j < len(lines) && lines[j] == lines[i] {
+			j++
+		}
+		out = append(out, lines[i])
+		if repeats := j - i - 1; repeats > 0 {
+			out = append(out, fmt.Sprintf("... (repeated %d more times)", repeats))
+		}
+		i = j
+	}
+	return out
+}
+
+// dropGoTestNoise drops the lines of go test outputs about the tests which
+// passed or were skipped, counting them.
+func dropGoTestNoise(lines []string) []string {
+	var out []string
+	var passed int
+	for _, line := range lines {
+		if !goTestNoisePattern.MatchString(line) {
+			out = append(out, line)
+			continue
+		}
+		if strings.Contains(line, "--- PASS: ") {
+			passed++
+		}
+	}
+	if len(out) == len(lines) {
+		return lines
+	}
+	if passed > 0 {
+		out = append(out, fmt.Sprintf("(%d passing tests left out)", passed))
+	}
+	return out
+}
+
+// dropOtherGoroutines keeps the first goroutine of Go stack dumps, the one
+// which panicked or failed, and drops the others.
+func dropOtherGoroutines(lines []string) []string {
+	var out []string
+	var seen, dropped int
+	inDropped := false
+	for _, line := range lines {
+		if goroutinePattern.MatchString(line) {
+			seen++
+			inDropped = seen > 1
+			if inDropped {
+				dropped++
+			}
+		} else if inDropped && strings.TrimSpace(line) == "" {
+			continue
+		} else if inDropped && !strings.HasPrefix(line, "\t") && !strings.Contains(line, "(") {
+			// The dump is over.
+			inDropped = false
+		}
+		if !inDropped {
+			out = append(out, line)
+		}
+	}
+	if dropped > 0 {
+		out = append(out, fmt.Sprintf("(%d more goroutines left out)", dropped))
+	}
+	return out
+}
+
+// extractErrors keeps the first and last lines of a long log, and the lines
+// reporting errors with some context, marking the lines left out.
+func extractErrors(lines []string) []string {
+	const maxLines = logHeadLines + logTailLines + 40
+	if len(lines) <= maxLines {
+		return lines
+	}
+	keep := make([]bool, len(lines))
+	for i := range lines {
+		if i < logHeadLines || i >= len(lines)-logTailLines {
+			keep[i] = true
+			continue
+		}
+		if errorLinePattern.MatchString(lines[i]) {
+			for j := max(i-errorContextLines, 0); j <= min(i+errorContextLines, len(lines)-1); j++ {
+				keep[j] = true
+			}
+		}
+	}
+	var out []string
+	omitted := 0
+	for i, line := range lines {
+		if !keep[i] {
+			omitted++
+			continue
+		}
+		if omitted > 0 {
+			out = append(out, fmt.Sprintf("... (%d lines left out)", omitted))
+			omitted = 0
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// summarizeOutput has the small model compress the output.
+func (a *agent) summarizeOutput(ctx context.Context, sessionID, output string) (string, error) {
+	cfg := config.Get()
+	providerCfg := cfg.GetProviderForModel(config.SelectedModelTypeSmall)
+	model := cfg.GetModelByType(config.SelectedModelTypeSmall)
+	if providerCfg == nil || model == nil {
+		return "", fmt.Errorf("no small model configured")
+	}
+	p, err := provider.NewProvider(
+		*providerCfg,
+		provider.WithModel(config.SelectedModelTypeSmall),
+		provider.WithSystemMessage(compressSystemPrompt),
+	)
+	if err != nil {
+		return "", err
+	}
+	msgs := []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: output}},
+	}}
+	a.auditRequest(sessionID, "compression", providerCfg.ID, model.ID, len(msgs), 0)
+	var response *provider.ProviderResponse
+	for event := range p.StreamResponse(ctx, a.redactMessages(sessionID, msgs), nil) {
+		if event.Error != nil {
+			return "", event.Error
+		}
+		if event.Type == provider.EventComplete {
+			response = event.Response
+		}
+	}
+	if response == nil {
+		return "", fmt.Errorf("no response received")
+	}
+	return strings.TrimSpace(response.Content), nil
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressOutput(t *testing.T) {
+	t.Parallel()
+
+	t.Run("progress and repeats", func(t *testing.T) {
+		t.Parallel()
+		output := "\x1b[32mDownloading\x1b[0m 10%\r50%\r100%\nwarn\nwarn\nwarn\ndone\n"
+		require.Equal(t, "100%\nwarn\n... (repeated 2 more times)\ndone", compressOutput(output))
+	})
+
+	t.Run("go test", func(t *testing.T) {
+		t.Parallel()
+		output := strings.Join([]string{
+			"=== RUN   TestA",
+			"--- PASS: TestA (0.00s)",
+			"=== RUN   TestB",
+			"    b_test.go:12: got 1, want 2",
+			"--- FAIL: TestB (0.00s)",
+			"FAIL",
+			"FAIL\texample.com/b\t0.01s",
+			"ok  \texample.com/a\t0.01s",
+			"?   \texample.com/c\t[no test files]",
+		}, "\n")
+		require.Equal(t, strings.Join([]string{
+			"    b_test.go:12: got 1, want 2",
+			"--- FAIL: TestB (0.00s)",
+			"FAIL",
+			"FAIL\texample.com/b\t0.01s",
+			"(1 passing tests left out)",
+		}, "\n"), compressOutput(output))
+	})
+
+	t.Run("goroutines", func(t *testing.T) {
+		t.Parallel()
+		output := strings.Join([]string{
+			"panic: boom",
+			"",
+			"goroutine 1 [running]:",
+			"main.main()",
+			"\t/src/main.go:5 +0x1d",
+			"",
+			"goroutine 7 [chan receive]:",
+			"main.worker()",
+			"\t/src/main.go:9 +0x2a",
+			"",
+			"exit status 2",
+		}, "\n")
+		require.Equal(t, strings.Join([]string{
+			"panic: boom",
+			"",
+			"goroutine 1 [running]:",
+			"main.main()",
+			"\t/src/main.go:5 +0x1d",
+			"",
+			"exit status 2",
+			"(1 more goroutines left out)",
+		}, "\n"), compressOutput(output))
+	})
+
+	t.Run("long log", func(t *testing.T) {
+		t.Parallel()
+		var lines []string
+		for i := range 200 {
+			lines = append(lines, fmt.Sprintf("compiling unit %d", i))
+		}
+		lines[100] = "src/app.c:42:7: error: unknown type name 'foo'"
+		compressed := strings.Split(compressOutput(strings.Join(lines, "\n")), "\n")
+		require.Equal(t, []string{
+			"compiling unit 0",
+			"compiling unit 1",
+			"compiling unit 2",
+			"compiling unit 3",
+			"compiling unit 4",
+			"... (93 lines left out)",
+			"compiling unit 98",
+			"compiling unit 99",
+			"src/app.c:42:7: error: unknown type name 'foo'",
+			"compiling unit 101",
+			"compiling unit 102",
+			"... (77 lines left out)",
+		}, compressed[:12])
+		require.Len(t, compressed, 12+logTailLines)
+		require.Equal(t, "compiling unit 199", compressed[len(compressed)-1])
+	})
+}
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"log/slog"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/dataset"
+	"github.com/charmbracelet/crush/internal/llm/prompt"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/redact"
+)
+
+// newDataset returns the writer of the dataset of the finished turns, or nil
+// when it is disabled. The secrets are masked in the records even when the
+// redaction of the requests is disabled.
+func newDataset(cfg *config.Config) *dataset.Writer {
+	opts := cfg.Options.Dataset
+	if opts == nil || !opts.Enabled {
+		return nil
+	}
+	var redactOpts redact.Options
+	if r := cfg.Options.Redaction; r != nil {
+		redactOpts = redact.Options{Allowlist: r.Allowlist, Entropy: r.Entropy}
+	}
+	w, err := dataset.New(cfg.Options.DataDirectory, opts.Path, dataset.Options{
+		Format:   opts.Format,
+		Redactor: redact.New(redactOpts),
+		Patterns: opts.Redact,
+	})
+	if err != nil {
+		slog.Warn("Disabling the dataset", "error", err)
+		return nil
+	}
+	return w
+}
+
+// recordDataset appends the finished turn, the messages ending with its
+// final answer, to the dataset.
+func (a *agent) recordDataset(tp turnProvider, opts RunOptions, msgs []message.Message) {
+	if a.dataset == nil {
+		return
+	}
+	promptID := agentPromptMap[a.agentCfg.ID]
+	if promptID == "" {
+		promptID = prompt.PromptDefault
+	}
+	var infos []tools.ToolInfo
+	for _, tool := range a.runTools(opts) {
+		infos = append(infos, tool.Info())
+	}
+	if err := a.dataset.Record(dataset.Turn{
+		System:   prompt.GetPrompt(promptID, tp.id, config.Get().Options.ContextPaths...),
+		Messages: msgs,
+		Tools:    infos,
+	}); err != nil {
+		slog.Error("Failed to record the turn in the dataset", "error", err)
+	}
+}
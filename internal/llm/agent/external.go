@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/filewatch"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// withExternalChanges tells the model about the files it read or edited
+// which were changed since by the user or another program, with their diff,
+// so it doesn't work on stale content. The stored message is left untouched.
+func (a *agent) withExternalChanges(sessionID string, msg message.Message) message.Message {
+	changes := filewatch.Changes(sessionID)
+	if len(changes) == 0 {
+		return msg
+	}
+	var sb strings.Builder
+	sb.WriteString("<external_changes>\nThese files were changed outside of your tools since you last read or edited them, " +
+		"by the user or another program. Work from their current content and keep the changes:\n")
+	for _, c := range changes {
+		if c.Removed {
+			fmt.Fprintf(&sb, "- %s was deleted\n", c.Path)
+			continue
+		}
+		fmt.Fprintf(&sb, "- %s:\n```diff\n%s```\n", c.Path, c.Diff)
+	}
+	sb.WriteString("</external_changes>")
+	return withNote(msg, sb.String())
+}
+
+// withNote appends the note to the first text part of the message, without
+// changing the parts of the stored message.
+func withNote(msg message.Message, note string) message.Message {
+	parts := slices.Clone(msg.Parts)
+	for i, part := range parts {
+		if c, ok := part.(message.TextContent); ok {
+			parts[i] = message.TextContent{Text: c.Text + "\n\n" + note}
+			msg.Parts = parts
+			return msg
+		}
+	}
+	return msg
+}
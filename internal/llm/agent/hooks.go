@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/hooks"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+const (
+	// maxHookContinuations is how many times in a row the turn_complete
+	// hooks can keep the agent working on a prompt.
+	maxHookContinuations = 3
+	// maxHookDiffSize bounds the diff of the changes the post_edit hooks
+	// made to a file.
+	maxHookDiffSize = 4000
+)
+
+// runTool runs the tool call after the pre_tool_call hooks, which can block
+// it or change its input, then runs the formatters, linters and post_edit
+// hooks of the file it edited. What they did is added to the response.
+func (a *agent) runTool(ctx context.Context, sessionID string, tool tools.BaseTool, call tools.ToolCall) (tools.ToolResponse, error) {
+	cfg := config.Get()
+	var feedback []string
+	if hooks.Has(cfg.Hooks, config.HookPreToolCall) {
+		result := hooks.Run(ctx, cfg.Hooks, hooks.Input{
+			Event:      config.HookPreToolCall,
+			SessionID:  sessionID,
+			WorkingDir: cfg.WorkingDir(),
+			ToolName:   call.Name,
+			ToolInput:  toolInput(call.Input),
+		})
+		if result.Blocked {
+			return tools.NewTextErrorResponse(result.Reason), nil
+		}
+		if result.ToolInput != nil {
+			call.Input = string(result.ToolInput)
+		}
+		feedback = result.Messages
+	}
+
+	response, err := runToolWithLimits(ctx, tool, call)
+	if err != nil {
+		return response, err
+	}
+
+	path, edited := editedFile(call.Name, call.Input, cfg.WorkingDir())
+	postEdit := cfg.Options.PostEdit != nil && cfg.Options.PostEdit.Enabled
+	if edited && !response.IsError && (postEdit || hooks.Has(cfg.Hooks, config.HookPostEdit)) {
+		before, _ := os.ReadFile(path)
+		failures := hooks.PostEdit(ctx, cfg.Options.PostEdit, cfg.WorkingDir(), path)
+		result := hooks.Run(ctx, cfg.Hooks, hooks.Input{
+			Event:      config.HookPostEdit,
+			SessionID:  sessionID,
+			WorkingDir: cfg.WorkingDir(),
+			ToolName:   call.Name,
+			ToolInput:  toolInput(call.Input),
+			FilePath:   path,
+		})
+		if after, err := os.ReadFile(path); err == nil && !bytes.Equal(before, after) {
+			unified, _, _ := diff.GenerateDiff(string(before), string(after), path)
+			if len(unified) > maxHookDiffSize {
+				unified = unified[:maxHookDiffSize] + "\n... (diff truncated, view the file for the rest)"
+			}
+			feedback = append(feedback, "The formatters and post_edit hooks changed the file after your edit:\n"+unified)
+			tools.RecordFileChange(sessionID, path)
+		}
+		if len(failures) > 0 {
+			response.IsError = true
+			feedback = append(feedback, strings.Join(failures, "\n\n")+"\nYour edit was applied but the checks failed, fix the file now.")
+		}
+		feedback = append(feedback, result.Messages...)
+		if result.Blocked {
+			response.IsError = true
+			feedback = append(feedback, result.Reason+"\nYour edit was applied, fix the file.")
+		}
+	}
+
+	if len(feedback) > 0 {
+		response.Content += "\n\n<hook_feedback>\n" + strings.Join(feedback, "\n\n") + "\n</hook_feedback>"
+	}
+	return response, nil
+}
+
+// toolInput returns the input of a tool call for the hooks, nil when it
+// isn't valid JSON.
+func toolInput(input string) json.RawMessage {
+	if !json.Valid([]byte(input)) {
+		return nil
+	}
+	return json.RawMessage(input)
+}
+
+// turnCompletePrompt runs the turn_complete hooks when the agent ends its
+// turn. It returns the message keeping the agent working when a hook blocked
+// the end of the turn, until the continuations are exhausted.
+func (a *agent) turnCompletePrompt(ctx context.Context, sessionID string, msg message.Message, editedFiles []string, continuations *int) (string, bool) {
+	cfg := config.Get()
+	if !hooks.Has(cfg.Hooks, config.HookTurnComplete) {
+		return "", false
+	}
+	result := hooks.Run(ctx, cfg.Hooks, hooks.Input{
+		Event:       config.HookTurnComplete,
+		SessionID:   sessionID,
+		WorkingDir:  cfg.WorkingDir(),
+		Response:    msg.Content().String(),
+		EditedFiles: editedFiles,
+	})
+	if !result.Blocked || *continuations >= maxHookContinuations {
+		return "", false
+	}
+	*continuations++
+	return fmt.Sprintf("<hook_feedback>\n%s\n</hook_feedback>\n\nThe turn_complete hooks didn't let you end your turn (attempt %d of %d). Address their feedback, or explain why it should be left as it is.",
+		result.Reason, *continuations, maxHookContinuations), true
+}
+
+// EndSessions runs the session_end hooks of the sessions which ran prompts.
+func (a *agent) EndSessions(ctx context.Context) {
+	cfg := config.Get()
+	if !hooks.Has(cfg.Hooks, config.HookSessionEnd) {
+		return
+	}
+	for sessionID := range a.promptedSessions.Seq2() {
+		hooks.Run(ctx, cfg.Hooks, hooks.Input{
+			Event:      config.HookSessionEnd,
+			SessionID:  sessionID,
+			WorkingDir: cfg.WorkingDir(),
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/ledger"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// changedFiles holds the files successfully changed while answering a
+// prompt, in the order they were first changed.
+type changedFiles []string
+
+// track records the files changed by the tool calls of a message whose
+// results aren't errors.
+func (c *changedFiles) track(msg message.Message, results *message.Message) {
+	if results == nil {
+		return
+	}
+	failed := make(map[string]bool)
+	for _, r := range results.ToolResults() {
+		failed[r.ToolCallID] = r.IsError
+	}
+	for _, call := range msg.ToolCalls() {
+		if !slices.Contains(fileEditTools, call.Name) || failed[call.ID] {
+			continue
+		}
+		var params struct {
+			FilePath string `json:"file_path"`
+		}
+		if err := json.Unmarshal([]byte(call.Input), &params); err != nil || params.FilePath == "" {
+			continue
+		}
+		if !slices.Contains(*c, params.FilePath) {
+			*c = append(*c, params.FilePath)
+		}
+	}
+}
+
+// recordLedger adds the files changed while answering the prompt to the
+// ledger of the repository, with the prompt as the reason of the change.
+func (a *agent) recordLedger(ctx context.Context, sessionID, prompt string, files changedFiles) {
+	if a.ledger == nil || len(files) == 0 {
+		return
+	}
+	var title string
+	if sess, err := a.sessions.Get(ctx, sessionID); err == nil {
+		title = sess.Title
+	}
+	now := time.Now().Unix()
+	entries := make([]ledger.Entry, len(files))
+	for i, path := range files {
+		entries[i] = ledger.Entry{
+			Time:         now,
+			SessionID:    sessionID,
+			SessionTitle: title,
+			Path:         path,
+			Summary:      prompt,
+			Model:        a.Model().ID,
+		}
+	}
+	if err := a.ledger.Append(entries...); err != nil {
+		slog.Error("Failed to record changed files in the ledger", "error", err)
+	}
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+var errToolTimeout = errors.New("tool call timed out")
+
+// toolTimeoutGrace is how long a timed out tool has to return what it did so
+// far before its call is reported as timed out without it.
+const toolTimeoutGrace = 5 * time.Second
+
+// runToolWithLimits runs the tool call within the limits configured for the
+// tool and its category. Exceeded limits are reported as tool errors, so a
+// hanging tool doesn't block the turn.
+func runToolWithLimits(ctx context.Context, tool tools.BaseTool, call tools.ToolCall) (tools.ToolResponse, error) {
+	category := tools.Category(call.Name)
+	// MCP tools may be renamed with a prefix that doesn't start with mcp_.
+	if _, ok := tool.(*McpTool); ok {
+		category = tools.CategoryMCP
+	}
+	limits := config.Get().Options.ToolLimits.For(call.Name, category)
+	if limits.MaxMemoryMB > 0 || limits.MaxCPUSeconds > 0 {
+		ctx = shell.WithLimits(ctx, shell.Limits{
+			MemoryBytes: uint64(limits.MaxMemoryMB) << 20,
+			CPUSeconds:  uint64(limits.MaxCPUSeconds),
+		})
+	}
+
+	var response tools.ToolResponse
+	var err error
+	if limits.Timeout > 0 {
+		response, err = runWithTimeout(ctx, tool, call, time.Duration(limits.Timeout)*time.Second)
+		if errors.Is(err, errToolTimeout) {
+			return tools.NewLimitErrorResponse(tools.LimitViolation{
+				Tool:  call.Name,
+				Limit: tools.LimitTimeout,
+				Value: limits.Timeout,
+			}, response.Content), nil
+		}
+	} else {
+		response, err = tool.Run(ctx, call)
+	}
+	if err != nil {
+		return response, err
+	}
+
+	if limits.MaxOutputBytes > 0 && len(response.Content) > limits.MaxOutputBytes {
+		return tools.NewLimitErrorResponse(tools.LimitViolation{
+			Tool:  call.Name,
+			Limit: tools.LimitMaxOutputBytes,
+			Value: limits.MaxOutputBytes,
+		}, truncateToValidUTF8(response.Content, limits.MaxOutputBytes)), nil
+	}
+	return response, nil
+}
+
+// runWithTimeout runs the tool call and returns errToolTimeout, with the
+// response of the tool if it still returned one, when it takes too long.
+func runWithTimeout(ctx context.Context, tool tools.BaseTool, call tools.ToolCall, timeout time.Duration) (tools.ToolResponse, error) {
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, errToolTimeout)
+	defer cancel()
+
+	type result struct {
+		response tools.ToolResponse
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := tool.Run(ctx, call)
+		done <- result{response: response, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		if context.Cause(ctx) == errToolTimeout {
+			return r.response, errToolTimeout
+		}
+		return r.response, r.err
+	case <-ctx.Done():
+		if context.Cause(ctx) != errToolTimeout {
+			return tools.ToolResponse{}, ctx.Err()
+		}
+	}
+
+	select {
+	case r := <-done:
+		return r.response, errToolTimeout
+	case <-time.After(toolTimeoutGrace):
+		return tools.ToolResponse{}, errToolTimeout
+	}
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/stretchr/testify/require"
+)
+
+type slowTool struct {
+	delay time.Duration
+}
+
+func (slowTool) Info() tools.ToolInfo { return tools.ToolInfo{Name: "slow"} }
+
+func (slowTool) Name() string { return "slow" }
+
+func (s slowTool) Run(ctx context.Context, _ tools.ToolCall) (tools.ToolResponse, error) {
+	select {
+	case <-time.After(s.delay):
+		return tools.NewTextResponse("done"), nil
+	case <-ctx.Done():
+		return tools.NewTextResponse("partial"), ctx.Err()
+	}
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("finishes in time", func(t *testing.T) {
+		t.Parallel()
+		resp, err := runWithTimeout(t.Context(), slowTool{}, tools.ToolCall{}, time.Second)
+		require.NoError(t, err)
+		require.Equal(t, "done", resp.Content)
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		t.Parallel()
+		resp, err := runWithTimeout(t.Context(), slowTool{delay: time.Minute}, tools.ToolCall{}, 10*time.Millisecond)
+		require.ErrorIs(t, err, errToolTimeout)
+		require.Equal(t, "partial", resp.Content)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		_, err := runWithTimeout(ctx, slowTool{delay: time.Minute}, tools.ToolCall{}, time.Minute)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,300 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+const (
+	defaultMCPHealthCheckInterval = 30 * time.Second
+
+	// Servers failing their health check are restarted with an exponential
+	// backoff, and left in error after mcpMaxRestarts failed attempts.
+	mcpRestartMinBackoff = time.Second
+	mcpRestartMaxBackoff = time.Minute
+	mcpMaxRestarts       = 10
+)
+
+var (
+	// mcpCtx is the context the servers are started with, it ends when crush
+	// exits.
+	mcpCtx = context.Background()
+	// mcpMonitors cancels the health checks and the restarts of each running
+	// server.
+	mcpMonitors = csync.NewMap[string, context.CancelFunc]()
+	// mcpLifecycleMu serializes starting and stopping servers, between the
+	// config reloads and the restarts asked by the user.
+	mcpLifecycleMu sync.Mutex
+)
+
+// startMCPClient connects to a server in the background, then checks its
+// health until it is stopped.
+func startMCPClient(ctx context.Context, name string, m config.MCPConfig) {
+	if m.Disabled {
+		updateMCPState(name, MCPStateDisabled, nil, nil, 0)
+		slog.Debug("skipping disabled mcp", "name", name)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	mcpMonitors.Set(name, cancel)
+
+	// Set initial starting state
+	updateMCPState(name, MCPStateStarting, nil, nil, 0)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				var err error
+				switch v := r.(type) {
+				case error:
+					err = v
+				case string:
+					err = fmt.Errorf("panic: %s", v)
+				default:
+					err = fmt.Errorf("panic: %v", v)
+				}
+				updateMCPState(name, MCPStateError, err, nil, 0)
+				slog.Error("panic in mcp client initialization", "error", err, "name", name)
+			}
+		}()
+
+		if err := connectMCPServer(ctx, name, m); err != nil {
+			return
+		}
+		monitorMCPClient(ctx, name, m)
+	}()
+}
+
+// connectMCPServer connects to a server and registers its tools and prompts.
+func connectMCPServer(ctx context.Context, name string, m config.MCPConfig) error {
+	initCtx, cancel := context.WithTimeout(ctx, mcpTimeout(m))
+	defer cancel()
+	c, err := createAndInitializeClient(initCtx, name, m)
+	if err != nil {
+		return err
+	}
+	// The server may have been stopped while it was connecting.
+	if err := ctx.Err(); err != nil {
+		_ = c.Close()
+		return err
+	}
+	mcpClients.Set(name, c)
+
+	// The connection may have waited for an authorization that outlasted its
+	// timeout.
+	listCtx, cancelList := context.WithTimeout(ctx, mcpTimeout(m))
+	defer cancelList()
+	tools := getTools(listCtx, name, m, mcpPermissions, c, config.Get().WorkingDir())
+	mcpTools.Set(name, tools)
+	loadMCPPrompts(listCtx, name, c)
+	updateMCPState(name, MCPStateConnected, nil, c, len(tools))
+	return nil
+}
+
+// monitorMCPClient pings a server at its health check interval, and restarts
+// it when it doesn't answer.
+func monitorMCPClient(ctx context.Context, name string, m config.MCPConfig) {
+	interval := mcpHealthCheckInterval(m)
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := checkMCPHealth(ctx, name, m)
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+		slog.Warn("mcp server is unhealthy, restarting it", "error", err, "name", name)
+		if !restartMCPClient(ctx, name, m, err) {
+			return
+		}
+	}
+}
+
+// checkMCPHealth pings a server and records how long it took to answer.
+func checkMCPHealth(ctx context.Context, name string, m config.MCPConfig) error {
+	c, ok := mcpClients.Get(name)
+	if !ok {
+		return fmt.Errorf("mcp '%s' not available", name)
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, mcpTimeout(m))
+	defer cancel()
+	start := time.Now()
+	if err := c.Ping(pingCtx); err != nil {
+		if !errors.Is(err, transport.ErrSessionTerminated) {
+			return err
+		}
+		// The server is up but forgot the session, e.g. it restarted or
+		// expired it, a new one is started right away.
+		_, err = reconnectMCPClient(ctx, name, m, c)
+		return err
+	}
+	latency := time.Since(start)
+
+	info, ok := mcpStates.Get(name)
+	if !ok || info.State != MCPStateConnected {
+		return nil
+	}
+	info.Latency = latency
+	info.CheckedAt = time.Now()
+	mcpStates.Set(name, info)
+	mcpBroker.Publish(pubsub.UpdatedEvent, MCPEvent{
+		Type:      MCPEventHealthChecked,
+		Name:      name,
+		State:     info.State,
+		ToolCount: info.ToolCount,
+	})
+	return nil
+}
+
+// restartMCPClient closes a server that failed, then connects to it again
+// with an exponential backoff. It returns false when the server is stopped or
+// couldn't be restarted.
+func restartMCPClient(ctx context.Context, name string, m config.MCPConfig, cause error) bool {
+	closeMCPClient(name)
+	updateMCPState(name, MCPStateError, cause, nil, 0)
+
+	backoff := mcpRestartMinBackoff
+	for range mcpMaxRestarts {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		if err := connectMCPServer(ctx, name, m); err == nil {
+			slog.Info("restarted mcp server", "name", name)
+			return true
+		}
+		backoff = min(backoff*2, mcpRestartMaxBackoff)
+	}
+	slog.Error("giving up restarting mcp server", "name", name, "attempts", mcpMaxRestarts)
+	return false
+}
+
+// reconnectMCPClient replaces the stale client of a server with a new one,
+// in a new session for HTTP servers. When another caller already replaced it,
+// the current client is returned. The client lives as long as ctx.
+func reconnectMCPClient(ctx context.Context, name string, m config.MCPConfig, stale *client.Client) (*client.Client, error) {
+	mcpLifecycleMu.Lock()
+	defer mcpLifecycleMu.Unlock()
+	if c, ok := mcpClients.Get(name); ok && c != stale {
+		return c, nil
+	}
+	slog.Info("reconnecting to mcp server", "name", name)
+	closeMCPClient(name)
+	if err := connectMCPServer(ctx, name, m); err != nil {
+		return nil, err
+	}
+	c, ok := mcpClients.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("mcp '%s' not available", name)
+	}
+	return c, nil
+}
+
+// closeMCPClient closes the client of a server and forgets its tools and
+// prompts.
+func closeMCPClient(name string) {
+	if c, ok := mcpClients.Take(name); ok {
+		_ = c.Close()
+	}
+	mcpTools.Del(name)
+	mcpPrompts.Del(name)
+}
+
+// stopMCPClient stops the health checks of a server and closes it.
+func stopMCPClient(name string) {
+	if cancel, ok := mcpMonitors.Take(name); ok {
+		cancel()
+	}
+	closeMCPClient(name)
+}
+
+// RestartMCP closes a server, whatever its state, and connects to it again.
+func RestartMCP(name string) error {
+	m, ok := config.Get().MCP[name]
+	if !ok {
+		return fmt.Errorf("mcp '%s' not configured", name)
+	}
+	if m.Disabled {
+		return fmt.Errorf("mcp '%s' is disabled", name)
+	}
+	mcpLifecycleMu.Lock()
+	defer mcpLifecycleMu.Unlock()
+	stopMCPClient(name)
+	startMCPClient(mcpCtx, name, m)
+	return nil
+}
+
+// UpdateMCP applies the changes of the MCP servers when the configuration is
+// reloaded. Servers added are started, the ones removed or disabled are
+// stopped and the ones changed are restarted, the others keep running.
+func UpdateMCP(previous, next config.MCPs) {
+	mcpLifecycleMu.Lock()
+	defer mcpLifecycleMu.Unlock()
+	stopped, started := diffMCPs(previous, next)
+	for _, name := range stopped {
+		slog.Info("stopping mcp server", "name", name)
+		stopMCPClient(name)
+		if _, ok := next[name]; !ok {
+			mcpStates.Del(name)
+			mcpBroker.Publish(pubsub.DeletedEvent, MCPEvent{
+				Type:  MCPEventStateChanged,
+				Name:  name,
+				State: MCPStateDisabled,
+			})
+		}
+	}
+	for _, name := range started {
+		slog.Info("starting mcp server", "name", name)
+		startMCPClient(mcpCtx, name, next[name])
+	}
+}
+
+// diffMCPs returns the servers to stop and to start to go from the previous
+// configuration to the next one, ordered by name. Changed servers are in
+// both.
+func diffMCPs(previous, next config.MCPs) (stopped, started []string) {
+	for name, m := range previous {
+		if n, ok := next[name]; !ok || !reflect.DeepEqual(m, n) {
+			stopped = append(stopped, name)
+		}
+	}
+	for name, n := range next {
+		if m, ok := previous[name]; !ok || !reflect.DeepEqual(m, n) {
+			started = append(started, name)
+		}
+	}
+	slices.Sort(stopped)
+	slices.Sort(started)
+	return stopped, started
+}
+
+func mcpHealthCheckInterval(m config.MCPConfig) time.Duration {
+	switch {
+	case m.HealthCheckInterval < 0:
+		return 0
+	case m.HealthCheckInterval == 0:
+		return defaultMCPHealthCheckInterval
+	}
+	return time.Duration(m.HealthCheckInterval) * time.Second
+}
@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffMCPs(t *testing.T) {
+	t.Parallel()
+
+	previous := config.MCPs{
+		"kept":     {Type: config.MCPStdio, Command: "kept"},
+		"removed":  {Type: config.MCPStdio, Command: "removed"},
+		"changed":  {Type: config.MCPStdio, Command: "changed", Args: []string{"--old"}},
+		"disabled": {Type: config.MCPHttp, URL: "http://localhost:3000/mcp"},
+	}
+	next := config.MCPs{
+		"kept":     {Type: config.MCPStdio, Command: "kept"},
+		"changed":  {Type: config.MCPStdio, Command: "changed", Args: []string{"--new"}},
+		"disabled": {Type: config.MCPHttp, URL: "http://localhost:3000/mcp", Disabled: true},
+		"added":    {Type: config.MCPSse, URL: "http://localhost:3001/sse"},
+	}
+
+	stopped, started := diffMCPs(previous, next)
+	require.Equal(t, []string{"changed", "disabled", "removed"}, stopped)
+	require.Equal(t, []string{"added", "changed", "disabled"}, started)
+
+	stopped, started = diffMCPs(next, next)
+	require.Empty(t, stopped)
+	require.Empty(t, started)
+}
+
+func TestMCPHealthCheckInterval(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, defaultMCPHealthCheckInterval, mcpHealthCheckInterval(config.MCPConfig{}))
+	require.Equal(t, 5*time.Second, mcpHealthCheckInterval(config.MCPConfig{HealthCheckInterval: 5}))
+	require.Zero(t, mcpHealthCheckInterval(config.MCPConfig{HealthCheckInterval: -1}))
+}
@@ -0,0 +1,275 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+const (
+	defaultMCPOAuthCallbackPort = 19876
+	// mcpOAuthTimeout is how long the user has to authorize in the browser.
+	mcpOAuthTimeout = 5 * time.Minute
+)
+
+// mcpOAuthMu serializes the authorizations, they share the callback port.
+var mcpOAuthMu sync.Mutex
+
+// mcpOAuthConfig returns the OAuth configuration of the transport of an MCP
+// server, with the client registered in a previous run if the configuration
+// doesn't set one.
+func mcpOAuthConfig(name string, m config.MCPConfig) (transport.OAuthConfig, error) {
+	store := newMCPTokenStore(name, m.URL)
+	secret, err := m.OAuth.ResolvedClientSecret()
+	if err != nil {
+		return transport.OAuthConfig{}, fmt.Errorf("failed to resolve oauth client secret: %w", err)
+	}
+	clientID := m.OAuth.ClientID
+	if clientID == "" {
+		clientID, secret = store.Client()
+	}
+	return transport.OAuthConfig{
+		ClientID:     clientID,
+		ClientSecret: secret,
+		RedirectURI:  mcpOAuthRedirectURI(m),
+		Scopes:       m.OAuth.Scopes,
+		TokenStore:   store,
+		PKCEEnabled:  true,
+	}, nil
+}
+
+func mcpOAuthRedirectURI(m config.MCPConfig) string {
+	return fmt.Sprintf("http://localhost:%d/callback", cmp.Or(m.OAuth.CallbackPort, defaultMCPOAuthCallbackPort))
+}
+
+// authorizeMCP runs the authorization code flow with PKCE for an MCP server:
+// it registers the client if needed, opens the authorization page in the
+// browser and exchanges the code received on the local callback for a token,
+// which the handler saves in the token store.
+func authorizeMCP(ctx context.Context, name string, m config.MCPConfig, handler *transport.OAuthHandler) error {
+	mcpOAuthMu.Lock()
+	defer mcpOAuthMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, mcpOAuthTimeout)
+	defer cancel()
+
+	if handler.GetClientID() == "" {
+		if err := handler.RegisterClient(ctx, "Crush"); err != nil {
+			return fmt.Errorf("failed to register oauth client: %w", err)
+		}
+		if err := newMCPTokenStore(name, m.URL).SaveClient(handler.GetClientID(), handler.GetClientSecret()); err != nil {
+			return err
+		}
+	}
+
+	verifier, err := client.GenerateCodeVerifier()
+	if err != nil {
+		return fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	state, err := client.GenerateState()
+	if err != nil {
+		return fmt.Errorf("failed to generate state: %w", err)
+	}
+
+	port := cmp.Or(m.OAuth.CallbackPort, defaultMCPOAuthCallbackPort)
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen for the oauth callback: %w", err)
+	}
+	callbacks := make(chan mcpOAuthCallback, 1)
+	srv := &http.Server{
+		Handler:           mcpOAuthCallbackHandler(callbacks),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Close()
+
+	authURL, err := handler.GetAuthorizationURL(ctx, state, client.GenerateCodeChallenge(verifier))
+	if err != nil {
+		return fmt.Errorf("failed to get authorization url: %w", err)
+	}
+	updateMCPState(name, MCPStateAuthorizing, nil, nil, 0)
+	slog.Info("Authorize the MCP server in the browser", "name", name, "url", authURL)
+	if err := openBrowser(authURL); err != nil {
+		slog.Warn("Failed to open the browser, open the authorization url manually", "name", name, "error", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("oauth authorization not completed: %w", ctx.Err())
+	case cb := <-callbacks:
+		if cb.err != "" {
+			return fmt.Errorf("oauth authorization failed: %s", cb.err)
+		}
+		if err := handler.ProcessAuthorizationResponse(ctx, cb.code, cb.state, verifier); err != nil {
+			return fmt.Errorf("failed to exchange the authorization code: %w", err)
+		}
+	}
+	slog.Info("Authorized MCP server", "name", name)
+	return nil
+}
+
+type mcpOAuthCallback struct {
+	code  string
+	state string
+	err   string
+}
+
+// mcpOAuthCallbackHandler receives the redirect of the browser after the
+// user authorized, or refused, the client.
+func mcpOAuthCallbackHandler(callbacks chan<- mcpOAuthCallback) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		cb := mcpOAuthCallback{
+			code:  query.Get("code"),
+			state: query.Get("state"),
+			err:   cmp.Or(query.Get("error_description"), query.Get("error")),
+		}
+		if cb.err == "" && cb.code == "" {
+			cb.err = "no authorization code in the callback"
+		}
+
+		message := "Crush is authorized, you can close this window."
+		if cb.err != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			message = "Authorization failed: " + cb.err
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<!doctype html><title>Crush</title><p>%s</p>", html.EscapeString(message))
+
+		select {
+		case callbacks <- cb:
+		default:
+		}
+	})
+	return mux
+}
+
+func openBrowser(url string) error {
+	var c *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		c = exec.CommandContext(context.TODO(), "open", url)
+	case "windows":
+		c = exec.CommandContext(context.TODO(), "rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		c = exec.CommandContext(context.TODO(), "xdg-open", url)
+	}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	go func() { _ = c.Wait() }()
+	return nil
+}
+
+// mcpTokenStore keeps the token and the registered client of an MCP server
+// in a file only the user can read, in the global data directory. The file
+// is ignored when the URL of the server changes.
+type mcpTokenStore struct {
+	path string
+	url  string
+}
+
+type mcpOAuthFile struct {
+	URL          string           `json:"url"`
+	ClientID     string           `json:"client_id,omitempty"`
+	ClientSecret string           `json:"client_secret,omitempty"`
+	Token        *transport.Token `json:"token,omitempty"`
+}
+
+// mcpTokenStoreMu guards the token files, the transport may refresh a token
+// while another request reads it.
+var mcpTokenStoreMu sync.Mutex
+
+func newMCPTokenStore(name, url string) *mcpTokenStore {
+	dir := filepath.Join(filepath.Dir(config.GlobalConfigData()), "mcp-oauth")
+	return &mcpTokenStore{
+		path: filepath.Join(dir, name+".json"),
+		url:  url,
+	}
+}
+
+// GetToken implements [transport.TokenStore].
+func (s *mcpTokenStore) GetToken() (*transport.Token, error) {
+	mcpTokenStoreMu.Lock()
+	defer mcpTokenStoreMu.Unlock()
+	f := s.read()
+	if f.Token == nil {
+		return nil, errors.New("no token available")
+	}
+	return f.Token, nil
+}
+
+// SaveToken implements [transport.TokenStore].
+func (s *mcpTokenStore) SaveToken(token *transport.Token) error {
+	mcpTokenStoreMu.Lock()
+	defer mcpTokenStoreMu.Unlock()
+	f := s.read()
+	f.Token = token
+	return s.write(f)
+}
+
+// Client returns the client registered with the server, if any.
+func (s *mcpTokenStore) Client() (id, secret string) {
+	mcpTokenStoreMu.Lock()
+	defer mcpTokenStoreMu.Unlock()
+	f := s.read()
+	return f.ClientID, f.ClientSecret
+}
+
+// SaveClient saves the client registered with the server, a new client
+// invalidates the token of the previous one.
+func (s *mcpTokenStore) SaveClient(id, secret string) error {
+	mcpTokenStoreMu.Lock()
+	defer mcpTokenStoreMu.Unlock()
+	return s.write(mcpOAuthFile{ClientID: id, ClientSecret: secret})
+}
+
+func (s *mcpTokenStore) read() mcpOAuthFile {
+	var f mcpOAuthFile
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return f
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		slog.Warn("Ignoring invalid MCP OAuth token file", "path", s.path, "error", err)
+		return mcpOAuthFile{}
+	}
+	if f.URL != s.url {
+		return mcpOAuthFile{}
+	}
+	return f
+}
+
+func (s *mcpTokenStore) write(f mcpOAuthFile) error {
+	f.URL = s.url
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPTokenStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "mcp-oauth", "linear.json")
+	store := &mcpTokenStore{path: path, url: "https://mcp.linear.app/sse"}
+
+	_, err := store.GetToken()
+	require.Error(t, err)
+
+	require.NoError(t, store.SaveClient("client", "secret"))
+	require.NoError(t, store.SaveToken(&transport.Token{AccessToken: "access", RefreshToken: "refresh"}))
+
+	token, err := store.GetToken()
+	require.NoError(t, err)
+	require.Equal(t, "access", token.AccessToken)
+	id, secret := store.Client()
+	require.Equal(t, "client", id)
+	require.Equal(t, "secret", secret)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// A new client invalidates the token of the previous one.
+	require.NoError(t, store.SaveClient("other", ""))
+	_, err = store.GetToken()
+	require.Error(t, err)
+
+	moved := &mcpTokenStore{path: path, url: "https://mcp.linear.app/mcp"}
+	id, _ = moved.Client()
+	require.Empty(t, id)
+}
+
+func TestMCPOAuthCallbackHandler(t *testing.T) {
+	t.Parallel()
+
+	callbacks := make(chan mcpOAuthCallback, 1)
+	handler := mcpOAuthCallbackHandler(callbacks)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback?code=abc&state=xyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, mcpOAuthCallback{code: "abc", state: "xyz"}, <-callbacks)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback?error=access_denied&state=xyz", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "access_denied", (<-callbacks).err)
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MCPPromptPrefix prefixes the command IDs of MCP prompts.
+const MCPPromptPrefix = "mcp:"
+
+// MCPPrompt is a prompt template published by an MCP server.
+type MCPPrompt struct {
+	Server      string
+	Name        string
+	Description string
+	Arguments   []MCPPromptArgument
+}
+
+type MCPPromptArgument struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// ID returns the command ID of the prompt, e.g. mcp:github:review_pr.
+func (p MCPPrompt) ID() string {
+	return MCPPromptPrefix + p.Server + ":" + p.Name
+}
+
+// mcpPrompts holds the prompts of each connected server, they are listed
+// when the server connects and again when it reports that they changed.
+var mcpPrompts = csync.NewMap[string, []MCPPrompt]()
+
+// MCPPrompts returns the prompts of every connected MCP server, ordered by
+// server and name.
+func MCPPrompts() []MCPPrompt {
+	byServer := maps.Collect(mcpPrompts.Seq2())
+	var result []MCPPrompt
+	for _, name := range slices.Sorted(maps.Keys(byServer)) {
+		result = append(result, byServer[name]...)
+	}
+	return result
+}
+
+// loadMCPPrompts lists the prompts of a server that has some.
+func loadMCPPrompts(ctx context.Context, name string, c *client.Client) {
+	if c.GetServerCapabilities().Prompts == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, mcpTimeout(config.Get().MCP[name]))
+	defer cancel()
+
+	result, err := c.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		slog.Error("error listing mcp prompts", "error", err, "name", name)
+		return
+	}
+	prompts := make([]MCPPrompt, 0, len(result.Prompts))
+	for _, p := range result.Prompts {
+		prompt := MCPPrompt{
+			Server:      name,
+			Name:        p.Name,
+			Description: p.Description,
+		}
+		for _, arg := range p.Arguments {
+			prompt.Arguments = append(prompt.Arguments, MCPPromptArgument{
+				Name:        arg.Name,
+				Description: arg.Description,
+				Required:    arg.Required,
+			})
+		}
+		prompts = append(prompts, prompt)
+	}
+	slices.SortFunc(prompts, func(a, b MCPPrompt) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	mcpPrompts.Set(name, prompts)
+}
+
+// GetMCPPrompt renders a prompt of an MCP server with the arguments, as the
+// text to send to the model.
+func GetMCPPrompt(ctx context.Context, server, name string, args map[string]string) (string, error) {
+	c, err := getOrRenewClient(ctx, server)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, mcpTimeout(config.Get().MCP[server]))
+	defer cancel()
+
+	result, err := c.GetPrompt(ctx, mcp.GetPromptRequest{
+		Params: mcp.GetPromptParams{
+			Name:      name,
+			Arguments: args,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting prompt %s: %w", name, err)
+	}
+	text := promptText(result.Messages)
+	if text == "" {
+		return "", fmt.Errorf("prompt %s is empty", name)
+	}
+	return text, nil
+}
+
+// promptText joins the text of the prompt messages. Embedded text resources
+// are included, other contents like images are left out.
+func promptText(messages []mcp.PromptMessage) string {
+	var parts []string
+	for _, msg := range messages {
+		switch content := msg.Content.(type) {
+		case mcp.TextContent:
+			parts = append(parts, content.Text)
+		case mcp.EmbeddedResource:
+			if resource, ok := content.Resource.(mcp.TextResourceContents); ok {
+				parts = append(parts, message.BinaryContent{
+					Path:     resource.URI,
+					MIMEType: cmp.Or(resource.MIMEType, "text/plain"),
+					Data:     []byte(resource.Text),
+				}.Text())
+			}
+		default:
+			slog.Debug("skipping unsupported mcp prompt content", "type", fmt.Sprintf("%T", content))
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n\n"))
+}
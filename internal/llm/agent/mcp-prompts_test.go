@@ -0,0 +1,21 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptText(t *testing.T) {
+	t.Parallel()
+
+	text := promptText([]mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("Review this diff:")),
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewEmbeddedResource(mcp.TextResourceContents{URI: "git://diff", Text: "+added"})),
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewImageContent("aGk=", "image/png")),
+	})
+	require.Equal(t, "Review this diff:\n\n<resource uri=\"git://diff\" mime_type=\"text/plain\">\n+added\n</resource>", text)
+
+	require.Equal(t, "mcp:github:review_pr", MCPPrompt{Server: "github", Name: "review_pr"}.ID())
+}
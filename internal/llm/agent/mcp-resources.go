@@ -0,0 +1,276 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/yosida95/uritemplate/v3"
+)
+
+// MCPResource is a resource, or a resource template, exposed by an MCP
+// server.
+type MCPResource struct {
+	Server      string
+	URI         string
+	Name        string
+	Description string
+	MimeType    string
+	// Template is set for resource templates, their URI is an RFC 6570 URI
+	// template to fill in before reading it.
+	Template bool
+}
+
+// Variables returns the variables of a resource template.
+func (r MCPResource) Variables() ([]string, error) {
+	t, err := uritemplate.New(r.URI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource template %s: %w", r.URI, err)
+	}
+	return t.Varnames(), nil
+}
+
+// Expand fills in the variables of a resource template and returns the URI of
+// the resource.
+func (r MCPResource) Expand(values map[string]string) (string, error) {
+	t, err := uritemplate.New(r.URI)
+	if err != nil {
+		return "", fmt.Errorf("invalid resource template %s: %w", r.URI, err)
+	}
+	vars := uritemplate.Values{}
+	for name, value := range values {
+		vars.Set(name, uritemplate.String(value))
+	}
+	return t.Expand(vars)
+}
+
+type mcpSubscription struct {
+	server string
+	uri    string
+}
+
+// mcpSubscriptions holds the resources subscribed to, to subscribe to them
+// again when their server reconnects.
+var mcpSubscriptions = csync.NewMap[mcpSubscription, struct{}]()
+
+// ListMCPResources returns the resources and resource templates of every
+// connected MCP server that has resources, ordered by server and name.
+func ListMCPResources(ctx context.Context) ([]MCPResource, error) {
+	var result []MCPResource
+	var errs []error
+	for name, c := range mcpClients.Seq2() {
+		if c.GetServerCapabilities().Resources == nil {
+			continue
+		}
+		resources, err := listMCPResources(ctx, name, c)
+		if err != nil {
+			slog.Error("error listing mcp resources", "error", err, "name", name)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		result = append(result, resources...)
+	}
+	slices.SortFunc(result, func(a, b MCPResource) int {
+		return cmp.Or(
+			cmp.Compare(a.Server, b.Server),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.URI, b.URI),
+		)
+	})
+	if len(result) == 0 && len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return result, nil
+}
+
+func listMCPResources(ctx context.Context, name string, c *client.Client) ([]MCPResource, error) {
+	m := config.Get().MCP[name]
+	ctx, cancel := context.WithTimeout(ctx, mcpTimeout(m))
+	defer cancel()
+
+	resources, err := c.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]MCPResource, 0, len(resources.Resources))
+	for _, r := range resources.Resources {
+		result = append(result, MCPResource{
+			Server:      name,
+			URI:         r.URI,
+			Name:        cmp.Or(r.Name, r.URI),
+			Description: r.Description,
+			MimeType:    r.MIMEType,
+		})
+	}
+
+	// Templates are optional, servers without any may not implement the
+	// method at all.
+	templates, err := c.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+	if err != nil {
+		slog.Debug("error listing mcp resource templates", "error", err, "name", name)
+		return result, nil
+	}
+	for _, t := range templates.ResourceTemplates {
+		if t.URITemplate == nil || t.URITemplate.Template == nil {
+			continue
+		}
+		result = append(result, MCPResource{
+			Server:      name,
+			URI:         t.URITemplate.Raw(),
+			Name:        cmp.Or(t.Name, t.URITemplate.Raw()),
+			Description: t.Description,
+			MimeType:    t.MIMEType,
+			Template:    true,
+		})
+	}
+	return result, nil
+}
+
+// ReadMCPResource reads a resource of an MCP server as an attachment. The
+// path of the attachment is the URI of the resource.
+func ReadMCPResource(ctx context.Context, server, uri string) (message.Attachment, error) {
+	c, err := getOrRenewClient(ctx, server)
+	if err != nil {
+		return message.Attachment{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, mcpTimeout(config.Get().MCP[server]))
+	defer cancel()
+
+	result, err := c.ReadResource(ctx, mcp.ReadResourceRequest{
+		Params: mcp.ReadResourceParams{URI: uri},
+	})
+	if err != nil {
+		return message.Attachment{}, fmt.Errorf("error reading resource %s: %w", uri, err)
+	}
+	return resourceAttachment(uri, result.Contents)
+}
+
+// resourceAttachment merges the contents of a resource into an attachment.
+// Resources with several text contents, like directories, are joined.
+func resourceAttachment(uri string, contents []mcp.ResourceContents) (message.Attachment, error) {
+	if len(contents) == 0 {
+		return message.Attachment{}, fmt.Errorf("resource %s is empty", uri)
+	}
+
+	attachment := message.Attachment{
+		FilePath: uri,
+		FileName: resourceName(uri),
+	}
+	var texts []string
+	for _, content := range contents {
+		switch content := content.(type) {
+		case mcp.TextResourceContents:
+			attachment.MimeType = cmp.Or(attachment.MimeType, content.MIMEType)
+			texts = append(texts, content.Text)
+		case mcp.BlobResourceContents:
+			if len(contents) > 1 {
+				return message.Attachment{}, fmt.Errorf("resource %s has several binary contents", uri)
+			}
+			data, err := base64.StdEncoding.DecodeString(content.Blob)
+			if err != nil {
+				return message.Attachment{}, fmt.Errorf("error decoding resource %s: %w", uri, err)
+			}
+			attachment.MimeType = cmp.Or(content.MIMEType, "application/octet-stream")
+			attachment.Content = data
+			return attachment, nil
+		}
+	}
+	attachment.MimeType = cmp.Or(attachment.MimeType, "text/plain")
+	if !message.IsTextMIMEType(attachment.MimeType) {
+		attachment.MimeType = "text/plain"
+	}
+	attachment.Content = []byte(strings.Join(texts, "\n"))
+	return attachment, nil
+}
+
+func resourceName(uri string) string {
+	name := strings.TrimRight(uri, "/")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 && i < len(name)-1 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// SubscribeMCPResource subscribes to the updates of a resource, for servers
+// that support it. An MCPEventResourceUpdated event is published on each
+// update.
+func SubscribeMCPResource(ctx context.Context, server, uri string) error {
+	c, err := getOrRenewClient(ctx, server)
+	if err != nil {
+		return err
+	}
+	key := mcpSubscription{server: server, uri: uri}
+	if _, ok := mcpSubscriptions.Get(key); ok {
+		return nil
+	}
+	if err := subscribeMCPResource(ctx, server, c, uri); err != nil {
+		return err
+	}
+	mcpSubscriptions.Set(key, struct{}{})
+	return nil
+}
+
+func subscribeMCPResource(ctx context.Context, server string, c *client.Client, uri string) error {
+	if caps := c.GetServerCapabilities().Resources; caps == nil || !caps.Subscribe {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, mcpTimeout(config.Get().MCP[server]))
+	defer cancel()
+	if err := c.Subscribe(ctx, mcp.SubscribeRequest{
+		Params: mcp.SubscribeParams{URI: uri},
+	}); err != nil {
+		return fmt.Errorf("error subscribing to resource %s: %w", uri, err)
+	}
+	return nil
+}
+
+// resubscribeMCPResources subscribes a reconnected client to the resources
+// subscribed to before.
+func resubscribeMCPResources(ctx context.Context, server string, c *client.Client) {
+	for key := range mcpSubscriptions.Seq2() {
+		if key.server != server {
+			continue
+		}
+		if err := subscribeMCPResource(ctx, server, c, key.uri); err != nil {
+			slog.Error("error resubscribing to mcp resource", "error", err, "name", server, "uri", key.uri)
+		}
+	}
+}
+
+// handleMCPNotification publishes the resource notifications of a server
+// and reloads its prompts when they change.
+func handleMCPNotification(server string) func(mcp.JSONRPCNotification) {
+	return func(n mcp.JSONRPCNotification) {
+		switch n.Method {
+		case mcp.MethodNotificationResourceUpdated:
+			uri, _ := n.Params.AdditionalFields["uri"].(string)
+			if uri == "" {
+				return
+			}
+			mcpBroker.Publish(pubsub.UpdatedEvent, MCPEvent{
+				Type: MCPEventResourceUpdated,
+				Name: server,
+				URI:  uri,
+			})
+		case mcp.MethodNotificationPromptsListChanged:
+			if c, ok := mcpClients.Get(server); ok {
+				go loadMCPPrompts(context.Background(), server, c)
+			}
+		case mcp.MethodNotificationResourcesListChanged:
+			mcpBroker.Publish(pubsub.UpdatedEvent, MCPEvent{
+				Type: MCPEventResourcesChanged,
+				Name: server,
+			})
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceAttachment(t *testing.T) {
+	t.Parallel()
+
+	attachment, err := resourceAttachment("file:///repo/docs/", []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: "file:///repo/docs/a.md", MIMEType: "text/markdown", Text: "# A"},
+		mcp.TextResourceContents{URI: "file:///repo/docs/b.md", MIMEType: "text/markdown", Text: "# B"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "file:///repo/docs/", attachment.FilePath)
+	require.Equal(t, "docs", attachment.FileName)
+	require.Equal(t, "text/markdown", attachment.MimeType)
+	require.Equal(t, "# A\n# B", string(attachment.Content))
+
+	attachment, err = resourceAttachment("db://schema", []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: "db://schema", MIMEType: "application/x-sql", Text: "CREATE TABLE t"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "schema", attachment.FileName)
+	require.Equal(t, "text/plain", attachment.MimeType)
+
+	attachment, err = resourceAttachment("img://logo", []mcp.ResourceContents{
+		mcp.BlobResourceContents{URI: "img://logo", MIMEType: "image/png", Blob: base64.StdEncoding.EncodeToString([]byte("png"))},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "image/png", attachment.MimeType)
+	require.Equal(t, "png", string(attachment.Content))
+
+	_, err = resourceAttachment("empty://", nil)
+	require.Error(t, err)
+}
+
+func TestMCPResourceExpand(t *testing.T) {
+	t.Parallel()
+
+	r := MCPResource{URI: "repo://{owner}/{repo}/issues{?state}", Template: true}
+	vars, err := r.Variables()
+	require.NoError(t, err)
+	require.Equal(t, []string{"owner", "repo", "state"}, vars)
+
+	uri, err := r.Expand(map[string]string{"owner": "charm bracelet", "repo": "crush"})
+	require.NoError(t, err)
+	require.Equal(t, "repo://charm%20bracelet/crush/issues", uri)
+}
+
+func TestHandleMCPNotification(t *testing.T) {
+	t.Parallel()
+
+	events := SubscribeMCPEvents(t.Context())
+
+	var n mcp.JSONRPCNotification
+	require.NoError(t, json.Unmarshal([]byte(`{"jsonrpc":"2.0","method":"notifications/resources/updated","params":{"uri":"doc://readme"}}`), &n))
+	handleMCPNotification("docs")(n)
+
+	event := <-events
+	require.Equal(t, MCPEventResourceUpdated, event.Payload.Type)
+	require.Equal(t, "docs", event.Payload.Name)
+	require.Equal(t, "doc://readme", event.Payload.URI)
+}
@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MCPSamplingToolName is the tool name of the permission requests made for
+// MCP sampling, their action is the name of the server. Allowing
+// "mcp_sampling:<server>" skips the prompt for that server.
+const MCPSamplingToolName = "mcp_sampling"
+
+// defaultSamplingSystemPrompt is used when the server doesn't send one.
+const defaultSamplingSystemPrompt = "You are a helpful assistant."
+
+// MCPSamplingPermissionsParams describes a sampling request to the user
+// before it is sent to the model.
+type MCPSamplingPermissionsParams struct {
+	Server       string               `json:"server"`
+	Model        string               `json:"model"`
+	SystemPrompt string               `json:"system_prompt,omitempty"`
+	Messages     []MCPSamplingMessage `json:"messages"`
+	MaxTokens    int                  `json:"max_tokens,omitempty"`
+	// ModelHints and the priorities are the model preferences of the
+	// server.
+	ModelHints           []string `json:"model_hints,omitempty"`
+	CostPriority         float64  `json:"cost_priority,omitempty"`
+	SpeedPriority        float64  `json:"speed_priority,omitempty"`
+	IntelligencePriority float64  `json:"intelligence_priority,omitempty"`
+}
+
+type MCPSamplingMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// mcpSampler completes the sampling requests of a server with the configured
+// models, once the user allows it. Temperature and stop sequences are not
+// supported by the providers and are ignored.
+type mcpSampler struct {
+	server      string
+	permissions permission.Service
+}
+
+func (s *mcpSampler) CreateMessage(ctx context.Context, request mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	cfg := config.Get()
+	modelType := samplingModelType(cfg, request.ModelPreferences)
+	providerCfg := cfg.GetProviderForModel(modelType)
+	model := cfg.GetModelByType(modelType)
+	if providerCfg == nil || model == nil {
+		return nil, fmt.Errorf("no %s model configured for sampling", modelType)
+	}
+
+	messages, params, err := samplingMessages(request.Messages)
+	if err != nil {
+		return nil, err
+	}
+	params.Server = s.server
+	params.Model = model.Name
+	params.SystemPrompt = request.SystemPrompt
+	params.MaxTokens = request.MaxTokens
+	if prefs := request.ModelPreferences; prefs != nil {
+		for _, hint := range prefs.Hints {
+			params.ModelHints = append(params.ModelHints, hint.Name)
+		}
+		params.CostPriority = prefs.CostPriority
+		params.SpeedPriority = prefs.SpeedPriority
+		params.IntelligencePriority = prefs.IntelligencePriority
+	}
+
+	if s.permissions != nil && !s.permissions.Request(permission.CreatePermissionRequest{
+		ToolName:    MCPSamplingToolName,
+		Action:      s.server,
+		Path:        cfg.WorkingDir(),
+		Description: fmt.Sprintf("The %s MCP server requests a completion from %s", s.server, model.Name),
+		Params:      params,
+	}) {
+		return nil, permission.ErrorPermissionDenied
+	}
+
+	opts := []provider.ProviderClientOption{
+		provider.WithModel(modelType),
+		provider.WithSystemMessage(cmp.Or(request.SystemPrompt, defaultSamplingSystemPrompt)),
+	}
+	if request.MaxTokens > 0 {
+		opts = append(opts, provider.WithMaxTokens(int64(request.MaxTokens)))
+	}
+	p, err := provider.NewProvider(*providerCfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	response, err := p.SendMessages(ctx, messages, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error sampling %s: %w", model.Name, err)
+	}
+
+	result := &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{
+			Role:    mcp.RoleAssistant,
+			Content: mcp.NewTextContent(response.Content),
+		},
+		Model: model.ID,
+	}
+	switch response.FinishReason {
+	case message.FinishReasonEndTurn:
+		result.StopReason = "endTurn"
+	case message.FinishReasonMaxTokens:
+		result.StopReason = "maxTokens"
+	}
+	return result, nil
+}
+
+// samplingModelType picks the configured model for a sampling request: the
+// first hint matching the large or the small model, else the small model
+// when the server cares more about cost or speed than intelligence.
+func samplingModelType(cfg *config.Config, prefs *mcp.ModelPreferences) config.SelectedModelType {
+	if prefs == nil {
+		return config.SelectedModelTypeLarge
+	}
+	for _, hint := range prefs.Hints {
+		name := strings.ToLower(hint.Name)
+		if name == "" {
+			continue
+		}
+		for _, modelType := range []config.SelectedModelType{config.SelectedModelTypeLarge, config.SelectedModelTypeSmall} {
+			if strings.Contains(strings.ToLower(cfg.Models[modelType].Model), name) {
+				return modelType
+			}
+		}
+	}
+	if max(prefs.CostPriority, prefs.SpeedPriority) > prefs.IntelligencePriority {
+		return config.SelectedModelTypeSmall
+	}
+	return config.SelectedModelTypeLarge
+}
+
+// samplingMessages converts the messages of a sampling request, along with
+// their description for the permission prompt.
+func samplingMessages(messages []mcp.SamplingMessage) ([]message.Message, MCPSamplingPermissionsParams, error) {
+	var params MCPSamplingPermissionsParams
+	result := make([]message.Message, 0, len(messages))
+	for _, msg := range messages {
+		role := message.User
+		if msg.Role == mcp.RoleAssistant {
+			role = message.Assistant
+		}
+
+		content := msg.Content
+		if m, ok := content.(map[string]any); ok {
+			parsed, err := mcp.ParseContent(m)
+			if err != nil {
+				return nil, params, fmt.Errorf("invalid sampling message: %w", err)
+			}
+			content = parsed
+		}
+
+		var part message.ContentPart
+		var description string
+		switch content := content.(type) {
+		case mcp.TextContent:
+			part = message.TextContent{Text: content.Text}
+			description = content.Text
+		case mcp.ImageContent:
+			if role != message.User {
+				return nil, params, fmt.Errorf("images are only supported in user messages")
+			}
+			data, err := base64.StdEncoding.DecodeString(content.Data)
+			if err != nil {
+				return nil, params, fmt.Errorf("error decoding sampling image: %w", err)
+			}
+			part = message.BinaryContent{MIMEType: content.MIMEType, Data: data}
+			description = fmt.Sprintf("[image %s]", content.MIMEType)
+		default:
+			return nil, params, fmt.Errorf("unsupported sampling message content %T", content)
+		}
+
+		result = append(result, message.Message{Role: role, Parts: []message.ContentPart{part}})
+		params.Messages = append(params.Messages, MCPSamplingMessage{
+			Role:    string(msg.Role),
+			Content: description,
+		})
+	}
+	if len(result) == 0 {
+		return nil, params, fmt.Errorf("sampling request has no messages")
+	}
+	return result, params, nil
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamplingModelType(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Models: map[config.SelectedModelType]config.SelectedModel{
+			config.SelectedModelTypeLarge: {Model: "claude-sonnet-4", Provider: "anthropic"},
+			config.SelectedModelTypeSmall: {Model: "claude-3-5-haiku", Provider: "anthropic"},
+		},
+	}
+	hints := func(names ...string) []mcp.ModelHint {
+		var result []mcp.ModelHint
+		for _, name := range names {
+			result = append(result, mcp.ModelHint{Name: name})
+		}
+		return result
+	}
+
+	require.Equal(t, config.SelectedModelTypeLarge, samplingModelType(cfg, nil))
+	require.Equal(t, config.SelectedModelTypeSmall, samplingModelType(cfg, &mcp.ModelPreferences{
+		Hints: hints("gpt-4o", "haiku"),
+	}))
+	require.Equal(t, config.SelectedModelTypeLarge, samplingModelType(cfg, &mcp.ModelPreferences{
+		Hints:        hints("Sonnet"),
+		CostPriority: 1,
+	}))
+	require.Equal(t, config.SelectedModelTypeSmall, samplingModelType(cfg, &mcp.ModelPreferences{
+		Hints:                hints("gpt-4o"),
+		SpeedPriority:        0.8,
+		IntelligencePriority: 0.2,
+	}))
+	require.Equal(t, config.SelectedModelTypeLarge, samplingModelType(cfg, &mcp.ModelPreferences{
+		IntelligencePriority: 0.9,
+	}))
+}
+
+func TestSamplingMessages(t *testing.T) {
+	t.Parallel()
+
+	messages, params, err := samplingMessages([]mcp.SamplingMessage{
+		{Role: mcp.RoleUser, Content: mcp.NewTextContent("Summarize this")},
+		{Role: mcp.RoleAssistant, Content: map[string]any{"type": "text", "text": "Sure"}},
+		{Role: mcp.RoleUser, Content: map[string]any{
+			"type":     "image",
+			"data":     base64.StdEncoding.EncodeToString([]byte("png")),
+			"mimeType": "image/png",
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	require.Equal(t, message.User, messages[0].Role)
+	require.Equal(t, "Summarize this", messages[0].Content().Text)
+	require.Equal(t, message.Assistant, messages[1].Role)
+	require.Equal(t, "png", string(messages[2].BinaryContent()[0].Data))
+	require.Equal(t, []MCPSamplingMessage{
+		{Role: "user", Content: "Summarize this"},
+		{Role: "assistant", Content: "Sure"},
+		{Role: "user", Content: "[image image/png]"},
+	}, params.Messages)
+
+	_, _, err = samplingMessages(nil)
+	require.Error(t, err)
+
+	_, _, err = samplingMessages([]mcp.SamplingMessage{
+		{Role: mcp.RoleUser, Content: map[string]any{"type": "audio", "data": "", "mimeType": "audio/wav"}},
+	})
+	require.Error(t, err)
+}
@@ -4,9 +4,11 @@ import (
 	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
+	"net/http"
 	"slices"
 	"strings"
 	"sync"
@@ -31,6 +33,7 @@ const (
 	MCPStateStarting
 	MCPStateConnected
 	MCPStateError
+	MCPStateAuthorizing
 )
 
 func (s MCPState) String() string {
@@ -43,6 +46,8 @@ func (s MCPState) String() string {
 		return "connected"
 	case MCPStateError:
 		return "error"
+	case MCPStateAuthorizing:
+		return "authorizing"
 	default:
 		return "unknown"
 	}
@@ -53,6 +58,15 @@ type MCPEventType string
 
 const (
 	MCPEventStateChanged MCPEventType = "state_changed"
+	// MCPEventHealthChecked is published when a server answers its health
+	// check, with its latency in its state.
+	MCPEventHealthChecked MCPEventType = "health_checked"
+	// MCPEventResourceUpdated is published when a subscribed resource
+	// changes.
+	MCPEventResourceUpdated MCPEventType = "resource_updated"
+	// MCPEventResourcesChanged is published when the list of resources of a
+	// server changes.
+	MCPEventResourcesChanged MCPEventType = "resources_changed"
 )
 
 // MCPEvent represents an event in the MCP system
@@ -62,6 +76,8 @@ type MCPEvent struct {
 	State     MCPState
 	Error     error
 	ToolCount int
+	// URI is the resource of resource events.
+	URI string
 }
 
 // MCPClientInfo holds information about an MCP client's state
@@ -72,25 +88,36 @@ type MCPClientInfo struct {
 	Client      *client.Client
 	ToolCount   int
 	ConnectedAt time.Time
+	// Latency is the time the server took to answer its last health check,
+	// at CheckedAt.
+	Latency   time.Duration
+	CheckedAt time.Time
 }
 
 var (
-	mcpToolsOnce sync.Once
-	mcpTools     []tools.BaseTool
-	mcpClients   = csync.NewMap[string, *client.Client]()
-	mcpStates    = csync.NewMap[string, MCPClientInfo]()
-	mcpBroker    = pubsub.NewBroker[MCPEvent]()
+	mcpInitOnce sync.Once
+	mcpTools    = csync.NewMap[string, []tools.BaseTool]()
+	mcpClients  = csync.NewMap[string, *client.Client]()
+	mcpStates   = csync.NewMap[string, MCPClientInfo]()
+	mcpBroker   = pubsub.NewBroker[MCPEvent]()
+
+	// mcpPermissions asks the user before completing the sampling requests
+	// of the servers.
+	mcpPermissions permission.Service
 )
 
 type McpTool struct {
-	mcpName     string
+	mcpName string
+	// name is the name of the tool given to the model, see
+	// config.MCPConfig.ToolName.
+	name        string
 	tool        mcp.Tool
 	permissions permission.Service
 	workingDir  string
 }
 
 func (b *McpTool) Name() string {
-	return fmt.Sprintf("mcp_%s_%s", b.mcpName, b.tool.Name)
+	return b.name
 }
 
 func (b *McpTool) Info() tools.ToolInfo {
@@ -103,7 +130,7 @@ func (b *McpTool) Info() tools.ToolInfo {
 		parameters = make(map[string]any)
 	}
 	return tools.ToolInfo{
-		Name:        fmt.Sprintf("mcp_%s_%s", b.mcpName, b.tool.Name),
+		Name:        b.name,
 		Description: b.tool.Description,
 		Parameters:  parameters,
 		Required:    required,
@@ -126,6 +153,18 @@ func runTool(ctx context.Context, name, toolName string, input string) (tools.To
 			Arguments: args,
 		},
 	})
+	if errors.Is(err, transport.ErrSessionTerminated) {
+		// The request didn't reach the server, it is sent again in a new
+		// session.
+		if c, err = reconnectMCPClient(context.WithoutCancel(ctx), name, config.Get().MCP[name], c); err == nil {
+			result, err = c.CallTool(ctx, mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name:      toolName,
+					Arguments: args,
+				},
+			})
+		}
+	}
 	if err != nil {
 		return tools.NewTextErrorResponse(err.Error()), nil
 	}
@@ -157,15 +196,8 @@ func getOrRenewClient(ctx context.Context, name string) (*client.Client, error)
 		return c, nil
 	}
 	updateMCPState(name, MCPStateError, err, nil, state.ToolCount)
-
-	c, err = createAndInitializeClient(ctx, name, m)
-	if err != nil {
-		return nil, err
-	}
-
-	updateMCPState(name, MCPStateConnected, nil, c, state.ToolCount)
-	mcpClients.Set(name, c)
-	return c, nil
+	// The client outlives the request it is renewed for.
+	return reconnectMCPClient(context.WithoutCancel(ctx), name, m, c)
 }
 
 func (b *McpTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolResponse, error) {
@@ -192,7 +224,8 @@ func (b *McpTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolRes
 	return runTool(ctx, b.mcpName, b.tool.Name, params.Input)
 }
 
-func getTools(ctx context.Context, name string, permissions permission.Service, c *client.Client, workingDir string) []tools.BaseTool {
+// getTools returns the tools of the server that pass its filters.
+func getTools(ctx context.Context, name string, m config.MCPConfig, permissions permission.Service, c *client.Client, workingDir string) []tools.BaseTool {
 	result, err := c.ListTools(ctx, mcp.ListToolsRequest{})
 	if err != nil {
 		slog.Error("error listing tools", "error", err)
@@ -203,8 +236,13 @@ func getTools(ctx context.Context, name string, permissions permission.Service,
 	}
 	mcpTools := make([]tools.BaseTool, 0, len(result.Tools))
 	for _, tool := range result.Tools {
+		if !m.ToolEnabled(tool.Name) {
+			slog.Debug("skipping filtered mcp tool", "name", name, "tool", tool.Name)
+			continue
+		}
 		mcpTools = append(mcpTools, &McpTool{
 			mcpName:     name,
+			name:        m.ToolName(name, tool.Name),
 			tool:        tool,
 			permissions: permissions,
 			workingDir:  workingDir,
@@ -254,6 +292,9 @@ func updateMCPState(name string, state MCPState, err error, client *client.Clien
 
 // CloseMCPClients closes all MCP clients. This should be called during application shutdown.
 func CloseMCPClients() {
+	for cancel := range mcpMonitors.Seq() {
+		cancel()
+	}
 	for c := range mcpClients.Seq() {
 		_ = c.Close()
 	}
@@ -270,119 +311,152 @@ var mcpInitRequest = mcp.InitializeRequest{
 	},
 }
 
-func doGetMCPTools(ctx context.Context, permissions permission.Service, cfg *config.Config) []tools.BaseTool {
-	var wg sync.WaitGroup
-	result := csync.NewSlice[tools.BaseTool]()
-
-	// Initialize states for all configured MCPs
-	for name, m := range cfg.MCP {
-		if m.Disabled {
-			updateMCPState(name, MCPStateDisabled, nil, nil, 0)
-			slog.Debug("skipping disabled mcp", "name", name)
-			continue
+// initMCPClients starts all configured MCP servers in parallel without
+// blocking. The tools of each server are registered as soon as it finishes
+// connecting, so a slow server does not delay the others. Servers are then
+// restarted when they crash, and when the configuration is reloaded.
+func initMCPClients(ctx context.Context, permissions permission.Service, cfg *config.Config) {
+	mcpInitOnce.Do(func() {
+		mcpPermissions = permissions
+		mcpCtx = ctx
+		for name, m := range cfg.MCP {
+			startMCPClient(ctx, name, m)
 		}
+	})
+}
 
-		// Set initial starting state
-		updateMCPState(name, MCPStateStarting, nil, nil, 0)
-
-		wg.Add(1)
-		go func(name string, m config.MCPConfig) {
-			defer func() {
-				wg.Done()
-				if r := recover(); r != nil {
-					var err error
-					switch v := r.(type) {
-					case error:
-						err = v
-					case string:
-						err = fmt.Errorf("panic: %s", v)
-					default:
-						err = fmt.Errorf("panic: %v", v)
-					}
-					updateMCPState(name, MCPStateError, err, nil, 0)
-					slog.Error("panic in mcp client initialization", "error", err, "name", name)
-				}
-			}()
-
-			ctx, cancel := context.WithTimeout(ctx, mcpTimeout(m))
-			defer cancel()
-			c, err := createAndInitializeClient(ctx, name, m)
-			if err != nil {
-				return
-			}
-			mcpClients.Set(name, c)
-
-			tools := getTools(ctx, name, permissions, c, cfg.WorkingDir())
-			updateMCPState(name, MCPStateConnected, nil, c, len(tools))
-			result.Append(tools...)
-		}(name, m)
+// connectedMCPTools returns the tools of every MCP server that has connected
+// so far, ordered by server name to keep the tool list stable across turns.
+func connectedMCPTools() []tools.BaseTool {
+	byServer := maps.Collect(mcpTools.Seq2())
+	var result []tools.BaseTool
+	for _, name := range slices.Sorted(maps.Keys(byServer)) {
+		result = append(result, byServer[name]...)
 	}
-	wg.Wait()
-	return slices.Collect(result.Seq())
+	return result
 }
 
 func createAndInitializeClient(ctx context.Context, name string, m config.MCPConfig) (*client.Client, error) {
-	c, err := createMcpClient(m)
+	c, err := connectMcpClient(ctx, name, m)
+	if m.OAuth != nil && client.IsOAuthAuthorizationRequiredError(err) {
+		// The user may take longer to authorize than the timeout of the
+		// connection, both get their own.
+		ctx = context.WithoutCancel(ctx)
+		if err = authorizeMCP(ctx, name, m, client.GetOAuthHandler(err)); err == nil {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, mcpTimeout(m))
+			defer cancel()
+			c, err = connectMcpClient(ctx, name, m)
+		}
+	}
 	if err != nil {
 		updateMCPState(name, MCPStateError, err, nil, 0)
-		slog.Error("error creating mcp client", "error", err, "name", name)
+		slog.Error("error initializing mcp client", "error", err, "name", name)
 		return nil, err
 	}
-	// Only call Start() for non-stdio clients, as stdio clients auto-start
-	if m.Type != config.MCPStdio {
-		if err := c.Start(ctx); err != nil {
-			updateMCPState(name, MCPStateError, err, nil, 0)
-			slog.Error("error starting mcp client", "error", err, "name", name)
-			_ = c.Close()
-			return nil, err
-		}
+
+	slog.Info("Initialized mcp client", "name", name)
+	return c, nil
+}
+
+func connectMcpClient(ctx context.Context, name string, m config.MCPConfig) (*client.Client, error) {
+	c, err := createMcpClient(name, m)
+	if err != nil {
+		return nil, fmt.Errorf("error creating mcp client: %w", err)
+	}
+	// The process of stdio servers lives as long as the context it starts
+	// with.
+	startCtx := ctx
+	if m.Type == config.MCPStdio {
+		startCtx = context.WithoutCancel(ctx)
+	}
+	if err := c.Start(startCtx); err != nil {
+		_ = c.Close()
+		return nil, err
 	}
 	if _, err := c.Initialize(ctx, mcpInitRequest); err != nil {
-		updateMCPState(name, MCPStateError, err, nil, 0)
-		slog.Error("error initializing mcp client", "error", err, "name", name)
 		_ = c.Close()
 		return nil, err
 	}
-
-	slog.Info("Initialized mcp client", "name", name)
+	c.OnNotification(handleMCPNotification(name))
+	resubscribeMCPResources(ctx, name, c)
 	return c, nil
 }
 
-func createMcpClient(m config.MCPConfig) (*client.Client, error) {
+func createMcpClient(name string, m config.MCPConfig) (*client.Client, error) {
 	switch m.Type {
 	case config.MCPStdio:
 		if strings.TrimSpace(m.Command) == "" {
 			return nil, fmt.Errorf("mcp stdio config requires a non-empty 'command' field")
 		}
-		return client.NewStdioMCPClientWithOptions(
-			m.Command,
-			m.ResolvedEnv(),
-			m.Args,
-			transport.WithCommandLogger(mcpLogger{}),
-		)
+		return client.NewClient(
+			transport.NewStdioWithOptions(
+				m.Command,
+				m.ResolvedEnv(),
+				m.Args,
+				transport.WithCommandLogger(mcpLogger{}),
+			),
+			mcpSamplingOption(name),
+		), nil
 	case config.MCPHttp:
 		if strings.TrimSpace(m.URL) == "" {
 			return nil, fmt.Errorf("mcp http config requires a non-empty 'url' field")
 		}
-		return client.NewStreamableHttpClient(
-			m.URL,
+		opts := []transport.StreamableHTTPCOption{
 			transport.WithHTTPHeaders(m.ResolvedHeaders()),
 			transport.WithHTTPLogger(mcpLogger{}),
-		)
+			// Keeps a stream open for the notifications and the requests of
+			// the server between the requests of the client.
+			transport.WithContinuousListening(),
+		}
+		if policy := config.Get().EgressPolicy(); policy != nil {
+			opts = append(opts, transport.WithHTTPBasicClient(&http.Client{Transport: policy.Transport(nil)}))
+		}
+		if m.OAuth != nil {
+			oauth, err := mcpOAuthConfig(name, m)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, transport.WithHTTPOAuth(oauth))
+		}
+		trans, err := transport.NewStreamableHTTP(m.URL, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return client.NewClient(trans, mcpSamplingOption(name)), nil
 	case config.MCPSse:
 		if strings.TrimSpace(m.URL) == "" {
 			return nil, fmt.Errorf("mcp sse config requires a non-empty 'url' field")
 		}
-		return client.NewSSEMCPClient(
-			m.URL,
+		opts := []transport.ClientOption{
 			client.WithHeaders(m.ResolvedHeaders()),
 			transport.WithSSELogger(mcpLogger{}),
-		)
+		}
+		if policy := config.Get().EgressPolicy(); policy != nil {
+			opts = append(opts, transport.WithHTTPClient(&http.Client{Transport: policy.Transport(nil)}))
+		}
+		if m.OAuth != nil {
+			oauth, err := mcpOAuthConfig(name, m)
+			if err != nil {
+				return nil, err
+			}
+			return client.NewOAuthSSEClient(m.URL, oauth, opts...)
+		}
+		return client.NewSSEMCPClient(m.URL, opts...)
 	default:
 		return nil, fmt.Errorf("unsupported mcp type: %s", m.Type)
 	}
 }
 
+// mcpSamplingOption lets the server request completions. SSE servers can't
+// send requests to the client, so they don't get it.
+func mcpSamplingOption(name string) client.ClientOption {
+	return client.WithSamplingHandler(&mcpSampler{
+		server:      name,
+		permissions: mcpPermissions,
+	})
+}
+
 // for MCP's clients.
 type mcpLogger struct{}
 
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pin"
+)
+
+// maxPinnedFileSize is how much of each pinned file is sent.
+const maxPinnedFileSize = 100 * 1024
+
+// withPins adds the files and messages pinned by the user to the prompt.
+// The files are sent as they are now with every prompt, the messages only
+// once the history no longer has them, like after a summary.
+func (a *agent) withPins(ctx context.Context, sessionID string, history []message.Message, msg message.Message) message.Message {
+	if a.pins == nil {
+		return msg
+	}
+	pins, err := a.pins.List(sessionID)
+	if err != nil {
+		slog.Error("Failed to list the pins", "error", err)
+		return msg
+	}
+
+	var sb strings.Builder
+	for _, p := range pins {
+		switch p.Kind {
+		case pin.KindFile:
+			fmt.Fprintf(&sb, "<file path=%q>\n%s\n</file>\n", p.Path, readPinnedFile(p.Path))
+		case pin.KindMessage:
+			if slices.ContainsFunc(history, func(m message.Message) bool { return m.ID == p.MessageID }) {
+				continue
+			}
+			pinned, err := a.messages.Get(ctx, p.MessageID)
+			if err != nil {
+				// The message was rewound past.
+				continue
+			}
+			fmt.Fprintf(&sb, "<message role=%q>\n%s\n</message>\n", pinned.Role, pinned.Content().Text)
+		}
+	}
+	if sb.Len() == 0 {
+		return msg
+	}
+	return withNote(msg, "<pinned_context>\nThe user pinned these files and messages for you to always keep in mind, the files are shown as they are now:\n"+sb.String()+"</pinned_context>")
+}
+
+func readPinnedFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Sprintf("(the file can't be read: %v)", err)
+	}
+	content := strings.ToValidUTF8(string(data[:min(len(data), maxPinnedFileSize)]), "")
+	if len(data) > maxPinnedFileSize {
+		content += fmt.Sprintf("\n... (truncated, the file has %d bytes)", len(data))
+	}
+	return content
+}
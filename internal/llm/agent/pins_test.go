@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPins(t *testing.T) {
+	t.Parallel()
+
+	a := &agent{pins: pin.NewService(t.TempDir())}
+	user := message.Message{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "add the column"}}}
+	require.Equal(t, user, a.withPins(context.Background(), "session", nil, user))
+
+	file := filepath.Join(t.TempDir(), "schema.sql")
+	require.NoError(t, os.WriteFile(file, []byte("create table users;"), 0o644))
+	_, err := a.pins.PinFile("session", file)
+	require.NoError(t, err)
+	// The pinned message still in the history isn't sent again.
+	history := []message.Message{{ID: "msg-1", Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "use postgres"}}}}
+	_, err = a.pins.PinMessage("session", "msg-1", "you: use postgres", "use postgres")
+	require.NoError(t, err)
+
+	msg := a.withPins(context.Background(), "session", history, user)
+	pinned := msg.Content().Text
+	require.True(t, strings.HasPrefix(pinned, "add the column\n\n<pinned_context>\n"))
+	require.Contains(t, pinned, "<file path=\""+file+"\">\ncreate table users;\n</file>")
+	require.NotContains(t, pinned, "use postgres")
+
+	// The files are read when the prompt is sent.
+	require.NoError(t, os.WriteFile(file, []byte("create table posts;"), 0o644))
+	msg = a.withPins(context.Background(), "session", history, user)
+	require.Contains(t, msg.Content().Text, "create table posts;")
+}
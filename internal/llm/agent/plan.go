@@ -0,0 +1,353 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// ErrPlanEmpty is returned when executing a plan without proposed actions.
+var ErrPlanEmpty = errors.New("no proposed actions to execute")
+
+// planTools are the tools changing the files or running commands, which only
+// propose their action in plan mode. Bash commands that only read and the
+// queries of read-only databases still run.
+var planTools = []string{
+	tools.BashToolName,
+	tools.RunTestsToolName,
+	tools.DBQueryToolName,
+	tools.EditToolName,
+	tools.MultiEditToolName,
+	tools.WriteToolName,
+	tools.DownloadToolName,
+	tools.RenameSymbolToolName,
+	tools.CodeActionToolName,
+	tools.FormatFileToolName,
+}
+
+// ProposedAction is a tool call the agent proposed in plan mode instead of
+// running it.
+type ProposedAction struct {
+	ToolCallID string
+	// MessageID is the message of the tool call, the tools need it to ask
+	// for permissions when the plan is executed.
+	MessageID string
+	Tool      string
+	Input     string
+	// Summary describes the action on one line, e.g. the command or the
+	// changed file.
+	Summary string
+	// Diff is the change of the file, for the actions whose change is known
+	// beforehand.
+	Diff string
+}
+
+// PlanResult is the outcome of an executed action.
+type PlanResult struct {
+	Action ProposedAction
+	Output string
+	Err    error
+}
+
+// sessionPlan holds the actions proposed in a session, with the content the
+// files would have once they are applied, so that successive edits of a file
+// are shown on top of each other.
+type sessionPlan struct {
+	mu      sync.Mutex
+	actions []ProposedAction
+	files   map[string]string
+}
+
+func (a *agent) SetPlanMode(enabled bool) {
+	a.planMode.Store(enabled)
+}
+
+func (a *agent) PlanMode() bool {
+	return a.planMode.Load()
+}
+
+// Plan returns the actions proposed in the session, in order.
+func (a *agent) Plan(sessionID string) []ProposedAction {
+	plan, ok := a.plans.Get(sessionID)
+	if !ok {
+		return nil
+	}
+	plan.mu.Lock()
+	defer plan.mu.Unlock()
+	return slices.Clone(plan.actions)
+}
+
+func (a *agent) DiscardPlan(sessionID string) {
+	a.plans.Del(sessionID)
+}
+
+// isPlanned reports whether the tool call is only proposed in plan mode.
+func (a *agent) isPlanned(tool tools.BaseTool, call message.ToolCall) bool {
+	if !a.planMode.Load() {
+		return false
+	}
+	if _, ok := tool.(*McpTool); ok {
+		// The effects of the MCP tools aren't known.
+		return true
+	}
+	if !slices.Contains(planTools, call.Name) {
+		return false
+	}
+	switch call.Name {
+	case tools.BashToolName:
+		var params tools.BashParams
+		if json.Unmarshal([]byte(call.Input), &params) == nil && tools.IsReadOnlyCommand(params.Command) {
+			return false
+		}
+	case tools.DBQueryToolName:
+		var params tools.DBQueryParams
+		if json.Unmarshal([]byte(call.Input), &params) != nil {
+			return true
+		}
+		if params.Action != "" && params.Action != "query" {
+			// Listing the tables and their columns only reads.
+			return false
+		}
+		if cfg := config.Get(); cfg != nil {
+			if db, ok := cfg.Databases[params.Database]; ok && !db.ReadWrite {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// propose records the tool call in the plan of the session, and returns the
+// result telling the model that the action was recorded, not run.
+func (a *agent) propose(sessionID, messageID string, call message.ToolCall) tools.ToolResponse {
+	plan := a.plans.GetOrSet(sessionID, func() *sessionPlan {
+		return &sessionPlan{files: make(map[string]string)}
+	})
+	plan.mu.Lock()
+	defer plan.mu.Unlock()
+
+	action := ProposedAction{
+		ToolCallID: call.ID,
+		MessageID:  messageID,
+		Tool:       call.Name,
+		Input:      call.Input,
+	}
+	summary, diff, err := plan.preview(call)
+	if err != nil {
+		return tools.NewTextErrorResponse(fmt.Sprintf("Plan mode: the action can't be proposed: %s", err))
+	}
+	action.Summary, action.Diff = summary, diff
+	plan.actions = append(plan.actions, action)
+
+	return tools.NewTextResponse(fmt.Sprintf(
+		"Plan mode: proposed action #%d (%s) was recorded but NOT applied. "+
+			"The files are unchanged until the user approves the plan; "+
+			"later edits of the same file must still match its current content "+
+			"and are shown on top of this one.",
+		len(plan.actions), summary,
+	))
+}
+
+// preview describes the action, with the diff of the file it changes when
+// it can be computed without running it.
+func (p *sessionPlan) preview(call message.ToolCall) (string, string, error) {
+	var params struct {
+		FilePath   string                     `json:"file_path"`
+		Content    string                     `json:"content"`
+		OldString  string                     `json:"old_string"`
+		NewString  string                     `json:"new_string"`
+		ReplaceAll bool                       `json:"replace_all"`
+		Edits      []tools.MultiEditOperation `json:"edits"`
+		Command    string                     `json:"command"`
+		URL        string                     `json:"url"`
+		Symbol     string                     `json:"symbol"`
+		NewName    string                     `json:"new_name"`
+		Path       string                     `json:"path"`
+		Filter     string                     `json:"filter"`
+		Database   string                     `json:"database"`
+		Query      string                     `json:"query"`
+	}
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return "", "", fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	switch call.Name {
+	case tools.BashToolName:
+		return "run " + params.Command, "", nil
+	case tools.RunTestsToolName:
+		return strings.TrimSpace(fmt.Sprintf("run the tests %s %s", params.Path, params.Filter)), "", nil
+	case tools.DBQueryToolName:
+		return fmt.Sprintf("query %s: %s", params.Database, params.Query), "", nil
+	case tools.DownloadToolName:
+		return fmt.Sprintf("download %s to %s", params.URL, params.FilePath), "", nil
+	case tools.RenameSymbolToolName:
+		return fmt.Sprintf("rename %s to %s in %s", params.Symbol, params.NewName, params.FilePath), "", nil
+	case tools.CodeActionToolName, tools.FormatFileToolName:
+		return fmt.Sprintf("%s %s", call.Name, params.FilePath), "", nil
+	case tools.WriteToolName, tools.EditToolName, tools.MultiEditToolName:
+	default:
+		return call.Name + " " + call.Input, "", nil
+	}
+
+	path := params.FilePath
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(config.Get().WorkingDir(), path)
+	}
+	before, ok := p.files[path]
+	if !ok {
+		content, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", "", err
+		}
+		before = string(content)
+	}
+
+	after := before
+	switch call.Name {
+	case tools.WriteToolName:
+		after = params.Content
+	case tools.EditToolName:
+		edit := tools.MultiEditOperation{OldString: params.OldString, NewString: params.NewString, ReplaceAll: params.ReplaceAll}
+		var err error
+		if after, err = applyEdit(after, edit); err != nil {
+			return "", "", err
+		}
+	case tools.MultiEditToolName:
+		for i, edit := range params.Edits {
+			var err error
+			if after, err = applyEdit(after, edit); err != nil {
+				return "", "", fmt.Errorf("edit %d: %w", i+1, err)
+			}
+		}
+	}
+	p.files[path] = after
+
+	rel := path
+	if r, err := filepath.Rel(config.Get().WorkingDir(), path); err == nil && !strings.HasPrefix(r, "..") {
+		rel = r
+	}
+	d, additions, removals := diff.GenerateDiff(before, after, rel)
+	return fmt.Sprintf("%s %s (+%d -%d)", call.Name, rel, additions, removals), d, nil
+}
+
+// applyEdit replaces the old string like the edit tools do. An empty old
+// string creates the content.
+func applyEdit(content string, edit tools.MultiEditOperation) (string, error) {
+	if edit.OldString == "" {
+		return edit.NewString, nil
+	}
+	switch n := strings.Count(content, edit.OldString); {
+	case n == 0:
+		return "", errors.New("old_string not found in the file")
+	case n > 1 && !edit.ReplaceAll:
+		return "", errors.New("old_string appears multiple times in the file, provide more context or set replace_all")
+	}
+	if edit.ReplaceAll {
+		return strings.ReplaceAll(content, edit.OldString, edit.NewString), nil
+	}
+	return strings.Replace(content, edit.OldString, edit.NewString, 1), nil
+}
+
+// ExecutePlan runs the proposed actions of the session in order, with their
+// permissions granted as the user approved the plan. It stops at the first
+// failed action, keeping the remaining ones in the plan.
+func (a *agent) ExecutePlan(ctx context.Context, sessionID string) ([]PlanResult, error) {
+	if a.IsSessionBusy(sessionID) {
+		return nil, ErrSessionBusy
+	}
+	plan, ok := a.plans.Get(sessionID)
+	if !ok {
+		return nil, ErrPlanEmpty
+	}
+	plan.mu.Lock()
+	defer plan.mu.Unlock()
+	if len(plan.actions) == 0 {
+		return nil, ErrPlanEmpty
+	}
+
+	available := a.availableTools()
+	var results []PlanResult
+	for len(plan.actions) > 0 {
+		action := plan.actions[0]
+		result := PlanResult{Action: action}
+		idx := slices.IndexFunc(available, func(t tools.BaseTool) bool { return t.Name() == action.Tool })
+		if idx < 0 {
+			result.Err = fmt.Errorf("tool not found: %s", action.Tool)
+			results = append(results, result)
+			break
+		}
+		a.permissions.ApproveToolCall(action.ToolCallID)
+		toolCtx := context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
+		toolCtx = context.WithValue(toolCtx, tools.MessageIDContextKey, action.MessageID)
+		response, err := a.runTool(toolCtx, sessionID, available[idx], tools.ToolCall{
+			ID:    action.ToolCallID,
+			Name:  action.Tool,
+			Input: action.Input,
+		})
+		switch {
+		case err != nil:
+			result.Err = err
+		case response.IsError:
+			result.Err = errors.New(response.Content)
+		default:
+			result.Output = response.Content
+		}
+		results = append(results, result)
+		if result.Err != nil {
+			break
+		}
+		plan.actions = plan.actions[1:]
+	}
+	if len(plan.actions) == 0 {
+		a.plans.Del(sessionID)
+	}
+	a.executedPlans.Set(sessionID, append(a.executedPlan(sessionID), results...))
+	return results, nil
+}
+
+// executedPlan returns the results of the plans executed since the last
+// prompt of the session.
+func (a *agent) executedPlan(sessionID string) []PlanResult {
+	results, _ := a.executedPlans.Get(sessionID)
+	return results
+}
+
+// withPlanNotes tells the model about plan mode and about the plans the user
+// executed since its last answer. The stored message is left untouched.
+func (a *agent) withPlanNotes(sessionID string, msg message.Message) message.Message {
+	var sb strings.Builder
+	if results, ok := a.executedPlans.Take(sessionID); ok && len(results) > 0 {
+		sb.WriteString("<executed_plan>\nThe user approved the plan, its actions were run:\n")
+		for _, r := range results {
+			if r.Err != nil {
+				fmt.Fprintf(&sb, "- %s: FAILED: %s\n", r.Action.Summary, r.Err)
+				continue
+			}
+			fmt.Fprintf(&sb, "- %s: done\n", r.Action.Summary)
+		}
+		sb.WriteString("</executed_plan>")
+	}
+	if a.planMode.Load() {
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString("<plan_mode>\nPlan mode is on: file changes and commands that aren't read-only are " +
+			"recorded as proposed actions for the user to review, and are not applied. " +
+			"Investigate with the read-only tools, then propose the complete set of changes.\n</plan_mode>")
+	}
+	if sb.Len() == 0 {
+		return msg
+	}
+	return withNote(msg, sb.String())
+}
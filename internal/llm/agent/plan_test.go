@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyEdit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("replaces once", func(t *testing.T) {
+		t.Parallel()
+		got, err := applyEdit("a b c", tools.MultiEditOperation{OldString: "b", NewString: "x"})
+		require.NoError(t, err)
+		require.Equal(t, "a x c", got)
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		t.Parallel()
+		_, err := applyEdit("a a", tools.MultiEditOperation{OldString: "a", NewString: "x"})
+		require.Error(t, err)
+	})
+
+	t.Run("replaces all", func(t *testing.T) {
+		t.Parallel()
+		got, err := applyEdit("a a", tools.MultiEditOperation{OldString: "a", NewString: "x", ReplaceAll: true})
+		require.NoError(t, err)
+		require.Equal(t, "x x", got)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		_, err := applyEdit("a", tools.MultiEditOperation{OldString: "b", NewString: "x"})
+		require.Error(t, err)
+	})
+
+	t.Run("creates", func(t *testing.T) {
+		t.Parallel()
+		got, err := applyEdit("", tools.MultiEditOperation{NewString: "new"})
+		require.NoError(t, err)
+		require.Equal(t, "new", got)
+	})
+}
+
+func TestIsPlanned(t *testing.T) {
+	t.Parallel()
+
+	a := &agent{}
+	edit := message.ToolCall{Name: tools.EditToolName, Input: `{}`}
+	require.False(t, a.isPlanned(nil, edit), "plan mode is off")
+
+	a.SetPlanMode(true)
+	require.True(t, a.isPlanned(nil, edit))
+	require.False(t, a.isPlanned(nil, message.ToolCall{Name: tools.ViewToolName, Input: `{}`}))
+	require.False(t, a.isPlanned(nil, message.ToolCall{Name: tools.BashToolName, Input: `{"command":"ls -la"}`}))
+	require.True(t, a.isPlanned(nil, message.ToolCall{Name: tools.BashToolName, Input: `{"command":"rm -rf build"}`}))
+	for _, command := range []string{"ls && rm -rf src", "echo x > f", "cat go.mod | tee out", "ls $(rm -rf src)", "ls; rm f", "ls &"} {
+		input, err := json.Marshal(tools.BashParams{Command: command})
+		require.NoError(t, err)
+		require.True(t, a.isPlanned(nil, message.ToolCall{Name: tools.BashToolName, Input: string(input)}), command)
+	}
+	require.True(t, a.isPlanned(nil, message.ToolCall{Name: tools.RunTestsToolName, Input: `{}`}))
+	require.False(t, a.isPlanned(nil, message.ToolCall{Name: tools.DBQueryToolName, Input: `{"database":"app","action":"tables"}`}))
+	require.True(t, a.isPlanned(nil, message.ToolCall{Name: tools.DBQueryToolName, Input: `{"database":"app","query":"DELETE FROM users"}`}))
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/prompt"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+const (
+	// prewarmMinInterval skips the warm-up requests when the provider was
+	// used recently, e.g. when switching between sessions.
+	prewarmMinInterval = 30 * time.Second
+	prewarmTimeout     = 30 * time.Second
+	prewarmMessage     = "Hi"
+)
+
+// Prewarm sends a one token request to the provider in the background, when
+// enabled, so the first prompt doesn't wait for the connection, the
+// credentials and the prompt cache. With a keep alive the requests are sent
+// again while the agent is idle, until ctx is done.
+func (a *agent) Prewarm(ctx context.Context) {
+	opts := config.Get().Options.Prewarm
+	if opts == nil || !opts.Enabled {
+		return
+	}
+	go a.prewarm(ctx)
+	if opts.KeepAlive > 0 {
+		a.keepAliveOnce.Do(func() {
+			go a.keepAlive(ctx, time.Duration(opts.KeepAlive)*time.Second)
+		})
+	}
+}
+
+func (a *agent) keepAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if opts := config.Get().Options.Prewarm; opts == nil || !opts.Enabled {
+			return
+		}
+		if time.Since(a.lastRequestTime()) >= interval {
+			a.prewarm(ctx)
+		}
+	}
+}
+
+func (a *agent) prewarm(ctx context.Context) {
+	if a.IsBusy() || time.Since(a.lastRequestTime()) < prewarmMinInterval {
+		return
+	}
+	a.markRequest()
+
+	p, err := a.prewarmProvider()
+	if err != nil {
+		slog.Warn("Failed to create the warm-up provider", "error", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, prewarmTimeout)
+	defer cancel()
+
+	start := time.Now()
+	// The tools are sent too, they are part of the cached prefix.
+	_, err = p.SendMessages(ctx, []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: prewarmMessage}},
+	}}, a.availableTools())
+	if err != nil {
+		// The connection is warm even when the request is rejected, e.g.
+		// by models that need more tokens to think.
+		slog.Debug("Warm-up request failed", "error", err, "duration", time.Since(start))
+		return
+	}
+	slog.Debug("Warmed up provider", "provider", a.providerID, "duration", time.Since(start))
+}
+
+// prewarmProvider returns a provider sending the same system prompt as the
+// agent's, limited to one token.
+func (a *agent) prewarmProvider() (provider.Provider, error) {
+	cfg := config.Get()
+	providerCfg := cfg.GetProviderForModel(a.agentCfg.Model)
+	if providerCfg == nil {
+		return nil, fmt.Errorf("provider for agent %s not found in config", a.agentCfg.Name)
+	}
+	promptID := agentPromptMap[a.agentCfg.ID]
+	if promptID == "" {
+		promptID = prompt.PromptDefault
+	}
+	return provider.NewProvider(
+		*providerCfg,
+		provider.WithModel(a.agentCfg.Model),
+		provider.WithSystemMessage(prompt.GetPrompt(promptID, providerCfg.ID, cfg.Options.ContextPaths...)),
+		provider.WithSystemContext(prompt.SystemContext(promptID)),
+		provider.WithMaxTokens(1),
+	)
+}
+
+// markRequest records that a request is sent to the provider, warming it.
+func (a *agent) markRequest() {
+	a.lastRequest.Store(time.Now().UnixNano())
+}
+
+func (a *agent) lastRequestTime() time.Time {
+	return time.Unix(0, a.lastRequest.Load())
+}
@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptQueue(t *testing.T) {
+	t.Parallel()
+
+	a := &agent{promptQueue: csync.NewMap[string, []string]()}
+	a.promptQueue.Set("s", []string{"first", "second", "third"})
+
+	require.Equal(t, []string{"first", "second", "third"}, a.QueuedPromptList("s"))
+	require.Empty(t, a.QueuedPromptList("other"))
+
+	require.True(t, a.UpdateQueuedPrompt("s", 1, "edited"))
+	require.False(t, a.UpdateQueuedPrompt("s", 3, "missing"))
+	require.Equal(t, []string{"first", "edited", "third"}, a.QueuedPromptList("s"))
+
+	prompt, ok := a.RemoveQueuedPrompt("s", 0)
+	require.True(t, ok)
+	require.Equal(t, "first", prompt)
+	require.Equal(t, []string{"edited", "third"}, a.QueuedPromptList("s"))
+
+	_, ok = a.RemoveQueuedPrompt("s", 2)
+	require.False(t, ok)
+
+	a.RemoveQueuedPrompt("s", 0)
+	a.RemoveQueuedPrompt("s", 0)
+	require.Zero(t, a.QueuedPrompts("s"))
+	_, ok = a.promptQueue.Get("s")
+	require.False(t, ok)
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/redact"
+)
+
+// redactMessages returns the messages with the secrets of their tool results
+// and attached files masked. The stored messages are left untouched, so the
+// user still sees the original content.
+func (a *agent) redactMessages(sessionID string, msgs []message.Message) []message.Message {
+	if a.redactor == nil {
+		return msgs
+	}
+
+	// The file read by each tool call, to mask all the values of the .env
+	// files.
+	callPaths := make(map[string]string)
+	for _, msg := range msgs {
+		for _, call := range msg.ToolCalls() {
+			var params struct {
+				FilePath string `json:"file_path"`
+			}
+			if json.Unmarshal([]byte(call.Input), &params) == nil && params.FilePath != "" {
+				callPaths[call.ID] = params.FilePath
+			}
+		}
+	}
+
+	redacted := make([]message.Message, len(msgs))
+	for i, msg := range msgs {
+		redacted[i] = msg
+		if msg.Role != message.User && msg.Role != message.Tool {
+			continue
+		}
+		var parts []message.ContentPart
+		for j, part := range msg.Parts {
+			var changed message.ContentPart
+			switch p := part.(type) {
+			case message.ToolResult:
+				envFile := redact.IsEnvFile(callPaths[p.ToolCallID])
+				var text string
+				var findings []redact.Finding
+				// The edit tools match against the output of the view tool,
+				// which only gets the known patterns masked, the edits
+				// refusing to match the masks. Only .env files, made of
+				// secrets, are masked as a whole.
+				if p.Name == tools.ViewToolName && !envFile {
+					text, findings = a.redactor.RedactPatterns(p.Content)
+				} else {
+					text, findings = a.redactor.Redact(p.Content, envFile)
+				}
+				if len(findings) > 0 {
+					a.recordRedactions(sessionID, p.Name, findings)
+					p.Content = text
+					changed = p
+				}
+			case message.BinaryContent:
+				if !p.IsText() {
+					continue
+				}
+				text, findings := a.redactor.Redact(string(p.Data), redact.IsEnvFile(p.Path))
+				if len(findings) > 0 {
+					a.recordRedactions(sessionID, p.Path, findings)
+					p.Data = []byte(text)
+					changed = p
+				}
+			}
+			if changed == nil {
+				continue
+			}
+			if parts == nil {
+				parts = make([]message.ContentPart, len(msg.Parts))
+				copy(parts, msg.Parts)
+			}
+			parts[j] = changed
+		}
+		if parts != nil {
+			redacted[i].Parts = parts
+		}
+	}
+	return redacted
+}
+
+func (a *agent) recordRedactions(sessionID, source string, findings []redact.Finding) {
+	if err := a.redactions.Record(sessionID, source, findings); err != nil {
+		slog.Error("Failed to record redacted secrets", "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// defaultRoutingRules send the prompts about files or asking for changes to
+// the large model, and the other short questions to the small one.
+var defaultRoutingRules = []config.RoutingRule{
+	{Model: config.SelectedModelTypeLarge, MinFiles: 1},
+	{Model: config.SelectedModelTypeLarge, Match: `(?i)\b(implement|refactor|fix|add|write|change|update|create|rename|remove|delete|move|migrate|debug|test|edit)\b`},
+	{Model: config.SelectedModelTypeSmall, MaxLength: 200, NoToolCalls: true},
+}
+
+// fileNamePattern matches the words looking like file names, with an
+// extension.
+var fileNamePattern = regexp.MustCompile(`^[\w-]{2,}\.[A-Za-z][A-Za-z0-9]{0,7}$`)
+
+// promptTraits are what the routing rules know of a prompt.
+type promptTraits struct {
+	length    int
+	files     int
+	toolCalls bool
+	content   string
+}
+
+func newPromptTraits(content string, attachments int, history []message.Message) promptTraits {
+	traits := promptTraits{
+		length:  utf8.RuneCountInString(strings.TrimSpace(content)),
+		files:   attachments + countFileMentions(content),
+		content: content,
+	}
+	for _, msg := range history {
+		if len(msg.ToolCalls()) > 0 {
+			traits.toolCalls = true
+			break
+		}
+	}
+	return traits
+}
+
+// countFileMentions counts the distinct paths and file names of the text.
+func countFileMentions(text string) int {
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		word = strings.Trim(word, "`'\"()[]{}<>,;:!?")
+		word = strings.TrimPrefix(word, "@")
+		word = strings.TrimRight(word, ".")
+		if word == "" || seen[word] || strings.Contains(word, "://") {
+			continue
+		}
+		if strings.Contains(strings.Trim(word, "/"), "/") || fileNamePattern.MatchString(word) {
+			seen[word] = true
+		}
+	}
+	return len(seen)
+}
+
+// routeModel returns the type of the model answering the prompt, the model
+// of the agent when the routing is disabled or no rule matches.
+func routeModel(cfg *config.Config, agentModel config.SelectedModelType, traits promptTraits) config.SelectedModelType {
+	routing := cfg.Options.Routing
+	if routing == nil || !routing.Enabled || agentModel != config.SelectedModelTypeLarge {
+		return agentModel
+	}
+	if _, ok := cfg.Models[config.SelectedModelTypeSmall]; !ok {
+		return agentModel
+	}
+	rules := routing.Rules
+	if len(rules) == 0 {
+		rules = defaultRoutingRules
+	}
+	for i, rule := range rules {
+		if matchesRule(rule, traits) {
+			slog.Debug("Routed the prompt", "rule", i, "model", rule.Model)
+			return rule.Model
+		}
+	}
+	return agentModel
+}
+
+func matchesRule(rule config.RoutingRule, traits promptTraits) bool {
+	switch rule.Model {
+	case config.SelectedModelTypeLarge, config.SelectedModelTypeSmall:
+	default:
+		slog.Warn("Ignoring the routing rule of an unknown model", "model", rule.Model)
+		return false
+	}
+	if rule.MaxLength > 0 && traits.length > rule.MaxLength {
+		return false
+	}
+	if traits.files < rule.MinFiles {
+		return false
+	}
+	if rule.NoToolCalls && traits.toolCalls {
+		return false
+	}
+	if rule.Match != "" {
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			slog.Warn("Ignoring the routing rule with an invalid expression", "match", rule.Match, "error", err)
+			return false
+		}
+		if !re.MatchString(traits.content) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+func TestCountFileMentions(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 0, countFileMentions("What is a goroutine, e.g. in a server?"))
+	require.Equal(t, 0, countFileMentions("See https://go.dev/doc for details."))
+	require.Equal(t, 1, countFileMentions("Why does `main.go` panic?"))
+	require.Equal(t, 2, countFileMentions("Move @internal/app/app.go into cmd/root.go, then check cmd/root.go."))
+}
+
+func TestRouteModel(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Models: map[config.SelectedModelType]config.SelectedModel{
+			config.SelectedModelTypeLarge: {Model: "large"},
+			config.SelectedModelTypeSmall: {Model: "small"},
+		},
+		Options: &config.Options{Routing: &config.RoutingOptions{Enabled: true}},
+	}
+	toolHistory := []message.Message{{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.ToolCall{ID: "1", Name: "view"}},
+	}}
+	route := func(content string, attachments int, history []message.Message) config.SelectedModelType {
+		return routeModel(cfg, config.SelectedModelTypeLarge, newPromptTraits(content, attachments, history))
+	}
+
+	require.Equal(t, config.SelectedModelTypeSmall, route("What is the difference between a slice and an array?", 0, nil))
+	require.Equal(t, config.SelectedModelTypeLarge, route("What does internal/app/app.go do?", 0, nil))
+	require.Equal(t, config.SelectedModelTypeLarge, route("What is in this screenshot?", 1, nil))
+	require.Equal(t, config.SelectedModelTypeLarge, route("Refactor the config loading", 0, nil))
+	require.Equal(t, config.SelectedModelTypeLarge, route("Yes, go ahead", 0, toolHistory))
+	require.Equal(t, config.SelectedModelTypeSmall, routeModel(cfg, config.SelectedModelTypeSmall, newPromptTraits("Refactor it", 0, nil)))
+
+	cfg.Options.Routing.Rules = []config.RoutingRule{
+		{Model: config.SelectedModelTypeSmall, Match: `(?i)^explain`},
+		{Model: "medium"},
+		{Model: config.SelectedModelTypeSmall, Match: `(`},
+	}
+	require.Equal(t, config.SelectedModelTypeSmall, route("Explain how internal/app/app.go starts the agent", 0, nil))
+	require.Equal(t, config.SelectedModelTypeLarge, route("What is a slice?", 0, nil))
+
+	cfg.Options.Routing.Enabled = false
+	require.Equal(t, config.SelectedModelTypeLarge, route("Explain slices", 0, nil))
+}
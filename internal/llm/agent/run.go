@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/prompt"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+)
+
+// RunOptions change how the agent answers a prompt, like the prompt of a
+// custom command. They are set on the context given to Run, and are ignored
+// when the prompt is queued behind a running one.
+type RunOptions struct {
+	// Model is the type of the model answering, the model picked by the
+	// routing rules or the model of the agent when empty.
+	Model config.SelectedModelType
+	// AllowedTools restricts the tools the model can call to these ones,
+	// among the tools of the agent. Nil keeps them all.
+	AllowedTools []string
+}
+
+type runOptionsKey struct{}
+
+// WithRunOptions returns a context running the agent with the options.
+func WithRunOptions(ctx context.Context, opts RunOptions) context.Context {
+	return context.WithValue(ctx, runOptionsKey{}, opts)
+}
+
+func runOptionsFrom(ctx context.Context) RunOptions {
+	opts, _ := ctx.Value(runOptionsKey{}).(RunOptions)
+	return opts
+}
+
+// turnProvider is the provider answering the turns of a run.
+type turnProvider struct {
+	provider provider.Provider
+	id       string
+	model    catwalk.Model
+}
+
+// providerFor returns the provider of the agent, or a provider for the model
+// of the options when it isn't the model of the agent.
+func (a *agent) providerFor(opts RunOptions) (turnProvider, error) {
+	if opts.Model == "" || opts.Model == a.agentCfg.Model {
+		return turnProvider{provider: a.provider, id: a.providerID, model: a.Model()}, nil
+	}
+	cfg := config.Get()
+	providerCfg := cfg.GetProviderForModel(opts.Model)
+	model := cfg.GetModelByType(opts.Model)
+	if providerCfg == nil || model == nil {
+		return turnProvider{}, fmt.Errorf("no %s model configured", opts.Model)
+	}
+	promptID := agentPromptMap[a.agentCfg.ID]
+	if promptID == "" {
+		promptID = prompt.PromptDefault
+	}
+	p, err := provider.NewProvider(
+		*providerCfg,
+		provider.WithModel(opts.Model),
+		provider.WithSystemMessage(prompt.GetPrompt(promptID, providerCfg.ID, cfg.Options.ContextPaths...)),
+		provider.WithSystemContext(prompt.SystemContext(promptID)),
+	)
+	if err != nil {
+		return turnProvider{}, fmt.Errorf("failed to create the provider of the %s model: %w", opts.Model, err)
+	}
+	return turnProvider{provider: p, id: providerCfg.ID, model: *model}, nil
+}
+
+// runTools returns the tools available for a turn, restricted to the allowed
+// tools of the options.
+func (a *agent) runTools(opts RunOptions) []tools.BaseTool {
+	available := a.availableTools()
+	if opts.AllowedTools == nil {
+		return available
+	}
+	return slices.DeleteFunc(available, func(tool tools.BaseTool) bool {
+		return !slices.Contains(opts.AllowedTools, tool.Name())
+	})
+}
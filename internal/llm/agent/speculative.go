@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+const verifyDraftPrompt = `The last answer above is a draft written by a smaller model. Check it against what the user asked, including the changes it made with the tools.
+Reply with APPROVE alone when it is correct and complete.
+Otherwise reply with ESCALATE followed by what is wrong or missing, in one or two sentences.`
+
+// hedges are the phrases of the drafts the small model isn't confident
+// about, which go to the large model without being verified.
+var hedges = []string{
+	"i'm not sure",
+	"i am not sure",
+	"i'm not certain",
+	"i am not certain",
+	"i don't know",
+	"i do not know",
+	"i'm unable to",
+	"i am unable to",
+	"i can't determine",
+	"i cannot determine",
+	"not confident",
+}
+
+// route is how the model answering a turn was picked by the routing rules.
+type route struct {
+	model config.SelectedModelType
+	// speculative tells the answer of the small model is a draft the large
+	// model verifies.
+	speculative bool
+	verified    bool
+	escalated   bool
+}
+
+func newRoute(cfg *config.Config, agentModel, model config.SelectedModelType) route {
+	routing := cfg.Options.Routing
+	if routing == nil || !routing.Enabled || agentModel != config.SelectedModelTypeLarge {
+		return route{}
+	}
+	return route{
+		model:       model,
+		speculative: routing.Speculative && model == config.SelectedModelTypeSmall,
+	}
+}
+
+// String shows the route in the usage of the session, empty when the
+// routing is disabled.
+func (r route) String() string {
+	switch {
+	case r.escalated:
+		return fmt.Sprintf("%s → %s", config.SelectedModelTypeSmall, config.SelectedModelTypeLarge)
+	case r.verified:
+		return fmt.Sprintf("%s draft ✓", r.model)
+	case r.speculative:
+		return fmt.Sprintf("%s draft", r.model)
+	}
+	return string(r.model)
+}
+
+// drafting reports whether the answer of the turn still needs to be verified.
+func (r route) drafting() bool {
+	return r.speculative && !r.verified && !r.escalated
+}
+
+// lowConfidence reports whether the draft shows the small model wasn't up
+// to the task.
+func lowConfidence(draft message.Message) bool {
+	content := strings.ToLower(strings.TrimSpace(draft.Content().Text))
+	if content == "" {
+		return len(draft.ToolCalls()) == 0
+	}
+	content = strings.ReplaceAll(content, "’", "'")
+	for _, hedge := range hedges {
+		if strings.Contains(content, hedge) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVerdict reads the answer of the large model to verifyDraftPrompt,
+// returning what is wrong with the draft unless it is approved. Answers
+// which are neither are taken as escalations.
+func parseVerdict(content string) (string, bool) {
+	verdict := strings.TrimSpace(content)
+	if strings.HasPrefix(strings.ToUpper(verdict), "APPROVE") {
+		return "", true
+	}
+	if strings.HasPrefix(strings.ToUpper(verdict), "ESCALATE") {
+		verdict = strings.TrimLeft(verdict[len("ESCALATE"):], ":.- \n")
+	}
+	return verdict, false
+}
+
+// verifyDraft has the large model check the draft ending the turn, and
+// returns what is wrong with it when the large model should answer instead.
+// The draft is kept when it can't be verified.
+func (a *agent) verifyDraft(ctx context.Context, sessionID string, r *route, large turnProvider, history []message.Message) (string, bool) {
+	defer func() {
+		a.routes.Set(sessionID, *r)
+	}()
+	if lowConfidence(history[len(history)-1]) {
+		r.escalated = true
+		return "The smaller model wasn't confident in its answer.", false
+	}
+
+	history = append(history, message.Message{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: verifyDraftPrompt}},
+	})
+	a.auditRequest(sessionID, "verification", large.id, large.model.ID, len(history), 0)
+	var response *provider.ProviderResponse
+	for event := range large.provider.StreamResponse(ctx, a.redactMessages(sessionID, history), nil) {
+		if event.Error != nil {
+			slog.Warn("Failed to verify the draft of the small model", "error", event.Error)
+			return "", true
+		}
+		if event.Type == provider.EventComplete {
+			response = event.Response
+		}
+	}
+	if response == nil {
+		return "", true
+	}
+
+	reason, approved := parseVerdict(response.Content)
+	r.verified = approved
+	r.escalated = !approved
+	// The route is published with the usage of the verification.
+	a.routes.Set(sessionID, *r)
+	if err := a.TrackUsage(ctx, sessionID, large.model, response.Usage); err != nil {
+		slog.Error("Failed to track the usage of the verification", "error", err)
+	}
+	return reason, approved
+}
+
+// escalationPrompt asks the large model to answer the turn the draft of the
+// small model didn't get right.
+func escalationPrompt(reason string) string {
+	if reason == "" {
+		reason = "It isn't good enough."
+	}
+	return fmt.Sprintf("<verification>\nThe previous answer was drafted by a smaller model and didn't pass the review: %s\nAnswer the request again, fixing what is wrong or missing, and keep the changes which are right.\n</verification>", reason)
+}
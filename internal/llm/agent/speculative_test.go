@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoute(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Options: &config.Options{}}
+	require.Equal(t, route{}, newRoute(cfg, config.SelectedModelTypeLarge, config.SelectedModelTypeSmall))
+
+	cfg.Options.Routing = &config.RoutingOptions{Enabled: true}
+	r := newRoute(cfg, config.SelectedModelTypeLarge, config.SelectedModelTypeSmall)
+	require.Equal(t, "small", r.String())
+	require.False(t, r.drafting())
+
+	cfg.Options.Routing.Speculative = true
+	require.Equal(t, "large", newRoute(cfg, config.SelectedModelTypeLarge, config.SelectedModelTypeLarge).String())
+	require.Equal(t, route{}, newRoute(cfg, config.SelectedModelTypeSmall, config.SelectedModelTypeSmall))
+
+	r = newRoute(cfg, config.SelectedModelTypeLarge, config.SelectedModelTypeSmall)
+	require.Equal(t, "small draft", r.String())
+	require.True(t, r.drafting())
+	r.verified = true
+	require.Equal(t, "small draft ✓", r.String())
+	require.False(t, r.drafting())
+	r.verified, r.escalated = false, true
+	require.Equal(t, "small → large", r.String())
+	require.False(t, r.drafting())
+}
+
+func TestLowConfidence(t *testing.T) {
+	t.Parallel()
+
+	draft := func(text string, calls ...message.ToolCall) message.Message {
+		msg := message.Message{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: text}}}
+		for _, call := range calls {
+			msg.AddToolCall(call)
+		}
+		return msg
+	}
+	require.False(t, lowConfidence(draft("The slice shares the array it was cut from.")))
+	require.True(t, lowConfidence(draft("I’m not sure which version you use, but it may be")))
+	require.True(t, lowConfidence(draft("")))
+	require.False(t, lowConfidence(draft("", message.ToolCall{ID: "1", Name: "view"})))
+}
+
+func TestParseVerdict(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		content  string
+		reason   string
+		approved bool
+	}{
+		{"APPROVE", "", true},
+		{"approve.\n", "", true},
+		{"ESCALATE: the test file wasn't updated.", "the test file wasn't updated.", false},
+		{"ESCALATE\nIt misses the error case.", "It misses the error case.", false},
+		{"The draft is wrong.", "The draft is wrong.", false},
+	}
+	for _, tt := range tests {
+		reason, approved := parseVerdict(tt.content)
+		require.Equal(t, tt.approved, approved, tt.content)
+		require.Equal(t, tt.reason, reason, tt.content)
+	}
+}
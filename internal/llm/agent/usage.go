@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+
+	"github.com/charmbracelet/crush/internal/llm/provider"
+)
+
+// Usage is the token usage of a session, published as the agent streams its
+// responses.
+type Usage struct {
+	// Cost is the cost of the session so far.
+	Cost float64
+	// TurnTokens are the tokens of the requests answering the last prompt.
+	TurnTokens int64
+	// ContextTokens are the tokens of the last request, filling the context
+	// window of the model.
+	ContextTokens int64
+	ContextWindow int64
+	// InputTokens are the input tokens of the turn, CacheReadTokens of them
+	// were read from the prompt cache.
+	InputTokens     int64
+	CacheReadTokens int64
+	// Route is how the model answering the turn was picked, like
+	// "small draft ✓", empty when the routing is disabled.
+	Route string
+}
+
+// ContextUsed returns the fraction of the context window that is used,
+// between 0 and 1.
+func (u Usage) ContextUsed() float64 {
+	if u.ContextWindow <= 0 {
+		return 0
+	}
+	return min(1, float64(u.ContextTokens)/float64(u.ContextWindow))
+}
+
+// CacheHitRatio returns the fraction of the input tokens of the turn that
+// were read from the cache, between 0 and 1.
+func (u Usage) CacheHitRatio() float64 {
+	if u.InputTokens <= 0 {
+		return 0
+	}
+	return float64(u.CacheReadTokens) / float64(u.InputTokens)
+}
+
+// newUsage returns the usage of a session from the usage of the turn and of
+// its last request.
+func newUsage(model catwalk.Model, cost float64, turn, last provider.TokenUsage) Usage {
+	return Usage{
+		Cost:            cost,
+		TurnTokens:      totalTokens(turn),
+		ContextTokens:   totalTokens(last),
+		ContextWindow:   model.ContextWindow,
+		InputTokens:     turn.InputTokens + turn.CacheCreationTokens + turn.CacheReadTokens,
+		CacheReadTokens: turn.CacheReadTokens,
+	}
+}
+
+func usageCost(model catwalk.Model, usage provider.TokenUsage) float64 {
+	return model.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
+		model.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
+		model.CostPer1MIn/1e6*float64(usage.InputTokens) +
+		model.CostPer1MOut/1e6*float64(usage.OutputTokens)
+}
+
+func addUsage(a, b provider.TokenUsage) provider.TokenUsage {
+	return provider.TokenUsage{
+		InputTokens:         a.InputTokens + b.InputTokens,
+		OutputTokens:        a.OutputTokens + b.OutputTokens,
+		CacheCreationTokens: a.CacheCreationTokens + b.CacheCreationTokens,
+		CacheReadTokens:     a.CacheReadTokens + b.CacheReadTokens,
+	}
+}
+
+func totalTokens(usage provider.TokenUsage) int64 {
+	return usage.InputTokens + usage.OutputTokens + usage.CacheCreationTokens + usage.CacheReadTokens
+}
@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charmbracelet/crush/internal/llm/provider"
+)
+
+func TestNewUsage(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{ContextWindow: 10_000}
+	first := provider.TokenUsage{InputTokens: 1_000, OutputTokens: 200, CacheCreationTokens: 3_000}
+	last := provider.TokenUsage{InputTokens: 500, OutputTokens: 300, CacheReadTokens: 3_200}
+
+	usage := newUsage(model, 0.5, addUsage(first, last), last)
+	require.Equal(t, 0.5, usage.Cost)
+	require.Equal(t, int64(8_200), usage.TurnTokens)
+	require.Equal(t, int64(4_000), usage.ContextTokens)
+	require.InDelta(t, 0.4, usage.ContextUsed(), 1e-9)
+	require.InDelta(t, 3_200.0/7_700.0, usage.CacheHitRatio(), 1e-9)
+
+	require.Zero(t, Usage{}.ContextUsed())
+	require.Zero(t, Usage{}.CacheHitRatio())
+	require.Equal(t, 1.0, Usage{ContextTokens: 20, ContextWindow: 10}.ContextUsed())
+}
+
+func TestUsageCost(t *testing.T) {
+	t.Parallel()
+
+	model := catwalk.Model{CostPer1MIn: 3, CostPer1MOut: 15, CostPer1MInCached: 3.75, CostPer1MOutCached: 0.3}
+	usage := provider.TokenUsage{
+		InputTokens:         1_000_000,
+		OutputTokens:        100_000,
+		CacheCreationTokens: 200_000,
+		CacheReadTokens:     1_000_000,
+	}
+	require.InDelta(t, 3+1.5+0.75+0.3, usageCost(model, usage), 1e-9)
+}
@@ -7,11 +7,13 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/repomap"
 )
 
 func CoderPrompt(p string, contextFiles ...string) string {
@@ -31,7 +33,10 @@ func CoderPrompt(p string, contextFiles ...string) string {
 	envInfo := getEnvironmentInfo()
 
 	basePrompt = fmt.Sprintf("%s\n\n%s\n%s", basePrompt, envInfo, lspInformation())
+	basePrompt = withRole(config.Get(), basePrompt)
 
+	// The context of every project comes first, the project can refine it.
+	contextFiles = append([]string{config.GlobalContextFile()}, contextFiles...)
 	contextContent := getContextFromPaths(config.Get().WorkingDir(), contextFiles)
 	if contextContent != "" {
 		return fmt.Sprintf("%s\n\n# Project-Specific Context\n Make sure to follow the instructions in the context below\n%s", basePrompt, contextContent)
@@ -51,6 +56,31 @@ var openaiCoderPrompt []byte
 //go:embed v2.md
 var coderV2Prompt []byte
 
+var repoMap = sync.OnceValue(func() *repomap.Map {
+	cfg := config.Get()
+	var maxTokens int
+	if cfg.Options.RepoMap != nil {
+		maxTokens = cfg.Options.RepoMap.MaxTokens
+	}
+	return repomap.New(cfg.WorkingDir(), maxTokens)
+})
+
+// RepoMap returns the map of the repository appended to the system prompt of
+// the coder agent, rebuilt as its files change, or nothing when it is
+// disabled.
+func RepoMap() string {
+	if opts := config.Get().Options.RepoMap; opts != nil && opts.Disabled {
+		return ""
+	}
+	text := repoMap().String()
+	if text == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<repo_map>
+The source files of the project, the most referenced by the others first, with their main declarations. Use it to find where to look before listing or searching the project.
+%s</repo_map>`, text)
+}
+
 func getEnvironmentInfo() string {
 	cwd := config.Get().WorkingDir()
 	isGit := isGitRepo(cwd)
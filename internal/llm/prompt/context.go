@@ -0,0 +1,176 @@
+package prompt
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+)
+
+const (
+	// maxContextFileSize is the size past which a context file, with the
+	// files it includes, is truncated.
+	maxContextFileSize = 32 * 1024
+	// maxIncludeDepth bounds the nesting of the @include directives.
+	maxIncludeDepth = 5
+	// includeDirective is the line prefix including another file in a
+	// context file, its path being relative to the context file.
+	includeDirective = "@include "
+)
+
+func processContextPaths(workDir string, paths []string) string {
+	var wg sync.WaitGroup
+
+	// Track processed files to avoid duplicates
+	processedFiles := csync.NewMap[string, bool]()
+	// The files of each path, kept in the order of the paths so the prompt
+	// is the same from one run to the next.
+	results := make([][]string, len(paths))
+
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, p string) {
+			defer wg.Done()
+
+			// Expand ~ and environment variables before processing
+			p = expandPath(p)
+
+			// Use absolute path if provided, otherwise join with workDir
+			fullPath := p
+			if !filepath.IsAbs(p) {
+				fullPath = filepath.Join(workDir, p)
+			}
+
+			// Check if the path is a directory using os.Stat
+			info, err := os.Stat(fullPath)
+			if err != nil {
+				return // Skip if path doesn't exist or can't be accessed
+			}
+
+			process := func(path string) {
+				// Check if we've already processed this file (case-insensitive)
+				lowerPath := strings.ToLower(path)
+				var alreadyProcessed bool
+				processedFiles.Update(lowerPath, func(_ bool, ok bool) (bool, bool) {
+					alreadyProcessed = ok
+					return true, true
+				})
+				if alreadyProcessed {
+					return
+				}
+				if result := processFile(path); result != "" {
+					results[i] = append(results[i], result)
+				}
+			}
+
+			if info.IsDir() {
+				filepath.WalkDir(fullPath, func(path string, d os.DirEntry, err error) error {
+					if err != nil {
+						return err
+					}
+					if !d.IsDir() {
+						process(path)
+					}
+					return nil
+				})
+			} else {
+				process(fullPath)
+			}
+		}(i, path)
+	}
+	wg.Wait()
+
+	var files []string
+	for _, result := range results {
+		files = append(files, result...)
+	}
+	return strings.Join(budgetContext(files, config.Get().ContextMaxSize()), "\n")
+}
+
+func processFile(filePath string) string {
+	content, err := readContextFile(filePath, map[string]bool{}, 0)
+	if err != nil {
+		return ""
+	}
+	if len(content) > maxContextFileSize {
+		slog.Warn("Context file truncated, it is larger than the size of a context file", "path", filePath, "size", len(content), "max", maxContextFileSize)
+		content = truncateContext(content, maxContextFileSize)
+	}
+	return "# From:" + filePath + "\n" + content
+}
+
+// readContextFile reads the context file, replacing its @include lines with
+// the files they point to.
+func readContextFile(path string, seen map[string]bool, depth int) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	seen[path] = true
+	defer delete(seen, path)
+
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, includeDirective) {
+			continue
+		}
+		include := expandPath(strings.TrimSpace(strings.TrimPrefix(trimmed, includeDirective)))
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		switch {
+		case seen[include]:
+			slog.Warn("Skipped the include of a context file including itself", "path", path, "include", include)
+		case depth >= maxIncludeDepth:
+			slog.Warn("Skipped the include of a context file nested too deep", "path", path, "include", include)
+		default:
+			included, err := readContextFile(include, seen, depth+1)
+			if err != nil {
+				slog.Warn("Failed to include a file in a context file", "path", path, "include", include, "error", err)
+				continue
+			}
+			if !strings.HasSuffix(included, "\n") && strings.HasSuffix(line, "\n") {
+				included += "\n"
+			}
+			lines[i] = included
+		}
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// budgetContext truncates the context files to fit in the size, dropping
+// the files left once it is reached.
+func budgetContext(files []string, size int) []string {
+	budgeted := make([]string, 0, len(files))
+	left := size
+	for i, file := range files {
+		if len(file) <= left {
+			budgeted = append(budgeted, file)
+			left -= len(file) + 1
+			continue
+		}
+		path, _, _ := strings.Cut(strings.TrimPrefix(file, "# From:"), "\n")
+		slog.Warn("Context files truncated, they are larger than the context budget", "path", path, "dropped", len(files)-i-1, "max", size)
+		if left > 0 {
+			budgeted = append(budgeted, truncateContext(file, left))
+		}
+		break
+	}
+	return budgeted
+}
+
+// truncateContext cuts the content to the size at a line boundary, noting
+// what was left out.
+func truncateContext(content string, size int) string {
+	cut := content[:size]
+	if i := strings.LastIndex(cut, "\n"); i > 0 {
+		cut = cut[:i+1]
+	}
+	return fmt.Sprintf("%s\n[%d bytes truncated to fit the context budget]\n", strings.TrimRight(cut, "\n"), len(content)-len(cut))
+}
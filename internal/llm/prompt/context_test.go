@@ -0,0 +1,55 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadContextFileIncludes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write("CRUSH.md", "# Project\n@include docs/style.md\n  @include missing.md\nEnd\n")
+	write("docs/style.md", "Use tabs.\n@include ../CRUSH.md\n@include nested.md")
+	write("docs/nested.md", "Nested.")
+
+	content, err := readContextFile(filepath.Join(dir, "CRUSH.md"), map[string]bool{}, 0)
+	require.NoError(t, err)
+	// The include cycle and the missing file are left as they are.
+	require.Equal(t, "# Project\nUse tabs.\n@include ../CRUSH.md\nNested.\n  @include missing.md\nEnd\n", content)
+}
+
+func TestBudgetContext(t *testing.T) {
+	t.Parallel()
+
+	first := "# From:a\n" + strings.Repeat("a\n", 10)
+	second := "# From:b\n" + strings.Repeat("b\n", 10)
+	third := "# From:c\nc"
+
+	require.Equal(t, []string{first, second, third}, budgetContext([]string{first, second, third}, 100))
+
+	budgeted := budgetContext([]string{first, second, third}, len(first)+15)
+	require.Len(t, budgeted, 2)
+	require.Equal(t, first, budgeted[0])
+	require.Equal(t, "# From:b\nb\nb\n[16 bytes truncated to fit the context budget]\n", budgeted[1])
+}
+
+func TestProcessFileTruncates(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "CRUSH.md")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("line\n", maxContextFileSize)), 0o644))
+
+	result := processFile(path)
+	require.Less(t, len(result), maxContextFileSize+200)
+	require.True(t, strings.HasSuffix(result, "bytes truncated to fit the context budget]\n"))
+}
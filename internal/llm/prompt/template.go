@@ -0,0 +1,115 @@
+package prompt
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/lsp/install"
+)
+
+//go:embed reviewer.md
+var reviewerRolePrompt []byte
+
+//go:embed planner.md
+var plannerRolePrompt []byte
+
+// rolePrompts are the instructions added to the coder prompt for the other
+// built-in roles.
+var rolePrompts = map[string][]byte{
+	config.PromptRoleReviewer: reviewerRolePrompt,
+	config.PromptRolePlanner:  plannerRolePrompt,
+}
+
+// TemplateData are the variables of the system prompt templates.
+type TemplateData struct {
+	// Project is the name of the working directory.
+	Project string
+	// Language lists the languages of the project, found by the files at
+	// its root.
+	Language string
+	// Branch is the current git branch, empty outside of a git repository or
+	// on a detached HEAD.
+	Branch string
+	// Date is today's date, as 2006-01-02.
+	Date string
+	Role string
+	// Prompt is the built-in system prompt of the role.
+	Prompt string
+}
+
+// withRole returns the prompt of the role of the coder agent.
+func withRole(cfg *config.Config, basePrompt string) string {
+	role := cfg.PromptRole()
+	return rolePrompt(role, cfg.PromptTemplate(role), cfg.WorkingDir(), basePrompt)
+}
+
+// rolePrompt returns the built-in prompt followed by the instructions of the
+// role, replaced by the template file of the role when it has one. The
+// built-in prompt is kept when the template fails.
+func rolePrompt(role, file, workingDir, basePrompt string) string {
+	if instructions, ok := rolePrompts[role]; ok {
+		basePrompt = basePrompt + "\n\n" + string(instructions)
+	}
+	if file == "" {
+		if role != config.PromptRoleCoder && rolePrompts[role] == nil {
+			slog.Warn("The role has no system prompt template, using the coder prompt", "role", role)
+		}
+		return basePrompt
+	}
+	prompt, err := renderTemplate(file, templateData(workingDir, role, basePrompt))
+	if err != nil {
+		slog.Error("Failed to render the system prompt template, using the built-in prompt", "role", role, "error", err)
+		return basePrompt
+	}
+	return prompt
+}
+
+func renderTemplate(file string, data TemplateData) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(file)).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	return b.String(), nil
+}
+
+func templateData(workingDir, role, basePrompt string) TemplateData {
+	var languages []string
+	for _, s := range install.Detect(workingDir) {
+		languages = append(languages, s.Language)
+	}
+	return TemplateData{
+		Project:  filepath.Base(workingDir),
+		Language: strings.Join(languages, ", "),
+		Branch:   gitBranch(workingDir),
+		Date:     time.Now().Format(time.DateOnly),
+		Role:     role,
+		Prompt:   basePrompt,
+	}
+}
+
+func gitBranch(dir string) string {
+	cmd := exec.Command("git", "branch", "--show-current")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,44 @@
+package prompt
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+func TestRolePrompt(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example\n"), 0o644))
+	git := exec.Command("git", "init", "-q", "-b", "feature")
+	git.Dir = dir
+	require.NoError(t, git.Run())
+
+	template := filepath.Join(dir, "reviewer.md")
+	require.NoError(t, os.WriteFile(template, []byte(
+		"Reviewing {{.Project}} ({{.Language}}) on {{.Branch}} as {{.Role}}, {{.Date}}.\n{{.Prompt}}",
+	), 0o644))
+
+	// The built-in roles add their instructions to the prompt.
+	require.Equal(t, "base", rolePrompt(config.PromptRoleCoder, "", dir, "base"))
+	require.Equal(t, "base\n\n"+string(plannerRolePrompt), rolePrompt(config.PromptRolePlanner, "", dir, "base"))
+	require.Equal(t, "base", rolePrompt("unknown", "", dir, "base"))
+
+	got := rolePrompt(config.PromptRoleReviewer, template, dir, "base")
+	first, rest, _ := strings.Cut(got, "\n")
+	require.Equal(t, "Reviewing "+filepath.Base(dir)+" (Go) on feature as reviewer, "+time.Now().Format(time.DateOnly)+".", first)
+	require.Equal(t, "base\n\n"+string(reviewerRolePrompt), rest)
+
+	// A broken template keeps the built-in prompt.
+	require.NoError(t, os.WriteFile(template, []byte("{{.Missing}}"), 0o644))
+	require.Equal(t, "base\n\n"+string(reviewerRolePrompt), rolePrompt(config.PromptRoleReviewer, template, dir, "base"))
+	require.Equal(t, "base", rolePrompt(config.PromptRoleCoder, filepath.Join(dir, "missing.md"), dir, "base"))
+}
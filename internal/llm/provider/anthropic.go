@@ -1,14 +1,12 @@
 package provider
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
@@ -17,6 +15,7 @@ import (
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/bedrock"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
 	"github.com/anthropics/anthropic-sdk-go/vertex"
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/charmbracelet/crush/internal/config"
@@ -32,8 +31,7 @@ type anthropicClient struct {
 	providerOptions   providerClientOptions
 	tp                AnthropicClientType
 	client            anthropic.Client
-	adjustedMaxTokens int  // Used when context limit is hit
-	isOnPremise       bool // 온프레미스 모드 플래그
+	adjustedMaxTokens int // Used when context limit is hit
 }
 
 type AnthropicClient ProviderClient
@@ -47,20 +45,11 @@ const (
 )
 
 func newAnthropicClient(opts providerClientOptions, tp AnthropicClientType) AnthropicClient {
-	// 온프레미스 환경 체크 (대소문자 무시, trailing slash 정규화)
-	normalizedURL := strings.ToLower(strings.TrimRight(opts.baseURL, "/"))
-	isOnPremise := opts.baseURL != "" && strings.HasSuffix(normalizedURL, "/v2/api/claude")
-	
-	var client anthropic.Client
-	if !isOnPremise {
-		client = createAnthropicClient(opts, tp)
-	}
-	
+	opts.disableCache = promptCacheDisabled(opts, tp)
 	return &anthropicClient{
 		providerOptions: opts,
 		tp:              tp,
-		client:          client,
-		isOnPremise:     isOnPremise,
+		client:          createAnthropicClient(opts, tp),
 	}
 }
 
@@ -138,6 +127,10 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 			var contentBlocks []anthropic.ContentBlockParamUnion
 			contentBlocks = append(contentBlocks, content)
 			for _, binaryContent := range msg.BinaryContent() {
+				if binaryContent.IsText() {
+					contentBlocks = append(contentBlocks, anthropic.NewTextBlock(binaryContent.Text()))
+					continue
+				}
 				base64Image := binaryContent.String(catwalk.InferenceProviderAnthropic)
 				imageBlock := anthropic.NewImageBlockBase64(binaryContent.MIMEType, base64Image)
 				contentBlocks = append(contentBlocks, imageBlock)
@@ -147,8 +140,10 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 		case message.Assistant:
 			blocks := []anthropic.ContentBlockParamUnion{}
 
-			// Add thinking blocks first if present (required when thinking is enabled with tool use)
-			if reasoningContent := msg.ReasoningContent(); reasoningContent.Thinking != "" {
+			// Add thinking blocks first if present (required when thinking is enabled with tool use).
+			// Reasoning of other providers or models can't be replayed, it has no valid signature.
+			if msg.CanReplayReasoning(message.ReasoningFormatAnthropic, a.providerOptions.config.ID) {
+				reasoningContent := msg.ReasoningContent()
 				thinkingBlock := anthropic.NewThinkingBlock(reasoningContent.Signature, reasoningContent.Thinking)
 				blocks = append(blocks, thinkingBlock)
 			}
@@ -218,6 +213,9 @@ func (a *anthropicClient) convertTools(tools []tools.BaseTool) []anthropic.ToolU
 }
 
 func (a *anthropicClient) finishReason(reason string) message.FinishReason {
+	if r, ok := mapStopReason(a.providerOptions.config.Normalize, reason); ok {
+		return r
+	}
 	switch reason {
 	case "end_turn":
 		return message.FinishReasonEndTurn
@@ -241,7 +239,28 @@ func (a *anthropicClient) isThinkingEnabled() bool {
 	return a.Model().CanReason && modelConfig.Think
 }
 
-func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, tools []anthropic.ToolUnionParam) anthropic.MessageNewParams {
+// canThink reports whether thinking can be enabled for the messages. When the
+// model is in the middle of a tool use turn, Anthropic requires the turn to
+// start with a signed thinking block, which is missing when the turn was
+// started by another provider or model or without thinking.
+func (a *anthropicClient) canThink(messages []message.Message) bool {
+	if !a.isThinkingEnabled() {
+		return false
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role == message.User {
+			return true
+		}
+		if msg.Role == message.Assistant && len(msg.ToolCalls()) > 0 && !msg.CanReplayReasoning(message.ReasoningFormatAnthropic, a.providerOptions.config.ID) {
+			slog.Debug("Thinking disabled for a tool use turn without a valid thinking block")
+			return false
+		}
+	}
+	return true
+}
+
+func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, tools []anthropic.ToolUnionParam, think bool) anthropic.MessageNewParams {
 	model := a.providerOptions.model(a.providerOptions.modelType)
 	var thinkingParam anthropic.ThinkingConfigParamUnion
 	cfg := config.Get()
@@ -255,7 +274,7 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 	if modelConfig.MaxTokens > 0 {
 		maxTokens = modelConfig.MaxTokens
 	}
-	if a.isThinkingEnabled() {
+	if think {
 		thinkingParam = anthropic.ThinkingConfigParamOfEnabled(int64(float64(maxTokens) * 0.8))
 		temperature = anthropic.Float(1)
 	}
@@ -278,12 +297,13 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 		})
 	}
 
-	systemBlocks = append(systemBlocks, anthropic.TextBlockParam{
-		Text: a.providerOptions.systemMessage,
-		CacheControl: anthropic.CacheControlEphemeralParam{
+	systemBlock := anthropic.TextBlockParam{Text: a.providerOptions.system()}
+	if !a.providerOptions.disableCache {
+		systemBlock.CacheControl = anthropic.CacheControlEphemeralParam{
 			Type: "ephemeral",
-		},
-	})
+		}
+	}
+	systemBlocks = append(systemBlocks, systemBlock)
 
 	return anthropic.MessageNewParams{
 		Model:       anthropic.Model(model.ID),
@@ -297,19 +317,15 @@ func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, to
 }
 
 func (a *anthropicClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (response *ProviderResponse, err error) {
-	// 온프레미스 모드 체크
-	if a.isOnPremise {
-		return a.sendOnPremise(ctx, messages, tools)
-	}
-	
 	attempts := 0
 	for {
 		attempts++
 		// Prepare messages on each attempt in case max_tokens was adjusted
-		preparedMessages := a.preparedMessages(a.convertMessages(messages), a.convertTools(tools))
+		think := a.canThink(messages)
+		preparedMessages := a.preparedMessages(a.convertMessages(messages), a.convertTools(tools), think)
 
 		var opts []option.RequestOption
-		if a.isThinkingEnabled() {
+		if think {
 			opts = append(opts, option.WithHeaderAdd("anthropic-beta", "interleaved-thinking-2025-05-14"))
 		}
 		anthropicResponse, err := a.client.Messages.New(
@@ -351,32 +367,17 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 }
 
 func (a *anthropicClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
-	// 온프레미스 모드 체크
-	if a.isOnPremise {
-		eventChan := make(chan ProviderEvent)
-		go func() {
-			defer close(eventChan)
-			response, err := a.sendOnPremise(ctx, messages, tools)
-			if err != nil {
-				eventChan <- ProviderEvent{Type: EventError, Error: err}
-				return
-			}
-			eventChan <- ProviderEvent{Type: EventContentDelta, Content: response.Content}
-			eventChan <- ProviderEvent{Type: EventComplete, Response: response}
-		}()
-		return eventChan
-	}
-	
 	attempts := 0
 	eventChan := make(chan ProviderEvent)
 	go func() {
 		for {
 			attempts++
 			// Prepare messages on each attempt in case max_tokens was adjusted
-			preparedMessages := a.preparedMessages(a.convertMessages(messages), a.convertTools(tools))
+			think := a.canThink(messages)
+			preparedMessages := a.preparedMessages(a.convertMessages(messages), a.convertTools(tools), think)
 
 			var opts []option.RequestOption
-			if a.isThinkingEnabled() {
+			if think {
 				opts = append(opts, option.WithHeaderAdd("anthropic-beta", "interleaved-thinking-2025-05-14"))
 			}
 
@@ -385,97 +386,7 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 				preparedMessages,
 				opts...,
 			)
-			accumulatedMessage := anthropic.Message{}
-
-			currentToolCallID := ""
-			for anthropicStream.Next() {
-				event := anthropicStream.Current()
-				err := accumulatedMessage.Accumulate(event)
-				if err != nil {
-					slog.Warn("Error accumulating message", "error", err)
-					continue
-				}
-
-				switch event := event.AsAny().(type) {
-				case anthropic.ContentBlockStartEvent:
-					switch event.ContentBlock.Type {
-					case "text":
-						eventChan <- ProviderEvent{Type: EventContentStart}
-					case "tool_use":
-						currentToolCallID = event.ContentBlock.ID
-						eventChan <- ProviderEvent{
-							Type: EventToolUseStart,
-							ToolCall: &message.ToolCall{
-								ID:       event.ContentBlock.ID,
-								Name:     event.ContentBlock.Name,
-								Finished: false,
-							},
-						}
-					}
-
-				case anthropic.ContentBlockDeltaEvent:
-					if event.Delta.Type == "thinking_delta" && event.Delta.Thinking != "" {
-						eventChan <- ProviderEvent{
-							Type:     EventThinkingDelta,
-							Thinking: event.Delta.Thinking,
-						}
-					} else if event.Delta.Type == "signature_delta" && event.Delta.Signature != "" {
-						eventChan <- ProviderEvent{
-							Type:      EventSignatureDelta,
-							Signature: event.Delta.Signature,
-						}
-					} else if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
-						eventChan <- ProviderEvent{
-							Type:    EventContentDelta,
-							Content: event.Delta.Text,
-						}
-					} else if event.Delta.Type == "input_json_delta" {
-						if currentToolCallID != "" {
-							eventChan <- ProviderEvent{
-								Type: EventToolUseDelta,
-								ToolCall: &message.ToolCall{
-									ID:       currentToolCallID,
-									Finished: false,
-									Input:    event.Delta.PartialJSON,
-								},
-							}
-						}
-					}
-				case anthropic.ContentBlockStopEvent:
-					if currentToolCallID != "" {
-						eventChan <- ProviderEvent{
-							Type: EventToolUseStop,
-							ToolCall: &message.ToolCall{
-								ID: currentToolCallID,
-							},
-						}
-						currentToolCallID = ""
-					} else {
-						eventChan <- ProviderEvent{Type: EventContentStop}
-					}
-
-				case anthropic.MessageStopEvent:
-					content := ""
-					for _, block := range accumulatedMessage.Content {
-						if text, ok := block.AsAny().(anthropic.TextBlock); ok {
-							content += text.Text
-						}
-					}
-
-					eventChan <- ProviderEvent{
-						Type: EventComplete,
-						Response: &ProviderResponse{
-							Content:      content,
-							ToolCalls:    a.toolCalls(accumulatedMessage),
-							Usage:        a.usage(accumulatedMessage),
-							FinishReason: a.finishReason(string(accumulatedMessage.StopReason)),
-						},
-						Content: content,
-					}
-				}
-			}
-
-			err := anthropicStream.Err()
+			err := a.forwardStream(anthropicStream, eventChan)
 			if err == nil || errors.Is(err, io.EOF) {
 				close(eventChan)
 				return
@@ -513,6 +424,108 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 	return eventChan
 }
 
+// forwardStream sends the events of the stream of a message to the
+// channel, and returns the error which ended the stream.
+func (a *anthropicClient) forwardStream(stream *ssestream.Stream[anthropic.MessageStreamEventUnion], eventChan chan<- ProviderEvent) error {
+	accumulatedMessage := anthropic.Message{}
+
+	currentToolCallID := ""
+	for stream.Next() {
+		event := stream.Current()
+		err := accumulatedMessage.Accumulate(event)
+		if err != nil {
+			slog.Warn("Error accumulating message", "error", err)
+			continue
+		}
+
+		switch event := event.AsAny().(type) {
+		case anthropic.MessageStartEvent, anthropic.MessageDeltaEvent:
+			eventChan <- ProviderEvent{
+				Type:     EventUsage,
+				Response: &ProviderResponse{Usage: a.usage(accumulatedMessage)},
+			}
+		case anthropic.ContentBlockStartEvent:
+			switch event.ContentBlock.Type {
+			case "text":
+				eventChan <- ProviderEvent{Type: EventContentStart}
+			case "tool_use":
+				currentToolCallID = event.ContentBlock.ID
+				eventChan <- ProviderEvent{
+					Type: EventToolUseStart,
+					ToolCall: &message.ToolCall{
+						ID:       event.ContentBlock.ID,
+						Name:     event.ContentBlock.Name,
+						Finished: false,
+					},
+				}
+			}
+
+		case anthropic.ContentBlockDeltaEvent:
+			if event.Delta.Type == "thinking_delta" && event.Delta.Thinking != "" {
+				eventChan <- ProviderEvent{
+					Type:            EventThinkingDelta,
+					Thinking:        event.Delta.Thinking,
+					ReasoningFormat: message.ReasoningFormatAnthropic,
+				}
+			} else if event.Delta.Type == "signature_delta" && event.Delta.Signature != "" {
+				eventChan <- ProviderEvent{
+					Type:            EventSignatureDelta,
+					Signature:       event.Delta.Signature,
+					ReasoningFormat: message.ReasoningFormatAnthropic,
+				}
+			} else if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				eventChan <- ProviderEvent{
+					Type:    EventContentDelta,
+					Content: event.Delta.Text,
+				}
+			} else if event.Delta.Type == "input_json_delta" {
+				if currentToolCallID != "" {
+					eventChan <- ProviderEvent{
+						Type: EventToolUseDelta,
+						ToolCall: &message.ToolCall{
+							ID:       currentToolCallID,
+							Finished: false,
+							Input:    event.Delta.PartialJSON,
+						},
+					}
+				}
+			}
+		case anthropic.ContentBlockStopEvent:
+			if currentToolCallID != "" {
+				eventChan <- ProviderEvent{
+					Type: EventToolUseStop,
+					ToolCall: &message.ToolCall{
+						ID: currentToolCallID,
+					},
+				}
+				currentToolCallID = ""
+			} else {
+				eventChan <- ProviderEvent{Type: EventContentStop}
+			}
+
+		case anthropic.MessageStopEvent:
+			content := ""
+			for _, block := range accumulatedMessage.Content {
+				if text, ok := block.AsAny().(anthropic.TextBlock); ok {
+					content += text.Text
+				}
+			}
+
+			eventChan <- ProviderEvent{
+				Type: EventComplete,
+				Response: &ProviderResponse{
+					Content:      content,
+					ToolCalls:    a.toolCalls(accumulatedMessage),
+					Usage:        a.usage(accumulatedMessage),
+					FinishReason: a.finishReason(string(accumulatedMessage.StopReason)),
+				},
+				Content: content,
+			}
+		}
+	}
+	return stream.Err()
+}
+
 func (a *anthropicClient) shouldRetry(attempts int, err error) (bool, int64, error) {
 	var apiErr *anthropic.Error
 	if !errors.As(err, &apiErr) {
@@ -524,14 +537,25 @@ func (a *anthropicClient) shouldRetry(attempts int, err error) (bool, int64, err
 	}
 
 	if apiErr.StatusCode == 401 {
-		a.providerOptions.apiKey, err = config.Get().Resolve(a.providerOptions.config.APIKey)
-		if err != nil {
-			return false, 0, fmt.Errorf("failed to resolve API key: %w", err)
+		changed, refreshErr := a.providerOptions.refreshAPIKey()
+		if refreshErr != nil {
+			return false, 0, refreshErr
+		}
+		if !changed {
+			return false, 0, err
 		}
 		a.client = createAnthropicClient(a.providerOptions, a.tp)
 		return true, 0, nil
 	}
 
+	// Some regions and models of Bedrock and Vertex AI reject the cache
+	// fields, the request is sent again without them.
+	if !a.providerOptions.disableCache && isPromptCacheError(apiErr) {
+		slog.Warn("Prompt caching is not supported, disabling it", "provider", a.providerOptions.config.ID, "model", a.Model().ID)
+		a.providerOptions.disableCache = true
+		return true, 0, nil
+	}
+
 	// Handle context limit exceeded error (400 Bad Request)
 	if apiErr.StatusCode == 400 {
 		if adjusted, ok := a.handleContextLimitError(apiErr); ok {
@@ -545,6 +569,11 @@ func (a *anthropicClient) shouldRetry(attempts int, err error) (bool, int64, err
 	if apiErr.StatusCode != 429 && apiErr.StatusCode != 529 && !isOverloaded {
 		return false, 0, err
 	}
+	if apiErr.StatusCode == 529 || strings.Contains(apiErr.Error(), "overloaded") {
+		if overloadedErr := overloadedRetryError(a.providerOptions.config.ID, attempts, err); overloadedErr != nil {
+			return false, 0, overloadedErr
+		}
+	}
 
 	retryMs := 0
 	retryAfterValues := apiErr.Response.Header.Values("Retry-After")
@@ -619,149 +648,3 @@ func (a *anthropicClient) usage(msg anthropic.Message) TokenUsage {
 func (a *anthropicClient) Model() catwalk.Model {
 	return a.providerOptions.model(a.providerOptions.modelType)
 }
-
-// sendOnPremise는 온프레미스 서버에 직접 HTTP 요청을 보냅니다
-func (a *anthropicClient) sendOnPremise(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (response *ProviderResponse, err error) {
-	// Panic 복구 안전장치
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("panic in sendOnPremise: %v", r)
-			slog.Error("Panic recovered in sendOnPremise", "error", r)
-		}
-	}()
-	
-	// API 키 검증
-	if a.providerOptions.apiKey == "" {
-		return nil, fmt.Errorf("API key is required for on-premise authentication")
-	}
-	// 간단한 메시지 변환 (텍스트만 지원)
-	var anthropicMessages []map[string]string
-	var systemMessage string
-	
-	for _, msg := range messages {
-		switch msg.Role {
-		case message.System:
-			systemMessage = msg.Content().Text
-		case message.User, message.Assistant:
-			anthropicMessages = append(anthropicMessages, map[string]string{
-				"role":    string(msg.Role),
-				"content": msg.Content().Text,
-			})
-		}
-	}
-	
-	// max_tokens 결정 (우선순위: adjustedMaxTokens > providerOptions > 최대값)
-	maxTokens := 8192 // Claude 3.5 Sonnet 최대 출력 토큰
-	if a.adjustedMaxTokens > 0 {
-		maxTokens = a.adjustedMaxTokens
-	} else if a.providerOptions.maxTokens > 0 {
-		maxTokens = int(a.providerOptions.maxTokens)
-	}
-	
-	// 요청 구성 (회사 온프레미스 API 형식 정확히 일치)
-	request := map[string]interface{}{
-		"model":      a.Model().ID, // 모델 ID 사용
-		"max_tokens": maxTokens,    // 최대 8192 토큰
-		"stream":     false,        // 스트리밍 비활성화
-		"messages":   anthropicMessages,
-	}
-	if systemMessage != "" {
-		request["system"] = systemMessage
-	}
-	
-	requestBody, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-	
-	// HTTP 요청 생성 (trailing slash 안전 처리)
-	baseURL := strings.TrimRight(a.providerOptions.baseURL, "/")
-	url := baseURL + "/messages"
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", a.providerOptions.apiKey)
-	
-	slog.Info("OnPremise sending request", "url", url, "model", a.Model().ID)
-	
-	// Context가 이미 취소되었는지 확인
-	if ctx.Err() != nil {
-		return nil, fmt.Errorf("request cancelled before execution: %w", ctx.Err())
-	}
-	
-	// HTTP 클라이언트 설정 (Context는 Request에 이미 embedded됨)
-	client := &http.Client{Timeout: 60 * time.Second}
-	
-	slog.Debug("OnPremise request starting", "url", url, "model", a.Model().ID)
-	
-	// HTTP 요청 실행 (Context 처리 자동으로 됨)
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		slog.Error("OnPremise request failed", "error", err, "url", url)
-		return nil, fmt.Errorf("network request failed to %s: %w", url, err)
-	}
-	defer resp.Body.Close()
-	
-	// 응답 읽기
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-	
-	if resp.StatusCode != http.StatusOK {
-		errorMsg := string(body)
-		slog.Error("OnPremise API error", "status", resp.StatusCode, "body", errorMsg)
-		
-		switch resp.StatusCode {
-		case http.StatusUnauthorized:
-			return nil, fmt.Errorf("authentication failed (401): check CRUSH_ANTHROPIC_API_KEY")
-		case http.StatusForbidden:
-			return nil, fmt.Errorf("access forbidden (403): insufficient permissions")
-		case http.StatusNotFound:
-			return nil, fmt.Errorf("endpoint not found (404): check CRUSH_ANTHROPIC_BASE_URL")
-		case http.StatusInternalServerError:
-			return nil, fmt.Errorf("server error (500): on-premise service issue")
-		default:
-			return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, errorMsg)
-		}
-	}
-	
-	// 응답 파싱
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-	
-	// 콘텐츠 추출
-	var responseText string
-	if content, ok := result["content"].([]interface{}); ok && len(content) > 0 {
-		if textBlock, ok := content[0].(map[string]interface{}); ok {
-			if text, ok := textBlock["text"].(string); ok {
-				responseText = text
-			}
-		}
-	}
-	
-	// Usage 정보 추출 (있으면)
-	var inputTokens, outputTokens int64
-	if usage, ok := result["usage"].(map[string]interface{}); ok {
-		if input, ok := usage["input_tokens"].(float64); ok {
-			inputTokens = int64(input)
-		}
-		if output, ok := usage["output_tokens"].(float64); ok {
-			outputTokens = int64(output)
-		}
-	}
-	
-	return &ProviderResponse{
-		Content: responseText,
-		Usage: TokenUsage{
-			InputTokens:  inputTokens,
-			OutputTokens: outputTokens,
-		},
-		FinishReason: message.FinishReasonEndTurn,
-	}, nil
-}
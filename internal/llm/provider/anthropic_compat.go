@@ -0,0 +1,469 @@
+package provider
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/network"
+)
+
+const (
+	// compatMessagesPath is the path of the messages endpoint of the
+	// gateways not configuring it.
+	compatMessagesPath = "/messages"
+	// compatMaxTokens is the max_tokens of the requests of the models
+	// without a default, which the messages API requires.
+	compatMaxTokens = 8192
+
+	defaultCompatConnectTimeout = 10 * time.Second
+	defaultProxyTotalTimeout    = 60 * time.Second
+)
+
+// anthropicCompatClient sends the requests of the messages API of Anthropic
+// over plain HTTP, to the gateways implementing it like LiteLLM, Cloudflare
+// AI Gateway or in-house proxies. It converts the messages and responses
+// like the SDK client does.
+type anthropicCompatClient struct {
+	*anthropicClient
+	httpClient *http.Client
+	// proxy is set by the proxy compat mode: the requests aren't streamed
+	// and the API key is sent as is in the Authorization header.
+	proxy bool
+}
+
+type AnthropicCompatClient ProviderClient
+
+func newAnthropicCompatClient(opts providerClientOptions) AnthropicCompatClient {
+	proxy := opts.config.CompatMode == config.CompatModeProxy
+	opts.disableCache = promptCacheDisabled(opts, AnthropicClientTypeNormal)
+	// The in-house gateways may reject the cache_control fields, they are
+	// only sent when prompt_cache turns them on.
+	if proxy && opts.config.PromptCache == nil {
+		opts.disableCache = true
+	}
+	return &anthropicCompatClient{
+		anthropicClient: &anthropicClient{providerOptions: opts, tp: AnthropicClientTypeNormal},
+		httpClient:      newCompatHTTPClient(opts.config.Timeouts, proxy),
+		proxy:           proxy,
+	}
+}
+
+// compatError is the error status answered by a gateway.
+type compatError struct {
+	Provider   string
+	StatusCode int
+	Header     http.Header
+	Message    string
+}
+
+func (e *compatError) Error() string {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return fmt.Sprintf("authentication failed (401): check the API key of the %s provider", e.Provider)
+	case http.StatusForbidden:
+		return "access forbidden (403): insufficient permissions"
+	case http.StatusNotFound:
+		return fmt.Sprintf("endpoint not found (404): check the base URL of the %s provider", e.Provider)
+	case http.StatusInternalServerError:
+		return "server error (500): gateway service issue"
+	}
+	return fmt.Sprintf("HTTP error %d: %s", e.StatusCode, e.Message)
+}
+
+// newCompatHTTPClient returns the client of the requests to a gateway, using
+// the proxy and TLS settings of crush and the timeouts of the provider. The
+// total timeout of the requests defaults to a minute when they aren't
+// streamed.
+func newCompatHTTPClient(timeouts *config.GatewayTimeouts, proxy bool) *http.Client {
+	if timeouts == nil {
+		timeouts = &config.GatewayTimeouts{}
+	}
+	connect := cmp.Or(time.Duration(timeouts.Connect)*time.Second, defaultCompatConnectTimeout)
+	transport := network.Transport()
+	transport.DialContext = (&net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connect
+	transport.ResponseHeaderTimeout = time.Duration(timeouts.ResponseHeader) * time.Second
+	total := time.Duration(timeouts.Total) * time.Second
+	if proxy {
+		total = cmp.Or(total, defaultProxyTotalTimeout)
+	}
+	return &http.Client{Transport: transport, Timeout: total}
+}
+
+func (c *anthropicCompatClient) paths() config.GatewayPaths {
+	if paths := c.providerOptions.config.Paths; paths != nil {
+		return *paths
+	}
+	return config.GatewayPaths{}
+}
+
+// endpoint returns the URL of the endpoint of the gateway at the path, with
+// its {model} placeholder replaced by the model of the client.
+func (c *anthropicCompatClient) endpoint(path string) string {
+	path = strings.ReplaceAll(path, "{model}", url.PathEscape(c.Model().ID))
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return strings.TrimRight(c.providerOptions.baseURL, "/") + path
+}
+
+// newRequest returns a POST request of the body to the gateway,
+// authenticated by the API key unless the extra headers set their own
+// authentication headers. The interleaved thinking beta is requested when
+// the model thinks, like the SDK requests do.
+func (c *anthropicCompatClient) newRequest(ctx context.Context, endpoint string, body []byte, think bool) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.proxy {
+		req.Header.Set("Authorization", c.providerOptions.apiKey)
+	} else {
+		req.Header.Set("x-api-key", c.providerOptions.apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	}
+	if think {
+		req.Header.Set("anthropic-beta", "interleaved-thinking-2025-05-14")
+	}
+	for key, value := range c.providerOptions.extraHeaders {
+		req.Header.Set(key, value)
+	}
+	return req, nil
+}
+
+// request returns the body of the messages request, as the SDK would send
+// it, and whether the model thinks.
+func (c *anthropicCompatClient) request(messages []message.Message, tools []tools.BaseTool, stream bool) (map[string]any, bool, error) {
+	think := c.canThink(messages)
+	params := c.preparedMessages(c.convertMessages(messages), c.convertTools(tools), think)
+	if params.MaxTokens <= 0 {
+		params.MaxTokens = compatMaxTokens
+	}
+	params.System = slices.DeleteFunc(params.System, func(block anthropic.TextBlockParam) bool {
+		return block.Text == ""
+	})
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var request map[string]any
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, false, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	request["stream"] = stream
+	return request, think, nil
+}
+
+// do sends the request and returns its response, or a *compatError when its
+// status isn't OK.
+func (c *anthropicCompatClient) do(ctx context.Context, endpoint string, body []byte, think bool) (*http.Response, error) {
+	req, err := c.newRequest(ctx, endpoint, body, think)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("network request failed to %s: %w", endpoint, err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	msg := errorMessage(c.providerOptions.config.Normalize, respBody)
+	slog.Error("Gateway API error", "provider", c.providerOptions.config.ID, "status", resp.StatusCode, "body", msg)
+	return nil, &compatError{Provider: c.providerOptions.config.ID, StatusCode: resp.StatusCode, Header: resp.Header, Message: msg}
+}
+
+// retry sends the request again while shouldRetry tells so.
+func (c *anthropicCompatClient) retry(ctx context.Context, send func() error) error {
+	for attempts := 1; ; attempts++ {
+		err := send()
+		if err == nil {
+			return nil
+		}
+		retry, after, retryErr := c.shouldRetry(attempts, err)
+		if !retry {
+			return retryErr
+		}
+		slog.Warn("Retrying the gateway request", "attempt", attempts, "max_retries", maxRetries, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(after) * time.Millisecond):
+		}
+	}
+}
+
+func (c *anthropicCompatClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	response, _, err := c.sendMessage(ctx, messages, tools)
+	return response, err
+}
+
+// sendMessage sends a non-streamed request and returns its response, along
+// with the message answered.
+func (c *anthropicCompatClient) sendMessage(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, anthropic.Message, error) {
+	var result anthropic.Message
+	if c.providerOptions.apiKey == "" && c.proxy {
+		return nil, result, fmt.Errorf("API key is required for gateway authentication")
+	}
+	request, think, err := c.request(messages, tools, false)
+	if err != nil {
+		return nil, result, err
+	}
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, result, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := c.endpoint(cmp.Or(c.paths().Messages, compatMessagesPath))
+	var body []byte
+	err = c.retry(ctx, func() error {
+		slog.Debug("Gateway request starting", "url", endpoint, "model", c.Model().ID)
+		resp, err := c.do(ctx, endpoint, requestBody, think)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, result, err
+	}
+
+	body, err = transformResponse(ctx, c.providerOptions.config.Normalize, body)
+	if err != nil {
+		return nil, result, err
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, result, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var content string
+	for _, block := range result.Content {
+		if text, ok := block.AsAny().(anthropic.TextBlock); ok {
+			content += text.Text
+		}
+	}
+	toolCalls := c.toolCalls(result)
+	usage := c.usage(result)
+	if usage.InputTokens == 0 {
+		usage.InputTokens = c.countTokens(ctx, request)
+	}
+	// Some gateways leave out the stop reason.
+	finishReason := message.FinishReasonEndTurn
+	if result.StopReason != "" {
+		finishReason = c.finishReason(string(result.StopReason))
+	} else if len(toolCalls) > 0 {
+		finishReason = message.FinishReasonToolUse
+	}
+	return &ProviderResponse{
+		Content:      content,
+		ToolCalls:    toolCalls,
+		Usage:        usage,
+		FinishReason: finishReason,
+	}, result, nil
+}
+
+func (c *anthropicCompatClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	eventChan := make(chan ProviderEvent)
+	go func() {
+		defer close(eventChan)
+		var err error
+		if c.proxy {
+			err = c.streamFromSend(ctx, messages, tools, eventChan)
+		} else {
+			err = c.streamMessage(ctx, messages, tools, eventChan)
+		}
+		if err != nil {
+			eventChan <- ProviderEvent{Type: EventError, Error: err}
+		}
+	}()
+	return eventChan
+}
+
+// streamFromSend sends a non-streamed request and the events of its
+// response, the thinking blocks like the SDK stream does.
+func (c *anthropicCompatClient) streamFromSend(ctx context.Context, messages []message.Message, tools []tools.BaseTool, eventChan chan<- ProviderEvent) error {
+	response, result, err := c.sendMessage(ctx, messages, tools)
+	if err != nil {
+		return err
+	}
+	for _, block := range result.Content {
+		if thinking, ok := block.AsAny().(anthropic.ThinkingBlock); ok {
+			eventChan <- ProviderEvent{Type: EventThinkingDelta, Thinking: thinking.Thinking, ReasoningFormat: message.ReasoningFormatAnthropic}
+			if thinking.Signature != "" {
+				eventChan <- ProviderEvent{Type: EventSignatureDelta, Signature: thinking.Signature, ReasoningFormat: message.ReasoningFormatAnthropic}
+			}
+		}
+	}
+	eventChan <- ProviderEvent{Type: EventContentDelta, Content: response.Content}
+	eventChan <- ProviderEvent{Type: EventComplete, Response: response}
+	return nil
+}
+
+// streamMessage sends a streamed request and forwards its server-sent
+// events like the SDK client does. Only the requests failing before the
+// stream starts are retried.
+func (c *anthropicCompatClient) streamMessage(ctx context.Context, messages []message.Message, tools []tools.BaseTool, eventChan chan<- ProviderEvent) error {
+	request, think, err := c.request(messages, tools, true)
+	if err != nil {
+		return err
+	}
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := c.endpoint(cmp.Or(c.paths().Messages, compatMessagesPath))
+	var resp *http.Response
+	err = c.retry(ctx, func() error {
+		slog.Debug("Gateway stream starting", "url", endpoint, "model", c.Model().ID)
+		resp, err = c.do(ctx, endpoint, requestBody, think)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	stream := ssestream.NewStream[anthropic.MessageStreamEventUnion](typedEventDecoder{ssestream.NewDecoder(resp)}, nil)
+	defer stream.Close()
+	if err := c.forwardStream(stream, eventChan); err != nil && !errors.Is(err, io.EOF) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// typedEventDecoder takes the type of the events without an event field
+// from the type of their data, some gateways only sending data lines.
+type typedEventDecoder struct {
+	ssestream.Decoder
+}
+
+func (d typedEventDecoder) Event() ssestream.Event {
+	event := d.Decoder.Event()
+	if event.Type == "" {
+		var data struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(event.Data, &data) == nil {
+			event.Type = data.Type
+		}
+	}
+	return event
+}
+
+// shouldRetry tells whether the request to the gateway is sent again, and
+// after how many milliseconds, like anthropicClient.shouldRetry does for the
+// SDK errors. The requests which failed to connect are retried too, they
+// never reached the gateway.
+func (c *anthropicCompatClient) shouldRetry(attempts int, err error) (bool, int64, error) {
+	if attempts > maxRetries {
+		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true, compatBackoff(attempts, nil), nil
+	}
+	var apiErr *compatError
+	if !errors.As(err, &apiErr) {
+		return false, 0, err
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusUnauthorized:
+		changed, refreshErr := c.providerOptions.refreshAPIKey()
+		if refreshErr != nil {
+			return false, 0, refreshErr
+		}
+		return changed, 0, err
+	case apiErr.StatusCode == 529 || strings.Contains(apiErr.Message, "overloaded"):
+		if overloadedErr := overloadedRetryError(c.providerOptions.config.ID, attempts, err); overloadedErr != nil {
+			return false, 0, overloadedErr
+		}
+	case apiErr.StatusCode == http.StatusTooManyRequests, apiErr.StatusCode >= 500:
+	default:
+		return false, 0, err
+	}
+	return true, compatBackoff(attempts, apiErr.Header), nil
+}
+
+// compatBackoff returns the milliseconds to wait before the next attempt,
+// the Retry-After header of the answer, in seconds or as a date, when there
+// is one.
+func compatBackoff(attempts int, header http.Header) int64 {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return int64(seconds) * 1000
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return max(time.Until(date).Milliseconds(), 0)
+		}
+	}
+	backoffMs := 2000 * (1 << (attempts - 1))
+	jitterMs := int(float64(backoffMs) * 0.2)
+	return int64(backoffMs + jitterMs)
+}
+
+// countTokens asks the count_tokens endpoint of the gateway for the input
+// tokens of the request. It returns 0 when the endpoint isn't configured or
+// fails, the usage being only informative.
+func (c *anthropicCompatClient) countTokens(ctx context.Context, request map[string]any) int64 {
+	path := c.paths().CountTokens
+	if path == "" {
+		return 0
+	}
+	countRequest := map[string]any{
+		"model":    request["model"],
+		"messages": request["messages"],
+	}
+	for _, key := range []string{"system", "tools", "thinking"} {
+		if value, ok := request[key]; ok {
+			countRequest[key] = value
+		}
+	}
+	body, err := json.Marshal(countRequest)
+	if err != nil {
+		return 0
+	}
+	endpoint := c.endpoint(path)
+	resp, err := c.do(ctx, endpoint, body, false)
+	if err != nil {
+		slog.Debug("Failed to count the tokens of the request", "url", endpoint, "error", err)
+		return 0
+	}
+	defer resp.Body.Close()
+	var result struct {
+		InputTokens int64 `json:"input_tokens"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		slog.Debug("Failed to count the tokens of the request", "url", endpoint, "error", err)
+		return 0
+	}
+	return result.InputTokens
+}
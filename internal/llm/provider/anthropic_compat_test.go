@@ -0,0 +1,363 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicCompatProxy(t *testing.T) {
+	t.Parallel()
+
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		require.Equal(t, "gateway-key", r.Header.Get("Authorization"))
+		require.Equal(t, "crush", r.Header.Get("X-Client"))
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "claude-sonnet-4", req["model"])
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/deployments/claude-sonnet-4/invoke":
+			w.Write([]byte(`{"content":[{"type":"text","text":"Hello"}],"stop_reason":"end_turn"}`))
+		case "/tokens":
+			require.NotContains(t, req, "max_tokens")
+			w.Write([]byte(`{"input_tokens":42}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newAnthropicCompatClient(providerClientOptions{
+		baseURL: server.URL + "/",
+		config: config.ProviderConfig{
+			CompatMode: config.CompatModeProxy,
+			Paths:      &config.GatewayPaths{Messages: "/deployments/{model}/invoke", CountTokens: "tokens"},
+		},
+		apiKey:       "gateway-key",
+		extraHeaders: map[string]string{"X-Client": "crush"},
+		modelType:    config.SelectedModelTypeLarge,
+		model: func(config.SelectedModelType) catwalk.Model {
+			return catwalk.Model{ID: "claude-sonnet-4"}
+		},
+	})
+
+	response, err := client.send(context.Background(), []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Hi"}},
+	}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Hello", response.Content)
+	require.Equal(t, int64(42), response.Usage.InputTokens)
+	require.Equal(t, []string{"/deployments/claude-sonnet-4/invoke", "/tokens"}, requested)
+}
+
+func TestAnthropicCompatProxyRetry(t *testing.T) {
+	t.Parallel()
+
+	statuses := []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusOK}
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[attempts]
+		attempts++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(`{"content":[{"type":"text","text":"Hello"}],"usage":{"input_tokens":3,"output_tokens":1}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := newAnthropicCompatClient(providerClientOptions{
+		baseURL:   server.URL,
+		config:    config.ProviderConfig{CompatMode: config.CompatModeProxy},
+		apiKey:    "gateway-key",
+		modelType: config.SelectedModelTypeLarge,
+		model: func(config.SelectedModelType) catwalk.Model {
+			return catwalk.Model{ID: "claude-sonnet-4"}
+		},
+	})
+
+	response, err := client.send(context.Background(), []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Hi"}},
+	}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "Hello", response.Content)
+	require.Equal(t, 3, attempts)
+
+	statuses, attempts = []int{http.StatusBadRequest}, 0
+	_, err = client.send(context.Background(), []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Hi"}},
+	}}, nil)
+	var apiErr *compatError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Equal(t, 1, attempts)
+}
+
+func TestCompatBackoff(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, int64(2400), compatBackoff(1, nil))
+	require.Equal(t, int64(4800), compatBackoff(2, http.Header{}))
+	require.Equal(t, int64(7000), compatBackoff(1, http.Header{"Retry-After": {"7"}}))
+	require.Equal(t, int64(0), compatBackoff(1, http.Header{"Retry-After": {"Mon, 02 Jan 2006 15:04:05 GMT"}}))
+	later := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	require.InDelta(t, 60_000, compatBackoff(1, http.Header{"Retry-After": {later}}), 2_000)
+}
+
+func TestNewCompatHTTPClient(t *testing.T) {
+	t.Parallel()
+
+	client := newCompatHTTPClient(nil, true)
+	require.Equal(t, defaultProxyTotalTimeout, client.Timeout)
+	require.Zero(t, client.Transport.(*http.Transport).ResponseHeaderTimeout)
+
+	client = newCompatHTTPClient(&config.GatewayTimeouts{Connect: 3, ResponseHeader: 120, Total: 300}, true)
+	require.Equal(t, 300*time.Second, client.Timeout)
+	require.Equal(t, 3*time.Second, client.Transport.(*http.Transport).TLSHandshakeTimeout)
+	require.Equal(t, 120*time.Second, client.Transport.(*http.Transport).ResponseHeaderTimeout)
+
+	client = newCompatHTTPClient(nil, false)
+	require.Zero(t, client.Timeout)
+}
+
+type viewTool struct{}
+
+func (viewTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{Name: "view", Description: "Views a file.", Parameters: map[string]any{"path": map[string]any{"type": "string"}}}
+}
+func (viewTool) Name() string { return "view" }
+func (viewTool) Run(context.Context, tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.ToolResponse{}, nil
+}
+
+func TestAnthropicCompatProxyContentBlocks(t *testing.T) {
+	t.Parallel()
+
+	var request struct {
+		Stream   bool `json:"stream"`
+		Messages []struct {
+			Role    string           `json:"role"`
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+		Tools []map[string]any `json:"tools"`
+	}
+	var raw string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		raw = string(body)
+		require.NoError(t, json.Unmarshal(body, &request))
+		w.Write([]byte(`{"content":[{"type":"text","text":"Let me look."},{"type":"tool_use","id":"call_2","name":"view","input":{"path":"main.go"}}],"usage":{"input_tokens":10,"output_tokens":5}}`))
+	}))
+	defer server.Close()
+
+	client := newAnthropicCompatClient(providerClientOptions{
+		baseURL:       server.URL,
+		config:        config.ProviderConfig{CompatMode: config.CompatModeProxy},
+		apiKey:        "gateway-key",
+		systemMessage: "You are a coder.",
+		modelType:     config.SelectedModelTypeLarge,
+		model: func(config.SelectedModelType) catwalk.Model {
+			return catwalk.Model{ID: "claude-sonnet-4", DefaultMaxTokens: 1024, SupportsImages: true}
+		},
+	})
+
+	response, err := client.send(context.Background(), []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{
+			message.TextContent{Text: "What is in this screenshot?"},
+			message.BinaryContent{Path: "shot.png", MIMEType: "image/png", Data: []byte("png")},
+		}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.ToolCall{ID: "call_1", Name: "view", Input: `{"path":"README.md"}`, Type: "tool_use", Finished: true},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "call_1", Name: "view", Content: "# Crush"},
+		}},
+	}, []tools.BaseTool{viewTool{}})
+	require.NoError(t, err)
+
+	require.False(t, request.Stream)
+	require.NotContains(t, raw, "cache_control")
+	require.Len(t, request.Messages, 3)
+	require.Equal(t, "text", request.Messages[0].Content[0]["type"])
+	require.Equal(t, "image", request.Messages[0].Content[1]["type"])
+	require.Equal(t, "tool_use", request.Messages[1].Content[0]["type"])
+	require.Equal(t, "tool_result", request.Messages[2].Content[0]["type"])
+	require.Equal(t, "call_1", request.Messages[2].Content[0]["tool_use_id"])
+	require.Len(t, request.Tools, 1)
+	require.Equal(t, "view", request.Tools[0]["name"])
+
+	require.Equal(t, "Let me look.", response.Content)
+	require.Equal(t, message.FinishReasonToolUse, response.FinishReason)
+	require.Len(t, response.ToolCalls, 1)
+	require.Equal(t, "call_2", response.ToolCalls[0].ID)
+	require.JSONEq(t, `{"path":"main.go"}`, response.ToolCalls[0].Input)
+	require.Equal(t, int64(10), response.Usage.InputTokens)
+}
+
+func TestAnthropicCompatProxyStreamThinking(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"content":[{"type":"thinking","thinking":"The user greets me.","signature":"sig"},{"type":"text","text":"Hello"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	client := newAnthropicCompatClient(providerClientOptions{
+		baseURL:   server.URL,
+		config:    config.ProviderConfig{CompatMode: config.CompatModeProxy},
+		apiKey:    "gateway-key",
+		modelType: config.SelectedModelTypeLarge,
+		model: func(config.SelectedModelType) catwalk.Model {
+			return catwalk.Model{ID: "claude-sonnet-4", DefaultMaxTokens: 1024}
+		},
+	})
+
+	var events []ProviderEvent
+	for event := range client.stream(context.Background(), []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Hi"}},
+	}}, nil) {
+		events = append(events, event)
+	}
+	require.Len(t, events, 4)
+	require.Equal(t, EventThinkingDelta, events[0].Type)
+	require.Equal(t, "The user greets me.", events[0].Thinking)
+	require.Equal(t, message.ReasoningFormatAnthropic, events[0].ReasoningFormat)
+	require.Equal(t, EventSignatureDelta, events[1].Type)
+	require.Equal(t, "sig", events[1].Signature)
+	require.Equal(t, EventContentDelta, events[2].Type)
+	require.Equal(t, "Hello", events[2].Content)
+	require.Equal(t, EventComplete, events[3].Type)
+	require.Equal(t, message.FinishReasonEndTurn, events[3].Response.FinishReason)
+}
+
+func TestAnthropicCompatStream(t *testing.T) {
+	t.Parallel()
+
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4","content":[],"usage":{"input_tokens":12,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"look."}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"call_1","name":"view","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\":\"main.go\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}`,
+		`{"type":"message_stop"}`,
+	}
+	for _, tt := range []struct {
+		name       string
+		eventLines bool
+	}{
+		{name: "event lines", eventLines: true},
+		{name: "data lines only"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/v1/messages", r.URL.Path)
+				require.Equal(t, "compat-key", r.Header.Get("x-api-key"))
+				require.Equal(t, "2023-06-01", r.Header.Get("anthropic-version"))
+				var req map[string]any
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				require.Equal(t, true, req["stream"])
+
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, event := range events {
+					if tt.eventLines {
+						var data struct {
+							Type string `json:"type"`
+						}
+						require.NoError(t, json.Unmarshal([]byte(event), &data))
+						fmt.Fprintf(w, "event: %s\n", data.Type)
+					}
+					fmt.Fprintf(w, "data: %s\n\n", event)
+				}
+			}))
+			defer server.Close()
+
+			client := newAnthropicCompatClient(providerClientOptions{
+				baseURL:   server.URL + "/v1",
+				config:    config.ProviderConfig{ID: "gateway", Type: config.TypeAnthropicCompat},
+				apiKey:    "compat-key",
+				modelType: config.SelectedModelTypeLarge,
+				model: func(config.SelectedModelType) catwalk.Model {
+					return catwalk.Model{ID: "claude-sonnet-4", DefaultMaxTokens: 1024}
+				},
+			})
+
+			var content string
+			var complete *ProviderResponse
+			for event := range client.stream(context.Background(), []message.Message{{
+				Role:  message.User,
+				Parts: []message.ContentPart{message.TextContent{Text: "Hi"}},
+			}}, []tools.BaseTool{viewTool{}}) {
+				require.NotEqual(t, EventError, event.Type, event.Error)
+				switch event.Type {
+				case EventContentDelta:
+					content += event.Content
+				case EventComplete:
+					complete = event.Response
+				}
+			}
+			require.Equal(t, "Let me look.", content)
+			require.NotNil(t, complete)
+			require.Equal(t, message.FinishReasonToolUse, complete.FinishReason)
+			require.Len(t, complete.ToolCalls, 1)
+			require.Equal(t, "view", complete.ToolCalls[0].Name)
+			require.JSONEq(t, `{"path":"main.go"}`, complete.ToolCalls[0].Input)
+			require.Equal(t, int64(12), complete.Usage.InputTokens)
+			require.Equal(t, int64(9), complete.Usage.OutputTokens)
+		})
+	}
+}
+
+func TestAnthropicCompatStreamError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := newAnthropicCompatClient(providerClientOptions{
+		baseURL:   server.URL,
+		config:    config.ProviderConfig{ID: "gateway", Type: config.TypeAnthropicCompat},
+		apiKey:    "compat-key",
+		modelType: config.SelectedModelTypeLarge,
+		model: func(config.SelectedModelType) catwalk.Model {
+			return catwalk.Model{ID: "claude-sonnet-4", DefaultMaxTokens: 1024}
+		},
+	})
+
+	var err error
+	for event := range client.stream(context.Background(), []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Hi"}},
+	}}, nil) {
+		if event.Type == EventError {
+			err = event.Error
+		}
+	}
+	require.EqualError(t, err, "endpoint not found (404): check the base URL of the gateway provider")
+}
@@ -32,27 +32,26 @@ func newBedrockClient(opts providerClientOptions) BedrockClient {
 		}
 	}
 
+	baseModel := opts.model
 	opts.model = func(modelType config.SelectedModelType) catwalk.Model {
-		model := config.Get().GetModelByType(modelType)
+		model := baseModel(modelType)
 
 		// Prefix the model name with region
 		regionPrefix := region[:2]
 		modelName := model.ID
 		model.ID = fmt.Sprintf("%s.%s", regionPrefix, modelName)
-		return *model
+		return model
 	}
 
 	model := opts.model(opts.modelType)
 
 	// Determine which provider to use based on the model
 	if strings.Contains(string(model.ID), "anthropic") {
-		// Create Anthropic client with Bedrock configuration
-		anthropicOpts := opts
-		// TODO: later find a way to check if the AWS account has caching enabled
-		opts.disableCache = true // Disable cache for Bedrock
+		// Create Anthropic client with Bedrock configuration, prompt caching
+		// is enabled for the models supporting it.
 		return &bedrockClient{
 			providerOptions: opts,
-			childProvider:   newAnthropicClient(anthropicOpts, AnthropicClientTypeBedrock),
+			childProvider:   newAnthropicClient(opts, AnthropicClientTypeBedrock),
 		}
 	}
 
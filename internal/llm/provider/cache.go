@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// bedrockCacheModels are the Claude models supporting prompt caching on
+// Bedrock. Older models reject the cache_control fields.
+var bedrockCacheModels = []string{
+	"claude-3-5-haiku",
+	"claude-3-7-sonnet",
+	"claude-sonnet-4",
+	"claude-opus-4",
+	"claude-haiku-4",
+}
+
+// vertexCacheModels are the Claude models supporting prompt caching on
+// Vertex AI.
+var vertexCacheModels = []string{
+	"claude-3-haiku",
+	"claude-3-opus",
+	"claude-3-5-haiku",
+	"claude-3-5-sonnet-v2",
+	"claude-3-7-sonnet",
+	"claude-sonnet-4",
+	"claude-opus-4",
+	"claude-haiku-4",
+}
+
+// promptCacheSupported reports whether the deployment of the model accepts
+// cache_control fields. The direct API supports them on every model, Bedrock
+// and Vertex AI only on some of them.
+func promptCacheSupported(tp AnthropicClientType, modelID string) bool {
+	var models []string
+	switch tp {
+	case AnthropicClientTypeBedrock:
+		models = bedrockCacheModels
+	case AnthropicClientTypeVertex:
+		models = vertexCacheModels
+	default:
+		return true
+	}
+	return slices.ContainsFunc(models, func(m string) bool {
+		return strings.Contains(modelID, m)
+	})
+}
+
+// promptCacheDisabled reports whether prompt caching must be left off for
+// the client: when it was disabled by an option, turned off in the provider
+// configuration, or isn't supported by the deployment of the model.
+func promptCacheDisabled(opts providerClientOptions, tp AnthropicClientType) bool {
+	if opts.disableCache {
+		return true
+	}
+	if opts.config.PromptCache != nil {
+		return !*opts.config.PromptCache
+	}
+	return !promptCacheSupported(tp, opts.model(opts.modelType).ID)
+}
+
+// isPromptCacheError reports whether a request was rejected because the
+// region or the model doesn't support prompt caching, so it can be sent
+// again without cache_control fields.
+func isPromptCacheError(apiErr *anthropic.Error) bool {
+	if apiErr.StatusCode != 400 {
+		return false
+	}
+	msg := strings.ToLower(apiErr.Error())
+	return strings.Contains(msg, "cache_control") || strings.Contains(msg, "prompt caching")
+}
@@ -0,0 +1,28 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptCacheSupported(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tp    AnthropicClientType
+		model string
+		want  bool
+	}{
+		{AnthropicClientTypeNormal, "claude-3-haiku-20240307", true},
+		{AnthropicClientTypeBedrock, "us.anthropic.claude-sonnet-4-20250514-v1:0", true},
+		{AnthropicClientTypeBedrock, "us.anthropic.claude-3-7-sonnet-20250219-v1:0", true},
+		{AnthropicClientTypeBedrock, "us.anthropic.claude-3-haiku-20240307-v1:0", false},
+		{AnthropicClientTypeVertex, "claude-3-5-sonnet-v2@20241022", true},
+		{AnthropicClientTypeVertex, "claude-3-5-sonnet@20240620", false},
+		{AnthropicClientTypeVertex, "claude-opus-4-1@20250805", true},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, promptCacheSupported(tt.tp, tt.model), "%s %s", tt.tp, tt.model)
+	}
+}
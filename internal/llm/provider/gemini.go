@@ -62,6 +62,10 @@ func (g *geminiClient) convertMessages(messages []message.Message) []*genai.Cont
 			var parts []*genai.Part
 			parts = append(parts, &genai.Part{Text: msg.Content().String()})
 			for _, binaryContent := range msg.BinaryContent() {
+				if binaryContent.IsText() {
+					parts = append(parts, &genai.Part{Text: binaryContent.Text()})
+					continue
+				}
 				imageFormat := strings.Split(binaryContent.MIMEType, "/")
 				parts = append(parts, &genai.Part{InlineData: &genai.Blob{
 					MIMEType: imageFormat[1],
@@ -184,7 +188,7 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 	if modelConfig.MaxTokens > 0 {
 		maxTokens = modelConfig.MaxTokens
 	}
-	systemMessage := g.providerOptions.systemMessage
+	systemMessage := g.providerOptions.system()
 	if g.providerOptions.systemPromptPrefix != "" {
 		systemMessage = g.providerOptions.systemPromptPrefix + "\n" + systemMessage
 	}
@@ -284,7 +288,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 	if g.providerOptions.maxTokens > 0 {
 		maxTokens = g.providerOptions.maxTokens
 	}
-	systemMessage := g.providerOptions.systemMessage
+	systemMessage := g.providerOptions.system()
 	if g.providerOptions.systemPromptPrefix != "" {
 		systemMessage = g.providerOptions.systemPromptPrefix + "\n" + systemMessage
 	}
@@ -429,9 +433,12 @@ func (g *geminiClient) shouldRetry(attempts int, err error) (bool, int64, error)
 
 	// Check for token expiration (401 Unauthorized)
 	if contains(errMsg, "unauthorized", "invalid api key", "api key expired") {
-		g.providerOptions.apiKey, err = config.Get().Resolve(g.providerOptions.config.APIKey)
-		if err != nil {
-			return false, 0, fmt.Errorf("failed to resolve API key: %w", err)
+		changed, refreshErr := g.providerOptions.refreshAPIKey()
+		if refreshErr != nil {
+			return false, 0, refreshErr
+		}
+		if !changed {
+			return false, 0, err
 		}
 		g.client, err = createGeminiClient(g.providerOptions)
 		if err != nil {
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+// pingMaxTokens bounds the answer to a ping, only its success matters.
+const pingMaxTokens = 16
+
+// Ping sends the smallest request to the model of the provider, telling
+// whether its credentials and the model work, and how long it took to
+// answer.
+func Ping(ctx context.Context, cfg config.ProviderConfig, model catwalk.Model) (time.Duration, error) {
+	if cfg.Type != catwalk.TypeOpenAI && cfg.Type != catwalk.TypeAzure {
+		// The thinking budget of the Anthropic models is larger than the
+		// tokens of the ping, the OpenAI ones need it for the limit to be
+		// accepted.
+		model.CanReason = false
+	}
+	p, err := NewProvider(
+		cfg,
+		WithCatalogModel(model),
+		WithMaxTokens(pingMaxTokens),
+		WithDisableCache(true),
+		WithSystemMessage("Answer with one word."),
+	)
+	if err != nil {
+		return 0, err
+	}
+	msgs := []message.Message{{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "ping"}},
+	}}
+	start := time.Now()
+	_, err = p.SendMessages(ctx, msgs, nil)
+	return time.Since(start), err
+}
+
+// StatusCode returns the HTTP status the provider answered with in the
+// error, 0 when it didn't answer.
+func StatusCode(err error) int {
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return anthropicErr.StatusCode
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode
+	}
+	var compatErr *compatError
+	if errors.As(err, &compatErr) {
+		return compatErr.StatusCode
+	}
+	var geminiErr genai.APIError
+	if errors.As(err, &geminiErr) {
+		return geminiErr.Code
+	}
+	return 0
+}
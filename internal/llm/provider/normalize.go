@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/tidwall/gjson"
+)
+
+// transformTimeout bounds the transformer command of a response.
+const transformTimeout = 30 * time.Second
+
+// mapStopReason maps a stop reason of a gateway with the stop_reasons of its
+// normalization. It returns false when the stop reason isn't mapped, so the
+// standard stop reasons of the provider type apply.
+func mapStopReason(n *config.ResponseNormalization, reason string) (message.FinishReason, bool) {
+	if n == nil {
+		return "", false
+	}
+	mapped, ok := n.StopReasons[reason]
+	if !ok {
+		return "", false
+	}
+	switch r := message.FinishReason(mapped); r {
+	case message.FinishReasonEndTurn,
+		message.FinishReasonMaxTokens,
+		message.FinishReasonToolUse,
+		message.FinishReasonError:
+		return r, true
+	default:
+		return message.FinishReasonUnknown, true
+	}
+}
+
+// transformResponse pipes a response body through the transformer command of
+// the normalization, if any.
+func transformResponse(ctx context.Context, n *config.ResponseNormalization, body []byte) ([]byte, error) {
+	if n == nil || n.Command == "" {
+		return body, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, transformTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, n.Command, n.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("response transformer %s failed: %w: %s", n.Command, err, msg)
+		}
+		return nil, fmt.Errorf("response transformer %s failed: %w", n.Command, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// errorMessage extracts the message of an error body with the error path of
+// the normalization, falling back to the whole body.
+func errorMessage(n *config.ResponseNormalization, body []byte) string {
+	if n != nil && n.ErrorMessagePath != "" {
+		if msg := gjson.GetBytes(body, n.ErrorMessagePath); msg.Exists() && msg.String() != "" {
+			return msg.String()
+		}
+	}
+	return string(body)
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapStopReason(t *testing.T) {
+	t.Parallel()
+
+	n := &config.ResponseNormalization{StopReasons: map[string]string{
+		"COMPLETE":  "end_turn",
+		"TRUNCATED": "max_tokens",
+		"WEIRD":     "bogus",
+	}}
+
+	r, ok := mapStopReason(n, "COMPLETE")
+	require.True(t, ok)
+	require.Equal(t, message.FinishReasonEndTurn, r)
+
+	r, ok = mapStopReason(n, "WEIRD")
+	require.True(t, ok)
+	require.Equal(t, message.FinishReasonUnknown, r)
+
+	_, ok = mapStopReason(n, "end_turn")
+	require.False(t, ok)
+	_, ok = mapStopReason(nil, "COMPLETE")
+	require.False(t, ok)
+}
+
+func TestErrorMessage(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"error": {"detail": {"message": "quota exceeded"}}}`)
+	require.Equal(t, "quota exceeded", errorMessage(&config.ResponseNormalization{ErrorMessagePath: "error.detail.message"}, body))
+	require.Equal(t, string(body), errorMessage(&config.ResponseNormalization{ErrorMessagePath: "missing"}, body))
+	require.Equal(t, string(body), errorMessage(nil, body))
+}
+
+func TestTransformResponse(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses tr")
+	}
+
+	out, err := transformResponse(t.Context(), &config.ResponseNormalization{Command: "tr", Args: []string{"a-z", "A-Z"}}, []byte("done"))
+	require.NoError(t, err)
+	require.Equal(t, "DONE", string(out))
+
+	out, err = transformResponse(t.Context(), nil, []byte("done"))
+	require.NoError(t, err)
+	require.Equal(t, "done", string(out))
+}
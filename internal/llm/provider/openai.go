@@ -20,6 +20,7 @@ import (
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/openai/openai-go/packages/param"
+	"github.com/openai/openai-go/packages/respjson"
 	"github.com/openai/openai-go/shared"
 )
 
@@ -31,10 +32,13 @@ type openaiClient struct {
 type OpenAIClient ProviderClient
 
 func newOpenAIClient(opts providerClientOptions) OpenAIClient {
-	return &openaiClient{
+	client := &openaiClient{
 		providerOptions: opts,
 		client:          createOpenAIClient(opts),
 	}
+	// The features of custom endpoints are probed while crush starts.
+	client.startProbe()
+	return client
 }
 
 func createOpenAIClient(opts providerClientOptions) openai.Client {
@@ -68,7 +72,7 @@ func createOpenAIClient(opts providerClientOptions) openai.Client {
 func (o *openaiClient) convertMessages(messages []message.Message) (openaiMessages []openai.ChatCompletionMessageParamUnion) {
 	isAnthropicModel := o.providerOptions.config.ID == string(catwalk.InferenceProviderOpenRouter) && strings.HasPrefix(o.Model().ID, "anthropic/")
 	// Add system message first
-	systemMessage := o.providerOptions.systemMessage
+	systemMessage := o.providerOptions.system()
 	if o.providerOptions.systemPromptPrefix != "" {
 		systemMessage = o.providerOptions.systemPromptPrefix + "\n" + systemMessage
 	}
@@ -103,6 +107,10 @@ func (o *openaiClient) convertMessages(messages []message.Message) (openaiMessag
 			hasBinaryContent := false
 			for _, binaryContent := range msg.BinaryContent() {
 				hasBinaryContent = true
+				if binaryContent.IsText() {
+					content = append(content, openai.ChatCompletionContentPartUnionParam{OfText: &openai.ChatCompletionContentPartTextParam{Text: binaryContent.Text()}})
+					continue
+				}
 				imageURL := openai.ChatCompletionContentPartImageImageURLParam{URL: binaryContent.String(catwalk.InferenceProviderOpenAI)}
 				imageBlock := openai.ChatCompletionContentPartImageParam{ImageURL: imageURL}
 
@@ -207,6 +215,9 @@ func (o *openaiClient) convertTools(tools []tools.BaseTool) []openai.ChatComplet
 }
 
 func (o *openaiClient) finishReason(reason string) message.FinishReason {
+	if r, ok := mapStopReason(o.providerOptions.config.Normalize, reason); ok {
+		return r
+	}
 	switch reason {
 	case "stop":
 		return message.FinishReasonEndTurn
@@ -268,6 +279,7 @@ func (o *openaiClient) preparedParams(messages []openai.ChatCompletionMessagePar
 
 func (o *openaiClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (response *ProviderResponse, err error) {
 	params := o.preparedParams(o.convertMessages(messages), o.convertTools(tools))
+	adaptParams(&params, o.capabilities(ctx))
 	attempts := 0
 	for {
 		attempts++
@@ -319,9 +331,16 @@ func (o *openaiClient) send(ctx context.Context, messages []message.Message, too
 }
 
 func (o *openaiClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	caps := o.capabilities(ctx)
+	if !caps.streaming {
+		return o.streamFromSend(ctx, messages, tools)
+	}
 	params := o.preparedParams(o.convertMessages(messages), o.convertTools(tools))
-	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
-		IncludeUsage: openai.Bool(true),
+	adaptParams(&params, caps)
+	if caps.streamUsage {
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.Bool(true),
+		}
 	}
 
 	attempts := 0
@@ -351,15 +370,11 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 				}
 				acc.AddChunk(chunk)
 				for i, choice := range chunk.Choices {
-					reasoning, ok := choice.Delta.JSON.ExtraFields["reasoning"]
-					if ok && reasoning.Raw() != "" {
-						reasoningStr := ""
-						json.Unmarshal([]byte(reasoning.Raw()), &reasoningStr)
-						if reasoningStr != "" {
-							eventChan <- ProviderEvent{
-								Type:     EventThinkingDelta,
-								Thinking: reasoningStr,
-							}
+					if thinking, format := reasoningDelta(choice.Delta.JSON.ExtraFields); thinking != "" {
+						eventChan <- ProviderEvent{
+							Type:            EventThinkingDelta,
+							Thinking:        thinking,
+							ReasoningFormat: format,
 						}
 					}
 					if choice.Delta.Content != "" {
@@ -485,6 +500,25 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 	return eventChan
 }
 
+// streamFromSend answers with the events of a whole response, for the
+// endpoints which can't stream.
+func (o *openaiClient) streamFromSend(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	eventChan := make(chan ProviderEvent)
+	go func() {
+		defer close(eventChan)
+		response, err := o.send(ctx, messages, tools)
+		if err != nil {
+			eventChan <- ProviderEvent{Type: EventError, Error: err}
+			return
+		}
+		if response.Content != "" {
+			eventChan <- ProviderEvent{Type: EventContentDelta, Content: response.Content}
+		}
+		eventChan <- ProviderEvent{Type: EventComplete, Response: response}
+	}()
+	return eventChan
+}
+
 func (o *openaiClient) shouldRetry(attempts int, err error) (bool, int64, error) {
 	if attempts > maxRetries {
 		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
@@ -498,17 +532,26 @@ func (o *openaiClient) shouldRetry(attempts int, err error) (bool, int64, error)
 	if errors.As(err, &apiErr) {
 		// Check for token expiration (401 Unauthorized)
 		if apiErr.StatusCode == 401 {
-			o.providerOptions.apiKey, err = config.Get().Resolve(o.providerOptions.config.APIKey)
-			if err != nil {
-				return false, 0, fmt.Errorf("failed to resolve API key: %w", err)
+			changed, refreshErr := o.providerOptions.refreshAPIKey()
+			if refreshErr != nil {
+				return false, 0, refreshErr
+			}
+			if !changed {
+				return false, 0, err
 			}
 			o.client = createOpenAIClient(o.providerOptions)
 			return true, 0, nil
 		}
 
-		if apiErr.StatusCode != 429 && apiErr.StatusCode != 500 {
+		overloaded := apiErr.StatusCode == 503 || apiErr.StatusCode == 529 || strings.Contains(strings.ToLower(apiErr.Message), "overloaded")
+		if apiErr.StatusCode != 429 && apiErr.StatusCode != 500 && !overloaded {
 			return false, 0, err
 		}
+		if overloaded {
+			if overloadedErr := overloadedRetryError(o.providerOptions.config.ID, attempts, err); overloadedErr != nil {
+				return false, 0, overloadedErr
+			}
+		}
 
 		retryAfterValues = apiErr.Response.Header.Values("Retry-After")
 	}
@@ -565,5 +608,33 @@ func (o *openaiClient) usage(completion openai.ChatCompletion) TokenUsage {
 }
 
 func (o *openaiClient) Model() catwalk.Model {
-	return o.providerOptions.model(o.providerOptions.modelType)
+	model := o.providerOptions.model(o.providerOptions.modelType)
+	if caps, ok := o.probedCapabilities(); ok && !caps.images {
+		model.SupportsImages = false
+	}
+	return model
+}
+
+// reasoningDelta returns the reasoning of a streamed delta, which OpenAI
+// compatible APIs return in fields the SDK doesn't know about: reasoning
+// for OpenRouter and reasoning_content for DeepSeek and compatible APIs.
+func reasoningDelta(fields map[string]respjson.Field) (string, message.ReasoningFormat) {
+	formats := []struct {
+		field  string
+		format message.ReasoningFormat
+	}{
+		{"reasoning", message.ReasoningFormatOpenAI},
+		{"reasoning_content", message.ReasoningFormatDeepSeek},
+	}
+	for _, f := range formats {
+		field, ok := fields[f.field]
+		if !ok || field.Raw() == "" {
+			continue
+		}
+		var reasoning string
+		if err := json.Unmarshal([]byte(field.Raw()), &reasoning); err == nil && reasoning != "" {
+			return reasoning, f.format
+		}
+	}
+	return "", ""
 }
@@ -0,0 +1,265 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/param"
+)
+
+// probeTimeout bounds the probing of an endpoint, the requests sent until
+// then use every feature.
+const probeTimeout = 30 * time.Second
+
+// probeImage is a 1x1 PNG, sent to check the support of images.
+const probeImage = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
+
+// endpointCapabilities are the features of the requests an OpenAI compatible
+// endpoint accepts. vLLM, LiteLLM, llama.cpp or LM Studio each reject some
+// of them depending on the model they serve.
+type endpointCapabilities struct {
+	tools               bool
+	images              bool
+	streaming           bool
+	streamUsage         bool
+	maxCompletionTokens bool
+}
+
+var allCapabilities = endpointCapabilities{
+	tools:               true,
+	images:              true,
+	streaming:           true,
+	streamUsage:         true,
+	maxCompletionTokens: true,
+}
+
+type endpointProbe struct {
+	done chan struct{}
+	caps endpointCapabilities
+}
+
+var (
+	// probes holds the probe of each endpoint and model, they are probed
+	// once per run.
+	probes   = make(map[string]*endpointProbe)
+	probesMu sync.Mutex
+)
+
+// shouldProbe tells whether the provider is an OpenAI compatible endpoint
+// which isn't in the catalog, whose features are unknown.
+func shouldProbe(cfg config.ProviderConfig) bool {
+	return cfg.Type == catwalk.TypeOpenAI &&
+		cfg.BaseURL != "" &&
+		!cfg.DisableProbe &&
+		!slices.Contains(catwalk.KnownProviders(), catwalk.InferenceProvider(cfg.ID))
+}
+
+// capabilities returns the features of the endpoint, waiting for its probe
+// until the context is done.
+func (o *openaiClient) capabilities(ctx context.Context) endpointCapabilities {
+	p := o.startProbe()
+	if p == nil {
+		return allCapabilities
+	}
+	select {
+	case <-p.done:
+		return p.caps
+	case <-ctx.Done():
+		return allCapabilities
+	}
+}
+
+// probedCapabilities returns the features of the endpoint if its probe is
+// done.
+func (o *openaiClient) probedCapabilities() (endpointCapabilities, bool) {
+	p := o.startProbe()
+	if p == nil {
+		return allCapabilities, true
+	}
+	select {
+	case <-p.done:
+		return p.caps, true
+	default:
+		return endpointCapabilities{}, false
+	}
+}
+
+// startProbe starts probing the endpoint in the background the first time
+// it is called for the endpoint and model. It returns nil when the endpoint
+// isn't probed.
+func (o *openaiClient) startProbe() *endpointProbe {
+	if !shouldProbe(o.providerOptions.config) {
+		return nil
+	}
+	model := o.providerOptions.model(o.providerOptions.modelType)
+	key := o.providerOptions.config.ID + " " + o.providerOptions.baseURL + " " + model.ID
+
+	probesMu.Lock()
+	defer probesMu.Unlock()
+	p, ok := probes[key]
+	if !ok {
+		p = &endpointProbe{done: make(chan struct{})}
+		probes[key] = p
+		go func() {
+			defer close(p.done)
+			p.caps = probeEndpoint(o.client, o.providerOptions.config.ID, model)
+		}()
+	}
+	return p
+}
+
+// probeEndpoint sends minimal requests to the endpoint, one per feature. A
+// feature is only turned off when the endpoint rejects the request using it
+// while it accepts the same request without it.
+func probeEndpoint(client openai.Client, providerID string, model catwalk.Model) endpointCapabilities {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	if page, err := client.Models.List(ctx); err != nil {
+		slog.Debug("Failed to list the models of the endpoint", "provider", providerID, "error", err)
+	} else if len(page.Data) > 0 && !slices.ContainsFunc(page.Data, func(m openai.Model) bool { return m.ID == model.ID }) {
+		slog.Warn("The endpoint doesn't list the model", "provider", providerID, "model", model.ID)
+	}
+
+	base := func() openai.ChatCompletionNewParams {
+		return openai.ChatCompletionNewParams{
+			Model:     openai.ChatModel(model.ID),
+			Messages:  []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Reply with OK.")},
+			MaxTokens: openai.Int(1),
+		}
+	}
+	accepts := func(params openai.ChatCompletionNewParams) (bool, error) {
+		_, err := client.Chat.Completions.New(ctx, params)
+		if err == nil {
+			return true, nil
+		}
+		if isRejected(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if ok, err := accepts(base()); !ok {
+		// Nothing can be told from the failures of the plain request.
+		slog.Debug("Failed to probe the endpoint", "provider", providerID, "model", model.ID, "error", err)
+		return allCapabilities
+	}
+
+	caps := allCapabilities
+	withTools := base()
+	withTools.Tools = []openai.ChatCompletionToolParam{{
+		Function: openai.FunctionDefinitionParam{
+			Name:        "noop",
+			Description: openai.String("Does nothing."),
+			Parameters:  openai.FunctionParameters{"type": "object", "properties": map[string]any{}},
+		},
+	}}
+	caps.tools = probeFeature(accepts, withTools)
+
+	withImage := base()
+	withImage.Messages = []openai.ChatCompletionMessageParamUnion{
+		openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+			openai.TextContentPart("Reply with OK."),
+			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: probeImage}),
+		}),
+	}
+	caps.images = probeFeature(accepts, withImage)
+
+	if model.CanReason {
+		withMaxCompletion := base()
+		withMaxCompletion.MaxTokens = param.Opt[int64]{}
+		withMaxCompletion.MaxCompletionTokens = openai.Int(1)
+		caps.maxCompletionTokens = probeFeature(accepts, withMaxCompletion)
+	}
+
+	streams := func(params openai.ChatCompletionNewParams) (bool, error) {
+		stream := client.Chat.Completions.NewStreaming(ctx, params)
+		for stream.Next() {
+		}
+		err := stream.Err()
+		if err == nil {
+			return true, nil
+		}
+		if isRejected(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	withUsage := base()
+	withUsage.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+	caps.streamUsage = probeFeature(streams, withUsage)
+	if !caps.streamUsage {
+		caps.streaming = probeFeature(streams, base())
+	}
+
+	if !caps.tools {
+		slog.Warn("The endpoint doesn't support tools, the agent can only answer with text", "provider", providerID, "model", model.ID)
+	}
+	slog.Info(
+		"Probed the endpoint",
+		"provider", providerID,
+		"model", model.ID,
+		"tools", caps.tools,
+		"images", caps.images,
+		"streaming", caps.streaming,
+		"stream_usage", caps.streamUsage,
+		"max_completion_tokens", caps.maxCompletionTokens,
+	)
+	return caps
+}
+
+// probeFeature tells whether the request using a feature is accepted, the
+// feature being assumed supported when the request failed for another
+// reason.
+func probeFeature(accepts func(openai.ChatCompletionNewParams) (bool, error), params openai.ChatCompletionNewParams) bool {
+	ok, err := accepts(params)
+	return ok || err != nil
+}
+
+// isRejected tells whether the endpoint rejected the request itself, rather
+// than failing to answer it.
+func isRejected(err error) bool {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// adaptParams leaves out of the request the features the endpoint doesn't
+// support.
+func adaptParams(params *openai.ChatCompletionNewParams, caps endpointCapabilities) {
+	if !caps.tools {
+		params.Tools = nil
+	}
+	if !caps.maxCompletionTokens && params.MaxCompletionTokens.Valid() {
+		params.MaxTokens = params.MaxCompletionTokens
+		params.MaxCompletionTokens = param.Opt[int64]{}
+	}
+	if caps.images {
+		return
+	}
+	for _, msg := range params.Messages {
+		if msg.OfUser == nil {
+			continue
+		}
+		parts := msg.OfUser.Content.OfArrayOfContentParts
+		for i, part := range parts {
+			if part.OfImageURL != nil {
+				parts[i] = openai.TextContentPart("[An image was attached, the model can't see images.]")
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/require"
+)
+
+// newLimitedEndpoint serves an OpenAI compatible endpoint rejecting the
+// tools, the images and the stream options, like some local servers.
+func newLimitedEndpoint(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"object":"list","data":[{"id":"local-model","object":"model"}]}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]any
+		require.NoError(t, json.Unmarshal(body, &req))
+		if _, ok := req["tools"]; ok || strings.Contains(string(body), "image_url") || req["stream_options"] != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"unsupported parameter","type":"invalid_request_error"}}`))
+			return
+		}
+		if req["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(`data: {"id":"1","object":"chat.completion.chunk","created":1,"model":"local-model","choices":[{"index":0,"delta":{"content":"OK"},"finish_reason":"stop"}]}` + "\n\n"))
+			w.Write([]byte("data: [DONE]\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","object":"chat.completion","created":1,"model":"local-model","choices":[{"index":0,"message":{"role":"assistant","content":"OK"},"finish_reason":"stop"}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProbeEndpoint(t *testing.T) {
+	t.Parallel()
+
+	server := newLimitedEndpoint(t)
+	client := openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL))
+
+	caps := probeEndpoint(client, "local", catwalk.Model{ID: "local-model"})
+	require.Equal(t, endpointCapabilities{
+		tools:               false,
+		images:              false,
+		streaming:           true,
+		streamUsage:         false,
+		maxCompletionTokens: true,
+	}, caps)
+}
+
+func TestProbeEndpointUnreachable(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	client := openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL), option.WithMaxRetries(0))
+
+	require.Equal(t, allCapabilities, probeEndpoint(client, "local", catwalk.Model{ID: "local-model"}))
+}
+
+func TestShouldProbe(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, shouldProbe(config.ProviderConfig{ID: "vllm", Type: catwalk.TypeOpenAI, BaseURL: "http://localhost:8000/v1"}))
+	require.False(t, shouldProbe(config.ProviderConfig{ID: "vllm", Type: catwalk.TypeOpenAI, BaseURL: "http://localhost:8000/v1", DisableProbe: true}))
+	require.False(t, shouldProbe(config.ProviderConfig{ID: "openai", Type: catwalk.TypeOpenAI, BaseURL: "https://api.openai.com/v1"}))
+	require.False(t, shouldProbe(config.ProviderConfig{ID: "gateway", Type: catwalk.TypeAnthropic, BaseURL: "http://localhost:8000"}))
+}
+
+func TestAdaptParams(t *testing.T) {
+	t.Parallel()
+
+	params := openai.ChatCompletionNewParams{
+		Model: "local-model",
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+				openai.TextContentPart("What is this?"),
+				openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: probeImage}),
+			}),
+		},
+		Tools:               []openai.ChatCompletionToolParam{{Function: openai.FunctionDefinitionParam{Name: "view"}}},
+		MaxCompletionTokens: openai.Int(1024),
+	}
+	adaptParams(&params, endpointCapabilities{streaming: true})
+
+	require.Nil(t, params.Tools)
+	require.False(t, params.MaxCompletionTokens.Valid())
+	require.Equal(t, int64(1024), params.MaxTokens.Value)
+	parts := params.Messages[0].OfUser.Content.OfArrayOfContentParts
+	require.Nil(t, parts[1].OfImageURL)
+	require.NotNil(t, parts[1].OfText)
+}
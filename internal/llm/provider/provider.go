@@ -27,6 +27,9 @@ const (
 	EventComplete       EventType = "complete"
 	EventError          EventType = "error"
 	EventWarning        EventType = "warning"
+	// EventUsage carries the usage of the response so far in Response.Usage,
+	// for the providers reporting it while streaming.
+	EventUsage EventType = "usage"
 )
 
 type TokenUsage struct {
@@ -49,9 +52,12 @@ type ProviderEvent struct {
 	Content   string
 	Thinking  string
 	Signature string
-	Response  *ProviderResponse
-	ToolCall  *message.ToolCall
-	Error     error
+	// ReasoningFormat is the format of the reasoning of thinking and
+	// signature deltas.
+	ReasoningFormat message.ReasoningFormat
+	Response        *ProviderResponse
+	ToolCall        *message.ToolCall
+	Error           error
 }
 type Provider interface {
 	SendMessages(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error)
@@ -69,6 +75,7 @@ type providerClientOptions struct {
 	model              func(config.SelectedModelType) catwalk.Model
 	disableCache       bool
 	systemMessage      string
+	systemContext      func() string
 	systemPromptPrefix string
 	maxTokens          int64
 	extraHeaders       map[string]string
@@ -76,6 +83,33 @@ type providerClientOptions struct {
 	extraParams        map[string]string
 }
 
+// refreshAPIKey gets the API key again after the provider rejected it, from
+// the credential helper of the provider or its api_key. It reports whether
+// the key changed, retrying with the rejected key being pointless.
+func (o *providerClientOptions) refreshAPIKey() (bool, error) {
+	restore := config.PushPopCrushEnv()
+	defer restore()
+	key, err := config.Get().RefreshProviderAPIKey(o.config, o.apiKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve API key: %w", err)
+	}
+	changed := key != o.apiKey
+	o.apiKey = key
+	return changed, nil
+}
+
+// system returns the system message, followed by the context of the
+// request when there is one.
+func (o *providerClientOptions) system() string {
+	if o.systemContext == nil {
+		return o.systemMessage
+	}
+	if context := o.systemContext(); context != "" {
+		return o.systemMessage + "\n\n" + context
+	}
+	return o.systemMessage
+}
+
 type ProviderClientOption func(*providerClientOptions)
 
 type ProviderClient interface {
@@ -121,6 +155,16 @@ func WithModel(model config.SelectedModelType) ProviderClientOption {
 	}
 }
 
+// WithCatalogModel makes the provider answer with the model instead of the
+// model of its type in the configuration, keeping the settings of the type.
+func WithCatalogModel(model catwalk.Model) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.model = func(config.SelectedModelType) catwalk.Model {
+			return model
+		}
+	}
+}
+
 func WithDisableCache(disableCache bool) ProviderClientOption {
 	return func(options *providerClientOptions) {
 		options.disableCache = disableCache
@@ -133,6 +177,15 @@ func WithSystemMessage(systemMessage string) ProviderClientOption {
 	}
 }
 
+// WithSystemContext appends the context returned for each request to the
+// system message, for what changes during a session like the map of the
+// repository.
+func WithSystemContext(systemContext func() string) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.systemContext = systemContext
+	}
+}
+
 func WithMaxTokens(maxTokens int64) ProviderClientOption {
 	return func(options *providerClientOptions) {
 		options.maxTokens = maxTokens
@@ -142,7 +195,7 @@ func WithMaxTokens(maxTokens int64) ProviderClientOption {
 func NewProvider(cfg config.ProviderConfig, opts ...ProviderClientOption) (Provider, error) {
 	restore := config.PushPopCrushEnv()
 	defer restore()
-	resolvedAPIKey, err := config.Get().Resolve(cfg.APIKey)
+	resolvedAPIKey, err := config.Get().ProviderAPIKey(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve API key for provider %s: %w", cfg.ID, err)
 	}
@@ -174,10 +227,21 @@ func NewProvider(cfg config.ProviderConfig, opts ...ProviderClientOption) (Provi
 	}
 	switch cfg.Type {
 	case catwalk.TypeAnthropic:
+		if cfg.CompatMode == config.CompatModeProxy {
+			return &baseProvider[AnthropicCompatClient]{
+				options: clientOptions,
+				client:  newAnthropicCompatClient(clientOptions),
+			}, nil
+		}
 		return &baseProvider[AnthropicClient]{
 			options: clientOptions,
 			client:  newAnthropicClient(clientOptions, AnthropicClientTypeNormal),
 		}, nil
+	case config.TypeAnthropicCompat:
+		return &baseProvider[AnthropicCompatClient]{
+			options: clientOptions,
+			client:  newAnthropicCompatClient(clientOptions),
+		}, nil
 	case catwalk.TypeOpenAI:
 		return &baseProvider[OpenAIClient]{
 			options: clientOptions,
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+const (
+	// defaultOverloadRetries is how many times an overloaded request is
+	// retried before the status page is checked.
+	defaultOverloadRetries = 3
+	statusCheckTimeout     = 5 * time.Second
+)
+
+// statusPages are the Statuspage summaries of the providers.
+var statusPages = map[string]string{
+	string(catwalk.InferenceProviderAnthropic): "https://status.anthropic.com/api/v2/summary.json",
+	string(catwalk.InferenceProviderOpenAI):    "https://status.openai.com/api/v2/summary.json",
+}
+
+// ProviderStatus is the status reported by the status page of a provider.
+type ProviderStatus struct {
+	// Description is the overall status, e.g. "Partial System Outage".
+	Description string
+	Incidents   []Incident
+}
+
+type Incident struct {
+	Name   string
+	Status string
+	// Update is the text of the latest update of the incident.
+	Update string
+}
+
+// Summary returns the status and its incidents on one line.
+func (s ProviderStatus) Summary() string {
+	parts := []string{s.Description}
+	for _, i := range s.Incidents {
+		parts = append(parts, fmt.Sprintf("%s (%s)", i.Name, i.Status))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// OverloadedError is returned when the provider was still overloaded after
+// the retries. Status is set when the status page of the provider could be
+// checked.
+type OverloadedError struct {
+	Provider string
+	Attempts int
+	Status   *ProviderStatus
+	Err      error
+}
+
+func (e *OverloadedError) Error() string {
+	msg := fmt.Sprintf("%s is overloaded, gave up after %d attempts", e.Provider, e.Attempts)
+	if e.Status != nil {
+		msg += ". Status: " + e.Status.Summary()
+	}
+	return msg
+}
+
+func (e *OverloadedError) Unwrap() error {
+	return e.Err
+}
+
+// overloadRetries returns how many times an overloaded request is retried.
+func overloadRetries() int {
+	if opts := config.Get().Options.Overload; opts != nil && opts.MaxRetries > 0 {
+		return opts.MaxRetries
+	}
+	return defaultOverloadRetries
+}
+
+// overloadedRetryError returns nil while an overloaded request is still to
+// be retried, then the error explaining why the provider is overloaded.
+// Backing off more is pointless while the provider has an incident.
+func overloadedRetryError(providerID string, attempts int, err error) error {
+	if attempts <= overloadRetries() {
+		return nil
+	}
+	return newOverloadedError(providerID, attempts, err)
+}
+
+// newOverloadedError checks the status page of the provider to explain why
+// it is overloaded.
+func newOverloadedError(providerID string, attempts int, err error) *OverloadedError {
+	ctx, cancel := context.WithTimeout(context.Background(), statusCheckTimeout)
+	defer cancel()
+	status, statusErr := CheckStatus(ctx, providerID)
+	if statusErr != nil {
+		slog.Debug("Failed to check the provider status", "provider", providerID, "error", statusErr)
+	}
+	return &OverloadedError{
+		Provider: providerID,
+		Attempts: attempts,
+		Status:   status,
+		Err:      err,
+	}
+}
+
+// CheckStatus returns the status of the provider from its status page. It
+// returns nil without an error when the status page of the provider isn't
+// known.
+func CheckStatus(ctx context.Context, providerID string) (*ProviderStatus, error) {
+	url := statusPages[providerID]
+	if opts := config.Get().Options.Overload; opts != nil && opts.StatusPages[providerID] != "" {
+		url = opts.StatusPages[providerID]
+	}
+	if url == "" {
+		return nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status page returned %s", resp.Status)
+	}
+	return parseStatusSummary(resp.Body)
+}
+
+// statusSummary is the part of a Statuspage summary.json used.
+type statusSummary struct {
+	Status struct {
+		Description string `json:"description"`
+	} `json:"status"`
+	Incidents []struct {
+		Name            string `json:"name"`
+		Status          string `json:"status"`
+		IncidentUpdates []struct {
+			Body string `json:"body"`
+		} `json:"incident_updates"`
+	} `json:"incidents"`
+}
+
+func parseStatusSummary(r io.Reader) (*ProviderStatus, error) {
+	var summary statusSummary
+	if err := json.NewDecoder(r).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("invalid status page: %w", err)
+	}
+	status := &ProviderStatus{Description: summary.Status.Description}
+	for _, i := range summary.Incidents {
+		incident := Incident{Name: i.Name, Status: i.Status}
+		// Updates are listed most recent first.
+		if len(i.IncidentUpdates) > 0 {
+			incident.Update = i.IncidentUpdates[0].Body
+		}
+		status.Incidents = append(status.Incidents, incident)
+	}
+	return status, nil
+}
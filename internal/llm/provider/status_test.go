@@ -0,0 +1,29 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatusSummary(t *testing.T) {
+	t.Parallel()
+
+	status, err := parseStatusSummary(strings.NewReader(`{
+		"status": {"indicator": "major", "description": "Partial System Outage"},
+		"incidents": [{
+			"name": "Elevated errors on Claude Opus",
+			"status": "investigating",
+			"incident_updates": [
+				{"body": "We are investigating."},
+				{"body": "Older update."}
+			]
+		}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, "Partial System Outage", status.Description)
+	require.Len(t, status.Incidents, 1)
+	require.Equal(t, "We are investigating.", status.Incidents[0].Update)
+	require.Equal(t, "Partial System Outage; Elevated errors on Claude Opus (investigating)", status.Summary())
+}
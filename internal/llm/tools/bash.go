@@ -3,12 +3,20 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/crush/internal/egress"
+	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/shell"
+	"github.com/charmbracelet/crush/internal/trash"
+	"mvdan.cc/sh/v3/syntax"
 )
 
 type BashParams struct {
@@ -19,6 +27,17 @@ type BashParams struct {
 type BashPermissionsParams struct {
 	Command string `json:"command"`
 	Timeout int    `json:"timeout"`
+	// WorkingDir is where the command runs, the persistent shell keeping
+	// the directory of the previous commands.
+	WorkingDir string `json:"working_dir,omitempty"`
+	// Env are the variables of the shell differing from the environment
+	// of crush, like the ones exported by the previous commands.
+	Env []string `json:"env,omitempty"`
+}
+
+// ShellCommand implements permission.ShellCommand.
+func (p BashPermissionsParams) ShellCommand() string {
+	return p.Command
 }
 
 type BashResponseMetadata struct {
@@ -29,7 +48,20 @@ type BashResponseMetadata struct {
 }
 type bashTool struct {
 	permissions permission.Service
+	trashBin    trash.Service
 	workingDir  string
+	shell       BashShell
+}
+
+// BashShell configures the shell running the commands of the bash tool.
+type BashShell struct {
+	// Program is the shell running the commands, the built-in interpreter
+	// when empty.
+	Program string
+	// SourceRC sources the rc files of the user before each command.
+	SourceRC bool
+	// Env are the variables added to the environment of the commands.
+	Env []string
 }
 
 const (
@@ -114,17 +146,32 @@ var bannedCommands = []string{
 	"ufw",
 }
 
-func bashDescription() string {
-	bannedCommandsStr := strings.Join(bannedCommands, ", ")
-	return fmt.Sprintf(`Executes a given bash command in a persistent shell session with optional timeout, ensuring proper handling and security measures.
-
-CROSS-PLATFORM SHELL SUPPORT:
+// interpreterShellSupport tells how to write the commands run by the built-in
+// interpreter.
+const interpreterShellSupport = `CROSS-PLATFORM SHELL SUPPORT:
 * This tool uses a shell interpreter (mvdan/sh) that mimics the Bash language,
   so you should use Bash syntax in all platforms, including Windows.
   The most common shell builtins and core utils are available in Windows as
   well.
 * Make sure to use forward slashes (/) as path separators in commands, even on
   Windows. Example: "ls C:/foo/bar" instead of "ls C:\foo\bar".
+* On Windows, batch files (.bat, .cmd) and the cmd.exe builtins like dir or
+  copy run through cmd.exe, and PowerShell scripts (.ps1) through PowerShell,
+  so you can run them like any other command.`
+
+func bashDescription(program string) string {
+	bannedCommandsStr := strings.Join(bannedCommands, ", ")
+	shellSupport := interpreterShellSupport
+	if program != "" {
+		shellSupport = fmt.Sprintf(`SHELL:
+* Commands run in %s, the shell of the user, so use its syntax.
+* The working directory and the exported variables carry over between
+  commands, like an activated virtualenv, but each command starts a new
+  shell, so the functions, aliases and unexported variables don't.`, program)
+	}
+	return fmt.Sprintf(`Executes a given bash command in a persistent shell session with optional timeout, ensuring proper handling and security measures.
+
+%s
 
 Before executing the command, please follow these steps:
 
@@ -154,7 +201,7 @@ Usage notes:
 - You can specify an optional timeout in milliseconds (up to 600000ms / 10 minutes). If not specified, commands will timeout after 30 minutes.
 - VERY IMPORTANT: You MUST avoid using search commands like 'find' and 'grep'. Instead use Grep, Glob, or Agent tools to search. You MUST avoid read tools like 'cat', 'head', 'tail', and 'ls', and use FileRead and LS tools to read files.
 - When issuing multiple commands, use the ';' or '&&' operator to separate them. DO NOT use newlines (newlines are ok in quoted strings).
-- IMPORTANT: All commands share the same shell session. Shell state (environment variables, virtual environments, current directory, etc.) persist between commands. For example, if you set an environment variable as part of a command, the environment variable will persist for subsequent commands.
+- IMPORTANT: All commands share the same shell session. Shell state (environment variables, virtual environments, current directory, etc.) persist between commands. For example, if you set an environment variable as part of a command, the environment variable will persist for subsequent commands. Don't activate an environment again in each command, it stays active. Use the reset_shell tool to start over from the working directory and environment of the project.
 - Try to maintain your current working directory throughout the session by using absolute paths and avoiding usage of 'cd'. You may use 'cd' if the User explicitly requests it.
 <good-example>
 pytest /foo/bar/tests
@@ -269,7 +316,7 @@ EOF
 
 Important:
 - Return an empty response - the user will see the gh output directly
-- Never update git config`, bannedCommandsStr, MaxOutputLength)
+- Never update git config`, shellSupport, bannedCommandsStr, MaxOutputLength)
 }
 
 func blockFuncs() []shell.BlockFunc {
@@ -304,14 +351,36 @@ func blockFuncs() []shell.BlockFunc {
 	}
 }
 
-func NewBashTool(permission permission.Service, workingDir string) BaseTool {
+// NewBashTool returns the bash tool. When egressPolicy isn't nil, the
+// commands are pointed to a local proxy enforcing it.
+func NewBashTool(permission permission.Service, trashBin trash.Service, workingDir string, egressPolicy *egress.Policy, bashShell BashShell) BaseTool {
 	// Set up command blocking on the persistent shell
 	persistentShell := shell.GetPersistentShell(workingDir)
 	persistentShell.SetBlockFuncs(blockFuncs())
+	if err := persistentShell.SetProgram(bashShell.Program, bashShell.SourceRC); err != nil {
+		slog.Error("Failed to set the shell of the bash commands, using the built-in one", "error", err)
+		bashShell.Program = ""
+	}
+	for _, kv := range bashShell.Env {
+		k, v, _ := strings.Cut(kv, "=")
+		persistentShell.SetEnv(k, v)
+	}
+	if egressPolicy != nil {
+		proxyURL, err := egressPolicy.ProxyURL()
+		if err != nil {
+			slog.Error("Failed to start the egress proxy of the bash commands", "error", err)
+		} else {
+			for k, v := range egress.ProxyEnv(proxyURL) {
+				persistentShell.SetEnv(k, v)
+			}
+		}
+	}
 
 	return &bashTool{
 		permissions: permission,
+		trashBin:    trashBin,
 		workingDir:  workingDir,
+		shell:       bashShell,
 	}
 }
 
@@ -322,7 +391,7 @@ func (b *bashTool) Name() string {
 func (b *bashTool) Info() ToolInfo {
 	return ToolInfo{
 		Name:        BashToolName,
-		Description: bashDescription(),
+		Description: bashDescription(b.shell.Program),
 		Parameters: map[string]any{
 			"command": map[string]any{
 				"type":        "string",
@@ -337,6 +406,49 @@ func (b *bashTool) Info() ToolInfo {
 	}
 }
 
+// IsReadOnlyCommand reports whether the command is one of the safe commands
+// that only read, which run without asking for permission.
+func IsReadOnlyCommand(command string) bool {
+	if !isSimpleCommand(command) {
+		return false
+	}
+	cmdLower := strings.ToLower(command)
+	for _, safe := range safeCommands {
+		if strings.HasPrefix(cmdLower, safe) {
+			if len(cmdLower) == len(safe) || cmdLower[len(safe)] == ' ' || cmdLower[len(safe)] == '-' {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSimpleCommand tells whether the command is a single command, without
+// other commands chained or piped to it, redirections or substitutions, so
+// that its first words tell what it does.
+func isSimpleCommand(command string) bool {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil || len(file.Stmts) != 1 {
+		return false
+	}
+	stmt := file.Stmts[0]
+	if stmt.Background || stmt.Coprocess || stmt.Negated || len(stmt.Redirs) > 0 {
+		return false
+	}
+	if _, ok := stmt.Cmd.(*syntax.CallExpr); !ok {
+		return false
+	}
+	simple := true
+	syntax.Walk(stmt, func(node syntax.Node) bool {
+		switch node.(type) {
+		case *syntax.CmdSubst, *syntax.ProcSubst:
+			simple = false
+		}
+		return simple
+	})
+	return simple
+}
+
 func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 	var params BashParams
 	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
@@ -353,22 +465,13 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse("missing command"), nil
 	}
 
-	isSafeReadOnly := false
-	cmdLower := strings.ToLower(params.Command)
-
-	for _, safe := range safeCommands {
-		if strings.HasPrefix(cmdLower, safe) {
-			if len(cmdLower) == len(safe) || cmdLower[len(safe)] == ' ' || cmdLower[len(safe)] == '-' {
-				isSafeReadOnly = true
-				break
-			}
-		}
-	}
+	isSafeReadOnly := IsReadOnlyCommand(params.Command)
 
 	sessionID, messageID := GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for executing shell command")
 	}
+	persistentShell := shell.GetSessionShell(sessionID, b.workingDir)
 	if !isSafeReadOnly {
 		p := b.permissions.Request(
 			permission.CreatePermissionRequest{
@@ -379,7 +482,10 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 				Action:      "execute",
 				Description: fmt.Sprintf("Execute command: %s", params.Command),
 				Params: BashPermissionsParams{
-					Command: params.Command,
+					Command:    params.Command,
+					Timeout:    params.Timeout,
+					WorkingDir: persistentShell.GetWorkingDir(),
+					Env:        changedEnv(persistentShell.GetEnv(), b.shell.Env),
 				},
 			},
 		)
@@ -394,8 +500,25 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		defer cancel()
 	}
 
-	persistentShell := shell.GetPersistentShell(b.workingDir)
-	stdout, stderr, err := persistentShell.Exec(ctx, params.Command)
+	// Files removed with rm go to the trash of the session.
+	if b.trashBin != nil {
+		ctx = shell.WithRemove(ctx, func(path string) error {
+			_, err := b.trashBin.Delete(sessionID, path, BashToolName)
+			return err
+		})
+	}
+
+	// The output is shown while the command runs.
+	stream := newOutputStream(sessionID, call.ID)
+	stdout, stderr, err := persistentShell.Exec(shell.WithOutput(ctx, stream), params.Command)
+	stream.Close()
+	// The programs of Windows end their lines with CRLF.
+	stdout, _ = fsext.ToUnixLineEndings(stdout)
+	stderr, _ = fsext.ToUnixLineEndings(stderr)
+	if errors.Is(err, shell.ErrCPULimitExceeded) {
+		limits, _ := shell.LimitsFromContext(ctx)
+		return NewLimitErrorResponse(LimitViolation{Tool: BashToolName, Limit: LimitMaxCPUSeconds, Value: int(limits.CPUSeconds)}, truncateOutput(stdout+stderr)), nil
+	}
 
 	// Get the current working directory after command execution
 	currentWorkingDir := persistentShell.GetWorkingDir()
@@ -405,6 +528,14 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return ToolResponse{}, fmt.Errorf("error executing command: %w", err)
 	}
 
+	// Keep the full output around as an artifact when it does not fit.
+	var artifacts []ToolArtifact
+	if len(stdout) > MaxOutputLength {
+		artifacts = append(artifacts, ToolArtifact{Name: "stdout.txt", MimeType: "text/plain", Data: []byte(stdout)})
+	}
+	if len(stderr) > MaxOutputLength {
+		artifacts = append(artifacts, ToolArtifact{Name: "stderr.txt", MimeType: "text/plain", Data: []byte(stderr)})
+	}
 	stdout = truncateOutput(stdout)
 	stderr = truncateOutput(stderr)
 
@@ -445,7 +576,11 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return WithResponseMetadata(NewTextResponse(BashNoOutput), metadata), nil
 	}
 	stdout += fmt.Sprintf("\n\n<cwd>%s</cwd>", currentWorkingDir)
-	return WithResponseMetadata(NewTextResponse(stdout), metadata), nil
+	response := WithResponseMetadata(NewTextResponse(stdout), metadata)
+	for _, artifact := range artifacts {
+		response = WithResponseArtifact(response, artifact)
+	}
+	return response, nil
 }
 
 func truncateOutput(content string) string {
@@ -467,3 +602,21 @@ func countLines(s string) int {
 	}
 	return len(strings.Split(s, "\n"))
 }
+
+// changedEnv returns the variables of the environment which aren't the same
+// in the environment of the process or the configured ones, whose values may
+// be secrets.
+func changedEnv(env, configured []string) []string {
+	inherited := make(map[string]bool)
+	for _, kv := range slices.Concat(os.Environ(), configured) {
+		inherited[kv] = true
+	}
+	var changed []string
+	for _, kv := range env {
+		if !inherited[kv] {
+			changed = append(changed, kv)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
@@ -0,0 +1,234 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+)
+
+type CallHierarchyParams struct {
+	FilePath  string `json:"file_path"`
+	Line      int    `json:"line"`
+	Symbol    string `json:"symbol"`
+	Direction string `json:"direction,omitempty"`
+}
+
+type CallHierarchyResponseMetadata struct {
+	Direction     string `json:"direction"`
+	NumberOfCalls int    `json:"number_of_calls"`
+}
+
+// Directions of the call hierarchy.
+const (
+	CallDirectionIncoming = "incoming"
+	CallDirectionOutgoing = "outgoing"
+)
+
+type callHierarchyTool struct {
+	lspClients map[string]*lsp.Client
+	workingDir string
+}
+
+const (
+	CallHierarchyToolName    = "call_hierarchy"
+	callHierarchyDescription = `Lists the callers or the callees of a function or method, using the language server.
+
+WHEN TO USE THIS TOOL:
+- Use "incoming" to answer "who calls this function?", e.g. before changing its signature
+- Use "outgoing" to see which functions a function calls, to follow its logic
+- More precise than grep: only real calls are returned, resolved through imports and methods
+
+HOW TO USE:
+- Provide the "file_path" of a file where the function appears
+- Provide the "line" (starting at 1) of one of its occurrences, e.g. its declaration
+- Provide the "symbol" as written on that line
+- Optionally provide the "direction": "incoming" (default) or "outgoing"
+
+OUTPUT:
+- One entry per calling (or called) function, with its location and the lines of each call site
+
+LIMITATIONS:
+- Needs a language server supporting call hierarchies (gopls, typescript-language-server, rust-analyzer, clangd...)
+- Only direct calls are listed, call the tool again on a result to go one level further
+`
+
+	maxCallHierarchyCalls = 100
+)
+
+func NewCallHierarchyTool(lspClients map[string]*lsp.Client, workingDir string) BaseTool {
+	return &callHierarchyTool{
+		lspClients: lspClients,
+		workingDir: workingDir,
+	}
+}
+
+func (c *callHierarchyTool) Name() string {
+	return CallHierarchyToolName
+}
+
+func (c *callHierarchyTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        CallHierarchyToolName,
+		Description: callHierarchyDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path of a file where the function appears",
+			},
+			"line": map[string]any{
+				"type":        "integer",
+				"description": "The line of an occurrence of the function, starting at 1",
+			},
+			"symbol": map[string]any{
+				"type":        "string",
+				"description": "The name of the function, as written on the line",
+			},
+			"direction": map[string]any{
+				"type":        "string",
+				"enum":        []string{CallDirectionIncoming, CallDirectionOutgoing},
+				"description": "List the callers (incoming, default) or the callees (outgoing)",
+			},
+		},
+		Required: []string{"file_path", "line", "symbol"},
+	}
+}
+
+// hierarchyCall is a caller or a callee, with the ranges of its call sites.
+type hierarchyCall struct {
+	item protocol.CallHierarchyItem
+	// sitesURI is the file of the call sites: the caller for incoming
+	// calls, the function itself for outgoing calls.
+	sitesURI protocol.DocumentURI
+	sites    []protocol.Range
+}
+
+func (c *callHierarchyTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params CallHierarchyParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.FilePath == "" || params.Symbol == "" {
+		return NewTextErrorResponse("file_path and symbol are required"), nil
+	}
+	direction := cmp.Or(params.Direction, CallDirectionIncoming)
+	if direction != CallDirectionIncoming && direction != CallDirectionOutgoing {
+		return NewTextErrorResponse(fmt.Sprintf("invalid direction %q, use %q or %q", params.Direction, CallDirectionIncoming, CallDirectionOutgoing)), nil
+	}
+	filePath := fsext.AbsPath(params.FilePath, c.workingDir)
+
+	position, err := symbolPosition(filePath, params.Line, params.Symbol)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	client, err := lspClientFor(ctx, c.lspClients, filePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	items, err := client.PrepareCallHierarchy(ctx, protocol.CallHierarchyPrepareParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+			Position:     position,
+		},
+	})
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error preparing the call hierarchy of %s: %s", params.Symbol, err)), nil
+	}
+	if len(items) == 0 {
+		return NewTextErrorResponse(fmt.Sprintf("%s is not a function or method known to the language server", params.Symbol)), nil
+	}
+
+	var calls []hierarchyCall
+	for _, item := range items {
+		found, err := c.calls(ctx, client, item, direction)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("error listing the %s calls of %s: %s", direction, params.Symbol, err)), nil
+		}
+		calls = append(calls, found...)
+	}
+	if len(calls) == 0 {
+		if direction == CallDirectionIncoming {
+			return NewTextResponse(fmt.Sprintf("No callers of %s found", params.Symbol)), nil
+		}
+		return NewTextResponse(fmt.Sprintf("%s calls no functions", params.Symbol)), nil
+	}
+
+	slices.SortFunc(calls, func(a, b hierarchyCall) int {
+		return compareLocations(
+			protocol.Location{URI: a.item.URI, Range: a.item.SelectionRange},
+			protocol.Location{URI: b.item.URI, Range: b.item.SelectionRange},
+		)
+	})
+	truncated := len(calls) > maxCallHierarchyCalls
+	if truncated {
+		calls = calls[:maxCallHierarchyCalls]
+	}
+
+	snippets := newLineSnippets()
+	var sb strings.Builder
+	if direction == CallDirectionIncoming {
+		fmt.Fprintf(&sb, "%s is called by %d functions:\n", params.Symbol, len(calls))
+	} else {
+		fmt.Fprintf(&sb, "%s calls %d functions:\n", params.Symbol, len(calls))
+	}
+	for _, hc := range calls {
+		itemPath, err := hc.item.URI.Path()
+		if err != nil {
+			continue
+		}
+		kind := strings.ToLower(protocol.TableKindMap[hc.item.Kind])
+		fmt.Fprintf(&sb, "\n%s %s (%s:%d)", kind, hc.item.Name, relPath(c.workingDir, itemPath), hc.item.SelectionRange.Start.Line+1)
+		if hc.item.Detail != "" {
+			fmt.Fprintf(&sb, " %s", hc.item.Detail)
+		}
+		sb.WriteString("\n")
+		sitesPath, err := hc.sitesURI.Path()
+		if err != nil {
+			continue
+		}
+		for _, r := range hc.sites {
+			line := int(r.Start.Line) + 1
+			fmt.Fprintf(&sb, "  %s:%d: %s\n", relPath(c.workingDir, sitesPath), line, snippets.line(sitesPath, line))
+		}
+	}
+	output := sb.String()
+	if truncated {
+		output += fmt.Sprintf("\n(Results are truncated to the first %d functions)", maxCallHierarchyCalls)
+	}
+
+	return WithResponseMetadata(
+		NewTextResponse(strings.TrimRight(output, "\n")),
+		CallHierarchyResponseMetadata{
+			Direction:     direction,
+			NumberOfCalls: len(calls),
+		},
+	), nil
+}
+
+func (c *callHierarchyTool) calls(ctx context.Context, client *lsp.Client, item protocol.CallHierarchyItem, direction string) ([]hierarchyCall, error) {
+	var calls []hierarchyCall
+	if direction == CallDirectionIncoming {
+		incoming, err := client.IncomingCalls(ctx, protocol.CallHierarchyIncomingCallsParams{Item: item})
+		if err != nil {
+			return nil, err
+		}
+		for _, in := range incoming {
+			calls = append(calls, hierarchyCall{item: in.From, sitesURI: in.From.URI, sites: in.FromRanges})
+		}
+		return calls, nil
+	}
+	outgoing, err := client.OutgoingCalls(ctx, protocol.CallHierarchyOutgoingCallsParams{Item: item})
+	if err != nil {
+		return nil, err
+	}
+	for _, out := range outgoing {
+		calls = append(calls, hierarchyCall{item: out.To, sitesURI: item.URI, sites: out.FromRanges})
+	}
+	return calls, nil
+}
@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/trash"
+)
+
+type CodeActionParams struct {
+	FilePath string `json:"file_path"`
+	Line     int    `json:"line"`
+	EndLine  int    `json:"end_line,omitempty"`
+	Action   string `json:"action,omitempty"`
+}
+
+// CodeActionCommandPermissionsParams is a command of the language server run
+// by a code action, its changes can't be shown beforehand.
+type CodeActionCommandPermissionsParams struct {
+	FilePath string `json:"file_path"`
+	Action   string `json:"action"`
+	Command  string `json:"command"`
+}
+
+type codeActionTool struct {
+	lspEditor
+}
+
+const (
+	CodeActionToolName    = "code_action"
+	codeActionDescription = `Lists and applies the code actions of the language server for lines of a file: quick fixes of diagnostics, refactorings (extract function, inline variable...), import organization...
+
+WHEN TO USE THIS TOOL:
+- Use to fix a diagnostic the way the language server suggests, e.g. adding a missing import
+- Use for the refactorings the language server implements, instead of rewriting the code by hand
+
+HOW TO USE:
+- Call it with the "file_path" and the "line" (starting at 1), or the lines up to "end_line", to list the available actions
+- Call it again with the same lines and the exact title of one of them as "action" to apply it
+- The user is shown the diff of all the changed files before they are written
+
+LIMITATIONS:
+- Needs a language server configured for the language of the file
+- The changes of the actions running a command of the language server can't be shown beforehand
+`
+)
+
+func NewCodeActionTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, trashBin trash.Service, workingDir string) BaseTool {
+	return &codeActionTool{lspEditor{
+		lspClients:  lspClients,
+		permissions: permissions,
+		files:       files,
+		trashBin:    trashBin,
+		workingDir:  workingDir,
+	}}
+}
+
+func (c *codeActionTool) Name() string {
+	return CodeActionToolName
+}
+
+func (c *codeActionTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        CodeActionToolName,
+		Description: codeActionDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path of the file",
+			},
+			"line": map[string]any{
+				"type":        "integer",
+				"description": "The first line of the code to act on, starting at 1",
+			},
+			"end_line": map[string]any{
+				"type":        "integer",
+				"description": "The last line of the code to act on, defaults to line",
+			},
+			"action": map[string]any{
+				"type":        "string",
+				"description": "The title of the action to apply, as listed by a previous call. Leave empty to list the actions",
+			},
+		},
+		Required: []string{"file_path", "line"},
+	}
+}
+
+func (c *codeActionTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params CodeActionParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.FilePath == "" {
+		return NewTextErrorResponse("file_path is required"), nil
+	}
+	filePath := c.absPath(params.FilePath)
+	endLine := max(params.EndLine, params.Line)
+
+	rng, err := linesRange(filePath, params.Line, endLine)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	client, err := c.clientFor(ctx, filePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	actions, err := codeActions(ctx, client, filePath, rng)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error listing code actions: %s", err)), nil
+	}
+
+	if params.Action == "" {
+		return NewTextResponse(formatCodeActions(actions, params.Line, endLine)), nil
+	}
+
+	var action *protocol.CodeAction
+	for i := range actions {
+		if strings.EqualFold(actions[i].Title, params.Action) {
+			action = &actions[i]
+			break
+		}
+	}
+	if action == nil {
+		return NewTextErrorResponse(fmt.Sprintf("no code action %q for these lines\n\n%s", params.Action, formatCodeActions(actions, params.Line, endLine))), nil
+	}
+	if action.Edit == nil && action.Command == nil {
+		resolved, err := client.ResolveCodeAction(ctx, *action)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("error resolving the code action: %s", err)), nil
+		}
+		action = &resolved
+	}
+
+	response := NewTextResponse("")
+	if action.Edit != nil {
+		response, err = c.apply(ctx, call, CodeActionToolName, fmt.Sprintf("Apply the code action %q to %s", action.Title, filePath), *action.Edit)
+		if err != nil || response.IsError || action.Command == nil {
+			return response, err
+		}
+	}
+	if action.Command != nil {
+		text, err := c.runCommand(ctx, call, client, filePath, action)
+		if err != nil {
+			return ToolResponse{}, err
+		}
+		response.Content = strings.TrimSpace(response.Content + "\n" + text)
+	}
+	return response, nil
+}
+
+// runCommand runs the command of a code action. The server sends the changes
+// it makes to the files back as a workspace edit, applied when it is received.
+func (c *codeActionTool) runCommand(ctx context.Context, call ToolCall, client *lsp.Client, filePath string, action *protocol.CodeAction) (string, error) {
+	sessionID, _ := GetContextValues(ctx)
+	p := c.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        c.workingDir,
+			ToolCallID:  call.ID,
+			ToolName:    CodeActionToolName,
+			Action:      "execute",
+			Description: fmt.Sprintf("Run the command %s of the language server for the code action %q", action.Command.Command, action.Title),
+			Params: CodeActionCommandPermissionsParams{
+				FilePath: filePath,
+				Action:   action.Title,
+				Command:  action.Command.Command,
+			},
+		},
+	)
+	if !p {
+		return "", permission.ErrorPermissionDenied
+	}
+	if _, err := client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{
+		Command:   action.Command.Command,
+		Arguments: action.Command.Arguments,
+	}); err != nil {
+		return fmt.Sprintf("Error running the command %s: %s", action.Command.Command, err), nil
+	}
+	recordFileRead(filePath)
+	return fmt.Sprintf("Ran the command %s of the language server. View the changed files again before editing them.", action.Command.Command), nil
+}
+
+// codeActions returns the enabled code actions for a range of a file, with
+// the diagnostics of the range as context.
+func codeActions(ctx context.Context, client *lsp.Client, filePath string, rng protocol.Range) ([]protocol.CodeAction, error) {
+	pullLspDiagnostics(ctx, client, filePath)
+	uri := protocol.URIFromPath(filePath)
+	diagnostics := []protocol.Diagnostic{}
+	for _, d := range client.GetFileDiagnostics(uri) {
+		if d.Range.Start.Line <= rng.End.Line && d.Range.End.Line >= rng.Start.Line {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	items, err := client.CodeAction(ctx, protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        rng,
+		Context:      protocol.CodeActionContext{Diagnostics: diagnostics},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var actions []protocol.CodeAction
+	for _, item := range items {
+		switch v := item.Value.(type) {
+		case protocol.CodeAction:
+			if v.Disabled == nil {
+				actions = append(actions, v)
+			}
+		case protocol.Command:
+			// Bare commands are code actions without an edit.
+			actions = append(actions, protocol.CodeAction{Title: v.Title, Command: &v})
+		}
+	}
+	return actions, nil
+}
+
+func formatCodeActions(actions []protocol.CodeAction, line, endLine int) string {
+	lines := fmt.Sprintf("line %d", line)
+	if endLine > line {
+		lines = fmt.Sprintf("lines %d-%d", line, endLine)
+	}
+	if len(actions) == 0 {
+		return fmt.Sprintf("No code actions available for %s", lines)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Code actions available for %s:\n", lines)
+	for _, a := range actions {
+		sb.WriteString("- ")
+		sb.WriteString(a.Title)
+		if a.Kind != "" {
+			fmt.Fprintf(&sb, " [%s]", a.Kind)
+		}
+		if a.IsPreferred {
+			sb.WriteString(" (preferred)")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\nCall code_action again with the title of an action to apply it.")
+	return sb.String()
+}
+
+// linesRange returns the range covering the lines of a file, starting at 1.
+func linesRange(filePath string, line, endLine int) (protocol.Range, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return protocol.Range{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	lines := strings.Split(string(content), "\n")
+	if line < 1 || endLine > len(lines) {
+		return protocol.Range{}, fmt.Errorf("lines %d-%d are out of range, the file has %d lines", line, endLine, len(lines))
+	}
+	last := strings.TrimSuffix(lines[endLine-1], "\r")
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(line - 1)},
+		End: protocol.Position{
+			Line:      uint32(endLine - 1),
+			Character: uint32(len(utf16.Encode([]rune(last)))),
+		},
+	}, nil
+}
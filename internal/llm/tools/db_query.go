@@ -0,0 +1,453 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/permission"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+)
+
+type DBQueryParams struct {
+	Database string `json:"database"`
+	Action   string `json:"action"`
+	Query    string `json:"query"`
+	Table    string `json:"table"`
+}
+
+type DBQueryPermissionsParams struct {
+	Database string `json:"database"`
+	Query    string `json:"query"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+type DBQueryResponseMetadata struct {
+	Database  string `json:"database"`
+	Rows      int    `json:"rows"`
+	Truncated bool   `json:"truncated"`
+}
+
+type dbQueryTool struct {
+	permissions permission.Service
+	databases   config.Databases
+}
+
+const (
+	DBQueryToolName = "db_query"
+
+	dbQueryActionQuery    = "query"
+	dbQueryActionTables   = "tables"
+	dbQueryActionDescribe = "describe"
+
+	defaultDBQueryMaxRows = 100
+	defaultDBQueryTimeout = 30 // seconds
+	maxDBQueryValueLength = 200
+)
+
+func dbQueryDescription(databases config.Databases) string {
+	var names []string
+	for _, db := range databases.Sorted() {
+		mode := "read-only"
+		if db.Database.ReadWrite {
+			mode = "read-write"
+		}
+		names = append(names, fmt.Sprintf("- %s (%s, %s)", db.Name, db.Database.Type, mode))
+	}
+	return fmt.Sprintf(`Runs SQL queries against the databases configured by the user and inspects their schema.
+
+WHEN TO USE THIS TOOL:
+- Use when you need to know what a table looks like or what data it holds
+- Use instead of asking the user to paste schemas or query results
+
+HOW TO USE:
+- Provide the "database" name, it can be omitted when only one database is configured
+- Use the "tables" action to list the tables of the database
+- Use the "describe" action with a "table" to list its columns
+- Use the "query" action (the default) with a SQL "query" to run it
+
+AVAILABLE DATABASES:
+%s
+
+LIMITATIONS:
+- Queries run in read-only transactions unless the database is configured as read-write
+- Results are limited to a maximum number of rows, use LIMIT and precise filters
+- Long values are truncated
+`, strings.Join(names, "\n"))
+}
+
+func NewDBQueryTool(permissions permission.Service, databases config.Databases) BaseTool {
+	return &dbQueryTool{
+		permissions: permissions,
+		databases:   databases,
+	}
+}
+
+func (d *dbQueryTool) Name() string {
+	return DBQueryToolName
+}
+
+func (d *dbQueryTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        DBQueryToolName,
+		Description: dbQueryDescription(d.databases),
+		Parameters: map[string]any{
+			"database": map[string]any{
+				"type":        "string",
+				"description": "The name of the configured database",
+			},
+			"action": map[string]any{
+				"type":        "string",
+				"description": "The action to run, defaults to query",
+				"enum":        []string{dbQueryActionQuery, dbQueryActionTables, dbQueryActionDescribe},
+			},
+			"query": map[string]any{
+				"type":        "string",
+				"description": "The SQL query to run (for the query action)",
+			},
+			"table": map[string]any{
+				"type":        "string",
+				"description": "The table to describe (for the describe action)",
+			},
+		},
+		Required: []string{},
+	}
+}
+
+func (d *dbQueryTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params DBQueryParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse("invalid parameters"), nil
+	}
+
+	name := params.Database
+	if name == "" && len(d.databases) == 1 {
+		for k := range d.databases {
+			name = k
+		}
+	}
+	dbConfig, ok := d.databases[name]
+	if !ok {
+		return NewTextErrorResponse(fmt.Sprintf("unknown database: %q", name)), nil
+	}
+
+	var query string
+	var args []any
+	switch params.Action {
+	case "", dbQueryActionQuery:
+		if strings.TrimSpace(params.Query) == "" {
+			return NewTextErrorResponse("query is required"), nil
+		}
+		// Postgres runs every statement of a simple query, a COMMIT in
+		// there would end the read-only transaction.
+		if countSQLStatements(params.Query) > 1 {
+			return NewTextErrorResponse("only one statement can run at a time"), nil
+		}
+		query = params.Query
+	case dbQueryActionTables:
+		query = listTablesQuery(dbConfig.Type)
+	case dbQueryActionDescribe:
+		if params.Table == "" {
+			return NewTextErrorResponse("table is required"), nil
+		}
+		query, args = describeTableQuery(dbConfig.Type, params.Table)
+	default:
+		return NewTextErrorResponse(fmt.Sprintf("unknown action: %q", params.Action)), nil
+	}
+	if query == "" {
+		return NewTextErrorResponse(fmt.Sprintf("unsupported database type: %q", dbConfig.Type)), nil
+	}
+
+	// Every action needs an explicit permission, even schema introspection
+	// resolves the DSN, which may run a command.
+	action, description := dbQueryActionQuery, fmt.Sprintf("Run query on %s: %s", name, query)
+	switch params.Action {
+	case dbQueryActionTables:
+		action, description = params.Action, fmt.Sprintf("List the tables of %s", name)
+	case dbQueryActionDescribe:
+		action, description = params.Action, fmt.Sprintf("Describe the table %s of %s", params.Table, name)
+	}
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for running queries")
+	}
+	p := d.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        name,
+			ToolCallID:  call.ID,
+			ToolName:    DBQueryToolName,
+			Action:      action,
+			Description: description,
+			Params: DBQueryPermissionsParams{
+				Database: name,
+				Query:    query,
+				ReadOnly: !dbConfig.ReadWrite,
+			},
+		},
+	)
+	if !p {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	output, metadata, err := runDBQuery(ctx, dbConfig, query, args...)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	metadata.Database = name
+	return WithResponseMetadata(NewTextResponse(output), metadata), nil
+}
+
+// runDBQuery runs the query in a transaction, read-only unless the database
+// is configured as read-write, and formats up to MaxRows rows.
+func runDBQuery(ctx context.Context, cfg config.DatabaseConfig, query string, args ...any) (string, DBQueryResponseMetadata, error) {
+	var metadata DBQueryResponseMetadata
+
+	driver := dbDriverName(cfg.Type)
+	if driver == "" {
+		return "", metadata, fmt.Errorf("unsupported database type: %q", cfg.Type)
+	}
+	dsn, err := cfg.ResolvedDSN()
+	if err != nil {
+		return "", metadata, fmt.Errorf("failed to resolve connection string: %w", err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultDBQueryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return "", metadata, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: !cfg.ReadWrite})
+	if err != nil {
+		return "", metadata, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Read-only transactions are always rolled back, just in case.
+	defer tx.Rollback() //nolint:errcheck
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", metadata, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", metadata, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	maxRows := cfg.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultDBQueryMaxRows
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.Join(columns, " | "))
+	sb.WriteString("\n")
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if metadata.Rows == maxRows {
+			metadata.Truncated = true
+			break
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return "", metadata, fmt.Errorf("failed to read row: %w", err)
+		}
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = formatDBValue(v)
+		}
+		sb.WriteString(strings.Join(cells, " | "))
+		sb.WriteString("\n")
+		metadata.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return "", metadata, fmt.Errorf("failed to read rows: %w", err)
+	}
+	rows.Close()
+
+	if cfg.ReadWrite {
+		if err := tx.Commit(); err != nil {
+			return "", metadata, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+	}
+
+	if len(columns) == 0 {
+		return "Query executed successfully", metadata, nil
+	}
+	if metadata.Truncated {
+		sb.WriteString(fmt.Sprintf("\n(results truncated to %d rows)", maxRows))
+	} else {
+		sb.WriteString(fmt.Sprintf("\n(%d rows)", metadata.Rows))
+	}
+	return sb.String(), metadata, nil
+}
+
+// countSQLStatements counts the non-empty statements of the query, skipping
+// the semicolons in strings, quoted identifiers, comments and dollar-quoted
+// bodies.
+func countSQLStatements(query string) int {
+	count := 0
+	empty := true
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ';':
+			if !empty {
+				count++
+			}
+			empty = true
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 1
+			}
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end
+			}
+			continue
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 3
+			}
+			continue
+		case c == '$':
+			if tag := dollarQuoteTag(query[i:]); tag != "" {
+				end := strings.Index(query[i+len(tag):], tag)
+				if end < 0 {
+					i = len(query)
+				} else {
+					i += len(tag) + end + len(tag) - 1
+				}
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		}
+		empty = false
+	}
+	if !empty {
+		count++
+	}
+	return count
+}
+
+// dollarQuoteTag returns the $tag$ opening a Postgres dollar-quoted string
+// at the start of s, if any.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func dbDriverName(t config.DatabaseType) string {
+	switch t {
+	case config.DatabasePostgres:
+		return "postgres"
+	case config.DatabaseMySQL:
+		return "mysql"
+	case config.DatabaseSQLite:
+		return "sqlite3"
+	}
+	return ""
+}
+
+func listTablesQuery(t config.DatabaseType) string {
+	switch t {
+	case config.DatabasePostgres:
+		return `SELECT table_schema, table_name, table_type
+FROM information_schema.tables
+WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY table_schema, table_name`
+	case config.DatabaseMySQL:
+		return `SELECT table_name, table_type
+FROM information_schema.tables
+WHERE table_schema = DATABASE()
+ORDER BY table_name`
+	case config.DatabaseSQLite:
+		return `SELECT name, type
+FROM sqlite_master
+WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'
+ORDER BY name`
+	}
+	return ""
+}
+
+func describeTableQuery(t config.DatabaseType, table string) (string, []any) {
+	switch t {
+	case config.DatabasePostgres:
+		schema, name, ok := strings.Cut(table, ".")
+		if !ok {
+			schema, name = "public", table
+		}
+		return `SELECT column_name, data_type, is_nullable, column_default
+FROM information_schema.columns
+WHERE table_schema = $1 AND table_name = $2
+ORDER BY ordinal_position`, []any{schema, name}
+	case config.DatabaseMySQL:
+		return `SELECT column_name, column_type, is_nullable, column_default, column_key
+FROM information_schema.columns
+WHERE table_schema = DATABASE() AND table_name = ?
+ORDER BY ordinal_position`, []any{table}
+	case config.DatabaseSQLite:
+		return `SELECT name, type, "notnull", dflt_value, pk
+FROM pragma_table_info(?)
+ORDER BY cid`, []any{table}
+	}
+	return "", nil
+}
+
+func formatDBValue(v any) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		s = string(v)
+	case time.Time:
+		s = v.Format(time.RFC3339)
+	default:
+		s = fmt.Sprint(v)
+	}
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	if len(s) > maxDBQueryValueLength {
+		s = s[:maxDBQueryValueLength] + "..."
+	}
+	return s
+}
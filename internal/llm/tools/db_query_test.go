@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDBQuerySQLite(t *testing.T) {
+	t.Parallel()
+
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+INSERT INTO users (name) VALUES ('alice'), ('bob'), ('carol');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	cfg := config.DatabaseConfig{Type: config.DatabaseSQLite, DSN: dsn, MaxRows: 2}
+	ctx := context.Background()
+
+	t.Run("query with row limit", func(t *testing.T) {
+		t.Parallel()
+		out, meta, err := runDBQuery(ctx, cfg, "SELECT id, name FROM users ORDER BY id")
+		require.NoError(t, err)
+		require.Equal(t, 2, meta.Rows)
+		require.True(t, meta.Truncated)
+		require.Equal(t, "id | name\n1 | alice\n2 | bob\n\n(results truncated to 2 rows)", out)
+	})
+
+	t.Run("read-only by default", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := runDBQuery(ctx, cfg, "DELETE FROM users")
+		require.Error(t, err)
+	})
+
+	t.Run("tables", func(t *testing.T) {
+		t.Parallel()
+		out, _, err := runDBQuery(ctx, cfg, listTablesQuery(config.DatabaseSQLite))
+		require.NoError(t, err)
+		require.Contains(t, out, "users | table")
+	})
+
+	t.Run("describe", func(t *testing.T) {
+		t.Parallel()
+		query, args := describeTableQuery(config.DatabaseSQLite, "users")
+		out, meta, err := runDBQuery(ctx, cfg, query, args...)
+		require.NoError(t, err)
+		require.Equal(t, 2, meta.Rows)
+		require.Contains(t, out, "name | TEXT | 1 | NULL | 0")
+	})
+}
+
+func TestCountSQLStatements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"SELECT 1", 1},
+		{"SELECT 1;", 1},
+		{"SELECT 1; ; ", 1},
+		{"COMMIT; DELETE FROM users", 2},
+		{"SELECT ';' AS s, \"a;b\" FROM t", 1},
+		{"SELECT 1 -- ; DROP TABLE t\n", 1},
+		{"SELECT 1 /* ; */", 1},
+		{"SELECT 1; -- trailing comment", 1},
+		{"SELECT $body$ a; b $body$, $1", 1},
+		{"SELECT $$;$$; DELETE FROM t", 2},
+		{"SELECT 'it''s'; SELECT 2", 2},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, countSQLStatements(tt.query), tt.query)
+	}
+}
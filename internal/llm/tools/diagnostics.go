@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -97,9 +98,23 @@ func notifyLspOpenFile(ctx context.Context, filePath string, lsps map[string]*ls
 		if err != nil {
 			continue
 		}
+		pullLspDiagnostics(ctx, client, filePath)
 	}
 }
 
+// pullLspDiagnostics refreshes the diagnostics of a file from the servers
+// answering diagnostic requests. It reports whether they were pulled.
+func pullLspDiagnostics(ctx context.Context, client *lsp.Client, filePath string) bool {
+	if !client.SupportsPullDiagnostics() {
+		return false
+	}
+	if err := client.PullDiagnostics(ctx, filePath); err != nil {
+		slog.Debug("Failed to pull diagnostics", "file", filePath, "error", err)
+		return false
+	}
+	return true
+}
+
 func waitForLspDiagnostics(ctx context.Context, filePath string, lsps map[string]*lsp.Client) {
 	if len(lsps) == 0 {
 		return
@@ -107,6 +122,8 @@ func waitForLspDiagnostics(ctx context.Context, filePath string, lsps map[string
 
 	diagChan := make(chan struct{}, 1)
 
+	// The servers answering diagnostic requests are not waited for.
+	notified, pulled := 0, 0
 	for _, client := range lsps {
 		originalDiags := client.GetDiagnostics()
 
@@ -144,6 +161,13 @@ func waitForLspDiagnostics(ctx context.Context, filePath string, lsps map[string
 				continue
 			}
 		}
+		notified++
+		if pullLspDiagnostics(ctx, client, filePath) {
+			pulled++
+		}
+	}
+	if pulled == notified {
+		return
 	}
 
 	select {
@@ -167,56 +191,6 @@ func getDiagnostics(filePath string, lsps map[string]*lsp.Client) string {
 	fileDiagnostics := []string{}
 	projectDiagnostics := []string{}
 
-	formatDiagnostic := func(pth string, diagnostic protocol.Diagnostic, source string) string {
-		severity := "Info"
-		switch diagnostic.Severity {
-		case protocol.SeverityError:
-			severity = "Error"
-		case protocol.SeverityWarning:
-			severity = "Warn"
-		case protocol.SeverityHint:
-			severity = "Hint"
-		}
-
-		location := fmt.Sprintf("%s:%d:%d", pth, diagnostic.Range.Start.Line+1, diagnostic.Range.Start.Character+1)
-
-		sourceInfo := ""
-		if diagnostic.Source != "" {
-			sourceInfo = diagnostic.Source
-		} else if source != "" {
-			sourceInfo = source
-		}
-
-		codeInfo := ""
-		if diagnostic.Code != nil {
-			codeInfo = fmt.Sprintf("[%v]", diagnostic.Code)
-		}
-
-		tagsInfo := ""
-		if len(diagnostic.Tags) > 0 {
-			tags := []string{}
-			for _, tag := range diagnostic.Tags {
-				switch tag {
-				case protocol.Unnecessary:
-					tags = append(tags, "unnecessary")
-				case protocol.Deprecated:
-					tags = append(tags, "deprecated")
-				}
-			}
-			if len(tags) > 0 {
-				tagsInfo = fmt.Sprintf(" (%s)", strings.Join(tags, ", "))
-			}
-		}
-
-		return fmt.Sprintf("%s: %s [%s]%s%s %s",
-			severity,
-			location,
-			sourceInfo,
-			codeInfo,
-			tagsInfo,
-			diagnostic.Message)
-	}
-
 	for lspName, client := range lsps {
 		diagnostics := client.GetDiagnostics()
 		if len(diagnostics) > 0 {
@@ -298,6 +272,72 @@ func getDiagnostics(filePath string, lsps map[string]*lsp.Client) string {
 	return output.String()
 }
 
+func formatDiagnostic(pth string, diagnostic protocol.Diagnostic, source string) string {
+	severity := "Info"
+	switch diagnostic.Severity {
+	case protocol.SeverityError:
+		severity = "Error"
+	case protocol.SeverityWarning:
+		severity = "Warn"
+	case protocol.SeverityHint:
+		severity = "Hint"
+	}
+
+	location := fmt.Sprintf("%s:%d:%d", pth, diagnostic.Range.Start.Line+1, diagnostic.Range.Start.Character+1)
+
+	sourceInfo := ""
+	if diagnostic.Source != "" {
+		sourceInfo = diagnostic.Source
+	} else if source != "" {
+		sourceInfo = source
+	}
+
+	codeInfo := ""
+	if diagnostic.Code != nil {
+		codeInfo = fmt.Sprintf("[%v]", diagnostic.Code)
+	}
+
+	tagsInfo := ""
+	if len(diagnostic.Tags) > 0 {
+		tags := []string{}
+		for _, tag := range diagnostic.Tags {
+			switch tag {
+			case protocol.Unnecessary:
+				tags = append(tags, "unnecessary")
+			case protocol.Deprecated:
+				tags = append(tags, "deprecated")
+			}
+		}
+		if len(tags) > 0 {
+			tagsInfo = fmt.Sprintf(" (%s)", strings.Join(tags, ", "))
+		}
+	}
+
+	return fmt.Sprintf("%s: %s [%s]%s%s %s",
+		severity,
+		location,
+		sourceInfo,
+		codeInfo,
+		tagsInfo,
+		diagnostic.Message)
+}
+
+// FileErrors returns the errors the LSP clients report for a file, formatted
+// as in the tool results.
+func FileErrors(filePath string, lsps map[string]*lsp.Client) []string {
+	var errs []string
+	uri := protocol.URIFromPath(filePath)
+	for lspName, client := range lsps {
+		for _, diag := range client.GetFileDiagnostics(uri) {
+			if diag.Severity == protocol.SeverityError {
+				errs = append(errs, formatDiagnostic(filePath, diag, lspName))
+			}
+		}
+	}
+	slices.Sort(errs)
+	return errs
+}
+
 func countSeverity(diagnostics []string, severity string) int {
 	count := 0
 	for _, diag := range diagnostics {
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/crush/internal/egress"
+	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/permission"
 )
 
@@ -65,15 +68,11 @@ TIPS:
 - Set appropriate timeouts for large files or slow connections`
 )
 
-func NewDownloadTool(permissions permission.Service, workingDir string) BaseTool {
+func NewDownloadTool(permissions permission.Service, workingDir string, egressPolicy *egress.Policy) BaseTool {
 	return &downloadTool{
 		client: &http.Client{
-			Timeout: 5 * time.Minute, // Default 5 minute timeout for downloads
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
-			},
+			Timeout:   5 * time.Minute, // Default 5 minute timeout for downloads
+			Transport: egressPolicy.Transport(newToolTransport()),
 		},
 		permissions: permissions,
 		workingDir:  workingDir,
@@ -125,12 +124,7 @@ func (t *downloadTool) Run(ctx context.Context, call ToolCall) (ToolResponse, er
 	}
 
 	// Convert relative path to absolute path
-	var filePath string
-	if filepath.IsAbs(params.FilePath) {
-		filePath = params.FilePath
-	} else {
-		filePath = filepath.Join(t.workingDir, params.FilePath)
-	}
+	filePath := fsext.AbsPath(params.FilePath, t.workingDir)
 
 	sessionID, messageID := GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
@@ -172,6 +166,9 @@ func (t *downloadTool) Run(ctx context.Context, call ToolCall) (ToolResponse, er
 	req.Header.Set("User-Agent", "crush/1.0")
 
 	resp, err := t.client.Do(req)
+	if errors.Is(err, egress.ErrBlocked) {
+		return NewTextErrorResponse(err.Error()), nil
+	}
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to download from URL: %w", err)
 	}
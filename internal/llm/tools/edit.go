@@ -8,7 +8,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/charmbracelet/crush/internal/diff"
 	"github.com/charmbracelet/crush/internal/fsext"
@@ -16,6 +15,8 @@ import (
 
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/redact"
+	"github.com/charmbracelet/crush/internal/trash"
 )
 
 type EditParams struct {
@@ -42,6 +43,7 @@ type editTool struct {
 	lspClients  map[string]*lsp.Client
 	permissions permission.Service
 	files       history.Service
+	trashBin    trash.Service
 	workingDir  string
 }
 
@@ -104,11 +106,12 @@ WINDOWS NOTES:
 Remember: when making multiple file edits in a row to the same file, you should prefer to send all edits in a single message with multiple calls to this tool, rather than multiple messages with a single call each.`
 )
 
-func NewEditTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, workingDir string) BaseTool {
+func NewEditTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, trashBin trash.Service, workingDir string) BaseTool {
 	return &editTool{
 		lspClients:  lspClients,
 		permissions: permissions,
 		files:       files,
+		trashBin:    trashBin,
 		workingDir:  workingDir,
 	}
 }
@@ -153,8 +156,10 @@ func (e *editTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse("file_path is required"), nil
 	}
 
-	if !filepath.IsAbs(params.FilePath) {
-		params.FilePath = filepath.Join(e.workingDir, params.FilePath)
+	params.FilePath = fsext.AbsPath(params.FilePath, e.workingDir)
+
+	if redact.HasMask(params.OldString) {
+		return NewTextErrorResponse(maskedOldStringMessage), nil
 	}
 
 	var response ToolResponse
@@ -257,6 +262,7 @@ func (e *editTool) createNewFile(ctx context.Context, filePath, content string,
 
 	recordFileWrite(filePath)
 	recordFileRead(filePath)
+	recordSessionFileWrite(ctx, e.files, sessionID, filePath, "", content)
 
 	return WithResponseMetadata(
 		NewTextResponse("File created: "+filePath),
@@ -286,44 +292,23 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		return NewTextErrorResponse("you must read the file before editing it. Use the View tool first"), nil
 	}
 
-	modTime := fileInfo.ModTime()
-	lastRead := getLastReadTime(filePath)
-	if modTime.After(lastRead) {
-		return NewTextErrorResponse(
-			fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
-				filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
-			)), nil
-	}
-
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
 	}
+	if response, conflict := checkConflict(filePath, content, func(content string) (string, bool) {
+		newContent, errMsg := applyEdit(content, oldString, "", replaceAll)
+		return newContent, errMsg == ""
+	}); conflict {
+		return response, nil
+	}
 
 	oldContent, isCrlf := fsext.ToUnixLineEndings(string(content))
+	oldString, _ = unixEditStrings(oldString, "")
 
-	var newContent string
-	var deletionCount int
-
-	if replaceAll {
-		newContent = strings.ReplaceAll(oldContent, oldString, "")
-		deletionCount = strings.Count(oldContent, oldString)
-		if deletionCount == 0 {
-			return NewTextErrorResponse("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"), nil
-		}
-	} else {
-		index := strings.Index(oldContent, oldString)
-		if index == -1 {
-			return NewTextErrorResponse("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"), nil
-		}
-
-		lastIndex := strings.LastIndex(oldContent, oldString)
-		if index != lastIndex {
-			return NewTextErrorResponse("old_string appears multiple times in the file. Please provide more context to ensure a unique match, or set replace_all to true"), nil
-		}
-
-		newContent = oldContent[:index] + oldContent[index+len(oldString):]
-		deletionCount = 1
+	newContent, errMsg := applyEdit(oldContent, oldString, "", replaceAll)
+	if errMsg != "" {
+		return NewTextErrorResponse(errMsg), nil
 	}
 
 	sessionID, messageID := GetContextValues(ctx)
@@ -361,6 +346,8 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 		newContent, _ = fsext.ToWindowsLineEndings(newContent)
 	}
 
+	keepOriginal(e.trashBin, sessionID, filePath, EditToolName)
+
 	err = os.WriteFile(filePath, []byte(newContent), 0o644)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
@@ -390,6 +377,7 @@ func (e *editTool) deleteContent(ctx context.Context, filePath, oldString string
 
 	recordFileWrite(filePath)
 	recordFileRead(filePath)
+	recordSessionFileWrite(ctx, e.files, sessionID, filePath, oldContent, newContent)
 
 	return WithResponseMetadata(
 		NewTextResponse("Content deleted from file: "+filePath),
@@ -419,44 +407,23 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 		return NewTextErrorResponse("you must read the file before editing it. Use the View tool first"), nil
 	}
 
-	modTime := fileInfo.ModTime()
-	lastRead := getLastReadTime(filePath)
-	if modTime.After(lastRead) {
-		return NewTextErrorResponse(
-			fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
-				filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
-			)), nil
-	}
-
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
 	}
+	if response, conflict := checkConflict(filePath, content, func(content string) (string, bool) {
+		newContent, errMsg := applyEdit(content, oldString, newString, replaceAll)
+		return newContent, errMsg == ""
+	}); conflict {
+		return response, nil
+	}
 
 	oldContent, isCrlf := fsext.ToUnixLineEndings(string(content))
+	oldString, newString = unixEditStrings(oldString, newString)
 
-	var newContent string
-	var replacementCount int
-
-	if replaceAll {
-		newContent = strings.ReplaceAll(oldContent, oldString, newString)
-		replacementCount = strings.Count(oldContent, oldString)
-		if replacementCount == 0 {
-			return NewTextErrorResponse("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"), nil
-		}
-	} else {
-		index := strings.Index(oldContent, oldString)
-		if index == -1 {
-			return NewTextErrorResponse("old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"), nil
-		}
-
-		lastIndex := strings.LastIndex(oldContent, oldString)
-		if index != lastIndex {
-			return NewTextErrorResponse("old_string appears multiple times in the file. Please provide more context to ensure a unique match, or set replace_all to true"), nil
-		}
-
-		newContent = oldContent[:index] + newString + oldContent[index+len(oldString):]
-		replacementCount = 1
+	newContent, errMsg := applyEdit(oldContent, oldString, newString, replaceAll)
+	if errMsg != "" {
+		return NewTextErrorResponse(errMsg), nil
 	}
 
 	if oldContent == newContent {
@@ -496,6 +463,8 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 		newContent, _ = fsext.ToWindowsLineEndings(newContent)
 	}
 
+	keepOriginal(e.trashBin, sessionID, filePath, EditToolName)
+
 	err = os.WriteFile(filePath, []byte(newContent), 0o644)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
@@ -525,6 +494,7 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 
 	recordFileWrite(filePath)
 	recordFileRead(filePath)
+	recordSessionFileWrite(ctx, e.files, sessionID, filePath, oldContent, newContent)
 
 	return WithResponseMetadata(
 		NewTextResponse("Content replaced in file: "+filePath),
@@ -535,3 +505,35 @@ func (e *editTool) replaceContent(ctx context.Context, filePath, oldString, newS
 			Removals:   removals,
 		}), nil
 }
+
+// maskedOldStringMessage tells the model why the old_string of an edit
+// holding a mask of a secret, taken from a view output, is refused.
+const maskedOldStringMessage = "old_string contains a [REDACTED:...] mask, the secrets are masked in what the model sees but not in the file. Edit around the masked line instead"
+
+// unixEditStrings converts the strings of an edit to Unix line endings, the
+// content being matched with them whatever the file uses.
+func unixEditStrings(oldString, newString string) (string, string) {
+	oldString, _ = fsext.ToUnixLineEndings(oldString)
+	newString, _ = fsext.ToUnixLineEndings(newString)
+	return oldString, newString
+}
+
+// applyEdit replaces oldString with newString in the content, returning the
+// error for the model when it can't.
+func applyEdit(content, oldString, newString string, replaceAll bool) (string, string) {
+	if replaceAll {
+		if !strings.Contains(content, oldString) {
+			return "", "old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"
+		}
+		return strings.ReplaceAll(content, oldString, newString), ""
+	}
+
+	index := strings.Index(content, oldString)
+	if index == -1 {
+		return "", "old_string not found in file. Make sure it matches exactly, including whitespace and line breaks"
+	}
+	if index != strings.LastIndex(content, oldString) {
+		return "", "old_string appears multiple times in the file. Please provide more context to ensure a unique match, or set replace_all to true"
+	}
+	return content[:index] + newString + content[index+len(oldString):], ""
+}
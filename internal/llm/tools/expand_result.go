@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+)
+
+type ExpandResultParams struct {
+	ResultID string `json:"result_id"`
+	Pattern  string `json:"pattern"`
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"`
+}
+
+type ExpandResultResponseMetadata struct {
+	ResultID string `json:"result_id"`
+	Lines    int    `json:"lines"`
+	Matches  int    `json:"matches"`
+}
+
+type expandResultTool struct {
+	artifacts artifact.Service
+}
+
+const (
+	ExpandResultToolName    = "expand_result"
+	expandResultDescription = `Reads the full text of a compressed tool result.
+
+WHEN TO USE THIS TOOL:
+- Use when a tool result is wrapped in <compressed_result> and the lines you need were left out, like the output of a passing test or the middle of a build log
+- Use instead of running the command again
+
+HOW TO USE:
+- Provide the "result_id" given in the compressed result
+- Optionally provide a "pattern", a regular expression, to only read the matching lines with their line numbers
+- Optionally provide an "offset" (line number to start from, 0-based) and a "limit" (number of lines to read)
+
+LIMITATIONS:
+- Reads up to 2000 lines by default, long lines are truncated
+`
+)
+
+func NewExpandResultTool(artifacts artifact.Service) BaseTool {
+	return &expandResultTool{artifacts: artifacts}
+}
+
+func (e *expandResultTool) Name() string {
+	return ExpandResultToolName
+}
+
+func (e *expandResultTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ExpandResultToolName,
+		Description: expandResultDescription,
+		Parameters: map[string]any{
+			"result_id": map[string]any{
+				"type":        "string",
+				"description": "The ID of the compressed result",
+			},
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "Regular expression the lines read must match",
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "The line number to start reading from (0-based)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "The number of lines to read (defaults to 2000)",
+			},
+		},
+		Required: []string{"result_id"},
+	}
+}
+
+func (e *expandResultTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ExpandResultParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.ResultID == "" {
+		return NewTextErrorResponse("result_id is required"), nil
+	}
+	var pattern *regexp.Regexp
+	if params.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(params.Pattern); err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("invalid pattern: %s", err)), nil
+		}
+	}
+
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required for expanding results")
+	}
+	a, err := e.artifacts.Get(sessionID, params.ResultID)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if !a.IsText() {
+		return NewTextErrorResponse(fmt.Sprintf("%s is not a text result", params.ResultID)), nil
+	}
+	data, err := e.artifacts.Read(sessionID, a.ID)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading result: %w", err)
+	}
+
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+	if params.Limit <= 0 {
+		params.Limit = DefaultReadLimit
+	}
+	metadata := ExpandResultResponseMetadata{ResultID: a.ID}
+	var output string
+	if pattern == nil {
+		content, lineCount, err := readText(bytes.NewReader(data), params.Offset, params.Limit)
+		if err != nil {
+			return ToolResponse{}, fmt.Errorf("error reading result: %w", err)
+		}
+		metadata.Lines = lineCount
+		output = addLineNumbers(content, params.Offset+1)
+		if read := params.Offset + len(strings.Split(content, "\n")); lineCount > read {
+			output += fmt.Sprintf("\n\n(The result has %d lines. Use 'offset' parameter to read beyond line %d)", lineCount, read)
+		}
+	} else {
+		lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		metadata.Lines = len(lines)
+		var matches []string
+		for i, line := range lines {
+			if !pattern.MatchString(line) {
+				continue
+			}
+			metadata.Matches++
+			if metadata.Matches <= params.Offset || len(matches) >= params.Limit {
+				continue
+			}
+			if len(line) > MaxLineLength {
+				line = line[:MaxLineLength] + "..."
+			}
+			matches = append(matches, addLineNumbers(line, i+1))
+		}
+		output = strings.Join(matches, "\n")
+		switch {
+		case metadata.Matches == 0:
+			output = "No lines match the pattern."
+		case metadata.Matches > params.Offset+len(matches):
+			output += fmt.Sprintf("\n\n(%d lines match. Use 'offset' parameter to read beyond match %d)", metadata.Matches, params.Offset+len(matches))
+		}
+	}
+
+	return WithResponseMetadata(
+		NewTextResponse(fmt.Sprintf("<result id=%q>\n%s\n</result>", a.ID, output)),
+		metadata,
+	), nil
+}
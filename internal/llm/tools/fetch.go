@@ -3,15 +3,19 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 	"unicode/utf8"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/charmbracelet/crush/internal/egress"
+	"github.com/charmbracelet/crush/internal/network"
 	"github.com/charmbracelet/crush/internal/permission"
 )
 
@@ -23,6 +27,7 @@ type FetchParams struct {
 
 type FetchPermissionsParams struct {
 	URL     string `json:"url"`
+	Method  string `json:"method"`
 	Format  string `json:"format"`
 	Timeout int    `json:"timeout,omitempty"`
 }
@@ -66,21 +71,27 @@ TIPS:
 - Set appropriate timeouts for potentially slow websites`
 )
 
-func NewFetchTool(permissions permission.Service, workingDir string) BaseTool {
+func NewFetchTool(permissions permission.Service, workingDir string, egressPolicy *egress.Policy) BaseTool {
 	return &fetchTool{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
-			},
+			Timeout:   30 * time.Second,
+			Transport: egressPolicy.Transport(newToolTransport()),
 		},
 		permissions: permissions,
 		workingDir:  workingDir,
 	}
 }
 
+// newToolTransport returns the transport of the tools requesting URLs, with
+// the proxy and TLS settings of the network.
+func newToolTransport() *http.Transport {
+	t := network.Transport()
+	t.MaxIdleConns = 100
+	t.MaxIdleConnsPerHost = 10
+	t.IdleConnTimeout = 90 * time.Second
+	return t
+}
+
 func (t *fetchTool) Name() string {
 	return FetchToolName
 }
@@ -140,7 +151,12 @@ func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			ToolName:    FetchToolName,
 			Action:      "fetch",
 			Description: fmt.Sprintf("Fetch content from URL: %s", params.URL),
-			Params:      FetchPermissionsParams(params),
+			Params: FetchPermissionsParams{
+				URL:     params.URL,
+				Method:  http.MethodGet,
+				Format:  params.Format,
+				Timeout: params.Timeout,
+			},
 		},
 	)
 
@@ -168,6 +184,9 @@ func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	req.Header.Set("User-Agent", "crush/1.0")
 
 	resp, err := t.client.Do(req)
+	if errors.Is(err, egress.ErrBlocked) {
+		return NewTextErrorResponse(err.Error()), nil
+	}
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to fetch URL: %w", err)
 	}
@@ -232,13 +251,42 @@ func (t *fetchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 	// calculate byte size of content
 	contentSize := int64(len(content))
 	if contentSize > MaxReadSize {
-		content = content[:MaxReadSize]
-		content += fmt.Sprintf("\n\n[Content truncated to %d bytes]", MaxReadSize)
+		// The full content is kept as an artifact the model can page through.
+		response := NewTextResponse(content[:MaxReadSize] + fmt.Sprintf("\n\n[Content truncated to %d bytes]", MaxReadSize))
+		return WithResponseArtifact(response, ToolArtifact{
+			Name:     fetchArtifactName(params.URL, format),
+			MimeType: fetchArtifactMimeType(format),
+			Data:     []byte(content),
+		}), nil
 	}
 
 	return NewTextResponse(content), nil
 }
 
+func fetchArtifactName(rawURL, format string) string {
+	name := "page"
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		name = u.Host
+	}
+	switch format {
+	case "markdown":
+		return name + ".md"
+	case "html":
+		return name + ".html"
+	}
+	return name + ".txt"
+}
+
+func fetchArtifactMimeType(format string) string {
+	switch format {
+	case "markdown":
+		return "text/markdown"
+	case "html":
+		return "text/html"
+	}
+	return "text/plain"
+}
+
 func extractTextFromHTML(html string) (string, error) {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
 	if err != nil {
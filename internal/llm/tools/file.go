@@ -1,15 +1,39 @@
 package tools
 
 import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/filewatch"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/trash"
 )
 
+// maxConflictBaseSize is the size above which the content of the files read
+// isn't kept for the merge previews of the conflicts, only its hash.
+const maxConflictBaseSize = 512 * 1024
+
+// maxConflictContentSize bounds the current content and the merge preview in
+// a conflict response.
+const maxConflictContentSize = 32 * 1024
+
 // File record to track when files were read/written
 type fileRecord struct {
 	path      string
 	readTime  time.Time
 	writeTime time.Time
+	// hash is the SHA-256 of the content last read, and content the content
+	// itself when it is small enough.
+	hash    [sha256.Size]byte
+	content []byte
 }
 
 var (
@@ -26,6 +50,13 @@ func recordFileRead(path string) {
 		record = fileRecord{path: path}
 	}
 	record.readTime = time.Now()
+	record.hash, record.content = [sha256.Size]byte{}, nil
+	if content, err := os.ReadFile(path); err == nil {
+		record.hash = sha256.Sum256(content)
+		if len(content) <= maxConflictBaseSize {
+			record.content = content
+		}
+	}
 	fileRecords[path] = record
 }
 
@@ -40,6 +71,66 @@ func getLastReadTime(path string) time.Time {
 	return record.readTime
 }
 
+// ConflictResponseMetadata is the metadata of the responses refusing to
+// change a file modified since the agent last read it.
+type ConflictResponseMetadata struct {
+	FilePath       string `json:"file_path"`
+	CurrentContent string `json:"current_content"`
+	// MergePreview is the diff from the current content to the merge of
+	// the change with the modifications, empty when the content last read
+	// isn't known.
+	MergePreview string `json:"merge_preview,omitempty"`
+	Conflicts    int    `json:"conflicts"`
+}
+
+// checkConflict compares the current content of the file with the content
+// the agent last read. When they differ, it returns a conflict response with
+// the current content and a preview of the merge of the change the tool
+// would make with the modifications, instead of overwriting them. change
+// applies the change of the tool to a content, failing when it doesn't
+// apply.
+func checkConflict(path string, current []byte, change func(content string) (string, bool)) (ToolResponse, bool) {
+	fileRecordMutex.RLock()
+	record, exists := fileRecords[path]
+	fileRecordMutex.RUnlock()
+	if !exists || record.hash == [sha256.Size]byte{} || sha256.Sum256(current) == record.hash {
+		return ToolResponse{}, false
+	}
+
+	currentContent, _ := fsext.ToUnixLineEndings(string(current))
+	metadata := ConflictResponseMetadata{
+		FilePath:       path,
+		CurrentContent: currentContent,
+	}
+	if record.content != nil {
+		base, _ := fsext.ToUnixLineEndings(string(record.content))
+		if ours, ok := change(base); ok {
+			merged, conflicts := diff.Merge(base, ours, currentContent, "your change", "current content")
+			metadata.MergePreview, _, _ = diff.GenerateDiff(currentContent, merged, path)
+			metadata.Conflicts = conflicts
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<conflict>\nFile %s was modified since you last read it, by the user or another program. Your change was not applied, so as not to overwrite the modifications.\n", path)
+	if len(currentContent) > maxConflictContentSize {
+		sb.WriteString("\nThe file is too large to show its current content, read it again before changing it.\n")
+	} else {
+		fmt.Fprintf(&sb, "\n<current_content>\n%s\n</current_content>\n", addLineNumbers(currentContent, 1))
+		// The model knows the current content from now on.
+		recordFileRead(path)
+	}
+	switch {
+	case metadata.MergePreview == "":
+	case len(metadata.MergePreview) > maxConflictContentSize:
+		sb.WriteString("\nThe preview of the merge of your change with the modifications is too large to be shown.\n")
+	default:
+		fmt.Fprintf(&sb, "\n<merge_preview conflicts=\"%d\">\nThe diff from the current content to the merge of your change with the modifications, the lines both changed being between conflict markers:\n%s</merge_preview>\n", metadata.Conflicts, metadata.MergePreview)
+	}
+	sb.WriteString("\nMake your change again on top of the current content, keeping the modifications.\n</conflict>")
+	return WithResponseMetadata(NewTextErrorResponse(sb.String()), metadata), true
+}
+
 func recordFileWrite(path string) {
 	fileRecordMutex.Lock()
 	defer fileRecordMutex.Unlock()
@@ -51,3 +142,58 @@ func recordFileWrite(path string) {
 	record.writeTime = time.Now()
 	fileRecords[path] = record
 }
+
+// RecordFileChange records the content of a file changed outside of the
+// tools in a way the agent was told about, like by a hook formatting it, as
+// the content the agent knows.
+func RecordFileChange(sessionID, path string) {
+	recordFileRead(path)
+	filewatch.Track(sessionID, path)
+}
+
+// recordSessionFileRead adds a read of the file to the index of files touched
+// in the session, and watches it for changes made outside of the tools.
+func recordSessionFileRead(ctx context.Context, files history.Service, sessionID, path string, line int) {
+	filewatch.Track(sessionID, path)
+	if files == nil || sessionID == "" {
+		return
+	}
+	if err := files.RecordRead(ctx, sessionID, path, line); err != nil {
+		slog.Debug("Error recording session file read", "error", err)
+	}
+}
+
+// recordSessionFileWrite adds a modification of the file to the index of
+// files touched in the session, positioned at the first changed line, and
+// watches it for changes made outside of the tools.
+func recordSessionFileWrite(ctx context.Context, files history.Service, sessionID, path, oldContent, newContent string) {
+	filewatch.Track(sessionID, path)
+	if files == nil || sessionID == "" {
+		return
+	}
+	if err := files.RecordWrite(ctx, sessionID, path, firstChangedLine(oldContent, newContent)); err != nil {
+		slog.Debug("Error recording session file write", "error", err)
+	}
+}
+
+// keepOriginal copies the file to the trash of the session before it is
+// overwritten, failing to do so doesn't fail the tool.
+func keepOriginal(trashBin trash.Service, sessionID, path, toolName string) {
+	if trashBin == nil || sessionID == "" {
+		return
+	}
+	if _, err := trashBin.Keep(sessionID, path, toolName); err != nil {
+		slog.Warn("Error keeping the original file in the trash", "path", path, "error", err)
+	}
+}
+
+// firstChangedLine returns the first line, starting at 1, that differs
+// between the two contents.
+func firstChangedLine(oldContent, newContent string) int {
+	n := min(len(oldContent), len(newContent))
+	i := 0
+	for i < n && oldContent[i] == newContent[i] {
+		i++
+	}
+	return strings.Count(oldContent[:i], "\n") + 1
+}
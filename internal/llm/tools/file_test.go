@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConflict(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "main.go")
+	base := "package main\n\nfunc main() {\n\tprintln(1)\n}\n\nfunc helper() {}\n"
+	require.NoError(t, os.WriteFile(path, []byte(base), 0o644))
+	recordFileRead(path)
+	change := func(content string) (string, bool) {
+		newContent, errMsg := applyEdit(content, "println(1)", "println(2)", false)
+		return newContent, errMsg == ""
+	}
+
+	_, conflict := checkConflict(path, []byte(base), change)
+	require.False(t, conflict)
+
+	// The user changes another part of the file.
+	current := "package main\n\nfunc main() {\n\tprintln(1)\n}\n\nfunc helper() {\n\tprintln(\"help\")\n}\n"
+	require.NoError(t, os.WriteFile(path, []byte(current), 0o644))
+	response, conflict := checkConflict(path, []byte(current), change)
+	require.True(t, conflict)
+	require.True(t, response.IsError)
+	require.Contains(t, response.Content, "<current_content>")
+	require.Contains(t, response.Content, `<merge_preview conflicts="0">`)
+
+	var metadata ConflictResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(response.Metadata), &metadata))
+	require.Equal(t, path, metadata.FilePath)
+	require.Equal(t, current, metadata.CurrentContent)
+	require.Zero(t, metadata.Conflicts)
+	require.Contains(t, metadata.MergePreview, "-\tprintln(1)\n+\tprintln(2)\n")
+
+	// The current content was shown, the change can be made again.
+	_, conflict = checkConflict(path, []byte(current), change)
+	require.False(t, conflict)
+
+	// The user changes the line the agent changes.
+	base = "package main\n\nfunc main() {\n\tprintln(3)\n}\n"
+	require.NoError(t, os.WriteFile(path, []byte(base), 0o644))
+	recordFileRead(path)
+	current = "package main\n\nfunc main() {\n\tprintln(5)\n}\n"
+	require.NoError(t, os.WriteFile(path, []byte(current), 0o644))
+	response, conflict = checkConflict(path, []byte(current), func(string) (string, bool) {
+		return "package main\n\nfunc main() {\n\tprintln(4)\n}\n", true
+	})
+	require.True(t, conflict)
+	require.NoError(t, json.Unmarshal([]byte(response.Metadata), &metadata))
+	require.Equal(t, 1, metadata.Conflicts)
+	require.Contains(t, metadata.MergePreview, "+<<<<<<< your change\n+\tprintln(4)\n+=======\n")
+}
+
+func TestApplyEdit(t *testing.T) {
+	t.Parallel()
+
+	content, errMsg := applyEdit("a b a", "a", "c", true)
+	require.Empty(t, errMsg)
+	require.Equal(t, "c b c", content)
+
+	_, errMsg = applyEdit("a b a", "a", "c", false)
+	require.Contains(t, errMsg, "appears multiple times")
+
+	_, errMsg = applyEdit("a b a", "d", "c", false)
+	require.Contains(t, errMsg, "not found")
+
+	content, errMsg = applyEdit("a b a", "b ", "", false)
+	require.Empty(t, errMsg)
+	require.Equal(t, "a a", content)
+}
+
+func TestEditRefusesMasks(t *testing.T) {
+	t.Parallel()
+
+	edit := &editTool{workingDir: t.TempDir()}
+	response, err := edit.Run(t.Context(), ToolCall{Input: `{"file_path":"config.go","old_string":"key := \"[REDACTED:api-key]\"","new_string":"key := os.Getenv(\"KEY\")"}`})
+	require.NoError(t, err)
+	require.True(t, response.IsError)
+	require.Equal(t, maskedOldStringMessage, response.Content)
+
+	multiEdit := &multiEditTool{}
+	err = multiEdit.validateEdits([]MultiEditOperation{
+		{OldString: "package main", NewString: "package app"},
+		{OldString: "[REDACTED:jwt]", NewString: "token"},
+	})
+	require.EqualError(t, err, "edit 2: "+maskedOldStringMessage)
+}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/symbols"
+)
+
+type FindDefinitionParams struct {
+	Symbol string `json:"symbol"`
+	Path   string `json:"path"`
+}
+
+type FindDefinitionResponseMetadata struct {
+	NumberOfDefinitions int  `json:"number_of_definitions"`
+	Truncated           bool `json:"truncated"`
+}
+
+type findDefinitionTool struct {
+	workingDir string
+}
+
+const (
+	FindDefinitionToolName    = "find_definition_fast"
+	findDefinitionDescription = `Finds where a symbol (function, method, class, type...) is declared by parsing the source files, without needing a language server.
+
+WHEN TO USE THIS TOOL:
+- Use when you know the name of a symbol and need its declaration
+- Use when no language server is configured for the project
+- Faster and more precise than grep for declarations, as usages are not returned
+
+HOW TO USE:
+- Provide the "symbol" name, e.g. "NewServer"
+- Qualify methods with their type to narrow the results, e.g. "Server.Start"
+- Optionally provide a "path" to search in (defaults to the current working directory)
+
+SUPPORTED LANGUAGES:
+Go, Python, JavaScript, TypeScript, Rust, Java, C, C++, C#, Ruby, PHP, Kotlin, Swift, Scala, Lua and Bash.
+
+LIMITATIONS:
+- Names are matched exactly, falling back to a case-insensitive match
+- Go methods are not qualified by their receiver, search for the method name alone
+- Results are limited to 50 definitions
+`
+
+	maxDefinitions = 50
+)
+
+func NewFindDefinitionTool(workingDir string) BaseTool {
+	return &findDefinitionTool{workingDir: workingDir}
+}
+
+func (f *findDefinitionTool) Name() string {
+	return FindDefinitionToolName
+}
+
+func (f *findDefinitionTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        FindDefinitionToolName,
+		Description: symbolsDescription(findDefinitionDescription),
+		Parameters: map[string]any{
+			"symbol": map[string]any{
+				"type":        "string",
+				"description": "The name of the symbol, optionally qualified by its container, e.g. Server.Start",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "The file or directory to search in. Defaults to the current working directory.",
+			},
+		},
+		Required: []string{"symbol"},
+	}
+}
+
+type definition struct {
+	path   string
+	symbol symbols.Symbol
+}
+
+func (f *findDefinitionTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params FindDefinitionParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.Symbol == "" {
+		return NewTextErrorResponse("symbol is required"), nil
+	}
+
+	container, name := "", params.Symbol
+	if i := strings.LastIndexAny(params.Symbol, ".:"); i > 0 && i < len(params.Symbol)-1 {
+		container = strings.TrimRight(params.Symbol[:i], ".:")
+		name = params.Symbol[i+1:]
+	}
+
+	// Exact matches first, case-insensitive ones are only used when there
+	// is no exact match.
+	var exact, folded []definition
+	err := walkSymbols(ctx, resolveSymbolsPath(f.workingDir, params.Path), func(path string, syms []symbols.Symbol) bool {
+		for _, s := range symbols.Flatten(syms) {
+			switch {
+			case matchesDefinition(s, container, name, false):
+				exact = append(exact, definition{path: path, symbol: s})
+			case matchesDefinition(s, container, name, true):
+				folded = append(folded, definition{path: path, symbol: s})
+			}
+		}
+		return len(exact) <= maxDefinitions
+	})
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error searching definitions: %s", err)), nil
+	}
+
+	found := exact
+	if len(found) == 0 {
+		found = folded
+	}
+	if len(found) == 0 {
+		return NewTextResponse(fmt.Sprintf("No definition found for %s", params.Symbol)), nil
+	}
+
+	var metadata FindDefinitionResponseMetadata
+	if len(found) > maxDefinitions {
+		found = found[:maxDefinitions]
+		metadata.Truncated = true
+	}
+	metadata.NumberOfDefinitions = len(found)
+
+	var sb strings.Builder
+	for _, d := range found {
+		fmt.Fprintf(&sb, "%s:%d: %s %s", relativeSymbolsPath(f.workingDir, d.path), d.symbol.Line, d.symbol.Kind, d.symbol.Name)
+		if d.symbol.Container != "" {
+			fmt.Fprintf(&sb, " (in %s)", d.symbol.Container)
+		}
+		sb.WriteString("\n")
+		fmt.Fprintf(&sb, "  %s\n", d.symbol.Signature)
+	}
+	if metadata.Truncated {
+		fmt.Fprintf(&sb, "\n(Results are truncated to %d definitions. Consider using a more specific path or a qualified name.)", maxDefinitions)
+	}
+	return WithResponseMetadata(NewTextResponse(strings.TrimSpace(sb.String())), metadata), nil
+}
+
+func matchesDefinition(s symbols.Symbol, container, name string, fold bool) bool {
+	eq := func(a, b string) bool {
+		if fold {
+			return strings.EqualFold(a, b)
+		}
+		return a == b
+	}
+	// Some languages already qualify names, e.g. Lua's M.run or C++'s
+	// Shape::area.
+	if container != "" && (eq(s.Name, container+"."+name) || eq(s.Name, container+"::"+name) || eq(s.Name, container+":"+name)) {
+		return true
+	}
+	if !eq(s.Name, name) {
+		return false
+	}
+	return container == "" || eq(s.Container, container)
+}
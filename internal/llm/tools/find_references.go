@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+)
+
+type FindReferencesParams struct {
+	FilePath           string `json:"file_path"`
+	Line               int    `json:"line"`
+	Symbol             string `json:"symbol"`
+	IncludeDeclaration bool   `json:"include_declaration,omitempty"`
+}
+
+type FindReferencesResponseMetadata struct {
+	NumberOfReferences int  `json:"number_of_references"`
+	NumberOfFiles      int  `json:"number_of_files"`
+	Truncated          bool `json:"truncated"`
+}
+
+type findReferencesTool struct {
+	lspClients map[string]*lsp.Client
+	workingDir string
+}
+
+const (
+	FindReferencesToolName    = "find_references"
+	findReferencesDescription = `Finds every reference to a symbol (function, method, type, variable, field...) in the workspace, using the language server.
+
+WHEN TO USE THIS TOOL:
+- Use to answer "where is this used?" or "who calls this function?" before changing a symbol
+- Much more precise than grep: only real references are returned, not comments or unrelated symbols with the same name
+
+HOW TO USE:
+- Provide the "file_path" of a file where the symbol appears
+- Provide the "line" (starting at 1) of one of its occurrences, e.g. its declaration
+- Provide the "symbol" as written on that line
+- Set "include_declaration" to also list the declaration itself
+
+OUTPUT:
+- References are grouped by file, each with its line, column and the text of the line
+
+LIMITATIONS:
+- Needs a language server configured for the language of the file
+- Results are limited to 200 references
+`
+
+	maxReferences = 200
+)
+
+func NewFindReferencesTool(lspClients map[string]*lsp.Client, workingDir string) BaseTool {
+	return &findReferencesTool{
+		lspClients: lspClients,
+		workingDir: workingDir,
+	}
+}
+
+func (f *findReferencesTool) Name() string {
+	return FindReferencesToolName
+}
+
+func (f *findReferencesTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        FindReferencesToolName,
+		Description: findReferencesDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path of a file where the symbol appears",
+			},
+			"line": map[string]any{
+				"type":        "integer",
+				"description": "The line of an occurrence of the symbol, starting at 1",
+			},
+			"symbol": map[string]any{
+				"type":        "string",
+				"description": "The name of the symbol, as written on the line",
+			},
+			"include_declaration": map[string]any{
+				"type":        "boolean",
+				"description": "Also list the declaration of the symbol (default false)",
+			},
+		},
+		Required: []string{"file_path", "line", "symbol"},
+	}
+}
+
+func (f *findReferencesTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params FindReferencesParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.FilePath == "" || params.Symbol == "" {
+		return NewTextErrorResponse("file_path and symbol are required"), nil
+	}
+	filePath := fsext.AbsPath(params.FilePath, f.workingDir)
+
+	position, err := symbolPosition(filePath, params.Line, params.Symbol)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	client, err := lspClientFor(ctx, f.lspClients, filePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	locations, err := client.References(ctx, protocol.ReferenceParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+			Position:     position,
+		},
+		Context: protocol.ReferenceContext{IncludeDeclaration: params.IncludeDeclaration},
+	})
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error finding references to %s: %s", params.Symbol, err)), nil
+	}
+	if len(locations) == 0 {
+		return NewTextResponse(fmt.Sprintf("No references to %s found", params.Symbol)), nil
+	}
+
+	slices.SortFunc(locations, compareLocations)
+	truncated := len(locations) > maxReferences
+	if truncated {
+		locations = locations[:maxReferences]
+	}
+
+	snippets := newLineSnippets()
+	var sb strings.Builder
+	var files int
+	var current protocol.DocumentURI
+	for _, loc := range locations {
+		path, err := loc.URI.Path()
+		if err != nil {
+			continue
+		}
+		if loc.URI != current {
+			if current != "" {
+				sb.WriteString("\n")
+			}
+			current = loc.URI
+			files++
+			fmt.Fprintf(&sb, "%s:\n", relPath(f.workingDir, path))
+		}
+		line := int(loc.Range.Start.Line) + 1
+		fmt.Fprintf(&sb, "  Line %d, Col %d: %s\n", line, loc.Range.Start.Character+1, snippets.line(path, line))
+	}
+	output := fmt.Sprintf("Found %d references to %s in %d files\n\n", len(locations), params.Symbol, files) + sb.String()
+	if truncated {
+		output += fmt.Sprintf("\n(Results are truncated to the first %d references)", maxReferences)
+	}
+
+	return WithResponseMetadata(
+		NewTextResponse(strings.TrimRight(output, "\n")),
+		FindReferencesResponseMetadata{
+			NumberOfReferences: len(locations),
+			NumberOfFiles:      files,
+			Truncated:          truncated,
+		},
+	), nil
+}
+
+func compareLocations(a, b protocol.Location) int {
+	return cmp.Or(
+		strings.Compare(string(a.URI), string(b.URI)),
+		cmp.Compare(a.Range.Start.Line, b.Range.Start.Line),
+		cmp.Compare(a.Range.Start.Character, b.Range.Start.Character),
+	)
+}
+
+// relPath returns the path relative to the working directory when it is
+// inside it, to keep the results short.
+func relPath(workingDir, path string) string {
+	if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// maxSnippetLength keeps the lines of minified files from flooding the
+// results.
+const maxSnippetLength = 200
+
+// lineSnippets reads the lines of the files referenced in results, each
+// file once.
+type lineSnippets map[string][]string
+
+func newLineSnippets() lineSnippets {
+	return make(lineSnippets)
+}
+
+// line returns the trimmed text of a line of the file, starting at 1.
+func (s lineSnippets) line(path string, line int) string {
+	lines, ok := s[path]
+	if !ok {
+		content, err := os.ReadFile(path)
+		if err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+		s[path] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	text := strings.TrimSpace(lines[line-1])
+	if len(text) > maxSnippetLength {
+		text = text[:maxSnippetLength] + "..."
+	}
+	return text
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/trash"
+)
+
+type FormatFileParams struct {
+	FilePath string `json:"file_path"`
+}
+
+type formatFileTool struct {
+	lspEditor
+}
+
+const (
+	FormatFileToolName    = "format_file"
+	formatFileDescription = `Formats a file with the formatter of its language server, e.g. gofmt through gopls.
+
+WHEN TO USE THIS TOOL:
+- Use after editing a file, instead of fixing its indentation and spacing by hand
+- Use when the project expects files to be formatted by its tooling
+
+HOW TO USE:
+- Provide the "file_path" of the file to format
+- The user is shown the diff before the file is written
+
+LIMITATIONS:
+- Needs a language server configured for the language of the file, and supporting formatting
+- The settings of the formatter are the ones of the language server and the project
+`
+)
+
+func NewFormatFileTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, trashBin trash.Service, workingDir string) BaseTool {
+	return &formatFileTool{lspEditor{
+		lspClients:  lspClients,
+		permissions: permissions,
+		files:       files,
+		trashBin:    trashBin,
+		workingDir:  workingDir,
+	}}
+}
+
+func (f *formatFileTool) Name() string {
+	return FormatFileToolName
+}
+
+func (f *formatFileTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        FormatFileToolName,
+		Description: formatFileDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path of the file to format",
+			},
+		},
+		Required: []string{"file_path"},
+	}
+}
+
+func (f *formatFileTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params FormatFileParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.FilePath == "" {
+		return NewTextErrorResponse("file_path is required"), nil
+	}
+	filePath := f.absPath(params.FilePath)
+
+	client, err := f.clientFor(ctx, filePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	uri := protocol.URIFromPath(filePath)
+	edits, err := client.Formatting(ctx, protocol.DocumentFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Options: protocol.FormattingOptions{
+			TabSize:      4,
+			InsertSpaces: true,
+		},
+	})
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error formatting %s: %s", filePath, err)), nil
+	}
+	return f.apply(ctx, call, FormatFileToolName, fmt.Sprintf("Format file %s", filePath), protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{uri: edits},
+	})
+}
@@ -6,12 +6,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"os"
 	"os/exec"
 	"path/filepath"
-	"sort"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/dustin/go-humanize"
 )
 
 const (
@@ -25,8 +28,9 @@ WHEN TO USE THIS TOOL:
 
 HOW TO USE:
 - Provide a glob pattern to match against file paths
+- Optionally give more patterns in patterns, the files matching any of them are returned
 - Optionally specify a starting directory (defaults to current working directory)
-- Results are sorted with most recently modified files first
+- Results are sorted with most recently modified files first, with their size and modification time
 
 GLOB PATTERN SYNTAX:
 - '*' matches any sequence of non-separator characters
@@ -34,6 +38,7 @@ GLOB PATTERN SYNTAX:
 - '?' matches any single non-separator character
 - '[...]' matches any character in the brackets
 - '[!...]' matches any character not in the brackets
+- '{a,b}' matches any of the comma-separated alternatives
 
 COMMON PATTERN EXAMPLES:
 - '*.js' - Find all JavaScript files in the current directory
@@ -45,6 +50,7 @@ LIMITATIONS:
 - Results are limited to 100 files (newest first)
 - Does not search file contents (use Grep tool for that)
 - Hidden files (starting with '.') are skipped
+- Files ignored by the .gitignore and .crushignore files are skipped
 
 WINDOWS NOTES:
 - Path separators are handled automatically (both / and \ work)
@@ -58,13 +64,23 @@ TIPS:
 )
 
 type GlobParams struct {
-	Pattern string `json:"pattern"`
-	Path    string `json:"path"`
+	Pattern  string   `json:"pattern"`
+	Patterns []string `json:"patterns,omitempty"`
+	Path     string   `json:"path"`
+}
+
+// GlobEntry is a file found, given to the model with its size and
+// modification time.
+type GlobEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
 }
 
 type GlobResponseMetadata struct {
-	NumberOfFiles int  `json:"number_of_files"`
-	Truncated     bool `json:"truncated"`
+	NumberOfFiles int         `json:"number_of_files"`
+	Truncated     bool        `json:"truncated"`
+	Files         []GlobEntry `json:"files,omitempty"`
 }
 
 type globTool struct {
@@ -90,6 +106,13 @@ func (g *globTool) Info() ToolInfo {
 				"type":        "string",
 				"description": "The glob pattern to match files against",
 			},
+			"patterns": map[string]any{
+				"type":        "array",
+				"description": "More glob patterns, the files matching any of the patterns being returned",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
 			"path": map[string]any{
 				"type":        "string",
 				"description": "The directory to search in. Defaults to the current working directory.",
@@ -105,54 +128,72 @@ func (g *globTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
 	}
 
-	if params.Pattern == "" {
+	var patterns []string
+	for _, pattern := range append([]string{params.Pattern}, params.Patterns...) {
+		if pattern != "" && !slices.Contains(patterns, pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) == 0 {
 		return NewTextErrorResponse("pattern is required"), nil
 	}
 
-	searchPath := params.Path
-	if searchPath == "" {
-		searchPath = g.workingDir
-	}
+	searchPath := fsext.AbsPath(params.Path, g.workingDir)
 
-	files, truncated, err := globFiles(ctx, params.Pattern, searchPath, 100)
+	files, truncated, err := globFiles(ctx, patterns, searchPath, 100)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error finding files: %w", err)
 	}
 
-	var output string
+	var output strings.Builder
+	entries := make([]GlobEntry, 0, len(files))
 	if len(files) == 0 {
-		output = "No files found"
+		output.WriteString("No files found")
 	} else {
-		output = strings.Join(files, "\n")
+		fmt.Fprintf(&output, "<files count=\"%d\">\n", len(files))
+		for _, file := range files {
+			entries = append(entries, GlobEntry{Path: file.Path, Size: file.Size, ModTime: file.ModTime})
+			fmt.Fprintf(&output, "%s (%s, modified %s)\n", file.Path, humanize.Bytes(uint64(file.Size)), file.ModTime.Format(time.DateTime))
+		}
+		output.WriteString("</files>")
 		if truncated {
-			output += "\n\n(Results are truncated. Consider using a more specific path or pattern.)"
+			output.WriteString("\n\n(Results are truncated. Consider using a more specific path or pattern.)")
 		}
 	}
 
 	return WithResponseMetadata(
-		NewTextResponse(output),
+		NewTextResponse(output.String()),
 		GlobResponseMetadata{
 			NumberOfFiles: len(files),
 			Truncated:     truncated,
+			Files:         entries,
 		},
 	), nil
 }
 
-func globFiles(ctx context.Context, pattern, searchPath string, limit int) ([]string, bool, error) {
-	cmdRg := getRgCmd(ctx, pattern)
+// globFiles returns the files matching any of the patterns, the most
+// recently modified first.
+func globFiles(ctx context.Context, patterns []string, searchPath string, limit int) ([]fsext.FileInfo, bool, error) {
+	cmdRg := getRgCmd(ctx, patterns...)
 	if cmdRg != nil {
 		cmdRg.Dir = searchPath
-		matches, err := runRipgrep(cmdRg, searchPath, limit)
+		matches, err := runRipgrep(cmdRg, searchPath)
 		if err == nil {
-			return matches, len(matches) >= limit && limit > 0, nil
+			truncated := limit > 0 && len(matches) > limit
+			if truncated {
+				matches = matches[:limit]
+			}
+			return matches, truncated, nil
 		}
 		slog.Warn("Ripgrep execution failed, falling back to doublestar", "error", err)
 	}
 
-	return fsext.GlobWithDoubleStar(pattern, searchPath, limit)
+	return fsext.GlobFiles(patterns, searchPath, limit)
 }
 
-func runRipgrep(cmd *exec.Cmd, searchRoot string, limit int) ([]string, error) {
+// runRipgrep returns the files listed by ripgrep which aren't ignored, the
+// most recently modified first.
+func runRipgrep(cmd *exec.Cmd, searchRoot string) ([]fsext.FileInfo, error) {
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() == 1 {
@@ -161,7 +202,9 @@ func runRipgrep(cmd *exec.Cmd, searchRoot string, limit int) ([]string, error) {
 		return nil, fmt.Errorf("ripgrep: %w\n%s", err, out)
 	}
 
-	var matches []string
+	// Ripgrep doesn't know about the .crushignore files.
+	walker := fsext.NewFastGlobWalker(searchRoot)
+	var matches []fsext.FileInfo
 	for p := range bytes.SplitSeq(out, []byte{0}) {
 		if len(p) == 0 {
 			continue
@@ -170,18 +213,16 @@ func runRipgrep(cmd *exec.Cmd, searchRoot string, limit int) ([]string, error) {
 		if !filepath.IsAbs(absPath) {
 			absPath = filepath.Join(searchRoot, absPath)
 		}
-		if fsext.SkipHidden(absPath) {
+		if walker.ShouldSkip(absPath) {
+			continue
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
 			continue
 		}
-		matches = append(matches, absPath)
+		matches = append(matches, fsext.FileInfo{Path: absPath, ModTime: info.ModTime(), Size: info.Size()})
 	}
 
-	sort.SliceStable(matches, func(i, j int) bool {
-		return len(matches[i]) < len(matches[j])
-	})
-
-	if limit > 0 && len(matches) > limit {
-		matches = matches[:limit]
-	}
+	fsext.SortByModTime(matches)
 	return matches, nil
 }
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobTool(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":        "package main\n",
+		"web/app.ts":     "export {}\n",
+		"web/styles.css": "body {}\n",
+	} {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	input, err := json.Marshal(GlobParams{Pattern: "**/*.go", Patterns: []string{"web/*.{ts,tsx}"}, Path: tempDir})
+	require.NoError(t, err)
+	response, err := NewGlobTool(tempDir).Run(t.Context(), ToolCall{Input: string(input)})
+	require.NoError(t, err)
+	require.Contains(t, response.Content, "<files count=\"2\">\n")
+	require.Contains(t, response.Content, filepath.Join(tempDir, "main.go")+" (13 B, modified ")
+	require.Contains(t, response.Content, filepath.Join(tempDir, "web", "app.ts")+" (10 B, modified ")
+	require.NotContains(t, response.Content, "styles.css")
+
+	var meta GlobResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(response.Metadata), &meta))
+	require.Equal(t, 2, meta.NumberOfFiles)
+	require.Len(t, meta.Files, 2)
+	require.NotZero(t, meta.Files[0].ModTime)
+}
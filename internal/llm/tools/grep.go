@@ -2,19 +2,21 @@ package tools
 
 import (
 	"bufio"
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"sort"
-	"strconv"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/crush/internal/fsext"
 )
@@ -73,18 +75,35 @@ type GrepParams struct {
 	Pattern     string `json:"pattern"`
 	Path        string `json:"path"`
 	Include     string `json:"include"`
+	Type        string `json:"type,omitempty"`
 	LiteralText bool   `json:"literal_text"`
+	Multiline   bool   `json:"multiline,omitempty"`
+	Context     int    `json:"context,omitempty"`
+	CountOnly   bool   `json:"count_only,omitempty"`
+}
+
+// grepOptions are the options of a search, the pattern being a regex.
+type grepOptions struct {
+	pattern   string
+	include   string
+	fileType  string
+	multiline bool
+	context   int
 }
 
 type grepMatch struct {
-	path     string
-	modTime  time.Time
-	lineNum  int
+	path    string
+	modTime time.Time
+	lineNum int
+	// lineText has several lines for the multiline matches.
 	lineText string
+	// context is set for the lines around the matches.
+	context bool
 }
 
 type GrepResponseMetadata struct {
 	NumberOfMatches int  `json:"number_of_matches"`
+	NumberOfFiles   int  `json:"number_of_files"`
 	Truncated       bool `json:"truncated"`
 }
 
@@ -92,9 +111,29 @@ type grepTool struct {
 	workingDir string
 }
 
+const (
+	// maxGrepMatches bounds the matches shown, the others being summarized.
+	maxGrepMatches = 100
+	// maxGrepFileMatches bounds the matches shown for a file so a single
+	// file doesn't fill the results.
+	maxGrepFileMatches = 10
+	// maxGrepCountFiles bounds the files listed with count_only.
+	maxGrepCountFiles = 500
+	// maxGrepCollected bounds the matches collected, the search stopping
+	// past it.
+	maxGrepCollected = 10_000
+	maxGrepContext   = 10
+	// maxGrepLineLength bounds the lines shown, minified files having very
+	// long ones.
+	maxGrepLineLength = 500
+	// maxGrepFileSize is the size above which the files are skipped when
+	// searching without ripgrep.
+	maxGrepFileSize = 10 * 1024 * 1024
+)
+
 const (
 	GrepToolName    = "grep"
-	grepDescription = `Fast content search tool that finds files containing specific text or patterns, returning matching file paths sorted by modification time (newest first).
+	grepDescription = `Fast content search tool that finds files containing specific text or patterns, returning the matching lines grouped by file, the files sorted by modification time (newest first).
 
 WHEN TO USE THIS TOOL:
 - Use when you need to find files containing specific text or patterns
@@ -105,30 +144,37 @@ HOW TO USE:
 - Provide a regex pattern to search for within file contents
 - Set literal_text=true if you want to search for the exact text with special characters (recommended for non-regex users)
 - Optionally specify a starting directory (defaults to current working directory)
-- Optionally provide an include pattern to filter which files to search
-- Results are sorted with most recently modified files first
+- Optionally provide an include pattern or a file type to filter which files to search
+- Set multiline=true for patterns spanning several lines, like 'struct \{\n\s+Name'
+- Set context to show that many lines before and after each match
+- Set count_only=true to only get the number of matches in each file
+- Results are sorted with most recently modified files first, with the number of matches of each file
 
 REGEX PATTERN SYNTAX (when literal_text=false):
 - Supports standard regular expression syntax
 - 'function' searches for the literal text "function"
 - 'log\..*Error' finds text starting with "log." and ending with "Error"
 - 'import\s+.*\s+from' finds import statements in JavaScript/TypeScript
+- '.' doesn't match newlines, even with multiline=true, use '[\s\S]' or '(?s)' for it
 
 COMMON INCLUDE PATTERN EXAMPLES:
 - '*.js' - Only search JavaScript files
 - '*.{ts,tsx}' - Only search TypeScript files
 - '*.go' - Only search Go files
 
+COMMON FILE TYPES:
+- go, js, ts, py, rust, java, c, cpp, cs, ruby, php, kotlin, swift, sh, md, json, yaml, toml, html, css, sql
+
 LIMITATIONS:
-- Results are limited to 100 files (newest first)
+- At most 100 matches are shown, and 10 per file, the others are summarized with their count
 - Performance depends on the number of files being searched
-- Very large binary files may be skipped
+- Binary files are skipped
 - Hidden files (starting with '.') are skipped
 
 IGNORE FILE SUPPORT:
 - Respects .gitignore patterns to skip ignored files and directories
 - Respects .crushignore patterns for additional ignore rules
-- Both ignore files are automatically detected in the search root directory
+- The ignore files of the subdirectories apply to their files, like in git
 
 CROSS-PLATFORM NOTES:
 - Uses ripgrep (rg) command if available for better performance
@@ -138,10 +184,40 @@ CROSS-PLATFORM NOTES:
 TIPS:
 - For faster, more targeted searches, first use Glob to find relevant files, then use Grep
 - When doing iterative exploration that may require multiple rounds of searching, consider using the Agent tool instead
-- Always check if results are truncated and refine your search pattern if needed
+- When there are many matches, use count_only=true to find where they are, then search the files of interest
 - Use literal_text=true when searching for exact text containing special characters like dots, parentheses, etc.`
 )
 
+// grepFileTypes are the patterns of the file types, named like in ripgrep,
+// used when searching without it.
+var grepFileTypes = map[string][]string{
+	"c":        {"*.c", "*.h"},
+	"cpp":      {"*.cpp", "*.cc", "*.cxx", "*.c++", "*.hpp", "*.hh", "*.hxx", "*.h"},
+	"cs":       {"*.cs"},
+	"css":      {"*.css", "*.scss"},
+	"go":       {"*.go"},
+	"html":     {"*.html", "*.htm"},
+	"java":     {"*.java"},
+	"js":       {"*.js", "*.jsx", "*.mjs", "*.cjs", "*.vue"},
+	"json":     {"*.json"},
+	"kotlin":   {"*.kt", "*.kts"},
+	"lua":      {"*.lua"},
+	"markdown": {"*.md", "*.markdown", "*.mdx"},
+	"md":       {"*.md", "*.markdown", "*.mdx"},
+	"php":      {"*.php"},
+	"protobuf": {"*.proto"},
+	"py":       {"*.py", "*.pyi"},
+	"ruby":     {"*.rb", "Gemfile", "Rakefile"},
+	"rust":     {"*.rs"},
+	"scala":    {"*.scala", "*.sbt"},
+	"sh":       {"*.sh", "*.bash", "*.zsh"},
+	"sql":      {"*.sql"},
+	"swift":    {"*.swift"},
+	"toml":     {"*.toml"},
+	"ts":       {"*.ts", "*.tsx", "*.mts", "*.cts"},
+	"yaml":     {"*.yaml", "*.yml"},
+}
+
 func NewGrepTool(workingDir string) BaseTool {
 	return &grepTool{
 		workingDir: workingDir,
@@ -169,10 +245,26 @@ func (g *grepTool) Info() ToolInfo {
 				"type":        "string",
 				"description": "File pattern to include in the search (e.g. \"*.js\", \"*.{ts,tsx}\")",
 			},
+			"type": map[string]any{
+				"type":        "string",
+				"description": "File type to search, named like in ripgrep (e.g. \"go\", \"py\", \"ts\")",
+			},
 			"literal_text": map[string]any{
 				"type":        "boolean",
 				"description": "If true, the pattern will be treated as literal text with special regex characters escaped. Default is false.",
 			},
+			"multiline": map[string]any{
+				"type":        "boolean",
+				"description": "If true, the pattern can match across lines. Default is false.",
+			},
+			"context": map[string]any{
+				"type":        "integer",
+				"description": "Number of lines to show before and after each match (at most 10). Default is 0.",
+			},
+			"count_only": map[string]any{
+				"type":        "boolean",
+				"description": "If true, only the number of matches of each file is returned. Default is false.",
+			},
 		},
 		Required: []string{"pattern"},
 	}
@@ -199,6 +291,11 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	if params.Pattern == "" {
 		return NewTextErrorResponse("pattern is required"), nil
 	}
+	if params.Type != "" {
+		if _, ok := grepFileTypes[params.Type]; !ok && getRg() == "" {
+			return NewTextErrorResponse(fmt.Sprintf("unknown file type %q, use one of: %s", params.Type, strings.Join(slices.Sorted(maps.Keys(grepFileTypes)), ", "))), nil
+		}
+	}
 
 	// If literal_text is true, escape the pattern
 	searchPattern := params.Pattern
@@ -206,219 +303,448 @@ func (g *grepTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		searchPattern = escapeRegexPattern(params.Pattern)
 	}
 
-	searchPath := params.Path
-	if searchPath == "" {
-		searchPath = g.workingDir
-	}
+	searchPath := fsext.AbsPath(params.Path, g.workingDir)
 
-	matches, truncated, err := searchFiles(ctx, searchPattern, searchPath, params.Include, 100)
+	opts := grepOptions{
+		pattern:   searchPattern,
+		include:   params.Include,
+		fileType:  params.Type,
+		multiline: params.Multiline,
+		context:   min(max(params.Context, 0), maxGrepContext),
+	}
+	if params.CountOnly {
+		opts.context = 0
+	}
+	matches, capped, err := searchFiles(ctx, opts, searchPath)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error searching files: %w", err)
 	}
 
+	files := groupMatches(matches)
+	total := 0
+	for _, file := range files {
+		total += file.matches
+	}
+
 	var output strings.Builder
-	if len(matches) == 0 {
+	truncated := capped
+	switch {
+	case total == 0:
 		output.WriteString("No files found")
-	} else {
-		fmt.Fprintf(&output, "Found %d matches\n", len(matches))
-
-		currentFile := ""
-		for _, match := range matches {
-			if currentFile != match.path {
-				if currentFile != "" {
-					output.WriteString("\n")
-				}
-				currentFile = match.path
-				fmt.Fprintf(&output, "%s:\n", match.path)
-			}
-			if match.lineNum > 0 {
-				fmt.Fprintf(&output, "  Line %d: %s\n", match.lineNum, match.lineText)
-			} else {
-				fmt.Fprintf(&output, "  %s\n", match.path)
-			}
-		}
-
-		if truncated {
-			output.WriteString("\n(Results are truncated. Consider using a more specific path or pattern.)")
-		}
+	case params.CountOnly:
+		truncated = writeMatchCounts(&output, files, total, capped) || truncated
+	default:
+		truncated = writeMatches(&output, files, total, opts.context, capped) || truncated
 	}
 
 	return WithResponseMetadata(
 		NewTextResponse(output.String()),
 		GrepResponseMetadata{
-			NumberOfMatches: len(matches),
+			NumberOfMatches: total,
+			NumberOfFiles:   len(files),
 			Truncated:       truncated,
 		},
 	), nil
 }
 
-func searchFiles(ctx context.Context, pattern, rootPath, include string, limit int) ([]grepMatch, bool, error) {
-	matches, err := searchWithRipgrep(ctx, pattern, rootPath, include)
-	if err != nil {
-		matches, err = searchFilesWithRegex(pattern, rootPath, include)
-		if err != nil {
-			return nil, false, err
+// grepFile are the lines found in a file, in order.
+type grepFile struct {
+	path    string
+	modTime time.Time
+	lines   []grepMatch
+	matches int
+}
+
+// groupMatches groups the lines by file, the files ranked with the most
+// recently modified first.
+func groupMatches(matches []grepMatch) []*grepFile {
+	byPath := make(map[string]*grepFile)
+	var files []*grepFile
+	for _, match := range matches {
+		file := byPath[match.path]
+		if file == nil {
+			file = &grepFile{path: match.path, modTime: match.modTime}
+			byPath[match.path] = file
+			files = append(files, file)
+		}
+		file.lines = append(file.lines, match)
+		if !match.context {
+			file.matches++
 		}
 	}
-
-	sort.Slice(matches, func(i, j int) bool {
-		return matches[i].modTime.After(matches[j].modTime)
+	for _, file := range files {
+		slices.SortStableFunc(file.lines, func(a, b grepMatch) int {
+			return cmp.Compare(a.lineNum, b.lineNum)
+		})
+	}
+	slices.SortStableFunc(files, func(a, b *grepFile) int {
+		if c := b.modTime.Compare(a.modTime); c != 0 {
+			return c
+		}
+		return strings.Compare(a.path, b.path)
 	})
+	return files
+}
+
+func foundSummary(total, files int, capped bool) string {
+	if capped {
+		return fmt.Sprintf("Found more than %d matches in %d files (the search stopped there)\n", total, files)
+	}
+	return fmt.Sprintf("Found %d matches in %d files\n", total, files)
+}
+
+// writeMatches writes the matching lines of the files within the budget, the
+// others being summarized, and reports whether some were left out.
+func writeMatches(output *strings.Builder, files []*grepFile, total, context int, capped bool) bool {
+	output.WriteString(foundSummary(total, len(files), capped))
+	shown, hiddenMatches, hiddenFiles := 0, 0, 0
+	for _, file := range files {
+		budget := min(maxGrepFileMatches, maxGrepMatches-shown)
+		if budget <= 0 {
+			hiddenMatches += file.matches
+			hiddenFiles++
+			continue
+		}
+		fmt.Fprintf(output, "\n%s (%s):\n", file.path, pluralMatches(file.matches))
+		shown += writeFileLines(output, file, budget, context)
+		if file.matches > budget {
+			fmt.Fprintf(output, "  (%d more matches in this file)\n", file.matches-budget)
+		}
+	}
+	if hiddenFiles > 0 {
+		fmt.Fprintf(output, "\n(%d more matches in %d files not shown. Use a more specific path, pattern, include or type, or count_only=true to see the matches of every file.)", hiddenMatches, hiddenFiles)
+	}
+	return shown < total
+}
+
+// writeFileLines writes the lines of the first matches of the file with
+// their context, separating the groups of lines which don't follow each
+// other, and returns the number of matches written.
+func writeFileLines(output *strings.Builder, file *grepFile, budget, context int) int {
+	var shown []grepMatch
+	written, lastLine := 0, 0
+	for _, line := range file.lines {
+		if !line.context {
+			if written == budget {
+				break
+			}
+			written++
+			lastLine = line.lineNum + strings.Count(line.lineText, "\n")
+		}
+		shown = append(shown, line)
+	}
+	// The context of the first match left out isn't shown.
+	for len(shown) > 0 && shown[len(shown)-1].context && shown[len(shown)-1].lineNum > lastLine+context {
+		shown = shown[:len(shown)-1]
+	}
+
+	prev := 0
+	for _, line := range shown {
+		if context > 0 && prev > 0 && line.lineNum > prev+1 {
+			output.WriteString("  --\n")
+		}
+		sep := ":"
+		if line.context {
+			sep = "-"
+		}
+		texts := strings.Split(line.lineText, "\n")
+		for i, text := range texts {
+			fmt.Fprintf(output, "  Line %d%s %s\n", line.lineNum+i, sep, truncateLine(text))
+		}
+		prev = line.lineNum + len(texts) - 1
+	}
+	return written
+}
+
+// writeMatchCounts writes the number of matches of each file, and reports
+// whether some files were left out.
+func writeMatchCounts(output *strings.Builder, files []*grepFile, total int, capped bool) bool {
+	output.WriteString(foundSummary(total, len(files), capped))
+	for i, file := range files {
+		if i == maxGrepCountFiles {
+			fmt.Fprintf(output, "\n(%d more files not shown. Use a more specific path, pattern, include or type.)", len(files)-i)
+			return true
+		}
+		fmt.Fprintf(output, "%s: %s\n", file.path, pluralMatches(file.matches))
+	}
+	return false
+}
 
-	truncated := len(matches) > limit
-	if truncated {
-		matches = matches[:limit]
+func pluralMatches(n int) string {
+	if n == 1 {
+		return "1 match"
 	}
+	return fmt.Sprintf("%d matches", n)
+}
 
-	return matches, truncated, nil
+func truncateLine(text string) string {
+	if len(text) <= maxGrepLineLength {
+		return text
+	}
+	cut := maxGrepLineLength
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "... (line truncated)"
 }
 
-func searchWithRipgrep(ctx context.Context, pattern, path, include string) ([]grepMatch, error) {
-	cmd := getRgSearchCmd(ctx, pattern, path, include)
+// searchFiles returns the matching lines, with their context, and whether
+// the search stopped at maxGrepCollected matches.
+func searchFiles(ctx context.Context, opts grepOptions, rootPath string) ([]grepMatch, bool, error) {
+	matches, capped, err := searchWithRipgrep(ctx, opts, rootPath)
+	if err != nil {
+		matches, capped, err = searchFilesWithRegex(opts, rootPath)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return matches, capped, nil
+}
+
+// rgMessage is a message of the JSON output of ripgrep.
+type rgMessage struct {
+	Type string `json:"type"`
+	Data struct {
+		Path struct {
+			Text string `json:"text"`
+		} `json:"path"`
+		Lines struct {
+			Text string `json:"text"`
+		} `json:"lines"`
+		LineNumber int `json:"line_number"`
+	} `json:"data"`
+}
+
+func searchWithRipgrep(ctx context.Context, opts grepOptions, path string) ([]grepMatch, bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := getRgSearchCmd(ctx, opts, path)
 	if cmd == nil {
-		return nil, fmt.Errorf("ripgrep not found in $PATH")
+		return nil, false, fmt.Errorf("ripgrep not found in $PATH")
 	}
 
+	// Ripgrep only reads the .crushignore files given, the ones of the
+	// subdirectories are applied to its results.
 	cmd.Args = append(
 		cmd.Args,
 		"--ignore-file", filepath.Join(path, ".gitignore"),
 		"--ignore-file", filepath.Join(path, ".crushignore"),
 	)
+	walker := fsext.NewFastGlobWalker(path)
 
-	output, err := cmd.Output()
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return []grepMatch{}, nil
-		}
-		return nil, err
+		return nil, false, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, false, err
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	matches := make([]grepMatch, 0, len(lines))
-
-	for _, line := range lines {
-		if line == "" {
+	matches := []grepMatch{}
+	modTimes := make(map[string]time.Time)
+	found, capped := 0, false
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxGrepFileSize)
+	for scanner.Scan() {
+		var msg rgMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
 			continue
 		}
-
-		// Parse ripgrep output format: file:line:content
-		parts := strings.SplitN(line, ":", 3)
-		if len(parts) < 3 {
+		if msg.Type != "match" && msg.Type != "context" {
 			continue
 		}
-
-		filePath := parts[0]
-		lineNum, err := strconv.Atoi(parts[1])
-		if err != nil {
+		filePath := msg.Data.Path.Text
+		if filePath == "" || walker.ShouldSkip(filePath) {
 			continue
 		}
-		lineText := parts[2]
-
-		fileInfo, err := os.Stat(filePath)
-		if err != nil {
-			continue // Skip files we can't access
+		modTime, ok := modTimes[filePath]
+		if !ok {
+			fileInfo, err := os.Stat(filePath)
+			if err != nil {
+				continue // Skip files we can't access
+			}
+			modTime = fileInfo.ModTime()
+			modTimes[filePath] = modTime
+		}
+		if msg.Type == "match" {
+			if found == maxGrepCollected {
+				capped = true
+				break
+			}
+			found++
 		}
-
 		matches = append(matches, grepMatch{
 			path:     filePath,
-			modTime:  fileInfo.ModTime(),
-			lineNum:  lineNum,
-			lineText: lineText,
+			modTime:  modTime,
+			lineNum:  msg.Data.LineNumber,
+			lineText: strings.TrimSuffix(strings.TrimSuffix(msg.Data.Lines.Text, "\n"), "\r"),
+			context:  msg.Type == "context",
 		})
 	}
-
-	return matches, nil
+	if capped {
+		cancel()
+		_ = cmd.Wait()
+		return matches, true, nil
+	}
+	if err := cmd.Wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return []grepMatch{}, false, nil
+		}
+		return nil, false, err
+	}
+	return matches, false, nil
 }
 
-func searchFilesWithRegex(pattern, rootPath, include string) ([]grepMatch, error) {
+func searchFilesWithRegex(opts grepOptions, rootPath string) ([]grepMatch, bool, error) {
 	matches := []grepMatch{}
 
 	// Use cached regex compilation
-	regex, err := searchRegexCache.get(pattern)
+	regex, err := searchRegexCache.get(opts.pattern)
 	if err != nil {
-		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+		return nil, false, fmt.Errorf("invalid regex pattern: %w", err)
 	}
 
 	var includePattern *regexp.Regexp
-	if include != "" {
-		regexPattern := globToRegex(include)
+	if opts.include != "" {
+		regexPattern := globToRegex(opts.include)
 		includePattern, err = globRegexCache.get(regexPattern)
 		if err != nil {
-			return nil, fmt.Errorf("invalid include pattern: %w", err)
+			return nil, false, fmt.Errorf("invalid include pattern: %w", err)
 		}
 	}
+	typePatterns, ok := grepFileTypes[opts.fileType]
+	if opts.fileType != "" && !ok {
+		return nil, false, fmt.Errorf("unknown file type: %s", opts.fileType)
+	}
 
 	// Create walker with gitignore and crushignore support
 	walker := fsext.NewFastGlobWalker(rootPath)
 
+	found, capped := 0, false
 	err = filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
 
 		if info.IsDir() {
-			return nil // Skip directories
+			if path != rootPath && walker.ShouldSkip(path) {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
 		// Use walker's shouldSkip method instead of just SkipHidden
-		if walker.ShouldSkip(path) {
+		if walker.ShouldSkip(path) || info.Size() > maxGrepFileSize {
 			return nil
 		}
 
 		if includePattern != nil && !includePattern.MatchString(path) {
 			return nil
 		}
+		if typePatterns != nil && !slices.ContainsFunc(typePatterns, func(pattern string) bool {
+			matched, _ := filepath.Match(pattern, info.Name())
+			return matched
+		}) {
+			return nil
+		}
 
-		match, lineNum, lineText, err := fileContainsPattern(path, regex)
+		lines, err := fileMatches(path, regex, opts)
 		if err != nil {
 			return nil // Skip files we can't read
 		}
 
-		if match {
-			matches = append(matches, grepMatch{
-				path:     path,
-				modTime:  info.ModTime(),
-				lineNum:  lineNum,
-				lineText: lineText,
-			})
-
-			if len(matches) >= 200 {
-				return filepath.SkipAll
+		for _, line := range lines {
+			if !line.context {
+				if found == maxGrepCollected {
+					capped = true
+					return filepath.SkipAll
+				}
+				found++
 			}
+			line.path = path
+			line.modTime = info.ModTime()
+			matches = append(matches, line)
 		}
-
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	return matches, nil
+	return matches, capped, nil
 }
 
-func fileContainsPattern(filePath string, pattern *regexp.Regexp) (bool, int, string, error) {
+// fileMatches returns the lines of the file matching the pattern, the
+// overlapping multiline matches being merged, with their context.
+func fileMatches(filePath string, pattern *regexp.Regexp, opts grepOptions) ([]grepMatch, error) {
 	// Quick binary file detection
 	if isBinaryFile(filePath) {
-		return false, 0, "", nil
+		return nil, nil
 	}
 
-	file, err := os.Open(filePath)
+	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return false, 0, "", err
+		return nil, err
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		if pattern.MatchString(line) {
-			return true, lineNum, line, nil
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	// Each match is the range of lines from start to end, indexed from 0.
+	type lineRange struct{ start, end int }
+	var ranges []lineRange
+	if opts.multiline {
+		// starts are the offsets of the lines.
+		starts := make([]int, len(lines))
+		offset := 0
+		for i, line := range lines {
+			starts[i] = offset
+			offset += len(line) + 1
+		}
+		lineOf := func(offset int) int {
+			i, found := slices.BinarySearch(starts, offset)
+			if !found {
+				i--
+			}
+			return i
+		}
+		for _, loc := range pattern.FindAllStringIndex(content, -1) {
+			end := loc[1]
+			if end > loc[0] {
+				// A match ending with a newline ends on its line.
+				end--
+			}
+			r := lineRange{lineOf(loc[0]), min(lineOf(end), len(lines)-1)}
+			if n := len(ranges); n > 0 && r.start <= ranges[n-1].end {
+				ranges[n-1].end = max(ranges[n-1].end, r.end)
+				continue
+			}
+			ranges = append(ranges, r)
+		}
+	} else {
+		for i, line := range lines {
+			if pattern.MatchString(line) {
+				ranges = append(ranges, lineRange{i, i})
+			}
 		}
 	}
 
-	return false, 0, "", scanner.Err()
+	var matches []grepMatch
+	next := 0 // The first line which isn't written yet.
+	for i, r := range ranges {
+		for n := max(next, r.start-opts.context); n < r.start; n++ {
+			matches = append(matches, grepMatch{lineNum: n + 1, lineText: lines[n], context: true})
+		}
+		matches = append(matches, grepMatch{lineNum: r.start + 1, lineText: strings.Join(lines[r.start:r.end+1], "\n")})
+		next = r.end + 1
+		after := min(r.end+opts.context, len(lines)-1)
+		if i+1 < len(ranges) {
+			after = min(after, ranges[i+1].start-1)
+		}
+		for ; next <= after; next++ {
+			matches = append(matches, grepMatch{lineNum: next + 1, lineText: lines[next], context: true})
+		}
+	}
+	return matches, nil
 }
 
 var binaryExts = map[string]struct{}{
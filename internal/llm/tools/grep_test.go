@@ -3,10 +3,13 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -135,10 +138,10 @@ func TestSearchImplementations(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("file4.txt\n"), 0o644))
 	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".crushignore"), []byte("file5.txt\n"), 0o644))
 
-	for name, fn := range map[string]func(pattern, path, include string) ([]grepMatch, error){
+	for name, fn := range map[string]func(opts grepOptions, path string) ([]grepMatch, bool, error){
 		"regex": searchFilesWithRegex,
-		"rg": func(pattern, path, include string) ([]grepMatch, error) {
-			return searchWithRipgrep(t.Context(), pattern, path, include)
+		"rg": func(opts grepOptions, path string) ([]grepMatch, bool, error) {
+			return searchWithRipgrep(t.Context(), opts, path)
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
@@ -148,8 +151,9 @@ func TestSearchImplementations(t *testing.T) {
 				t.Skip("rg is not in $PATH")
 			}
 
-			matches, err := fn("hello world", tempDir, "")
+			matches, capped, err := fn(grepOptions{pattern: "hello world"}, tempDir)
 			require.NoError(t, err)
+			require.False(t, capped)
 
 			require.Equal(t, len(matches), 4)
 			for _, match := range matches {
@@ -166,6 +170,119 @@ func TestSearchImplementations(t *testing.T) {
 	}
 }
 
+func TestFileMatches(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "main.go")
+	content := "package main\n\ntype User struct {\n\tName string\n}\n\nfunc main() {\n\tprintln(\"user\")\n}\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	t.Run("context", func(t *testing.T) {
+		t.Parallel()
+		matches, err := fileMatches(path, regexp.MustCompile(`Name|println`), grepOptions{context: 1})
+		require.NoError(t, err)
+		require.Equal(t, []grepMatch{
+			{lineNum: 3, lineText: "type User struct {", context: true},
+			{lineNum: 4, lineText: "\tName string"},
+			{lineNum: 5, lineText: "}", context: true},
+			{lineNum: 7, lineText: "func main() {", context: true},
+			{lineNum: 8, lineText: "\tprintln(\"user\")"},
+			{lineNum: 9, lineText: "}", context: true},
+		}, matches)
+	})
+
+	t.Run("multiline", func(t *testing.T) {
+		t.Parallel()
+		matches, err := fileMatches(path, regexp.MustCompile(`struct \{\n\s+Name`), grepOptions{multiline: true})
+		require.NoError(t, err)
+		require.Equal(t, []grepMatch{
+			{lineNum: 3, lineText: "type User struct {\n\tName string"},
+		}, matches)
+	})
+}
+
+func TestSearchFilesWithRegexType(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	for path, content := range map[string]string{
+		"main.go":          "hello",
+		"app.ts":           "hello",
+		"sub/lib.go":       "hello",
+		"sub/gen.go":       "hello",
+		"sub/.crushignore": "gen.go\n",
+	} {
+		fullPath := filepath.Join(tempDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0o755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0o644))
+	}
+
+	matches, _, err := searchFilesWithRegex(grepOptions{pattern: "hello", fileType: "go"}, tempDir)
+	require.NoError(t, err)
+	var paths []string
+	for _, match := range matches {
+		rel, err := filepath.Rel(tempDir, match.path)
+		require.NoError(t, err)
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	// The .crushignore of the subdirectory applies to its files.
+	require.ElementsMatch(t, []string{"main.go", "sub/lib.go"}, paths)
+
+	_, _, err = searchFilesWithRegex(grepOptions{pattern: "hello", fileType: "cobol"}, tempDir)
+	require.Error(t, err)
+}
+
+func TestWriteMatches(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	var matches []grepMatch
+	for i := range 30 {
+		matches = append(matches, grepMatch{path: "big.go", modTime: now, lineNum: i + 1, lineText: "match"})
+	}
+	for i := range 200 {
+		path := fmt.Sprintf("file%03d.go", i)
+		matches = append(matches, grepMatch{path: path, modTime: now.Add(-time.Hour), lineNum: 1, lineText: "match"})
+	}
+	files := groupMatches(matches)
+	require.Equal(t, "big.go", files[0].path)
+
+	var output strings.Builder
+	truncated := writeMatches(&output, files, 230, 0, false)
+	require.True(t, truncated)
+	text := output.String()
+	require.True(t, strings.HasPrefix(text, "Found 230 matches in 201 files\n\nbig.go (30 matches):\n  Line 1: match\n"), text)
+	require.Contains(t, text, "  Line 10: match\n  (20 more matches in this file)\n")
+	require.Contains(t, text, "file089.go (1 match):\n")
+	require.NotContains(t, text, "file090.go")
+	require.True(t, strings.HasSuffix(text, "(110 more matches in 110 files not shown. Use a more specific path, pattern, include or type, or count_only=true to see the matches of every file.)"), text)
+
+	output.Reset()
+	require.False(t, writeMatchCounts(&output, files, 230, false))
+	require.Contains(t, output.String(), "big.go: 30 matches\nfile000.go: 1 match\n")
+}
+
+func TestWriteFileLinesContext(t *testing.T) {
+	t.Parallel()
+
+	file := &grepFile{path: "main.go", lines: []grepMatch{
+		{lineNum: 1, lineText: "a", context: true},
+		{lineNum: 2, lineText: "match"},
+		{lineNum: 3, lineText: "b", context: true},
+		{lineNum: 9, lineText: "c", context: true},
+		{lineNum: 10, lineText: "match"},
+	}, matches: 2}
+
+	var output strings.Builder
+	require.Equal(t, 2, writeFileLines(&output, file, 10, 1))
+	require.Equal(t, "  Line 1- a\n  Line 2: match\n  Line 3- b\n  --\n  Line 9- c\n  Line 10: match\n", output.String())
+
+	// The context before the first match left out isn't shown.
+	output.Reset()
+	require.Equal(t, 1, writeFileLines(&output, file, 1, 1))
+	require.Equal(t, "  Line 1- a\n  Line 2: match\n  Line 3- b\n", output.String())
+}
+
 // Benchmark to show performance improvement
 func BenchmarkRegexCacheVsCompile(b *testing.B) {
 	cache := newRegexCache()
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tool categories group the tools sharing limits.
+const (
+	CategoryShell    = "shell"
+	CategoryFile     = "file"
+	CategoryNetwork  = "network"
+	CategoryLSP      = "lsp"
+	CategoryDatabase = "database"
+	CategoryMCP      = "mcp"
+	CategoryAgent    = "agent"
+)
+
+// Category returns the category of a tool.
+func Category(name string) string {
+	switch name {
+	case BashToolName, RunTestsToolName:
+		return CategoryShell
+	case FetchToolName, DownloadToolName, SourcegraphToolName:
+		return CategoryNetwork
+	case DiagnosticsToolName, FindDefinitionToolName, ListSymbolsToolName, OutlineFileToolName,
+		RenameSymbolToolName, CodeActionToolName, FormatFileToolName, FindReferencesToolName, CallHierarchyToolName,
+		WorkspaceSymbolsToolName:
+		return CategoryLSP
+	case DBQueryToolName:
+		return CategoryDatabase
+	case "agent": // the agent tool, defined by the agent package
+		return CategoryAgent
+	}
+	if strings.HasPrefix(name, "mcp_") {
+		return CategoryMCP
+	}
+	return CategoryFile
+}
+
+// Limits exceeded by tool calls.
+const (
+	LimitTimeout        = "timeout"
+	LimitMaxOutputBytes = "max_output_bytes"
+	LimitMaxMemoryMB    = "max_memory_mb"
+	LimitMaxCPUSeconds  = "max_cpu_seconds"
+)
+
+// LimitViolation is the metadata of the error returned when a tool call
+// exceeds one of its limits.
+type LimitViolation struct {
+	Tool  string `json:"tool"`
+	Limit string `json:"limit"`
+	Value int    `json:"value"`
+}
+
+// NewLimitErrorResponse reports a limit exceeded by a tool call, with the
+// output the tool produced before that, if any.
+func NewLimitErrorResponse(v LimitViolation, output string) ToolResponse {
+	var msg string
+	switch v.Limit {
+	case LimitTimeout:
+		msg = fmt.Sprintf("Tool %s timed out after %d seconds", v.Tool, v.Value)
+	case LimitMaxOutputBytes:
+		msg = fmt.Sprintf("Output of tool %s exceeded %d bytes and was truncated", v.Tool, v.Value)
+	case LimitMaxMemoryMB:
+		msg = fmt.Sprintf("Tool %s exceeded its memory limit of %d MB", v.Tool, v.Value)
+	case LimitMaxCPUSeconds:
+		msg = fmt.Sprintf("Tool %s exceeded its CPU time limit of %d seconds", v.Tool, v.Value)
+	default:
+		msg = fmt.Sprintf("Tool %s exceeded its %s limit of %d", v.Tool, v.Limit, v.Value)
+	}
+	if output != "" {
+		msg = strings.TrimRight(output, "\n") + "\n\n" + msg
+	}
+	return WithResponseMetadata(NewTextErrorResponse(msg), v)
+}
@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/symbols"
+)
+
+type ListSymbolsParams struct {
+	Path  string `json:"path"`
+	Query string `json:"query"`
+	Kind  string `json:"kind"`
+}
+
+type ListSymbolsResponseMetadata struct {
+	NumberOfSymbols int  `json:"number_of_symbols"`
+	NumberOfFiles   int  `json:"number_of_files"`
+	Truncated       bool `json:"truncated"`
+}
+
+type listSymbolsTool struct {
+	workingDir string
+}
+
+const (
+	ListSymbolsToolName    = "list_symbols"
+	listSymbolsDescription = `Lists the symbols (functions, methods, classes, types...) declared in a file or in the source files of a directory, without needing a language server.
+
+WHEN TO USE THIS TOOL:
+- Use to get an overview of what a package or directory declares
+- Use to find symbols whose name contains some text, e.g. all handlers
+- Use instead of grep when you are looking for declarations rather than usages
+
+HOW TO USE:
+- Provide a "path" to a file or directory (defaults to the current working directory)
+- Optionally provide a "query" to keep only the symbols whose name contains it (case-insensitive)
+- Optionally provide a "kind" to keep only one kind of symbol: function, method, class, interface, struct, enum, trait, impl, type, module, constant or variable
+
+SUPPORTED LANGUAGES:
+Go, Python, JavaScript, TypeScript, Rust, Java, C, C++, C#, Ruby, PHP, Kotlin, Swift, Scala, Lua and Bash.
+
+LIMITATIONS:
+- Results are limited to 200 symbols, use a more specific path or query
+- Files ignored by .gitignore and .crushignore are skipped
+`
+
+	maxListedSymbols = 200
+	maxSymbolFiles   = 5000
+)
+
+// symbolsDescription adds the limits of the heuristic parser to the
+// description of a symbol tool, in the builds without tree-sitter.
+func symbolsDescription(description string) string {
+	if symbols.Precise() {
+		return description
+	}
+	return description + "- Declarations are recognized line by line, those spanning several lines may be missed and their end lines are estimated\n"
+}
+
+func NewListSymbolsTool(workingDir string) BaseTool {
+	return &listSymbolsTool{workingDir: workingDir}
+}
+
+func (l *listSymbolsTool) Name() string {
+	return ListSymbolsToolName
+}
+
+func (l *listSymbolsTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ListSymbolsToolName,
+		Description: symbolsDescription(listSymbolsDescription),
+		Parameters: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "The file or directory to list the symbols of. Defaults to the current working directory.",
+			},
+			"query": map[string]any{
+				"type":        "string",
+				"description": "Only list the symbols whose name contains this text (case-insensitive)",
+			},
+			"kind": map[string]any{
+				"type":        "string",
+				"description": "Only list the symbols of this kind",
+			},
+		},
+		Required: []string{},
+	}
+}
+
+func (l *listSymbolsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ListSymbolsParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	searchPath := resolveSymbolsPath(l.workingDir, params.Path)
+	if _, err := os.Stat(searchPath); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("path does not exist: %s", searchPath)), nil
+	}
+
+	query := strings.ToLower(params.Query)
+	var sb strings.Builder
+	var metadata ListSymbolsResponseMetadata
+	err := walkSymbols(ctx, searchPath, func(path string, syms []symbols.Symbol) bool {
+		var lines []string
+		for _, s := range symbols.Flatten(syms) {
+			if query != "" && !strings.Contains(strings.ToLower(s.Name), query) {
+				continue
+			}
+			if params.Kind != "" && string(s.Kind) != params.Kind {
+				continue
+			}
+			if metadata.NumberOfSymbols == maxListedSymbols {
+				metadata.Truncated = true
+				break
+			}
+			lines = append(lines, "  "+formatSymbol(s))
+			metadata.NumberOfSymbols++
+		}
+		if len(lines) > 0 {
+			metadata.NumberOfFiles++
+			sb.WriteString(relativeSymbolsPath(l.workingDir, path) + ":\n")
+			sb.WriteString(strings.Join(lines, "\n") + "\n")
+		}
+		return !metadata.Truncated
+	})
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error listing symbols: %w", err)
+	}
+
+	output := sb.String()
+	if metadata.NumberOfSymbols == 0 {
+		output = "No symbols found"
+	}
+	if metadata.Truncated {
+		output += fmt.Sprintf("\n(Results are truncated to %d symbols. Consider using a more specific path or query.)", maxListedSymbols)
+	}
+	return WithResponseMetadata(NewTextResponse(strings.TrimSpace(output)), metadata), nil
+}
+
+// walkSymbols parses path, or every supported source file under it, and
+// calls fn with the symbols of each file until fn returns false.
+func walkSymbols(ctx context.Context, path string, fn func(path string, syms []symbols.Symbol) bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files, _, err = fsext.ListDirectory(path, nil, maxSymbolFiles)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, ok := symbols.LanguageForFile(file); !ok {
+			continue
+		}
+		syms, err := symbols.ParseFile(file)
+		// Unreadable and oversized files are skipped.
+		if err != nil || len(syms) == 0 {
+			continue
+		}
+		if !fn(file, syms) {
+			break
+		}
+	}
+	return nil
+}
+
+func resolveSymbolsPath(workingDir, path string) string {
+	if path == "" {
+		return workingDir
+	}
+	return fsext.AbsPath(path, workingDir)
+}
+
+func relativeSymbolsPath(workingDir, path string) string {
+	if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+func formatSymbol(s symbols.Symbol) string {
+	out := fmt.Sprintf("%d: %s %s", s.Line, s.Kind, s.Name)
+	if s.Container != "" {
+		out += " (in " + s.Container + ")"
+	}
+	return out
+}
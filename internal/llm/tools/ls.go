@@ -127,9 +127,7 @@ func (l *lsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
 		return ToolResponse{}, fmt.Errorf("error expanding path: %w", err)
 	}
 
-	if !filepath.IsAbs(searchPath) {
-		searchPath = filepath.Join(l.workingDir, searchPath)
-	}
+	searchPath = fsext.AbsPath(searchPath, l.workingDir)
 
 	// Check if directory is outside working directory and request permission if needed
 	absWorkingDir, err := filepath.Abs(l.workingDir)
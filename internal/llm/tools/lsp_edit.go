@@ -0,0 +1,241 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+	"github.com/charmbracelet/crush/internal/lsp/util"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/trash"
+)
+
+// LSPFileEdit is a file changed by an edit computed by a language server.
+type LSPFileEdit struct {
+	FilePath   string `json:"file_path"`
+	OldContent string `json:"old_content,omitempty"`
+	NewContent string `json:"new_content,omitempty"`
+}
+
+// LSPEditPermissionsParams are the files the rename, code action and format
+// tools are about to change.
+type LSPEditPermissionsParams struct {
+	Files []LSPFileEdit `json:"files"`
+}
+
+type LSPEditResponseMetadata struct {
+	Files     []LSPFileEdit `json:"files"`
+	Additions int           `json:"additions"`
+	Removals  int           `json:"removals"`
+}
+
+// LSPEditContents joins the files changed by an edit so they are shown as a
+// single diff, each file under its own header.
+func LSPEditContents(files []LSPFileEdit) (oldContent, newContent string) {
+	if len(files) == 1 {
+		return files[0].OldContent, files[0].NewContent
+	}
+	var before, after strings.Builder
+	for _, f := range files {
+		header := fmt.Sprintf("── %s ──\n", fsext.PrettyPath(f.FilePath))
+		before.WriteString(header)
+		before.WriteString(ensureTrailingNewline(f.OldContent))
+		after.WriteString(header)
+		after.WriteString(ensureTrailingNewline(f.NewContent))
+	}
+	return before.String(), after.String()
+}
+
+func ensureTrailingNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
+
+// lspEditor applies the workspace edits computed by the language servers,
+// through the same permission, trash and history as the edit tools.
+type lspEditor struct {
+	lspClients  map[string]*lsp.Client
+	permissions permission.Service
+	files       history.Service
+	trashBin    trash.Service
+	workingDir  string
+}
+
+func (e *lspEditor) absPath(path string) string {
+	return fsext.AbsPath(path, e.workingDir)
+}
+
+func (e *lspEditor) clientFor(ctx context.Context, filePath string) (*lsp.Client, error) {
+	return lspClientFor(ctx, e.lspClients, filePath)
+}
+
+// lspClientFor returns the first ready language server handling the file,
+// with the file synced so the server answers on its current content.
+func lspClientFor(ctx context.Context, lspClients map[string]*lsp.Client, filePath string) (*lsp.Client, error) {
+	for _, name := range slices.Sorted(maps.Keys(lspClients)) {
+		client := lspClients[name]
+		if client.GetServerState() != lsp.StateReady || !client.HandlesFile(filePath) {
+			continue
+		}
+		// The server computes its edits on the content it knows.
+		if err := syncLspFile(ctx, client, filePath); err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+	return nil, fmt.Errorf("no language server available for %s", filePath)
+}
+
+func syncLspFile(ctx context.Context, client *lsp.Client, filePath string) error {
+	if client.IsFileOpen(filePath) {
+		return client.NotifyChange(ctx, filePath)
+	}
+	return client.OpenFile(ctx, filePath)
+}
+
+// apply asks for the permission to apply a workspace edit, showing its diff,
+// then writes the changed files.
+func (e *lspEditor) apply(ctx context.Context, call ToolCall, toolName, description string, edit protocol.WorkspaceEdit) (ToolResponse, error) {
+	textEdits, err := util.WorkspaceTextEdits(edit)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error applying the edit: %s", err)), nil
+	}
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for editing files")
+	}
+
+	var files []LSPFileEdit
+	var crlf []bool
+	for _, uri := range slices.Sorted(maps.Keys(textEdits)) {
+		path, err := uri.Path()
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("invalid file URI %s: %s", uri, err)), nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
+		}
+		edited, err := util.ApplyTextEditsToContent(content, textEdits[uri])
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("error applying the edit to %s: %s", path, err)), nil
+		}
+		oldContent, isCrlf := fsext.ToUnixLineEndings(string(content))
+		newContent, _ := fsext.ToUnixLineEndings(string(edited))
+		if oldContent == newContent {
+			continue
+		}
+		files = append(files, LSPFileEdit{FilePath: path, OldContent: oldContent, NewContent: newContent})
+		crlf = append(crlf, isCrlf)
+	}
+	if len(files) == 0 {
+		return NewTextResponse("No changes needed."), nil
+	}
+
+	p := e.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        fsext.PathOrPrefix(files[0].FilePath, e.workingDir),
+			ToolCallID:  call.ID,
+			ToolName:    toolName,
+			Action:      "write",
+			Description: description,
+			Params:      LSPEditPermissionsParams{Files: files},
+		},
+	)
+	if !p {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	metadata := LSPEditResponseMetadata{Files: files}
+	var sb strings.Builder
+	for i, f := range files {
+		content := f.NewContent
+		if crlf[i] {
+			content, _ = fsext.ToWindowsLineEndings(content)
+		}
+		keepOriginal(e.trashBin, sessionID, f.FilePath, toolName)
+		if err := os.WriteFile(f.FilePath, []byte(content), 0o644); err != nil {
+			return ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
+		}
+		e.recordVersions(ctx, sessionID, f)
+
+		_, additions, removals := diff.GenerateDiff(f.OldContent, f.NewContent, strings.TrimPrefix(f.FilePath, e.workingDir))
+		metadata.Additions += additions
+		metadata.Removals += removals
+		fmt.Fprintf(&sb, "Changed %s (+%d -%d)\n", f.FilePath, additions, removals)
+	}
+
+	// Diagnostics are waited for on the first file only, the others are
+	// synced so the servers see their new content.
+	for _, f := range files[1:] {
+		for _, client := range e.lspClients {
+			if client.IsFileOpen(f.FilePath) {
+				_ = client.NotifyChange(ctx, f.FilePath)
+			}
+		}
+	}
+	waitForLspDiagnostics(ctx, files[0].FilePath, e.lspClients)
+	text := "<result>\n" + strings.TrimSpace(sb.String()) + "\n</result>\n"
+	text += getDiagnostics(files[0].FilePath, e.lspClients)
+	return WithResponseMetadata(NewTextResponse(text), metadata), nil
+}
+
+// recordVersions stores the new version of the file in the history of the
+// session, like the edit tools do.
+func (e *lspEditor) recordVersions(ctx context.Context, sessionID string, f LSPFileEdit) {
+	file, err := e.files.GetByPathAndSession(ctx, f.FilePath, sessionID)
+	if err != nil {
+		if _, err = e.files.Create(ctx, sessionID, f.FilePath, f.OldContent); err != nil {
+			slog.Debug("Error creating file history", "error", err)
+		}
+	} else if file.Content != f.OldContent {
+		// The file was changed by the user, its current content is kept as
+		// an intermediate version.
+		if _, err = e.files.CreateVersion(ctx, sessionID, f.FilePath, f.OldContent); err != nil {
+			slog.Debug("Error creating file history version", "error", err)
+		}
+	}
+	if _, err = e.files.CreateVersion(ctx, sessionID, f.FilePath, f.NewContent); err != nil {
+		slog.Debug("Error creating file history version", "error", err)
+	}
+
+	recordFileWrite(f.FilePath)
+	recordFileRead(f.FilePath)
+	recordSessionFileWrite(ctx, e.files, sessionID, f.FilePath, f.OldContent, f.NewContent)
+}
+
+// symbolPosition returns the position of the first occurrence of the symbol
+// on a line of the file, the line starting at 1. Columns are counted in UTF-16
+// code units, as language servers expect by default.
+func symbolPosition(filePath string, line int, symbol string) (protocol.Position, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return protocol.Position{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	lines := strings.Split(string(content), "\n")
+	if line < 1 || line > len(lines) {
+		return protocol.Position{}, fmt.Errorf("line %d is out of range, the file has %d lines", line, len(lines))
+	}
+	text := lines[line-1]
+	idx := strings.Index(text, symbol)
+	if idx == -1 {
+		return protocol.Position{}, fmt.Errorf("symbol %q not found on line %d", symbol, line)
+	}
+	return protocol.Position{
+		Line:      uint32(line - 1),
+		Character: uint32(len(utf16.Encode([]rune(text[:idx])))),
+	}, nil
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolPosition(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nvar café, total = 1, 2\n"), 0o644))
+
+	pos, err := symbolPosition(path, 3, "total")
+	require.NoError(t, err)
+	// "é" is one UTF-16 code unit but two bytes.
+	require.Equal(t, protocol.Position{Line: 2, Character: 10}, pos)
+
+	_, err = symbolPosition(path, 3, "missing")
+	require.Error(t, err)
+	_, err = symbolPosition(path, 10, "total")
+	require.Error(t, err)
+}
+
+func TestLSPEditContents(t *testing.T) {
+	t.Parallel()
+
+	oldContent, newContent := LSPEditContents([]LSPFileEdit{
+		{FilePath: "/a.go", OldContent: "foo\n", NewContent: "bar\n"},
+	})
+	require.Equal(t, "foo\n", oldContent)
+	require.Equal(t, "bar\n", newContent)
+
+	oldContent, newContent = LSPEditContents([]LSPFileEdit{
+		{FilePath: "/a.go", OldContent: "foo", NewContent: "bar"},
+		{FilePath: "/b.go", OldContent: "foo()\n", NewContent: "bar()\n"},
+	})
+	require.Equal(t, "── /a.go ──\nfoo\n── /b.go ──\nfoo()\n", oldContent)
+	require.Equal(t, "── /a.go ──\nbar\n── /b.go ──\nbar()\n", newContent)
+}
@@ -8,13 +8,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/charmbracelet/crush/internal/diff"
 	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/history"
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/redact"
+	"github.com/charmbracelet/crush/internal/trash"
 )
 
 type MultiEditOperation struct {
@@ -46,6 +47,7 @@ type multiEditTool struct {
 	lspClients  map[string]*lsp.Client
 	permissions permission.Service
 	files       history.Service
+	trashBin    trash.Service
 	workingDir  string
 }
 
@@ -95,11 +97,12 @@ If you want to create a new file, use:
 - Subsequent edits: normal edit operations on the created content`
 )
 
-func NewMultiEditTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, workingDir string) BaseTool {
+func NewMultiEditTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, trashBin trash.Service, workingDir string) BaseTool {
 	return &multiEditTool{
 		lspClients:  lspClients,
 		permissions: permissions,
 		files:       files,
+		trashBin:    trashBin,
 		workingDir:  workingDir,
 	}
 }
@@ -161,9 +164,7 @@ func (m *multiEditTool) Run(ctx context.Context, call ToolCall) (ToolResponse, e
 		return NewTextErrorResponse("at least one edit operation is required"), nil
 	}
 
-	if !filepath.IsAbs(params.FilePath) {
-		params.FilePath = filepath.Join(m.workingDir, params.FilePath)
-	}
+	params.FilePath = fsext.AbsPath(params.FilePath, m.workingDir)
 
 	// Validate all edits before applying any
 	if err := m.validateEdits(params.Edits); err != nil {
@@ -205,6 +206,9 @@ func (m *multiEditTool) validateEdits(edits []MultiEditOperation) error {
 		if i > 0 && edit.OldString == "" {
 			return fmt.Errorf("edit %d: only the first edit can have empty old_string (for file creation)", i+1)
 		}
+		if redact.HasMask(edit.OldString) {
+			return fmt.Errorf("edit %d: %s", i+1, maskedOldStringMessage)
+		}
 	}
 	return nil
 }
@@ -287,6 +291,7 @@ func (m *multiEditTool) processMultiEditWithCreation(ctx context.Context, params
 
 	recordFileWrite(params.FilePath)
 	recordFileRead(params.FilePath)
+	recordSessionFileWrite(ctx, m.files, sessionID, params.FilePath, "", currentContent)
 
 	return WithResponseMetadata(
 		NewTextResponse(fmt.Sprintf("File created with %d edits: %s", len(params.Edits), params.FilePath)),
@@ -319,22 +324,25 @@ func (m *multiEditTool) processMultiEditExistingFile(ctx context.Context, params
 		return NewTextErrorResponse("you must read the file before editing it. Use the View tool first"), nil
 	}
 
-	// Check if file was modified since last read
-	modTime := fileInfo.ModTime()
-	lastRead := getLastReadTime(params.FilePath)
-	if modTime.After(lastRead) {
-		return NewTextErrorResponse(
-			fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
-				params.FilePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339),
-			)), nil
-	}
-
 	// Read current file content
 	content, err := os.ReadFile(params.FilePath)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("failed to read file: %w", err)
 	}
 
+	// Check if file was modified since last read
+	if response, conflict := checkConflict(params.FilePath, content, func(content string) (string, bool) {
+		for _, edit := range params.Edits {
+			var err error
+			if content, err = m.applyEditToContent(content, edit); err != nil {
+				return "", false
+			}
+		}
+		return content, true
+	}); conflict {
+		return response, nil
+	}
+
 	oldContent, isCrlf := fsext.ToUnixLineEndings(string(content))
 	currentContent := oldContent
 
@@ -381,6 +389,8 @@ func (m *multiEditTool) processMultiEditExistingFile(ctx context.Context, params
 		currentContent, _ = fsext.ToWindowsLineEndings(currentContent)
 	}
 
+	keepOriginal(m.trashBin, sessionID, params.FilePath, MultiEditToolName)
+
 	// Write the updated content
 	err = os.WriteFile(params.FilePath, []byte(currentContent), 0o644)
 	if err != nil {
@@ -411,6 +421,7 @@ func (m *multiEditTool) processMultiEditExistingFile(ctx context.Context, params
 
 	recordFileWrite(params.FilePath)
 	recordFileRead(params.FilePath)
+	recordSessionFileWrite(ctx, m.files, sessionID, params.FilePath, oldContent, currentContent)
 
 	return WithResponseMetadata(
 		NewTextResponse(fmt.Sprintf("Applied %d edits to file: %s", len(params.Edits), params.FilePath)),
@@ -433,6 +444,8 @@ func (m *multiEditTool) applyEditToContent(content string, edit MultiEditOperati
 		return "", fmt.Errorf("old_string cannot be empty for content replacement")
 	}
 
+	edit.OldString, edit.NewString = unixEditStrings(edit.OldString, edit.NewString)
+
 	var newContent string
 	var replacementCount int
 
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/symbols"
+)
+
+type OutlineFileParams struct {
+	FilePath string `json:"file_path"`
+}
+
+type OutlineFileResponseMetadata struct {
+	FilePath        string `json:"file_path"`
+	NumberOfSymbols int    `json:"number_of_symbols"`
+}
+
+type outlineFileTool struct {
+	workingDir string
+}
+
+const (
+	OutlineFileToolName    = "outline_file"
+	outlineFileDescription = `Shows the outline of a source file: its declarations nested by container, with their line ranges and signatures, without needing a language server.
+
+WHEN TO USE THIS TOOL:
+- Use before reading a large file to find the part you need, then view it with an offset
+- Use to understand the structure of a file, e.g. which methods a class has
+
+HOW TO USE:
+- Provide the "file_path" of the file to outline
+
+SUPPORTED LANGUAGES:
+Go, Python, JavaScript, TypeScript, Rust, Java, C, C++, C#, Ruby, PHP, Kotlin, Swift, Scala, Lua and Bash.
+
+LIMITATIONS:
+- Files larger than 1MB are not parsed
+`
+)
+
+func NewOutlineFileTool(workingDir string) BaseTool {
+	return &outlineFileTool{workingDir: workingDir}
+}
+
+func (o *outlineFileTool) Name() string {
+	return OutlineFileToolName
+}
+
+func (o *outlineFileTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        OutlineFileToolName,
+		Description: symbolsDescription(outlineFileDescription),
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path to the file to outline",
+			},
+		},
+		Required: []string{"file_path"},
+	}
+}
+
+func (o *outlineFileTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params OutlineFileParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.FilePath == "" {
+		return NewTextErrorResponse("file_path is required"), nil
+	}
+
+	filePath := resolveSymbolsPath(o.workingDir, params.FilePath)
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("file not found: %s", filePath)), nil
+	}
+	if info.IsDir() {
+		return NewTextErrorResponse(fmt.Sprintf("path is a directory, use the %s tool instead: %s", ListSymbolsToolName, filePath)), nil
+	}
+
+	syms, err := symbols.ParseFile(filePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	var sb strings.Builder
+	count := writeOutline(&sb, syms, 0)
+	output := sb.String()
+	if count == 0 {
+		output = "No symbols found"
+	}
+	return WithResponseMetadata(
+		NewTextResponse(strings.TrimRight(output, "\n")),
+		OutlineFileResponseMetadata{
+			FilePath:        filePath,
+			NumberOfSymbols: count,
+		},
+	), nil
+}
+
+func writeOutline(sb *strings.Builder, syms []symbols.Symbol, depth int) int {
+	count := 0
+	for _, s := range syms {
+		fmt.Fprintf(sb, "%s%s %s [%d-%d]: %s\n", strings.Repeat("  ", depth), s.Kind, s.Name, s.Line, s.EndLine, s.Signature)
+		count += 1 + writeOutline(sb, s.Children, depth+1)
+	}
+	return count
+}
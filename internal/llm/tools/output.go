@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+const (
+	// maxStreamedOutput is the size of the tail of the output streamed
+	// while a command runs.
+	maxStreamedOutput = 32 * 1024
+	// outputInterval is how often the output of a running command is
+	// published, at most.
+	outputInterval = 100 * time.Millisecond
+)
+
+// ToolOutput is the output a running tool call wrote so far, shown until
+// its result is ready. The model only gets the result.
+type ToolOutput struct {
+	SessionID  string
+	ToolCallID string
+	// Output is the tail of the standard output and error.
+	Output  string
+	Started time.Time
+}
+
+var outputBroker = pubsub.NewBroker[ToolOutput]()
+
+// SubscribeToolOutput returns a channel for the output of the running tool
+// calls. The events carry the whole tail of the output, so the ones dropped
+// for slow subscribers don't matter.
+func SubscribeToolOutput(ctx context.Context) <-chan pubsub.Event[ToolOutput] {
+	return outputBroker.Subscribe(ctx)
+}
+
+// outputStream publishes the output written to it while a tool call runs.
+type outputStream struct {
+	mu      sync.Mutex
+	output  ToolOutput
+	tail    []byte
+	changed bool
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newOutputStream(sessionID, toolCallID string) *outputStream {
+	s := &outputStream{
+		output: ToolOutput{
+			SessionID:  sessionID,
+			ToolCallID: toolCallID,
+			Started:    time.Now(),
+		},
+		// The command is shown running before it writes anything.
+		changed: true,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *outputStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tail = append(s.tail, p...)
+	if len(s.tail) > maxStreamedOutput {
+		s.tail = s.tail[len(s.tail)-maxStreamedOutput:]
+	}
+	s.changed = true
+	return len(p), nil
+}
+
+func (s *outputStream) run() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(outputInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			s.publish()
+			return
+		case <-ticker.C:
+			s.publish()
+		}
+	}
+}
+
+func (s *outputStream) publish() {
+	s.mu.Lock()
+	if !s.changed {
+		s.mu.Unlock()
+		return
+	}
+	s.changed = false
+	output := s.output
+	// The tail may start in the middle of a character.
+	output.Output = strings.ToValidUTF8(string(s.tail), "")
+	s.mu.Unlock()
+	outputBroker.Publish(pubsub.UpdatedEvent, output)
+}
+
+// Close publishes the output written last and stops the stream.
+func (s *outputStream) Close() {
+	close(s.done)
+	<-s.stopped
+}
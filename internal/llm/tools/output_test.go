@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputStream(t *testing.T) {
+	t.Parallel()
+
+	events := SubscribeToolOutput(t.Context())
+	stream := newOutputStream("output-session", "call")
+	_, err := stream.Write([]byte("building\n"))
+	require.NoError(t, err)
+	_, err = stream.Write([]byte(strings.Repeat("x", maxStreamedOutput)))
+	require.NoError(t, err)
+	stream.Close()
+
+	var last ToolOutput
+	for event := range events {
+		if event.Payload.SessionID != "output-session" {
+			continue
+		}
+		last = event.Payload
+		if len(last.Output) == maxStreamedOutput {
+			break
+		}
+	}
+	require.Equal(t, "call", last.ToolCallID)
+	// Only the tail is kept.
+	require.Equal(t, strings.Repeat("x", maxStreamedOutput), last.Output)
+	require.False(t, last.Started.IsZero())
+}
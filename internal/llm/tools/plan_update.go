@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type PlanStepStatus string
+
+const (
+	PlanStepPending    PlanStepStatus = "pending"
+	PlanStepInProgress PlanStepStatus = "in_progress"
+	PlanStepCompleted  PlanStepStatus = "completed"
+)
+
+type PlanStep struct {
+	Content string         `json:"content"`
+	Status  PlanStepStatus `json:"status"`
+}
+
+type PlanUpdateParams struct {
+	Steps []PlanStep `json:"steps"`
+}
+
+type PlanUpdateResponseMetadata struct {
+	Steps     []PlanStep `json:"steps"`
+	Completed int        `json:"completed"`
+}
+
+type planUpdateTool struct{}
+
+const (
+	PlanUpdateToolName    = "plan_update"
+	planUpdateDescription = `Creates or updates the plan of the current task, shown to the user as a checklist that follows your progress.
+
+WHEN TO USE THIS TOOL:
+- Use at the start of a task that takes three or more distinct steps, to lay out the plan
+- Use again each time a step is started or completed, so the user can see where you are
+- Use when the plan changes, e.g. a step turns out to be unnecessary or a new one is needed
+
+HOW TO USE:
+- Provide the full list of "steps" every time, each with its "content" and "status"
+- The status is one of "pending", "in_progress" or "completed"
+- Keep a single step in progress at a time, and mark it completed as soon as it is done
+- Keep the steps short and concrete, e.g. "Add the migration" rather than "Work on the database"
+
+LIMITATIONS:
+- The plan replaces the previous one, steps left out of the list are dropped
+- Do not use it for trivial tasks that take a single step
+`
+)
+
+func NewPlanUpdateTool() BaseTool {
+	return &planUpdateTool{}
+}
+
+func (p *planUpdateTool) Name() string {
+	return PlanUpdateToolName
+}
+
+func (p *planUpdateTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        PlanUpdateToolName,
+		Description: planUpdateDescription,
+		Parameters: map[string]any{
+			"steps": map[string]any{
+				"type":        "array",
+				"description": "The steps of the plan, in order",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"content": map[string]any{
+							"type":        "string",
+							"description": "What the step does",
+						},
+						"status": map[string]any{
+							"type":        "string",
+							"enum":        []string{string(PlanStepPending), string(PlanStepInProgress), string(PlanStepCompleted)},
+							"description": "The status of the step",
+						},
+					},
+					"required": []string{"content", "status"},
+				},
+			},
+		},
+		Required: []string{"steps"},
+	}
+}
+
+func (p *planUpdateTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	steps, err := ParsePlanUpdate(call.Input)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	completed := 0
+	for _, step := range steps {
+		if step.Status == PlanStepCompleted {
+			completed++
+		}
+	}
+	output := fmt.Sprintf("Plan updated: %d of %d steps completed.", completed, len(steps))
+	if completed == len(steps) {
+		output += " All the steps are done."
+	}
+
+	return WithResponseMetadata(
+		NewTextResponse(output),
+		PlanUpdateResponseMetadata{
+			Steps:     steps,
+			Completed: completed,
+		},
+	), nil
+}
+
+// ParsePlanUpdate returns the steps of a plan_update call input, an error
+// when they are not a valid plan.
+func ParsePlanUpdate(input string) ([]PlanStep, error) {
+	var params PlanUpdateParams
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return nil, fmt.Errorf("error parsing parameters: %w", err)
+	}
+	if len(params.Steps) == 0 {
+		return nil, fmt.Errorf("steps are required")
+	}
+	for i, step := range params.Steps {
+		if strings.TrimSpace(step.Content) == "" {
+			return nil, fmt.Errorf("step %d has no content", i+1)
+		}
+		switch step.Status {
+		case PlanStepPending, PlanStepInProgress, PlanStepCompleted:
+		default:
+			return nil, fmt.Errorf("step %d has an invalid status %q, use pending, in_progress or completed", i+1, step.Status)
+		}
+	}
+	return params.Steps, nil
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlanUpdate(t *testing.T) {
+	t.Parallel()
+
+	steps, err := ParsePlanUpdate(`{"steps":[{"content":"Add the migration","status":"completed"},{"content":"Update the handler","status":"in_progress"},{"content":"Write the tests","status":"pending"}]}`)
+	require.NoError(t, err)
+	require.Equal(t, []PlanStep{
+		{Content: "Add the migration", Status: PlanStepCompleted},
+		{Content: "Update the handler", Status: PlanStepInProgress},
+		{Content: "Write the tests", Status: PlanStepPending},
+	}, steps)
+
+	_, err = ParsePlanUpdate(`{"steps":[]}`)
+	require.ErrorContains(t, err, "steps are required")
+	_, err = ParsePlanUpdate(`{"steps":[{"content":" ","status":"pending"}]}`)
+	require.ErrorContains(t, err, "step 1 has no content")
+	_, err = ParsePlanUpdate(`{"steps":[{"content":"Ship it","status":"done"}]}`)
+	require.ErrorContains(t, err, `invalid status "done"`)
+	_, err = ParsePlanUpdate(`{"steps":`)
+	require.Error(t, err)
+}
+
+func TestPlanUpdateTool(t *testing.T) {
+	t.Parallel()
+
+	tool := NewPlanUpdateTool()
+	resp, err := tool.Run(t.Context(), ToolCall{
+		Name:  PlanUpdateToolName,
+		Input: `{"steps":[{"content":"Add the migration","status":"completed"},{"content":"Update the handler","status":"in_progress"}]}`,
+	})
+	require.NoError(t, err)
+	require.False(t, resp.IsError)
+	require.Equal(t, "Plan updated: 1 of 2 steps completed.", resp.Content)
+
+	var meta PlanUpdateResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(resp.Metadata), &meta))
+	require.Equal(t, 1, meta.Completed)
+	require.Len(t, meta.Steps, 2)
+
+	resp, err = tool.Run(t.Context(), ToolCall{
+		Name:  PlanUpdateToolName,
+		Input: `{"steps":[{"content":"Ship it","status":"unknown"}]}`,
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError)
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+)
+
+type ReadArtifactParams struct {
+	ArtifactID string `json:"artifact_id"`
+	Offset     int    `json:"offset"`
+	Limit      int    `json:"limit"`
+}
+
+type ReadArtifactResponseMetadata struct {
+	ArtifactID string `json:"artifact_id"`
+	Name       string `json:"name"`
+	Content    string `json:"content"`
+}
+
+type readArtifactTool struct {
+	artifacts artifact.Service
+}
+
+const (
+	ReadArtifactToolName    = "read_artifact"
+	readArtifactDescription = `Reads an artifact stored for the current session.
+
+WHEN TO USE THIS TOOL:
+- Use when a tool result references an artifact, e.g. the full output of a command or a fetched page that was too large to include
+- Use to page through large outputs instead of running the command again
+
+HOW TO USE:
+- Provide the "artifact_id" given in the tool result
+- Optionally provide an "offset" (line number to start from, 0-based) and a "limit" (number of lines to read)
+
+LIMITATIONS:
+- Only text artifacts can be read, images and binary files can only be opened by the user
+- Reads up to 2000 lines by default, long lines are truncated
+`
+)
+
+func NewReadArtifactTool(artifacts artifact.Service) BaseTool {
+	return &readArtifactTool{artifacts: artifacts}
+}
+
+func (r *readArtifactTool) Name() string {
+	return ReadArtifactToolName
+}
+
+func (r *readArtifactTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ReadArtifactToolName,
+		Description: readArtifactDescription,
+		Parameters: map[string]any{
+			"artifact_id": map[string]any{
+				"type":        "string",
+				"description": "The ID of the artifact to read",
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "The line number to start reading from (0-based)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "The number of lines to read (defaults to 2000)",
+			},
+		},
+		Required: []string{"artifact_id"},
+	}
+}
+
+func (r *readArtifactTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ReadArtifactParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.ArtifactID == "" {
+		return NewTextErrorResponse("artifact_id is required"), nil
+	}
+
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required for reading artifacts")
+	}
+
+	a, err := r.artifacts.Get(sessionID, params.ArtifactID)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if !a.IsText() {
+		return NewTextErrorResponse(fmt.Sprintf("Artifact %s is a %s file of %d bytes and cannot be read as text", a.Name, a.MimeType, a.Size)), nil
+	}
+
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+	if params.Limit <= 0 {
+		params.Limit = DefaultReadLimit
+	}
+	data, err := r.artifacts.Read(sessionID, a.ID)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading artifact: %w", err)
+	}
+	content, lineCount, err := readText(bytes.NewReader(data), params.Offset, params.Limit)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading artifact: %w", err)
+	}
+
+	output := fmt.Sprintf("<artifact id=%q name=%q>\n", a.ID, a.Name)
+	output += addLineNumbers(content, params.Offset+1)
+	if read := params.Offset + len(strings.Split(content, "\n")); lineCount > read {
+		output += fmt.Sprintf("\n\n(Artifact has %d lines. Use 'offset' parameter to read beyond line %d)", lineCount, read)
+	}
+	output += "\n</artifact>"
+
+	return WithResponseMetadata(
+		NewTextResponse(output),
+		ReadArtifactResponseMetadata{
+			ArtifactID: a.ID,
+			Name:       a.Name,
+			Content:    content,
+		},
+	), nil
+}
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/trash"
+)
+
+type RenameSymbolParams struct {
+	FilePath string `json:"file_path"`
+	Line     int    `json:"line"`
+	Symbol   string `json:"symbol"`
+	NewName  string `json:"new_name"`
+}
+
+type renameSymbolTool struct {
+	lspEditor
+}
+
+const (
+	RenameSymbolToolName    = "rename_symbol"
+	renameSymbolDescription = `Renames a symbol (variable, function, type, field...) everywhere it is used in the workspace, using the language server.
+
+WHEN TO USE THIS TOOL:
+- Use to rename a symbol instead of editing each of its usages, or replacing its name with sed
+- Only the references to the symbol are renamed, not unrelated text with the same name
+
+HOW TO USE:
+- Provide the "file_path" of a file where the symbol appears
+- Provide the "line" (starting at 1) of one of its occurrences, e.g. its declaration
+- Provide the "symbol" as written on that line, and its "new_name"
+- The user is shown the diff of all the changed files before they are written
+
+LIMITATIONS:
+- Needs a language server configured for the language of the file
+- Renames creating, moving or deleting files are not supported
+`
+)
+
+func NewRenameSymbolTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, trashBin trash.Service, workingDir string) BaseTool {
+	return &renameSymbolTool{lspEditor{
+		lspClients:  lspClients,
+		permissions: permissions,
+		files:       files,
+		trashBin:    trashBin,
+		workingDir:  workingDir,
+	}}
+}
+
+func (r *renameSymbolTool) Name() string {
+	return RenameSymbolToolName
+}
+
+func (r *renameSymbolTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        RenameSymbolToolName,
+		Description: renameSymbolDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path of a file where the symbol appears",
+			},
+			"line": map[string]any{
+				"type":        "integer",
+				"description": "The line of an occurrence of the symbol, starting at 1",
+			},
+			"symbol": map[string]any{
+				"type":        "string",
+				"description": "The current name of the symbol, as written on the line",
+			},
+			"new_name": map[string]any{
+				"type":        "string",
+				"description": "The new name of the symbol",
+			},
+		},
+		Required: []string{"file_path", "line", "symbol", "new_name"},
+	}
+}
+
+func (r *renameSymbolTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params RenameSymbolParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.FilePath == "" || params.Symbol == "" || params.NewName == "" {
+		return NewTextErrorResponse("file_path, symbol and new_name are required"), nil
+	}
+	if params.Symbol == params.NewName {
+		return NewTextErrorResponse("new_name is the same as the current name"), nil
+	}
+	filePath := r.absPath(params.FilePath)
+
+	position, err := symbolPosition(filePath, params.Line, params.Symbol)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	client, err := r.clientFor(ctx, filePath)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	edit, err := client.Rename(ctx, protocol.RenameParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+		Position:     position,
+		NewName:      params.NewName,
+	})
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error renaming %s: %s", params.Symbol, err)), nil
+	}
+	return r.apply(ctx, call, RenameSymbolToolName, fmt.Sprintf("Rename %s to %s", params.Symbol, params.NewName), edit)
+}
@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+type resetShellTool struct {
+	workingDir string
+}
+
+const (
+	ResetShellToolName    = "reset_shell"
+	resetShellDescription = `Resets the persistent shell the bash tool runs the commands of the session in.
+
+WHEN TO USE THIS TOOL:
+- When the shell is in a bad state, like the wrong virtualenv activated or variables you don't want anymore
+- When the next commands should start from the working directory of the project again
+
+HOW TO USE:
+- Call it without parameters
+- The working directory, variables and activated environments of the previous commands are dropped
+- Nothing is run and no file is changed
+`
+)
+
+func NewResetShellTool(workingDir string) BaseTool {
+	return &resetShellTool{
+		workingDir: workingDir,
+	}
+}
+
+func (r *resetShellTool) Name() string {
+	return ResetShellToolName
+}
+
+func (r *resetShellTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ResetShellToolName,
+		Description: resetShellDescription,
+		Parameters:  map[string]any{},
+		Required:    []string{},
+	}
+}
+
+func (r *resetShellTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	sessionID, _ := GetContextValues(ctx)
+	if sessionID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID is required for resetting the shell")
+	}
+	shell.ResetSessionShell(sessionID)
+	return NewTextResponse(fmt.Sprintf("The shell was reset, the next commands start in %s with the initial environment.", r.workingDir)), nil
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -22,13 +23,18 @@ var getRg = sync.OnceValue(func() string {
 	return path
 })
 
-func getRgCmd(ctx context.Context, globPattern string) *exec.Cmd {
+func getRgCmd(ctx context.Context, globPatterns ...string) *exec.Cmd {
 	name := getRg()
 	if name == "" {
 		return nil
 	}
-	args := []string{"--files", "-L", "--null"}
-	if globPattern != "" {
+	// The .gitignore files apply even outside of git repositories, like
+	// without ripgrep.
+	args := []string{"--files", "-L", "--null", "--no-require-git"}
+	for _, globPattern := range globPatterns {
+		if globPattern == "" {
+			continue
+		}
 		if !filepath.IsAbs(globPattern) && !strings.HasPrefix(globPattern, "/") {
 			globPattern = "/" + globPattern
 		}
@@ -37,17 +43,28 @@ func getRgCmd(ctx context.Context, globPattern string) *exec.Cmd {
 	return exec.CommandContext(ctx, name, args...)
 }
 
-func getRgSearchCmd(ctx context.Context, pattern, path, include string) *exec.Cmd {
+func getRgSearchCmd(ctx context.Context, opts grepOptions, path string) *exec.Cmd {
 	name := getRg()
 	if name == "" {
 		return nil
 	}
-	// Use -n to show line numbers and include the matched line
-	args := []string{"-H", "-n", pattern}
-	if include != "" {
-		args = append(args, "--glob", include)
+	// Use --json to get the line numbers, the context lines and the
+	// multiline matches in a structured way. The .gitignore files apply
+	// even outside of git repositories, like without ripgrep.
+	args := []string{"--json", "--no-require-git"}
+	if opts.multiline {
+		args = append(args, "--multiline")
+	}
+	if opts.context > 0 {
+		args = append(args, "--context", strconv.Itoa(opts.context))
+	}
+	if opts.fileType != "" {
+		args = append(args, "--type", opts.fileType)
+	}
+	if opts.include != "" {
+		args = append(args, "--glob", opts.include)
 	}
-	args = append(args, path)
+	args = append(args, "--regexp", opts.pattern, path)
 
 	return exec.CommandContext(ctx, name, args...)
 }
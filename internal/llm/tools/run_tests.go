@@ -0,0 +1,568 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/shell"
+	"github.com/charmbracelet/x/ansi"
+)
+
+type RunTestsParams struct {
+	Framework string `json:"framework"`
+	Path      string `json:"path"`
+	Filter    string `json:"filter"`
+	Timeout   int    `json:"timeout"`
+}
+
+type RunTestsPermissionsParams struct {
+	Command string `json:"command"`
+}
+
+type TestFailure struct {
+	Name    string `json:"name"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type RunTestsResponseMetadata struct {
+	Framework string        `json:"framework"`
+	Command   string        `json:"command"`
+	Passed    int           `json:"passed"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	Failures  []TestFailure `json:"failures,omitempty"`
+	StartTime int64         `json:"start_time"`
+	EndTime   int64         `json:"end_time"`
+}
+
+type runTestsTool struct {
+	permissions permission.Service
+	workingDir  string
+}
+
+const (
+	RunTestsToolName = "run_tests"
+
+	testFrameworkGo     = "go"
+	testFrameworkPytest = "pytest"
+	testFrameworkJest   = "jest"
+
+	maxFailureMessageLines = 15
+	maxReportedFailures    = 30
+)
+
+const runTestsDescription = `Runs the project's test suite and returns a compact, structured summary of the results.
+
+WHEN TO USE THIS TOOL:
+- Use this instead of running test commands through the bash tool
+- Use after making changes to verify nothing is broken
+- The summary only contains counts and the failing tests, which saves a lot of context compared to raw test logs
+
+HOW TO USE:
+- Leave "framework" empty to detect it from the project files (go.mod, package.json, pyproject.toml, pytest.ini, ...)
+- Optionally provide a "path" to limit the run to a package, directory or file (e.g. "./internal/config/...", "tests/test_api.py")
+- Optionally provide a "filter" to only run matching tests (go test -run, pytest -k, jest -t)
+
+SUPPORTED FRAMEWORKS:
+- go: runs "go test -json"
+- pytest: runs "python -m pytest"
+- jest: runs "npx jest --json"
+
+OUTPUT:
+- Number of passed, failed and skipped tests
+- For each failure: the test name, the file and line of the failure when available, and a trimmed failure message
+- If the output can't be parsed (e.g. a build error), the end of the raw output is returned instead
+
+LIMITATIONS:
+- Only the frameworks listed above are supported, use the bash tool for anything else
+- Failure messages are trimmed to keep the summary compact
+`
+
+func NewRunTestsTool(permissions permission.Service, workingDir string) BaseTool {
+	return &runTestsTool{
+		permissions: permissions,
+		workingDir:  workingDir,
+	}
+}
+
+func (r *runTestsTool) Name() string {
+	return RunTestsToolName
+}
+
+func (r *runTestsTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        RunTestsToolName,
+		Description: runTestsDescription,
+		Parameters: map[string]any{
+			"framework": map[string]any{
+				"type":        "string",
+				"description": "The test framework to use, detected from the project when empty",
+				"enum":        []string{testFrameworkGo, testFrameworkPytest, testFrameworkJest},
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Optional package, directory or file to test",
+			},
+			"filter": map[string]any{
+				"type":        "string",
+				"description": "Optional pattern to select the tests to run",
+			},
+			"timeout": map[string]any{
+				"type":        "number",
+				"description": "Optional timeout in milliseconds (max 600000)",
+			},
+		},
+		Required: []string{},
+	}
+}
+
+func (r *runTestsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params RunTestsParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse("invalid parameters"), nil
+	}
+
+	// Test suites tend to be slow, so default to the maximum timeout.
+	if params.Timeout <= 0 || params.Timeout > MaxTimeout {
+		params.Timeout = MaxTimeout
+	}
+
+	framework := params.Framework
+	if framework == "" {
+		framework = detectTestFramework(r.workingDir)
+		if framework == "" {
+			return NewTextErrorResponse("could not detect the test framework, please provide one"), nil
+		}
+	}
+
+	command, err := testCommand(framework, params.Path, params.Filter)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for running tests")
+	}
+
+	p := r.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        r.workingDir,
+			ToolCallID:  call.ID,
+			ToolName:    RunTestsToolName,
+			Action:      "execute",
+			Description: fmt.Sprintf("Run tests: %s", command),
+			Params: RunTestsPermissionsParams{
+				Command: command,
+			},
+		},
+	)
+	if !p {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(params.Timeout)*time.Millisecond)
+	defer cancel()
+
+	startTime := time.Now()
+	sh := shell.NewShell(&shell.Options{
+		WorkingDir: r.workingDir,
+		BlockFuncs: blockFuncs(),
+	})
+	stream := newOutputStream(sessionID, call.ID)
+	stdout, stderr, err := sh.Exec(shell.WithOutput(ctx, stream), command)
+	stream.Close()
+	if errors.Is(err, shell.ErrCPULimitExceeded) {
+		limits, _ := shell.LimitsFromContext(ctx)
+		return NewLimitErrorResponse(LimitViolation{Tool: RunTestsToolName, Limit: LimitMaxCPUSeconds, Value: int(limits.CPUSeconds)}, truncateOutput(stdout+stderr)), nil
+	}
+	interrupted := shell.IsInterrupt(err)
+	exitCode := shell.ExitCode(err)
+	if exitCode == 0 && !interrupted && err != nil {
+		return ToolResponse{}, fmt.Errorf("error running tests: %w", err)
+	}
+
+	var report testReport
+	switch framework {
+	case testFrameworkGo:
+		report = parseGoTestOutput(stdout)
+	case testFrameworkPytest:
+		report = parsePytestOutput(stdout)
+	case testFrameworkJest:
+		report = parseJestOutput(stdout)
+	}
+
+	metadata := RunTestsResponseMetadata{
+		Framework: framework,
+		Command:   command,
+		Passed:    report.passed,
+		Failed:    report.failed,
+		Skipped:   report.skipped,
+		Failures:  report.failures,
+		StartTime: startTime.UnixMilli(),
+		EndTime:   time.Now().UnixMilli(),
+	}
+
+	var output strings.Builder
+	switch {
+	case interrupted:
+		output.WriteString("Test run was aborted before completion\n\n")
+	case report.empty() && exitCode != 0:
+		// Nothing could be parsed, most likely a build or setup error.
+		raw := strings.TrimSpace(strings.Join([]string{report.extra, stderr}, "\n"))
+		if raw == "" {
+			raw = stdout
+		}
+		output.WriteString(fmt.Sprintf("Tests failed to run (exit code %d):\n\n%s", exitCode, truncateOutput(raw)))
+		return WithResponseMetadata(NewTextErrorResponse(output.String()), metadata), nil
+	}
+	output.WriteString(report.summary(framework))
+	return WithResponseMetadata(NewTextResponse(output.String()), metadata), nil
+}
+
+// detectTestFramework guesses the test framework from the files in dir.
+func detectTestFramework(dir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	switch {
+	case exists("go.mod"):
+		return testFrameworkGo
+	case exists("package.json"):
+		return testFrameworkJest
+	case exists("pytest.ini"), exists("pyproject.toml"), exists("setup.py"),
+		exists("setup.cfg"), exists("tox.ini"), exists("conftest.py"):
+		return testFrameworkPytest
+	}
+	return ""
+}
+
+// testCommand builds the command used to run the tests of the given
+// framework.
+func testCommand(framework, path, filter string) (string, error) {
+	var args []string
+	switch framework {
+	case testFrameworkGo:
+		if path == "" {
+			path = "./..."
+		}
+		args = []string{"go", "test", "-json"}
+		if filter != "" {
+			args = append(args, "-run", shellQuote(filter))
+		}
+		args = append(args, shellQuote(path))
+	case testFrameworkPytest:
+		args = []string{"python", "-m", "pytest", "-q", "-rfE", "--tb=line", "-p", "no:cacheprovider"}
+		if filter != "" {
+			args = append(args, "-k", shellQuote(filter))
+		}
+		if path != "" {
+			args = append(args, shellQuote(path))
+		}
+	case testFrameworkJest:
+		args = []string{"npx", "jest", "--json", "--testLocationInResults", "--silent"}
+		if filter != "" {
+			args = append(args, "-t", shellQuote(filter))
+		}
+		if path != "" {
+			args = append(args, shellQuote(path))
+		}
+	default:
+		return "", fmt.Errorf("unsupported test framework: %s", framework)
+	}
+	return strings.Join(args, " "), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+type testReport struct {
+	passed   int
+	failed   int
+	skipped  int
+	failures []TestFailure
+	// extra holds output that isn't attached to any test, e.g. build errors.
+	extra string
+}
+
+func (r testReport) empty() bool {
+	return r.passed == 0 && r.failed == 0 && r.skipped == 0 && len(r.failures) == 0
+}
+
+func (r testReport) summary(framework string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s: %d passed, %d failed, %d skipped\n", framework, r.passed, r.failed, r.skipped))
+	for i, f := range r.failures {
+		if i == maxReportedFailures {
+			sb.WriteString(fmt.Sprintf("\n... and %d more failures\n", len(r.failures)-maxReportedFailures))
+			break
+		}
+		sb.WriteString("\nFAIL " + f.Name)
+		if f.File != "" {
+			sb.WriteString(" " + f.File)
+			if f.Line > 0 {
+				sb.WriteString(":" + strconv.Itoa(f.Line))
+			}
+		}
+		sb.WriteString("\n")
+		if f.Message != "" {
+			for _, ln := range strings.Split(f.Message, "\n") {
+				sb.WriteString("    " + ln + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// trimFailureMessage keeps the first lines of a failure message.
+func trimFailureMessage(msg string) string {
+	lines := strings.Split(strings.TrimSpace(ansi.Strip(msg)), "\n")
+	if len(lines) > maxFailureMessageLines {
+		lines = append(lines[:maxFailureMessageLines], fmt.Sprintf("... (%d more lines)", len(lines)-maxFailureMessageLines))
+	}
+	return strings.Join(lines, "\n")
+}
+
+var goFailureLocation = regexp.MustCompile(`^\s+([\w.\-/]+\.go):(\d+):`)
+
+type goTestEvent struct {
+	Action  string `json:"Action"`
+	Package string `json:"Package"`
+	Test    string `json:"Test"`
+	Output  string `json:"Output"`
+}
+
+// parseGoTestOutput parses the output of "go test -json".
+func parseGoTestOutput(output string) testReport {
+	var report testReport
+	testOutput := make(map[string]*strings.Builder)
+	packageOutput := make(map[string]*strings.Builder)
+	failedTests := make(map[string]bool)
+	var extra strings.Builder
+
+	appendTo := func(m map[string]*strings.Builder, key, s string) {
+		sb, ok := m[key]
+		if !ok {
+			sb = &strings.Builder{}
+			m[key] = sb
+		}
+		sb.WriteString(s)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var event goTestEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil || event.Action == "" {
+			extra.WriteString(line + "\n")
+			continue
+		}
+		key := event.Package + " " + event.Test
+		switch event.Action {
+		case "output", "build-output":
+			if event.Test != "" {
+				appendTo(testOutput, key, event.Output)
+			} else {
+				appendTo(packageOutput, event.Package, event.Output)
+			}
+		case "pass":
+			if event.Test != "" {
+				report.passed++
+			}
+		case "skip":
+			if event.Test != "" {
+				report.skipped++
+			}
+		case "fail":
+			if event.Test == "" {
+				if !failedTests[event.Package] {
+					// The package failed without any failing test, e.g. it
+					// didn't build or panicked in init.
+					var msg string
+					if sb, ok := packageOutput[event.Package]; ok {
+						msg = sb.String()
+					}
+					report.failures = append(report.failures, TestFailure{
+						Name:    event.Package,
+						Message: trimFailureMessage(msg),
+					})
+				}
+				continue
+			}
+			report.failed++
+			failedTests[event.Package] = true
+			failedTests[key] = true
+			if hasFailedSubtest(failedTests, key) {
+				// The subtest failures are already reported.
+				continue
+			}
+			failure := TestFailure{Name: event.Test + " (" + event.Package + ")"}
+			if sb, ok := testOutput[key]; ok {
+				var msg []string
+				for _, ln := range strings.Split(sb.String(), "\n") {
+					if strings.HasPrefix(strings.TrimSpace(ln), "=== RUN") ||
+						strings.HasPrefix(strings.TrimSpace(ln), "--- FAIL") ||
+						strings.TrimSpace(ln) == "" {
+						continue
+					}
+					if failure.File == "" {
+						if m := goFailureLocation.FindStringSubmatch(ln); m != nil {
+							failure.File = m[1]
+							failure.Line, _ = strconv.Atoi(m[2])
+						}
+					}
+					msg = append(msg, strings.TrimSpace(ln))
+				}
+				failure.Message = trimFailureMessage(strings.Join(msg, "\n"))
+			}
+			report.failures = append(report.failures, failure)
+		}
+	}
+	report.extra = extra.String()
+	return report
+}
+
+func hasFailedSubtest(failedTests map[string]bool, key string) bool {
+	for k := range failedTests {
+		if strings.HasPrefix(k, key+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	pytestFailureLine = regexp.MustCompile(`^(FAILED|ERROR) (\S+)(?: - (.*))?$`)
+	pytestTraceLine   = regexp.MustCompile(`^(\S+\.py):(\d+): `)
+	pytestCount       = regexp.MustCompile(`(\d+) (passed|failed|skipped|errors?|xfailed|xpassed)`)
+)
+
+// parsePytestOutput parses the output of "pytest -q -rfE --tb=line".
+func parsePytestOutput(output string) testReport {
+	var report testReport
+	type location struct {
+		file string
+		line int
+	}
+	var traces []location
+	var extra strings.Builder
+	for _, ln := range strings.Split(output, "\n") {
+		ln = strings.TrimRight(ln, "\r")
+		if m := pytestTraceLine.FindStringSubmatch(ln); m != nil {
+			line, _ := strconv.Atoi(m[2])
+			traces = append(traces, location{file: m[1], line: line})
+			continue
+		}
+		if m := pytestFailureLine.FindStringSubmatch(ln); m != nil {
+			failure := TestFailure{Name: m[2], Message: trimFailureMessage(m[3])}
+			file, _, _ := strings.Cut(m[2], "::")
+			for _, tr := range traces {
+				if strings.HasSuffix(filepath.ToSlash(tr.file), filepath.ToSlash(file)) {
+					failure.File = file
+					failure.Line = tr.line
+					break
+				}
+			}
+			report.failures = append(report.failures, failure)
+			continue
+		}
+		if strings.Contains(ln, " in ") && pytestCount.MatchString(ln) {
+			for _, m := range pytestCount.FindAllStringSubmatch(ln, -1) {
+				n, _ := strconv.Atoi(m[1])
+				switch m[2] {
+				case "passed", "xpassed":
+					report.passed += n
+				case "failed", "error", "errors":
+					report.failed += n
+				case "skipped", "xfailed":
+					report.skipped += n
+				}
+			}
+			continue
+		}
+		extra.WriteString(ln + "\n")
+	}
+	report.extra = extra.String()
+	return report
+}
+
+type jestReport struct {
+	NumPassedTests  int `json:"numPassedTests"`
+	NumFailedTests  int `json:"numFailedTests"`
+	NumPendingTests int `json:"numPendingTests"`
+	TestResults     []struct {
+		Name             string `json:"name"`
+		Status           string `json:"status"`
+		Message          string `json:"message"`
+		AssertionResults []struct {
+			FullName        string   `json:"fullName"`
+			Status          string   `json:"status"`
+			FailureMessages []string `json:"failureMessages"`
+			Location        *struct {
+				Line int `json:"line"`
+			} `json:"location"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+}
+
+// parseJestOutput parses the output of "jest --json".
+func parseJestOutput(output string) testReport {
+	var report testReport
+	// Jest may print other things before the JSON report.
+	start := strings.Index(output, "{")
+	if start == -1 {
+		report.extra = output
+		return report
+	}
+	var jr jestReport
+	if err := json.Unmarshal([]byte(output[start:]), &jr); err != nil {
+		report.extra = output
+		return report
+	}
+	report.passed = jr.NumPassedTests
+	report.failed = jr.NumFailedTests
+	report.skipped = jr.NumPendingTests
+	for _, suite := range jr.TestResults {
+		suiteFailures := 0
+		for _, a := range suite.AssertionResults {
+			if a.Status != "failed" {
+				continue
+			}
+			suiteFailures++
+			failure := TestFailure{
+				Name:    a.FullName,
+				File:    suite.Name,
+				Message: trimFailureMessage(strings.Join(a.FailureMessages, "\n")),
+			}
+			if a.Location != nil {
+				failure.Line = a.Location.Line
+			}
+			report.failures = append(report.failures, failure)
+		}
+		if suite.Status == "failed" && suiteFailures == 0 {
+			// The suite failed to run, e.g. because of a syntax error.
+			report.failures = append(report.failures, TestFailure{
+				Name:    filepath.Base(suite.Name),
+				File:    suite.Name,
+				Message: trimFailureMessage(suite.Message),
+			})
+		}
+	}
+	return report
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoTestOutput(t *testing.T) {
+	t.Parallel()
+
+	output := `{"Action":"run","Package":"example.com/foo","Test":"TestOK"}
+{"Action":"pass","Package":"example.com/foo","Test":"TestOK","Elapsed":0}
+{"Action":"run","Package":"example.com/foo","Test":"TestBad"}
+{"Action":"output","Package":"example.com/foo","Test":"TestBad","Output":"=== RUN   TestBad\n"}
+{"Action":"output","Package":"example.com/foo","Test":"TestBad","Output":"    foo_test.go:12: expected 1, got 2\n"}
+{"Action":"output","Package":"example.com/foo","Test":"TestBad","Output":"--- FAIL: TestBad (0.00s)\n"}
+{"Action":"fail","Package":"example.com/foo","Test":"TestBad","Elapsed":0}
+{"Action":"skip","Package":"example.com/foo","Test":"TestSkipped","Elapsed":0}
+{"Action":"fail","Package":"example.com/foo","Elapsed":0.1}
+{"Action":"output","Package":"example.com/bar","Output":"bar.go:3:2: undefined: x\n"}
+{"Action":"fail","Package":"example.com/bar","Elapsed":0}
+`
+	report := parseGoTestOutput(output)
+	require.Equal(t, 1, report.passed)
+	require.Equal(t, 1, report.failed)
+	require.Equal(t, 1, report.skipped)
+	require.Len(t, report.failures, 2)
+
+	require.Equal(t, "TestBad (example.com/foo)", report.failures[0].Name)
+	require.Equal(t, "foo_test.go", report.failures[0].File)
+	require.Equal(t, 12, report.failures[0].Line)
+	require.Equal(t, "foo_test.go:12: expected 1, got 2", report.failures[0].Message)
+
+	require.Equal(t, "example.com/bar", report.failures[1].Name)
+	require.Contains(t, report.failures[1].Message, "undefined: x")
+}
+
+func TestParsePytestOutput(t *testing.T) {
+	t.Parallel()
+
+	output := `..F.s
+/tmp/project/tests/test_api.py:42: AssertionError: assert 1 == 2
+=========================== short test summary info ============================
+FAILED tests/test_api.py::test_create - AssertionError: assert 1 == 2
+3 passed, 1 failed, 1 skipped in 0.12s
+`
+	report := parsePytestOutput(output)
+	require.Equal(t, 3, report.passed)
+	require.Equal(t, 1, report.failed)
+	require.Equal(t, 1, report.skipped)
+	require.Equal(t, []TestFailure{{
+		Name:    "tests/test_api.py::test_create",
+		File:    "tests/test_api.py",
+		Line:    42,
+		Message: "AssertionError: assert 1 == 2",
+	}}, report.failures)
+}
+
+func TestParseJestOutput(t *testing.T) {
+	t.Parallel()
+
+	output := `{"numPassedTests":2,"numFailedTests":1,"numPendingTests":0,"testResults":[
+{"name":"/app/sum.test.js","status":"failed","message":"","assertionResults":[
+{"fullName":"sum adds","status":"passed","failureMessages":[]},
+{"fullName":"sum subtracts","status":"failed","failureMessages":["Error: expect(received).toBe(expected)"],"location":{"line":7,"column":3}}]},
+{"name":"/app/broken.test.js","status":"failed","message":"SyntaxError: Unexpected token","assertionResults":[]}]}`
+	report := parseJestOutput(output)
+	require.Equal(t, 2, report.passed)
+	require.Equal(t, 1, report.failed)
+	require.Len(t, report.failures, 2)
+	require.Equal(t, TestFailure{
+		Name:    "sum subtracts",
+		File:    "/app/sum.test.js",
+		Line:    7,
+		Message: "Error: expect(received).toBe(expected)",
+	}, report.failures[0])
+	require.Equal(t, "broken.test.js", report.failures[1].Name)
+	require.Equal(t, "SyntaxError: Unexpected token", report.failures[1].Message)
+}
+
+func TestTestCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd, err := testCommand(testFrameworkGo, "", "TestFoo")
+	require.NoError(t, err)
+	require.Equal(t, "go test -json -run 'TestFoo' './...'", cmd)
+
+	cmd, err = testCommand(testFrameworkPytest, "tests/it's.py", "")
+	require.NoError(t, err)
+	require.Equal(t, `python -m pytest -q -rfE --tb=line -p no:cacheprovider 'tests/it'\''s.py'`, cmd)
+
+	_, err = testCommand("cargo", "", "")
+	require.Error(t, err)
+}
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/charmbracelet/crush/internal/egress"
 )
 
 type SourcegraphParams struct {
@@ -125,15 +127,11 @@ TIPS:
 - Use type:file to find relevant files`
 )
 
-func NewSourcegraphTool() BaseTool {
+func NewSourcegraphTool(egressPolicy *egress.Policy) BaseTool {
 	return &sourcegraphTool{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
-			},
+			Timeout:   30 * time.Second,
+			Transport: egressPolicy.Transport(newToolTransport()),
 		},
 	}
 }
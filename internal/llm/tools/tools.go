@@ -32,6 +32,17 @@ type ToolResponse struct {
 	Content  string           `json:"content"`
 	Metadata string           `json:"metadata,omitempty"`
 	IsError  bool             `json:"is_error"`
+	// Artifacts are stored in the session artifact store by the agent, the
+	// model only gets a handle to each of them.
+	Artifacts []ToolArtifact `json:"-"`
+}
+
+// ToolArtifact is a file, image or large output returned by a tool by
+// reference instead of inlined in the conversation.
+type ToolArtifact struct {
+	Name     string
+	MimeType string
+	Data     []byte
 }
 
 func NewTextResponse(content string) ToolResponse {
@@ -52,6 +63,11 @@ func WithResponseMetadata(response ToolResponse, metadata any) ToolResponse {
 	return response
 }
 
+func WithResponseArtifact(response ToolResponse, artifact ToolArtifact) ToolResponse {
+	response.Artifacts = append(response.Artifacts, artifact)
+	return response
+}
+
 func NewTextErrorResponse(content string) ToolResponse {
 	return ToolResponse{
 		Type:    ToolResponseTypeText,
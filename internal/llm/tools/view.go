@@ -2,17 +2,23 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"unicode/utf8"
 
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/symbols"
 )
 
 type ViewParams struct {
@@ -31,6 +37,7 @@ type viewTool struct {
 	lspClients  map[string]*lsp.Client
 	workingDir  string
 	permissions permission.Service
+	files       history.Service
 }
 
 type ViewResponseMetadata struct {
@@ -39,11 +46,16 @@ type ViewResponseMetadata struct {
 }
 
 const (
-	ViewToolName     = "view"
+	ViewToolName = "view"
+	// MaxReadSize is the size above which a file is only read in parts, its
+	// outline being returned when no part is given.
 	MaxReadSize      = 250 * 1024
 	DefaultReadLimit = 2000
 	MaxLineLength    = 2000
-	viewDescription  = `File viewing tool that reads and displays the contents of files with line numbers, allowing you to examine code, logs, or text data.
+	// MaxViewTokens bounds the lines returned, the lines in the middle
+	// being left out past it.
+	MaxViewTokens   = 16_000
+	viewDescription = `File viewing tool that reads and displays the contents of files with line numbers, allowing you to examine code, logs, or text data.
 
 WHEN TO USE THIS TOOL:
 - Use when you need to read the contents of a specific file
@@ -64,9 +76,10 @@ FEATURES:
 - Suggests similar file names when the requested file isn't found
 
 LIMITATIONS:
-- Maximum file size is 250KB
+- Files larger than 250KB must be read in parts with offset and limit, without them their outline is returned instead
 - Default reading limit is 2000 lines
-- Lines longer than 2000 characters are truncated
+- At most about 16000 tokens are returned, the lines in the middle are left out past it with the range to read them again
+- Lines longer than 2000 characters are truncated, minified files are reported as such
 - Cannot display binary files or images
 - Images can be identified but not displayed
 
@@ -81,11 +94,12 @@ TIPS:
 - When viewing large files, use the offset parameter to read specific sections`
 )
 
-func NewViewTool(lspClients map[string]*lsp.Client, permissions permission.Service, workingDir string) BaseTool {
+func NewViewTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, workingDir string) BaseTool {
 	return &viewTool{
 		lspClients:  lspClients,
 		workingDir:  workingDir,
 		permissions: permissions,
+		files:       files,
 	}
 }
 
@@ -127,10 +141,7 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	}
 
 	// Handle relative paths
-	filePath := params.FilePath
-	if !filepath.IsAbs(filePath) {
-		filePath = filepath.Join(v.workingDir, filePath)
-	}
+	filePath := fsext.AbsPath(params.FilePath, v.workingDir)
 
 	// Check if file is outside working directory and request permission if needed
 	absWorkingDir, err := filepath.Abs(v.workingDir)
@@ -205,17 +216,6 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse(fmt.Sprintf("Path is a directory, not a file: %s", filePath)), nil
 	}
 
-	// Check file size
-	if fileInfo.Size() > MaxReadSize {
-		return NewTextErrorResponse(fmt.Sprintf("File is too large (%d bytes). Maximum size is %d bytes",
-			fileInfo.Size(), MaxReadSize)), nil
-	}
-
-	// Set default limit if not provided
-	if params.Limit <= 0 {
-		params.Limit = DefaultReadLimit
-	}
-
 	// Check if it's an image file
 	isImage, imageType := isImageFile(filePath)
 	// TODO: handle images
@@ -223,6 +223,22 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse(fmt.Sprintf("This is an image file of type: %s\n", imageType)), nil
 	}
 
+	if binary, mimeType := sniffBinary(filePath); binary {
+		return NewTextErrorResponse(fmt.Sprintf("%s is a binary file (%s, %d bytes) and can't be displayed", filePath, mimeType, fileInfo.Size())), nil
+	}
+
+	// Large files are only read in parts, reading them at once would fill
+	// the context.
+	if fileInfo.Size() > MaxReadSize && params.Offset <= 0 && params.Limit <= 0 {
+		return fileTooLargeResponse(filePath, fileInfo.Size()), nil
+	}
+
+	// Set default limit if not provided
+	if params.Limit <= 0 {
+		params.Limit = DefaultReadLimit
+	}
+	params.Offset = max(params.Offset, 0)
+
 	// Read the file content
 	content, lineCount, err := readTextFile(filePath, params.Offset, params.Limit)
 	isValidUt8 := utf8.ValidString(content)
@@ -235,17 +251,24 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 
 	notifyLspOpenFile(ctx, filePath, v.lspClients)
 	output := "<file>\n"
-	// Format the output with line numbers
-	output += addLineNumbers(content, params.Offset+1)
+	// Format the output with line numbers, keeping the top and the bottom
+	// of the lines read when they don't fit the budget.
+	output += truncateMiddle(addLineNumbers(content, params.Offset+1), params.Offset, MaxViewTokens*bytesPerToken)
 
 	// Add a note if the content was truncated
 	if lineCount > params.Offset+len(strings.Split(content, "\n")) {
 		output += fmt.Sprintf("\n\n(File has more lines. Use 'offset' parameter to read beyond line %d)",
 			params.Offset+len(strings.Split(content, "\n")))
 	}
+	if lineCount > 0 && fileInfo.Size()/int64(lineCount) > minifiedLineLength {
+		output += fmt.Sprintf("\n\n(The file looks minified or generated, its %d lines average %d characters and lines longer than %d characters are cut. Search it with the grep tool instead of reading it)",
+			lineCount, fileInfo.Size()/int64(lineCount), MaxLineLength)
+	}
 	output += "\n</file>\n"
 	output += getDiagnostics(filePath, v.lspClients)
 	recordFileRead(filePath)
+	sessionID, _ := GetContextValues(ctx)
+	recordSessionFileRead(ctx, v.files, sessionID, filePath, params.Offset+1)
 	return WithResponseMetadata(
 		NewTextResponse(output),
 		ViewResponseMetadata{
@@ -286,7 +309,10 @@ func readTextFile(filePath string, offset, limit int) (string, int, error) {
 		return "", 0, err
 	}
 	defer file.Close()
+	return readText(file, offset, limit)
+}
 
+func readText(file io.ReadSeeker, offset, limit int) (string, int, error) {
 	lineCount := 0
 
 	scanner := NewLineScanner(file)
@@ -294,13 +320,13 @@ func readTextFile(filePath string, offset, limit int) (string, int, error) {
 		for lineCount < offset && scanner.Scan() {
 			lineCount++
 		}
-		if err = scanner.Err(); err != nil {
+		if err := scanner.Err(); err != nil {
 			return "", 0, err
 		}
 	}
 
 	if offset == 0 {
-		_, err = file.Seek(0, io.SeekStart)
+		_, err := file.Seek(0, io.SeekStart)
 		if err != nil {
 			return "", 0, err
 		}
@@ -351,13 +377,107 @@ func isImageFile(filePath string) (bool, string) {
 	}
 }
 
+const (
+	bytesPerToken = 4
+	// Files whose lines average more characters are reported as minified.
+	minifiedLineLength = 500
+	// binarySniffSize is how much of a file is looked at to tell whether it
+	// is binary.
+	binarySniffSize = 8000
+	// maxScanLineSize is the longest line read, minified files having very
+	// long ones.
+	maxScanLineSize = 16 * 1024 * 1024
+)
+
+// sniffBinary reports whether the file has NUL bytes in its beginning, like
+// git does, with its detected MIME type.
+func sniffBinary(filePath string) (bool, string) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false, ""
+	}
+	defer f.Close()
+	buf := make([]byte, binarySniffSize)
+	n, _ := io.ReadFull(f, buf)
+	buf = buf[:n]
+	if bytes.IndexByte(buf, 0) < 0 {
+		return false, ""
+	}
+	return true, http.DetectContentType(buf)
+}
+
+// fileTooLargeResponse describes a file too large to be read at once, with
+// its outline so the model can read the parts it needs.
+func fileTooLargeResponse(filePath string, size int64) ToolResponse {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<file_too_large path=%q size=\"%d\" lines=\"%d\">\n", filePath, size, countFileLines(filePath))
+	sb.WriteString("The file is too large to be read at once. Read the parts you need with the offset and limit parameters, or search it with the grep tool.\n")
+	if syms, err := symbols.ParseFile(filePath); err == nil && len(syms) > 0 {
+		var outline strings.Builder
+		writeOutline(&outline, syms, 0)
+		text := outline.String()
+		maxSize := MaxViewTokens * bytesPerToken
+		if len(text) > maxSize {
+			text = text[:strings.LastIndex(text[:maxSize], "\n")+1] + "... (outline truncated)\n"
+		}
+		sb.WriteString("Its outline, with the line ranges of its symbols:\n<outline>\n" + text + "</outline>\n")
+	}
+	sb.WriteString("</file_too_large>")
+	return NewTextResponse(sb.String())
+}
+
+func countFileLines(filePath string) int {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	lines := 0
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := f.Read(buf)
+		lines += bytes.Count(buf[:n], []byte{'\n'})
+		if err != nil {
+			return lines
+		}
+	}
+}
+
+// truncateMiddle leaves out the lines in the middle of the numbered lines
+// read from offset past maxSize bytes, keeping the top and the bottom.
+func truncateMiddle(numbered string, offset, maxSize int) string {
+	if len(numbered) <= maxSize {
+		return numbered
+	}
+	lines := strings.Split(numbered, "\n")
+	headSize := maxSize * 2 / 3
+	head, size := 0, 0
+	for head < len(lines) && size+len(lines[head])+1 <= headSize {
+		size += len(lines[head]) + 1
+		head++
+	}
+	tail := 0
+	for tail < len(lines)-head && size+len(lines[len(lines)-1-tail])+1 <= maxSize {
+		size += len(lines[len(lines)-1-tail]) + 1
+		tail++
+	}
+	first, last := offset+head+1, offset+len(lines)-tail
+	marker := fmt.Sprintf("... (lines %d to %d were left out to fit the context, read them with offset %d and limit %d) ...",
+		first, last, first-1, last-first+1)
+	kept := append(slices.Clip(lines[:head]), marker)
+	return strings.Join(append(kept, lines[len(lines)-tail:]...), "\n")
+}
+
 type LineScanner struct {
 	scanner *bufio.Scanner
 }
 
 func NewLineScanner(r io.Reader) *LineScanner {
+	scanner := bufio.NewScanner(r)
+	// Minified files have very long lines.
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScanLineSize)
 	return &LineScanner{
-		scanner: bufio.NewScanner(r),
+		scanner: scanner,
 	}
 }
 
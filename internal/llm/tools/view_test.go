@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateMiddle(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	for i := range 100 {
+		lines = append(lines, fmt.Sprintf("line %02d", i+1))
+	}
+	content := strings.Join(lines, "\n")
+	require.Equal(t, content, truncateMiddle(content, 0, len(content)))
+
+	// The lines take 8 bytes with their newline, 9 for the last one, so 10
+	// fit in the head and 5 in the tail.
+	text := truncateMiddle(content, 10, 121)
+	kept := strings.Split(text, "\n")
+	require.Len(t, kept, 16)
+	require.Equal(t, "line 10", kept[9])
+	require.Equal(t, "... (lines 21 to 105 were left out to fit the context, read them with offset 20 and limit 85) ...", kept[10])
+	require.Equal(t, "line 96", kept[11])
+	require.Equal(t, "line 100", kept[15])
+}
+
+func TestSniffBinary(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	text := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(text, []byte("hello\n"), 0o644))
+	binary, _ := sniffBinary(text)
+	require.False(t, binary)
+
+	data := filepath.Join(dir, "data")
+	require.NoError(t, os.WriteFile(data, []byte("%PDF-1.4\x00\x01\x02"), 0o644))
+	binary, mimeType := sniffBinary(data)
+	require.True(t, binary)
+	require.Equal(t, "application/pdf", mimeType)
+}
+
+func TestFileTooLargeResponse(t *testing.T) {
+	t.Parallel()
+
+	var sb strings.Builder
+	sb.WriteString("package big\n\n")
+	for i := 0; sb.Len() <= MaxReadSize; i++ {
+		fmt.Fprintf(&sb, "func Function%d() {\n\tprintln(%d)\n}\n\n", i, i)
+	}
+	path := filepath.Join(t.TempDir(), "big.go")
+	require.NoError(t, os.WriteFile(path, []byte(sb.String()), 0o644))
+
+	response := fileTooLargeResponse(path, int64(sb.Len()))
+	require.False(t, response.IsError)
+	require.True(t, strings.HasPrefix(response.Content, fmt.Sprintf("<file_too_large path=%q size=\"%d\" lines=\"%d\">\n", path, sb.Len(), strings.Count(sb.String(), "\n"))))
+	require.Contains(t, response.Content, "<outline>\n")
+	require.Contains(t, response.Content, "Function0")
+	require.Contains(t, response.Content, "... (outline truncated)\n</outline>\n</file_too_large>")
+	require.LessOrEqual(t, len(response.Content), MaxViewTokens*bytesPerToken+1000)
+}
@@ -0,0 +1,317 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+)
+
+type WorkspaceSymbolsParams struct {
+	Query string `json:"query"`
+	Kind  string `json:"kind,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+type WorkspaceSymbolsResponseMetadata struct {
+	NumberOfSymbols int      `json:"number_of_symbols"`
+	Servers         []string `json:"servers"`
+	Truncated       bool     `json:"truncated"`
+}
+
+// workspaceSymbol is a symbol returned by a language server.
+type workspaceSymbol struct {
+	Name      string
+	Kind      protocol.SymbolKind
+	Container string
+	Location  protocol.Location
+	Server    string
+}
+
+type workspaceSymbolsCacheEntry struct {
+	symbols []workspaceSymbol
+	servers []string
+	expires time.Time
+}
+
+type workspaceSymbolsTool struct {
+	lspClients map[string]*lsp.Client
+	workingDir string
+
+	mu    sync.Mutex
+	cache map[string]workspaceSymbolsCacheEntry
+}
+
+const (
+	WorkspaceSymbolsToolName    = "workspace_symbols"
+	workspaceSymbolsDescription = `Searches the symbols (functions, methods, types, classes, constants...) of the whole workspace by name, using all the connected language servers, like "Go to Symbol in Workspace" in an IDE.
+
+WHEN TO USE THIS TOOL:
+- Use when you know (part of) the name of a symbol but not the file declaring it
+- More precise than grep: only declarations are returned, with their kind and container
+- Works across languages when several language servers are configured
+
+HOW TO USE:
+- Provide a "query", e.g. "NewServer" or "handleReq"; servers match it loosely, so partial names work
+- Optionally provide a "kind" to keep only one kind of symbol, e.g. function, method, struct, class, interface, constant or variable
+- Optionally provide a "limit" on the number of results (default 50, at most 200)
+
+OUTPUT:
+- Symbols ranked by how well their name matches the query: exact matches first, then prefixes, then substrings
+- Each with its kind, container, file and line
+
+LIMITATIONS:
+- Needs at least one language server supporting workspace symbols
+- Results are cached for 30 seconds, symbols added meanwhile may be missing
+`
+
+	defaultWorkspaceSymbols = 50
+	maxWorkspaceSymbols     = 200
+	workspaceSymbolsTTL     = 30 * time.Second
+)
+
+func NewWorkspaceSymbolsTool(lspClients map[string]*lsp.Client, workingDir string) BaseTool {
+	return &workspaceSymbolsTool{
+		lspClients: lspClients,
+		workingDir: workingDir,
+		cache:      make(map[string]workspaceSymbolsCacheEntry),
+	}
+}
+
+func (w *workspaceSymbolsTool) Name() string {
+	return WorkspaceSymbolsToolName
+}
+
+func (w *workspaceSymbolsTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        WorkspaceSymbolsToolName,
+		Description: workspaceSymbolsDescription,
+		Parameters: map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "The name, or part of the name, of the symbol",
+			},
+			"kind": map[string]any{
+				"type":        "string",
+				"description": "Keep only the symbols of this kind, e.g. function, method, struct or class",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "The maximum number of results (default 50, at most 200)",
+			},
+		},
+		Required: []string{"query"},
+	}
+}
+
+func (w *workspaceSymbolsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params WorkspaceSymbolsParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if strings.TrimSpace(params.Query) == "" {
+		return NewTextErrorResponse("query is required"), nil
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultWorkspaceSymbols
+	}
+	limit = min(limit, maxWorkspaceSymbols)
+
+	symbols, servers, err := w.search(ctx, params.Query)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+	if params.Kind != "" {
+		symbols = slices.DeleteFunc(slices.Clone(symbols), func(s workspaceSymbol) bool {
+			return !strings.EqualFold(protocol.TableKindMap[s.Kind], params.Kind)
+		})
+	}
+	if len(symbols) == 0 {
+		return NewTextResponse(fmt.Sprintf("No symbols matching %q found", params.Query)), nil
+	}
+	truncated := len(symbols) > limit
+	if truncated {
+		symbols = symbols[:limit]
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Found %d symbols matching %q\n\n", len(symbols), params.Query)
+	for _, s := range symbols {
+		kind := strings.ToLower(protocol.TableKindMap[s.Kind])
+		name := s.Name
+		if s.Container != "" {
+			name = s.Container + "." + s.Name
+		}
+		path, err := s.Location.URI.Path()
+		if err != nil {
+			path = string(s.Location.URI)
+		}
+		fmt.Fprintf(&sb, "%s %s - %s:%d\n", kind, name, relPath(w.workingDir, path), s.Location.Range.Start.Line+1)
+	}
+	if truncated {
+		fmt.Fprintf(&sb, "\n(Results are truncated to the first %d symbols, use a more specific query)", limit)
+	}
+
+	return WithResponseMetadata(
+		NewTextResponse(strings.TrimRight(sb.String(), "\n")),
+		WorkspaceSymbolsResponseMetadata{
+			NumberOfSymbols: len(symbols),
+			Servers:         servers,
+			Truncated:       truncated,
+		},
+	), nil
+}
+
+// search queries all the ready language servers in parallel, and returns
+// their ranked symbols with the names of the servers that answered. The
+// results are cached briefly, as the model often repeats its queries.
+func (w *workspaceSymbolsTool) search(ctx context.Context, query string) ([]workspaceSymbol, []string, error) {
+	w.mu.Lock()
+	entry, ok := w.cache[query]
+	w.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.symbols, entry.servers, nil
+	}
+
+	type result struct {
+		server  string
+		symbols []workspaceSymbol
+		err     error
+	}
+	var wg sync.WaitGroup
+	results := make(chan result, len(w.lspClients))
+	for _, name := range slices.Sorted(maps.Keys(w.lspClients)) {
+		client := w.lspClients[name]
+		if client.GetServerState() != lsp.StateReady {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			symbols, err := querySymbols(ctx, client, name, query)
+			results <- result{server: name, symbols: symbols, err: err}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	var symbols []workspaceSymbol
+	var servers []string
+	var errs []string
+	for r := range results {
+		if r.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", r.server, r.err))
+			continue
+		}
+		servers = append(servers, r.server)
+		symbols = append(symbols, r.symbols...)
+	}
+	if len(servers) == 0 {
+		if len(errs) > 0 {
+			slices.Sort(errs)
+			return nil, nil, fmt.Errorf("error searching workspace symbols: %s", strings.Join(errs, "; "))
+		}
+		return nil, nil, fmt.Errorf("no language server available")
+	}
+	slices.Sort(servers)
+	symbols = rankWorkspaceSymbols(dedupWorkspaceSymbols(symbols), query)
+
+	w.mu.Lock()
+	for q, e := range w.cache {
+		if time.Now().After(e.expires) {
+			delete(w.cache, q)
+		}
+	}
+	w.cache[query] = workspaceSymbolsCacheEntry{
+		symbols: symbols,
+		servers: servers,
+		expires: time.Now().Add(workspaceSymbolsTTL),
+	}
+	w.mu.Unlock()
+	return symbols, servers, nil
+}
+
+func querySymbols(ctx context.Context, client *lsp.Client, server, query string) ([]workspaceSymbol, error) {
+	result, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	results, err := result.Results()
+	if err != nil {
+		return nil, err
+	}
+	symbols := make([]workspaceSymbol, 0, len(results))
+	for _, r := range results {
+		s := workspaceSymbol{
+			Name:     r.GetName(),
+			Location: r.GetLocation(),
+			Server:   server,
+		}
+		switch v := r.(type) {
+		case *protocol.WorkspaceSymbol:
+			s.Kind, s.Container = v.Kind, v.ContainerName
+		case *protocol.SymbolInformation:
+			s.Kind, s.Container = v.Kind, v.ContainerName
+		}
+		symbols = append(symbols, s)
+	}
+	return symbols, nil
+}
+
+// dedupWorkspaceSymbols removes the symbols returned by several servers, e.g.
+// a TypeScript and an ESLint server both covering the same files.
+func dedupWorkspaceSymbols(symbols []workspaceSymbol) []workspaceSymbol {
+	type key struct {
+		name string
+		uri  protocol.DocumentURI
+		line uint32
+	}
+	seen := make(map[key]bool, len(symbols))
+	return slices.DeleteFunc(symbols, func(s workspaceSymbol) bool {
+		k := key{s.Name, s.Location.URI, s.Location.Range.Start.Line}
+		if seen[k] {
+			return true
+		}
+		seen[k] = true
+		return false
+	})
+}
+
+// symbolMatchRank ranks how well a name matches the query, lower is better:
+// exact, case-insensitive exact, prefix, substring, then the loose matches of
+// the servers.
+func symbolMatchRank(name, query string) int {
+	lowerName, lowerQuery := strings.ToLower(name), strings.ToLower(query)
+	switch {
+	case name == query:
+		return 0
+	case lowerName == lowerQuery:
+		return 1
+	case strings.HasPrefix(lowerName, lowerQuery):
+		return 2
+	case strings.Contains(lowerName, lowerQuery):
+		return 3
+	default:
+		return 4
+	}
+}
+
+func rankWorkspaceSymbols(symbols []workspaceSymbol, query string) []workspaceSymbol {
+	slices.SortStableFunc(symbols, func(a, b workspaceSymbol) int {
+		return cmp.Or(
+			cmp.Compare(symbolMatchRank(a.Name, query), symbolMatchRank(b.Name, query)),
+			cmp.Compare(len(a.Name), len(b.Name)),
+			compareLocations(a.Location, b.Location),
+		)
+	})
+	return symbols
+}
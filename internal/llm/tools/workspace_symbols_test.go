@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankWorkspaceSymbols(t *testing.T) {
+	t.Parallel()
+
+	symbol := func(name, uri string, line uint32) workspaceSymbol {
+		return workspaceSymbol{
+			Name: name,
+			Location: protocol.Location{
+				URI:   protocol.DocumentURI(uri),
+				Range: protocol.Range{Start: protocol.Position{Line: line}},
+			},
+		}
+	}
+	symbols := []workspaceSymbol{
+		symbol("newServerConfig", "file:///a.go", 1),
+		symbol("NewServerFromFlags", "file:///a.go", 2),
+		symbol("nsrv", "file:///b.go", 3),
+		symbol("NewServer", "file:///c.go", 4),
+		symbol("newserver", "file:///d.go", 5),
+		symbol("NewServer", "file:///c.go", 4),
+	}
+
+	var names []string
+	for _, s := range rankWorkspaceSymbols(dedupWorkspaceSymbols(symbols), "NewServer") {
+		names = append(names, s.Name)
+	}
+	require.Equal(t, []string{"NewServer", "newserver", "newServerConfig", "NewServerFromFlags", "nsrv"}, names)
+}
@@ -8,7 +8,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/charmbracelet/crush/internal/diff"
 	"github.com/charmbracelet/crush/internal/fsext"
@@ -16,6 +15,7 @@ import (
 
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/trash"
 )
 
 type WriteParams struct {
@@ -33,6 +33,7 @@ type writeTool struct {
 	lspClients  map[string]*lsp.Client
 	permissions permission.Service
 	files       history.Service
+	trashBin    trash.Service
 	workingDir  string
 }
 
@@ -59,7 +60,7 @@ HOW TO USE:
 FEATURES:
 - Can create new files or overwrite existing ones
 - Creates parent directories automatically if they don't exist
-- Checks if the file has been modified since last read for safety
+- Refuses to overwrite a file modified since it was last read, returning its current content and a preview of the merge of your change with the modifications
 - Avoids unnecessary writes when content hasn't changed
 
 LIMITATIONS:
@@ -78,11 +79,12 @@ TIPS:
 - Always include descriptive comments when making changes to existing code`
 )
 
-func NewWriteTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, workingDir string) BaseTool {
+func NewWriteTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, trashBin trash.Service, workingDir string) BaseTool {
 	return &writeTool{
 		lspClients:  lspClients,
 		permissions: permissions,
 		files:       files,
+		trashBin:    trashBin,
 		workingDir:  workingDir,
 	}
 }
@@ -123,10 +125,7 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return NewTextErrorResponse("content is required"), nil
 	}
 
-	filePath := params.FilePath
-	if !filepath.IsAbs(filePath) {
-		filePath = filepath.Join(w.workingDir, filePath)
-	}
+	filePath := fsext.AbsPath(params.FilePath, w.workingDir)
 
 	fileInfo, err := os.Stat(filePath)
 	if err == nil {
@@ -134,17 +133,25 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 			return NewTextErrorResponse(fmt.Sprintf("Path is a directory, not a file: %s", filePath)), nil
 		}
 
-		modTime := fileInfo.ModTime()
-		lastRead := getLastReadTime(filePath)
-		if modTime.After(lastRead) {
-			return NewTextErrorResponse(fmt.Sprintf("File %s has been modified since it was last read.\nLast modification: %s\nLast read: %s\n\nPlease read the file again before modifying it.",
-				filePath, modTime.Format(time.RFC3339), lastRead.Format(time.RFC3339))), nil
+		if getLastReadTime(filePath).IsZero() {
+			return NewTextErrorResponse(fmt.Sprintf("File %s already exists and was not read. Please read the file before overwriting it.", filePath)), nil
 		}
 
 		oldContent, readErr := os.ReadFile(filePath)
+		if readErr == nil && strings.Contains(string(oldContent), "\r\n") {
+			// Keep the Windows line endings of the file.
+			params.Content, _ = fsext.ToWindowsLineEndings(params.Content)
+		}
 		if readErr == nil && string(oldContent) == params.Content {
 			return NewTextErrorResponse(fmt.Sprintf("File %s already contains the exact content. No changes made.", filePath)), nil
 		}
+		if readErr == nil {
+			if response, conflict := checkConflict(filePath, oldContent, func(string) (string, bool) {
+				return params.Content, true
+			}); conflict {
+				return response, nil
+			}
+		}
 	} else if !os.IsNotExist(err) {
 		return ToolResponse{}, fmt.Errorf("error checking file: %w", err)
 	}
@@ -192,6 +199,10 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
+	if oldContent != "" {
+		keepOriginal(w.trashBin, sessionID, filePath, WriteToolName)
+	}
+
 	err = os.WriteFile(filePath, []byte(params.Content), 0o644)
 	if err != nil {
 		return ToolResponse{}, fmt.Errorf("error writing file: %w", err)
@@ -221,6 +232,7 @@ func (w *writeTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error
 
 	recordFileWrite(filePath)
 	recordFileRead(filePath)
+	recordSessionFileWrite(ctx, w.files, sessionID, filePath, oldContent, params.Content)
 	waitForLspDiagnostics(ctx, filePath, w.lspClients)
 
 	result := fmt.Sprintf("File successfully written: %s", filePath)
@@ -62,6 +62,9 @@ type Client struct {
 
 	// Server state
 	serverState atomic.Value
+
+	// pullDiagnostics is set when the server answers diagnostic requests.
+	pullDiagnostics atomic.Bool
 }
 
 // NewClient creates a new LSP client.
@@ -194,6 +197,7 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 					PublishDiagnostics: protocol.PublishDiagnosticsClientCapabilities{
 						VersionSupport: true,
 					},
+					Diagnostic: &protocol.DiagnosticClientCapabilities{},
 					SemanticTokens: protocol.SemanticTokensClientCapabilities{
 						Requests: protocol.ClientSemanticTokensRequestOptions{
 							Range: &protocol.Or_ClientSemanticTokensRequestOptions_range{},
@@ -224,6 +228,7 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 	if err := c.Call(ctx, "initialize", initParams, &result); err != nil {
 		return nil, fmt.Errorf("initialize failed: %w", err)
 	}
+	c.pullDiagnostics.Store(result.Capabilities.DiagnosticProvider != nil)
 
 	if err := c.Notify(ctx, "initialized", struct{}{}); err != nil {
 		return nil, fmt.Errorf("initialized notification failed: %w", err)
@@ -806,6 +811,50 @@ func (c *Client) GetDiagnostics() map[protocol.DocumentURI][]protocol.Diagnostic
 	return maps.Clone(c.diagnostics)
 }
 
+// SupportsPullDiagnostics reports whether the server answers diagnostic
+// requests, instead of only publishing the diagnostics when it wants to.
+func (c *Client) SupportsPullDiagnostics() bool {
+	return c.pullDiagnostics.Load()
+}
+
+// PullDiagnostics asks the server for the diagnostics of a file and caches
+// them like the published ones. It does nothing when the server doesn't
+// support it.
+func (c *Client) PullDiagnostics(ctx context.Context, filepath string) error {
+	if !c.SupportsPullDiagnostics() {
+		return nil
+	}
+	uri := protocol.URIFromPath(filepath)
+	report, err := c.Diagnostic(ctx, protocol.DocumentDiagnosticParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pull diagnostics: %w", err)
+	}
+	// No previous result is sent, so the report is never unchanged.
+	if full, ok := report.Value.(protocol.RelatedFullDocumentDiagnosticReport); ok {
+		c.setDiagnostics(uri, full.Items)
+	}
+	return nil
+}
+
+func (c *Client) setDiagnostics(uri protocol.DocumentURI, diagnostics []protocol.Diagnostic) {
+	c.diagnosticsMu.Lock()
+	c.diagnostics[uri] = diagnostics
+
+	// Calculate total diagnostic count
+	totalCount := 0
+	for _, diagnostics := range c.diagnostics {
+		totalCount += len(diagnostics)
+	}
+	c.diagnosticsMu.Unlock()
+
+	// Trigger callback if set
+	if c.onDiagnosticsChanged != nil {
+		c.onDiagnosticsChanged(c.name, totalCount)
+	}
+}
+
 // OpenFileOnDemand opens a file only if it's not already open
 // This is used for lazy-loading files when they're actually needed
 func (c *Client) OpenFileOnDemand(ctx context.Context, filepath string) error {
@@ -102,18 +102,5 @@ func HandleDiagnostics(client *Client, params json.RawMessage) {
 		return
 	}
 
-	client.diagnosticsMu.Lock()
-	client.diagnostics[diagParams.URI] = diagParams.Diagnostics
-
-	// Calculate total diagnostic count
-	totalCount := 0
-	for _, diagnostics := range client.diagnostics {
-		totalCount += len(diagnostics)
-	}
-	client.diagnosticsMu.Unlock()
-
-	// Trigger callback if set
-	if client.onDiagnosticsChanged != nil {
-		client.onDiagnosticsChanged(client.name, totalCount)
-	}
+	client.setDiagnostics(diagParams.URI, diagParams.Diagnostics)
 }
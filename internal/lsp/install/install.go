@@ -0,0 +1,244 @@
+// Package install detects the languages of a project and installs the
+// language servers crush knows how to set up for them.
+package install
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// Server is a language server crush can install.
+type Server struct {
+	// Name is the name of the server in the lsp configuration.
+	Name     string
+	Language string
+	// Command is the executable of the server, Args its arguments.
+	Command   string
+	Args      []string
+	FileTypes []string
+	// Markers are the files found at the root of the projects of the
+	// language.
+	Markers []string
+	// Requires is the tool needed to install the server.
+	Requires string
+	install  func(ctx context.Context, dir string) error
+}
+
+// Servers are the language servers crush can install.
+var Servers = []Server{
+	{
+		Name:      "gopls",
+		Language:  "Go",
+		Command:   "gopls",
+		FileTypes: []string{"go", "mod", "sum", "work"},
+		Markers:   []string{"go.mod", "go.work"},
+		Requires:  "go",
+		install:   goInstall("golang.org/x/tools/gopls@latest"),
+	},
+	{
+		Name:      "rust-analyzer",
+		Language:  "Rust",
+		Command:   "rust-analyzer",
+		FileTypes: []string{"rs"},
+		Markers:   []string{"Cargo.toml"},
+		install:   installRustAnalyzer,
+	},
+	{
+		Name:      "typescript",
+		Language:  "TypeScript and JavaScript",
+		Command:   "typescript-language-server",
+		Args:      []string{"--stdio"},
+		FileTypes: []string{"ts", "tsx", "js", "jsx", "mjs", "cjs"},
+		Markers:   []string{"tsconfig.json", "jsconfig.json", "package.json"},
+		Requires:  "npm",
+		install:   npmInstall("typescript-language-server", "typescript"),
+	},
+	{
+		Name:      "pyright",
+		Language:  "Python",
+		Command:   "pyright-langserver",
+		Args:      []string{"--stdio"},
+		FileTypes: []string{"py", "pyi"},
+		Markers:   []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt", "Pipfile"},
+		Requires:  "npm",
+		install:   npmInstall("pyright"),
+	},
+}
+
+// Dir returns the directory the language servers are installed in, next to
+// the global data of crush so they are shared by every project.
+func Dir() string {
+	return filepath.Join(filepath.Dir(config.GlobalConfigData()), "lsp")
+}
+
+// Detect returns the servers of the languages used in the working directory,
+// found by the marker files at its root.
+func Detect(workingDir string) []Server {
+	var found []Server
+	for _, s := range Servers {
+		if slices.ContainsFunc(s.Markers, func(marker string) bool {
+			_, err := os.Stat(filepath.Join(workingDir, marker))
+			return err == nil
+		}) {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+// Find returns the path of the installed executable of the server, looking in
+// the installation directory first, then in the PATH.
+func Find(s Server, dir string) (string, bool) {
+	for _, path := range binPaths(s, dir) {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	if path, err := exec.LookPath(s.Command); err == nil {
+		return path, true
+	}
+	return "", false
+}
+
+// Install installs the server in the directory and returns the path of its
+// executable.
+func Install(ctx context.Context, s Server, dir string) (string, error) {
+	if s.Requires != "" {
+		if _, err := exec.LookPath(s.Requires); err != nil {
+			return "", fmt.Errorf("%s is needed to install %s", s.Requires, s.Name)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	if err := s.install(ctx, dir); err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", s.Name, err)
+	}
+	path, ok := Find(s, dir)
+	if !ok {
+		return "", fmt.Errorf("%s was installed but %s was not found", s.Name, s.Command)
+	}
+	return path, nil
+}
+
+// Config returns the lsp configuration running the executable of the
+// server.
+func Config(s Server, path string) config.LSPConfig {
+	return config.LSPConfig{
+		Command:   path,
+		Args:      s.Args,
+		FileTypes: s.FileTypes,
+	}
+}
+
+func binPaths(s Server, dir string) []string {
+	name := s.Command
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	paths := []string{filepath.Join(dir, "bin", name)}
+	if runtime.GOOS == "windows" {
+		paths = append(paths, filepath.Join(dir, "node", "node_modules", ".bin", s.Command+".cmd"))
+	} else {
+		paths = append(paths, filepath.Join(dir, "node", "node_modules", ".bin", s.Command))
+	}
+	return paths
+}
+
+func goInstall(pkg string) func(ctx context.Context, dir string) error {
+	return func(ctx context.Context, dir string) error {
+		cmd := exec.CommandContext(ctx, "go", "install", pkg)
+		cmd.Env = append(os.Environ(), "GOBIN="+filepath.Join(dir, "bin"))
+		return run(cmd)
+	}
+}
+
+func npmInstall(pkgs ...string) func(ctx context.Context, dir string) error {
+	return func(ctx context.Context, dir string) error {
+		args := append([]string{"install", "--no-audit", "--no-fund", "--prefix", filepath.Join(dir, "node")}, pkgs...)
+		return run(exec.CommandContext(ctx, "npm", args...))
+	}
+}
+
+// installRustAnalyzer installs rust-analyzer with rustup when it is
+// available, or downloads its latest release.
+func installRustAnalyzer(ctx context.Context, dir string) error {
+	if _, err := exec.LookPath("rustup"); err == nil {
+		return run(exec.CommandContext(ctx, "rustup", "component", "add", "rust-analyzer"))
+	}
+	target, ok := rustTargets[runtime.GOOS+"/"+runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("no rust-analyzer release for %s/%s, install it with rustup", runtime.GOOS, runtime.GOARCH)
+	}
+	url := "https://github.com/rust-lang/rust-analyzer/releases/latest/download/rust-analyzer-" + target + ".gz"
+	return downloadGzip(ctx, url, filepath.Join(dir, "bin", "rust-analyzer"))
+}
+
+var rustTargets = map[string]string{
+	"linux/amd64":  "x86_64-unknown-linux-gnu",
+	"linux/arm64":  "aarch64-unknown-linux-gnu",
+	"darwin/amd64": "x86_64-apple-darwin",
+	"darwin/arm64": "aarch64-apple-darwin",
+}
+
+func downloadGzip(ctx context.Context, url, dest string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download of %s failed: %s", url, resp.Status)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	tmp := dest + ".download"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, gz)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+func run(cmd *exec.Cmd) error {
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			return err
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
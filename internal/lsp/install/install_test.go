@@ -0,0 +1,44 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.Empty(t, Detect(dir))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pyproject.toml"), nil, 0o644))
+	var names []string
+	for _, s := range Detect(dir) {
+		names = append(names, s.Name)
+	}
+	require.Equal(t, []string{"gopls", "pyright"}, names)
+}
+
+func TestFindInstalled(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("npm shims are .cmd files on Windows")
+	}
+
+	dir := t.TempDir()
+	s := Server{Name: "fake", Command: "crush-fake-language-server"}
+	_, ok := Find(s, dir)
+	require.False(t, ok)
+
+	bin := filepath.Join(dir, "node", "node_modules", ".bin", s.Command)
+	require.NoError(t, os.MkdirAll(filepath.Dir(bin), 0o755))
+	require.NoError(t, os.WriteFile(bin, nil, 0o755))
+	path, ok := Find(s, dir)
+	require.True(t, ok)
+	require.Equal(t, bin, path)
+}
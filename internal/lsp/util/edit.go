@@ -22,6 +22,21 @@ func applyTextEdits(uri protocol.DocumentURI, edits []protocol.TextEdit) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
+	newContent, err := ApplyTextEditsToContent(content, edits)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, newContent, 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyTextEditsToContent returns the content once edited, without writing
+// it anywhere.
+func ApplyTextEditsToContent(content []byte, edits []protocol.TextEdit) ([]byte, error) {
 	// Detect line ending style
 	var lineEnding string
 	if bytes.Contains(content, []byte("\r\n")) {
@@ -40,7 +55,7 @@ func applyTextEdits(uri protocol.DocumentURI, edits []protocol.TextEdit) error {
 	for i, edit1 := range edits {
 		for j := i + 1; j < len(edits); j++ {
 			if rangesOverlap(edit1.Range, edits[j].Range) {
-				return fmt.Errorf("overlapping edits detected between edit %d and %d", i, j)
+				return nil, fmt.Errorf("overlapping edits detected between edit %d and %d", i, j)
 			}
 		}
 	}
@@ -59,7 +74,7 @@ func applyTextEdits(uri protocol.DocumentURI, edits []protocol.TextEdit) error {
 	for _, edit := range sortedEdits {
 		newLines, err := applyTextEdit(lines, edit)
 		if err != nil {
-			return fmt.Errorf("failed to apply edit: %w", err)
+			return nil, fmt.Errorf("failed to apply edit: %w", err)
 		}
 		lines = newLines
 	}
@@ -78,11 +93,7 @@ func applyTextEdits(uri protocol.DocumentURI, edits []protocol.TextEdit) error {
 		newContent.WriteString(lineEnding)
 	}
 
-	if err := os.WriteFile(path, []byte(newContent.String()), 0o644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	return nil
+	return []byte(newContent.String()), nil
 }
 
 func applyTextEdit(lines []string, edit protocol.TextEdit) ([]string, error) {
@@ -247,6 +258,29 @@ func ApplyWorkspaceEdit(edit protocol.WorkspaceEdit) error {
 	return nil
 }
 
+// WorkspaceTextEdits returns the text edits of a workspace edit by file. It
+// fails when the edit also creates, renames or deletes files.
+func WorkspaceTextEdits(edit protocol.WorkspaceEdit) (map[protocol.DocumentURI][]protocol.TextEdit, error) {
+	edits := make(map[protocol.DocumentURI][]protocol.TextEdit)
+	for uri, textEdits := range edit.Changes {
+		edits[uri] = append(edits[uri], textEdits...)
+	}
+	for _, change := range edit.DocumentChanges {
+		if change.TextDocumentEdit == nil {
+			return nil, fmt.Errorf("file operations are not supported")
+		}
+		uri := change.TextDocumentEdit.TextDocument.URI
+		for _, e := range change.TextDocumentEdit.Edits {
+			textEdit, err := e.AsTextEdit()
+			if err != nil {
+				return nil, fmt.Errorf("invalid edit type: %w", err)
+			}
+			edits[uri] = append(edits[uri], textEdit)
+		}
+	}
+	return edits, nil
+}
+
 func rangesOverlap(r1, r2 protocol.Range) bool {
 	if r1.Start.Line > r2.End.Line || r2.Start.Line > r1.End.Line {
 		return false
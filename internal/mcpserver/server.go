@@ -0,0 +1,197 @@
+// Package mcpserver exposes the built-in tools of crush to other MCP
+// clients.
+package mcpserver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/version"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// excludedTools need the agent to make sense, e.g. artifacts are only
+// stored for the tool calls of the agent.
+var excludedTools = []string{
+	tools.ReadArtifactToolName,
+}
+
+const instructions = `Tools of the crush coding agent, run in its working directory.
+Files must be viewed before they are edited.`
+
+// Server serves the built-in tools of an app over MCP. All the tool calls
+// run in a single crush session so their file history and permissions are
+// tracked like the ones of the agent.
+type Server struct {
+	app       *app.App
+	sessionID string
+	mcp       *server.MCPServer
+}
+
+// New creates the session of the server and registers the tools. When
+// allowed is not empty only the tools it names are exposed.
+func New(ctx context.Context, a *app.App, allowed []string) (*Server, error) {
+	sess, err := a.Sessions.Create(ctx, "MCP server")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session for the MCP server: %w", err)
+	}
+
+	s := &Server{
+		app:       a,
+		sessionID: sess.ID,
+		mcp: server.NewMCPServer(
+			"crush",
+			version.Version,
+			server.WithToolCapabilities(false),
+			server.WithInstructions(instructions),
+			server.WithRecovery(),
+		),
+	}
+
+	builtin := agent.BuiltinTools(a.Config(), a.Permissions, a.History, a.Artifacts, a.Trash, a.LSPClients)
+	for _, tool := range builtin {
+		name := tool.Name()
+		if slices.Contains(excludedTools, name) || (len(allowed) > 0 && !slices.Contains(allowed, name)) {
+			continue
+		}
+		s.mcp.AddTool(mcpTool(tool.Info()), s.handler(tool))
+	}
+	return s, nil
+}
+
+// SessionID returns the session the tool calls run in.
+func (s *Server) SessionID() string {
+	return s.sessionID
+}
+
+// ServeStdio serves the tools on the standard input and output until the
+// context is done or the input is closed.
+func (s *Server) ServeStdio(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
+	s.gatePermissions(ctx)
+	return server.NewStdioServer(s.mcp).Listen(ctx, stdin, stdout)
+}
+
+// ServeSSE serves the tools over SSE on the address until the context is
+// done.
+func (s *Server) ServeSSE(ctx context.Context, address, baseURL string) error {
+	s.gatePermissions(ctx)
+	sse := server.NewSSEServer(s.mcp, server.WithBaseURL(baseURL))
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- sse.Start(address)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return sse.Shutdown(context.Background())
+	}
+}
+
+// gatePermissions denies the permission requests of the tools that are not
+// allowed by the configuration, as there is nobody to ask, unless they can
+// be answered remotely.
+func (s *Server) gatePermissions(ctx context.Context) {
+	if s.app.Notifications.Enabled() && s.app.Notifications.Approvals().Serving() {
+		slog.Info("Permission requests of the MCP server are answered remotely")
+		return
+	}
+	requests := s.app.Permissions.Subscribe(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-requests:
+				if !ok {
+					return
+				}
+				slog.Info("Denied permission request of MCP tool call", "tool", event.Payload.ToolName, "action", event.Payload.Action)
+				s.app.Permissions.Deny(event.Payload)
+			}
+		}
+	}()
+}
+
+func mcpTool(info tools.ToolInfo) mcp.Tool {
+	required := info.Required
+	if required == nil {
+		required = []string{}
+	}
+	schema, _ := json.Marshal(map[string]any{
+		"type":       "object",
+		"properties": info.Parameters,
+		"required":   required,
+	})
+	return mcp.NewToolWithRawSchema(info.Name, info.Description, schema)
+}
+
+func (s *Server) handler(tool tools.BaseTool) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		input, err := json.Marshal(req.GetArguments())
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("invalid arguments", err), nil
+		}
+
+		// Every call is its own message, the tools only need the ids to
+		// be set.
+		ctx = context.WithValue(ctx, tools.SessionIDContextKey, s.sessionID)
+		ctx = context.WithValue(ctx, tools.MessageIDContextKey, uuid.New().String())
+		slog.Info("MCP tool call", "tool", tool.Name())
+		resp, err := tool.Run(ctx, tools.ToolCall{
+			ID:    uuid.New().String(),
+			Name:  tool.Name(),
+			Input: string(input),
+		})
+		if errors.Is(err, permission.ErrorPermissionDenied) {
+			return mcp.NewToolResultError(fmt.Sprintf(
+				"permission denied: allow the %s tool in permissions.allowed_tools of the crush configuration, or start the server with --yolo",
+				tool.Name(),
+			)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("tool failed", err), nil
+		}
+		return toolResult(resp), nil
+	}
+}
+
+func toolResult(resp tools.ToolResponse) *mcp.CallToolResult {
+	result := &mcp.CallToolResult{IsError: resp.IsError}
+	if resp.Content != "" {
+		result.Content = append(result.Content, mcp.NewTextContent(resp.Content))
+	}
+	for _, a := range resp.Artifacts {
+		uri := "crush-artifact:///" + a.Name
+		if strings.HasPrefix(a.MimeType, "text/") || a.MimeType == "application/json" {
+			result.Content = append(result.Content, mcp.NewEmbeddedResource(mcp.TextResourceContents{
+				URI:      uri,
+				MIMEType: a.MimeType,
+				Text:     string(a.Data),
+			}))
+			continue
+		}
+		result.Content = append(result.Content, mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+			URI:      uri,
+			MIMEType: a.MimeType,
+			Blob:     base64.StdEncoding.EncodeToString(a.Data),
+		}))
+	}
+	if len(result.Content) == 0 {
+		result.Content = append(result.Content, mcp.NewTextContent(""))
+	}
+	return result
+}
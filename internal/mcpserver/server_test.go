@@ -0,0 +1,53 @@
+package mcpserver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPTool(t *testing.T) {
+	t.Parallel()
+
+	tool := mcpTool(tools.NewGlobTool(t.TempDir()).Info())
+	require.Equal(t, tools.GlobToolName, tool.Name)
+
+	var schema struct {
+		Type       string         `json:"type"`
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+	}
+	require.NoError(t, json.Unmarshal(tool.RawInputSchema, &schema))
+	require.Equal(t, "object", schema.Type)
+	require.Contains(t, schema.Properties, "pattern")
+	require.Equal(t, []string{"pattern"}, schema.Required)
+}
+
+func TestToolResult(t *testing.T) {
+	t.Parallel()
+
+	resp := tools.WithResponseArtifact(tools.NewTextErrorResponse("output truncated"), tools.ToolArtifact{
+		Name:     "stdout.txt",
+		MimeType: "text/plain",
+		Data:     []byte("full output"),
+	})
+	resp = tools.WithResponseArtifact(resp, tools.ToolArtifact{
+		Name:     "image.png",
+		MimeType: "image/png",
+		Data:     []byte{0x89, 'P', 'N', 'G'},
+	})
+
+	result := toolResult(resp)
+	require.True(t, result.IsError)
+	require.Len(t, result.Content, 3)
+	require.Equal(t, "output truncated", result.Content[0].(mcp.TextContent).Text)
+
+	text := result.Content[1].(mcp.EmbeddedResource).Resource.(mcp.TextResourceContents)
+	require.Equal(t, "full output", text.Text)
+
+	blob := result.Content[2].(mcp.EmbeddedResource).Resource.(mcp.BlobResourceContents)
+	require.Equal(t, "iVBORw==", blob.Blob)
+}
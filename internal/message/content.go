@@ -2,7 +2,9 @@ package message
 
 import (
 	"encoding/base64"
+	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
@@ -35,11 +37,28 @@ type ContentPart interface {
 	isPart()
 }
 
+// ReasoningFormat is the wire format a provider returned the reasoning of a
+// model in. It decides whether the reasoning can be sent back to a provider.
+type ReasoningFormat string
+
+const (
+	// ReasoningFormatAnthropic is a thinking block with a signature, it is
+	// only valid for the provider that signed it.
+	ReasoningFormatAnthropic ReasoningFormat = "anthropic"
+	// ReasoningFormatOpenAI is a reasoning summary of an OpenAI compatible
+	// API, e.g. the reasoning field of OpenRouter.
+	ReasoningFormatOpenAI ReasoningFormat = "openai"
+	// ReasoningFormatDeepSeek is the reasoning_content field of DeepSeek and
+	// compatible APIs.
+	ReasoningFormatDeepSeek ReasoningFormat = "deepseek"
+)
+
 type ReasoningContent struct {
-	Thinking   string `json:"thinking"`
-	Signature  string `json:"signature"`
-	StartedAt  int64  `json:"started_at,omitempty"`
-	FinishedAt int64  `json:"finished_at,omitempty"`
+	Thinking   string          `json:"thinking"`
+	Signature  string          `json:"signature"`
+	Format     ReasoningFormat `json:"format,omitempty"`
+	StartedAt  int64           `json:"started_at,omitempty"`
+	FinishedAt int64           `json:"finished_at,omitempty"`
 }
 
 func (tc ReasoningContent) String() string {
@@ -82,8 +101,33 @@ func (bc BinaryContent) String(p catwalk.InferenceProvider) string {
 	return base64Encoded
 }
 
+// IsText reports whether the content is text, like MCP resources, and not an
+// image. Text content is sent to the model as text.
+func (bc BinaryContent) IsText() bool {
+	return IsTextMIMEType(bc.MIMEType)
+}
+
+// Text wraps text content with its origin so the model can tell attached
+// resources apart from the prompt.
+func (bc BinaryContent) Text() string {
+	return fmt.Sprintf("<resource uri=%q mime_type=%q>\n%s\n</resource>", bc.Path, bc.MIMEType, bc.Data)
+}
+
 func (BinaryContent) isPart() {}
 
+// IsTextMIMEType reports whether the MIME type is one of text content.
+func IsTextMIMEType(mimeType string) bool {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	if strings.HasPrefix(mimeType, "text/") {
+		return true
+	}
+	switch mimeType {
+	case "application/json", "application/xml", "application/yaml", "application/x-yaml", "application/javascript", "application/toml":
+		return true
+	}
+	return strings.HasSuffix(mimeType, "+json") || strings.HasSuffix(mimeType, "+xml")
+}
+
 type ToolCall struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
@@ -236,6 +280,7 @@ func (m *Message) AppendReasoningContent(delta string) {
 			m.Parts[i] = ReasoningContent{
 				Thinking:   c.Thinking + delta,
 				Signature:  c.Signature,
+				Format:     c.Format,
 				StartedAt:  c.StartedAt,
 				FinishedAt: c.FinishedAt,
 			}
@@ -256,6 +301,7 @@ func (m *Message) AppendReasoningSignature(signature string) {
 			m.Parts[i] = ReasoningContent{
 				Thinking:   c.Thinking,
 				Signature:  c.Signature + signature,
+				Format:     c.Format,
 				StartedAt:  c.StartedAt,
 				FinishedAt: c.FinishedAt,
 			}
@@ -265,6 +311,39 @@ func (m *Message) AppendReasoningSignature(signature string) {
 	m.Parts = append(m.Parts, ReasoningContent{Signature: signature})
 }
 
+// SetReasoningFormat records the format the reasoning of the message was
+// returned in, the first format set wins.
+func (m *Message) SetReasoningFormat(format ReasoningFormat) {
+	for i, part := range m.Parts {
+		if c, ok := part.(ReasoningContent); ok {
+			if c.Format == "" {
+				c.Format = format
+				m.Parts[i] = c
+			}
+			return
+		}
+	}
+}
+
+// CanReplayReasoning reports whether the reasoning of the message can be sent
+// back in the format to the provider with the ID. Only signed thinking is
+// accepted as input, and only by the provider that signed it: OpenAI
+// compatible APIs drop reasoning summaries and DeepSeek rejects requests
+// that include reasoning_content. Reasoning stored without a format predates
+// it and was only kept for signed thinking.
+func (m *Message) CanReplayReasoning(format ReasoningFormat, providerID string) bool {
+	reasoning := m.ReasoningContent()
+	if reasoning.Thinking == "" || reasoning.Signature == "" {
+		return false
+	}
+	switch format {
+	case ReasoningFormatAnthropic:
+		return (reasoning.Format == "" || reasoning.Format == ReasoningFormatAnthropic) && m.Provider == providerID
+	default:
+		return false
+	}
+}
+
 func (m *Message) FinishThinking() {
 	for i, part := range m.Parts {
 		if c, ok := part.(ReasoningContent); ok {
@@ -272,6 +351,7 @@ func (m *Message) FinishThinking() {
 				m.Parts[i] = ReasoningContent{
 					Thinking:   c.Thinking,
 					Signature:  c.Signature,
+					Format:     c.Format,
 					StartedAt:  c.StartedAt,
 					FinishedAt: time.Now().Unix(),
 				}
@@ -0,0 +1,90 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanReplayReasoning(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		provider  string
+		reasoning ReasoningContent
+		want      bool
+	}{
+		{
+			name:      "signed thinking of the same provider",
+			provider:  "anthropic",
+			reasoning: ReasoningContent{Thinking: "hmm", Signature: "sig", Format: ReasoningFormatAnthropic},
+			want:      true,
+		},
+		{
+			name:      "signed thinking stored without a format",
+			provider:  "anthropic",
+			reasoning: ReasoningContent{Thinking: "hmm", Signature: "sig"},
+			want:      true,
+		},
+		{
+			name:      "signed thinking of another provider",
+			provider:  "bedrock",
+			reasoning: ReasoningContent{Thinking: "hmm", Signature: "sig", Format: ReasoningFormatAnthropic},
+		},
+		{
+			name:      "thinking without a signature",
+			provider:  "anthropic",
+			reasoning: ReasoningContent{Thinking: "hmm", Format: ReasoningFormatAnthropic},
+		},
+		{
+			name:      "openrouter reasoning",
+			provider:  "anthropic",
+			reasoning: ReasoningContent{Thinking: "hmm", Format: ReasoningFormatOpenAI},
+		},
+		{
+			name:      "deepseek reasoning",
+			provider:  "anthropic",
+			reasoning: ReasoningContent{Thinking: "hmm", Format: ReasoningFormatDeepSeek},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			msg := Message{Role: Assistant, Provider: "anthropic", Parts: []ContentPart{tt.reasoning}}
+			require.Equal(t, tt.want, msg.CanReplayReasoning(ReasoningFormatAnthropic, tt.provider))
+			require.False(t, msg.CanReplayReasoning(ReasoningFormatDeepSeek, tt.provider))
+		})
+	}
+}
+
+func TestSetReasoningFormat(t *testing.T) {
+	t.Parallel()
+
+	msg := Message{Role: Assistant}
+	msg.AppendReasoningContent("let me think")
+	msg.SetReasoningFormat(ReasoningFormatDeepSeek)
+	msg.AppendReasoningContent(", done")
+	msg.SetReasoningFormat(ReasoningFormatOpenAI)
+	msg.FinishThinking()
+
+	reasoning := msg.ReasoningContent()
+	require.Equal(t, "let me think, done", reasoning.Thinking)
+	require.Equal(t, ReasoningFormatDeepSeek, reasoning.Format)
+}
+
+func TestIsTextMIMEType(t *testing.T) {
+	t.Parallel()
+
+	for mimeType, want := range map[string]bool{
+		"text/plain":                   true,
+		"text/markdown; charset=utf-8": true,
+		"application/json":             true,
+		"application/ld+json":          true,
+		"image/png":                    false,
+		"application/octet-stream":     false,
+	} {
+		require.Equal(t, want, IsTextMIMEType(mimeType), mimeType)
+	}
+}
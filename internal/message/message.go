@@ -25,7 +25,10 @@ type Service interface {
 	Update(ctx context.Context, message Message) error
 	Get(ctx context.Context, id string) (Message, error)
 	List(ctx context.Context, sessionID string) ([]Message, error)
+	ListBefore(ctx context.Context, sessionID, beforeID string, limit int) ([]Message, error)
 	Delete(ctx context.Context, id string) error
+	// Move moves the message to another session, keeping its ID and time.
+	Move(ctx context.Context, message Message, sessionID string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
 }
 
@@ -54,6 +57,20 @@ func (s *service) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (s *service) Move(ctx context.Context, message Message, sessionID string) error {
+	err := s.q.MoveMessage(ctx, db.MoveMessageParams{
+		SessionID: sessionID,
+		ID:        message.ID,
+	})
+	if err != nil {
+		return err
+	}
+	s.Publish(pubsub.DeletedEvent, message)
+	message.SessionID = sessionID
+	s.Publish(pubsub.CreatedEvent, message)
+	return nil
+}
+
 func (s *service) Create(ctx context.Context, sessionID string, params CreateMessageParams) (Message, error) {
 	if params.Role != Assistant {
 		params.Parts = append(params.Parts, Finish{
@@ -145,6 +162,29 @@ func (s *service) List(ctx context.Context, sessionID string) ([]Message, error)
 	return messages, nil
 }
 
+// ListBefore returns up to limit messages of the session that were created
+// before the message with beforeID, in chronological order. An empty beforeID
+// returns the latest messages.
+func (s *service) ListBefore(ctx context.Context, sessionID, beforeID string, limit int) ([]Message, error) {
+	dbMessages, err := s.q.ListMessagesBySessionBefore(ctx, db.ListMessagesBySessionBeforeParams{
+		SessionID: sessionID,
+		ID:        beforeID,
+		Limit:     int64(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]Message, len(dbMessages))
+	for i, dbMessage := range dbMessages {
+		// Rows come newest first; reverse them into chronological order.
+		messages[len(dbMessages)-1-i], err = s.fromDBItem(dbMessage)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
 func (s *service) fromDBItem(item db.Message) (Message, error) {
 	parts, err := unmarshallParts([]byte(item.Parts))
 	if err != nil {
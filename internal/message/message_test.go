@@ -0,0 +1,62 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListBefore(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	defer conn.Close()
+	q := db.New(conn)
+	sessions := session.NewService(q, nil)
+	messages := NewService(q)
+
+	sess, err := sessions.Create(t.Context(), "paged")
+	require.NoError(t, err)
+	other, err := sessions.Create(t.Context(), "other")
+	require.NoError(t, err)
+	empty, err := sessions.Create(t.Context(), "empty")
+	require.NoError(t, err)
+	var ids []string
+	for range 5 {
+		msg, err := messages.Create(t.Context(), sess.ID, CreateMessageParams{Role: User, Parts: []ContentPart{TextContent{Text: "hi"}}})
+		require.NoError(t, err)
+		ids = append(ids, msg.ID)
+		// The messages of other sessions are created in between.
+		_, err = messages.Create(t.Context(), other.ID, CreateMessageParams{Role: User, Parts: []ContentPart{TextContent{Text: "hi"}}})
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name      string
+		sessionID string
+		beforeID  string
+		limit     int
+		want      []string
+	}{
+		{"latest", sess.ID, "", 2, ids[3:]},
+		{"before", sess.ID, ids[3], 2, ids[1:3]},
+		{"exactly the rest", sess.ID, ids[3], 3, ids[:3]},
+		{"fewer than the limit", sess.ID, ids[2], 5, ids[:2]},
+		{"before the first", sess.ID, ids[0], 5, []string{}},
+		{"empty session", empty.ID, "", 5, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := messages.ListBefore(t.Context(), tt.sessionID, tt.beforeID, tt.limit)
+			require.NoError(t, err)
+			got := make([]string, len(page))
+			for i, msg := range page {
+				got[i] = msg.ID
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Package network configures the proxy and the TLS settings of the outbound
+// HTTP connections of crush, for the networks intercepting TLS or only
+// reachable through a proxy.
+//
+// The transport is installed as http.DefaultTransport, so it also applies to
+// the clients of the provider SDKs and of the MCP servers, which use it.
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// defaultTransport is the transport of the standard library, the options
+// apply to a copy of it.
+var defaultTransport = http.DefaultTransport.(*http.Transport)
+
+// proxySchemes are the schemes of the proxies the transport supports.
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// Options configure the transport.
+type Options struct {
+	// Proxy is the URL of the proxy of every request, replacing the
+	// HTTP_PROXY and HTTPS_PROXY environment variables.
+	Proxy string
+	// NoProxy holds the hosts, domains and CIDRs reached without the proxy,
+	// replacing the NO_PROXY environment variable.
+	NoProxy []string
+	// CAFile is a PEM bundle of certificate authorities trusted in addition
+	// to those of the system.
+	CAFile string
+	// CertFile and KeyFile are the PEM certificate and key presented to the
+	// servers asking for a client certificate.
+	CertFile string
+	KeyFile  string
+}
+
+// Configure makes the transport of the options the default one.
+func Configure(opts Options) error {
+	t, err := NewTransport(opts)
+	if err != nil {
+		return err
+	}
+	http.DefaultTransport = t
+	return nil
+}
+
+// Transport returns a copy of the default transport, for the clients tuning
+// their connections.
+func Transport() *http.Transport {
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		return t.Clone()
+	}
+	return defaultTransport.Clone()
+}
+
+// NewTransport returns a copy of the transport of the standard library
+// configured with the options.
+func NewTransport(opts Options) (*http.Transport, error) {
+	t := defaultTransport.Clone()
+	if opts.Proxy != "" || len(opts.NoProxy) > 0 {
+		proxy, err := proxyFunc(opts)
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = proxy
+	}
+
+	if opts.CAFile == "" && opts.CertFile == "" && opts.KeyFile == "" {
+		return t, nil
+	}
+	tlsConfig := &tls.Config{}
+	if opts.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate in the CA bundle %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, fmt.Errorf("the client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	t.TLSClientConfig = tlsConfig
+	return t, nil
+}
+
+// proxyFunc returns the proxy of each request, the options overriding the
+// environment variables.
+func proxyFunc(opts Options) (func(*http.Request) (*url.URL, error), error) {
+	cfg := httpproxy.FromEnvironment()
+	if opts.Proxy != "" {
+		u, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		if !slices.Contains(proxySchemes, u.Scheme) || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q, expected %s://host:port", opts.Proxy, strings.Join(proxySchemes, "|"))
+		}
+		cfg.HTTPProxy = opts.Proxy
+		cfg.HTTPSProxy = opts.Proxy
+	}
+	if len(opts.NoProxy) > 0 {
+		cfg.NoProxy = strings.Join(opts.NoProxy, ",")
+	}
+	proxy := cfg.ProxyFunc()
+	return func(r *http.Request) (*url.URL, error) {
+		return proxy(r.URL)
+	}, nil
+}
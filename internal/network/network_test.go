@@ -0,0 +1,81 @@
+package network
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransportProxy(t *testing.T) {
+	t.Parallel()
+
+	tr, err := NewTransport(Options{
+		Proxy:   "socks5://proxy.corp:1080",
+		NoProxy: []string{"internal.corp", "10.0.0.0/8"},
+	})
+	require.NoError(t, err)
+
+	for target, expected := range map[string]string{
+		"https://api.openai.com/v1":  "socks5://proxy.corp:1080",
+		"http://example.com":         "socks5://proxy.corp:1080",
+		"https://git.internal.corp/": "",
+		"http://10.1.2.3:8080/":      "",
+	} {
+		req, err := http.NewRequest(http.MethodGet, target, nil)
+		require.NoError(t, err)
+		proxy, err := tr.Proxy(req)
+		require.NoError(t, err)
+		if expected == "" {
+			require.Nil(t, proxy, target)
+		} else {
+			require.Equal(t, expected, proxy.String(), target)
+		}
+	}
+}
+
+func TestNewTransportInvalid(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	for name, opts := range map[string]Options{
+		"proxy without scheme": {Proxy: "proxy.corp:8080"},
+		"unsupported scheme":   {Proxy: "ftp://proxy.corp"},
+		"missing CA bundle":    {CAFile: filepath.Join(dir, "missing.pem")},
+		"CA bundle not PEM":    {CAFile: notPEM},
+		"certificate only":     {CertFile: notPEM},
+	} {
+		_, err := NewTransport(opts)
+		require.Error(t, err, name)
+	}
+}
+
+func TestNewTransportCAFile(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, ca, 0o600))
+
+	_, err := (&http.Client{Transport: defaultTransport.Clone()}).Get(server.URL)
+	require.Error(t, err, "the certificate of the server isn't trusted by default")
+
+	tr, err := NewTransport(Options{CAFile: caFile})
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: tr}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
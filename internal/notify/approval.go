@@ -0,0 +1,301 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+// slackMaxClockSkew is how old a Slack request may be, to prevent replays.
+const slackMaxClockSkew = 5 * time.Minute
+
+// Approvals serves the pages and the Slack interactivity endpoint that
+// answer pending permission requests.
+type Approvals struct {
+	permissions        permission.Service
+	address            string
+	publicURL          string
+	slackSigningSecret string
+	// key signs the links of the approval pages, it is generated per run so
+	// links stop working once the process exits.
+	key     []byte
+	pending *csync.Map[string, permission.PermissionRequest]
+	serving atomic.Bool
+	client  *http.Client
+}
+
+func newApprovals(opts config.RemoteApprovalOptions, permissions permission.Service) (*Approvals, error) {
+	if opts.Address == "" || opts.PublicURL == "" {
+		return nil, errors.New("remote approval requires an address and a public URL")
+	}
+	secret, err := opts.ResolvedSlackSigningSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve Slack signing secret: %w", err)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &Approvals{
+		permissions:        permissions,
+		address:            opts.Address,
+		publicURL:          strings.TrimSuffix(opts.PublicURL, "/"),
+		slackSigningSecret: secret,
+		key:                key,
+		pending:            csync.NewMap[string, permission.PermissionRequest](),
+		client:             &http.Client{Timeout: notifyTimeout},
+	}, nil
+}
+
+// Serving reports whether the approval server is listening.
+func (a *Approvals) Serving() bool {
+	return a != nil && a.serving.Load()
+}
+
+// Start listens on the configured address and serves the approvals in the
+// background until the context is done.
+func (a *Approvals) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", a.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen for remote approvals: %w", err)
+	}
+	srv := &http.Server{
+		Handler:           a.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Serving remote approvals", "address", ln.Addr().String(), "public_url", a.publicURL)
+	a.serving.Store(true)
+	go func() {
+		defer a.serving.Store(false)
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Remote approval server failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Handler returns the HTTP handler of the approval server.
+func (a *Approvals) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /permissions/{id}", a.handlePage)
+	mux.HandleFunc("POST /permissions/{id}", a.handleAnswer)
+	mux.HandleFunc("POST /slack/actions", a.handleSlack)
+	return mux
+}
+
+// track registers a pending request and returns the URL of its page.
+func (a *Approvals) track(req permission.PermissionRequest) string {
+	a.pending.Set(req.ID, req)
+	return fmt.Sprintf("%s/permissions/%s?token=%s", a.publicURL, url.PathEscape(req.ID), a.token(req.ID))
+}
+
+// forget drops the pending request of a tool call that was answered
+// elsewhere, e.g. in the TUI.
+func (a *Approvals) forget(toolCallID string) {
+	for id, req := range a.pending.Seq2() {
+		if req.ToolCallID == toolCallID {
+			a.pending.Del(id)
+		}
+	}
+}
+
+// resolve answers a pending request, it returns false when the request was
+// already answered.
+func (a *Approvals) resolve(id string, granted bool) (permission.PermissionRequest, bool) {
+	req, ok := a.pending.Take(id)
+	if !ok {
+		return req, false
+	}
+	if granted {
+		a.permissions.Grant(req)
+	} else {
+		a.permissions.Deny(req)
+	}
+	slog.Info("Permission answered remotely", "tool", req.ToolName, "action", req.Action, "granted", granted)
+	return req, true
+}
+
+func (a *Approvals) token(id string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (a *Approvals) validToken(id, token string) bool {
+	return hmac.Equal([]byte(a.token(id)), []byte(token))
+}
+
+var approvalPage = template.Must(template.New("approval").Parse(`<!doctype html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width"><title>Crush permission request</title></head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 2em auto;">
+{{if .Request}}
+<h1>Permission requested</h1>
+<p><strong>Tool:</strong> <code>{{.Request.ToolName}}</code> <strong>Action:</strong> <code>{{.Request.Action}}</code></p>
+{{if .Request.Path}}<p><strong>Path:</strong> <code>{{.Request.Path}}</code></p>{{end}}
+{{if .Request.Description}}<pre style="white-space: pre-wrap;">{{.Request.Description}}</pre>{{end}}
+<form method="post">
+<input type="hidden" name="token" value="{{.Token}}">
+<button name="decision" value="allow">Allow</button>
+<button name="decision" value="deny">Deny</button>
+</form>
+{{else}}
+<p>{{.Message}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+type approvalPageData struct {
+	Request *permission.PermissionRequest
+	Token   string
+	Message string
+}
+
+func (a *Approvals) handlePage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	token := r.URL.Query().Get("token")
+	if !a.validToken(id, token) {
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+	}
+	data := approvalPageData{Token: token}
+	if req, ok := a.pending.Get(id); ok {
+		data.Request = &req
+	} else {
+		data.Message = "This permission request was already answered."
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = approvalPage.Execute(w, data)
+}
+
+func (a *Approvals) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !a.validToken(id, r.FormValue("token")) {
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+	}
+	granted := r.FormValue("decision") == "allow"
+	data := approvalPageData{Message: "This permission request was already answered."}
+	if _, ok := a.resolve(id, granted); ok {
+		data.Message = "Permission denied."
+		if granted {
+			data.Message = "Permission allowed."
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = approvalPage.Execute(w, data)
+}
+
+type slackInteraction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	ResponseURL string `json:"response_url"`
+}
+
+func (a *Approvals) handleSlack(w http.ResponseWriter, r *http.Request) {
+	if a.slackSigningSecret == "" {
+		http.Error(w, "Slack interactivity is not configured", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if err := verifySlackSignature(a.slackSigningSecret, r.Header, body, time.Now()); err != nil {
+		slog.Warn("Rejected Slack request", "error", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	var interaction slackInteraction
+	if err := json.Unmarshal([]byte(form.Get("payload")), &interaction); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+
+	for _, action := range interaction.Actions {
+		if action.ActionID != slackGrantAction && action.ActionID != slackDenyAction {
+			continue
+		}
+		granted := action.ActionID == slackGrantAction
+		req, ok := a.resolve(action.Value, granted)
+		text := "This permission request was already answered."
+		if ok {
+			verb := "Denied"
+			if granted {
+				verb = "Allowed"
+			}
+			text = fmt.Sprintf("%s `%s %s` (answered by <@%s>)", verb, slackEscape(req.ToolName), slackEscape(req.Action), interaction.User.ID)
+		}
+		if interaction.ResponseURL != "" {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+				defer cancel()
+				msg := map[string]any{"replace_original": ok, "text": text}
+				if err := postJSON(ctx, a.client, interaction.ResponseURL, msg); err != nil {
+					slog.Error("Failed to update Slack message", "error", err)
+				}
+			}()
+		}
+	}
+}
+
+// verifySlackSignature checks the signature Slack computes over the
+// timestamp and the body of its requests with the app signing secret.
+func verifySlackSignature(secret string, header http.Header, body []byte, now time.Time) error {
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("missing request timestamp")
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > slackMaxClockSkew || d < -slackMaxClockSkew {
+		return errors.New("request timestamp is too old")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+const (
+	discordColorInfo    = 0x6b50ff
+	discordColorWarning = 0xf5a623
+	discordColorError   = 0xed4245
+)
+
+// discordNotifier posts embeds to a Discord webhook. Discord webhooks can't
+// carry interactive buttons, so permission requests link to the approval
+// page instead.
+type discordNotifier struct {
+	url    string
+	client *http.Client
+}
+
+type discordMessage struct {
+	Username string         `json:"username"`
+	Content  string         `json:"content"`
+	Embeds   []discordEmbed `json:"embeds,omitempty"`
+}
+
+type discordEmbed struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Color       int    `json:"color"`
+}
+
+func (n *discordNotifier) Notify(ctx context.Context, e Event) error {
+	return postJSON(ctx, n.client, n.url, n.message(e))
+}
+
+func (n *discordNotifier) message(e Event) discordMessage {
+	embed := discordEmbed{Title: title(e), Color: discordColorInfo}
+	var sb strings.Builder
+	switch e.Type {
+	case config.NotificationPermissionRequested:
+		p := e.Permission
+		embed.Color = discordColorWarning
+		fmt.Fprintf(&sb, "**Tool:** `%s` **Action:** `%s`\n", p.ToolName, p.Action)
+		if p.Path != "" {
+			fmt.Fprintf(&sb, "**Path:** `%s`\n", p.Path)
+		}
+		if p.Description != "" {
+			fmt.Fprintf(&sb, "%s\n", truncate(p.Description, maxSummaryLength))
+		}
+		if e.ApprovalURL != "" {
+			embed.URL = e.ApprovalURL
+			fmt.Fprintf(&sb, "[Answer the request](%s)\n", e.ApprovalURL)
+		}
+	case config.NotificationSessionCompleted:
+		if e.Error != "" {
+			embed.Color = discordColorError
+			fmt.Fprintf(&sb, "**Error:** %s\n", e.Error)
+		}
+		if e.Summary != "" {
+			fmt.Fprintf(&sb, "%s\n", e.Summary)
+		}
+		fmt.Fprintf(&sb, "Cost: $%.4f", e.Cost)
+	}
+	embed.Description = strings.TrimSpace(sb.String())
+
+	return discordMessage{
+		Username: "Crush",
+		Content:  headline(e),
+		Embeds:   []discordEmbed{embed},
+	}
+}
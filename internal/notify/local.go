@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// appName is the title of the terminal and desktop notifications.
+const appName = "Crush"
+
+// terminalProtocol is the escape sequence a terminal shows notifications for.
+type terminalProtocol int
+
+const (
+	// osc777 is the notification sequence of rxvt, foot, Ghostty, WezTerm
+	// and the VTE based terminals.
+	osc777 terminalProtocol = iota
+	// osc9 is the notification sequence of iTerm2, kitty and Windows
+	// Terminal.
+	osc9
+)
+
+func newLocalNotifier(typ config.NotifierType) (Notifier, error) {
+	switch typ {
+	case config.NotifierTerminal:
+		return &terminalNotifier{protocol: detectTerminalProtocol(os.Getenv), tmux: os.Getenv("TMUX") != "", open: openTerminal}, nil
+	case config.NotifierBell:
+		return &bellNotifier{open: openTerminal}, nil
+	case config.NotifierDesktop:
+		if _, err := desktopCommand(runtime.GOOS, "", ""); err != nil {
+			return nil, err
+		}
+		return &desktopNotifier{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", typ)
+	}
+}
+
+// openTerminal opens the controlling terminal, so that the sequences reach
+// it even when the output is redirected or drawn by the TUI.
+func openTerminal() (io.WriteCloser, error) {
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONOUT$"
+	}
+	return os.OpenFile(name, os.O_WRONLY, 0)
+}
+
+func writeTerminal(open func() (io.WriteCloser, error), seq string) error {
+	w, err := open()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	_, err = io.WriteString(w, seq)
+	return err
+}
+
+// detectTerminalProtocol picks the notification sequence of the terminal
+// from its environment, OSC 777 when it isn't known to prefer OSC 9.
+func detectTerminalProtocol(getenv func(string) string) terminalProtocol {
+	switch {
+	case getenv("TERM_PROGRAM") == "iTerm.app",
+		getenv("WT_SESSION") != "",
+		getenv("KITTY_WINDOW_ID") != "",
+		getenv("TERM") == "xterm-kitty":
+		return osc9
+	default:
+		return osc777
+	}
+}
+
+// terminalNotifier writes the events as OSC notification sequences.
+type terminalNotifier struct {
+	protocol terminalProtocol
+	// tmux wraps the sequences to pass them through to the outer terminal.
+	tmux bool
+	open func() (io.WriteCloser, error)
+}
+
+func (n *terminalNotifier) Notify(_ context.Context, e Event) error {
+	return writeTerminal(n.open, n.sequence(e))
+}
+
+func (n *terminalNotifier) sequence(e Event) string {
+	body := sanitize(headline(e))
+	var seq string
+	switch n.protocol {
+	case osc9:
+		seq = fmt.Sprintf("\x1b]9;%s: %s\a", appName, body)
+	default:
+		seq = fmt.Sprintf("\x1b]777;notify;%s;%s\a", appName, body)
+	}
+	if n.tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// sanitize drops the control characters that would end the sequence early.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// bellNotifier rings the terminal bell.
+type bellNotifier struct {
+	open func() (io.WriteCloser, error)
+}
+
+func (n *bellNotifier) Notify(context.Context, Event) error {
+	return writeTerminal(n.open, "\a")
+}
+
+// desktopNotifier shows the events with the notification tool of the
+// platform.
+type desktopNotifier struct{}
+
+func (n *desktopNotifier) Notify(ctx context.Context, e Event) error {
+	args, err := desktopCommand(runtime.GOOS, appName, headline(e))
+	if err != nil {
+		return err
+	}
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// desktopCommand returns the command showing a notification on the
+// platform, an error when there is none.
+func desktopCommand(goos, title, body string) ([]string, error) {
+	switch goos {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(title))
+		return []string{"osascript", "-e", script}, nil
+	case "windows":
+		return nil, fmt.Errorf("desktop notifications are not supported on windows, use the terminal notifier")
+	default:
+		path, err := exec.LookPath("notify-send")
+		if err != nil {
+			return nil, fmt.Errorf("desktop notifications need notify-send: %w", err)
+		}
+		return []string{path, "--app-name=" + appName, "--", title, body}, nil
+	}
+}
+
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,283 @@
+// Package notify posts notifications about sessions to Slack, Discord and
+// webhooks, shows them in the terminal or on the desktop, and lets their
+// recipients answer the permission requests of headless jobs remotely.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+const (
+	notifyTimeout = 10 * time.Second
+	// maxSummaryLength is the length the final response of a session is
+	// truncated to in completion notifications.
+	maxSummaryLength = 500
+)
+
+// Event is something that happened in a session.
+type Event struct {
+	Type         config.NotificationEvent `json:"type"`
+	SessionID    string                   `json:"session_id"`
+	SessionTitle string                   `json:"session_title"`
+	// Summary is the final response of the session, for completions.
+	Summary string  `json:"summary,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	Cost    float64 `json:"cost,omitempty"`
+	// Duration is the time the turn took in seconds, for completions.
+	Duration float64 `json:"duration,omitempty"`
+	// Permission is the pending request, for permission requests.
+	Permission *permission.PermissionRequest `json:"permission,omitempty"`
+	// ApprovalURL is the page to answer the permission request from, set
+	// when remote approval is being served.
+	ApprovalURL string    `json:"approval_url,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// Notifier posts events to a destination.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+type namedNotifier struct {
+	name     string
+	config   config.NotifierConfig
+	notifier Notifier
+}
+
+// Service sends the events of an app to the configured notifiers.
+type Service struct {
+	notifiers []namedNotifier
+	sessions  session.Service
+	approvals *Approvals
+}
+
+// NewService creates the notifiers of the configuration. Notifiers that are
+// misconfigured are logged and skipped.
+func NewService(cfg *config.Config, sessions session.Service, permissions permission.Service) *Service {
+	s := &Service{sessions: sessions}
+	if opts := cfg.Options.RemoteApproval; opts != nil {
+		approvals, err := newApprovals(*opts, permissions)
+		if err != nil {
+			slog.Error("Failed to set up remote approval", "error", err)
+		} else {
+			s.approvals = approvals
+		}
+	}
+
+	client := &http.Client{Timeout: notifyTimeout}
+	for _, n := range cfg.Notifiers.Sorted() {
+		if n.Notifier.Disabled {
+			continue
+		}
+		if n.Notifier.Local() {
+			notifier, err := newLocalNotifier(n.Notifier.Type)
+			if err != nil {
+				slog.Error("Failed to set up notifier", "notifier", n.Name, "error", err)
+				continue
+			}
+			s.notifiers = append(s.notifiers, namedNotifier{name: n.Name, config: n.Notifier, notifier: notifier})
+			continue
+		}
+		url, err := n.Notifier.ResolvedURL()
+		if err != nil || url == "" {
+			slog.Error("Failed to resolve notifier URL", "notifier", n.Name, "error", err)
+			continue
+		}
+		var notifier Notifier
+		switch n.Notifier.Type {
+		case config.NotifierSlack:
+			notifier = &slackNotifier{url: url, client: client, approvals: s.approvals}
+		case config.NotifierDiscord:
+			notifier = &discordNotifier{url: url, client: client}
+		case config.NotifierWebhook:
+			notifier = &webhookNotifier{url: url, client: client}
+		default:
+			slog.Error("Unknown notifier type", "notifier", n.Name, "type", n.Notifier.Type)
+			continue
+		}
+		s.notifiers = append(s.notifiers, namedNotifier{name: n.Name, config: n.Notifier, notifier: notifier})
+	}
+
+	return s
+}
+
+// Enabled reports whether any notifier is configured.
+func (s *Service) Enabled() bool {
+	return len(s.notifiers) > 0
+}
+
+// Approvals returns the remote approval server, nil when remote approval is
+// not configured.
+func (s *Service) Approvals() *Approvals {
+	return s.approvals
+}
+
+// WatchPermissions notifies about the permission requests of the service
+// until the context is done.
+func (s *Service) WatchPermissions(ctx context.Context, permissions permission.Service) {
+	if !s.Enabled() {
+		return
+	}
+	requests := permissions.Subscribe(ctx)
+	answers := permissions.SubscribeNotifications(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-answers:
+				if !ok {
+					return
+				}
+				if s.approvals != nil && (event.Payload.Granted || event.Payload.Denied) {
+					s.approvals.forget(event.Payload.ToolCallID)
+				}
+			case event, ok := <-requests:
+				if !ok {
+					return
+				}
+				req := event.Payload
+				e := Event{
+					Type:       config.NotificationPermissionRequested,
+					SessionID:  req.SessionID,
+					Permission: &req,
+				}
+				if s.approvals != nil && s.approvals.Serving() {
+					e.ApprovalURL = s.approvals.track(req)
+				}
+				s.send(ctx, e)
+			}
+		}
+	}()
+}
+
+// WatchAgent notifies about the completed runs of an agent until the
+// context is done.
+func (s *Service) WatchAgent(ctx context.Context, subscribe func(context.Context) <-chan pubsub.Event[agent.AgentEvent]) {
+	if !s.Enabled() {
+		return
+	}
+	events := subscribe(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				result := event.Payload
+				// Errors without a message happen before the run starts.
+				if result.Type == agent.AgentEventTypeSummarize || result.Type == agent.AgentEventTypeUsage ||
+					result.Message.SessionID == "" {
+					continue
+				}
+				e := Event{
+					Type:      config.NotificationSessionCompleted,
+					SessionID: result.Message.SessionID,
+					Summary:   truncate(result.Message.Content().String(), maxSummaryLength),
+					Duration:  result.Duration.Seconds(),
+				}
+				if result.Error != nil {
+					e.Error = result.Error.Error()
+				}
+				s.send(ctx, e)
+			}
+		}
+	}()
+}
+
+func (s *Service) send(ctx context.Context, e Event) {
+	e.Time = time.Now()
+	if sess, err := s.sessions.Get(ctx, e.SessionID); err == nil {
+		e.SessionTitle = sess.Title
+		e.Cost = sess.Cost
+	}
+	for _, n := range s.notifiers {
+		if !notifies(n.config, e) {
+			continue
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+			defer cancel()
+			if err := n.notifier.Notify(ctx, e); err != nil {
+				slog.Error("Failed to send notification", "notifier", n.name, "event", e.Type, "error", err)
+			}
+		}()
+	}
+}
+
+// notifies reports whether the notifier is enabled for the event, the
+// completions of the turns shorter than its minimum duration left out.
+func notifies(n config.NotifierConfig, e Event) bool {
+	if e.Type == config.NotificationSessionCompleted && e.Duration < float64(n.MinDuration) {
+		return false
+	}
+	return n.Notifies(e.Type)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func title(e Event) string {
+	if e.SessionTitle != "" {
+		return e.SessionTitle
+	}
+	return e.SessionID
+}
+
+// headline is the one line plain text description of the event.
+func headline(e Event) string {
+	switch e.Type {
+	case config.NotificationPermissionRequested:
+		return fmt.Sprintf("Permission requested in %s: %s %s", title(e), e.Permission.ToolName, e.Permission.Action)
+	case config.NotificationSessionCompleted:
+		if e.Error != "" {
+			return fmt.Sprintf("Session %s failed", title(e))
+		}
+		return fmt.Sprintf("Session %s completed", title(e))
+	default:
+		return title(e)
+	}
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
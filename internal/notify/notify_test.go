@@ -0,0 +1,252 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestApprovals(t *testing.T, permissions permission.Service) *Approvals {
+	t.Helper()
+	approvals, err := newApprovals(config.RemoteApprovalOptions{
+		Address:            "127.0.0.1:0",
+		PublicURL:          "https://crush.example.com/",
+		SlackSigningSecret: "secret",
+	}, permissions)
+	require.NoError(t, err)
+	return approvals
+}
+
+// requestPermission asks for a permission in the background and returns the
+// pending request and the channel the answer is sent to.
+func requestPermission(t *testing.T, permissions permission.Service) (permission.PermissionRequest, <-chan bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	requests := permissions.Subscribe(ctx)
+
+	answer := make(chan bool, 1)
+	go func() {
+		answer <- permissions.Request(permission.CreatePermissionRequest{
+			SessionID:  "session",
+			ToolCallID: "call",
+			ToolName:   "bash",
+			Action:     "execute",
+			Path:       t.TempDir(),
+		})
+	}()
+
+	select {
+	case event := <-requests:
+		return event.Payload, answer
+	case <-time.After(5 * time.Second):
+		t.Fatal("no permission request published")
+		return permission.PermissionRequest{}, nil
+	}
+}
+
+func TestApprovalPage(t *testing.T) {
+	t.Parallel()
+
+	permissions := permission.NewPermissionService(t.TempDir(), false, nil)
+	approvals := newTestApprovals(t, permissions)
+	req, answer := requestPermission(t, permissions)
+
+	link, err := url.Parse(approvals.track(req))
+	require.NoError(t, err)
+	require.Equal(t, "crush.example.com", link.Host)
+	token := link.Query().Get("token")
+
+	handler := approvals.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, link.RequestURI(), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "bash")
+
+	rec = httptest.NewRecorder()
+	bad := httptest.NewRequest(http.MethodPost, link.Path, strings.NewReader("token=wrong&decision=allow"))
+	bad.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(rec, bad)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	post := httptest.NewRequest(http.MethodPost, link.Path, strings.NewReader("token="+token+"&decision=allow"))
+	post.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(rec, post)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "Permission allowed.")
+	require.True(t, <-answer)
+
+	_, ok := approvals.resolve(req.ID, false)
+	require.False(t, ok, "a request can only be answered once")
+}
+
+func TestSlackActions(t *testing.T) {
+	t.Parallel()
+
+	permissions := permission.NewPermissionService(t.TempDir(), false, nil)
+	approvals := newTestApprovals(t, permissions)
+	req, answer := requestPermission(t, permissions)
+	approvals.track(req)
+
+	payload, err := json.Marshal(map[string]any{
+		"type":    "block_actions",
+		"user":    map[string]string{"id": "U1"},
+		"actions": []map[string]string{{"action_id": slackDenyAction, "value": req.ID}},
+	})
+	require.NoError(t, err)
+	body := url.Values{"payload": {string(payload)}}.Encode()
+
+	send := func(signature string) int {
+		r := httptest.NewRequest(http.MethodPost, "/slack/actions", strings.NewReader(body))
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		r.Header.Set("X-Slack-Request-Timestamp", ts)
+		if signature == "" {
+			mac := hmac.New(sha256.New, []byte("secret"))
+			fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+			signature = "v0=" + hex.EncodeToString(mac.Sum(nil))
+		}
+		r.Header.Set("X-Slack-Signature", signature)
+		rec := httptest.NewRecorder()
+		approvals.Handler().ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, send("v0=forged"))
+	require.Equal(t, http.StatusOK, send(""))
+	require.False(t, <-answer)
+}
+
+func TestVerifySlackSignatureExpired(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	err := verifySlackSignature("secret", header, nil, time.Now())
+	require.ErrorContains(t, err, "too old")
+}
+
+func TestSlackMessage(t *testing.T) {
+	t.Parallel()
+
+	approvals := newTestApprovals(t, permission.NewPermissionService(t.TempDir(), false, nil))
+	n := &slackNotifier{approvals: approvals}
+	e := Event{
+		Type:         config.NotificationPermissionRequested,
+		SessionTitle: "Job: <deps>",
+		Permission:   &permission.PermissionRequest{ID: "perm", ToolName: "bash", Action: "execute"},
+		ApprovalURL:  "https://crush.example.com/permissions/perm",
+	}
+
+	msg := n.message(e)
+	require.Len(t, msg.Blocks, 2)
+	require.Contains(t, msg.Blocks[0].Text.Text, "&lt;deps&gt;")
+	require.Equal(t, slackGrantAction, msg.Blocks[1].Elements[0].ActionID)
+	require.Equal(t, "perm", msg.Blocks[1].Elements[0].Value)
+
+	// Without a signing secret the buttons can't be verified, so the
+	// message links to the approval page instead.
+	approvals.slackSigningSecret = ""
+	msg = n.message(e)
+	require.Len(t, msg.Blocks, 1)
+	require.Contains(t, msg.Blocks[0].Text.Text, e.ApprovalURL)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		received <- e
+	}))
+	defer srv.Close()
+
+	n := &webhookNotifier{url: srv.URL, client: srv.Client()}
+	err := n.Notify(t.Context(), Event{
+		Type:      config.NotificationSessionCompleted,
+		SessionID: "session",
+		Summary:   "done",
+	})
+	require.NoError(t, err)
+
+	e := <-received
+	require.Equal(t, config.NotificationSessionCompleted, e.Type)
+	require.Equal(t, "done", e.Summary)
+}
+
+type nopWriteCloser struct{ *strings.Builder }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestTerminalNotifier(t *testing.T) {
+	t.Parallel()
+
+	e := Event{Type: config.NotificationSessionCompleted, SessionTitle: "Fix\x1b the\a build"}
+
+	var out strings.Builder
+	n := &terminalNotifier{protocol: osc777, open: func() (io.WriteCloser, error) {
+		return nopWriteCloser{&out}, nil
+	}}
+	require.NoError(t, n.Notify(t.Context(), e))
+	require.Equal(t, "\x1b]777;notify;Crush;Session Fix  the  build completed\a", out.String())
+
+	n.protocol = osc9
+	require.Equal(t, "\x1b]9;Crush: Session Fix  the  build completed\a", n.sequence(e))
+
+	n.tmux = true
+	require.Equal(t, "\x1bPtmux;\x1b\x1b]9;Crush: Session Fix  the  build completed\a\x1b\\", n.sequence(e))
+}
+
+func TestDetectTerminalProtocol(t *testing.T) {
+	t.Parallel()
+
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	require.Equal(t, osc777, detectTerminalProtocol(env(nil)))
+	require.Equal(t, osc777, detectTerminalProtocol(env(map[string]string{"TERM_PROGRAM": "ghostty"})))
+	require.Equal(t, osc9, detectTerminalProtocol(env(map[string]string{"TERM_PROGRAM": "iTerm.app"})))
+	require.Equal(t, osc9, detectTerminalProtocol(env(map[string]string{"WT_SESSION": "1"})))
+	require.Equal(t, osc9, detectTerminalProtocol(env(map[string]string{"TERM": "xterm-kitty"})))
+}
+
+func TestDesktopCommand(t *testing.T) {
+	t.Parallel()
+
+	args, err := desktopCommand("darwin", "Crush", `Session "deps" completed`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"osascript", "-e", `display notification "Session \"deps\" completed" with title "Crush"`}, args)
+
+	_, err = desktopCommand("windows", "Crush", "done")
+	require.Error(t, err)
+}
+
+func TestNotifiesMinDuration(t *testing.T) {
+	t.Parallel()
+
+	n := config.NotifierConfig{Type: config.NotifierBell, MinDuration: 30}
+	require.False(t, notifies(n, Event{Type: config.NotificationSessionCompleted, Duration: 5}))
+	require.True(t, notifies(n, Event{Type: config.NotificationSessionCompleted, Duration: 45}))
+	require.True(t, notifies(n, Event{Type: config.NotificationPermissionRequested}))
+
+	n.Events = []config.NotificationEvent{config.NotificationSessionCompleted}
+	require.False(t, notifies(n, Event{Type: config.NotificationPermissionRequested}))
+}
@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+const (
+	slackGrantAction = "crush_grant"
+	slackDenyAction  = "crush_deny"
+)
+
+// slackNotifier posts Block Kit messages to a Slack incoming webhook. When
+// remote approval is served with a signing secret, permission requests get
+// Allow and Deny buttons; the Slack app must use the approval server as its
+// interactivity request URL.
+type slackNotifier struct {
+	url       string
+	client    *http.Client
+	approvals *Approvals
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string         `json:"type"`
+	Text     *slackText     `json:"text,omitempty"`
+	Elements []slackElement `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackElement struct {
+	Type     string     `json:"type"`
+	Text     *slackText `json:"text,omitempty"`
+	ActionID string     `json:"action_id,omitempty"`
+	Value    string     `json:"value,omitempty"`
+	Style    string     `json:"style,omitempty"`
+}
+
+func (n *slackNotifier) Notify(ctx context.Context, e Event) error {
+	return postJSON(ctx, n.client, n.url, n.message(e))
+}
+
+func (n *slackNotifier) message(e Event) slackMessage {
+	var sb strings.Builder
+	switch e.Type {
+	case config.NotificationPermissionRequested:
+		p := e.Permission
+		fmt.Fprintf(&sb, "*Permission requested* in _%s_\n", slackEscape(title(e)))
+		fmt.Fprintf(&sb, "*Tool:* `%s` *Action:* `%s`\n", slackEscape(p.ToolName), slackEscape(p.Action))
+		if p.Path != "" {
+			fmt.Fprintf(&sb, "*Path:* `%s`\n", slackEscape(p.Path))
+		}
+		if p.Description != "" {
+			fmt.Fprintf(&sb, ">%s\n", slackEscape(truncate(p.Description, maxSummaryLength)))
+		}
+	case config.NotificationSessionCompleted:
+		status := "completed"
+		if e.Error != "" {
+			status = "failed"
+		}
+		fmt.Fprintf(&sb, "*Session %s* _%s_ ($%.4f)\n", status, slackEscape(title(e)), e.Cost)
+		if e.Error != "" {
+			fmt.Fprintf(&sb, "*Error:* %s\n", slackEscape(e.Error))
+		}
+		if e.Summary != "" {
+			fmt.Fprintf(&sb, ">%s\n", slackEscape(strings.ReplaceAll(e.Summary, "\n", "\n>")))
+		}
+	}
+
+	buttons := e.ApprovalURL != "" && n.approvals != nil && n.approvals.slackSigningSecret != ""
+	if e.ApprovalURL != "" && !buttons {
+		fmt.Fprintf(&sb, "<%s|Answer the request>\n", e.ApprovalURL)
+	}
+
+	msg := slackMessage{
+		Text: headline(e),
+		Blocks: []slackBlock{{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: strings.TrimSpace(sb.String())},
+		}},
+	}
+	if buttons {
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type: "actions",
+			Elements: []slackElement{
+				{
+					Type:     "button",
+					Text:     &slackText{Type: "plain_text", Text: "Allow"},
+					ActionID: slackGrantAction,
+					Value:    e.Permission.ID,
+					Style:    "primary",
+				},
+				{
+					Type:     "button",
+					Text:     &slackText{Type: "plain_text", Text: "Deny"},
+					ActionID: slackDenyAction,
+					Value:    e.Permission.ID,
+					Style:    "danger",
+				},
+			},
+		})
+	}
+	return msg
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func slackEscape(s string) string {
+	return slackEscaper.Replace(s)
+}
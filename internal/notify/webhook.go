@@ -0,0 +1,16 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+)
+
+// webhookNotifier posts the events as JSON to a URL.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, e Event) error {
+	return postJSON(ctx, n.client, n.url, e)
+}
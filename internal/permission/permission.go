@@ -3,11 +3,14 @@ package permission
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
 	"sync"
 
+	"github.com/charmbracelet/crush/internal/audit"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/google/uuid"
@@ -46,11 +49,32 @@ type Service interface {
 	pubsub.Suscriber[PermissionRequest]
 	GrantPersistent(permission PermissionRequest)
 	Grant(permission PermissionRequest)
+	// GrantAlways grants the permission and allows its tool and action
+	// without asking from now on. It returns the rule added to the allowed
+	// tools.
+	GrantAlways(permission PermissionRequest) string
 	Deny(permission PermissionRequest)
+	// DenyWithFeedback denies the permission, the feedback telling the
+	// agent what to do instead.
+	DenyWithFeedback(permission PermissionRequest, feedback string)
+	// Feedback returns, once, why the permission requested by the tool call
+	// was denied, when the agent should go on instead of ending its turn.
+	Feedback(toolCallID string) (string, bool)
 	Request(opts CreatePermissionRequest) bool
 	AutoApproveSession(sessionID string)
 	SetSkipRequests(skip bool)
 	SkipRequests() bool
+	// SetAllowedTools replaces the tools, or tool:action pairs, allowed
+	// without asking, when the configuration is reloaded.
+	SetAllowedTools(allowedTools []string)
+	// SetProfile sets the profile whose rules decide on the permissions
+	// first, nil for none.
+	SetProfile(profile *Profile)
+	// SetAuditLog records the permission decisions in the audit log.
+	SetAuditLog(log *audit.Log)
+	// ApproveToolCall grants the next permission requested by the tool
+	// call, which the user approved beforehand, e.g. as part of a plan.
+	ApproveToolCall(toolCallID string)
 	SubscribeNotifications(ctx context.Context) <-chan pubsub.Event[PermissionNotification]
 }
 
@@ -66,6 +90,11 @@ type permissionService struct {
 	autoApproveSessionsMu sync.RWMutex
 	skip                  bool
 	allowedTools          []string
+	profile               *Profile
+	allowedToolsMu        sync.RWMutex // guards allowedTools and profile
+	audit                 *audit.Log
+	approvedToolCalls     *csync.Map[string, bool]
+	feedback              *csync.Map[string, string]
 
 	// used to make sure we only process one request at a time
 	requestMu     sync.Mutex
@@ -106,6 +135,34 @@ func (s *permissionService) Grant(permission PermissionRequest) {
 	}
 }
 
+// Rule returns the tool:action pair of the allowed tools granting the
+// permission.
+func Rule(permission PermissionRequest) string {
+	return permission.ToolName + ":" + permission.Action
+}
+
+func (s *permissionService) GrantAlways(permission PermissionRequest) string {
+	rule := Rule(permission)
+	s.allowedToolsMu.Lock()
+	if !slices.Contains(s.allowedTools, rule) {
+		s.allowedTools = append(slices.Clip(s.allowedTools), rule)
+	}
+	s.allowedToolsMu.Unlock()
+	s.Grant(permission)
+	return rule
+}
+
+func (s *permissionService) DenyWithFeedback(permission PermissionRequest, feedback string) {
+	if feedback != "" {
+		s.feedback.Set(permission.ToolCallID, feedback)
+	}
+	s.Deny(permission)
+}
+
+func (s *permissionService) Feedback(toolCallID string) (string, bool) {
+	return s.feedback.Take(toolCallID)
+}
+
 func (s *permissionService) Deny(permission PermissionRequest) {
 	s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
 		ToolCallID: permission.ToolCallID,
@@ -122,9 +179,60 @@ func (s *permissionService) Deny(permission PermissionRequest) {
 	}
 }
 
+// Reasons of the permission decisions recorded in the audit log.
+const (
+	reasonSkipRequests = "skip_requests"
+	reasonAllowedTools = "allowed_tools"
+	reasonAutoApprove  = "session_auto_approve"
+	reasonSessionGrant = "session_grant"
+	reasonUser         = "user"
+	reasonPlan         = "plan"
+	reasonProfile      = "profile"
+)
+
 func (s *permissionService) Request(opts CreatePermissionRequest) bool {
+	granted, reason := s.request(opts)
+	if s.audit != nil {
+		if err := s.audit.Record(audit.TypePermission, opts.SessionID, audit.Permission{
+			Tool:       opts.ToolName,
+			Action:     opts.Action,
+			Path:       opts.Path,
+			ToolCallID: opts.ToolCallID,
+			Granted:    granted,
+			Reason:     reason,
+		}); err != nil {
+			slog.Error("Failed to record the permission decision", "error", err)
+		}
+	}
+	return granted
+}
+
+// request decides on the permission, asking the user when no rule applies,
+// and tells why it was granted or denied.
+func (s *permissionService) request(opts CreatePermissionRequest) (bool, string) {
 	if s.skip {
-		return true
+		return true, reasonSkipRequests
+	}
+	if _, ok := s.approvedToolCalls.Take(opts.ToolCallID); ok {
+		return true, reasonPlan
+	}
+	s.allowedToolsMu.RLock()
+	profile := s.profile
+	s.allowedToolsMu.RUnlock()
+	var askAlways bool
+	if profile != nil {
+		switch profile.decide(opts, s.workingDir) {
+		case allow:
+			return true, reasonProfile
+		case deny:
+			if opts.ToolCallID == "" {
+				return false, reasonProfile
+			}
+			s.feedback.Set(opts.ToolCallID, fmt.Sprintf("The %s permission profile denies it, don't try to work around it.", profile.Name))
+			return false, reasonProfile
+		case ask:
+			askAlways = true
+		}
 	}
 
 	// tell the UI that a permission was requested
@@ -136,8 +244,11 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 
 	// Check if the tool/action combination is in the allowlist
 	commandKey := opts.ToolName + ":" + opts.Action
-	if slices.Contains(s.allowedTools, commandKey) || slices.Contains(s.allowedTools, opts.ToolName) {
-		return true
+	s.allowedToolsMu.RLock()
+	allowed := slices.Contains(s.allowedTools, commandKey) || slices.Contains(s.allowedTools, opts.ToolName)
+	s.allowedToolsMu.RUnlock()
+	if allowed && !askAlways {
+		return true, reasonAllowedTools
 	}
 
 	s.autoApproveSessionsMu.RLock()
@@ -145,7 +256,7 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 	s.autoApproveSessionsMu.RUnlock()
 
 	if autoApprove {
-		return true
+		return true, reasonAutoApprove
 	}
 
 	fileInfo, err := os.Stat(opts.Path)
@@ -174,18 +285,18 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 
 	s.sessionPermissionsMu.RLock()
 	for _, p := range s.sessionPermissions {
-		if p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
+		if !askAlways && p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
 			s.sessionPermissionsMu.RUnlock()
-			return true
+			return true, reasonSessionGrant
 		}
 	}
 	s.sessionPermissionsMu.RUnlock()
 
 	s.sessionPermissionsMu.RLock()
 	for _, p := range s.sessionPermissions {
-		if p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
+		if !askAlways && p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
 			s.sessionPermissionsMu.RUnlock()
-			return true
+			return true, reasonSessionGrant
 		}
 	}
 	s.sessionPermissionsMu.RUnlock()
@@ -199,7 +310,7 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 	// Publish the request
 	s.Publish(pubsub.CreatedEvent, permission)
 
-	return <-respCh
+	return <-respCh, reasonUser
 }
 
 func (s *permissionService) AutoApproveSession(sessionID string) {
@@ -212,6 +323,14 @@ func (s *permissionService) SubscribeNotifications(ctx context.Context) <-chan p
 	return s.notificationBroker.Subscribe(ctx)
 }
 
+func (s *permissionService) SetAuditLog(log *audit.Log) {
+	s.audit = log
+}
+
+func (s *permissionService) ApproveToolCall(toolCallID string) {
+	s.approvedToolCalls.Set(toolCallID, true)
+}
+
 func (s *permissionService) SetSkipRequests(skip bool) {
 	s.skip = skip
 }
@@ -220,6 +339,18 @@ func (s *permissionService) SkipRequests() bool {
 	return s.skip
 }
 
+func (s *permissionService) SetProfile(profile *Profile) {
+	s.allowedToolsMu.Lock()
+	defer s.allowedToolsMu.Unlock()
+	s.profile = profile
+}
+
+func (s *permissionService) SetAllowedTools(allowedTools []string) {
+	s.allowedToolsMu.Lock()
+	defer s.allowedToolsMu.Unlock()
+	s.allowedTools = allowedTools
+}
+
 func NewPermissionService(workingDir string, skip bool, allowedTools []string) Service {
 	return &permissionService{
 		Broker:              pubsub.NewBroker[PermissionRequest](),
@@ -230,5 +361,7 @@ func NewPermissionService(workingDir string, skip bool, allowedTools []string) S
 		skip:                skip,
 		allowedTools:        allowedTools,
 		pendingRequests:     csync.NewMap[string, chan bool](),
+		approvedToolCalls:   csync.NewMap[string, bool](),
+		feedback:            csync.NewMap[string, string](),
 	}
 }
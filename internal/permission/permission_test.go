@@ -94,6 +94,23 @@ func TestPermissionService_SkipMode(t *testing.T) {
 	}
 }
 
+func TestPermissionService_SetAllowedTools(t *testing.T) {
+	service := NewPermissionService("/tmp", false, []string{})
+	service.SetAllowedTools([]string{"bash:execute"})
+
+	result := service.Request(CreatePermissionRequest{
+		SessionID:   "test-session",
+		ToolName:    "bash",
+		Action:      "execute",
+		Description: "test command",
+		Path:        "/tmp",
+	})
+
+	if !result {
+		t.Error("expected permission to be granted for the reloaded allowed tools")
+	}
+}
+
 func TestPermissionService_SequentialProperties(t *testing.T) {
 	t.Run("Sequential permission requests with persistent grants", func(t *testing.T) {
 		service := NewPermissionService("/tmp", false, []string{})
@@ -249,3 +266,61 @@ func TestPermissionService_SequentialProperties(t *testing.T) {
 		assert.True(t, result, "Repeated request should be auto-approved due to persistent permission")
 	})
 }
+
+func TestPermissionService_GrantAlways(t *testing.T) {
+	service := NewPermissionService("/tmp", false, []string{"view"})
+	events := service.Subscribe(t.Context())
+
+	req := CreatePermissionRequest{
+		SessionID:   "session1",
+		ToolName:    "bash",
+		Action:      "execute",
+		Description: "test command",
+		Path:        "/tmp",
+	}
+	var result bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		result = service.Request(req)
+	}()
+	event := <-events
+	assert.Equal(t, "bash:execute", service.GrantAlways(event.Payload))
+	wg.Wait()
+	assert.True(t, result)
+
+	// Other sessions are allowed too.
+	req.SessionID = "session2"
+	assert.True(t, service.Request(req), "Request should be allowed by the rule")
+}
+
+func TestPermissionService_DenyWithFeedback(t *testing.T) {
+	service := NewPermissionService("/tmp", false, []string{})
+	events := service.Subscribe(t.Context())
+
+	var result bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		result = service.Request(CreatePermissionRequest{
+			SessionID:  "session1",
+			ToolCallID: "call1",
+			ToolName:   "bash",
+			Action:     "execute",
+			Path:       "/tmp",
+		})
+	}()
+	event := <-events
+	service.DenyWithFeedback(event.Payload, "run the tests with -short")
+	wg.Wait()
+	assert.False(t, result)
+
+	feedback, ok := service.Feedback("call1")
+	assert.True(t, ok)
+	assert.Equal(t, "run the tests with -short", feedback)
+	// The feedback is only told once.
+	_, ok = service.Feedback("call1")
+	assert.False(t, ok)
+}
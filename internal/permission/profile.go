@@ -0,0 +1,302 @@
+package permission
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Profile is a named set of rules deciding on the permissions before the
+// allowed tools and the grants of the sessions.
+type Profile struct {
+	Name        string
+	Description string
+	// Allow are the tools, or tool:action pairs, granted without asking.
+	Allow []string
+	// AllowInWorkingDir are the tools, or tool:action pairs, granted
+	// without asking for the paths in the working directory.
+	AllowInWorkingDir []string
+	// Deny are the tools, or tool:action pairs, denied without asking.
+	Deny []string
+	// DenyCommands are the shell commands denied, matched on every command
+	// the parsed shell command runs, in substitutions, behind wrappers like
+	// env or sudo and in the scripts of sh -c, with the flags in between
+	// skipped. The commands which can't be told are denied too.
+	DenyCommands []string
+	// AllowAll grants the permissions the rules don't deny.
+	AllowAll bool
+	// DenyAll denies the permissions the rules don't grant, when nobody can
+	// answer the requests.
+	DenyAll bool
+	// AskMutations asks for the permissions changing something even when
+	// the allowed tools or the session grant them.
+	AskMutations bool
+	// Audit records the permission decisions in the audit log, even when it
+	// isn't enabled.
+	Audit bool
+}
+
+// ShellCommand is implemented by the params of the permissions running a
+// shell command, for the profiles to match it.
+type ShellCommand interface {
+	ShellCommand() string
+}
+
+// readActions are the actions which don't change anything.
+var readActions = []string{"read", "list"}
+
+// profiles are the built-in profiles by name.
+var profiles = map[string]Profile{
+	"yolo": {
+		Name:        "yolo",
+		Description: "Approve everything, recording it in the audit log",
+		AllowAll:    true,
+		Audit:       true,
+	},
+	"cautious": {
+		Name:         "cautious",
+		Description:  "Ask before every change, even for the allowed tools",
+		AskMutations: true,
+	},
+	"ci": {
+		Name:              "ci",
+		Description:       "Approve the read-only tools, the edits in the repository and the commands, but deny network access and package installs",
+		Allow:             []string{"view", "ls", "glob", "grep", "bash"},
+		AllowInWorkingDir: []string{"edit", "multiedit", "write", "rename_symbol", "code_action", "format_file"},
+		Deny:              []string{"fetch", "download", "sourcegraph"},
+		DenyCommands: []string{
+			// Network
+			"git clone", "git fetch", "git pull", "git push", "git submodule update",
+			"curl", "wget", "ssh", "scp", "sftp", "ftp", "rsync", "nc", "ncat", "netcat", "socat", "telnet",
+			"ping", "dig", "nslookup", "host",
+			// Package installs
+			"npm install", "npm i", "npm ci", "npm add", "npm update", "npx",
+			"yarn add", "yarn install", "pnpm add", "pnpm install", "bun add", "bun install",
+			"pip install", "pip3 install", "python -m pip install", "python3 -m pip install",
+			"uv add", "uv pip install", "poetry add", "poetry install", "pipx install",
+			"go get", "go install", "go mod download",
+			"cargo add", "cargo install", "gem install", "bundle install",
+			"composer install", "composer require", "brew install",
+		},
+		DenyAll: true,
+	},
+}
+
+// ProfileNames returns the names of the built-in profiles, sorted.
+func ProfileNames() []string {
+	return slices.Sorted(maps.Keys(profiles))
+}
+
+// LookupProfile returns the built-in profile with the name, nil for no name.
+func LookupProfile(name string) (*Profile, error) {
+	if name == "" {
+		return nil, nil
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown permission profile %q, expected one of %s", name, strings.Join(ProfileNames(), ", "))
+	}
+	return &profile, nil
+}
+
+// decision is the outcome of the rules of a profile.
+type decision int
+
+const (
+	// undecided leaves the permission to the allowed tools, the session
+	// grants and the user.
+	undecided decision = iota
+	allow
+	deny
+	// ask asks the user even when the allowed tools or the session grant
+	// the permission.
+	ask
+)
+
+// decide applies the rules of the profile to the permission request.
+func (p *Profile) decide(opts CreatePermissionRequest, workingDir string) decision {
+	switch {
+	case matchesRule(p.Deny, opts):
+		return deny
+	case p.deniesCommand(opts):
+		return deny
+	case matchesRule(p.Allow, opts):
+		return allow
+	case matchesRule(p.AllowInWorkingDir, opts) && inDir(opts.Path, workingDir):
+		return allow
+	case p.AllowAll:
+		return allow
+	case p.DenyAll:
+		return deny
+	case p.AskMutations && !slices.Contains(readActions, opts.Action):
+		return ask
+	}
+	return undecided
+}
+
+func (p *Profile) deniesCommand(opts CreatePermissionRequest) bool {
+	params, ok := opts.Params.(ShellCommand)
+	if !ok || len(p.DenyCommands) == 0 {
+		return false
+	}
+	commands, ok := parseCommands(params.ShellCommand())
+	if !ok {
+		return true
+	}
+	for _, command := range commands {
+		for _, denied := range p.DenyCommands {
+			if matchesCommand(command, strings.Fields(denied)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesRule reports whether a rule is the tool, or the tool:action pair,
+// of the request.
+func matchesRule(rules []string, opts CreatePermissionRequest) bool {
+	return slices.Contains(rules, opts.ToolName) || slices.Contains(rules, opts.ToolName+":"+opts.Action)
+}
+
+func inDir(path, dir string) bool {
+	if path == "" || dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// word is a word of a command, unknown when it expands to something only
+// known when the command runs.
+type word struct {
+	value   string
+	unknown bool
+}
+
+// wrappers are the commands running the command in their arguments.
+var wrappers = []string{"env", "sudo", "doas", "command", "builtin", "exec", "nice", "nohup", "time", "timeout", "xargs", "stdbuf", "ionice", "setsid", "chroot"}
+
+// shells are the commands running the script of their -c flag.
+var shells = []string{"sh", "bash", "zsh", "dash", "ksh", "fish"}
+
+// parseCommands parses the shell command, returning the words of every
+// command it runs, those of the scripts given to sh -c and eval included.
+// It fails for the commands which can't be parsed or whose name is only
+// known when they run.
+func parseCommands(command string) ([][]word, bool) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, false
+	}
+	var commands [][]word
+	ok := true
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, isCall := node.(*syntax.CallExpr)
+		if !ok || !isCall || len(call.Args) == 0 {
+			return ok
+		}
+		words := make([]word, len(call.Args))
+		for i, arg := range call.Args {
+			words[i] = wordOf(arg)
+		}
+		if words[0].unknown {
+			ok = false
+			return false
+		}
+		words[0].value = filepath.Base(words[0].value)
+		commands = append(commands, words)
+
+		var script string
+		switch {
+		case slices.Contains(shells, words[0].value):
+			for i := 1; i < len(words)-1; i++ {
+				if flag := words[i].value; strings.HasPrefix(flag, "-") && !strings.HasPrefix(flag, "--") && strings.Contains(flag, "c") {
+					script = words[i+1].value
+					if words[i+1].unknown {
+						ok = false
+					}
+					break
+				}
+			}
+		case words[0].value == "eval":
+			values := make([]string, 0, len(words)-1)
+			for _, w := range words[1:] {
+				if w.unknown {
+					ok = false
+				}
+				values = append(values, w.value)
+			}
+			script = strings.Join(values, " ")
+		}
+		if ok && script != "" {
+			var inner [][]word
+			inner, ok = parseCommands(script)
+			commands = append(commands, inner...)
+		}
+		return ok
+	})
+	return commands, ok
+}
+
+// wordOf returns the value of the word, when it's made of literals and
+// quoted literals only.
+func wordOf(w *syntax.Word) word {
+	var sb strings.Builder
+	for _, part := range w.Parts {
+		switch part := part.(type) {
+		case *syntax.Lit:
+			sb.WriteString(strings.ReplaceAll(part.Value, `\`, ""))
+		case *syntax.SglQuoted:
+			sb.WriteString(part.Value)
+		case *syntax.DblQuoted:
+			for _, part := range part.Parts {
+				lit, ok := part.(*syntax.Lit)
+				if !ok {
+					return word{unknown: true}
+				}
+				sb.WriteString(lit.Value)
+			}
+		default:
+			return word{unknown: true}
+		}
+	}
+	return word{value: sb.String()}
+}
+
+// matchesCommand reports whether the command runs the denied one, the
+// words of the denied one being found in order among its arguments. The
+// commands run by a wrapper are matched from any of its arguments.
+func matchesCommand(command []word, denied []string) bool {
+	if slices.Contains(wrappers, command[0].value) {
+		for i := 1; i < len(command); i++ {
+			if !command[i].unknown && matchesCommand(command[i:], denied) {
+				return true
+			}
+		}
+		return false
+	}
+	if filepath.Base(command[0].value) != denied[0] {
+		return false
+	}
+	rest := denied[1:]
+	// An unknown word may be the subcommand as long as no other one came
+	// before it.
+	subcommand := true
+	for _, w := range command[1:] {
+		if len(rest) == 0 {
+			break
+		}
+		switch {
+		case w.unknown && subcommand, !w.unknown && w.value == rest[0]:
+			rest = rest[1:]
+		case !w.unknown && !strings.HasPrefix(w.value, "-"):
+			subcommand = false
+		}
+	}
+	return len(rest) == 0
+}
@@ -0,0 +1,225 @@
+// Package pin keeps the files and messages pinned to the context of each
+// session, which are sent with every request even once the summary of the
+// session dropped them.
+package pin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+const pinsDirName = "pins"
+
+// Kinds of the pinned items.
+const (
+	KindFile    = "file"
+	KindMessage = "message"
+)
+
+// ErrPinned is returned when pinning an item pinned already.
+var ErrPinned = errors.New("already pinned")
+
+type Pin struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	Kind      string `json:"kind"`
+	// Path is the absolute path of a pinned file, which is sent as it is
+	// when the request is made.
+	Path      string `json:"path,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+	// Title is what the pin is listed as.
+	Title string `json:"title"`
+	// Tokens estimates what the pin adds to each request. The tokens of the
+	// files follow their size.
+	Tokens   int   `json:"tokens"`
+	PinnedAt int64 `json:"pinned_at"`
+}
+
+type Service interface {
+	pubsub.Suscriber[Pin]
+	// PinFile pins the file to the context of the session.
+	PinFile(sessionID, path string) (Pin, error)
+	// PinMessage pins the message to the context of the session, with its
+	// text to estimate its tokens.
+	PinMessage(sessionID, messageID, title, text string) (Pin, error)
+	Unpin(sessionID, id string) (Pin, error)
+	// List returns the pins of the session, in the order they were pinned.
+	List(sessionID string) ([]Pin, error)
+	DeleteSessionPins(sessionID string) error
+}
+
+type service struct {
+	*pubsub.Broker[Pin]
+	dir string
+	mu  sync.Mutex
+}
+
+// NewService returns the pins kept in the pins directory of the given data
+// directory.
+func NewService(dataDir string) Service {
+	return &service{
+		Broker: pubsub.NewBroker[Pin](),
+		dir:    filepath.Join(dataDir, pinsDirName),
+	}
+}
+
+// EstimateTokens estimates the tokens of a text of the given size in bytes,
+// at about four bytes each.
+func EstimateTokens(size int) int {
+	return (size + 3) / 4
+}
+
+func (s *service) sessionFile(sessionID string) string {
+	return filepath.Join(s.dir, filepath.Base(sessionID)+".json")
+}
+
+func (s *service) PinFile(sessionID, path string) (Pin, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Pin{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Pin{}, err
+	}
+	if info.IsDir() {
+		return Pin{}, fmt.Errorf("%s is a directory", path)
+	}
+	return s.add(Pin{
+		SessionID: sessionID,
+		Kind:      KindFile,
+		Path:      path,
+		Title:     path,
+		Tokens:    EstimateTokens(int(info.Size())),
+	})
+}
+
+func (s *service) PinMessage(sessionID, messageID, title, text string) (Pin, error) {
+	return s.add(Pin{
+		SessionID: sessionID,
+		Kind:      KindMessage,
+		MessageID: messageID,
+		Title:     title,
+		Tokens:    EstimateTokens(len(text)),
+	})
+}
+
+func (s *service) add(p Pin) (Pin, error) {
+	if p.SessionID == "" {
+		return Pin{}, errors.New("session ID is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pins, err := s.read(p.SessionID)
+	if err != nil {
+		return Pin{}, err
+	}
+	if slices.ContainsFunc(pins, func(e Pin) bool {
+		return e.Kind == p.Kind && e.Path == p.Path && e.MessageID == p.MessageID
+	}) {
+		return Pin{}, fmt.Errorf("%s: %w", p.Title, ErrPinned)
+	}
+	p.ID = uuid.New().String()[:8]
+	p.PinnedAt = time.Now().Unix()
+	if err := s.write(p.SessionID, append(pins, p)); err != nil {
+		return Pin{}, err
+	}
+	s.Publish(pubsub.CreatedEvent, p)
+	return p, nil
+}
+
+func (s *service) Unpin(sessionID, id string) (Pin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pins, err := s.read(sessionID)
+	if err != nil {
+		return Pin{}, err
+	}
+	i := slices.IndexFunc(pins, func(p Pin) bool { return p.ID == id })
+	if i < 0 {
+		return Pin{}, fmt.Errorf("pin not found: %s", id)
+	}
+	p := pins[i]
+	if err := s.write(sessionID, slices.Delete(pins, i, i+1)); err != nil {
+		return Pin{}, err
+	}
+	s.Publish(pubsub.DeletedEvent, p)
+	return p, nil
+}
+
+func (s *service) List(sessionID string) ([]Pin, error) {
+	s.mu.Lock()
+	pins, err := s.read(sessionID)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range pins {
+		if p.Kind != KindFile {
+			continue
+		}
+		if info, err := os.Stat(p.Path); err == nil {
+			pins[i].Tokens = EstimateTokens(int(info.Size()))
+		} else {
+			pins[i].Tokens = 0
+		}
+	}
+	return pins, nil
+}
+
+func (s *service) DeleteSessionPins(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.sessionFile(sessionID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete pins: %w", err)
+	}
+	return nil
+}
+
+func (s *service) read(sessionID string) ([]Pin, error) {
+	data, err := os.ReadFile(s.sessionFile(sessionID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pins: %w", err)
+	}
+	var pins []Pin
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, fmt.Errorf("failed to parse pins: %w", err)
+	}
+	return pins, nil
+}
+
+func (s *service) write(sessionID string, pins []Pin) error {
+	if len(pins) == 0 {
+		if err := os.Remove(s.sessionFile(sessionID)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to write pins: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create pins directory: %w", err)
+	}
+	data, err := json.Marshal(pins)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pins: %w", err)
+	}
+	if err := os.WriteFile(s.sessionFile(sessionID), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write pins: %w", err)
+	}
+	return nil
+}
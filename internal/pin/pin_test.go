@@ -0,0 +1,58 @@
+package pin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPins(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s := NewService(t.TempDir())
+	file := filepath.Join(dir, "schema.sql")
+	require.NoError(t, os.WriteFile(file, []byte("create table users;"), 0o644))
+
+	pinnedFile, err := s.PinFile("session", file)
+	require.NoError(t, err)
+	require.Equal(t, KindFile, pinnedFile.Kind)
+	require.Equal(t, 5, pinnedFile.Tokens)
+	_, err = s.PinFile("session", file)
+	require.ErrorIs(t, err, ErrPinned)
+	_, err = s.PinFile("session", dir)
+	require.Error(t, err)
+
+	pinnedMsg, err := s.PinMessage("session", "msg-1", "use postgres", "use postgres 16 for everything")
+	require.NoError(t, err)
+	require.Equal(t, 8, pinnedMsg.Tokens)
+
+	// The tokens of the files follow their size.
+	require.NoError(t, os.WriteFile(file, []byte("create table users; create table posts;"), 0o644))
+	pins, err := s.List("session")
+	require.NoError(t, err)
+	require.Len(t, pins, 2)
+	require.Equal(t, pinnedFile.ID, pins[0].ID)
+	require.Equal(t, 10, pins[0].Tokens)
+	require.Equal(t, pinnedMsg.ID, pins[1].ID)
+
+	pins, err = s.List("other")
+	require.NoError(t, err)
+	require.Empty(t, pins)
+
+	unpinned, err := s.Unpin("session", pinnedFile.ID)
+	require.NoError(t, err)
+	require.Equal(t, file, unpinned.Path)
+	_, err = s.Unpin("session", pinnedFile.ID)
+	require.Error(t, err)
+	pins, err = s.List("session")
+	require.NoError(t, err)
+	require.Len(t, pins, 1)
+
+	require.NoError(t, s.DeleteSessionPins("session"))
+	pins, err = s.List("session")
+	require.NoError(t, err)
+	require.Empty(t, pins)
+}
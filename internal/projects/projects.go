@@ -0,0 +1,164 @@
+// Package projects keeps a global index of the projects crush ran in, to
+// list and open their sessions from anywhere.
+package projects
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// IndexFileName is the name of the index in the user data directory.
+const IndexFileName = "projects.json"
+
+// Project is a working directory crush ran in.
+type Project struct {
+	Path     string    `json:"path"`
+	DataDir  string    `json:"data_dir"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// indexMu guards the index within the process, other processes may write it
+// concurrently so it is replaced atomically.
+var indexMu sync.Mutex
+
+func indexFile() string {
+	return filepath.Join(filepath.Dir(config.GlobalConfigData()), IndexFileName)
+}
+
+func read() ([]Project, error) {
+	data, err := os.ReadFile(indexFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var projects []Project
+	if err := json.Unmarshal(data, &projects); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", indexFile(), err)
+	}
+	return projects, nil
+}
+
+// Register records that crush ran in the working directory with the data
+// directory.
+func Register(path, dataDir string) error {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+
+	projects, err := read()
+	if err != nil {
+		return err
+	}
+	project := Project{Path: path, DataDir: dataDir, LastUsed: time.Now()}
+	if i := slices.IndexFunc(projects, func(p Project) bool { return p.DataDir == dataDir }); i >= 0 {
+		projects[i] = project
+	} else {
+		projects = append(projects, project)
+	}
+
+	data, err := json.MarshalIndent(projects, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(indexFile()), 0o700); err != nil {
+		return err
+	}
+	tmp := indexFile() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, indexFile())
+}
+
+// List returns the projects whose data directory still exists, the most
+// recently used first.
+func List() ([]Project, error) {
+	indexMu.Lock()
+	projects, err := read()
+	indexMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	projects = slices.DeleteFunc(projects, func(p Project) bool {
+		_, err := os.Stat(filepath.Join(p.DataDir, "crush.db"))
+		return err != nil
+	})
+	slices.SortFunc(projects, func(a, b Project) int {
+		return b.LastUsed.Compare(a.LastUsed)
+	})
+	return projects, nil
+}
+
+// Session is a session of a project.
+type Session struct {
+	session.Session
+	Project Project
+	// Model is the model of the last answer of the session.
+	Model string
+}
+
+// Sessions returns the sessions of the project, the most recently updated
+// first.
+func Sessions(ctx context.Context, project Project) ([]Session, error) {
+	conn, err := db.Connect(ctx, project.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	q := db.New(conn)
+
+	all, err := session.NewService(q, nil).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, 0, len(all))
+	for _, s := range all {
+		model, err := q.GetLastSessionModel(ctx, s.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		sessions = append(sessions, Session{Session: s, Project: project, Model: model.String})
+	}
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return cmp.Compare(b.UpdatedAt, a.UpdatedAt)
+	})
+	return sessions, nil
+}
+
+// AllSessions returns the sessions of all the projects, the most recently
+// updated first. The projects whose database can't be read are skipped.
+func AllSessions(ctx context.Context) ([]Session, error) {
+	projects, err := List()
+	if err != nil {
+		return nil, err
+	}
+	var sessions []Session
+	for _, p := range projects {
+		s, err := Sessions(ctx, p)
+		if err != nil {
+			slog.Warn("Failed to read the sessions of a project", "path", p.Path, "error", err)
+			continue
+		}
+		sessions = append(sessions, s...)
+	}
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return cmp.Compare(b.UpdatedAt, a.UpdatedAt)
+	})
+	return sessions, nil
+}
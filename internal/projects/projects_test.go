@@ -0,0 +1,68 @@
+package projects
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterAndList(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	first := filepath.Join(t.TempDir(), ".crush")
+	second := filepath.Join(t.TempDir(), ".crush")
+	gone := filepath.Join(t.TempDir(), ".crush")
+	for _, dir := range []string{first, second} {
+		require.NoError(t, os.MkdirAll(dir, 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "crush.db"), nil, 0o600))
+	}
+
+	require.NoError(t, Register("/first", first))
+	require.NoError(t, Register("/gone", gone))
+	require.NoError(t, Register("/second", second))
+	require.NoError(t, Register("/first", first))
+
+	projects, err := List()
+	require.NoError(t, err)
+	require.Len(t, projects, 2)
+	require.Equal(t, "/first", projects[0].Path)
+	require.Equal(t, "/second", projects[1].Path)
+}
+
+func TestSessions(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	dataDir := t.TempDir()
+	conn, err := db.Connect(t.Context(), dataDir)
+	require.NoError(t, err)
+	q := db.New(conn)
+	sessions := session.NewService(q, nil)
+	messages := message.NewService(q)
+	sess, err := sessions.Create(t.Context(), "Fix the parser")
+	require.NoError(t, err)
+	_, err = messages.Create(t.Context(), sess.ID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "Done"}},
+		Model: "gpt-4.1",
+	})
+	require.NoError(t, err)
+	_, err = sessions.Create(t.Context(), "Empty")
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	require.NoError(t, Register("/project", dataDir))
+	all, err := AllSessions(t.Context())
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	models := map[string]string{}
+	for _, s := range all {
+		require.Equal(t, "/project", s.Project.Path)
+		models[s.Title] = s.Model
+	}
+	require.Equal(t, map[string]string{"Fix the parser": "gpt-4.1", "Empty": ""}, models)
+}
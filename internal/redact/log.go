@@ -0,0 +1,78 @@
+package redact
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const logFileName = "redactions.jsonl"
+
+// LogEntry records that a secret was masked before being sent to the
+// provider.
+type LogEntry struct {
+	Time      int64  `json:"time"`
+	SessionID string `json:"session_id"`
+	// Source is where the secret was found, e.g. the tool or the attached
+	// file.
+	Source      string `json:"source"`
+	Rule        string `json:"rule"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Log appends the masked secrets to the redactions.jsonl file of the data
+// directory, each secret once per session and source.
+type Log struct {
+	path string
+	mu   sync.Mutex
+	seen map[LogEntry]bool
+}
+
+func NewLog(dataDir string) *Log {
+	return &Log{
+		path: filepath.Join(dataDir, logFileName),
+		seen: make(map[LogEntry]bool),
+	}
+}
+
+// Record appends the findings not recorded yet.
+func (l *Log) Record(sessionID, source string, findings []Finding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []LogEntry
+	for _, f := range findings {
+		key := LogEntry{SessionID: sessionID, Source: source, Rule: f.Rule, Fingerprint: f.Fingerprint}
+		if l.seen[key] {
+			continue
+		}
+		l.seen[key] = true
+		key.Time = time.Now().Unix()
+		entries = append(entries, key)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// Package redact masks the secrets found in the text sent to the providers,
+// like the output of the tools and the content of the attached files.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// rule finds one kind of secret. When the expression has a group, only the
+// group is the secret, e.g. the value of an assignment.
+type rule struct {
+	name string
+	re   *regexp.Regexp
+	// envOnly rules only apply to the content of .env files.
+	envOnly bool
+}
+
+// linePrefix matches the line numbers the view tool prefixes lines with.
+const linePrefix = `(?:[ \t]*\d+\|)?[ \t]*`
+
+var rules = []rule{
+	{name: "private-key", re: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{name: "aws-access-key-id", re: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{name: "aws-secret-access-key", re: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`)},
+	{name: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{name: "github-token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{name: "slack-token", re: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{name: "api-key", re: regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`)},
+	{name: "google-api-key", re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	// Environment variables are upper case by convention, which keeps this
+	// rule off the fields named like secrets in the source code.
+	{name: "env-secret", re: regexp.MustCompile(`(?m)^` + linePrefix + `(?:export[ \t]+)?[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY|PRIVATE_?KEY|ACCESS_?KEY|CREDENTIALS?)[A-Z0-9_]*[ \t]*[=:][ \t]*["']?([^\s"'#=:][^\s"'#]*)`)},
+	{name: "env-value", re: regexp.MustCompile(`(?m)^` + linePrefix + `(?:export[ \t]+)?[A-Za-z_][A-Za-z0-9_]*[ \t]*=[ \t]*["']?([^\s"'#][^"'#\n]*)`), envOnly: true},
+}
+
+const (
+	// Tokens are candidates for the entropy check from this length, shorter
+	// ones are too often identifiers.
+	minEntropyTokenLength = 32
+	// minEntropy is the Shannon entropy, in bits per character, from which a
+	// token is taken for a secret. Random base64 is above 5, hexadecimal
+	// hashes and UUIDs can't exceed 4.
+	minEntropy = 4.5
+)
+
+var entropyTokenRe = regexp.MustCompile(`[A-Za-z0-9+/_-]{32,}={0,2}`)
+
+// Finding is a secret that was masked. The secret itself is never kept, only
+// its fingerprint.
+type Finding struct {
+	Rule        string
+	Fingerprint string
+}
+
+// Options configure a Redactor.
+type Options struct {
+	// Allowlist holds regular expressions of the values never masked.
+	// Entries that aren't valid expressions are matched literally.
+	Allowlist []string
+	// Entropy turns on the detection of secrets by their entropy, on top of
+	// the known patterns. It's off by default since checksums, lockfiles
+	// and base64 content look just as random.
+	Entropy bool
+}
+
+// Redactor masks the secrets of texts.
+type Redactor struct {
+	allowlist []*regexp.Regexp
+	entropy   bool
+}
+
+func New(opts Options) *Redactor {
+	r := &Redactor{entropy: opts.Entropy}
+	for _, pattern := range opts.Allowlist {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			re = regexp.MustCompile(regexp.QuoteMeta(pattern))
+		}
+		r.allowlist = append(r.allowlist, re)
+	}
+	return r
+}
+
+// IsEnvFile reports whether all the values of the file at the path are to be
+// masked, like those of .env and .env.local.
+func IsEnvFile(path string) bool {
+	base := filepath.Base(path)
+	return base == ".env" || strings.HasPrefix(base, ".env.")
+}
+
+// Redact masks the secrets of the text, replacing each with
+// [REDACTED:<rule>]. envFile tells that the text is the content of a .env
+// file, whose values are all masked.
+func (r *Redactor) Redact(text string, envFile bool) (string, []Finding) {
+	text, findings := r.redactPatterns(text, envFile)
+	if r.entropy {
+		text = entropyTokenRe.ReplaceAllStringFunc(text, func(token string) string {
+			if !isHighEntropy(token) || r.allowed(token) {
+				return token
+			}
+			findings = append(findings, Finding{Rule: "high-entropy", Fingerprint: fingerprint(token)})
+			return mask("high-entropy")
+		})
+	}
+	return text, findings
+}
+
+// RedactPatterns masks the secrets of the text found by the known patterns
+// only, those unlikely to be anything else. It's for the content the model
+// edits, like the output of the view tool, where the masks of the entropy
+// check would get in the way.
+func (r *Redactor) RedactPatterns(text string) (string, []Finding) {
+	return r.redactPatterns(text, false)
+}
+
+func (r *Redactor) redactPatterns(text string, envFile bool) (string, []Finding) {
+	var findings []Finding
+	for _, rl := range rules {
+		if rl.envOnly && !envFile {
+			continue
+		}
+		text = r.replace(text, rl.re, rl.name, &findings)
+	}
+	return text, findings
+}
+
+// HasMask reports whether the text holds a mask of a secret.
+func HasMask(text string) bool {
+	return strings.Contains(text, maskPrefix)
+}
+
+func (r *Redactor) replace(text string, re *regexp.Regexp, name string, findings *[]Finding) string {
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		// Only the group is the secret when there is one.
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		secret := strings.TrimSpace(text[start:end])
+		// The values of .env files may have been masked by a previous rule.
+		if secret == "" || strings.HasPrefix(secret, maskPrefix) || r.allowed(secret) {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(mask(name))
+		last = end
+		*findings = append(*findings, Finding{Rule: name, Fingerprint: fingerprint(secret)})
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+func (r *Redactor) allowed(secret string) bool {
+	for _, re := range r.allowlist {
+		if re.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+const maskPrefix = "[REDACTED:"
+
+func mask(rule string) string {
+	return maskPrefix + rule + "]"
+}
+
+// fingerprint identifies a secret in the log without revealing it.
+func fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// isHighEntropy reports whether the token looks random: long, mixing upper
+// and lower case letters with digits, and with a high entropy.
+func isHighEntropy(token string) bool {
+	if len(token) < minEntropyTokenLength {
+		return false
+	}
+	var upper, lower, digit bool
+	for _, c := range token {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit && shannonEntropy(token) >= minEntropy
+}
+
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+	var entropy float64
+	n := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
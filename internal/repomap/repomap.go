@@ -0,0 +1,351 @@
+// Package repomap summarizes a repository for the system prompt: its files
+// with their main declarations, the files the most referenced by the others
+// first, within a token budget.
+package repomap
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/symbols"
+)
+
+const (
+	// DefaultMaxTokens is the size of the map when none is configured.
+	DefaultMaxTokens = 1024
+	// MaxFiles bounds the files listed, like for the ls tool.
+	MaxFiles = 5000
+
+	bytesPerToken     = 4
+	maxSymbolsPerFile = 8
+	// Shorter names, like loop variables, tell nothing about which file is
+	// referenced.
+	minNameLength = 3
+	// Names declared in more files, like String or New, are too common to
+	// tell which of the files is referenced.
+	maxDeclarations = 10
+	// Names declared in more files weigh less.
+	commonDeclarations = 5
+	// Long compound names, like ListDirectory or list_directory, are less
+	// likely to be used for something else than plain words, like Text.
+	specificNameLength = 8
+
+	damping    = 0.85
+	iterations = 30
+
+	// refreshInterval is how long the map is reused before the files are
+	// checked for changes again.
+	refreshInterval = 5 * time.Second
+)
+
+// file is a parsed source file of the repository.
+type file struct {
+	// path is relative to the root, with forward slashes.
+	path    string
+	modTime time.Time
+	size    int64
+	symbols []symbols.Symbol
+	// names are the identifiers used in the file.
+	names map[string]bool
+}
+
+// Map is the map of the repository in a directory, kept up to date as its
+// files change.
+type Map struct {
+	root      string
+	maxTokens int
+
+	mu      sync.Mutex
+	files   map[string]*file
+	text    string
+	checked time.Time
+}
+
+// New returns the map of the repository in root, of about maxTokens tokens.
+func New(root string, maxTokens int) *Map {
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxTokens
+	}
+	return &Map{
+		root:      root,
+		maxTokens: maxTokens,
+		files:     make(map[string]*file),
+	}
+}
+
+// String returns the map, built again when files were added, changed or
+// removed since it last was. Only the changed files are parsed again.
+func (m *Map) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.checked.IsZero() && time.Since(m.checked) < refreshInterval {
+		return m.text
+	}
+	m.checked = time.Now()
+	if m.scan() {
+		m.text = render(slices.Collect(maps.Values(m.files)), m.maxTokens*bytesPerToken)
+	}
+	return m.text
+}
+
+// scan parses the new and changed source files, reporting whether any file
+// was added, changed or removed.
+func (m *Map) scan() bool {
+	paths, _, err := fsext.ListDirectory(m.root, nil, MaxFiles)
+	if err != nil {
+		return false
+	}
+	changed := false
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if _, ok := symbols.LanguageForFile(path); !ok {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Size() > symbols.MaxFileSize {
+			continue
+		}
+		rel, err := filepath.Rel(m.root, path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+		if f, ok := m.files[rel]; ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+			continue
+		}
+		f, err := parseFile(path)
+		if err != nil {
+			delete(m.files, rel)
+			continue
+		}
+		f.path, f.modTime, f.size = rel, info.ModTime(), info.Size()
+		m.files[rel] = f
+		changed = true
+	}
+	for rel := range m.files {
+		if !seen[rel] {
+			delete(m.files, rel)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func parseFile(path string) (*file, error) {
+	lang, _ := symbols.LanguageForFile(path)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	syms, err := symbols.Parse(lang, src)
+	if err != nil {
+		return nil, err
+	}
+	return &file{
+		symbols: symbols.Flatten(syms),
+		names:   identifiers(src),
+	}, nil
+}
+
+// identifiers returns the words of the source that can be names.
+func identifiers(src []byte) map[string]bool {
+	names := make(map[string]bool)
+	start := -1
+	for i := 0; i <= len(src); i++ {
+		if i < len(src) && isNameByte(src[i]) {
+			if start < 0 {
+				if src[i] >= '0' && src[i] <= '9' {
+					// Skip numbers, like 0x1f.
+					for i < len(src) && isNameByte(src[i]) {
+						i++
+					}
+					continue
+				}
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= minNameLength {
+			names[string(src[start:i])] = true
+		}
+		start = -1
+	}
+	return names
+}
+
+func isNameByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// graph is the references between the files: a file references another
+// when it uses a name declared in it, like an imported function or type.
+type graph struct {
+	// edges are the weights of the references by referencing file index
+	// and referenced file index.
+	edges []map[int]float64
+	// references are the number of other files using each declared name.
+	references map[string]int
+}
+
+func newGraph(files []*file) graph {
+	declared := make(map[string][]int)
+	for i, f := range files {
+		for _, s := range f.symbols {
+			if len(s.Name) < minNameLength || s.Kind == symbols.KindVariable {
+				continue
+			}
+			if ids := declared[s.Name]; len(ids) == 0 || ids[len(ids)-1] != i {
+				declared[s.Name] = append(ids, i)
+			}
+		}
+	}
+	g := graph{
+		edges:      make([]map[int]float64, len(files)),
+		references: make(map[string]int),
+	}
+	for i, f := range files {
+		g.edges[i] = make(map[int]float64)
+		for name := range f.names {
+			ids := declared[name]
+			if len(ids) == 0 || len(ids) > maxDeclarations || slices.Contains(ids, i) {
+				// Names declared in the file itself are its own.
+				continue
+			}
+			g.references[name]++
+			w := nameWeight(name, len(ids))
+			for _, j := range ids {
+				g.edges[i][j] += w / float64(len(ids))
+			}
+		}
+	}
+	return g
+}
+
+// nameWeight is how telling a reference to the name declared in n files is.
+func nameWeight(name string, n int) float64 {
+	w := 1.0
+	if len(name) >= specificNameLength && isCompound(name) {
+		w *= 10
+	}
+	if strings.HasPrefix(name, "_") {
+		w *= 0.1
+	}
+	if n > commonDeclarations {
+		w *= 0.1
+	}
+	return w
+}
+
+// isCompound reports whether the name is made of several words, in camel or
+// snake case.
+func isCompound(name string) bool {
+	for i := 1; i < len(name); i++ {
+		if name[i] == '_' || name[i] >= 'A' && name[i] <= 'Z' && name[i-1] >= 'a' && name[i-1] <= 'z' {
+			return true
+		}
+	}
+	return false
+}
+
+// pageRank ranks the files by PageRank over the references, the files
+// referenced by important files being important.
+func (g graph) pageRank() []float64 {
+	n := len(g.edges)
+	if n == 0 {
+		return nil
+	}
+	out := make([]float64, n)
+	for i, edges := range g.edges {
+		for _, w := range edges {
+			out[i] += w
+		}
+	}
+	ranks := make([]float64, n)
+	for i := range ranks {
+		ranks[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for range iterations {
+		// The rank of the files referencing nothing is spread over all.
+		dangling := 0.0
+		for i, r := range ranks {
+			if out[i] == 0 {
+				dangling += r
+			}
+		}
+		for j := range next {
+			next[j] = (1-damping)/float64(n) + damping*dangling/float64(n)
+		}
+		for i, edges := range g.edges {
+			for j, w := range edges {
+				next[j] += damping * ranks[i] * w / out[i]
+			}
+		}
+		ranks, next = next, ranks
+	}
+	return ranks
+}
+
+// render lists the files, the highest ranked first, with their most
+// referenced declarations until maxSize bytes.
+func render(files []*file, maxSize int) string {
+	// Ties are broken by path for the map not to change between builds.
+	slices.SortFunc(files, func(a, b *file) int {
+		return strings.Compare(a.path, b.path)
+	})
+	g := newGraph(files)
+	ranks := g.pageRank()
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		// The ranks are rounded for the order not to depend on the order
+		// the floats were summed in.
+		return cmp.Compare(math.Round(ranks[b]*1e9), math.Round(ranks[a]*1e9))
+	})
+
+	var sb strings.Builder
+	for _, i := range order {
+		entry := renderFile(files[i], g.references)
+		if sb.Len()+len(entry) > maxSize {
+			break
+		}
+		sb.WriteString(entry)
+	}
+	return sb.String()
+}
+
+func renderFile(f *file, references map[string]int) string {
+	syms := slices.DeleteFunc(slices.Clone(f.symbols), func(s symbols.Symbol) bool {
+		return s.Kind == symbols.KindVariable
+	})
+	slices.SortStableFunc(syms, func(a, b symbols.Symbol) int {
+		return cmp.Compare(references[b.Name], references[a.Name])
+	})
+	syms = syms[:min(len(syms), maxSymbolsPerFile)]
+	slices.SortFunc(syms, func(a, b symbols.Symbol) int {
+		return cmp.Compare(a.Line, b.Line)
+	})
+
+	var sb strings.Builder
+	sb.WriteString(f.path + ":\n")
+	for _, s := range syms {
+		line := s.Signature
+		if line == "" {
+			line = fmt.Sprintf("%s %s", s.Kind, s.Name)
+		}
+		sb.WriteString("  " + line + "\n")
+	}
+	return sb.String()
+}
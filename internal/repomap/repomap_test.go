@@ -0,0 +1,118 @@
+package repomap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestMap(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, dir, "fsutil/list.go", `package fsutil
+
+func ListDirectory(path string) []string {
+	return nil
+}
+`)
+	writeFile(t, dir, "cmd/ls.go", `package cmd
+
+func runList() {
+	fsutil.ListDirectory(".")
+}
+`)
+	writeFile(t, dir, "cmd/tree.go", `package cmd
+
+func printTree() {
+	fsutil.ListDirectory(".")
+	runList()
+}
+`)
+	writeFile(t, dir, "README.md", "# Project\n")
+
+	m := New(dir, 0)
+	text := m.String()
+	require.True(t, strings.HasPrefix(text, "fsutil/list.go:\n  func ListDirectory(path string) []string\n"), text)
+	require.Contains(t, text, "cmd/ls.go:\n  func runList()\n")
+	require.Contains(t, text, "cmd/tree.go:\n  func printTree()\n")
+	require.NotContains(t, text, "README.md")
+	// The referenced files come before the ones referencing them.
+	require.Less(t, strings.Index(text, "cmd/ls.go"), strings.Index(text, "cmd/tree.go"))
+
+	t.Run("budget", func(t *testing.T) {
+		t.Parallel()
+		small := New(dir, 15)
+		require.Equal(t, "fsutil/list.go:\n  func ListDirectory(path string) []string\n", small.String())
+	})
+}
+
+func TestMapChanges(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, dir, "list.go", `package fsutil
+
+func ListDirectory(path string) []string {
+	return nil
+}
+`)
+	m := New(dir, 0)
+	require.Contains(t, m.String(), "ListDirectory")
+
+	writeFile(t, dir, "walk.go", `package fsutil
+
+func WalkDirectory(path string) {
+}
+`)
+	// The files are only checked again after a while.
+	require.NotContains(t, m.String(), "WalkDirectory")
+	m.checked = time.Time{}
+	require.Contains(t, m.String(), "walk.go:\n  func WalkDirectory(path string)\n")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "walk.go")))
+	m.checked = time.Time{}
+	require.NotContains(t, m.String(), "WalkDirectory")
+}
+
+func TestIdentifiers(t *testing.T) {
+	t.Parallel()
+
+	names := identifiers([]byte("x := 0x1fab + list_files(Config.Path, 42abc)"))
+	require.Equal(t, map[string]bool{
+		"list_files": true,
+		"Config":     true,
+		"Path":       true,
+	}, names)
+}
+
+func TestPageRank(t *testing.T) {
+	t.Parallel()
+
+	// 0 and 1 reference 2, which references nothing.
+	g := graph{edges: []map[int]float64{
+		{2: 1},
+		{2: 1},
+		{},
+	}}
+	ranks := g.pageRank()
+	require.Len(t, ranks, 3)
+	require.InDelta(t, ranks[0], ranks[1], 1e-9)
+	require.Greater(t, ranks[2], ranks[0])
+	sum := 0.0
+	for _, r := range ranks {
+		sum += r
+	}
+	require.InDelta(t, 1, sum, 1e-9)
+}
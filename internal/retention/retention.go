@@ -0,0 +1,238 @@
+// Package retention bounds the history kept in the data directory: the oldest
+// sessions are deleted with their artifacts and trashed files once they are
+// past the age, count or database size limits.
+package retention
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/trash"
+)
+
+// Policy holds the limits of the history kept. A zero limit is disabled.
+type Policy struct {
+	// MaxAge is how long a session is kept after its last update.
+	MaxAge time.Duration
+	// MaxSessions is the number of sessions kept, the most recently updated
+	// ones.
+	MaxSessions int
+	// MaxDBSize is the size in bytes of the data the database holds past
+	// which the oldest sessions are deleted.
+	MaxDBSize int64
+}
+
+// IsZero reports whether the policy has no limit.
+func (p Policy) IsZero() bool {
+	return p.MaxAge <= 0 && p.MaxSessions <= 0 && p.MaxDBSize <= 0
+}
+
+// Report describes what a pruning or a collection did.
+type Report struct {
+	// Sessions is the number of sessions deleted, sub-agent sessions
+	// excluded.
+	Sessions int
+	// SizeBefore and SizeAfter are the sizes in bytes of the database
+	// files.
+	SizeBefore int64
+	SizeAfter  int64
+}
+
+// Prune deletes the sessions past the limits of the policy, the oldest first,
+// with their artifacts and trashed files. It also deletes the sub-agent
+// sessions and the artifacts left behind by sessions deleted earlier.
+//
+// The space freed in the database is reused by new data but the file only
+// shrinks with GC.
+func Prune(ctx context.Context, conn *sql.DB, dataDir string, policy Policy) (Report, error) {
+	var report Report
+	size, err := fileSize(dataDir)
+	if err != nil {
+		return report, err
+	}
+	report.SizeBefore = size
+
+	q := db.New(conn)
+	artifacts := artifact.NewService(dataDir)
+	sessions := session.NewService(q, artifacts)
+	trashed := trash.NewService(dataDir)
+
+	all, err := sessions.List(ctx)
+	if err != nil {
+		return report, err
+	}
+	// Oldest first.
+	slices.SortFunc(all, func(a, b session.Session) int {
+		return cmp.Or(
+			cmp.Compare(a.UpdatedAt, b.UpdatedAt),
+			cmp.Compare(a.CreatedAt, b.CreatedAt),
+		)
+	})
+
+	remove := func(s session.Session) error {
+		if err := sessions.Delete(ctx, s.ID); err != nil {
+			return fmt.Errorf("failed to delete session %s: %w", s.ID, err)
+		}
+		report.Sessions++
+		return nil
+	}
+
+	cutoff := time.Now().Add(-policy.MaxAge).Unix()
+	for len(all) > 0 {
+		s := all[0]
+		expired := policy.MaxAge > 0 && s.UpdatedAt < cutoff
+		tooMany := policy.MaxSessions > 0 && len(all) > policy.MaxSessions
+		if !expired && !tooMany {
+			break
+		}
+		if err := remove(s); err != nil {
+			return report, err
+		}
+		all = all[1:]
+	}
+
+	if policy.MaxDBSize > 0 {
+		for len(all) > 0 {
+			used, err := usedSize(ctx, conn)
+			if err != nil {
+				return report, err
+			}
+			if used <= policy.MaxDBSize {
+				break
+			}
+			if err := remove(all[0]); err != nil {
+				return report, err
+			}
+			all = all[1:]
+		}
+	}
+
+	// Deleting a session leaves its sub-agent sessions, which can have
+	// sub-agent sessions of their own.
+	for {
+		n, err := q.DeleteOrphanSessions(ctx)
+		if err != nil {
+			return report, fmt.Errorf("failed to delete sub-agent sessions: %w", err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	if err := removeOrphanFiles(ctx, q, artifacts, trashed); err != nil {
+		return report, err
+	}
+
+	size, err = fileSize(dataDir)
+	if err != nil {
+		return report, err
+	}
+	report.SizeAfter = size
+	return report, nil
+}
+
+// GC prunes the sessions past the limits of the policy, then compacts the
+// database and truncates its write-ahead log.
+func GC(ctx context.Context, conn *sql.DB, dataDir string, policy Policy) (Report, error) {
+	report, err := Prune(ctx, conn, dataDir, policy)
+	if err != nil {
+		return report, err
+	}
+	if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+		return report, fmt.Errorf("failed to compact the database: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return report, fmt.Errorf("failed to truncate the write-ahead log: %w", err)
+	}
+	size, err := fileSize(dataDir)
+	if err != nil {
+		return report, err
+	}
+	report.SizeAfter = size
+	return report, nil
+}
+
+func removeOrphanFiles(ctx context.Context, q db.Querier, artifacts artifact.Service, trashed trash.Service) error {
+	exists := func(id string) (bool, error) {
+		_, err := q.GetSessionByID(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	ids, err := artifacts.Sessions()
+	if err != nil {
+		return fmt.Errorf("failed to list the artifacts: %w", err)
+	}
+	for _, id := range ids {
+		ok, err := exists(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := artifacts.DeleteSessionArtifacts(id); err != nil {
+				return fmt.Errorf("failed to delete the artifacts of session %s: %w", id, err)
+			}
+		}
+	}
+
+	ids, err = trashed.Sessions()
+	if err != nil {
+		return fmt.Errorf("failed to list the trash: %w", err)
+	}
+	for _, id := range ids {
+		ok, err := exists(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := trashed.DeleteSessionTrash(id); err != nil {
+				return fmt.Errorf("failed to delete the trash of session %s: %w", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// usedSize returns the size of the pages of the database holding data.
+func usedSize(ctx context.Context, conn *sql.DB) (int64, error) {
+	var pages, free, pageSize int64
+	for pragma, v := range map[string]*int64{
+		"page_count":     &pages,
+		"freelist_count": &free,
+		"page_size":      &pageSize,
+	} {
+		if err := conn.QueryRowContext(ctx, "PRAGMA "+pragma).Scan(v); err != nil {
+			return 0, fmt.Errorf("failed to read the database size: %w", err)
+		}
+	}
+	return (pages - free) * pageSize, nil
+}
+
+// fileSize returns the size of the database files.
+func fileSize(dataDir string) (int64, error) {
+	var size int64
+	for _, name := range []string{"crush.db", "crush.db-wal"} {
+		info, err := os.Stat(filepath.Join(dataDir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
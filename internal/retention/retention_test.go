@@ -0,0 +1,58 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	conn, err := db.Connect(t.Context(), dataDir)
+	require.NoError(t, err)
+	defer conn.Close()
+	sessions := session.NewService(db.New(conn), nil)
+	artifacts := artifact.NewService(dataDir)
+
+	// Let the test set when the sessions were last updated.
+	_, err = conn.ExecContext(t.Context(), "DROP TRIGGER update_sessions_updated_at")
+	require.NoError(t, err)
+
+	var ids []string
+	for i, title := range []string{"old", "older", "recent", "newest"} {
+		s, err := sessions.Create(t.Context(), title)
+		require.NoError(t, err)
+		ids = append(ids, s.ID)
+		_, err = artifacts.Create(s.ID, artifact.CreateParams{Name: "note.txt", MimeType: "text/plain", Data: []byte(title)})
+		require.NoError(t, err)
+		_, err = conn.ExecContext(t.Context(), "UPDATE sessions SET updated_at = ? WHERE id = ?", time.Now().Add(-time.Duration(10-i)*24*time.Hour).Unix(), s.ID)
+		require.NoError(t, err)
+	}
+	child, err := sessions.CreateTaskSession(t.Context(), "call_1", ids[0], "sub-agent")
+	require.NoError(t, err)
+
+	report, err := Prune(t.Context(), conn, dataDir, Policy{MaxAge: 9*24*time.Hour + time.Hour, MaxSessions: 2})
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Sessions)
+
+	left, err := sessions.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, left, 2)
+	_, err = sessions.Get(t.Context(), child.ID)
+	require.Error(t, err)
+
+	withArtifacts, err := artifacts.Sessions()
+	require.NoError(t, err)
+	require.ElementsMatch(t, ids[2:], withArtifacts)
+
+	report, err = GC(t.Context(), conn, dataDir, Policy{})
+	require.NoError(t, err)
+	require.Zero(t, report.Sessions)
+	require.Positive(t, report.SizeAfter)
+}
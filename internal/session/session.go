@@ -3,7 +3,9 @@ package session
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
+	"github.com/charmbracelet/crush/internal/artifact"
 	"github.com/charmbracelet/crush/internal/db"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/google/uuid"
@@ -27,6 +29,10 @@ type Service interface {
 	Create(ctx context.Context, title string) (Session, error)
 	CreateTitleSession(ctx context.Context, parentSessionID string) (Session, error)
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
+	// CreateBranchSession creates the session archiving the messages a
+	// session was rewound past. It is a child of the session, so it isn't
+	// listed with the other sessions.
+	CreateBranchSession(ctx context.Context, parentSessionID, title string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
 	List(ctx context.Context) ([]Session, error)
 	Save(ctx context.Context, session Session) (Session, error)
@@ -36,6 +42,9 @@ type Service interface {
 type service struct {
 	*pubsub.Broker[Session]
 	q db.Querier
+	// artifacts, when set, has the artifacts of the sessions deleted with
+	// them.
+	artifacts artifact.Service
 }
 
 func (s *service) Create(ctx context.Context, title string) (Session, error) {
@@ -79,6 +88,20 @@ func (s *service) CreateTitleSession(ctx context.Context, parentSessionID string
 	return session, nil
 }
 
+func (s *service) CreateBranchSession(ctx context.Context, parentSessionID, title string) (Session, error) {
+	dbSession, err := s.q.CreateSession(ctx, db.CreateSessionParams{
+		ID:              "branch-" + uuid.New().String(),
+		ParentSessionID: sql.NullString{String: parentSessionID, Valid: true},
+		Title:           title,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.CreatedEvent, session)
+	return session, nil
+}
+
 func (s *service) Delete(ctx context.Context, id string) error {
 	session, err := s.Get(ctx, id)
 	if err != nil {
@@ -89,6 +112,11 @@ func (s *service) Delete(ctx context.Context, id string) error {
 		return err
 	}
 	s.Publish(pubsub.DeletedEvent, session)
+	if s.artifacts != nil {
+		if err := s.artifacts.DeleteSessionArtifacts(session.ID); err != nil {
+			return fmt.Errorf("failed to delete the artifacts of session %s: %w", session.ID, err)
+		}
+	}
 	return nil
 }
 
@@ -147,10 +175,14 @@ func (s service) fromDBItem(item db.Session) Session {
 	}
 }
 
-func NewService(q db.Querier) Service {
+// NewService returns the sessions stored with the queries. The artifacts,
+// nil for the commands only reading the sessions, are deleted with their
+// session.
+func NewService(q db.Querier, artifacts artifact.Service) Service {
 	broker := pubsub.NewBroker[Session]()
 	return &service{
 		broker,
 		q,
+		artifacts,
 	}
 }
@@ -0,0 +1,37 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteRemovesArtifacts(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	conn, err := db.Connect(t.Context(), dataDir)
+	require.NoError(t, err)
+	defer conn.Close()
+	artifacts := artifact.NewService(dataDir)
+	sessions := NewService(db.New(conn), artifacts)
+
+	var ids []string
+	for _, title := range []string{"deleted", "kept"} {
+		s, err := sessions.Create(t.Context(), title)
+		require.NoError(t, err)
+		ids = append(ids, s.ID)
+		_, err = artifacts.Create(s.ID, artifact.CreateParams{Name: "note.txt", MimeType: "text/plain", Data: []byte(title)})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, sessions.Delete(t.Context(), ids[0]))
+	withArtifacts, err := artifacts.Sessions()
+	require.NoError(t, err)
+	require.Equal(t, ids[1:], withArtifacts)
+	left, err := artifacts.List(ids[0])
+	require.NoError(t, err)
+	require.Empty(t, left)
+}
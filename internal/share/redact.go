@@ -0,0 +1,158 @@
+package share
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces the secrets removed from shared content.
+const Redacted = "[REDACTED]"
+
+// Finding is a likely secret found in content to share.
+type Finding struct {
+	Kind string
+	// Line is the 1-based line the secret starts on.
+	Line int
+	// Start and End are the byte offsets of the secret in the content.
+	Start, End int
+}
+
+type secretRule struct {
+	kind    string
+	pattern *regexp.Regexp
+	// group is the submatch of the pattern holding the secret, 0 for the
+	// whole match.
+	group int
+}
+
+var secretRules = []secretRule{
+	{kind: "private key", pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{kind: "API key", pattern: regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_\-]{20,}`)},
+	{kind: "GitHub token", pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{kind: "AWS access key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{kind: "Slack token", pattern: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9\-]{10,}`)},
+	{kind: "Google API key", pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`)},
+	{kind: "bearer token", pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/\-]{20,}=*)`), group: 1},
+	{kind: "credential", pattern: regexp.MustCompile(`(?i)\b[A-Z0-9_]*(?:API_?KEY|SECRET|TOKEN|PASSWORD|PASSWD)[A-Z0-9_]*["']?\s*[:=]\s*["']?([^\s"',;]{8,})`), group: 1},
+	{kind: "URL credentials", pattern: regexp.MustCompile(`[a-z][a-z0-9+.\-]*://[^\s:/@]+:([^\s@/]+)@`), group: 1},
+}
+
+// Scan finds the likely secrets of the content, ordered by position. Secrets
+// matched by several rules are reported once.
+func Scan(content string) []Finding {
+	var findings []Finding
+	for _, rule := range secretRules {
+		for _, m := range rule.pattern.FindAllStringSubmatchIndex(content, -1) {
+			start, end := m[2*rule.group], m[2*rule.group+1]
+			if start < 0 || Redacted == content[start:end] {
+				continue
+			}
+			findings = append(findings, Finding{Kind: rule.kind, Start: start, End: end})
+		}
+	}
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		if a.Start != b.Start {
+			return a.Start - b.Start
+		}
+		return b.End - a.End
+	})
+
+	var result []Finding
+	for _, f := range findings {
+		if len(result) > 0 && f.Start < result[len(result)-1].End {
+			continue
+		}
+		f.Line = strings.Count(content[:f.Start], "\n") + 1
+		result = append(result, f)
+	}
+	return result
+}
+
+// Redact replaces the findings in the content.
+func Redact(content string, findings []Finding) string {
+	var sb strings.Builder
+	last := 0
+	for _, f := range findings {
+		sb.WriteString(content[last:f.Start])
+		sb.WriteString(Redacted)
+		last = f.End
+	}
+	sb.WriteString(content[last:])
+	return sb.String()
+}
+
+// ReplaceHome replaces the home directory in the paths of the content with
+// ~, as it usually contains the name of the user.
+func ReplaceHome(content, home string) string {
+	home = strings.TrimRight(home, `/\`)
+	if home == "" {
+		return content
+	}
+	return strings.ReplaceAll(content, home, "~")
+}
+
+// Mask shows only the ends of a secret, enough to recognize it.
+func Mask(secret string) string {
+	secret = strings.SplitN(secret, "\n", 2)[0]
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", min(len(secret)-8, 16)) + secret[len(secret)-4:]
+}
+
+// Prompter asks the questions of the redaction review.
+type Prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewScanner(in), out: out}
+}
+
+// Review asks about each finding whether to redact it and returns the
+// findings to redact. Answering "a" redacts the remaining findings and "q"
+// aborts the review with an error.
+func (p *Prompter) Review(content string, findings []Finding) ([]Finding, error) {
+	var redact []Finding
+	for i, f := range findings {
+		fmt.Fprintf(p.out, "[%d/%d] line %d, %s: %s\n", i+1, len(findings), f.Line, f.Kind, Mask(content[f.Start:f.End]))
+		answer, ok := p.ask("Redact? [Y/n/a/q]")
+		if !ok {
+			// Redact everything left when the input ends.
+			answer = "a"
+		}
+		switch answer {
+		case "", "y", "yes":
+			redact = append(redact, f)
+		case "n", "no":
+		case "a", "all":
+			return append(redact, findings[i:]...), nil
+		case "q", "quit":
+			return nil, fmt.Errorf("share aborted")
+		default:
+			fmt.Fprintf(p.out, "Unknown answer %q, redacting.\n", answer)
+			redact = append(redact, f)
+		}
+	}
+	return redact, nil
+}
+
+// Confirm asks a yes or no question, no being the default.
+func (p *Prompter) Confirm(question string) bool {
+	answer, _ := p.ask(question + " [y/N]")
+	return answer == "y" || answer == "yes"
+}
+
+func (p *Prompter) ask(question string) (string, bool) {
+	fmt.Fprint(p.out, question+" ")
+	if !p.in.Scan() {
+		fmt.Fprintln(p.out)
+		return "", false
+	}
+	return strings.ToLower(strings.TrimSpace(p.in.Text())), true
+}
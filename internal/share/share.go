@@ -0,0 +1,125 @@
+// Package share uploads sanitized session transcripts and definitions as
+// GitHub gists or to a paste service.
+package share
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// Document is the content to share.
+type Document struct {
+	// Filename names the file of the gist, its extension decides how it is
+	// rendered.
+	Filename    string
+	Description string
+	Content     string
+}
+
+// Uploader uploads a document and returns the link to it.
+type Uploader interface {
+	Upload(ctx context.Context, doc Document) (string, error)
+}
+
+// NewUploader returns the uploader of the options: the paste service when
+// one is configured, secret gists otherwise.
+func NewUploader(opts *config.ShareOptions) (Uploader, error) {
+	if opts == nil {
+		return Gist{}, nil
+	}
+	if opts.PasteURL == "" {
+		return Gist{Public: opts.Public}, nil
+	}
+	headers, err := opts.ResolvedHeaders()
+	if err != nil {
+		return nil, err
+	}
+	return Paste{URL: opts.PasteURL, Headers: headers}, nil
+}
+
+// Gist uploads documents as gists with the GitHub CLI, which handles the
+// authentication.
+type Gist struct {
+	Public bool
+}
+
+func (g Gist) Upload(ctx context.Context, doc Document) (string, error) {
+	args := []string{"gist", "create", "--filename", doc.Filename, "--desc", doc.Description}
+	if g.Public {
+		args = append(args, "--public")
+	}
+	args = append(args, "-")
+
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Stdin = strings.NewReader(doc.Content)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("gh gist create failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	// gh prints its progress before the URL of the gist.
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
+// Paste posts documents to a paste service. The service answers with the
+// link, either as plain text or as the url field of a JSON object.
+type Paste struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+func (p Paste) Upload(ctx context.Context, doc Document) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, strings.NewReader(doc.Content))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/markdown; charset=utf-8")
+	req.Header.Set("X-Filename", doc.Filename)
+	for k, v := range p.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to the paste service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the paste service response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("paste service returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		URL  string `json:"url"`
+		Link string `json:"link"`
+	}
+	if json.Unmarshal(body, &result) == nil && (result.URL != "" || result.Link != "") {
+		return cmp.Or(result.URL, result.Link), nil
+	}
+	if link := string(bytes.TrimSpace(body)); strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
+		return link, nil
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		return location, nil
+	}
+	return "", fmt.Errorf("paste service did not return a link")
+}
@@ -0,0 +1,104 @@
+package share
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// maxToolResultLength limits the tool results in transcripts, they are
+// mostly file contents and command output the reader doesn't need in full.
+const maxToolResultLength = 2000
+
+// TranscriptOptions change what a transcript includes.
+type TranscriptOptions struct {
+	// Thinking includes the reasoning of the answers, in folded blocks.
+	Thinking bool
+}
+
+// Transcript formats a session as markdown: the prompts, the answers and the
+// tool calls with their results. Attachments are left out, and so is the
+// reasoning unless the options include it.
+func Transcript(sess session.Session, messages []message.Message, opts TranscriptOptions) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", cmp.Or(sess.Title, "Crush session"))
+	fmt.Fprintf(&sb, "- Created: %s\n", time.Unix(sess.CreatedAt, 0).UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "- Messages: %d\n", len(messages))
+	fmt.Fprintf(&sb, "- Cost: $%.4f\n", sess.Cost)
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case message.User:
+			sb.WriteString("\n## User\n\n")
+			sb.WriteString(strings.TrimSpace(msg.Content().Text))
+			sb.WriteString("\n")
+			for _, b := range msg.BinaryContent() {
+				fmt.Fprintf(&sb, "\n_Attachment: %s_\n", b.MIMEType)
+			}
+		case message.Assistant:
+			if msg.Model != "" {
+				fmt.Fprintf(&sb, "\n## Assistant (%s)\n", msg.Model)
+			} else {
+				sb.WriteString("\n## Assistant\n")
+			}
+			if thinking := strings.TrimSpace(msg.ReasoningContent().Thinking); opts.Thinking && thinking != "" {
+				fmt.Fprintf(&sb, "\n<details>\n<summary>Thinking</summary>\n\n%s\n\n</details>\n", thinking)
+			}
+			if text := strings.TrimSpace(msg.Content().Text); text != "" {
+				sb.WriteString("\n" + text + "\n")
+			}
+			for _, call := range msg.ToolCalls() {
+				fmt.Fprintf(&sb, "\n**Tool call** `%s`\n\n", call.Name)
+				writeFence(&sb, "json", indentJSON(call.Input))
+			}
+		case message.Tool:
+			for _, result := range msg.ToolResults() {
+				label := "Result"
+				if result.IsError {
+					label = "Error"
+				}
+				fmt.Fprintf(&sb, "\n**%s** `%s`\n\n", label, result.Name)
+				writeFence(&sb, "", truncate(result.Content, maxToolResultLength))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// writeFence writes a code block with a fence longer than any backtick run
+// of the content.
+func writeFence(sb *strings.Builder, lang, content string) {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(sb, "%s%s\n%s\n%s\n", fence, lang, strings.TrimRight(content, "\n"), fence)
+}
+
+func indentJSON(input string) string {
+	var v any
+	if err := json.Unmarshal([]byte(input), &v); err != nil {
+		return input
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return input
+	}
+	return string(out)
+}
+
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit] + fmt.Sprintf("\n... (%d more bytes)", len(s)-limit)
+}
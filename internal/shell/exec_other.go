@@ -0,0 +1,13 @@
+//go:build !windows
+
+package shell
+
+import "mvdan.cc/sh/v3/interp"
+
+// windowsHandler leaves the commands to the next handlers, the scripts and
+// builtins of Windows only needing it there.
+func windowsHandler() func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return next
+	}
+}
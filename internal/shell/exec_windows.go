@@ -0,0 +1,79 @@
+//go:build windows
+
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// windowsHandler runs the batch files and the builtins of cmd.exe through
+// cmd.exe, and the PowerShell scripts through PowerShell, which Windows can't
+// start directly.
+func windowsHandler() func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			hc := interp.HandlerCtx(ctx)
+			resolved := args
+			path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+			if err == nil {
+				resolved = append([]string{path}, args[1:]...)
+			}
+			command, cmdLine, ok := windowsCommand(resolved, err == nil, powershell())
+			if !ok {
+				return next(ctx, args)
+			}
+			program, err := exec.LookPath(command[0])
+			if err != nil {
+				fmt.Fprintln(hc.Stderr, err)
+				return interp.ExitStatus(127)
+			}
+			cmd := exec.Cmd{
+				Path:   program,
+				Args:   command,
+				Env:    environ(hc.Env),
+				Dir:    hc.Dir,
+				Stdin:  hc.Stdin,
+				Stdout: hc.Stdout,
+				Stderr: hc.Stderr,
+			}
+			if cmdLine != "" {
+				// Go would quote the command line the way programs parse it,
+				// which isn't the way cmd.exe does.
+				cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: cmdLine}
+			}
+			if err := cmd.Start(); err != nil {
+				fmt.Fprintln(hc.Stderr, err)
+				return interp.ExitStatus(127)
+			}
+			stop := context.AfterFunc(ctx, func() {
+				_ = cmd.Process.Kill()
+			})
+			defer stop()
+
+			err = cmd.Wait()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return interp.ExitStatus(exitErr.ExitCode())
+			}
+			return err
+		}
+	}
+}
+
+// powershell returns PowerShell 7 when it's installed, Windows PowerShell
+// otherwise.
+func powershell() string {
+	if _, err := exec.LookPath("pwsh"); err == nil {
+		return "pwsh"
+	}
+	return "powershell"
+}
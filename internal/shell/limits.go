@@ -0,0 +1,46 @@
+package shell
+
+import (
+	"context"
+	"errors"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// ErrCPULimitExceeded is returned when a command is killed for exceeding its
+// CPU time limit.
+var ErrCPULimitExceeded = errors.New("command exceeded its CPU time limit")
+
+// Limits bounds the resources of each command run by the shell. They are
+// only enforced on Linux, zero values are unlimited.
+type Limits struct {
+	// MemoryBytes limits the address space of a command.
+	MemoryBytes uint64
+	// CPUSeconds limits the CPU time of a command.
+	CPUSeconds uint64
+}
+
+type limitsContextKey struct{}
+
+// WithLimits returns a context bounding the commands run with it.
+func WithLimits(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, limitsContextKey{}, limits)
+}
+
+// LimitsFromContext returns the limits set with WithLimits.
+func LimitsFromContext(ctx context.Context) (Limits, bool) {
+	limits, ok := ctx.Value(limitsContextKey{}).(Limits)
+	return limits, ok
+}
+
+func limitsHandler() func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			limits, ok := LimitsFromContext(ctx)
+			if !ok || limits == (Limits{}) {
+				return next(ctx, args)
+			}
+			return execWithLimits(ctx, args, limits, next)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+//go:build linux
+
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// killTimeout is how long a command has to stop after being interrupted,
+// like with the default exec handler.
+const killTimeout = 2 * time.Second
+
+// execWithLimits runs the command like the default exec handler, applying
+// the limits to the process as soon as it starts.
+func execWithLimits(ctx context.Context, args []string, limits Limits, _ interp.ExecHandlerFunc) error {
+	hc := interp.HandlerCtx(ctx)
+	path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+	if err != nil {
+		fmt.Fprintln(hc.Stderr, err)
+		return interp.ExitStatus(127)
+	}
+	cmd := exec.Cmd{
+		Path:        path,
+		Args:        args,
+		Env:         environ(hc.Env),
+		Dir:         hc.Dir,
+		Stdin:       hc.Stdin,
+		Stdout:      hc.Stdout,
+		Stderr:      hc.Stderr,
+		SysProcAttr: &syscall.SysProcAttr{Setpgid: true},
+	}
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(hc.Stderr, "%v\n", err)
+		return interp.ExitStatus(127)
+	}
+	if err := setLimits(cmd.Process.Pid, limits); err != nil {
+		_ = unix.Kill(-cmd.Process.Pid, unix.SIGKILL)
+		_ = cmd.Wait()
+		return fmt.Errorf("could not limit command: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = unix.Kill(-cmd.Process.Pid, unix.SIGINT)
+		time.Sleep(killTimeout)
+		_ = unix.Kill(-cmd.Process.Pid, unix.SIGKILL)
+	})
+	defer stop()
+
+	err = cmd.Wait()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return err
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return interp.ExitStatus(exitErr.ExitCode())
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	// The soft limit sends SIGXCPU, the hard one a second later SIGKILL.
+	cpu := exitErr.UserTime() + exitErr.SystemTime()
+	if limits.CPUSeconds > 0 && (status.Signal() == syscall.SIGXCPU || cpu >= time.Duration(limits.CPUSeconds)*time.Second) {
+		return ErrCPULimitExceeded
+	}
+	return interp.ExitStatus(128 + status.Signal())
+}
+
+func setLimits(pid int, limits Limits) error {
+	if limits.MemoryBytes > 0 {
+		rlimit := unix.Rlimit{Cur: limits.MemoryBytes, Max: limits.MemoryBytes}
+		if err := unix.Prlimit(pid, unix.RLIMIT_AS, &rlimit, nil); err != nil {
+			return err
+		}
+	}
+	if limits.CPUSeconds > 0 {
+		rlimit := unix.Rlimit{Cur: limits.CPUSeconds, Max: limits.CPUSeconds + 1}
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, &rlimit, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+//go:build linux
+
+package shell
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimitsExec(t *testing.T) {
+	t.Parallel()
+
+	ctx := WithLimits(t.Context(), Limits{MemoryBytes: 1 << 30})
+	shell := NewShell(&Options{WorkingDir: t.TempDir()})
+	shell.SetEnv("GREETING", "hello")
+
+	stdout, _, err := shell.Exec(ctx, "export GREETING; sh -c 'echo $GREETING; exit 3'")
+	if status := ExitCode(err); status != 3 {
+		t.Fatalf("Expected exit status 3, got %d: %v", status, err)
+	}
+	if strings.TrimSpace(stdout) != "hello" {
+		t.Fatalf("Expected the exported variable in the output, got %q", stdout)
+	}
+}
+
+func TestLimitsCPU(t *testing.T) {
+	t.Parallel()
+
+	ctx := WithLimits(t.Context(), Limits{CPUSeconds: 1})
+	shell := NewShell(&Options{WorkingDir: t.TempDir()})
+	_, _, err := shell.Exec(ctx, "sh -c 'while :; do :; done'")
+	if !errors.Is(err, ErrCPULimitExceeded) {
+		t.Fatalf("Expected the CPU limit to be exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package shell
+
+import (
+	"context"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// execWithLimits runs the command without limits, they are only supported on
+// Linux.
+func execWithLimits(ctx context.Context, args []string, _ Limits, next interp.ExecHandlerFunc) error {
+	return next(ctx, args)
+}
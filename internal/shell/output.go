@@ -0,0 +1,21 @@
+package shell
+
+import (
+	"context"
+	"io"
+)
+
+type outputContextKey struct{}
+
+// WithOutput returns a context whose commands also write their standard
+// output and error to w while they run, e.g. to show them live. w must be
+// safe for concurrent use, as both are written to it.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputContextKey{}, w)
+}
+
+// OutputFromContext returns the writer set with WithOutput.
+func OutputFromContext(ctx context.Context) (io.Writer, bool) {
+	w, ok := ctx.Value(outputContextKey{}).(io.Writer)
+	return w, ok && w != nil
+}
@@ -0,0 +1,37 @@
+package shell
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func TestWithOutput(t *testing.T) {
+	t.Parallel()
+
+	var output syncBuffer
+	ctx := WithOutput(t.Context(), &output)
+	shell := NewShell(&Options{WorkingDir: t.TempDir()})
+
+	stdout, stderr, err := shell.Exec(ctx, "echo out && echo err >&2")
+	if err != nil {
+		t.Fatalf("Expected the command to succeed, got %v", err)
+	}
+	if stdout != "out\n" || stderr != "err\n" {
+		t.Fatalf("Expected the output to be returned too, got %q and %q", stdout, stderr)
+	}
+	if got := output.buf.String(); got != "out\nerr\n" {
+		t.Fatalf("Expected both outputs to be written, got %q", got)
+	}
+}
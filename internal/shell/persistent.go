@@ -29,6 +29,34 @@ func GetPersistentShell(cwd string) *PersistentShell {
 	return shellInstance
 }
 
+var (
+	sessionShellsMu sync.Mutex
+	sessionShells   = make(map[string]*Shell)
+)
+
+// GetSessionShell returns the persistent shell of the session, a copy of the
+// singleton persistent shell made on first use. The working directory and
+// variables of the commands of a session carry over to its next commands
+// without leaking into the other sessions.
+func GetSessionShell(sessionID, cwd string) *Shell {
+	sessionShellsMu.Lock()
+	defer sessionShellsMu.Unlock()
+	if sh, ok := sessionShells[sessionID]; ok {
+		return sh
+	}
+	sh := GetPersistentShell(cwd).clone()
+	sessionShells[sessionID] = sh
+	return sh
+}
+
+// ResetSessionShell drops the persistent shell of the session, its next
+// commands starting over from the state of the singleton persistent shell.
+func ResetSessionShell(sessionID string) {
+	sessionShellsMu.Lock()
+	defer sessionShellsMu.Unlock()
+	delete(sessionShells, sessionID)
+}
+
 // slog.dapter adapts the internal slog.package to the Logger interface
 type loggingAdapter struct{}
 
@@ -0,0 +1,31 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionShell(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	first := GetSessionShell("session-shell-1", dir)
+	require.Same(t, first, GetSessionShell("session-shell-1", dir))
+
+	_, _, err := first.Exec(t.Context(), "export SESSION_VAR=1")
+	require.NoError(t, err)
+	stdout, _, err := GetSessionShell("session-shell-1", dir).Exec(t.Context(), "echo $SESSION_VAR")
+	require.NoError(t, err)
+	require.Equal(t, "1\n", stdout)
+
+	// The other sessions don't see it.
+	stdout, _, err = GetSessionShell("session-shell-2", dir).Exec(t.Context(), "echo $SESSION_VAR")
+	require.NoError(t, err)
+	require.Equal(t, "\n", stdout)
+
+	ResetSessionShell("session-shell-1")
+	stdout, _, err = GetSessionShell("session-shell-1", dir).Exec(t.Context(), "echo $SESSION_VAR")
+	require.NoError(t, err)
+	require.Equal(t, "\n", stdout)
+}
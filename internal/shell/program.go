@@ -0,0 +1,212 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// programs are the shells which can run the commands instead of the
+// built-in interpreter.
+var programs = []string{"bash", "zsh", "fish", "pwsh", "powershell", "sh"}
+
+// programName returns the name of the shell, the program possibly being a
+// path to it with either separator.
+func programName(program string) string {
+	name := strings.ToLower(program[strings.LastIndexAny(program, `/\`)+1:])
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// SetProgram makes the shell run the commands with the program, one of bash,
+// zsh, fish, pwsh, powershell or sh or the path to one of them, for them to
+// behave like in the terminal of the user. The rc files of the user are
+// sourced when sourceRC is set. An empty program runs the commands with the
+// built-in interpreter.
+func (s *Shell) SetProgram(program string, sourceRC bool) error {
+	if program != "" && !slices.Contains(programs, programName(program)) {
+		return fmt.Errorf("unsupported shell %q, expected one of %s", program, strings.Join(programs, ", "))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.program = program
+	s.sourceRC = sourceRC
+	return nil
+}
+
+// Program returns the shell running the commands, empty for the built-in
+// interpreter.
+func (s *Shell) Program() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.program
+}
+
+// execProgram runs the command with the program of the shell. The program
+// is started by the interpreter, for the handlers like the limits to apply
+// to it, and writes its working directory and environment to files when it
+// exits for the next commands to start with them, like after activating a
+// virtualenv. Its functions, aliases and unexported variables are lost.
+func (s *Shell) execProgram(ctx context.Context, command string, stdin io.Reader) (string, string, error) {
+	if args := blockedCommand(command, s.blockFuncs); args != nil {
+		return "", "", fmt.Errorf("command is not allowed for security reasons: %s", strings.Join(args, " "))
+	}
+
+	stateDir, err := os.MkdirTemp("", "crush-shell-*")
+	if err != nil {
+		return "", "", fmt.Errorf("could not create the shell state directory: %w", err)
+	}
+	defer os.RemoveAll(stateDir)
+	cwdFile := filepath.Join(stateDir, "cwd")
+	envFile := filepath.Join(stateDir, "env")
+
+	var words []string
+	for _, assign := range [][2]string{{"CRUSH_CWD_FILE", cwdFile}, {"CRUSH_ENV_FILE", envFile}} {
+		quoted, err := syntax.Quote(assign[1], syntax.LangBash)
+		if err != nil {
+			return "", "", fmt.Errorf("could not quote the shell state file: %w", err)
+		}
+		words = append(words, assign[0]+"="+quoted)
+	}
+	for _, arg := range programArgs(s.program, s.sourceRC, command) {
+		quoted, err := syntax.Quote(arg, syntax.LangBash)
+		if err != nil {
+			return "", "", fmt.Errorf("could not quote the command: %w", err)
+		}
+		words = append(words, quoted)
+	}
+	stdout, stderr, err := s.execPOSIX(ctx, strings.Join(words, " "), stdin)
+
+	if content, readErr := os.ReadFile(cwdFile); readErr == nil {
+		dir := strings.TrimSpace(string(content))
+		if info, statErr := os.Stat(dir); dir != "" && statErr == nil && info.IsDir() {
+			s.cwd = dir
+		}
+	}
+	if content, readErr := os.ReadFile(envFile); readErr == nil {
+		if env := parseEnv(string(content)); len(env) > 0 {
+			s.env = env
+		}
+	}
+	return stdout, stderr, err
+}
+
+// programVars are the variables of the environment the shells set
+// themselves, or crush for a single command, which aren't kept.
+var programVars = []string{"_", "SHLVL", "PWD", "OLDPWD", "CRUSH_CWD_FILE", "CRUSH_ENV_FILE"}
+
+// parseEnv parses the output of env, separated by NUL characters or by
+// lines, in which case the lines without = continue the value of the
+// previous variable.
+func parseEnv(content string) []string {
+	sep := "\n"
+	if strings.Contains(content, "\x00") {
+		sep = "\x00"
+	}
+	var env []string
+	for entry := range strings.SplitSeq(content, sep) {
+		name, _, ok := strings.Cut(entry, "=")
+		switch {
+		case ok && name != "" && !strings.ContainsAny(name, " \t"):
+			env = append(env, entry)
+		case entry != "" && len(env) > 0 && sep == "\n":
+			env[len(env)-1] += "\n" + entry
+		}
+	}
+	return slices.DeleteFunc(env, func(kv string) bool {
+		name, _, _ := strings.Cut(kv, "=")
+		return slices.Contains(programVars, name)
+	})
+}
+
+// programArgs returns the arguments running the command with the program,
+// which writes its working directory to the file of $CRUSH_CWD_FILE and its
+// environment to the file of $CRUSH_ENV_FILE when it exits.
+func programArgs(program string, sourceRC bool, command string) []string {
+	switch name := programName(program); name {
+	case "fish":
+		args := []string{program}
+		if !sourceRC {
+			args = append(args, "--no-config")
+		}
+		script := "function __crush_state --on-event fish_exit\n    pwd > $CRUSH_CWD_FILE\n    env -0 > $CRUSH_ENV_FILE 2>/dev/null; or env > $CRUSH_ENV_FILE\nend\n" + command
+		return append(args, "-c", script)
+	case "pwsh", "powershell":
+		args := []string{program, "-NoLogo", "-NonInteractive"}
+		if !sourceRC {
+			args = append(args, "-NoProfile")
+		}
+		script := "try {\n" + command + "\n} finally {\n" +
+			"    (Get-Location).ProviderPath | Set-Content -NoNewline -Path $env:CRUSH_CWD_FILE\n" +
+			"    (Get-ChildItem env: | ForEach-Object { \"$($_.Name)=$($_.Value)\" }) -join \"`0\" | Set-Content -NoNewline -Path $env:CRUSH_ENV_FILE\n" +
+			"}\nif ($LASTEXITCODE) { exit $LASTEXITCODE }"
+		return append(args, "-Command", script)
+	default:
+		var rc string
+		if sourceRC {
+			switch name {
+			case "bash":
+				// The aliases of the rc file are only expanded when asked in
+				// non-interactive shells.
+				rc = "shopt -s expand_aliases\n[ -f ~/.bashrc ] && . ~/.bashrc\n"
+			case "zsh":
+				rc = `[ -f "${ZDOTDIR:-$HOME}/.zshrc" ] && . "${ZDOTDIR:-$HOME}/.zshrc"` + "\n"
+			case "sh":
+				rc = `[ -n "$ENV" ] && [ -f "$ENV" ] && . "$ENV"` + "\n"
+			}
+		}
+		script := rc + `trap 'pwd > "$CRUSH_CWD_FILE"; env -0 > "$CRUSH_ENV_FILE" 2>/dev/null || env > "$CRUSH_ENV_FILE"' EXIT` + "\n" + command
+		return []string{program, "-c", script}
+	}
+}
+
+// blockedCommand returns the first command of the script a block function
+// blocks. The script is parsed as Bash, and split at its operators when it
+// uses the syntax of another shell.
+func blockedCommand(command string, blockFuncs []BlockFunc) []string {
+	if len(blockFuncs) == 0 {
+		return nil
+	}
+	var commands [][]string
+	if file, err := syntax.NewParser().Parse(strings.NewReader(command), ""); err == nil {
+		syntax.Walk(file, func(node syntax.Node) bool {
+			call, ok := node.(*syntax.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			args := make([]string, len(call.Args))
+			for i, word := range call.Args {
+				arg, err := expand.Literal(nil, word)
+				if err != nil {
+					arg = word.Lit()
+				}
+				args[i] = arg
+			}
+			commands = append(commands, args)
+			return true
+		})
+	} else {
+		isOperator := func(r rune) bool {
+			return strings.ContainsRune("\n;|&(){}", r)
+		}
+		for _, part := range strings.FieldsFunc(command, isOperator) {
+			if args := strings.Fields(part); len(args) > 0 {
+				commands = append(commands, args)
+			}
+		}
+	}
+	for _, args := range commands {
+		for _, blockFunc := range blockFuncs {
+			if blockFunc(args) {
+				return args
+			}
+		}
+	}
+	return nil
+}
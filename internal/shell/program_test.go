@@ -0,0 +1,83 @@
+package shell
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetProgram(t *testing.T) {
+	t.Parallel()
+
+	shell := NewShell(nil)
+	require.NoError(t, shell.SetProgram("/usr/local/bin/zsh", true))
+	require.Equal(t, "/usr/local/bin/zsh", shell.Program())
+	require.EqualError(t, shell.SetProgram("tcsh", false), `unsupported shell "tcsh", expected one of bash, zsh, fish, pwsh, powershell, sh`)
+}
+
+func TestProgramArgs(t *testing.T) {
+	t.Parallel()
+
+	args := programArgs("bash", true, "ll")
+	require.Equal(t, []string{"bash", "-c"}, args[:2])
+	require.True(t, strings.HasPrefix(args[2], "shopt -s expand_aliases\n[ -f ~/.bashrc ] && . ~/.bashrc\n"))
+	require.True(t, strings.HasSuffix(args[2], "\nll"))
+
+	require.Equal(t, []string{"zsh", "-c"}, programArgs("zsh", false, "ls")[:2])
+	require.Equal(t, []string{"fish", "--no-config", "-c"}, programArgs("fish", false, "ls")[:3])
+	require.Equal(t, []string{"fish", "-c"}, programArgs("fish", true, "ls")[:2])
+	require.Equal(t, []string{`C:\pwsh\pwsh.exe`, "-NoLogo", "-NonInteractive", "-NoProfile", "-Command"}, programArgs(`C:\pwsh\pwsh.exe`, false, "ls")[:5])
+}
+
+func TestBlockedCommand(t *testing.T) {
+	t.Parallel()
+
+	blockFuncs := []BlockFunc{CommandsBlocker([]string{"curl"})}
+	require.Equal(t, []string{"curl", "example.com"}, blockedCommand(`echo $(curl example.com)`, blockFuncs))
+	require.Equal(t, []string{"curl", "-s"}, blockedCommand(`ls; "curl" -s`, blockFuncs))
+	// The syntax of fish isn't Bash.
+	require.Equal(t, []string{"curl", "example.com"}, blockedCommand("for f in *.go; echo $f; end; curl example.com", blockFuncs))
+	require.Nil(t, blockedCommand("echo curl", blockFuncs))
+}
+
+func TestExecProgram(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	dir := t.TempDir()
+	shell := NewShell(&Options{WorkingDir: dir})
+	require.NoError(t, shell.SetProgram("sh", false))
+	shell.SetBlockFuncs([]BlockFunc{CommandsBlocker([]string{"curl"})})
+
+	stdout, _, err := shell.Exec(t.Context(), "mkdir sub && cd sub && echo $0")
+	require.NoError(t, err)
+	require.Equal(t, "sh\n", stdout)
+	resolved, err := filepath.EvalSymlinks(filepath.Join(dir, "sub"))
+	require.NoError(t, err)
+	require.Equal(t, resolved, shell.GetWorkingDir())
+
+	_, _, err = shell.Exec(t.Context(), "exit 3")
+	require.Equal(t, 3, ExitCode(err))
+
+	// The exported variables carry over, like the ones of a virtualenv.
+	_, _, err = shell.Exec(t.Context(), "export VIRTUAL_ENV=/venv; unexported=1")
+	require.NoError(t, err)
+	stdout, _, err = shell.Exec(t.Context(), `echo "$VIRTUAL_ENV:$unexported"`)
+	require.NoError(t, err)
+	require.Equal(t, "/venv:\n", stdout)
+
+	_, _, err = shell.Exec(t.Context(), "curl example.com")
+	require.EqualError(t, err, "command is not allowed for security reasons: curl example.com")
+}
+
+func TestParseEnv(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"A=1", "B=two\nlines"}, parseEnv("A=1\x00B=two\nlines\x00SHLVL=2\x00"))
+	require.Equal(t, []string{"A=1", "B=two\nlines"}, parseEnv("A=1\nB=two\nlines\nPWD=/tmp\n"))
+}
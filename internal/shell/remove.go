@@ -0,0 +1,121 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// RemoveFunc takes the place of deleting a file removed with rm, e.g. to
+// move it to a trash.
+type RemoveFunc func(path string) error
+
+type removeContextKey struct{}
+
+// WithRemove returns a context whose rm commands hand the files they remove
+// to fn instead of deleting them.
+func WithRemove(ctx context.Context, fn RemoveFunc) context.Context {
+	return context.WithValue(ctx, removeContextKey{}, fn)
+}
+
+// RemoveFromContext returns the function set with WithRemove.
+func RemoveFromContext(ctx context.Context) (RemoveFunc, bool) {
+	fn, ok := ctx.Value(removeContextKey{}).(RemoveFunc)
+	return fn, ok && fn != nil
+}
+
+// removeHandler runs the rm commands of a context with a RemoveFunc. Only
+// the common flags are handled, other commands, and the ones that would
+// fail like removing a missing file, are left to rm.
+func removeHandler() func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			remove, ok := RemoveFromContext(ctx)
+			if !ok || len(args) == 0 || args[0] != "rm" {
+				return next(ctx, args)
+			}
+			opts, ok := parseRemoveArgs(args[1:])
+			if !ok {
+				return next(ctx, args)
+			}
+
+			hc := interp.HandlerCtx(ctx)
+			type target struct{ arg, path string }
+			targets := make([]target, 0, len(opts.paths))
+			for _, arg := range opts.paths {
+				path := arg
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(hc.Dir, path)
+				}
+				info, err := os.Lstat(path)
+				if os.IsNotExist(err) && opts.force {
+					continue
+				}
+				if err != nil || (info.IsDir() && !opts.recursive) {
+					return next(ctx, args)
+				}
+				targets = append(targets, target{arg: arg, path: path})
+			}
+
+			failed := false
+			for _, t := range targets {
+				if err := remove(t.path); err != nil {
+					fmt.Fprintf(hc.Stderr, "rm: cannot remove '%s': %v\n", t.arg, err)
+					failed = true
+					continue
+				}
+				if opts.verbose {
+					fmt.Fprintf(hc.Stdout, "removed '%s'\n", t.arg)
+				}
+			}
+			if failed {
+				return interp.NewExitStatus(1)
+			}
+			return nil
+		}
+	}
+}
+
+type removeOptions struct {
+	recursive bool
+	force     bool
+	verbose   bool
+	paths     []string
+}
+
+func parseRemoveArgs(args []string) (removeOptions, bool) {
+	var opts removeOptions
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			opts.paths = append(opts.paths, args[i+1:]...)
+			return opts, len(opts.paths) > 0
+		case arg == "--recursive":
+			opts.recursive = true
+		case arg == "--force":
+			opts.force = true
+		case arg == "--verbose":
+			opts.verbose = true
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for _, flag := range arg[1:] {
+				switch flag {
+				case 'r', 'R':
+					opts.recursive = true
+				case 'f':
+					opts.force = true
+				case 'v':
+					opts.verbose = true
+				default:
+					return opts, false
+				}
+			}
+		default:
+			opts.paths = append(opts.paths, arg)
+		}
+	}
+	return opts, len(opts.paths) > 0
+}
@@ -0,0 +1,55 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRemoveHandler(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "sub/c.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var removed []string
+	ctx := WithRemove(t.Context(), func(path string) error {
+		removed = append(removed, path)
+		return os.RemoveAll(path)
+	})
+	shell := NewShell(&Options{WorkingDir: dir})
+
+	stdout, _, err := shell.Exec(ctx, "rm -v a.txt && rm -rf sub missing")
+	if err != nil {
+		t.Fatalf("Expected rm to succeed, got %v", err)
+	}
+	if strings.TrimSpace(stdout) != "removed 'a.txt'" {
+		t.Fatalf("Expected the verbose output, got %q", stdout)
+	}
+	expected := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "sub")}
+	if !slices.Equal(removed, expected) {
+		t.Fatalf("Expected %v to be removed, got %v", expected, removed)
+	}
+
+	// Commands that fail are left to rm.
+	_, _, err = shell.Exec(ctx, "rm missing")
+	if ExitCode(err) == 0 {
+		t.Fatal("Expected removing a missing file to fail")
+	}
+	if len(removed) != 2 {
+		t.Fatalf("Expected no more files to be removed, got %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); err != nil {
+		t.Fatalf("Expected b.txt to be kept, got %v", err)
+	}
+}
@@ -7,7 +7,9 @@
 // WINDOWS COMPATIBILITY:
 // This implementation provides both POSIX shell emulation (mvdan.cc/sh/v3),
 // even on Windows. Some caution has to be taken: commands should have forward
-// slashes (/) as path separators to work, even on Windows.
+// slashes (/) as path separators to work, even on Windows. There, the batch
+// files (.bat, .cmd) and the builtins of cmd.exe like dir or copy run through
+// cmd.exe, and the PowerShell scripts (.ps1) through PowerShell.
 package shell
 
 import (
@@ -15,6 +17,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"slices"
 	"strings"
@@ -56,6 +59,10 @@ type Shell struct {
 	mu         sync.Mutex
 	logger     Logger
 	blockFuncs []BlockFunc
+	// program is the shell running the commands instead of the built-in
+	// interpreter, see SetProgram.
+	program  string
+	sourceRC bool
 }
 
 // Options for creating a new shell
@@ -100,7 +107,23 @@ func (s *Shell) Exec(ctx context.Context, command string) (string, string, error
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.execPOSIX(ctx, command)
+	return s.exec(ctx, command, nil)
+}
+
+// ExecWithInput executes a command in the shell reading its standard input
+// from stdin
+func (s *Shell) ExecWithInput(ctx context.Context, command string, stdin io.Reader) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.exec(ctx, command, stdin)
+}
+
+func (s *Shell) exec(ctx context.Context, command string, stdin io.Reader) (string, string, error) {
+	if s.program != "" {
+		return s.execProgram(ctx, command, stdin)
+	}
+	return s.execPOSIX(ctx, command, stdin)
 }
 
 // GetWorkingDir returns the current working directory
@@ -150,6 +173,20 @@ func (s *Shell) SetEnv(key, value string) {
 	s.env = append(s.env, keyPrefix+value)
 }
 
+// clone returns a new shell with the state and options of the shell.
+func (s *Shell) clone() *Shell {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &Shell{
+		env:        slices.Clone(s.env),
+		cwd:        s.cwd,
+		logger:     s.logger,
+		blockFuncs: s.blockFuncs,
+		program:    s.program,
+		sourceRC:   s.sourceRC,
+	}
+}
+
 // SetBlockFuncs sets the command block functions for the shell
 func (s *Shell) SetBlockFuncs(blockFuncs []BlockFunc) {
 	s.mu.Lock()
@@ -229,19 +266,24 @@ func (s *Shell) blockHandler() func(next interp.ExecHandlerFunc) interp.ExecHand
 }
 
 // execPOSIX executes commands using POSIX shell emulation (cross-platform)
-func (s *Shell) execPOSIX(ctx context.Context, command string) (string, string, error) {
+func (s *Shell) execPOSIX(ctx context.Context, command string, stdin io.Reader) (string, string, error) {
 	line, err := syntax.NewParser().Parse(strings.NewReader(command), "")
 	if err != nil {
 		return "", "", fmt.Errorf("could not parse command: %w", err)
 	}
 
 	var stdout, stderr bytes.Buffer
+	var stdoutW, stderrW io.Writer = &stdout, &stderr
+	if w, ok := OutputFromContext(ctx); ok {
+		stdoutW = io.MultiWriter(&stdout, w)
+		stderrW = io.MultiWriter(&stderr, w)
+	}
 	runner, err := interp.New(
-		interp.StdIO(nil, &stdout, &stderr),
+		interp.StdIO(stdin, stdoutW, stderrW),
 		interp.Interactive(false),
 		interp.Env(expand.ListEnviron(s.env...)),
 		interp.Dir(s.cwd),
-		interp.ExecHandlers(s.blockHandler(), coreutils.ExecHandler),
+		interp.ExecHandlers(s.blockHandler(), removeHandler(), coreutils.ExecHandler, windowsHandler(), limitsHandler()),
 	)
 	if err != nil {
 		return "", "", fmt.Errorf("could not run command: %w", err)
@@ -257,6 +299,17 @@ func (s *Shell) execPOSIX(ctx context.Context, command string) (string, string,
 	return stdout.String(), stderr.String(), err
 }
 
+// environ lists the exported variables of the shell.
+func environ(env expand.Environ) []string {
+	var list []string
+	for name, vr := range env.Each {
+		if vr.IsSet() && vr.Exported && vr.Kind == expand.String {
+			list = append(list, name+"="+vr.Str)
+		}
+	}
+	return list
+}
+
 // IsInterrupt checks if an error is due to interruption
 func IsInterrupt(err error) bool {
 	return errors.Is(err, context.Canceled) ||
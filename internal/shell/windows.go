@@ -0,0 +1,58 @@
+package shell
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// cmdBuiltins are the commands of cmd.exe which aren't programs, run through
+// cmd.exe when no program of the name is found. The ones the interpreter has
+// as builtins, like type or echo, are left to it.
+var cmdBuiltins = []string{
+	"assoc", "cls", "copy", "del", "dir", "erase", "ftype", "md", "mklink",
+	"move", "rd", "ren", "rename", "rmdir", "start", "ver", "vol",
+}
+
+// windowsCommand returns how Windows runs the command when it can't start it
+// directly: the batch files and the builtins of cmd.exe run through cmd.exe,
+// with the command line to pass as is, and the PowerShell scripts through
+// PowerShell. inPath tells whether args[0] is a file found in the PATH or the
+// working directory.
+func windowsCommand(args []string, inPath bool, powershell string) (command []string, cmdLine string, ok bool) {
+	if len(args) == 0 {
+		return nil, "", false
+	}
+	ext := strings.ToLower(filepath.Ext(args[0]))
+	switch {
+	case ext == ".ps1":
+		command = []string{powershell, "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File"}
+		return append(command, args...), "", true
+	case ext == ".bat" || ext == ".cmd" || (!inPath && slices.Contains(cmdBuiltins, strings.ToLower(args[0]))):
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			if i == 0 {
+				// cmd.exe takes the slashes of the program for options.
+				arg = strings.ReplaceAll(arg, "/", `\`)
+			}
+			quoted[i] = cmdQuote(arg)
+		}
+		// With /s, cmd.exe only strips the outer quotes, keeping the ones of
+		// the arguments.
+		line := `"` + strings.Join(quoted, " ") + `"`
+		return []string{"cmd.exe", "/d", "/s", "/c", line}, "cmd.exe /d /s /c " + line, true
+	}
+	return nil, "", false
+}
+
+// cmdQuote quotes the argument for cmd.exe when it has spaces or special
+// characters.
+func cmdQuote(arg string) string {
+	if arg == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(arg, " \t\"&|<>^()%!,;=") {
+		return arg
+	}
+	return `"` + strings.ReplaceAll(arg, `"`, `""`) + `"`
+}
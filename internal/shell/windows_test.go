@@ -0,0 +1,57 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowsCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args    []string
+		inPath  bool
+		command []string
+		cmdLine string
+		ok      bool
+	}{
+		{
+			args:    []string{"scripts/build.bat", "release", "C:/My Files"},
+			inPath:  true,
+			command: []string{"cmd.exe", "/d", "/s", "/c", `"scripts\build.bat release "C:/My Files""`},
+			cmdLine: `cmd.exe /d /s /c "scripts\build.bat release "C:/My Files""`,
+			ok:      true,
+		},
+		{
+			args:    []string{"DIR", "/b"},
+			command: []string{"cmd.exe", "/d", "/s", "/c", `"DIR /b"`},
+			cmdLine: `cmd.exe /d /s /c "DIR /b"`,
+			ok:      true,
+		},
+		{
+			args:    []string{"setup.ps1", "-Force"},
+			inPath:  true,
+			command: []string{"pwsh", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", "setup.ps1", "-Force"},
+			ok:      true,
+		},
+		// A program named like a builtin is run as is.
+		{args: []string{"dir"}, inPath: true},
+		{args: []string{"go", "test"}, inPath: true},
+	}
+	for _, tt := range tests {
+		command, cmdLine, ok := windowsCommand(tt.args, tt.inPath, "pwsh")
+		require.Equal(t, tt.ok, ok, tt.args)
+		require.Equal(t, tt.command, command, tt.args)
+		require.Equal(t, tt.cmdLine, cmdLine, tt.args)
+	}
+}
+
+func TestCmdQuote(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, `""`, cmdQuote(""))
+	require.Equal(t, "main.go", cmdQuote("main.go"))
+	require.Equal(t, `"a & b"`, cmdQuote("a & b"))
+	require.Equal(t, `"say ""hi"""`, cmdQuote(`say "hi"`))
+}
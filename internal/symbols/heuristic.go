@@ -0,0 +1,237 @@
+//go:build !cgo
+
+package symbols
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Backend is the parser used to extract the symbols.
+const Backend = BackendHeuristic
+
+// pattern recognizes a declaration on a single line. The name is the
+// capture group called name. Loose patterns, which do not start with a
+// keyword, also match some statements and reject keywords as names.
+type pattern struct {
+	re    *regexp.Regexp
+	kind  Kind
+	loose bool
+}
+
+func p(kind Kind, expr string) pattern {
+	return pattern{re: regexp.MustCompile(expr), kind: kind}
+}
+
+func loose(kind Kind, expr string) pattern {
+	return pattern{re: regexp.MustCompile(expr), kind: kind, loose: true}
+}
+
+var (
+	jsPatterns = []pattern{
+		p(KindFunction, `^\s*(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:async\s+)?function\s*\*?\s+(?P<name>[\w$]+)`),
+		p(KindClass, `^\s*(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?class\s+(?P<name>[\w$]+)`),
+		p(KindInterface, `^\s*(?:export\s+)?(?:declare\s+)?interface\s+(?P<name>[\w$]+)`),
+		p(KindType, `^\s*(?:export\s+)?(?:declare\s+)?type\s+(?P<name>[\w$]+)\s*[=<]`),
+		p(KindEnum, `^\s*(?:export\s+)?(?:declare\s+)?(?:const\s+)?enum\s+(?P<name>[\w$]+)`),
+		p(KindModule, `^\s*(?:export\s+)?(?:declare\s+)?namespace\s+(?P<name>[\w$.]+)`),
+		p(KindFunction, `^\s*(?:export\s+)?(?:const|let|var)\s+(?P<name>[\w$]+)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|(?:\([^)]*\)|[\w$]+)\s*(?::[^=]+)?=>)`),
+		loose(KindMethod, `^\s+(?:(?:public|private|protected|static|async|readonly|override|abstract|get|set)\s+)*(?P<name>[\w$]+)\s*(?:<[^>]*>)?\([^)]*\)\s*(?::\s*[^{;]+)?\{?\s*$`),
+	}
+	cPatterns = []pattern{
+		p(KindModule, `^\s*namespace\s+(?P<name>[\w:]+)\s*\{?\s*$`),
+		p(KindClass, `^\s*(?:template\s*<[^>]*>\s*)?class\s+(?P<name>\w+)\s*(?:final\s*)?(?::[^;{]*)?(?:\{|$)`),
+		p(KindStruct, `^\s*(?:typedef\s+)?(?:struct|union)\s+(?P<name>\w+)\s*(?::[^;{]*)?(?:\{|$)`),
+		p(KindEnum, `^\s*(?:typedef\s+)?enum\s+(?:class\s+)?(?P<name>\w+)\s*(?::[^;{]*)?(?:\{|$)`),
+		p(KindType, `^\s*typedef\s+.*?(?P<name>\w+)\s*;\s*$`),
+		p(KindType, `^\s*using\s+(?P<name>\w+)\s*=`),
+		loose(KindFunction, `^(?:template\s*<[^>]*>\s*)?(?:[\w:<>,]+[\s*&]+)+(?P<name>[\w:~]+)\s*\([^;]*\)\s*(?:const\s*)?(?:noexcept\s*)?(?:\{.*)?$`),
+	}
+	javaPatterns = []pattern{
+		p(KindInterface, `^\s*(?:(?:public|private|protected|internal|static|abstract|sealed|partial)\s+)*(?:@)?interface\s+(?P<name>\w+)`),
+		p(KindEnum, `^\s*(?:(?:public|private|protected|internal|static)\s+)*enum\s+(?P<name>\w+)`),
+		p(KindStruct, `^\s*(?:(?:public|private|protected|internal|static|readonly|partial)\s+)*struct\s+(?P<name>\w+)`),
+		p(KindClass, `^\s*(?:(?:public|private|protected|internal|static|abstract|final|sealed|partial)\s+)*(?:class|record)\s+(?P<name>\w+)`),
+		p(KindModule, `^\s*namespace\s+(?P<name>[\w.]+)`),
+		loose(KindMethod, `^\s+(?:(?:public|private|protected|internal|static|final|abstract|synchronized|native|override|virtual|async|sealed|extern|partial|new|default)\s+)*(?:[\w<>\[\],.?]+\s+)?(?P<name>\w+)\s*\([^;=]*$`),
+	}
+	grammars = map[Language][]pattern{
+		LanguageGo: {
+			p(KindMethod, `^func\s+\([^)]*\)\s*(?P<name>\w+)`),
+			p(KindFunction, `^func\s+(?P<name>\w+)`),
+			p(KindStruct, `^\s*(?:type\s+)?(?P<name>\w+)\s+struct\s*\{`),
+			p(KindInterface, `^\s*(?:type\s+)?(?P<name>\w+)\s+interface\s*\{`),
+			p(KindType, `^type\s+(?P<name>\w+)\s*(?:\[[^\]]*\]\s*)?=?\s*[\w*\[]`),
+			p(KindConstant, `^const\s+(?P<name>\w+)`),
+			p(KindVariable, `^var\s+(?P<name>\w+)`),
+		},
+		LanguagePython: {
+			p(KindFunction, `^\s*(?:async\s+)?def\s+(?P<name>\w+)`),
+			p(KindClass, `^\s*class\s+(?P<name>\w+)`),
+		},
+		LanguageJavaScript: jsPatterns,
+		LanguageTypeScript: jsPatterns,
+		LanguageTSX:        jsPatterns,
+		LanguageRust: {
+			p(KindFunction, `^\s*(?:pub(?:\([^)]*\))?\s+)?(?:(?:const|async|unsafe|extern\s+"[^"]*")\s+)*fn\s+(?P<name>\w+)`),
+			p(KindStruct, `^\s*(?:pub(?:\([^)]*\))?\s+)?(?:struct|union)\s+(?P<name>\w+)`),
+			p(KindEnum, `^\s*(?:pub(?:\([^)]*\))?\s+)?enum\s+(?P<name>\w+)`),
+			p(KindTrait, `^\s*(?:pub(?:\([^)]*\))?\s+)?(?:unsafe\s+)?trait\s+(?P<name>\w+)`),
+			p(KindImpl, `^\s*(?:unsafe\s+)?impl(?:\s*<[^>]*>)?\s+(?P<name>[^{]+?)\s*(?:where\b.*)?\{?\s*$`),
+			p(KindModule, `^\s*(?:pub(?:\([^)]*\))?\s+)?mod\s+(?P<name>\w+)`),
+			p(KindType, `^\s*(?:pub(?:\([^)]*\))?\s+)?type\s+(?P<name>\w+)`),
+			p(KindConstant, `^(?:pub(?:\([^)]*\))?\s+)?const\s+(?P<name>\w+)`),
+			p(KindVariable, `^(?:pub(?:\([^)]*\))?\s+)?static\s+(?:mut\s+)?(?P<name>\w+)`),
+			p(KindFunction, `^\s*macro_rules!\s+(?P<name>\w+)`),
+		},
+		LanguageJava:   javaPatterns,
+		LanguageCSharp: javaPatterns,
+		LanguageC:      cPatterns,
+		LanguageCPP:    cPatterns,
+		LanguageRuby: {
+			p(KindFunction, `^\s*def\s+(?:self\.)?(?P<name>[\w]+[?!=]?)`),
+			p(KindClass, `^\s*class\s+(?P<name>[\w:]+)`),
+			p(KindModule, `^\s*module\s+(?P<name>[\w:]+)`),
+		},
+		LanguagePHP: {
+			p(KindModule, `^\s*namespace\s+(?P<name>[\w\\]+)`),
+			p(KindInterface, `^\s*interface\s+(?P<name>\w+)`),
+			p(KindTrait, `^\s*trait\s+(?P<name>\w+)`),
+			p(KindEnum, `^\s*enum\s+(?P<name>\w+)`),
+			p(KindClass, `^\s*(?:(?:abstract|final|readonly)\s+)*class\s+(?P<name>\w+)`),
+			p(KindFunction, `^\s*(?:(?:public|private|protected|static|abstract|final)\s+)*function\s+&?(?P<name>\w+)`),
+		},
+		LanguageKotlin: {
+			p(KindInterface, `^\s*(?:(?:public|private|protected|internal|sealed|fun)\s+)*interface\s+(?P<name>\w+)`),
+			p(KindEnum, `^\s*(?:(?:public|private|protected|internal)\s+)*enum\s+class\s+(?P<name>\w+)`),
+			p(KindClass, `^\s*(?:(?:public|private|protected|internal|open|abstract|sealed|data|inner|value|annotation)\s+)*(?:class|object)\s+(?P<name>\w+)`),
+			p(KindFunction, `^\s*(?:(?:public|private|protected|internal|open|override|abstract|suspend|inline|operator|infix|tailrec)\s+)*fun\s+(?:<[^>]*>\s*)?(?:[\w.]+\.)?(?P<name>\w+)`),
+		},
+		LanguageSwift: {
+			p(KindInterface, `^\s*(?:(?:public|private|fileprivate|internal|open)\s+)*protocol\s+(?P<name>\w+)`),
+			p(KindStruct, `^\s*(?:(?:public|private|fileprivate|internal)\s+)*struct\s+(?P<name>\w+)`),
+			p(KindEnum, `^\s*(?:(?:public|private|fileprivate|internal|indirect)\s+)*enum\s+(?P<name>\w+)`),
+			p(KindImpl, `^\s*(?:(?:public|private|fileprivate|internal)\s+)*extension\s+(?P<name>[\w.]+)`),
+			p(KindClass, `^\s*(?:(?:public|private|fileprivate|internal|open|final)\s+)*(?:class|actor)\s+(?P<name>\w+)`),
+			p(KindFunction, `^\s*(?:(?:public|private|fileprivate|internal|open|static|class|override|final|mutating)\s+)*func\s+(?P<name>\w+)`),
+		},
+		LanguageScala: {
+			p(KindTrait, `^\s*(?:(?:sealed|private|protected)\s+)*trait\s+(?P<name>\w+)`),
+			p(KindEnum, `^\s*enum\s+(?P<name>\w+)`),
+			p(KindClass, `^\s*(?:(?:abstract|final|sealed|case|private|protected|implicit)\s+)*(?:class|object)\s+(?P<name>\w+)`),
+			p(KindFunction, `^\s*(?:(?:override|private|protected|final|implicit|inline)\s+)*def\s+(?P<name>\w+)`),
+		},
+		LanguageLua: {
+			p(KindFunction, `^\s*(?:local\s+)?function\s+(?P<name>[\w.:]+)`),
+		},
+		LanguageBash: {
+			p(KindFunction, `^\s*function\s+(?P<name>[\w-]+)`),
+			loose(KindFunction, `^\s*(?P<name>[\w-]+)\s*\(\)`),
+		},
+	}
+)
+
+// notNames are keywords loose patterns would otherwise take for
+// declarations, e.g. `if (x) {`.
+var notNames = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "function": true, "else": true, "do": true, "try": true,
+	"new": true, "throw": true, "sizeof": true, "using": true, "lock": true,
+	"foreach": true, "synchronized": true, "when": true, "with": true,
+}
+
+type open struct {
+	indent  int
+	symbols *[]Symbol
+	index   int
+}
+
+func parse(lang Language, src []byte) ([]Symbol, error) {
+	patterns, ok := grammars[lang]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", lang)
+	}
+
+	var root []Symbol
+	// stack holds the symbols that may contain the next declarations,
+	// based on indentation.
+	var stack []open
+	closeUntil := func(indent, line int) {
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			top := stack[len(stack)-1]
+			(*top.symbols)[top.index].EndLine = line
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxFileSize)
+	lineNum := 0
+	offset := 0
+	lastLine := 0
+	for scanner.Scan() {
+		lineNum++
+		text := scanner.Text()
+		start := offset
+		offset += len(text) + 1
+
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			continue
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " \t"))
+		// A closing line ends the blocks opened at the same indentation.
+		if strings.HasPrefix(trimmed, "}") || trimmed == "end" {
+			closeUntil(indent, lineNum)
+			continue
+		}
+		lastLine = lineNum
+
+		kind, name := matchDeclaration(patterns, text)
+		if name == "" {
+			continue
+		}
+		closeUntil(indent, lineNum-1)
+
+		siblings := &root
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			siblings = &(*top.symbols)[top.index].Children
+			parent := (*top.symbols)[top.index].Kind
+			if kind == KindFunction && parent.isContainer() {
+				kind = KindMethod
+			}
+		}
+		*siblings = append(*siblings, Symbol{
+			Name:      name,
+			Kind:      kind,
+			Line:      lineNum,
+			EndLine:   lineNum,
+			Signature: signature(src, start),
+		})
+		stack = append(stack, open{indent: indent, symbols: siblings, index: len(*siblings) - 1})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	closeUntil(0, lastLine)
+	return root, nil
+}
+
+func matchDeclaration(patterns []pattern, line string) (Kind, string) {
+	for _, pat := range patterns {
+		m := pat.re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		name := strings.TrimSpace(m[pat.re.SubexpIndex("name")])
+		if name == "" || (pat.loose && notNames[name]) {
+			continue
+		}
+		return pat.kind, name
+	}
+	return "", ""
+}
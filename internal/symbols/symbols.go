@@ -0,0 +1,194 @@
+// Package symbols extracts the structure of source files (functions, types,
+// classes and so on) without a language server.
+//
+// With cgo the files are parsed with tree-sitter. Builds without cgo, like
+// the release builds, fall back to a line based heuristic that recognizes
+// the usual declaration forms of the same languages. Backend tells which
+// one the build uses.
+package symbols
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxFileSize is the size above which files are not parsed.
+const MaxFileSize = 1024 * 1024
+
+// The parsers Backend can be.
+const (
+	BackendTreeSitter = "tree-sitter"
+	BackendHeuristic  = "heuristic"
+)
+
+// Precise reports whether the files are parsed with tree-sitter, rather
+// than with the heuristic, which misses the declarations spanning several
+// lines and estimates where they end.
+func Precise() bool {
+	return Backend == BackendTreeSitter
+}
+
+type Kind string
+
+const (
+	KindFunction  Kind = "function"
+	KindMethod    Kind = "method"
+	KindClass     Kind = "class"
+	KindInterface Kind = "interface"
+	KindStruct    Kind = "struct"
+	KindEnum      Kind = "enum"
+	KindTrait     Kind = "trait"
+	KindImpl      Kind = "impl"
+	KindType      Kind = "type"
+	KindModule    Kind = "module"
+	KindConstant  Kind = "constant"
+	KindVariable  Kind = "variable"
+)
+
+// isContainer reports whether functions declared in symbols of the kind
+// are methods.
+func (k Kind) isContainer() bool {
+	switch k {
+	case KindClass, KindInterface, KindStruct, KindEnum, KindTrait, KindImpl:
+		return true
+	}
+	return false
+}
+
+type Symbol struct {
+	Name string
+	Kind Kind
+	// Line and EndLine are 1-based.
+	Line    int
+	EndLine int
+	// Signature is the first line of the declaration.
+	Signature string
+	// Container is the name of the enclosing symbol, only set by Flatten.
+	Container string
+	Children  []Symbol
+}
+
+type Language string
+
+const (
+	LanguageGo         Language = "go"
+	LanguagePython     Language = "python"
+	LanguageJavaScript Language = "javascript"
+	LanguageTypeScript Language = "typescript"
+	LanguageTSX        Language = "tsx"
+	LanguageRust       Language = "rust"
+	LanguageJava       Language = "java"
+	LanguageC          Language = "c"
+	LanguageCPP        Language = "cpp"
+	LanguageCSharp     Language = "csharp"
+	LanguageRuby       Language = "ruby"
+	LanguagePHP        Language = "php"
+	LanguageKotlin     Language = "kotlin"
+	LanguageSwift      Language = "swift"
+	LanguageScala      Language = "scala"
+	LanguageLua        Language = "lua"
+	LanguageBash       Language = "bash"
+)
+
+var languagesByExt = map[string]Language{
+	".go":    LanguageGo,
+	".py":    LanguagePython,
+	".pyi":   LanguagePython,
+	".js":    LanguageJavaScript,
+	".jsx":   LanguageJavaScript,
+	".mjs":   LanguageJavaScript,
+	".cjs":   LanguageJavaScript,
+	".ts":    LanguageTypeScript,
+	".mts":   LanguageTypeScript,
+	".cts":   LanguageTypeScript,
+	".tsx":   LanguageTSX,
+	".rs":    LanguageRust,
+	".java":  LanguageJava,
+	".c":     LanguageC,
+	".h":     LanguageC,
+	".cc":    LanguageCPP,
+	".cpp":   LanguageCPP,
+	".cxx":   LanguageCPP,
+	".hh":    LanguageCPP,
+	".hpp":   LanguageCPP,
+	".hxx":   LanguageCPP,
+	".cs":    LanguageCSharp,
+	".rb":    LanguageRuby,
+	".php":   LanguagePHP,
+	".kt":    LanguageKotlin,
+	".kts":   LanguageKotlin,
+	".swift": LanguageSwift,
+	".scala": LanguageScala,
+	".sc":    LanguageScala,
+	".lua":   LanguageLua,
+	".sh":    LanguageBash,
+	".bash":  LanguageBash,
+}
+
+// LanguageForFile returns the language of the file based on its extension.
+func LanguageForFile(path string) (Language, bool) {
+	lang, ok := languagesByExt[strings.ToLower(filepath.Ext(path))]
+	return lang, ok
+}
+
+// ParseFile reads and parses the file, returning its top level symbols.
+func ParseFile(path string) ([]Symbol, error) {
+	lang, ok := LanguageForFile(path)
+	if !ok {
+		return nil, fmt.Errorf("unsupported file type: %s", filepath.Base(path))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > MaxFileSize {
+		return nil, fmt.Errorf("file is too large to parse: %d bytes", info.Size())
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(lang, src)
+}
+
+// Parse parses the source code, returning its top level symbols.
+func Parse(lang Language, src []byte) ([]Symbol, error) {
+	return parse(lang, src)
+}
+
+// Flatten returns the symbols and all their descendants in source order,
+// with Container set and Children cleared.
+func Flatten(symbols []Symbol) []Symbol {
+	var out []Symbol
+	var walk func(symbols []Symbol, container string)
+	walk = func(symbols []Symbol, container string) {
+		for _, s := range symbols {
+			children := s.Children
+			s.Children = nil
+			s.Container = container
+			out = append(out, s)
+			walk(children, s.Name)
+		}
+	}
+	walk(symbols, "")
+	return out
+}
+
+const maxSignatureLength = 200
+
+// signature returns the first line of a declaration, without the opening
+// brace of its body.
+func signature(src []byte, start int) string {
+	line := string(src[start:])
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.TrimSpace(line)
+	line = strings.TrimSpace(strings.TrimSuffix(line, "{"))
+	if len(line) > maxSignatureLength {
+		line = line[:maxSignatureLength] + "..."
+	}
+	return line
+}
@@ -0,0 +1,241 @@
+package symbols
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The sources are written in the usual multi-line style so both the
+// tree-sitter and the heuristic backends give the same results.
+var parseTests = []struct {
+	lang Language
+	src  string
+	want []string
+}{
+	{
+		lang: LanguageGo,
+		src: `package main
+
+const Answer = 42
+
+type Server struct {
+	Addr string
+}
+
+type Handler interface {
+	Serve()
+}
+
+func (s *Server) Start() error {
+	var local int
+	return nil
+}
+
+func main() {
+}
+`,
+		want: []string{
+			"3 constant Answer",
+			"5 struct Server",
+			"9 interface Handler",
+			"13 method Start",
+			"18 function main",
+		},
+	},
+	{
+		lang: LanguagePython,
+		src: `import os
+
+class Greeter:
+    def __init__(self, name):
+        self.name = name
+
+    def greet(self):
+        return "hi"
+
+async def main():
+    pass
+`,
+		want: []string{
+			"3 class Greeter",
+			"4 method __init__ in Greeter",
+			"7 method greet in Greeter",
+			"10 function main",
+		},
+	},
+	{
+		lang: LanguageTypeScript,
+		src: `export interface Shape {
+  area(): number
+}
+
+export type Point = { x: number }
+
+export class Circle implements Shape {
+  area(): number {
+    return 1
+  }
+}
+
+export const fetcher = async (url: string) => {
+  return fetch(url)
+}
+
+function main() {
+}
+`,
+		want: []string{
+			"1 interface Shape",
+			"2 method area in Shape",
+			"5 type Point",
+			"7 class Circle",
+			"8 method area in Circle",
+			"13 function fetcher",
+			"17 function main",
+		},
+	},
+	{
+		lang: LanguageRust,
+		src: `pub struct Point {
+    x: i32,
+}
+
+pub trait Draw {
+    fn draw(&self);
+}
+
+impl Point {
+    pub fn new() -> Self {
+        Point { x: 0 }
+    }
+}
+
+fn main() {
+}
+`,
+		want: []string{
+			"1 struct Point",
+			"5 trait Draw",
+			"6 method draw in Draw",
+			"9 impl Point",
+			"10 method new in Point",
+			"15 function main",
+		},
+	},
+	{
+		lang: LanguageJava,
+		src: `package a;
+
+public class App {
+    public App() {
+    }
+
+    void run() {
+    }
+}
+`,
+		want: []string{
+			"3 class App",
+			"4 method App in App",
+			"7 method run in App",
+		},
+	},
+	{
+		lang: LanguageC,
+		src: `#include <stdio.h>
+
+struct point {
+	int x;
+};
+
+static int *make(int n)
+{
+	return 0;
+}
+
+int main(void)
+{
+	struct point *p;
+	return 0;
+}
+`,
+		want: []string{
+			"3 struct point",
+			"7 function make",
+			"12 function main",
+		},
+	},
+	{
+		lang: LanguageRuby,
+		src: `module Tools
+  class Parser
+    def parse(x)
+    end
+  end
+end
+`,
+		want: []string{
+			"1 module Tools",
+			"2 class Parser in Tools",
+			"3 method parse in Parser",
+		},
+	},
+	{
+		lang: LanguageBash,
+		src: `#!/bin/bash
+build() {
+  echo hi
+}
+
+function deploy {
+  echo
+}
+`,
+		want: []string{
+			"2 function build",
+			"6 function deploy",
+		},
+	},
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range parseTests {
+		t.Run(string(tt.lang), func(t *testing.T) {
+			t.Parallel()
+			symbols, err := Parse(tt.lang, []byte(tt.src))
+			require.NoError(t, err)
+
+			var got []string
+			for _, s := range Flatten(symbols) {
+				entry := fmt.Sprintf("%d %s %s", s.Line, s.Kind, s.Name)
+				if s.Container != "" {
+					entry += " in " + s.Container
+				}
+				got = append(got, entry)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLanguageForFile(t *testing.T) {
+	t.Parallel()
+
+	lang, ok := LanguageForFile("src/App.TSX")
+	require.True(t, ok)
+	require.Equal(t, LanguageTSX, lang)
+
+	_, ok = LanguageForFile("README.md")
+	require.False(t, ok)
+}
+
+func TestSignature(t *testing.T) {
+	t.Parallel()
+
+	src := []byte("func main() {\n}\n")
+	require.Equal(t, "func main()", signature(src, 0))
+}
@@ -0,0 +1,426 @@
+//go:build cgo
+
+package symbols
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/bash"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/csharp"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/kotlin"
+	"github.com/smacker/go-tree-sitter/lua"
+	"github.com/smacker/go-tree-sitter/php"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/ruby"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/scala"
+	"github.com/smacker/go-tree-sitter/swift"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// Backend is the parser used to extract the symbols.
+const Backend = BackendTreeSitter
+
+// grammar maps the declaration nodes of a tree-sitter grammar to symbol
+// kinds. refine, when set, can change the kind of a declaration or skip it
+// by returning an empty kind.
+type grammar struct {
+	language func() *sitter.Language
+	kinds    map[string]Kind
+	refine   func(n *sitter.Node, src []byte, kind Kind) Kind
+}
+
+var jsKinds = map[string]Kind{
+	"function_declaration":           KindFunction,
+	"generator_function_declaration": KindFunction,
+	"class_declaration":              KindClass,
+	"method_definition":              KindMethod,
+	"variable_declarator":            KindFunction,
+}
+
+var tsKinds = map[string]Kind{
+	"function_declaration":           KindFunction,
+	"generator_function_declaration": KindFunction,
+	"function_signature":             KindFunction,
+	"class_declaration":              KindClass,
+	"abstract_class_declaration":     KindClass,
+	"method_definition":              KindMethod,
+	"method_signature":               KindMethod,
+	"abstract_method_signature":      KindMethod,
+	"interface_declaration":          KindInterface,
+	"type_alias_declaration":         KindType,
+	"enum_declaration":               KindEnum,
+	"internal_module":                KindModule,
+	"variable_declarator":            KindFunction,
+}
+
+var cRefine = func(n *sitter.Node, _ []byte, kind Kind) Kind {
+	switch n.Type() {
+	case "struct_specifier", "union_specifier", "enum_specifier", "class_specifier":
+		// Only definitions, not uses like `struct foo *f`.
+		if n.ChildByFieldName("body") == nil {
+			return ""
+		}
+	}
+	return kind
+}
+
+var grammars = map[Language]grammar{
+	LanguageGo: {
+		language: golang.GetLanguage,
+		kinds: map[string]Kind{
+			"function_declaration": KindFunction,
+			"method_declaration":   KindMethod,
+			"type_spec":            KindType,
+			"type_alias":           KindType,
+			"const_spec":           KindConstant,
+			"var_spec":             KindVariable,
+		},
+		refine: func(n *sitter.Node, _ []byte, kind Kind) Kind {
+			if n.Type() != "type_spec" {
+				return kind
+			}
+			switch t := n.ChildByFieldName("type"); {
+			case t == nil:
+			case t.Type() == "struct_type":
+				return KindStruct
+			case t.Type() == "interface_type":
+				return KindInterface
+			}
+			return kind
+		},
+	},
+	LanguagePython: {
+		language: python.GetLanguage,
+		kinds: map[string]Kind{
+			"function_definition": KindFunction,
+			"class_definition":    KindClass,
+		},
+	},
+	LanguageJavaScript: {
+		language: javascript.GetLanguage,
+		kinds:    jsKinds,
+		refine:   jsRefine,
+	},
+	LanguageTypeScript: {
+		language: typescript.GetLanguage,
+		kinds:    tsKinds,
+		refine:   jsRefine,
+	},
+	LanguageTSX: {
+		language: tsx.GetLanguage,
+		kinds:    tsKinds,
+		refine:   jsRefine,
+	},
+	LanguageRust: {
+		language: rust.GetLanguage,
+		kinds: map[string]Kind{
+			"function_item":           KindFunction,
+			"function_signature_item": KindFunction,
+			"struct_item":             KindStruct,
+			"enum_item":               KindEnum,
+			"union_item":              KindStruct,
+			"trait_item":              KindTrait,
+			"impl_item":               KindImpl,
+			"mod_item":                KindModule,
+			"type_item":               KindType,
+			"const_item":              KindConstant,
+			"static_item":             KindVariable,
+			"macro_definition":        KindFunction,
+		},
+	},
+	LanguageJava: {
+		language: java.GetLanguage,
+		kinds: map[string]Kind{
+			"class_declaration":           KindClass,
+			"record_declaration":          KindClass,
+			"interface_declaration":       KindInterface,
+			"annotation_type_declaration": KindInterface,
+			"enum_declaration":            KindEnum,
+			"method_declaration":          KindMethod,
+			"constructor_declaration":     KindMethod,
+		},
+	},
+	LanguageC: {
+		language: c.GetLanguage,
+		kinds: map[string]Kind{
+			"function_definition": KindFunction,
+			"struct_specifier":    KindStruct,
+			"union_specifier":     KindStruct,
+			"enum_specifier":      KindEnum,
+			"type_definition":     KindType,
+		},
+		refine: cRefine,
+	},
+	LanguageCPP: {
+		language: cpp.GetLanguage,
+		kinds: map[string]Kind{
+			"function_definition":  KindFunction,
+			"class_specifier":      KindClass,
+			"struct_specifier":     KindStruct,
+			"union_specifier":      KindStruct,
+			"enum_specifier":       KindEnum,
+			"type_definition":      KindType,
+			"alias_declaration":    KindType,
+			"namespace_definition": KindModule,
+		},
+		refine: cRefine,
+	},
+	LanguageCSharp: {
+		language: csharp.GetLanguage,
+		kinds: map[string]Kind{
+			"class_declaration":                 KindClass,
+			"record_declaration":                KindClass,
+			"struct_declaration":                KindStruct,
+			"interface_declaration":             KindInterface,
+			"enum_declaration":                  KindEnum,
+			"method_declaration":                KindMethod,
+			"constructor_declaration":           KindMethod,
+			"property_declaration":              KindVariable,
+			"namespace_declaration":             KindModule,
+			"file_scoped_namespace_declaration": KindModule,
+		},
+	},
+	LanguageRuby: {
+		language: ruby.GetLanguage,
+		kinds: map[string]Kind{
+			"method":           KindFunction,
+			"singleton_method": KindFunction,
+			"class":            KindClass,
+			"module":           KindModule,
+		},
+	},
+	LanguagePHP: {
+		language: php.GetLanguage,
+		kinds: map[string]Kind{
+			"function_definition":   KindFunction,
+			"class_declaration":     KindClass,
+			"interface_declaration": KindInterface,
+			"trait_declaration":     KindTrait,
+			"enum_declaration":      KindEnum,
+			"method_declaration":    KindMethod,
+			"namespace_definition":  KindModule,
+		},
+	},
+	LanguageKotlin: {
+		language: kotlin.GetLanguage,
+		kinds: map[string]Kind{
+			"class_declaration":    KindClass,
+			"object_declaration":   KindClass,
+			"function_declaration": KindFunction,
+		},
+		refine: func(n *sitter.Node, _ []byte, kind Kind) Kind {
+			if n.Type() == "class_declaration" && hasChildOfType(n, "interface") {
+				return KindInterface
+			}
+			if n.Type() == "class_declaration" && hasChildOfType(n, "enum_class_body") {
+				return KindEnum
+			}
+			return kind
+		},
+	},
+	LanguageSwift: {
+		language: swift.GetLanguage,
+		kinds: map[string]Kind{
+			"class_declaration":    KindClass,
+			"protocol_declaration": KindInterface,
+			"function_declaration": KindFunction,
+		},
+		refine: func(n *sitter.Node, src []byte, kind Kind) Kind {
+			if n.Type() != "class_declaration" {
+				return kind
+			}
+			if k := n.ChildByFieldName("declaration_kind"); k != nil {
+				switch k.Content(src) {
+				case "struct":
+					return KindStruct
+				case "enum":
+					return KindEnum
+				case "extension":
+					return KindImpl
+				}
+			}
+			return kind
+		},
+	},
+	LanguageScala: {
+		language: scala.GetLanguage,
+		kinds: map[string]Kind{
+			"class_definition":     KindClass,
+			"object_definition":    KindClass,
+			"trait_definition":     KindTrait,
+			"enum_definition":      KindEnum,
+			"function_definition":  KindFunction,
+			"function_declaration": KindFunction,
+		},
+	},
+	LanguageLua: {
+		language: lua.GetLanguage,
+		kinds: map[string]Kind{
+			"function_statement": KindFunction,
+		},
+	},
+	LanguageBash: {
+		language: bash.GetLanguage,
+		kinds: map[string]Kind{
+			"function_definition": KindFunction,
+		},
+	},
+}
+
+// jsRefine keeps only the variables assigned a function, e.g.
+// `const handler = () => {}`.
+func jsRefine(n *sitter.Node, _ []byte, kind Kind) Kind {
+	if n.Type() != "variable_declarator" {
+		return kind
+	}
+	value := n.ChildByFieldName("value")
+	if value == nil {
+		return ""
+	}
+	switch value.Type() {
+	case "arrow_function", "function", "function_expression", "generator_function":
+		return KindFunction
+	}
+	return ""
+}
+
+func parse(lang Language, src []byte) ([]Symbol, error) {
+	g, ok := grammars[lang]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", lang)
+	}
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(g.language())
+	tree, err := parser.ParseCtx(context.Background(), nil, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+	defer tree.Close()
+
+	return collect(g, tree.RootNode(), src, "", false), nil
+}
+
+// collect walks the tree and returns the symbols found under n. parent is
+// the kind of the enclosing symbol.
+func collect(g grammar, n *sitter.Node, src []byte, parent Kind, inFunction bool) []Symbol {
+	var symbols []Symbol
+	for i := range int(n.NamedChildCount()) {
+		child := n.NamedChild(i)
+		kind, ok := g.kinds[child.Type()]
+		if ok && g.refine != nil {
+			kind = g.refine(child, src, kind)
+		}
+		if kind == KindFunction && parent.isContainer() {
+			kind = KindMethod
+		}
+		// Locals are not part of the structure of the file.
+		if (kind == KindVariable || kind == KindConstant) && inFunction {
+			kind = ""
+		}
+
+		name := ""
+		if kind != "" {
+			name = symbolName(child, src)
+		}
+		if name == "" {
+			symbols = append(symbols, collect(g, child, src, parent, inFunction)...)
+			continue
+		}
+
+		// Some grammars start nodes at the whitespace before them.
+		start, line := int(child.StartByte()), int(child.StartPoint().Row)+1
+		for start < len(src) && isSpace(src[start]) {
+			if src[start] == '\n' {
+				line++
+			}
+			start++
+		}
+
+		isFunction := kind == KindFunction || kind == KindMethod
+		symbols = append(symbols, Symbol{
+			Name:      name,
+			Kind:      kind,
+			Line:      line,
+			EndLine:   int(child.EndPoint().Row) + 1,
+			Signature: signature(src, lineStart(src, start)),
+			Children:  collect(g, child, src, kind, inFunction || isFunction),
+		})
+	}
+	return symbols
+}
+
+// symbolName finds the name of a declaration: its name field, the
+// innermost declarator for C-like declarations or the first identifier.
+func symbolName(n *sitter.Node, src []byte) string {
+	if n.Type() == "impl_item" {
+		return implName(n, src)
+	}
+	if name := n.ChildByFieldName("name"); name != nil {
+		return cleanName(name.Content(src))
+	}
+	if d := n.ChildByFieldName("declarator"); d != nil {
+		for {
+			next := d.ChildByFieldName("declarator")
+			if next == nil {
+				break
+			}
+			d = next
+		}
+		return cleanName(d.Content(src))
+	}
+	for i := range int(n.NamedChildCount()) {
+		child := n.NamedChild(i)
+		switch child.Type() {
+		case "identifier", "simple_identifier", "type_identifier", "constant", "name":
+			return cleanName(child.Content(src))
+		}
+	}
+	return ""
+}
+
+// implName names a Rust impl block after its type and trait, if any.
+func implName(n *sitter.Node, src []byte) string {
+	typ := n.ChildByFieldName("type")
+	if typ == nil {
+		return ""
+	}
+	if trait := n.ChildByFieldName("trait"); trait != nil {
+		return cleanName(trait.Content(src) + " for " + typ.Content(src))
+	}
+	return cleanName(typ.Content(src))
+}
+
+func hasChildOfType(n *sitter.Node, typ string) bool {
+	for i := range int(n.ChildCount()) {
+		if n.Child(i).Type() == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func cleanName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+func lineStart(src []byte, offset int) int {
+	for offset > 0 && src[offset-1] != '\n' {
+		offset--
+	}
+	return offset
+}
@@ -0,0 +1,341 @@
+// Package trash keeps the files deleted or overwritten by the agent per
+// session, so they can be restored even when they aren't tracked by git.
+package trash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	trashDirName   = "trash"
+	metadataSuffix = ".json"
+)
+
+// Reasons a file was put in the trash.
+const (
+	ReasonDeleted     = "deleted"
+	ReasonOverwritten = "overwritten"
+)
+
+// ErrExists is returned when restoring a file over one that exists.
+var ErrExists = errors.New("file exists")
+
+type Entry struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	// Path is where the file was, it is restored there.
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	// ToolName is the tool that deleted or overwrote the file.
+	ToolName  string `json:"tool_name,omitempty"`
+	IsDir     bool   `json:"is_dir,omitempty"`
+	Size      int64  `json:"size"`
+	TrashedAt int64  `json:"trashed_at"`
+	// File is the copy of the file in the trash.
+	File string `json:"file"`
+}
+
+type Service interface {
+	// Keep copies a file to the trash of the session before it is
+	// overwritten. Only the original is kept, files already in the trash
+	// of the session aren't copied again.
+	Keep(sessionID, path, toolName string) (Entry, error)
+	// Delete moves a file, or a directory, to the trash of the session.
+	Delete(sessionID, path, toolName string) (Entry, error)
+	// List returns the trashed files of the session, or of every session
+	// when it is empty, most recent first.
+	List(sessionID string) ([]Entry, error)
+	Get(id string) (Entry, error)
+	// Restore puts a trashed file back where it was and removes it from the
+	// trash. It fails with ErrExists when a file is there, unless force is
+	// set.
+	Restore(id string, force bool) (Entry, error)
+	DeleteSessionTrash(sessionID string) error
+	// Sessions returns the IDs of the sessions with trashed files.
+	Sessions() ([]string, error)
+}
+
+type service struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewService returns a trash keeping the files in the trash directory of the
+// given data directory.
+func NewService(dataDir string) Service {
+	return &service{dir: filepath.Join(dataDir, trashDirName)}
+}
+
+func (s *service) sessionDir(sessionID string) string {
+	return filepath.Join(s.dir, filepath.Base(sessionID))
+}
+
+func (s *service) Keep(sessionID, path, toolName string) (Entry, error) {
+	entries, err := s.List(sessionID)
+	if err != nil {
+		return Entry{}, err
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	if i := slices.IndexFunc(entries, func(e Entry) bool {
+		return e.Path == path && e.Reason == ReasonOverwritten
+	}); i >= 0 {
+		return entries[i], nil
+	}
+	return s.add(sessionID, path, toolName, ReasonOverwritten)
+}
+
+func (s *service) Delete(sessionID, path, toolName string) (Entry, error) {
+	return s.add(sessionID, path, toolName, ReasonDeleted)
+}
+
+func (s *service) add(sessionID, path, toolName, reason string) (Entry, error) {
+	if sessionID == "" {
+		return Entry{}, errors.New("session ID is required")
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.sessionDir(sessionID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return Entry{}, fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	id := uuid.New().String()[:8]
+	e := Entry{
+		ID:        id,
+		SessionID: sessionID,
+		Path:      path,
+		Reason:    reason,
+		ToolName:  toolName,
+		IsDir:     info.IsDir(),
+		Size:      info.Size(),
+		TrashedAt: time.Now().Unix(),
+		File:      filepath.Join(dir, id+"-"+filepath.Base(path)),
+	}
+	if reason == ReasonDeleted {
+		err = move(path, e.File)
+	} else {
+		err = copyPath(path, e.File)
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to put %s in the trash: %w", path, err)
+	}
+	if e.IsDir {
+		e.Size = dirSize(e.File)
+	}
+	if err := writeEntry(dir, e); err != nil {
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+func writeEntry(dir string, e Entry) error {
+	meta, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal trash entry: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, e.ID+metadataSuffix), meta, 0o600); err != nil {
+		return fmt.Errorf("failed to write trash entry: %w", err)
+	}
+	return nil
+}
+
+func readEntry(path string) (Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to read trash entry: %w", err)
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Entry{}, fmt.Errorf("failed to parse trash entry: %w", err)
+	}
+	return e, nil
+}
+
+func (s *service) List(sessionID string) ([]Entry, error) {
+	pattern := filepath.Join(s.dir, "*", "*"+metadataSuffix)
+	if sessionID != "" {
+		pattern = filepath.Join(s.sessionDir(sessionID), "*"+metadataSuffix)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	entries := make([]Entry, 0, len(matches))
+	for _, match := range matches {
+		e, err := readEntry(match)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		if a.TrashedAt != b.TrashedAt {
+			return int(b.TrashedAt - a.TrashedAt)
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return entries, nil
+}
+
+func (s *service) Get(id string) (Entry, error) {
+	matches, _ := filepath.Glob(filepath.Join(s.dir, "*", filepath.Base(id)+metadataSuffix))
+	if len(matches) == 0 {
+		return Entry{}, fmt.Errorf("trash entry not found: %s", id)
+	}
+	return readEntry(matches[0])
+}
+
+func (s *service) Restore(id string, force bool) (Entry, error) {
+	e, err := s.Get(id)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Lstat(e.Path); err == nil {
+		if !force {
+			return Entry{}, fmt.Errorf("%s: %w", e.Path, ErrExists)
+		}
+		if err := os.RemoveAll(e.Path); err != nil {
+			return Entry{}, fmt.Errorf("failed to replace %s: %w", e.Path, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(e.Path), 0o755); err != nil {
+		return Entry{}, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := move(e.File, e.Path); err != nil {
+		return Entry{}, fmt.Errorf("failed to restore %s: %w", e.Path, err)
+	}
+	if err := os.Remove(filepath.Join(s.sessionDir(e.SessionID), e.ID+metadataSuffix)); err != nil {
+		return Entry{}, fmt.Errorf("failed to remove trash entry: %w", err)
+	}
+	return e, nil
+}
+
+func (s *service) DeleteSessionTrash(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.RemoveAll(s.sessionDir(sessionID)); err != nil {
+		return fmt.Errorf("failed to delete trash: %w", err)
+	}
+	return nil
+}
+
+// move renames the file, or copies it then removes it when it is on another
+// file system.
+func move(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyPath(src, dst); err != nil {
+		_ = os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyPath copies a file, a symlink or a directory with its content.
+func copyPath(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+func (s *service) Sessions() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() {
+			ids = append(ids, e.Name())
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,62 @@
+package trash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrash(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s := NewService(t.TempDir())
+	file := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(file, []byte("original"), 0o644))
+
+	kept, err := s.Keep("session", file, "write")
+	require.NoError(t, err)
+	require.Equal(t, ReasonOverwritten, kept.Reason)
+	require.Equal(t, int64(len("original")), kept.Size)
+
+	// Only the original is kept.
+	require.NoError(t, os.WriteFile(file, []byte("edited"), 0o644))
+	again, err := s.Keep("session", file, "edit")
+	require.NoError(t, err)
+	require.Equal(t, kept.ID, again.ID)
+
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.MkdirAll(sub, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "a.txt"), []byte("a"), 0o644))
+	deleted, err := s.Delete("other", sub, "bash")
+	require.NoError(t, err)
+	require.True(t, deleted.IsDir)
+	require.NoDirExists(t, sub)
+
+	entries, err := s.List("session")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	entries, err = s.List("")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	_, err = s.Restore(kept.ID, false)
+	require.ErrorIs(t, err, ErrExists)
+	_, err = s.Restore(kept.ID, true)
+	require.NoError(t, err)
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "original", string(data))
+
+	_, err = s.Restore(deleted.ID, false)
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(sub, "a.txt"))
+
+	entries, err = s.List("")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+	_, err = s.Get(kept.ID)
+	require.Error(t, err)
+}
@@ -4,18 +4,22 @@ import (
 	"context"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/llm/tools"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/messages"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/blocks"
 	"github.com/charmbracelet/crush/internal/tui/exp/list"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
@@ -24,12 +28,28 @@ import (
 type SendMsg struct {
 	Text        string
 	Attachments []message.Attachment
+	// EditMessageID is the prompt the message replaces, the conversation
+	// being regenerated from it.
+	EditMessageID string
+}
+
+// EditPromptMsg opens the prompt in the editor, to send it again in place of
+// the original.
+type EditPromptMsg struct {
+	Prompt message.Message
 }
 
 type SessionSelectedMsg = session.Session
 
 type SessionClearedMsg struct{}
 
+// SessionRewoundMsg reloads the session once it was rewound, putting the
+// prompt it was rewound to back in the editor.
+type SessionRewoundMsg struct {
+	Session session.Session
+	Prompt  string
+}
+
 type SelectionCopyMsg struct {
 	clickCount   int
 	endSelection bool
@@ -40,6 +60,18 @@ const (
 	NotFound = -1
 )
 
+const (
+	// lowMemoryPageSize is the number of messages loaded from the database at
+	// a time in low memory mode.
+	lowMemoryPageSize = 50
+	// lowMemoryMaxItems is the number of list items kept in memory before the
+	// oldest ones are dropped in low memory mode.
+	lowMemoryMaxItems = 300
+	// lowMemoryMaxToolResult caps the size of tool results kept in memory in
+	// low memory mode.
+	lowMemoryMaxToolResult = 32 * 1024
+)
+
 // MessageListCmp represents a component that displays a list of chat messages
 // with support for real-time updates and session management.
 type MessageListCmp interface {
@@ -73,6 +105,10 @@ type messageListCmp struct {
 	lastClickY    int
 	clickCount    int
 	promptQueue   int
+
+	// Paging state used in low memory mode.
+	oldestMessageID  string
+	hasOlderMessages bool
 }
 
 // New creates a new message list component with custom keybindings
@@ -128,9 +164,11 @@ func (m *messageListCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if x < 0 || y < 0 || x >= m.width-2 || y >= m.height-1 {
 			return m, nil // Ignore clicks outside the component
 		}
-		if msg.Button == tea.MouseLeft {
+		switch msg.Button {
+		case tea.MouseLeft:
 			cmds = append(cmds, m.handleMouseClick(x, y))
-			return m, tea.Batch(cmds...)
+		case tea.MouseRight:
+			cmds = append(cmds, m.openBlocks(y))
 		}
 		return m, tea.Batch(cmds...)
 	case tea.MouseMotionMsg:
@@ -186,19 +224,35 @@ func (m *messageListCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.listCmp.EndSelection(msg.x, msg.y)
 			}
 			m.listCmp.SelectionStop()
+			// A single click without dragging opens the link under it.
+			if msg.clickCount == 1 && msg.endSelection && !m.listCmp.HasSelection() {
+				if link := linkAt(m.listCmp.LineAt(msg.y), msg.x); link != nil {
+					cmds = append(cmds, m.SelectionClear(), util.CmdHandler(link))
+					return m, tea.Batch(cmds...)
+				}
+			}
 			cmds = append(cmds, m.CopySelectedText(true))
 			return m, tea.Batch(cmds...)
 		}
 	case pubsub.Event[permission.PermissionNotification]:
 		cmds = append(cmds, m.handlePermissionRequest(msg.Payload))
 		return m, tea.Batch(cmds...)
+	case pubsub.Event[tools.ToolOutput]:
+		cmds = append(cmds, m.handleToolOutput(msg.Payload))
+		return m, tea.Batch(cmds...)
 	case SessionSelectedMsg:
 		if msg.ID != m.session.ID {
 			cmds = append(cmds, m.SetSession(msg))
 		}
 		return m, tea.Batch(cmds...)
+	case SessionRewoundMsg:
+		m.session = session.Session{}
+		cmds = append(cmds, m.SetSession(msg.Session))
+		return m, tea.Batch(cmds...)
 	case SessionClearedMsg:
 		m.session = session.Session{}
+		m.oldestMessageID = ""
+		m.hasOlderMessages = false
 		cmds = append(cmds, m.listCmp.SetItems([]list.Item{}))
 		return m, tea.Batch(cmds...)
 
@@ -209,13 +263,13 @@ func (m *messageListCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.MouseWheelMsg:
 		u, cmd := m.listCmp.Update(msg)
 		m.listCmp = u.(list.List[list.Item])
-		cmds = append(cmds, cmd)
+		cmds = append(cmds, cmd, m.loadOlderMessagesIfNeeded())
 		return m, tea.Batch(cmds...)
 	}
 
 	u, cmd := m.listCmp.Update(msg)
 	m.listCmp = u.(list.List[list.Item])
-	cmds = append(cmds, cmd)
+	cmds = append(cmds, cmd, m.loadOlderMessagesIfNeeded())
 	return m, tea.Batch(cmds...)
 }
 
@@ -255,6 +309,22 @@ func (m *messageListCmp) handlePermissionRequest(permission permission.Permissio
 	return nil
 }
 
+// handleToolOutput shows the output of a running tool call of the session.
+func (m *messageListCmp) handleToolOutput(output tools.ToolOutput) tea.Cmd {
+	if output.SessionID != m.session.ID {
+		return nil
+	}
+	items := m.listCmp.Items()
+	toolCallIndex := m.findToolCallByID(items, output.ToolCallID)
+	if toolCallIndex == NotFound {
+		return nil
+	}
+	toolCall := items[toolCallIndex].(messages.ToolCallCmp)
+	cmd := toolCall.SetOutput(output)
+	m.listCmp.UpdateItem(toolCall.ID(), toolCall)
+	return cmd
+}
+
 // handleChildSession handles messages from child sessions (agent tools).
 func (m *messageListCmp) handleChildSession(event pubsub.Event[message.Message]) tea.Cmd {
 	var cmds []tea.Cmd
@@ -302,7 +372,7 @@ func (m *messageListCmp) handleChildSession(event pubsub.Event[message.Message])
 	for _, tr := range event.Payload.ToolResults() {
 		for nestedInx, nestedTC := range nestedToolCalls {
 			if nestedTC.GetToolCall().ID == tr.ToolCallID {
-				nestedToolCalls[nestedInx].SetToolResult(tr)
+				nestedToolCalls[nestedInx].SetToolResult(m.capToolResult(tr))
 				break
 			}
 		}
@@ -369,7 +439,11 @@ func (m *messageListCmp) handleNewMessage(msg message.Message) tea.Cmd {
 // handleNewUserMessage adds a new user message to the list and updates the timestamp.
 func (m *messageListCmp) handleNewUserMessage(msg message.Message) tea.Cmd {
 	m.lastUserMessageTime = msg.CreatedAt
-	return m.listCmp.AppendItem(messages.NewMessageCmp(msg))
+	cmd := m.listCmp.AppendItem(messages.NewMessageCmp(msg))
+	if m.app.Config().LowMemory() {
+		return tea.Batch(cmd, m.trimOldMessages())
+	}
+	return cmd
 }
 
 // handleToolMessage updates existing tool calls with their results.
@@ -378,7 +452,7 @@ func (m *messageListCmp) handleToolMessage(msg message.Message) tea.Cmd {
 	for _, tr := range msg.ToolResults() {
 		if toolCallIndex := m.findToolCallByID(items, tr.ToolCallID); toolCallIndex != NotFound {
 			toolCall := items[toolCallIndex].(messages.ToolCallCmp)
-			toolCall.SetToolResult(tr)
+			toolCall.SetToolResult(m.capToolResult(tr))
 			m.listCmp.UpdateItem(toolCall.ID(), toolCall)
 		}
 	}
@@ -541,7 +615,9 @@ func (m *messageListCmp) SetSession(session session.Session) tea.Cmd {
 	}
 
 	m.session = session
-	sessionMessages, err := m.app.Messages.List(context.Background(), session.ID)
+	m.oldestMessageID = ""
+	m.hasOlderMessages = false
+	sessionMessages, err := m.listSessionMessages(session.ID)
 	if err != nil {
 		return util.ReportError(err)
 	}
@@ -562,12 +638,119 @@ func (m *messageListCmp) SetSession(session session.Session) tea.Cmd {
 	return m.listCmp.SetItems(uiMessages)
 }
 
+// listSessionMessages returns the messages of the session to display. In low
+// memory mode only the latest page is loaded.
+func (m *messageListCmp) listSessionMessages(sessionID string) ([]message.Message, error) {
+	if !m.app.Config().LowMemory() {
+		return m.app.Messages.List(context.Background(), sessionID)
+	}
+	return m.listMessagesPage(sessionID, "")
+}
+
+// listMessagesPage loads a page of messages created before the message with
+// beforeID and updates the paging state.
+func (m *messageListCmp) listMessagesPage(sessionID, beforeID string) ([]message.Message, error) {
+	page, err := m.app.Messages.ListBefore(context.Background(), sessionID, beforeID, lowMemoryPageSize)
+	if err != nil {
+		return nil, err
+	}
+	m.hasOlderMessages = len(page) == lowMemoryPageSize
+	if m.hasOlderMessages {
+		// Start the page at a user message so tool results stay together
+		// with the assistant message that requested them.
+		page = trimToTurnStart(page)
+	}
+	if len(page) > 0 {
+		m.oldestMessageID = page[0].ID
+	}
+	return page, nil
+}
+
+// trimToTurnStart drops the leading messages that belong to a turn started in
+// an earlier page. If the page holds no user message, only the leading tool
+// results are dropped.
+func trimToTurnStart(page []message.Message) []message.Message {
+	for i, msg := range page {
+		if msg.Role == message.User {
+			return page[i:]
+		}
+	}
+	for i, msg := range page {
+		if msg.Role != message.Tool {
+			return page[i:]
+		}
+	}
+	return page
+}
+
+// loadOlderMessagesIfNeeded prepends the previous page of messages when the
+// list is scrolled to the top in low memory mode.
+func (m *messageListCmp) loadOlderMessagesIfNeeded() tea.Cmd {
+	if !m.hasOlderMessages || m.session.ID == "" || m.height == 0 || !m.listCmp.AtTop() {
+		return nil
+	}
+	page, err := m.listMessagesPage(m.session.ID, m.oldestMessageID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+
+	// Converting updates the last user message time, keep the current one.
+	lastUserMessageTime := m.lastUserMessageTime
+	uiMessages := m.convertMessagesToUI(page, m.buildToolResultMap(page))
+	m.lastUserMessageTime = lastUserMessageTime
+
+	cmds := make([]tea.Cmd, 0, len(uiMessages))
+	for i := len(uiMessages) - 1; i >= 0; i-- {
+		cmds = append(cmds, m.listCmp.PrependItem(uiMessages[i]))
+	}
+	return tea.Batch(cmds...)
+}
+
+// trimOldMessages drops the oldest items once the list grows past
+// lowMemoryMaxItems. Items are dropped up to a user message so the remaining
+// list can be paged back in from the database.
+func (m *messageListCmp) trimOldMessages() tea.Cmd {
+	items := m.listCmp.Items()
+	excess := len(items) - lowMemoryMaxItems
+	if excess <= 0 {
+		return nil
+	}
+	for i := excess; i < len(items); i++ {
+		msg, ok := items[i].(messages.MessageCmp)
+		if !ok || msg.GetMessage().Role != message.User {
+			continue
+		}
+		var cmds []tea.Cmd
+		for _, item := range items[:i] {
+			cmds = append(cmds, m.listCmp.DeleteItem(item.ID()))
+		}
+		m.oldestMessageID = msg.GetMessage().ID
+		m.hasOlderMessages = true
+		return tea.Batch(cmds...)
+	}
+	return nil
+}
+
+// capToolResult truncates the tool result content in low memory mode.
+func (m *messageListCmp) capToolResult(tr message.ToolResult) message.ToolResult {
+	if !m.app.Config().LowMemory() || len(tr.Content) <= lowMemoryMaxToolResult {
+		return tr
+	}
+	// Cut at the start of a character, not in the middle of its bytes.
+	cut := lowMemoryMaxToolResult
+	for cut > 0 && !utf8.RuneStart(tr.Content[cut]) {
+		cut--
+	}
+	tr.Content = tr.Content[:cut] + "\n\n(truncated)"
+	return tr
+}
+
 // buildToolResultMap creates a map of tool call ID to tool result for efficient lookup.
 func (m *messageListCmp) buildToolResultMap(messages []message.Message) map[string]message.ToolResult {
 	toolResultMap := make(map[string]message.ToolResult)
 	for _, msg := range messages {
 		for _, tr := range msg.ToolResults() {
-			toolResultMap[tr.ToolCallID] = tr
+			toolResultMap[tr.ToolCallID] = m.capToolResult(tr)
 		}
 	}
 	return toolResultMap
@@ -725,6 +908,27 @@ func (m *messageListCmp) handleMouseClick(x, y int) tea.Cmd {
 	return nil
 }
 
+// openBlocks opens the blocks of the message under the mouse, to copy or save
+// them, with the code block under it selected.
+func (m *messageListCmp) openBlocks(y int) tea.Cmd {
+	item, ok := m.listCmp.ItemAt(y)
+	if !ok {
+		return nil
+	}
+	msgCmp, ok := item.(messages.MessageCmp)
+	if !ok {
+		return nil
+	}
+	msg := msgCmp.GetMessage()
+	content := msg.Content().Text
+	if content == "" {
+		return nil
+	}
+	return util.CmdHandler(dialogs.OpenDialogMsg{
+		Model: blocks.NewBlocksDialogCmp(content, m.listCmp.LineAt(y)),
+	})
+}
+
 // SelectionClear clears the current selection in the list component.
 func (m *messageListCmp) SelectionClear() tea.Cmd {
 	m.listCmp.SelectionClear()
@@ -0,0 +1,196 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/tui/components/chat/messages"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/stretchr/testify/require"
+)
+
+// conversation returns the messages of the turns, each made of a user
+// message, an assistant message calling a tool, its result and the answer.
+func conversation(turns int) []message.Message {
+	var msgs []message.Message
+	for i := range turns {
+		for j, role := range []message.MessageRole{message.User, message.Assistant, message.Tool, message.Assistant} {
+			msg := message.Message{ID: fmt.Sprintf("m%d-%d", i, j), Role: role}
+			if role == message.Tool {
+				msg.Parts = []message.ContentPart{message.ToolResult{ToolCallID: msg.ID, Content: "ok"}}
+			} else {
+				msg.Parts = []message.ContentPart{message.TextContent{Text: "hi"}}
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func ids(msgs []message.Message) []string {
+	result := make([]string, len(msgs))
+	for i, msg := range msgs {
+		result[i] = msg.ID
+	}
+	return result
+}
+
+func TestTrimToTurnStart(t *testing.T) {
+	t.Parallel()
+
+	turn := conversation(2)
+	tests := []struct {
+		name string
+		page []message.Message
+		want []message.Message
+	}{
+		{"empty", nil, nil},
+		{"turn start", turn, turn},
+		{"mid-turn", turn[2:], turn[4:]},
+		{"no user message", turn[2:4], turn[3:4]},
+		{"only tool results", turn[2:3], turn[2:3]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, ids(tt.want), ids(trimToTurnStart(tt.page)))
+		})
+	}
+}
+
+// testMessages pages through the messages like the database does.
+type testMessages struct {
+	message.Service
+	msgs []message.Message
+}
+
+func (s *testMessages) ListBefore(_ context.Context, _, beforeID string, limit int) ([]message.Message, error) {
+	end := len(s.msgs)
+	if beforeID != "" {
+		end = slices.IndexFunc(s.msgs, func(msg message.Message) bool { return msg.ID == beforeID })
+	}
+	return slices.Clone(s.msgs[max(0, end-limit):end]), nil
+}
+
+func newTestMessageList(msgs []message.Message) *messageListCmp {
+	m := New(&app.App{Messages: &testMessages{msgs: msgs}}).(*messageListCmp)
+	m.session = session.Session{ID: "session"}
+	m.SetSize(80, 20)
+	return m
+}
+
+func TestLoadOlderMessagesIfNeeded(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		// older are the messages before the ones displayed.
+		older           []message.Message
+		wantItems       int
+		wantOldest      string
+		wantHasOlder    bool
+		wantSecondItems int
+	}{
+		{
+			name:            "empty page",
+			older:           nil,
+			wantItems:       0,
+			wantOldest:      "displayed",
+			wantHasOlder:    false,
+			wantSecondItems: 0,
+		},
+		{
+			name:            "short page",
+			older:           conversation(3),
+			wantItems:       9,
+			wantOldest:      "m0-0",
+			wantHasOlder:    false,
+			wantSecondItems: 9,
+		},
+		{
+			// 52 messages, the page of 50 starts mid-turn and is trimmed
+			// to the next turn, the rest is loaded next.
+			name:            "page starting mid-turn",
+			older:           conversation(13),
+			wantItems:       36,
+			wantOldest:      "m1-0",
+			wantHasOlder:    true,
+			wantSecondItems: 39,
+		},
+		{
+			// A full page might have older messages, the next empty one
+			// tells there are none.
+			name:            "page size boundary",
+			older:           conversation(13)[:lowMemoryPageSize],
+			wantItems:       38,
+			wantOldest:      "m0-0",
+			wantHasOlder:    true,
+			wantSecondItems: 38,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			displayed := message.Message{ID: "displayed", Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "hi"}}}
+			m := newTestMessageList(append(slices.Clone(tt.older), displayed))
+			m.listCmp.SetItems([]list.Item{messages.NewMessageCmp(displayed)})
+			m.oldestMessageID = displayed.ID
+			m.hasOlderMessages = true
+
+			m.loadOlderMessagesIfNeeded()
+			require.Len(t, m.listCmp.Items(), tt.wantItems+1)
+			require.Equal(t, tt.wantOldest, m.oldestMessageID)
+			require.Equal(t, tt.wantHasOlder, m.hasOlderMessages)
+
+			// The user scrolls up to the oldest messages loaded.
+			m.listCmp.GoToTop()
+			m.loadOlderMessagesIfNeeded()
+			require.Len(t, m.listCmp.Items(), tt.wantSecondItems+1)
+			require.False(t, m.hasOlderMessages)
+		})
+	}
+}
+
+func TestTrimOldMessages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		items        int
+		wantItems    int
+		wantOldest   string
+		wantHasOlder bool
+	}{
+		{"under the limit", lowMemoryMaxItems, lowMemoryMaxItems, "", false},
+		{"at a turn start", lowMemoryMaxItems + 3, lowMemoryMaxItems, "m1-0", true},
+		{"mid-turn", lowMemoryMaxItems + 1, lowMemoryMaxItems - 2, "m1-0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Each turn is displayed as 3 items, the tool result being
+			// shown with its call.
+			var items []list.Item
+			for _, msg := range conversation(tt.items/3 + 1) {
+				if msg.Role != message.Tool {
+					items = append(items, messages.NewMessageCmp(msg))
+				}
+			}
+			items = items[:tt.items]
+			m := newTestMessageList(nil)
+			m.listCmp.SetItems(items)
+
+			m.trimOldMessages()
+			require.Len(t, m.listCmp.Items(), tt.wantItems)
+			require.Equal(t, tt.wantOldest, m.oldestMessageID)
+			require.Equal(t, tt.wantHasOlder, m.hasOlderMessages)
+		})
+	}
+}
@@ -0,0 +1,362 @@
+// Package diffpane implements the side pane of the chat page showing what the
+// agent changed in the session, updated as the edits land.
+package diffpane
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
+	"github.com/charmbracelet/crush/internal/tui/highlight"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Mode is what the pane shows.
+type Mode int
+
+const (
+	// ModeDiff shows the cumulative diff of the files changed in the session.
+	ModeDiff Mode = iota
+	// ModeFile shows the current content of the file changed last.
+	ModeFile
+)
+
+type filesLoadedMsg struct {
+	sessionID string
+	files     []history.File
+}
+
+type DiffPane interface {
+	util.Model
+	layout.Sizeable
+	layout.Focusable
+	layout.Help
+	SetSession(session.Session) tea.Cmd
+	Mode() Mode
+	// ShowFile shows the file at the line, 0 for none, reporting whether the
+	// file changed in the session.
+	ShowFile(path string, line int) bool
+}
+
+// fileHistory holds the first and last versions of a file in the session.
+type fileHistory struct {
+	initial history.File
+	latest  history.File
+}
+
+type diffPaneCmp struct {
+	width, height int
+	history       history.Service
+	session       session.Session
+	keyMap        KeyMap
+	focused       bool
+	mode          Mode
+
+	files []fileHistory // ordered by the last change, oldest first
+	// shown is the file ModeFile shows, the file changed last when empty.
+	shown string
+	lines []string
+	// yOffset is the first line shown.
+	yOffset int
+}
+
+func New(history history.Service) DiffPane {
+	return &diffPaneCmp{
+		history: history,
+		keyMap:  DefaultKeyMap(),
+	}
+}
+
+func (m *diffPaneCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (m *diffPaneCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case filesLoadedMsg:
+		if msg.sessionID != m.session.ID {
+			return m, nil
+		}
+		m.files = nil
+		for _, f := range msg.files {
+			m.addVersion(f)
+		}
+		m.render()
+	case pubsub.Event[history.File]:
+		if msg.Payload.SessionID != m.session.ID {
+			return m, nil
+		}
+		m.addVersion(msg.Payload)
+		m.render()
+	case app.ConfigReloadedMsg:
+		m.keyMap = DefaultKeyMap()
+	case tea.MouseWheelMsg:
+		switch msg.Button {
+		case tea.MouseWheelUp:
+			m.scroll(-3)
+		case tea.MouseWheelDown:
+			m.scroll(3)
+		}
+	case tea.KeyPressMsg:
+		if !m.focused {
+			return m, nil
+		}
+		switch {
+		case key.Matches(msg, m.keyMap.Mode):
+			m.mode = (m.mode + 1) % 2
+			m.shown = ""
+			m.yOffset = 0
+			m.render()
+		case key.Matches(msg, m.keyMap.Up):
+			m.scroll(-1)
+		case key.Matches(msg, m.keyMap.Down):
+			m.scroll(1)
+		case key.Matches(msg, m.keyMap.PageUp):
+			m.scroll(-m.contentHeight())
+		case key.Matches(msg, m.keyMap.PageDown):
+			m.scroll(m.contentHeight())
+		case key.Matches(msg, m.keyMap.Home):
+			m.yOffset = 0
+		case key.Matches(msg, m.keyMap.End):
+			m.scroll(len(m.lines))
+		}
+	}
+	return m, nil
+}
+
+// addVersion records a version of a file, moving the file to the end as the
+// one changed last.
+func (m *diffPaneCmp) addVersion(f history.File) {
+	i := slices.IndexFunc(m.files, func(h fileHistory) bool {
+		return h.initial.Path == f.Path
+	})
+	if i < 0 {
+		m.files = append(m.files, fileHistory{initial: f, latest: f})
+		return
+	}
+	h := m.files[i]
+	switch {
+	case f.Version == 0:
+		h.initial = f
+	case f.Version > h.latest.Version:
+		h.latest = f
+	default:
+		return
+	}
+	m.files = append(slices.Delete(m.files, i, i+1), h)
+}
+
+func (m *diffPaneCmp) scroll(delta int) {
+	maxOffset := max(0, len(m.lines)-m.contentHeight())
+	m.yOffset = min(max(0, m.yOffset+delta), maxOffset)
+}
+
+// render renders the content of the pane into lines, the pane showing the
+// ones in view.
+func (m *diffPaneCmp) render() {
+	m.lines = nil
+	width := m.contentWidth()
+	if width <= 0 || len(m.files) == 0 {
+		return
+	}
+	switch m.mode {
+	case ModeDiff:
+		m.lines = m.renderDiff(width)
+	case ModeFile:
+		m.lines = m.renderFile(width)
+	}
+	m.scroll(0)
+}
+
+func (m *diffPaneCmp) renderDiff(width int) []string {
+	t := styles.CurrentTheme()
+	var lines []string
+	for i := len(m.files) - 1; i >= 0; i-- {
+		h := m.files[i]
+		before, _ := fsext.ToUnixLineEndings(h.initial.Content)
+		after, _ := fsext.ToUnixLineEndings(h.latest.Content)
+		if before == after {
+			continue
+		}
+		path := relPath(h.latest.Path)
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, t.S().Text.Bold(true).Render(ansi.Truncate(path, width, "…")))
+		diff := core.DiffFormatter().
+			Before(path, before).
+			After(path, after).
+			Width(width).
+			Unified().
+			String()
+		lines = append(lines, strings.Split(diff, "\n")...)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, t.S().Subtle.Render("No changes yet"))
+	}
+	return lines
+}
+
+func (m *diffPaneCmp) renderFile(width int) []string {
+	t := styles.CurrentTheme()
+	f := m.files[len(m.files)-1].latest
+	if i := m.fileIndex(m.shown); i >= 0 {
+		f = m.files[i].latest
+	}
+	content, _ := fsext.ToUnixLineEndings(f.Content)
+	content = strings.ReplaceAll(content, "\t", "    ")
+	if !config.Get().LowMemory() {
+		if highlighted, err := highlight.SyntaxHighlight(content, f.Path, t.BgBase); err == nil {
+			content = highlighted
+		}
+	}
+	source := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	lines := make([]string, 0, len(source)+1)
+	lines = append(lines, t.S().Text.Bold(true).Render(ansi.Truncate(relPath(f.Path), width, "…")))
+	numWidth := len(fmt.Sprint(len(source)))
+	for i, line := range source {
+		num := t.S().Subtle.Render(fmt.Sprintf("%*d ", numWidth, i+1))
+		lines = append(lines, ansi.Truncate(num+line, width, ""))
+	}
+	return lines
+}
+
+func (m *diffPaneCmp) fileIndex(path string) int {
+	if path == "" {
+		return -1
+	}
+	return slices.IndexFunc(m.files, func(h fileHistory) bool {
+		return filepath.Clean(h.latest.Path) == filepath.Clean(path)
+	})
+}
+
+func (m *diffPaneCmp) ShowFile(path string, line int) bool {
+	if m.fileIndex(path) < 0 {
+		return false
+	}
+	m.mode = ModeFile
+	m.shown = path
+	m.render()
+	// The name of the file comes first, the line is shown below the line
+	// before it.
+	m.yOffset = 0
+	m.scroll(line - 1)
+	return true
+}
+
+func relPath(path string) string {
+	cwd := config.Get().WorkingDir()
+	return strings.TrimPrefix(strings.TrimPrefix(path, cwd), "/")
+}
+
+func (m *diffPaneCmp) View() string {
+	t := styles.CurrentTheme()
+	title := "Session Diff"
+	if m.mode == ModeFile {
+		title = "Current File"
+	}
+	titleStyle := t.S().Subtle
+	if m.focused {
+		titleStyle = t.S().Base.Foreground(t.Primary)
+	}
+	end := min(len(m.lines), m.yOffset+m.contentHeight())
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		titleStyle.Render(core.Section(title, m.contentWidth())),
+		strings.Join(m.lines[m.yOffset:end], "\n"),
+	)
+	return t.S().Base.
+		Width(m.width).
+		Height(m.height).
+		MaxHeight(m.height).
+		PaddingLeft(1).
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(t.Border).
+		Render(content)
+}
+
+// contentWidth is the width of the pane without the border and the padding.
+func (m *diffPaneCmp) contentWidth() int {
+	return m.width - 2
+}
+
+// contentHeight is the height of the pane without the title.
+func (m *diffPaneCmp) contentHeight() int {
+	return max(0, m.height-1)
+}
+
+func (m *diffPaneCmp) SetSize(width, height int) tea.Cmd {
+	if width != m.width {
+		m.width = width
+		m.height = height
+		m.render()
+		return nil
+	}
+	m.height = height
+	m.scroll(0)
+	return nil
+}
+
+func (m *diffPaneCmp) GetSize() (int, int) {
+	return m.width, m.height
+}
+
+func (m *diffPaneCmp) SetSession(s session.Session) tea.Cmd {
+	m.session = s
+	m.files = nil
+	m.lines = nil
+	m.shown = ""
+	m.yOffset = 0
+	if s.ID == "" {
+		return nil
+	}
+	return m.loadFiles
+}
+
+func (m *diffPaneCmp) loadFiles() tea.Msg {
+	files, err := m.history.ListBySession(context.Background(), m.session.ID)
+	if err != nil {
+		return util.InfoMsg{
+			Type: util.InfoTypeError,
+			Msg:  err.Error(),
+		}
+	}
+	return filesLoadedMsg{sessionID: m.session.ID, files: files}
+}
+
+func (m *diffPaneCmp) Mode() Mode {
+	return m.mode
+}
+
+func (m *diffPaneCmp) Focus() tea.Cmd {
+	m.focused = true
+	return nil
+}
+
+func (m *diffPaneCmp) Blur() tea.Cmd {
+	m.focused = false
+	return nil
+}
+
+func (m *diffPaneCmp) IsFocused() bool {
+	return m.focused
+}
+
+func (m *diffPaneCmp) Bindings() []key.Binding {
+	return m.keyMap.KeyBindings()
+}
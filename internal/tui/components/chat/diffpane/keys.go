@@ -0,0 +1,62 @@
+package diffpane
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
+)
+
+type KeyMap struct {
+	Mode,
+	Up,
+	Down,
+	PageUp,
+	PageDown,
+	Home,
+	End key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return keymap.Apply("diff_pane", KeyMap{
+		Mode: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "diff/file"),
+		),
+		Up: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑", "scroll up"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓", "scroll down"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup", "b"),
+			key.WithHelp("b/pgup", "page up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown", " ", "f"),
+			key.WithHelp("f/pgdn", "page down"),
+		),
+		Home: key.NewBinding(
+			key.WithKeys("g", "home"),
+			key.WithHelp("g", "home"),
+		),
+		End: key.NewBinding(
+			key.WithKeys("G", "end"),
+			key.WithHelp("G", "end"),
+		),
+	})
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Mode,
+		k.Up,
+		k.Down,
+		k.PageUp,
+		k.PageDown,
+		k.Home,
+		k.End,
+	}
+}
@@ -0,0 +1,378 @@
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/document"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/components/completions"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+// Sigils opening the completions, at the start of a word.
+const (
+	// SigilMention completes file paths, agent names and the symbols of the
+	// workspace, attaching the selected file or symbol to the prompt.
+	SigilMention = "@"
+	// SigilCommand completes slash commands at the start of the prompt, and
+	// file paths elsewhere.
+	SigilCommand = "/"
+	// SigilReference completes the recent prompts and the symbols of the
+	// workspace.
+	SigilReference = "#"
+)
+
+const (
+	maxRecentPrompts = 50
+	// recentPromptSessions is how many of the last sessions the recent
+	// prompts are taken from.
+	recentPromptSessions = 10
+	maxPromptTitleLength = 80
+
+	// minSymbolQueryLength avoids asking the language servers for every
+	// symbol of the workspace.
+	minSymbolQueryLength = 2
+	maxSymbolCompletions = 100
+	symbolsTimeout       = 2 * time.Second
+
+	// symbolContextLines is how many lines from its declaration are attached
+	// for a mentioned symbol.
+	symbolContextLines = 40
+)
+
+func isCompletionSigil(s string) bool {
+	return s == SigilMention || s == SigilCommand || s == SigilReference
+}
+
+// AgentCompletionItem is an agent of the configuration, mentioned in the
+// prompt.
+type AgentCompletionItem struct {
+	Name string
+}
+
+// CommandCompletionItem is a custom command, run as a slash command.
+type CommandCompletionItem struct {
+	Command commands.Command
+}
+
+// SymbolCompletionItem is a symbol of the workspace found by a language
+// server.
+type SymbolCompletionItem struct {
+	Symbol app.Symbol
+}
+
+// RecentPromptCompletionItem is a prompt sent in one of the last sessions.
+type RecentPromptCompletionItem struct {
+	Text string
+}
+
+// referencedText is the text inserted for a symbol, with where it is
+// declared so the agent can find it.
+func (s SymbolCompletionItem) referencedText() string {
+	return fmt.Sprintf("%s (%s:%d)", s.Symbol.Name, fsext.PrettyPath(s.Symbol.Path), s.Symbol.Line)
+}
+
+// SymbolCompletionsMsg carries the completions of a reference query, once
+// the language servers answered.
+type SymbolCompletionsMsg struct {
+	Query       string
+	Completions []completions.Completion
+}
+
+// startCompletions opens the completions of the sigil.
+func (m *editorCmp) startCompletions(sigil string, atStart bool) tea.Cmd {
+	return func() tea.Msg {
+		var items []completions.Completion
+		switch sigil {
+		case SigilCommand:
+			// Slash commands are only offered at the start of the prompt.
+			if atStart {
+				items = append(items, commandCompletions()...)
+			}
+			items = append(items, fileCompletions()...)
+		case SigilMention:
+			items = append(items, agentCompletions()...)
+			items = append(items, fileCompletions()...)
+		case SigilReference:
+			items = m.referenceCompletions(context.Background(), "")
+		}
+
+		x, y := m.completionsPosition()
+		return completions.OpenCompletionsMsg{
+			Completions: items,
+			X:           x,
+			Y:           y,
+		}
+	}
+}
+
+// fetchSymbolCompletions asks the language servers for the symbols matching
+// the query of a reference or a mention.
+func (m *editorCmp) fetchSymbolCompletions(sigil, query string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), symbolsTimeout)
+		defer cancel()
+		var items []completions.Completion
+		if sigil == SigilMention {
+			items = append(items, agentCompletions()...)
+			items = append(items, fileCompletions()...)
+			items = append(items, m.symbolCompletions(ctx, query)...)
+		} else {
+			items = m.referenceCompletions(ctx, query)
+		}
+		return SymbolCompletionsMsg{
+			Query:       query,
+			Completions: items,
+		}
+	}
+}
+
+// fileCompletions returns the files of the working directory not ignored by
+// git, the ones changed in the git working tree first.
+func fileCompletions() []completions.Completion {
+	files, _, _ := fsext.ListDirectory(".", nil, 0)
+	for i, file := range files {
+		files[i] = strings.TrimPrefix(file, "./")
+	}
+	slices.Sort(files)
+	changed := gitChangedFiles()
+	slices.SortStableFunc(files, func(a, b string) int {
+		switch ac, bc := changed[a], changed[b]; {
+		case ac && !bc:
+			return -1
+		case !ac && bc:
+			return 1
+		}
+		return 0
+	})
+	items := make([]completions.Completion, 0, len(files))
+	for _, file := range files {
+		items = append(items, completions.Completion{
+			Title: file,
+			Value: FileCompletionItem{
+				Path: file,
+			},
+		})
+	}
+	return items
+}
+
+// gitChangedFiles returns the files modified, added or untracked in the git
+// working tree, relative to the working directory. It is empty outside of a
+// git repository.
+func gitChangedFiles() map[string]bool {
+	changed := make(map[string]bool)
+	out, err := exec.Command("git", "status", "--porcelain", "--untracked-files=all", ".").Output()
+	if err != nil {
+		return changed
+	}
+	root, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return changed
+	}
+	cwd, _ := os.Getwd()
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) < 4 {
+			continue
+		}
+		path := line[3:]
+		// Renames are listed as "old -> new".
+		if _, after, ok := strings.Cut(path, " -> "); ok {
+			path = after
+		}
+		rel, err := filepath.Rel(cwd, filepath.Join(strings.TrimSpace(string(root)), strings.Trim(path, `"`)))
+		if err == nil {
+			changed[filepath.ToSlash(rel)] = true
+		}
+	}
+	return changed
+}
+
+func agentCompletions() []completions.Completion {
+	cfg := config.Get()
+	names := make([]string, 0, len(cfg.Agents))
+	for _, a := range cfg.Agents {
+		if !a.Disabled {
+			names = append(names, a.ID)
+		}
+	}
+	slices.Sort(names)
+	items := make([]completions.Completion, 0, len(names))
+	for _, name := range names {
+		items = append(items, completions.Completion{
+			Title: SigilMention + name,
+			Value: AgentCompletionItem{Name: name},
+		})
+	}
+	return items
+}
+
+// commandCompletions returns the built-in slash commands, the custom
+// commands and the prompts of the MCP servers.
+func commandCompletions() []completions.Completion {
+	var items []completions.Completion
+	for _, command := range []commands.Command{commands.InitCommand(), commands.RewindCommand(), commands.EditPromptCommand(), commands.PinCommand(), commands.UnpinCommand(), commands.StatusCommand()} {
+		items = append(items, completions.Completion{
+			Title: SigilCommand + command.ID,
+			Value: CommandCompletionItem{Command: command},
+		})
+	}
+	custom, _ := commands.LoadCustomCommands()
+	for _, command := range custom {
+		items = append(items, completions.Completion{
+			Title: SigilCommand + command.ID,
+			Value: CommandCompletionItem{Command: command},
+		})
+	}
+	for _, prompt := range agent.MCPPrompts() {
+		items = append(items, completions.Completion{
+			Title: prompt.ID(),
+			Value: PromptCompletionItem{Prompt: prompt},
+		})
+	}
+	return items
+}
+
+// referenceCompletions returns the recent prompts, then the symbols matching
+// the query when it is long enough.
+func (m *editorCmp) referenceCompletions(ctx context.Context, query string) []completions.Completion {
+	items := recentPromptCompletions(ctx, m.app)
+	if len([]rune(query)) < minSymbolQueryLength {
+		return items
+	}
+	return append(items, m.symbolCompletions(ctx, query)...)
+}
+
+// symbolCompletions returns the symbols of the workspace matching the query.
+func (m *editorCmp) symbolCompletions(ctx context.Context, query string) []completions.Completion {
+	symbols := m.app.WorkspaceSymbols(ctx, query)
+	items := make([]completions.Completion, 0, min(len(symbols), maxSymbolCompletions))
+	for _, s := range symbols[:min(len(symbols), maxSymbolCompletions)] {
+		item := SymbolCompletionItem{Symbol: s}
+		items = append(items, completions.Completion{
+			Title: item.referencedText(),
+			Value: item,
+		})
+	}
+	return items
+}
+
+// attachFile attaches a mentioned file to the prompt, when it is text, an
+// image or a document.
+func attachFile(path string) tea.Cmd {
+	return func() tea.Msg {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if info.Size() > filepicker.MaxAttachmentSize {
+			return util.InfoMsg{Type: util.InfoTypeWarn, Msg: fmt.Sprintf("%s is too large to be attached", path)}
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		if document.Is(path) {
+			attachment, err := filepicker.NewAttachment(path, content)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return filepicker.FilePickedMsg{Attachment: attachment}
+		}
+		mimeType := http.DetectContentType(content[:min(512, len(content))])
+		if !message.IsTextMIMEType(mimeType) && !strings.HasPrefix(mimeType, "image/") {
+			return util.InfoMsg{Type: util.InfoTypeWarn, Msg: fmt.Sprintf("%s is neither text, an image nor a document, it isn't attached", path)}
+		}
+		return filepicker.FilePickedMsg{Attachment: message.Attachment{
+			FilePath: path,
+			FileName: filepath.Base(path),
+			MimeType: mimeType,
+			Content:  content,
+		}}
+	}
+}
+
+// attachSymbol attaches the source of a mentioned symbol to the prompt, from
+// its declaration.
+func attachSymbol(s app.Symbol) tea.Cmd {
+	return func() tea.Msg {
+		content, err := os.ReadFile(s.Path)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		lines := strings.Split(string(content), "\n")
+		start := min(max(0, s.Line-1), len(lines))
+		end := min(start+symbolContextLines, len(lines))
+		return filepicker.FilePickedMsg{Attachment: message.Attachment{
+			FilePath: fmt.Sprintf("%s:%d", s.Path, s.Line),
+			FileName: s.Name,
+			MimeType: "text/plain",
+			Content:  []byte(strings.Join(lines[start:end], "\n")),
+		}}
+	}
+}
+
+// recentPromptCompletions returns the prompts of the last sessions, most
+// recent first and without duplicates.
+func recentPromptCompletions(ctx context.Context, a *app.App) []completions.Completion {
+	sessions, err := a.Sessions.List(ctx)
+	if err != nil {
+		return nil
+	}
+	var items []completions.Completion
+	seen := make(map[string]bool)
+	for _, s := range sessions[:min(len(sessions), recentPromptSessions)] {
+		msgs, err := a.Messages.List(ctx, s.ID)
+		if err != nil {
+			continue
+		}
+		for _, msg := range slices.Backward(msgs) {
+			if msg.Role != message.User {
+				continue
+			}
+			text := strings.TrimSpace(msg.Content().Text)
+			if text == "" || seen[text] {
+				continue
+			}
+			seen[text] = true
+			items = append(items, completions.Completion{
+				Title: promptTitle(text),
+				Value: RecentPromptCompletionItem{Text: text},
+			})
+			if len(items) == maxRecentPrompts {
+				return items
+			}
+		}
+	}
+	return items
+}
+
+// promptTitle is the first line of a prompt, shortened.
+func promptTitle(text string) string {
+	title, _, multiline := strings.Cut(text, "\n")
+	if r := []rune(title); len(r) > maxPromptTitleLength {
+		title = string(r[:maxPromptTitleLength-1]) + "…"
+	} else if multiline {
+		title += " …"
+	}
+	return SigilReference + " " + title
+}
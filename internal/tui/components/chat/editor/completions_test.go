@@ -0,0 +1,69 @@
+package editor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "test")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "committed.go"), []byte("package a\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "edited.go"), []byte("package a\n"), 0o644))
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "edited.go"), []byte("package b\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "new.go"), []byte("package a\n"), 0o644))
+
+	t.Chdir(filepath.Join(dir, "sub"))
+	require.Equal(t, map[string]bool{"edited.go": true, "new.go": true}, gitChangedFiles())
+
+	var paths []string
+	for _, item := range fileCompletions() {
+		paths = append(paths, item.Value.(FileCompletionItem).Path)
+	}
+	require.Equal(t, []string{"edited.go", "new.go"}, paths)
+}
+
+func TestAttachSymbol(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc main() {\n}\n"), 0o644))
+
+	msg := attachSymbol(app.Symbol{Name: "main", Path: path, Line: 3})()
+	picked, ok := msg.(filepicker.FilePickedMsg)
+	require.True(t, ok)
+	require.Equal(t, "main", picked.Attachment.FileName)
+	require.Equal(t, path+":3", picked.Attachment.FilePath)
+	require.Equal(t, "text/plain", picked.Attachment.MimeType)
+	require.Equal(t, "func main() {\n}\n", string(picked.Attachment.Content))
+}
+
+func TestAttachFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.md")
+	require.NoError(t, os.WriteFile(path, []byte("# Notes\n"), 0o644))
+
+	picked, ok := attachFile(path)().(filepicker.FilePickedMsg)
+	require.True(t, ok)
+	require.Equal(t, "notes.md", picked.Attachment.FileName)
+	require.Equal(t, "# Notes\n", string(picked.Attachment.Content))
+
+	require.Nil(t, attachFile(dir)())
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -17,8 +16,9 @@ import (
 	"github.com/charmbracelet/bubbles/v2/textarea"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/app"
-	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/llm/agent"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
@@ -27,6 +27,7 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/quit"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/lipgloss/v2"
@@ -49,6 +50,11 @@ type FileCompletionItem struct {
 	Path string // The file path
 }
 
+// PromptCompletionItem is a prompt of an MCP server, run as a slash command.
+type PromptCompletionItem struct {
+	Prompt agent.MCPPrompt
+}
+
 type editorCmp struct {
 	width              int
 	height             int
@@ -60,13 +66,24 @@ type editorCmp struct {
 	deleteMode         bool
 	readyPlaceholder   string
 	workingPlaceholder string
+	// editing is the ID of the prompt being edited, replaced by the message
+	// once sent.
+	editing string
 
 	keyMap EditorKeyMap
 
-	// File path completions
+	// Vim mode
+	vim        bool
+	vimNormal  bool
+	vimPending string
+
+	// Completions
 	currentQuery          string
 	completionsStartIndex int
 	isCompletionsOpen     bool
+	// completionsSigil is the sigil that opened the completions, one of
+	// SigilMention, SigilCommand or SigilReference.
+	completionsSigil string
 }
 
 var DeleteKeyMaps = DeleteAttachmentKeyMaps{
@@ -92,6 +109,12 @@ type OpenEditorMsg struct {
 	Text string
 }
 
+// ResourceRefreshedMsg replaces the attachments of an MCP resource that
+// changed since it was attached.
+type ResourceRefreshedMsg struct {
+	Attachment message.Attachment
+}
+
 func (m *editorCmp) openEditor(value string) tea.Cmd {
 	editor := os.Getenv("EDITOR")
 	if editor == "" {
@@ -149,8 +172,10 @@ func (m *editorCmp) send() tea.Cmd {
 
 	m.textarea.Reset()
 	attachments := m.attachments
+	editing := m.editing
 
 	m.attachments = nil
+	m.editing = ""
 	if value == "" {
 		return nil
 	}
@@ -160,12 +185,30 @@ func (m *editorCmp) send() tea.Cmd {
 
 	return tea.Batch(
 		util.CmdHandler(chat.SendMsg{
-			Text:        value,
-			Attachments: attachments,
+			Text:          value,
+			Attachments:   attachments,
+			EditMessageID: editing,
 		}),
 	)
 }
 
+func (m *editorCmp) attachmentIndex(path string) int {
+	return slices.IndexFunc(m.attachments, func(a message.Attachment) bool {
+		return a.FilePath == path
+	})
+}
+
+// refreshResource reads an attached MCP resource again after it changed.
+func refreshResource(server, uri string) tea.Cmd {
+	return func() tea.Msg {
+		attachment, err := agent.ReadMCPResource(context.Background(), server, uri)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeWarn, Msg: fmt.Sprintf("failed to refresh %s: %v", uri, err)}
+		}
+		return ResourceRefreshedMsg{Attachment: attachment}
+	}
+}
+
 func (m *editorCmp) repositionCompletions() tea.Msg {
 	x, y := m.completionsPosition()
 	return completions.RepositionCompletionsMsg{X: x, Y: y}
@@ -178,35 +221,79 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		return m, m.repositionCompletions
 	case filepicker.FilePickedMsg:
+		// Attaching a resource again replaces it.
+		if i := m.attachmentIndex(msg.Attachment.FilePath); i >= 0 {
+			m.attachments[i] = msg.Attachment
+			return m, nil
+		}
 		if len(m.attachments) >= maxAttachments {
-			return m, util.ReportError(fmt.Errorf("cannot add more than %d images", maxAttachments))
+			return m, util.ReportError(fmt.Errorf("cannot add more than %d attachments", maxAttachments))
 		}
 		m.attachments = append(m.attachments, msg.Attachment)
 		return m, nil
+	case pubsub.Event[agent.MCPEvent]:
+		if msg.Payload.Type == agent.MCPEventResourceUpdated && m.attachmentIndex(msg.Payload.URI) >= 0 {
+			return m, refreshResource(msg.Payload.Name, msg.Payload.URI)
+		}
+		return m, nil
+	case ResourceRefreshedMsg:
+		if i := m.attachmentIndex(msg.Attachment.FilePath); i >= 0 {
+			m.attachments[i] = msg.Attachment
+		}
+		return m, nil
 	case completions.CompletionsOpenedMsg:
 		m.isCompletionsOpen = true
 	case completions.CompletionsClosedMsg:
 		m.isCompletionsOpen = false
 		m.currentQuery = ""
 		m.completionsStartIndex = 0
+	case SymbolCompletionsMsg:
+		// Only the answer to the current query is shown.
+		if !m.isCompletionsOpen || (m.completionsSigil != SigilReference && m.completionsSigil != SigilMention) || msg.Query != m.currentQuery {
+			return m, nil
+		}
+		x, y := m.completionsPosition()
+		return m, tea.Sequence(
+			util.CmdHandler(completions.OpenCompletionsMsg{Completions: msg.Completions, X: x, Y: y}),
+			util.CmdHandler(completions.FilterCompletionsMsg{Query: msg.Query, Reopen: true, X: x - len(msg.Query), Y: y}),
+		)
 	case completions.SelectCompletionMsg:
 		if !m.isCompletionsOpen {
 			return m, nil
 		}
-		if item, ok := msg.Value.(FileCompletionItem); ok {
-			word := m.textarea.Word()
-			// If the selected item is a file, insert its path into the textarea
-			value := m.textarea.Value()
-			value = value[:m.completionsStartIndex] + // Remove the current query
-				item.Path + // Insert the file path
-				value[m.completionsStartIndex+len(word):] // Append the rest of the value
-			// XXX: This will always move the cursor to the end of the textarea.
-			m.textarea.SetValue(value)
-			m.textarea.MoveToEnd()
+		switch item := msg.Value.(type) {
+		case FileCompletionItem:
+			if m.completionsSigil == SigilMention {
+				m.insertCompletion(SigilMention+item.Path, msg.Insert)
+				if !msg.Insert {
+					return m, attachFile(item.Path)
+				}
+				break
+			}
+			m.insertCompletion(item.Path, msg.Insert)
+		case AgentCompletionItem:
+			m.insertCompletion(SigilMention+item.Name, msg.Insert)
+		case SymbolCompletionItem:
+			if m.completionsSigil == SigilMention {
+				m.insertCompletion(SigilMention+item.Symbol.Name, msg.Insert)
+				if !msg.Insert {
+					return m, attachSymbol(item.Symbol)
+				}
+				break
+			}
+			m.insertCompletion(item.referencedText(), msg.Insert)
+		case RecentPromptCompletionItem:
+			m.insertCompletion(item.Text, msg.Insert)
+		case PromptCompletionItem:
 			if !msg.Insert {
-				m.isCompletionsOpen = false
-				m.currentQuery = ""
-				m.completionsStartIndex = 0
+				// Replace the slash command with the prompt.
+				m.insertCompletion("", false)
+				return m, commands.RunMCPPrompt(item.Prompt)
+			}
+		case CommandCompletionItem:
+			if !msg.Insert {
+				m.insertCompletion("", false)
+				return m, item.Command.Handler(item.Command)
 			}
 		}
 
@@ -218,6 +305,20 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case OpenEditorMsg:
 		m.textarea.SetValue(msg.Text)
 		m.textarea.MoveToEnd()
+	case chat.EditPromptMsg:
+		m.editing = msg.Prompt.ID
+		m.attachments = nil
+		for _, bc := range msg.Prompt.BinaryContent() {
+			m.attachments = append(m.attachments, message.Attachment{
+				FilePath: bc.Path,
+				FileName: filepath.Base(bc.Path),
+				MimeType: bc.MIMEType,
+				Content:  bc.Data,
+			})
+		}
+		m.textarea.SetValue(msg.Prompt.Content().Text)
+		m.textarea.MoveToEnd()
+		return m, nil
 	case tea.PasteMsg:
 		path := strings.ReplaceAll(string(msg), "\\ ", " ")
 		// try to get an image
@@ -248,29 +349,46 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.textarea, cmd = m.textarea.Update(msg)
 			return m, cmd
 		}
-		mimeBufferSize := min(512, len(content))
-		mimeType := http.DetectContentType(content[:mimeBufferSize])
-		fileName := filepath.Base(path)
-		attachment := message.Attachment{FilePath: path, FileName: fileName, MimeType: mimeType, Content: content}
+		attachment, err := filepicker.NewAttachment(path, content)
+		if err != nil {
+			return m, util.ReportError(err)
+		}
 		return m, util.CmdHandler(filepicker.FilePickedMsg{
 			Attachment: attachment,
 		})
 
-	case commands.ToggleYoloModeMsg:
+	case commands.ToggleYoloModeMsg, commands.TogglePlanModeMsg:
 		m.setEditorPrompt()
 		return m, nil
+	case app.ConfigReloadedMsg:
+		m.keyMap = DefaultEditorKeyMap()
+		m.vim = keymap.Vim()
+		if !m.vim && m.vimNormal {
+			m.setVimNormal(false)
+		}
+		return m, nil
 	case tea.KeyPressMsg:
+		if m.vim && !m.isCompletionsOpen && !m.deleteMode {
+			if m.vimNormal && m.handleNormalMode(msg) {
+				return m, nil
+			}
+			if !m.vimNormal && key.Matches(msg, m.keyMap.VimNormal) {
+				m.setVimNormal(true)
+				return m, nil
+			}
+		}
 		cur := m.textarea.Cursor()
 		curIdx := m.textarea.Width()*cur.Y + cur.X
 		switch {
 		// Completions
-		case msg.String() == "/" && !m.isCompletionsOpen &&
+		case isCompletionSigil(msg.String()) && !m.isCompletionsOpen &&
 			// only show if beginning of prompt, or if previous char is a space or newline:
 			(len(m.textarea.Value()) == 0 || unicode.IsSpace(rune(m.textarea.Value()[len(m.textarea.Value())-1]))):
 			m.isCompletionsOpen = true
 			m.currentQuery = ""
 			m.completionsStartIndex = curIdx
-			cmds = append(cmds, m.startCompletions)
+			m.completionsSigil = msg.String()
+			cmds = append(cmds, m.startCompletions(m.completionsSigil, curIdx == 0))
 		case m.isCompletionsOpen && curIdx <= m.completionsStartIndex:
 			cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
 		}
@@ -303,6 +421,12 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, m.openEditor(m.textarea.Value())
 		}
 		if key.Matches(msg, DeleteKeyMaps.Escape) {
+			if m.editing != "" && !m.deleteMode {
+				m.editing = ""
+				m.attachments = nil
+				m.textarea.Reset()
+				return m, util.ReportInfo("Prompt edit canceled")
+			}
 			m.deleteMode = false
 			return m, nil
 		}
@@ -336,10 +460,14 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
 			} else {
 				word := m.textarea.Word()
-				if strings.HasPrefix(word, "/") {
+				if m.completionsSigil != "" && strings.HasPrefix(word, m.completionsSigil) {
 					// XXX: wont' work if editing in the middle of the field.
 					m.completionsStartIndex = strings.LastIndex(m.textarea.Value(), word)
-					m.currentQuery = word[1:]
+					query := word[len(m.completionsSigil):]
+					if (m.completionsSigil == SigilReference || m.completionsSigil == SigilMention) && query != m.currentQuery && len([]rune(query)) >= minSymbolQueryLength {
+						cmds = append(cmds, m.fetchSymbolCompletions(m.completionsSigil, query))
+					}
+					m.currentQuery = query
 					x, y := m.completionsPosition()
 					x -= len(m.currentQuery)
 					m.isCompletionsOpen = true
@@ -365,6 +493,10 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *editorCmp) setEditorPrompt() {
+	if m.vimNormal {
+		m.textarea.SetPromptFunc(4, vimNormalPromptFunc)
+		return
+	}
 	if m.app.Permissions.SkipRequests() {
 		m.textarea.SetPromptFunc(4, yoloPromptFunc)
 		return
@@ -423,15 +555,27 @@ func (m *editorCmp) View() string {
 	if m.app.Permissions.SkipRequests() {
 		m.textarea.Placeholder = "Yolo mode!"
 	}
-	if len(m.attachments) == 0 {
+	if m.app.CoderAgent != nil && m.app.CoderAgent.PlanMode() {
+		m.textarea.Placeholder = "Plan mode: changes are proposed, not applied"
+	}
+	if len(m.attachments) == 0 && m.editing == "" {
 		content := t.S().Base.Padding(1).Render(
 			m.textarea.View(),
 		)
 		return content
 	}
+	header := m.attachmentsContent()
+	if m.editing != "" {
+		// The notice shares the line of the attachments to keep the height
+		// of the editor.
+		header = lipgloss.JoinHorizontal(lipgloss.Left,
+			header,
+			t.S().Base.Foreground(t.FgMuted).PaddingLeft(1).Render("Editing a prompt, what follows it is regenerated on send (esc to cancel)"),
+		)
+	}
 	content := t.S().Base.Padding(0, 1, 1, 1).Render(
 		lipgloss.JoinVertical(lipgloss.Top,
-			m.attachmentsContent(),
+			header,
 			m.textarea.View(),
 		),
 	)
@@ -479,25 +623,21 @@ func (m *editorCmp) SetPosition(x, y int) tea.Cmd {
 	return nil
 }
 
-func (m *editorCmp) startCompletions() tea.Msg {
-	files, _, _ := fsext.ListDirectory(".", nil, 0)
-	slices.Sort(files)
-	completionItems := make([]completions.Completion, 0, len(files))
-	for _, file := range files {
-		file = strings.TrimPrefix(file, "./")
-		completionItems = append(completionItems, completions.Completion{
-			Title: file,
-			Value: FileCompletionItem{
-				Path: file,
-			},
-		})
-	}
-
-	x, y := m.completionsPosition()
-	return completions.OpenCompletionsMsg{
-		Completions: completionItems,
-		X:           x,
-		Y:           y,
+// insertCompletion replaces the word being completed, with its sigil, by the
+// text. Unless insert is set, the completions are closed.
+func (m *editorCmp) insertCompletion(text string, insert bool) {
+	word := m.textarea.Word()
+	value := m.textarea.Value()
+	value = value[:m.completionsStartIndex] + // Remove the current query
+		text +
+		value[m.completionsStartIndex+len(word):] // Append the rest of the value
+	// XXX: This will always move the cursor to the end of the textarea.
+	m.textarea.SetValue(value)
+	m.textarea.MoveToEnd()
+	if !insert {
+		m.isCompletionsOpen = false
+		m.currentQuery = ""
+		m.completionsStartIndex = 0
 	}
 }
 
@@ -525,6 +665,10 @@ func (c *editorCmp) Bindings() []key.Binding {
 // TODO: most likely we do not need to have the session here
 // we need to move some functionality to the page level
 func (c *editorCmp) SetSession(session session.Session) tea.Cmd {
+	if session.ID != c.session.ID {
+		// The prompt being edited belongs to the previous session.
+		c.editing = ""
+	}
 	c.session = session
 	return nil
 }
@@ -576,6 +720,7 @@ func New(app *app.App) Editor {
 		app:      app,
 		textarea: ta,
 		keyMap:   DefaultEditorKeyMap(),
+		vim:      keymap.Vim(),
 	}
 	e.setEditorPrompt()
 
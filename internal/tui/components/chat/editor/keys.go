@@ -2,6 +2,7 @@ package editor
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 type EditorKeyMap struct {
@@ -9,10 +10,12 @@ type EditorKeyMap struct {
 	SendMessage key.Binding
 	OpenEditor  key.Binding
 	Newline     key.Binding
+	// VimNormal switches to the normal mode when vim mode is enabled.
+	VimNormal key.Binding
 }
 
 func DefaultEditorKeyMap() EditorKeyMap {
-	return EditorKeyMap{
+	return keymap.Apply("editor", EditorKeyMap{
 		AddFile: key.NewBinding(
 			key.WithKeys("/"),
 			key.WithHelp("/", "add file"),
@@ -32,7 +35,11 @@ func DefaultEditorKeyMap() EditorKeyMap {
 			// to reflect that.
 			key.WithHelp("ctrl+j", "newline"),
 		),
-	}
+		VimNormal: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "normal mode"),
+		),
+	})
 }
 
 // KeyBindings implements layout.KeyMapProvider
@@ -0,0 +1,94 @@
+package editor
+
+import (
+	"github.com/charmbracelet/bubbles/v2/textarea"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+)
+
+// vimMotions are the keys of the normal mode, as the keys of the textarea
+// doing the same.
+var vimMotions = map[string][]tea.KeyPressMsg{
+	"h":  {{Code: tea.KeyLeft}},
+	"l":  {{Code: tea.KeyRight}},
+	"j":  {{Code: tea.KeyDown}},
+	"k":  {{Code: tea.KeyUp}},
+	"w":  {{Code: tea.KeyRight, Mod: tea.ModAlt}},
+	"e":  {{Code: tea.KeyRight, Mod: tea.ModAlt}},
+	"b":  {{Code: tea.KeyLeft, Mod: tea.ModAlt}},
+	"0":  {{Code: tea.KeyHome}},
+	"^":  {{Code: tea.KeyHome}},
+	"$":  {{Code: tea.KeyEnd}},
+	"gg": {{Code: tea.KeyHome, Mod: tea.ModCtrl}},
+	"G":  {{Code: tea.KeyEnd, Mod: tea.ModCtrl}},
+	"x":  {{Code: tea.KeyDelete}},
+	"X":  {{Code: tea.KeyBackspace}},
+	"D":  {{Code: 'k', Mod: tea.ModCtrl}},
+	"dw": {{Code: tea.KeyDelete, Mod: tea.ModAlt}},
+	"db": {{Code: tea.KeyBackspace, Mod: tea.ModAlt}},
+	"dd": {{Code: tea.KeyHome}, {Code: 'k', Mod: tea.ModCtrl}, {Code: tea.KeyDelete}},
+}
+
+// vimInserts are the keys of the normal mode switching to the insert mode,
+// after the keys of the textarea moving the cursor where to insert.
+var vimInserts = map[string][]tea.KeyPressMsg{
+	"i": nil,
+	"a": {{Code: tea.KeyRight}},
+	"I": {{Code: tea.KeyHome}},
+	"A": {{Code: tea.KeyEnd}},
+	"C": {{Code: 'k', Mod: tea.ModCtrl}},
+	"S": {{Code: tea.KeyHome}, {Code: 'k', Mod: tea.ModCtrl}},
+	"o": {{Code: tea.KeyEnd}, {Code: tea.KeyEnter}},
+	"O": {{Code: tea.KeyHome}, {Code: tea.KeyEnter}, {Code: tea.KeyUp}},
+}
+
+// vimPending are the keys starting a motion of two keys.
+var vimPending = map[string]bool{"g": true, "d": true}
+
+// handleNormalMode handles a key of the vim normal mode, returning false for
+// the keys left to the editor like enter or the ctrl bindings.
+func (m *editorCmp) handleNormalMode(msg tea.KeyPressMsg) bool {
+	k := msg.String()
+	if m.vimPending != "" {
+		k = m.vimPending + k
+		m.vimPending = ""
+	} else if vimPending[k] {
+		m.vimPending = k
+		return true
+	}
+	if keys, ok := vimMotions[k]; ok {
+		m.sendKeys(keys)
+		return true
+	}
+	if keys, ok := vimInserts[k]; ok {
+		m.sendKeys(keys)
+		m.setVimNormal(false)
+		return true
+	}
+	// Swallow the other printable keys rather than inserting them.
+	return msg.Text != ""
+}
+
+func (m *editorCmp) sendKeys(keys []tea.KeyPressMsg) {
+	for _, k := range keys {
+		if k.Code == tea.KeyEnter {
+			m.textarea.InsertRune('\n')
+			continue
+		}
+		m.textarea, _ = m.textarea.Update(k)
+	}
+}
+
+func (m *editorCmp) setVimNormal(normal bool) {
+	m.vimNormal = normal
+	m.vimPending = ""
+	m.setEditorPrompt()
+}
+
+func vimNormalPromptFunc(info textarea.PromptInfo) string {
+	t := styles.CurrentTheme()
+	if info.LineNumber == 0 {
+		return t.S().Base.Foreground(t.Secondary).Render("  N ")
+	}
+	return t.S().Muted.Render("::: ")
+}
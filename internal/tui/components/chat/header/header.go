@@ -7,10 +7,12 @@ import (
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/llm/tools"
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/lsp/protocol"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/tui/components/checklist"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/lipgloss/v2"
@@ -30,6 +32,7 @@ type header struct {
 	session     session.Session
 	lspClients  map[string]*lsp.Client
 	detailsOpen bool
+	plan        []tools.PlanStep
 }
 
 func New(lspClients map[string]*lsp.Client) Header {
@@ -45,6 +48,10 @@ func (h *header) Init() tea.Cmd {
 
 func (h *header) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case checklist.PlanUpdatedMsg:
+		if h.session.ID == msg.SessionID {
+			h.plan = msg.Steps
+		}
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent {
 			if h.session.ID == msg.Payload.ID {
@@ -118,6 +125,16 @@ func (h *header) details(availWidth int) string {
 		parts = append(parts, s.Error.Render(fmt.Sprintf("%s%d", styles.ErrorIcon, errorCount)))
 	}
 
+	if len(h.plan) > 0 {
+		completed, total := checklist.Progress(h.plan)
+		progress := fmt.Sprintf("%s %d/%d", styles.CheckIcon, completed, total)
+		if completed == total {
+			parts = append(parts, s.Success.Render(progress))
+		} else {
+			parts = append(parts, s.Muted.Render(progress))
+		}
+	}
+
 	agentCfg := config.Get().Agents["coder"]
 	model := config.Get().GetModelByType(agentCfg.Model)
 	percentage := (float64(h.session.CompletionTokens+h.session.PromptTokens) / float64(model.ContextWindow)) * 100
@@ -151,6 +168,7 @@ func (h *header) SetDetailsOpen(open bool) {
 // SetSession implements Header.
 func (h *header) SetSession(session session.Session) tea.Cmd {
 	h.session = session
+	h.plan = nil
 	return nil
 }
 
@@ -0,0 +1,116 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/rivo/uniseg"
+)
+
+// OpenFileMsg asks to show a file clicked in the chat, in the diff pane when
+// it shows the file or in the editor.
+type OpenFileMsg struct {
+	Path string
+	// Line is the line the path pointed at, 0 for none.
+	Line int
+}
+
+// OpenURLMsg asks to open a URL clicked in the chat in the browser.
+type OpenURLMsg struct {
+	URL string
+}
+
+// linkTrim are the characters around the links of the text, like the
+// brackets of markdown links and the punctuation ending sentences.
+const linkTrim = "()[]{}<>\"'`,;.!?*"
+
+// linkAt returns the message opening the URL or the existing file under the
+// cell column of the line, nil when there is none.
+func linkAt(line string, col int) any {
+	word := wordAt(line, col)
+	word = strings.Trim(word, linkTrim)
+	if word == "" {
+		return nil
+	}
+	if strings.HasPrefix(word, "http://") || strings.HasPrefix(word, "https://") {
+		return OpenURLMsg{URL: word}
+	}
+
+	path, lineNum := splitLine(strings.TrimSuffix(word, ":"))
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		cfg := config.Get()
+		if cfg == nil {
+			return nil
+		}
+		path = filepath.Join(cfg.WorkingDir(), path)
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return nil
+	}
+	return OpenFileMsg{Path: path, Line: lineNum}
+}
+
+// wordAt returns the space delimited word under the cell column of the line.
+func wordAt(line string, col int) string {
+	if col < 0 || col >= ansi.StringWidth(line) {
+		return ""
+	}
+	var word strings.Builder
+	start, pos := 0, 0
+	gr := uniseg.NewGraphemes(line)
+	for gr.Next() {
+		cluster := gr.Str()
+		width := ansi.StringWidth(cluster)
+		if strings.TrimSpace(cluster) == "" {
+			if col >= start && col < pos {
+				return word.String()
+			}
+			word.Reset()
+			pos += width
+			start = pos
+			continue
+		}
+		word.WriteString(cluster)
+		pos += width
+	}
+	if col >= start && col < pos {
+		return word.String()
+	}
+	return ""
+}
+
+// splitLine splits the line number, and the column, from paths like
+// main.go:12 or main.go:12:4.
+func splitLine(path string) (string, int) {
+	rest, last, ok := cutLastColon(path)
+	if !ok {
+		return path, 0
+	}
+	n, err := strconv.Atoi(last)
+	if err != nil {
+		return path, 0
+	}
+	if before, prev, ok := cutLastColon(rest); ok {
+		if line, err := strconv.Atoi(prev); err == nil {
+			return before, line
+		}
+	}
+	return rest, n
+}
+
+func cutLastColon(s string) (string, string, bool) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+1:], true
+}
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordAt(t *testing.T) {
+	t.Parallel()
+
+	line := "  see main.go:12 and ✓ done"
+	require.Equal(t, "main.go:12", wordAt(line, 6))
+	require.Equal(t, "main.go:12", wordAt(line, 15))
+	require.Equal(t, "", wordAt(line, 16))
+	require.Equal(t, "done", wordAt(line, 24))
+	require.Equal(t, "", wordAt(line, 100))
+}
+
+func TestSplitLine(t *testing.T) {
+	t.Parallel()
+
+	path, line := splitLine("main.go:12:4")
+	require.Equal(t, "main.go", path)
+	require.Equal(t, 12, line)
+
+	path, line = splitLine("main.go:12")
+	require.Equal(t, "main.go", path)
+	require.Equal(t, 12, line)
+
+	path, line = splitLine("main.go")
+	require.Equal(t, "main.go", path)
+	require.Zero(t, line)
+}
+
+func TestLinkAt(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+
+	require.Equal(t, OpenURLMsg{URL: "https://example.com/a"}, linkAt("docs: (https://example.com/a).", 10))
+	require.Equal(t, OpenFileMsg{Path: path, Line: 3}, linkAt("`"+path+":3`", 2))
+	require.Nil(t, linkAt(filepath.Join(dir, "missing.go"), 2))
+	require.Nil(t, linkAt(dir, 2))
+	require.Nil(t, linkAt("plain words", 2))
+}
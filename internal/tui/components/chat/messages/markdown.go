@@ -0,0 +1,144 @@
+package messages
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/glamour/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/charmbracelet/crush/internal/tui/highlight"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+)
+
+// markdownBlocks renders the markdown of a message, keeping the rendering of
+// its complete blocks so that, while the message streams, only the block
+// being written is rendered and highlighted again.
+type markdownBlocks struct {
+	width  int
+	blocks []markdownBlock
+}
+
+type markdownBlock struct {
+	source   string
+	rendered string
+}
+
+func (mb *markdownBlocks) render(content string, width int) string {
+	if width != mb.width {
+		mb.width = width
+		mb.blocks = nil
+	}
+	complete, tail := splitMarkdownBlocks(content)
+
+	kept := 0
+	for kept < len(mb.blocks) && kept < len(complete) && mb.blocks[kept].source == complete[kept] {
+		kept++
+	}
+	mb.blocks = mb.blocks[:kept]
+
+	var r *glamour.TermRenderer
+	renderer := func() *glamour.TermRenderer {
+		if r == nil {
+			r = styles.GetMarkdownRenderer(width)
+		}
+		return r
+	}
+	for _, source := range complete[kept:] {
+		mb.blocks = append(mb.blocks, markdownBlock{
+			source:   source,
+			rendered: renderMarkdownBlock(renderer(), source),
+		})
+	}
+
+	parts := make([]string, 0, len(mb.blocks)+1)
+	for _, b := range mb.blocks {
+		parts = append(parts, b.rendered)
+	}
+	if strings.TrimSpace(tail) != "" {
+		parts = append(parts, renderMarkdownBlock(renderer(), tail))
+	}
+	return joinMarkdownBlocks(parts)
+}
+
+// renderMarkdownBlock renders a block, naming the language of its code
+// fence in a way the highlighter understands.
+func renderMarkdownBlock(r *glamour.TermRenderer, source string) string {
+	if line, rest, ok := strings.Cut(source, "\n"); ok {
+		if fence := openingFence(line); fence != "" {
+			source = fence + highlight.FenceLanguage(line[len(fence):]) + "\n" + rest
+		}
+	}
+	rendered, _ := r.Render(source)
+	return strings.TrimRight(rendered, "\n")
+}
+
+// joinMarkdownBlocks puts the blank line between blocks that the renderer
+// puts between them, code blocks already starting with their own.
+func joinMarkdownBlocks(parts []string) string {
+	var b strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			b.WriteString("\n")
+			first, _, _ := strings.Cut(part, "\n")
+			if strings.TrimSpace(ansi.Strip(first)) != "" {
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// splitMarkdownBlocks splits the content at its top level code fences,
+// returning the blocks that can't change as more content is appended, and
+// the tail that is still being written.
+func splitMarkdownBlocks(content string) ([]string, string) {
+	var complete []string
+	start, offset := 0, 0
+	fence := ""
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if !strings.HasSuffix(line, "\n") {
+			// The last line is still being written.
+			break
+		}
+		trimmed := strings.TrimRight(line, "\r\n")
+		switch {
+		case fence == "":
+			if f := openingFence(trimmed); f != "" {
+				if strings.TrimSpace(content[start:offset]) != "" {
+					complete = append(complete, content[start:offset])
+				}
+				start = offset
+				fence = f
+			}
+		case isClosingFence(trimmed, fence):
+			complete = append(complete, content[start:offset+len(line)])
+			start = offset + len(line)
+			fence = ""
+		}
+		offset += len(line)
+	}
+	return complete, content[start:]
+}
+
+// openingFence returns the fence opening a code block on the line, only
+// for unindented fences as the others may belong to a list item.
+func openingFence(line string) string {
+	for _, char := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, char))
+		if n < 3 {
+			continue
+		}
+		if char == "`" && strings.Contains(line[n:], "`") {
+			// Not a fence, inline code.
+			return ""
+		}
+		return line[:n]
+	}
+	return ""
+}
+
+func isClosingFence(line, fence string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= len(fence) && strings.Trim(line, fence[:1]) == ""
+}
@@ -0,0 +1,63 @@
+package messages
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charmbracelet/crush/internal/tui/styles"
+)
+
+func TestSplitMarkdownBlocks(t *testing.T) {
+	t.Parallel()
+
+	content := "Some text.\n\n" +
+		"```go\nfunc main() {}\n```\n" +
+		"More text with `inline` code.\n\n" +
+		"~~~~\n```\nnested\n```\n~~~~\n" +
+		"- item\n\n  ```sh\n  ls\n  ```\n" +
+		"```py\nprint("
+
+	complete, tail := splitMarkdownBlocks(content)
+	require.Equal(t, []string{
+		"Some text.\n\n",
+		"```go\nfunc main() {}\n```\n",
+		"More text with `inline` code.\n\n",
+		"~~~~\n```\nnested\n```\n~~~~\n",
+		"- item\n\n  ```sh\n  ls\n  ```\n",
+	}, complete)
+	require.Equal(t, "```py\nprint(", tail)
+	require.Equal(t, content, strings.Join(complete, "")+tail)
+}
+
+func TestMarkdownBlocksRender(t *testing.T) {
+	t.Parallel()
+
+	content := "# Title\n\nSome **text**.\n\n```go:main.go\nfunc main() {}\n```\nThen a list:\n\n- a\n- b\n"
+	var mb markdownBlocks
+
+	// Streaming the content renders the blocks as they complete.
+	for i := range content {
+		mb.render(content[:i], 40)
+	}
+	got := mb.render(content, 40)
+	require.Len(t, mb.blocks, 2)
+
+	full, err := styles.GetMarkdownRenderer(40).Render(strings.Replace(content, "go:main.go", "go", 1))
+	require.NoError(t, err)
+	require.Equal(t, trimLines(ansi.Strip(full)), trimLines(ansi.Strip(got)))
+
+	// Editing an earlier block renders it again.
+	got = mb.render(strings.Replace(content, "Title", "Other", 1), 40)
+	require.Contains(t, ansi.Strip(got), "Other")
+}
+
+func trimLines(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -20,6 +20,8 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/anim"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/blocks"
 	"github.com/charmbracelet/crush/internal/tui/exp/list"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
@@ -28,6 +30,18 @@ import (
 // CopyKey is the key binding for copying message content to the clipboard.
 var CopyKey = key.NewBinding(key.WithKeys("c", "y", "C", "Y"), key.WithHelp("c/y", "copy"))
 
+// ToggleThinkingKey is the key binding for folding or unfolding the reasoning
+// of the selected assistant message.
+var ToggleThinkingKey = key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "toggle thinking"))
+
+// BlocksKey is the key binding for opening the code blocks of the selected
+// message, to copy or save one of them.
+var BlocksKey = key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "copy/save blocks"))
+
+// ScrollOutputKey is the key binding for scrolling the output of the
+// selected running tool call up and down.
+var ScrollOutputKey = key.NewBinding(key.WithKeys("[", "]"), key.WithHelp("[/]", "scroll output"))
+
 // ClearSelectionKey is the key binding for clearing the current selection in the chat interface.
 var ClearSelectionKey = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear selection"))
 
@@ -57,6 +71,11 @@ type messageCmp struct {
 
 	// Thinking viewport for displaying reasoning content
 	thinkingViewport viewport.Model
+	// thinkingExpanded shows the reasoning in full instead of its summary
+	thinkingExpanded bool
+
+	// markdown keeps the rendering of the content as it streams
+	markdown markdownBlocks
 }
 
 var focusedMessageBorder = lipgloss.Border{
@@ -80,6 +99,7 @@ func NewMessageCmp(msg message.Message) MessageCmp {
 			CycleColors: true,
 		}),
 		thinkingViewport: thinkingViewport,
+		thinkingExpanded: !config.Get().FoldThinking(),
 	}
 	return m
 }
@@ -113,6 +133,14 @@ func (m *messageCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				util.ReportInfo("Message copied to clipboard"),
 			)
 		}
+		if key.Matches(msg, BlocksKey) && m.message.Content().Text != "" {
+			return m, util.CmdHandler(dialogs.OpenDialogMsg{
+				Model: blocks.NewBlocksDialogCmp(m.message.Content().Text, ""),
+			})
+		}
+		if key.Matches(msg, ToggleThinkingKey) && m.message.ReasoningContent().Thinking != "" {
+			m.thinkingExpanded = !m.thinkingExpanded
+		}
 	}
 	return m, nil
 }
@@ -207,7 +235,7 @@ func (m *messageCmp) renderAssistantMessage() string {
 		if thinkingContent != "" {
 			parts = append(parts, "")
 		}
-		parts = append(parts, m.toMarkdown(content))
+		parts = append(parts, m.markdown.render(content, m.textWidth()))
 	}
 
 	joined := lipgloss.JoinVertical(lipgloss.Left, parts...)
@@ -219,7 +247,7 @@ func (m *messageCmp) renderAssistantMessage() string {
 func (m *messageCmp) renderUserMessage() string {
 	t := styles.CurrentTheme()
 	parts := []string{
-		m.toMarkdown(m.message.Content().String()),
+		m.markdown.render(m.message.Content().String(), m.textWidth()),
 	}
 
 	attachmentStyles := t.S().Text.
@@ -252,51 +280,70 @@ func (m *messageCmp) toMarkdown(content string) string {
 	return strings.TrimSuffix(rendered, "\n")
 }
 
+// renderThinkingContent renders the reasoning as a block of its own above
+// the answer: the tail of the reasoning while it streams, then a summary line
+// followed by the whole reasoning when it is unfolded.
 func (m *messageCmp) renderThinkingContent() string {
 	t := styles.CurrentTheme()
 	reasoningContent := m.message.ReasoningContent()
 	if reasoningContent.Thinking == "" {
 		return ""
 	}
-	lines := strings.Split(reasoningContent.Thinking, "\n")
-	var content strings.Builder
 	lineStyle := t.S().Subtle.Background(t.BgBaseLighter)
-	for i, line := range lines {
-		if line == "" {
-			continue
+	fold := "▸"
+	if m.thinkingExpanded {
+		fold = "▾"
+	}
+	if reasoningContent.FinishedAt > 0 {
+		duration := m.message.ThinkingDuration()
+		if duration.String() == "0s" {
+			return ""
+		}
+		m.anim.SetLabel("")
+		opts := core.StatusOpts{
+			Icon:        t.S().Subtle.Render(fold),
+			Title:       "Thought for",
+			Description: duration.String(),
 		}
-		content.WriteString(lineStyle.Width(m.textWidth() - 2).Render(line))
-		if i < len(lines)-1 {
-			content.WriteString("\n")
+		if !m.thinkingExpanded {
+			opts.ExtraContent = t.S().Subtle.Render(fmt.Sprintf("(%s to expand)", ToggleThinkingKey.Help().Key))
 		}
+		header := t.S().Base.PaddingLeft(1).Render(core.Status(opts, m.textWidth()-1))
+		if !m.thinkingExpanded {
+			return header
+		}
+		return header + "\n\n" + lineStyle.Width(m.textWidth()).Padding(0, 1).Render(m.thinkingLines(lineStyle))
 	}
-	fullContent := content.String()
+
+	var footer string
+	finishReason := m.message.FinishPart()
+	if finishReason != nil && finishReason.Reason == message.FinishReasonCanceled {
+		footer = t.S().Base.PaddingLeft(1).Render(m.toMarkdown("*Canceled*"))
+	} else if reasoningContent.StartedAt > 0 {
+		footer = m.anim.View()
+	}
+	if !m.thinkingExpanded {
+		return footer
+	}
+	fullContent := m.thinkingLines(lineStyle)
 	height := util.Clamp(lipgloss.Height(fullContent), 1, 10)
 	m.thinkingViewport.SetHeight(height)
 	m.thinkingViewport.SetWidth(m.textWidth())
 	m.thinkingViewport.SetContent(fullContent)
 	m.thinkingViewport.GotoBottom()
-	finishReason := m.message.FinishPart()
-	var footer string
-	if reasoningContent.StartedAt > 0 {
-		duration := m.message.ThinkingDuration()
-		if reasoningContent.FinishedAt > 0 {
-			if duration.String() == "0s" {
-				return ""
-			}
-			m.anim.SetLabel("")
-			opts := core.StatusOpts{
-				Title:       "Thought for",
-				Description: duration.String(),
-			}
-			return t.S().Base.PaddingLeft(1).Render(core.Status(opts, m.textWidth()-1))
-		} else if finishReason != nil && finishReason.Reason == message.FinishReasonCanceled {
-			footer = t.S().Base.PaddingLeft(1).Render(m.toMarkdown("*Canceled*"))
-		} else {
-			footer = m.anim.View()
+	return lineStyle.Width(m.textWidth()).Padding(0, 1).Render(m.thinkingViewport.View()) + "\n\n" + footer
+}
+
+// thinkingLines renders the non-empty lines of the reasoning.
+func (m *messageCmp) thinkingLines(lineStyle lipgloss.Style) string {
+	var lines []string
+	for line := range strings.SplitSeq(m.message.ReasoningContent().Thinking, "\n") {
+		if line == "" {
+			continue
 		}
+		lines = append(lines, lineStyle.Width(m.textWidth()-2).Render(line))
 	}
-	return lineStyle.Width(m.textWidth()).Padding(0, 1).Render(m.thinkingViewport.View()) + "\n\n" + footer
+	return strings.Join(lines, "\n")
 }
 
 // shouldSpin determines whether the message should show a loading animation.
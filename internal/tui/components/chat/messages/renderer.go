@@ -7,10 +7,13 @@ import (
 	"time"
 
 	"github.com/charmbracelet/crush/internal/ansiext"
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/llm/agent"
 	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/tui/components/checklist"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/exp/diffview"
 	"github.com/charmbracelet/crush/internal/tui/highlight"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/lipgloss/v2"
@@ -168,12 +171,25 @@ func init() {
 	registry.register(tools.EditToolName, func() renderer { return editRenderer{} })
 	registry.register(tools.MultiEditToolName, func() renderer { return multiEditRenderer{} })
 	registry.register(tools.WriteToolName, func() renderer { return writeRenderer{} })
+	registry.register(tools.RenameSymbolToolName, func() renderer { return lspEditRenderer{} })
+	registry.register(tools.CodeActionToolName, func() renderer { return lspEditRenderer{} })
+	registry.register(tools.FormatFileToolName, func() renderer { return lspEditRenderer{} })
 	registry.register(tools.FetchToolName, func() renderer { return fetchRenderer{} })
 	registry.register(tools.GlobToolName, func() renderer { return globRenderer{} })
 	registry.register(tools.GrepToolName, func() renderer { return grepRenderer{} })
+	registry.register(tools.ListSymbolsToolName, func() renderer { return listSymbolsRenderer{} })
+	registry.register(tools.FindDefinitionToolName, func() renderer { return findDefinitionRenderer{} })
+	registry.register(tools.OutlineFileToolName, func() renderer { return outlineFileRenderer{} })
+	registry.register(tools.FindReferencesToolName, func() renderer { return lspQueryRenderer{} })
+	registry.register(tools.CallHierarchyToolName, func() renderer { return lspQueryRenderer{} })
+	registry.register(tools.WorkspaceSymbolsToolName, func() renderer { return workspaceSymbolsRenderer{} })
 	registry.register(tools.LSToolName, func() renderer { return lsRenderer{} })
+	registry.register(tools.PlanUpdateToolName, func() renderer { return planUpdateRenderer{} })
+	registry.register(tools.ReadArtifactToolName, func() renderer { return readArtifactRenderer{} })
+	registry.register(tools.RunTestsToolName, func() renderer { return runTestsRenderer{} })
 	registry.register(tools.SourcegraphToolName, func() renderer { return sourcegraphRenderer{} })
 	registry.register(tools.DiagnosticsToolName, func() renderer { return diagnosticsRenderer{} })
+	registry.register(tools.DBQueryToolName, func() renderer { return dbQueryRenderer{} })
 	registry.register(agent.AgentToolName, func() renderer { return agentRenderer{} })
 }
 
@@ -295,7 +311,7 @@ func (er editRenderer) Render(v *toolCallCmp) string {
 			return renderPlainContent(v, v.result.Content)
 		}
 
-		formatter := core.DiffFormatter().
+		formatter := diffFormatter().
 			Before(fsext.PrettyPath(params.FilePath), meta.OldContent).
 			After(fsext.PrettyPath(params.FilePath), meta.NewContent).
 			Width(v.textWidth() - 2) // -2 for padding
@@ -346,7 +362,7 @@ func (mer multiEditRenderer) Render(v *toolCallCmp) string {
 			return renderPlainContent(v, v.result.Content)
 		}
 
-		formatter := core.DiffFormatter().
+		formatter := diffFormatter().
 			Before(fsext.PrettyPath(params.FilePath), meta.OldContent).
 			After(fsext.PrettyPath(params.FilePath), meta.NewContent).
 			Width(v.textWidth() - 2) // -2 for padding
@@ -368,6 +384,71 @@ func (mer multiEditRenderer) Render(v *toolCallCmp) string {
 	})
 }
 
+// -----------------------------------------------------------------------------
+//  LSP edit renderer
+// -----------------------------------------------------------------------------
+
+// lspEditRenderer handles the rename, code action and format tools, showing
+// the diff of all the files changed by the language server
+type lspEditRenderer struct {
+	baseRenderer
+}
+
+// Render displays the changed files with a formatted diff of changes
+func (lr lspEditRenderer) Render(v *toolCallCmp) string {
+	t := styles.CurrentTheme()
+	var params struct {
+		FilePath string `json:"file_path"`
+		Line     int    `json:"line"`
+		Symbol   string `json:"symbol"`
+		NewName  string `json:"new_name"`
+		Action   string `json:"action"`
+	}
+	var args []string
+	if err := lr.unmarshalParams(v.call.Input, &params); err == nil {
+		b := newParamBuilder().addMain(fsext.PrettyPath(params.FilePath))
+		switch v.call.Name {
+		case tools.RenameSymbolToolName:
+			b = b.addKeyValue("symbol", params.Symbol).addKeyValue("new_name", params.NewName)
+		case tools.CodeActionToolName:
+			b = b.addKeyValue("line", formatNonZero(params.Line)).addKeyValue("action", params.Action)
+		}
+		args = b.build()
+	}
+
+	return lr.renderWithParams(v, prettifyToolName(v.call.Name), args, func() string {
+		var meta tools.LSPEditResponseMetadata
+		if err := lr.unmarshalParams(v.result.Metadata, &meta); err != nil || len(meta.Files) == 0 {
+			return renderPlainContent(v, v.result.Content)
+		}
+
+		name := fsext.PrettyPath(meta.Files[0].FilePath)
+		if len(meta.Files) > 1 {
+			name = fmt.Sprintf("%d files", len(meta.Files))
+		}
+		oldContent, newContent := tools.LSPEditContents(meta.Files)
+		formatter := diffFormatter().
+			Before(name, oldContent).
+			After(name, newContent).
+			Width(v.textWidth() - 2) // -2 for padding
+		if v.textWidth() > 120 {
+			formatter = formatter.Split()
+		}
+		// add a message to the bottom if the content was truncated
+		formatted := formatter.String()
+		if lipgloss.Height(formatted) > responseContextHeight {
+			contentLines := strings.Split(formatted, "\n")
+			truncateMessage := t.S().Muted.
+				Background(t.BgBaseLighter).
+				PaddingLeft(2).
+				Width(v.textWidth() - 2).
+				Render(fmt.Sprintf("… (%d lines)", len(contentLines)-responseContextHeight))
+			formatted = strings.Join(contentLines[:responseContextHeight], "\n") + "\n" + truncateMessage
+		}
+		return formatted
+	})
+}
+
 // -----------------------------------------------------------------------------
 //  Write renderer
 // -----------------------------------------------------------------------------
@@ -480,7 +561,7 @@ func (gr globRenderer) Render(v *toolCallCmp) string {
 	var args []string
 	if err := gr.unmarshalParams(v.call.Input, &params); err == nil {
 		args = newParamBuilder().
-			addMain(params.Pattern).
+			addMain(strings.Join(append([]string{params.Pattern}, params.Patterns...), " ")).
 			addKeyValue("path", params.Path).
 			build()
 	}
@@ -508,7 +589,10 @@ func (gr grepRenderer) Render(v *toolCallCmp) string {
 			addMain(params.Pattern).
 			addKeyValue("path", params.Path).
 			addKeyValue("include", params.Include).
+			addKeyValue("type", params.Type).
 			addFlag("literal", params.LiteralText).
+			addFlag("multiline", params.Multiline).
+			addFlag("count", params.CountOnly).
 			build()
 	}
 
@@ -517,6 +601,135 @@ func (gr grepRenderer) Render(v *toolCallCmp) string {
 	})
 }
 
+// -----------------------------------------------------------------------------
+//  List symbols renderer
+// -----------------------------------------------------------------------------
+
+// listSymbolsRenderer handles symbol listing with query and kind filters
+type listSymbolsRenderer struct {
+	baseRenderer
+}
+
+// Render displays the searched path with optional query and kind parameters
+func (lr listSymbolsRenderer) Render(v *toolCallCmp) string {
+	var params tools.ListSymbolsParams
+	var args []string
+	if err := lr.unmarshalParams(v.call.Input, &params); err == nil {
+		path := params.Path
+		if path == "" {
+			path = "."
+		}
+		args = newParamBuilder().
+			addMain(fsext.PrettyPath(path)).
+			addKeyValue("query", params.Query).
+			addKeyValue("kind", params.Kind).
+			build()
+	}
+
+	return lr.renderWithParams(v, "Symbols", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
+// -----------------------------------------------------------------------------
+//  Find definition renderer
+// -----------------------------------------------------------------------------
+
+// findDefinitionRenderer handles symbol definition lookups
+type findDefinitionRenderer struct {
+	baseRenderer
+}
+
+// Render displays the looked up symbol with optional path parameter
+func (fr findDefinitionRenderer) Render(v *toolCallCmp) string {
+	var params tools.FindDefinitionParams
+	var args []string
+	if err := fr.unmarshalParams(v.call.Input, &params); err == nil {
+		args = newParamBuilder().
+			addMain(params.Symbol).
+			addKeyValue("path", params.Path).
+			build()
+	}
+
+	return fr.renderWithParams(v, "Definition", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
+// -----------------------------------------------------------------------------
+//  Outline file renderer
+// -----------------------------------------------------------------------------
+
+// outlineFileRenderer handles file outlines
+type outlineFileRenderer struct {
+	baseRenderer
+}
+
+// Render displays the outlined file path
+func (ofr outlineFileRenderer) Render(v *toolCallCmp) string {
+	var params tools.OutlineFileParams
+	var args []string
+	if err := ofr.unmarshalParams(v.call.Input, &params); err == nil {
+		args = newParamBuilder().addMain(fsext.PrettyPath(params.FilePath)).build()
+	}
+
+	return ofr.renderWithParams(v, "Outline", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
+// -----------------------------------------------------------------------------
+//  LSP query renderer
+// -----------------------------------------------------------------------------
+
+// lspQueryRenderer handles the find references and call hierarchy tools
+type lspQueryRenderer struct {
+	baseRenderer
+}
+
+// Render displays the looked up symbol with its file and the direction of
+// the call hierarchy
+func (lr lspQueryRenderer) Render(v *toolCallCmp) string {
+	var params struct {
+		FilePath  string `json:"file_path"`
+		Symbol    string `json:"symbol"`
+		Direction string `json:"direction"`
+	}
+	var args []string
+	if err := lr.unmarshalParams(v.call.Input, &params); err == nil {
+		args = newParamBuilder().
+			addMain(params.Symbol).
+			addKeyValue("file", fsext.PrettyPath(params.FilePath)).
+			addKeyValue("direction", params.Direction).
+			build()
+	}
+
+	return lr.renderWithParams(v, prettifyToolName(v.call.Name), args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
+// workspaceSymbolsRenderer handles the workspace symbol search
+type workspaceSymbolsRenderer struct {
+	baseRenderer
+}
+
+// Render displays the query with the optional kind filter
+func (wr workspaceSymbolsRenderer) Render(v *toolCallCmp) string {
+	var params tools.WorkspaceSymbolsParams
+	var args []string
+	if err := wr.unmarshalParams(v.call.Input, &params); err == nil {
+		args = newParamBuilder().
+			addMain(params.Query).
+			addKeyValue("kind", params.Kind).
+			build()
+	}
+
+	return wr.renderWithParams(v, prettifyToolName(v.call.Name), args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
 // -----------------------------------------------------------------------------
 //  LS renderer
 // -----------------------------------------------------------------------------
@@ -571,6 +784,134 @@ func (sr sourcegraphRenderer) Render(v *toolCallCmp) string {
 	})
 }
 
+// -----------------------------------------------------------------------------
+//  Run tests renderer
+// -----------------------------------------------------------------------------
+
+// runTestsRenderer handles test runs with their structured summary
+type runTestsRenderer struct {
+	baseRenderer
+}
+
+// Render displays the test target with the framework and the run summary
+func (rr runTestsRenderer) Render(v *toolCallCmp) string {
+	var params tools.RunTestsParams
+	var args []string
+	if err := rr.unmarshalParams(v.call.Input, &params); err == nil {
+		target := params.Path
+		if target == "" {
+			target = "project"
+		}
+		args = newParamBuilder().
+			addMain(target).
+			addKeyValue("framework", params.Framework).
+			addKeyValue("filter", params.Filter).
+			build()
+	}
+
+	return rr.renderWithParams(v, "Run Tests", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
+// -----------------------------------------------------------------------------
+//  DB query renderer
+// -----------------------------------------------------------------------------
+
+// dbQueryRenderer handles database queries and schema lookups
+type dbQueryRenderer struct {
+	baseRenderer
+}
+
+// Render displays the query or schema action with the database name
+func (dr dbQueryRenderer) Render(v *toolCallCmp) string {
+	var params tools.DBQueryParams
+	var args []string
+	if err := dr.unmarshalParams(v.call.Input, &params); err == nil {
+		main := strings.Join(strings.Fields(params.Query), " ")
+		switch params.Action {
+		case "tables":
+			main = "tables"
+		case "describe":
+			main = params.Table
+		}
+		args = newParamBuilder().
+			addMain(main).
+			addKeyValue("database", params.Database).
+			build()
+	}
+
+	return dr.renderWithParams(v, "DB Query", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
+// -----------------------------------------------------------------------------
+//  Plan update renderer
+// -----------------------------------------------------------------------------
+
+// planUpdateRenderer handles plan updates as a checklist of their steps
+type planUpdateRenderer struct {
+	baseRenderer
+}
+
+// Render displays the progress of the plan and its steps
+func (pr planUpdateRenderer) Render(v *toolCallCmp) string {
+	steps, err := tools.ParsePlanUpdate(v.call.Input)
+	var args []string
+	if err == nil {
+		completed, total := checklist.Progress(steps)
+		args = newParamBuilder().
+			addMain(fmt.Sprintf("%d/%d completed", completed, total)).
+			build()
+	}
+
+	return pr.renderWithParams(v, "Plan", args, func() string {
+		if err != nil {
+			return renderPlainContent(v, v.result.Content)
+		}
+		return strings.Join(checklist.RenderPlanList(steps, checklist.RenderOptions{
+			MaxWidth: v.textWidth() - 2,
+		}), "\n")
+	})
+}
+
+// -----------------------------------------------------------------------------
+//  Read artifact renderer
+// -----------------------------------------------------------------------------
+
+// readArtifactRenderer handles artifact reads like file views
+type readArtifactRenderer struct {
+	baseRenderer
+}
+
+// Render displays the artifact content with optional limit and offset parameters
+func (rr readArtifactRenderer) Render(v *toolCallCmp) string {
+	var params tools.ReadArtifactParams
+	if err := rr.unmarshalParams(v.call.Input, &params); err != nil {
+		return rr.renderError(v, "Invalid read artifact parameters")
+	}
+
+	var meta tools.ReadArtifactResponseMetadata
+	metaErr := rr.unmarshalParams(v.result.Metadata, &meta)
+	main := params.ArtifactID
+	if metaErr == nil && meta.Name != "" {
+		main = meta.Name
+	}
+	args := newParamBuilder().
+		addMain(main).
+		addKeyValue("limit", formatNonZero(params.Limit)).
+		addKeyValue("offset", formatNonZero(params.Offset)).
+		build()
+
+	return rr.renderWithParams(v, "Read Artifact", args, func() string {
+		if metaErr != nil {
+			return renderPlainContent(v, v.result.Content)
+		}
+		return renderCodeContent(v, meta.Name, meta.Content, params.Offset)
+	})
+}
+
 // -----------------------------------------------------------------------------
 //  Diagnostics renderer
 // -----------------------------------------------------------------------------
@@ -721,8 +1062,10 @@ func earlyState(header string, v *toolCallCmp) (string, bool) {
 	switch {
 	case v.result.IsError:
 		message = v.renderToolError()
-	case v.cancelled:
+	case v.cancelled && v.result.ToolCallID == "":
 		message = t.S().Base.Foreground(t.FgSubtle).Render("Canceled.")
+	case v.streaming():
+		message = v.renderOutput()
 	case v.result.ToolCallID == "":
 		if v.permissionRequested && !v.permissionGranted {
 			message = t.S().Base.Foreground(t.FgSubtle).Render("Requesting for permission...")
@@ -780,6 +1123,57 @@ func renderPlainContent(v *toolCallCmp, content string) string {
 	return strings.Join(out, "\n")
 }
 
+// outputLines splits the streamed output into the lines shown, keeping what
+// follows the last carriage return of each, like progress bars do.
+func outputLines(output string) []string {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return nil
+	}
+	lines := strings.Split(output, "\n")
+	for i, ln := range lines {
+		if j := strings.LastIndex(ln, "\r"); j >= 0 {
+			lines[i] = ln[j+1:]
+		}
+	}
+	return lines
+}
+
+// renderOutput displays the output streamed by the running tool, with the
+// animation and the elapsed time. The last lines are shown unless scrolled
+// up.
+func (v *toolCallCmp) renderOutput() string {
+	t := styles.CurrentTheme()
+	elapsed := time.Since(v.output.Started).Truncate(time.Second)
+	status := v.anim.View() + " " + t.S().Subtle.Render(elapsed.String())
+
+	lines := outputLines(v.output.Output)
+	if len(lines) == 0 {
+		return status
+	}
+	end := len(lines) - v.outputScroll
+	start := max(end-responseContextHeight, 0)
+	if start > 0 || end < len(lines) {
+		status += t.S().Subtle.Render(fmt.Sprintf("  lines %d-%d of %d (%s to scroll)", start+1, end, len(lines), ScrollOutputKey.Help().Key))
+	}
+
+	width := v.textWidth() - 2 // -2 for left padding
+	out := []string{status}
+	for _, ln := range lines[start:end] {
+		ln = strings.ReplaceAll(ln, "\t", "    ")
+		ln = " " + ansiext.Escape(ln)
+		if len(ln) > width {
+			ln = v.fit(ln, width)
+		}
+		out = append(out, t.S().Muted.
+			Width(width).
+			Background(t.BgBaseLighter).
+			Render(ln))
+	}
+	return strings.Join(out, "\n")
+}
+
 func getDigits(n int) int {
 	if n == 0 {
 		return 1
@@ -797,6 +1191,16 @@ func getDigits(n int) int {
 	return digits
 }
 
+// diffFormatter returns the diff formatter used for tool results. Syntax
+// highlighting is skipped in low memory mode.
+func diffFormatter() *diffview.DiffView {
+	formatter := core.DiffFormatter()
+	if config.Get().LowMemory() {
+		formatter = formatter.ChromaStyle(nil)
+	}
+	return formatter
+}
+
 func renderCodeContent(v *toolCallCmp, path, content string, offset int) string {
 	t := styles.CurrentTheme()
 	content = strings.ReplaceAll(content, "\r\n", "\n") // Normalize line endings
@@ -809,8 +1213,10 @@ func renderCodeContent(v *toolCallCmp, path, content string, offset int) string
 	}
 
 	bg := t.BgBase
-	highlighted, _ := highlight.SyntaxHighlight(strings.Join(lines, "\n"), path, bg)
-	lines = strings.Split(highlighted, "\n")
+	if !config.Get().LowMemory() {
+		highlighted, _ := highlight.SyntaxHighlight(strings.Join(lines, "\n"), path, bg)
+		lines = strings.Split(highlighted, "\n")
+	}
 
 	if len(strings.Split(content, "\n")) > responseContextHeight {
 		lines = append(lines, t.S().Muted.
@@ -866,6 +1272,8 @@ func prettifyToolName(name string) string {
 		return "Agent"
 	case tools.BashToolName:
 		return "Bash"
+	case tools.DBQueryToolName:
+		return "DB Query"
 	case tools.DownloadToolName:
 		return "Download"
 	case tools.EditToolName:
@@ -880,12 +1288,40 @@ func prettifyToolName(name string) string {
 		return "Grep"
 	case tools.LSToolName:
 		return "List"
+	case tools.ListSymbolsToolName:
+		return "Symbols"
+	case tools.FindDefinitionToolName:
+		return "Definition"
+	case tools.OutlineFileToolName:
+		return "Outline"
+	case tools.FindReferencesToolName:
+		return "References"
+	case tools.CallHierarchyToolName:
+		return "Call Hierarchy"
+	case tools.WorkspaceSymbolsToolName:
+		return "Workspace Symbols"
+	case tools.PlanUpdateToolName:
+		return "Plan"
+	case tools.ReadArtifactToolName:
+		return "Read Artifact"
+	case tools.ExpandResultToolName:
+		return "Expand Result"
+	case tools.RunTestsToolName:
+		return "Run Tests"
+	case tools.ResetShellToolName:
+		return "Reset Shell"
 	case tools.SourcegraphToolName:
 		return "Sourcegraph"
 	case tools.ViewToolName:
 		return "View"
 	case tools.WriteToolName:
 		return "Write"
+	case tools.RenameSymbolToolName:
+		return "Rename"
+	case tools.CodeActionToolName:
+		return "Code Action"
+	case tools.FormatFileToolName:
+		return "Format"
 	default:
 		return name
 	}
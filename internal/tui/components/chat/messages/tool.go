@@ -27,19 +27,20 @@ import (
 // ToolCallCmp defines the interface for tool call components in the chat interface.
 // It manages the display of tool execution including pending states, results, and errors.
 type ToolCallCmp interface {
-	util.Model                         // Basic Bubble Tea model interface
-	layout.Sizeable                    // Width/height management
-	layout.Focusable                   // Focus state management
-	GetToolCall() message.ToolCall     // Access to tool call data
-	GetToolResult() message.ToolResult // Access to tool result data
-	SetToolResult(message.ToolResult)  // Update tool result
-	SetToolCall(message.ToolCall)      // Update tool call
-	SetCancelled()                     // Mark as cancelled
-	ParentMessageID() string           // Get parent message ID
-	Spinning() bool                    // Animation state for pending tools
-	GetNestedToolCalls() []ToolCallCmp // Get nested tool calls
-	SetNestedToolCalls([]ToolCallCmp)  // Set nested tool calls
-	SetIsNested(bool)                  // Set whether this tool call is nested
+	util.Model                          // Basic Bubble Tea model interface
+	layout.Sizeable                     // Width/height management
+	layout.Focusable                    // Focus state management
+	GetToolCall() message.ToolCall      // Access to tool call data
+	GetToolResult() message.ToolResult  // Access to tool result data
+	SetToolResult(message.ToolResult)   // Update tool result
+	SetToolCall(message.ToolCall)       // Update tool call
+	SetCancelled()                      // Mark as cancelled
+	SetOutput(tools.ToolOutput) tea.Cmd // Update the output streamed while running
+	ParentMessageID() string            // Get parent message ID
+	Spinning() bool                     // Animation state for pending tools
+	GetNestedToolCalls() []ToolCallCmp  // Get nested tool calls
+	SetNestedToolCalls([]ToolCallCmp)   // Set nested tool calls
+	SetIsNested(bool)                   // Set whether this tool call is nested
 	ID() string
 	SetPermissionRequested() // Mark permission request
 	SetPermissionGranted()   // Mark permission granted
@@ -57,6 +58,8 @@ type toolCallCmp struct {
 	call                message.ToolCall   // The tool call being executed
 	result              message.ToolResult // The result of the tool execution
 	cancelled           bool               // Whether the tool call was cancelled
+	output              tools.ToolOutput   // Output streamed while the tool runs
+	outputScroll        int                // Lines the streamed output is scrolled up
 	permissionRequested bool
 	permissionGranted   bool
 
@@ -168,6 +171,13 @@ func (m *toolCallCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if key.Matches(msg, CopyKey) {
 			return m, m.copyTool()
 		}
+		if key.Matches(msg, ScrollOutputKey) && m.streaming() {
+			if msg.String() == "[" {
+				m.scrollOutput(1)
+			} else {
+				m.scrollOutput(-1)
+			}
+		}
 	}
 	return m, nil
 }
@@ -194,6 +204,34 @@ func (m *toolCallCmp) View() string {
 // SetCancelled marks the tool call as cancelled
 func (m *toolCallCmp) SetCancelled() {
 	m.cancelled = true
+	m.spinning = false
+}
+
+// SetOutput updates the output streamed while the tool runs, and starts the
+// animation shown with it.
+func (m *toolCallCmp) SetOutput(output tools.ToolOutput) tea.Cmd {
+	if m.result.ToolCallID != "" || m.cancelled {
+		return nil
+	}
+	m.output = output
+	m.scrollOutput(0)
+	if m.spinning {
+		return nil
+	}
+	m.spinning = true
+	return m.anim.Init()
+}
+
+// streaming reports whether the tool is running and streamed some output.
+func (m *toolCallCmp) streaming() bool {
+	return m.output.ToolCallID != "" && m.result.ToolCallID == "" && !m.cancelled
+}
+
+// scrollOutput scrolls the streamed output up by the lines, down when
+// negative, keeping a full page of it in view.
+func (m *toolCallCmp) scrollOutput(lines int) {
+	maxScroll := max(len(outputLines(m.output.Output))-responseContextHeight, 0)
+	m.outputScroll = min(max(m.outputScroll+lines, 0), maxScroll)
 }
 
 func (m *toolCallCmp) copyTool() tea.Cmd {
@@ -284,6 +322,31 @@ func (m *toolCallCmp) formatParametersForCopy() string {
 		if json.Unmarshal([]byte(m.call.Input), &params) == nil {
 			return fmt.Sprintf("**File:** %s", fsext.PrettyPath(params.FilePath))
 		}
+	case tools.RenameSymbolToolName:
+		var params tools.RenameSymbolParams
+		if json.Unmarshal([]byte(m.call.Input), &params) == nil {
+			var parts []string
+			parts = append(parts, fmt.Sprintf("**File:** %s", fsext.PrettyPath(params.FilePath)))
+			parts = append(parts, fmt.Sprintf("**Symbol:** %s", params.Symbol))
+			parts = append(parts, fmt.Sprintf("**New Name:** %s", params.NewName))
+			return strings.Join(parts, "\n")
+		}
+	case tools.CodeActionToolName:
+		var params tools.CodeActionParams
+		if json.Unmarshal([]byte(m.call.Input), &params) == nil {
+			var parts []string
+			parts = append(parts, fmt.Sprintf("**File:** %s", fsext.PrettyPath(params.FilePath)))
+			parts = append(parts, fmt.Sprintf("**Line:** %d", params.Line))
+			if params.Action != "" {
+				parts = append(parts, fmt.Sprintf("**Action:** %s", params.Action))
+			}
+			return strings.Join(parts, "\n")
+		}
+	case tools.FormatFileToolName:
+		var params tools.FormatFileParams
+		if json.Unmarshal([]byte(m.call.Input), &params) == nil {
+			return fmt.Sprintf("**File:** %s", fsext.PrettyPath(params.FilePath))
+		}
 	case tools.FetchToolName:
 		var params tools.FetchParams
 		if json.Unmarshal([]byte(m.call.Input), &params) == nil {
@@ -308,9 +371,21 @@ func (m *toolCallCmp) formatParametersForCopy() string {
 			if params.Include != "" {
 				parts = append(parts, fmt.Sprintf("**Include:** %s", params.Include))
 			}
+			if params.Type != "" {
+				parts = append(parts, fmt.Sprintf("**Type:** %s", params.Type))
+			}
 			if params.LiteralText {
 				parts = append(parts, "**Literal:** true")
 			}
+			if params.Multiline {
+				parts = append(parts, "**Multiline:** true")
+			}
+			if params.Context > 0 {
+				parts = append(parts, fmt.Sprintf("**Context:** %d", params.Context))
+			}
+			if params.CountOnly {
+				parts = append(parts, "**Count only:** true")
+			}
 			return strings.Join(parts, "\n")
 		}
 	case tools.GlobToolName:
@@ -318,6 +393,9 @@ func (m *toolCallCmp) formatParametersForCopy() string {
 		if json.Unmarshal([]byte(m.call.Input), &params) == nil {
 			var parts []string
 			parts = append(parts, fmt.Sprintf("**Pattern:** %s", params.Pattern))
+			if len(params.Patterns) > 0 {
+				parts = append(parts, fmt.Sprintf("**Patterns:** %s", strings.Join(params.Patterns, ", ")))
+			}
 			if params.Path != "" {
 				parts = append(parts, fmt.Sprintf("**Path:** %s", params.Path))
 			}
@@ -393,11 +471,15 @@ func (m *toolCallCmp) formatResultForCopy() string {
 		return m.formatMultiEditResultForCopy()
 	case tools.WriteToolName:
 		return m.formatWriteResultForCopy()
+	case tools.RenameSymbolToolName, tools.CodeActionToolName, tools.FormatFileToolName:
+		return m.formatLSPEditResultForCopy()
 	case tools.FetchToolName:
 		return m.formatFetchResultForCopy()
+	case tools.PlanUpdateToolName:
+		return m.formatPlanUpdateResultForCopy()
 	case agent.AgentToolName:
 		return m.formatAgentResultForCopy()
-	case tools.DownloadToolName, tools.GrepToolName, tools.GlobToolName, tools.LSToolName, tools.SourcegraphToolName, tools.DiagnosticsToolName:
+	case tools.DownloadToolName, tools.GrepToolName, tools.GlobToolName, tools.LSToolName, tools.SourcegraphToolName, tools.DiagnosticsToolName, tools.RunTestsToolName, tools.DBQueryToolName, tools.ReadArtifactToolName, tools.ListSymbolsToolName, tools.FindDefinitionToolName, tools.OutlineFileToolName, tools.FindReferencesToolName, tools.CallHierarchyToolName, tools.WorkspaceSymbolsToolName:
 		return fmt.Sprintf("```\n%s\n```", m.result.Content)
 	default:
 		return m.result.Content
@@ -542,6 +624,27 @@ func (m *toolCallCmp) formatMultiEditResultForCopy() string {
 	return result.String()
 }
 
+func (m *toolCallCmp) formatLSPEditResultForCopy() string {
+	var meta tools.LSPEditResponseMetadata
+	if m.result.Metadata == "" {
+		return m.result.Content
+	}
+
+	if json.Unmarshal([]byte(m.result.Metadata), &meta) != nil || len(meta.Files) == 0 {
+		return m.result.Content
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Changes: +%d -%d\n", meta.Additions, meta.Removals))
+	result.WriteString("```diff\n")
+	for _, f := range meta.Files {
+		diffContent, _, _ := diff.GenerateDiff(f.OldContent, f.NewContent, fsext.PrettyPath(f.FilePath))
+		result.WriteString(diffContent)
+	}
+	result.WriteString("\n```")
+	return result.String()
+}
+
 func (m *toolCallCmp) formatWriteResultForCopy() string {
 	var params tools.WriteParams
 	if json.Unmarshal([]byte(m.call.Input), &params) != nil {
@@ -623,6 +726,26 @@ func (m *toolCallCmp) formatFetchResultForCopy() string {
 	return result.String()
 }
 
+func (m *toolCallCmp) formatPlanUpdateResultForCopy() string {
+	steps, err := tools.ParsePlanUpdate(m.call.Input)
+	if err != nil {
+		return m.result.Content
+	}
+
+	var result strings.Builder
+	for _, step := range steps {
+		switch step.Status {
+		case tools.PlanStepCompleted:
+			result.WriteString(fmt.Sprintf("- [x] %s\n", step.Content))
+		case tools.PlanStepInProgress:
+			result.WriteString(fmt.Sprintf("- [ ] %s (in progress)\n", step.Content))
+		default:
+			result.WriteString(fmt.Sprintf("- [ ] %s\n", step.Content))
+		}
+	}
+	return strings.TrimSuffix(result.String(), "\n")
+}
+
 func (m *toolCallCmp) formatAgentResultForCopy() string {
 	var result strings.Builder
 
@@ -652,7 +775,7 @@ func (m *toolCallCmp) formatAgentResultForCopy() string {
 // SetToolCall updates the tool call data and stops spinning if finished
 func (m *toolCallCmp) SetToolCall(call message.ToolCall) {
 	m.call = call
-	if m.call.Finished {
+	if m.call.Finished && !m.streaming() {
 		m.spinning = false
 	}
 }
@@ -14,12 +14,16 @@ import (
 	"github.com/charmbracelet/crush/internal/diff"
 	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/llm/tools"
 	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/pin"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
+	"github.com/charmbracelet/crush/internal/tui/components/checklist"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
+	"github.com/charmbracelet/crush/internal/tui/components/core/status"
 	"github.com/charmbracelet/crush/internal/tui/components/files"
 	"github.com/charmbracelet/crush/internal/tui/components/logo"
 	lspcomponent "github.com/charmbracelet/crush/internal/tui/components/lsp"
@@ -28,6 +32,7 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/crush/internal/version"
 	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -44,6 +49,8 @@ const (
 	DefaultMaxFilesShown = 10
 	DefaultMaxLSPsShown  = 8
 	DefaultMaxMCPsShown  = 8
+	DefaultMaxPlanShown  = 10
+	DefaultMaxPinsShown  = 5
 	MinItemsPerSection   = 2 // Minimum items to show per section
 )
 
@@ -57,6 +64,13 @@ type SessionFilesMsg struct {
 	Files []SessionFile
 }
 
+// SessionPinsMsg carries the pins of the session, loaded again after each
+// change.
+type SessionPinsMsg struct {
+	SessionID string
+	Pins      []pin.Pin
+}
+
 type Sidebar interface {
 	util.Model
 	layout.Sizeable
@@ -73,12 +87,16 @@ type sidebarCmp struct {
 	compactMode   bool
 	history       history.Service
 	files         *csync.Map[string, SessionFile]
+	plan          []tools.PlanStep
+	pinService    pin.Service
+	pins          []pin.Pin
 }
 
-func New(history history.Service, lspClients map[string]*lsp.Client, compact bool) Sidebar {
+func New(history history.Service, pins pin.Service, lspClients map[string]*lsp.Client, compact bool) Sidebar {
 	return &sidebarCmp{
 		lspClients:  lspClients,
 		history:     history,
+		pinService:  pins,
 		compactMode: compact,
 		files:       csync.NewMap[string, SessionFile](),
 	}
@@ -96,9 +114,24 @@ func (m *sidebarCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.files.Set(file.FilePath, file)
 		}
 		return m, nil
+	case SessionPinsMsg:
+		if msg.SessionID == m.session.ID {
+			m.pins = msg.Pins
+		}
+		return m, nil
+	case pubsub.Event[pin.Pin]:
+		if msg.Payload.SessionID == m.session.ID {
+			return m, m.loadSessionPins
+		}
 
 	case chat.SessionClearedMsg:
 		m.session = session.Session{}
+		m.plan = nil
+		m.pins = nil
+	case checklist.PlanUpdatedMsg:
+		if msg.SessionID == m.session.ID {
+			m.plan = msg.Steps
+		}
 	case pubsub.Event[history.File]:
 		return m, m.handleFileHistoryEvent(msg)
 	case pubsub.Event[session.Session]:
@@ -149,6 +182,12 @@ func (m *sidebarCmp) View() string {
 	parts = append(parts,
 		m.currentModelBlock(),
 	)
+	if len(m.plan) > 0 {
+		parts = append(parts, "", m.planBlock())
+	}
+	if len(m.pins) > 0 {
+		parts = append(parts, "", m.pinsBlock())
+	}
 
 	// Check if we should use horizontal layout for sections
 	if m.compactMode && m.width > m.height {
@@ -265,6 +304,21 @@ func (m *sidebarCmp) loadSessionFiles() tea.Msg {
 	}
 }
 
+func (m *sidebarCmp) loadSessionPins() tea.Msg {
+	sessionID := m.session.ID
+	if m.pinService == nil || sessionID == "" {
+		return nil
+	}
+	pins, err := m.pinService.List(sessionID)
+	if err != nil {
+		return util.InfoMsg{
+			Type: util.InfoTypeError,
+			Msg:  err.Error(),
+		}
+	}
+	return SessionPinsMsg{SessionID: sessionID, Pins: pins}
+}
+
 func (m *sidebarCmp) SetSize(width, height int) tea.Cmd {
 	m.logo = m.logoBlock()
 	m.cwd = cwd()
@@ -320,6 +374,16 @@ func (m *sidebarCmp) calculateAvailableHeight() int {
 
 	usedHeight += 6 // 3 sections × 2 lines each (header + empty line)
 
+	if len(m.plan) > 0 {
+		usedHeight += 3 // Plan header, empty line and empty line before it
+		usedHeight += m.maxPlanShown()
+	}
+
+	if len(m.pins) > 0 {
+		usedHeight += 3 // Pins header, empty line and empty line before it
+		usedHeight += m.maxPinsShown()
+	}
+
 	// Base padding
 	usedHeight += 2 // Top and bottom padding
 
@@ -449,6 +513,62 @@ func (m *sidebarCmp) mcpBlockCompact(maxWidth int) string {
 	}, true)
 }
 
+// maxPlanShown returns how many lines the plan takes, it is shown in full
+// when it fits as it follows the progress of the agent.
+func (m *sidebarCmp) maxPlanShown() int {
+	maxItems := DefaultMaxPlanShown
+	if m.compactMode {
+		maxItems = 5
+	}
+	return min(len(m.plan), maxItems)
+}
+
+func (m *sidebarCmp) planBlock() string {
+	completed, total := checklist.Progress(m.plan)
+	info := styles.CurrentTheme().S().Subtle.Render(fmt.Sprintf("%d/%d", completed, total))
+	return checklist.RenderPlanBlock(m.plan, checklist.RenderOptions{
+		MaxWidth:    m.getMaxWidth(),
+		MaxItems:    m.maxPlanShown(),
+		ShowSection: true,
+		SectionName: core.SectionWithInfo("Plan", m.getMaxWidth(), info),
+	}, true)
+}
+
+func (m *sidebarCmp) maxPinsShown() int {
+	maxItems := DefaultMaxPinsShown
+	if m.compactMode {
+		maxItems = 3
+	}
+	return min(len(m.pins), maxItems)
+}
+
+// pinsBlock lists the pins of the session with the tokens they add to each
+// request.
+func (m *sidebarCmp) pinsBlock() string {
+	t := styles.CurrentTheme()
+	maxWidth := m.getMaxWidth()
+	total := 0
+	for _, p := range m.pins {
+		total += p.Tokens
+	}
+	info := t.S().Subtle.Render("~" + status.FormatTokens(int64(total)) + " tokens")
+	lines := []string{core.SectionWithInfo("Pinned", maxWidth, info), ""}
+	shown := m.maxPinsShown()
+	for _, p := range m.pins[:shown] {
+		title := p.Title
+		if p.Kind == pin.KindFile {
+			title = fsext.PrettyPath(p.Path)
+		}
+		tokens := " " + status.FormatTokens(int64(p.Tokens))
+		title = ansi.Truncate(title, max(0, maxWidth-2-lipgloss.Width(tokens)), "…")
+		lines = append(lines, t.S().Subtle.Render(styles.PinIcon)+" "+t.S().Muted.Render(title)+t.S().Subtle.Render(tokens))
+	}
+	if remaining := len(m.pins) - shown; remaining > 0 {
+		lines = append(lines, t.S().Base.Foreground(t.FgSubtle).Render(fmt.Sprintf("…and %d more", remaining)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
 func (m *sidebarCmp) filesBlock() string {
 	// Convert map to slice and handle type conversion
 	sessionFiles := slices.Collect(m.files.Seq())
@@ -570,7 +690,7 @@ func (s *sidebarCmp) currentModelBlock() string {
 			}
 			formatter := cases.Title(language.English, cases.NoLower)
 			parts = append(parts, reasoningInfoStyle.Render(formatter.String(fmt.Sprintf("Reasoning %s", reasoningEffort))))
-		case catwalk.TypeAnthropic:
+		case catwalk.TypeAnthropic, config.TypeAnthropicCompat:
 			formatter := cases.Title(language.English, cases.NoLower)
 			if selectedModel.Think {
 				parts = append(parts, reasoningInfoStyle.Render(formatter.String("Thinking on")))
@@ -598,7 +718,9 @@ func (s *sidebarCmp) currentModelBlock() string {
 // SetSession implements Sidebar.
 func (m *sidebarCmp) SetSession(session session.Session) tea.Cmd {
 	m.session = session
-	return m.loadSessionFiles
+	m.plan = nil
+	m.pins = nil
+	return tea.Batch(m.loadSessionFiles, m.loadSessionPins)
 }
 
 // SetCompactMode sets the compact mode for the sidebar.
@@ -2,6 +2,7 @@ package splash
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 type KeyMap struct {
@@ -16,7 +17,7 @@ type KeyMap struct {
 }
 
 func DefaultKeyMap() KeyMap {
-	return KeyMap{
+	return keymap.Apply("splash", KeyMap{
 		Select: key.NewBinding(
 			key.WithKeys("enter", "ctrl+y"),
 			key.WithHelp("enter", "confirm"),
@@ -49,5 +50,5 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "back"),
 		),
-	}
+	})
 }
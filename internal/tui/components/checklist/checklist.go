@@ -0,0 +1,161 @@
+package checklist
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+)
+
+// PlanUpdatedMsg is sent when the agent updates the plan of a session.
+type PlanUpdatedMsg struct {
+	SessionID string
+	Steps     []tools.PlanStep
+}
+
+// FromMessage returns the steps of the last plan the message sets with the
+// plan_update tool, false when it sets none.
+func FromMessage(msg message.Message) ([]tools.PlanStep, bool) {
+	calls := msg.ToolCalls()
+	for i := len(calls) - 1; i >= 0; i-- {
+		call := calls[i]
+		if call.Name != tools.PlanUpdateToolName || !call.Finished {
+			continue
+		}
+		if steps, err := tools.ParsePlanUpdate(call.Input); err == nil {
+			return steps, true
+		}
+	}
+	return nil, false
+}
+
+// Latest returns the steps of the last plan set in the messages, false when
+// the agent made no plan.
+func Latest(msgs []message.Message) ([]tools.PlanStep, bool) {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if steps, ok := FromMessage(msgs[i]); ok {
+			return steps, true
+		}
+	}
+	return nil, false
+}
+
+// Progress returns the number of completed steps and the number of steps.
+func Progress(steps []tools.PlanStep) (int, int) {
+	completed := 0
+	for _, step := range steps {
+		if step.Status == tools.PlanStepCompleted {
+			completed++
+		}
+	}
+	return completed, len(steps)
+}
+
+// RenderOptions contains options for rendering plans.
+type RenderOptions struct {
+	MaxWidth    int
+	MaxItems    int
+	ShowSection bool
+	SectionName string
+}
+
+// RenderPlanList renders the steps of a plan as a checklist. When there are
+// more steps than MaxItems, the completed steps at the top are folded first.
+func RenderPlanList(steps []tools.PlanStep, opts RenderOptions) []string {
+	t := styles.CurrentTheme()
+	planList := []string{}
+
+	if opts.ShowSection {
+		sectionName := opts.SectionName
+		if sectionName == "" {
+			sectionName = "Plan"
+		}
+		planList = append(planList, t.S().Subtle.Render(sectionName), "")
+	}
+
+	if len(steps) == 0 {
+		planList = append(planList, t.S().Base.Foreground(t.Border).Render("None"))
+		return planList
+	}
+
+	skipped, shown := visibleSteps(steps, opts.MaxItems)
+	if skipped > 0 {
+		planList = append(planList, t.S().Subtle.Render(fmt.Sprintf("%s %d completed", styles.CheckIcon, skipped)))
+	}
+	for _, step := range steps[skipped : skipped+shown] {
+		planList = append(planList, renderStep(step, opts.MaxWidth))
+	}
+	return planList
+}
+
+// visibleSteps returns how many steps at the top are folded into a single
+// line, and how many steps are shown after them, to fit in maxItems lines.
+func visibleSteps(steps []tools.PlanStep, maxItems int) (int, int) {
+	if maxItems <= 0 || len(steps) <= maxItems {
+		return 0, len(steps)
+	}
+	skipped := 0
+	for skipped < len(steps) && steps[skipped].Status == tools.PlanStepCompleted {
+		skipped++
+	}
+	// Fold no more steps than needed to fit, and not a single one as it
+	// would take a line anyway.
+	skipped = min(skipped, len(steps)-maxItems+1)
+	if skipped <= 1 {
+		return 0, maxItems
+	}
+	return skipped, min(len(steps)-skipped, maxItems-1)
+}
+
+func renderStep(step tools.PlanStep, width int) string {
+	t := styles.CurrentTheme()
+	var icon, content string
+	switch step.Status {
+	case tools.PlanStepCompleted:
+		icon = t.S().Base.Foreground(t.Success).Render(styles.CheckIcon)
+		content = t.S().Subtle.Strikethrough(true).Render(truncate(step.Content, width))
+	case tools.PlanStepInProgress:
+		icon = t.S().Base.Foreground(t.Warning).Render("●")
+		content = t.S().Text.Bold(true).Render(truncate(step.Content, width))
+	default:
+		icon = t.S().Subtle.Render("○")
+		content = t.S().Muted.Render(truncate(step.Content, width))
+	}
+	return icon + " " + content
+}
+
+// truncate fits the content of a step on the line after its icon.
+func truncate(content string, width int) string {
+	if width <= 0 {
+		return content
+	}
+	return ansi.Truncate(content, max(0, width-2), "…")
+}
+
+// RenderPlanBlock renders a complete plan block with optional truncation
+// indicator.
+func RenderPlanBlock(steps []tools.PlanStep, opts RenderOptions, showTruncationIndicator bool) string {
+	t := styles.CurrentTheme()
+	planList := RenderPlanList(steps, opts)
+
+	if showTruncationIndicator {
+		skipped, shown := visibleSteps(steps, opts.MaxItems)
+		if remaining := len(steps) - skipped - shown; remaining == 1 {
+			planList = append(planList, t.S().Base.Foreground(t.FgMuted).Render("…"))
+		} else if remaining > 1 {
+			planList = append(planList,
+				t.S().Base.Foreground(t.FgSubtle).Render(fmt.Sprintf("…and %d more", remaining)),
+			)
+		}
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, planList...)
+	if opts.MaxWidth > 0 {
+		return lipgloss.NewStyle().Width(opts.MaxWidth).Render(content)
+	}
+	return content
+}
@@ -0,0 +1,82 @@
+package checklist
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatest(t *testing.T) {
+	t.Parallel()
+
+	planCall := func(input string, finished bool) message.ToolCall {
+		return message.ToolCall{Name: tools.PlanUpdateToolName, Input: input, Finished: finished}
+	}
+	msgs := []message.Message{
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			planCall(`{"steps":[{"content":"First","status":"in_progress"}]}`, true),
+		}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			planCall(`{"steps":[{"content":"First","status":"completed"},{"content":"Second","status":"in_progress"}]}`, true),
+			// Invalid plans are ignored, like the tool rejects them.
+			planCall(`{"steps":[{"content":"First","status":"done"}]}`, true),
+			message.ToolCall{Name: tools.ViewToolName, Input: `{}`, Finished: true},
+		}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			// Still streaming.
+			planCall(`{"steps":[{"content":"Fir`, false),
+		}},
+	}
+
+	steps, ok := Latest(msgs)
+	require.True(t, ok)
+	require.Equal(t, []tools.PlanStep{
+		{Content: "First", Status: tools.PlanStepCompleted},
+		{Content: "Second", Status: tools.PlanStepInProgress},
+	}, steps)
+
+	completed, total := Progress(steps)
+	require.Equal(t, 1, completed)
+	require.Equal(t, 2, total)
+
+	_, ok = Latest(msgs[2:])
+	require.False(t, ok)
+}
+
+func TestVisibleSteps(t *testing.T) {
+	t.Parallel()
+
+	plan := func(completed, total int) []tools.PlanStep {
+		steps := make([]tools.PlanStep, total)
+		for i := range steps {
+			steps[i] = tools.PlanStep{Content: "Step", Status: tools.PlanStepPending}
+			if i < completed {
+				steps[i].Status = tools.PlanStepCompleted
+			}
+		}
+		return steps
+	}
+
+	for _, tt := range []struct {
+		name                     string
+		steps                    []tools.PlanStep
+		maxItems                 int
+		wantSkipped, wantVisible int
+	}{
+		{"fits", plan(2, 4), 5, 0, 4},
+		{"no limit", plan(2, 20), 0, 0, 20},
+		{"nothing completed", plan(0, 8), 5, 0, 5},
+		{"single completed step", plan(1, 8), 5, 0, 5},
+		{"folds completed steps", plan(3, 8), 5, 3, 4},
+		{"folds only what is needed", plan(7, 8), 5, 4, 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			skipped, shown := visibleSteps(tt.steps, tt.maxItems)
+			require.Equal(t, tt.wantSkipped, skipped)
+			require.Equal(t, tt.wantVisible, shown)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package completions
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 type KeyMap struct {
@@ -14,7 +15,7 @@ type KeyMap struct {
 }
 
 func DefaultKeyMap() KeyMap {
-	return KeyMap{
+	return keymap.Apply("completions", KeyMap{
 		Down: key.NewBinding(
 			key.WithKeys("down"),
 			key.WithHelp("down", "move down"),
@@ -39,7 +40,7 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+p"),
 			key.WithHelp("ctrl+p", "insert previous"),
 		),
-	}
+	})
 }
 
 // KeyBindings implements layout.KeyMapProvider
@@ -1,10 +1,18 @@
 package status
 
 import (
+	"fmt"
+	"image/color"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/v2/help"
 	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/lipgloss/v2"
@@ -23,8 +31,16 @@ type statusCmp struct {
 	messageTTL time.Duration
 	help       help.Model
 	keyMap     help.KeyMap
+
+	// sessionID is the session whose usage is shown, usage is set once it
+	// is known.
+	sessionID string
+	usage     *agent.Usage
 }
 
+// contextBarWidth is the number of cells of the context window bar.
+const contextBarWidth = 5
+
 // clearMessageCmd is a command that clears status messages after a timeout
 func (m *statusCmp) clearMessageCmd(ttl time.Duration) tea.Cmd {
 	return tea.Tick(ttl, func(time.Time) tea.Msg {
@@ -40,8 +56,26 @@ func (m *statusCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
-		m.help.Width = msg.Width - 2
 		return m, nil
+	case chat.SessionSelectedMsg:
+		if msg.ID != m.sessionID {
+			m.sessionID = msg.ID
+			m.usage = sessionUsage(msg)
+		}
+	case chat.SessionClearedMsg:
+		m.sessionID = ""
+		m.usage = nil
+	case pubsub.Event[session.Session]:
+		// Summarizing the session empties its context.
+		if msg.Payload.ID == m.sessionID && m.usage != nil {
+			m.usage.Cost = msg.Payload.Cost
+			m.usage.ContextTokens = msg.Payload.PromptTokens + msg.Payload.CompletionTokens
+		}
+	case pubsub.Event[agent.AgentEvent]:
+		if msg.Payload.Type == agent.AgentEventTypeUsage && msg.Payload.SessionID == m.sessionID {
+			usage := msg.Payload.Usage
+			m.usage = &usage
+		}
 
 	// Handle status info
 	case util.InfoMsg:
@@ -59,11 +93,113 @@ func (m *statusCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m *statusCmp) View() string {
 	t := styles.CurrentTheme()
-	status := t.S().Base.Padding(0, 1, 1, 1).Render(m.help.View(m.keyMap))
 	if m.info.Msg != "" {
-		status = m.infoMsg()
+		return m.infoMsg()
+	}
+	usage := m.usageView()
+	m.help.Width = m.width - 2
+	if usage != "" {
+		m.help.Width -= lipgloss.Width(usage) + 2
+		if m.help.Width < m.width/2 {
+			// Not enough room, the keys matter more.
+			usage = ""
+			m.help.Width = m.width - 2
+		}
+	}
+	helpView := m.help.View(m.keyMap)
+	if usage == "" {
+		return t.S().Base.Padding(0, 1, 1, 1).Render(helpView)
+	}
+	helpView = lipgloss.NewStyle().Width(m.help.Width + 2).Render(helpView)
+	return t.S().Base.Padding(0, 1, 1, 1).Render(
+		lipgloss.JoinHorizontal(lipgloss.Top, helpView, usage),
+	)
+}
+
+// sessionUsage returns the usage stored with the session, until the usage of
+// its next turn is known.
+func sessionUsage(sess session.Session) *agent.Usage {
+	if sess.ID == "" {
+		return nil
+	}
+	usage := &agent.Usage{
+		Cost:          sess.Cost,
+		ContextTokens: sess.PromptTokens + sess.CompletionTokens,
+	}
+	if cfg := config.Get(); cfg != nil {
+		if model := cfg.GetModelByType(cfg.Agents["coder"].Model); model != nil {
+			usage.ContextWindow = model.ContextWindow
+		}
+	}
+	return usage
+}
+
+// usageView shows the cost of the session, how the model was routed, the
+// tokens of the last turn, how full the context window is and how much of the
+// input was cached.
+func (m *statusCmp) usageView() string {
+	if m.usage == nil {
+		return ""
+	}
+	t := styles.CurrentTheme()
+	muted := t.S().Muted
+	subtle := t.S().Subtle
+	sep := subtle.Render(" · ")
+
+	parts := []string{muted.Render(fmt.Sprintf("$%.2f", m.usage.Cost))}
+	if m.usage.Route != "" {
+		parts = append(parts, muted.Render(m.usage.Route))
+	}
+	if m.usage.TurnTokens > 0 {
+		parts = append(parts, muted.Render(FormatTokens(m.usage.TurnTokens))+subtle.Render(" turn"))
+	}
+	if m.usage.ContextWindow > 0 {
+		used := m.usage.ContextUsed()
+		percent := t.S().Base.Foreground(contextColor(used)).Render(fmt.Sprintf("%d%%", int(used*100)))
+		parts = append(parts, contextBar(used)+" "+percent)
+	}
+	if m.usage.InputTokens > 0 {
+		parts = append(parts, muted.Render(fmt.Sprintf("%d%%", int(m.usage.CacheHitRatio()*100)))+subtle.Render(" cached"))
+	}
+	return strings.Join(parts, sep)
+}
+
+// contextColor goes from green to red as the context window fills up.
+func contextColor(used float64) color.Color {
+	t := styles.CurrentTheme()
+	return styles.GradientColor(used, t.Success, t.Warning, t.Error)
+}
+
+// contextBar shows how full the context window is, the filled cells taking
+// the color of the gradient at their position.
+func contextBar(used float64) string {
+	t := styles.CurrentTheme()
+	filled := int(used*contextBarWidth + 0.5)
+	var b strings.Builder
+	for i := range contextBarWidth {
+		if i >= filled {
+			b.WriteString(t.S().Subtle.Render("▱"))
+			continue
+		}
+		position := float64(i) / float64(contextBarWidth-1)
+		b.WriteString(t.S().Base.Foreground(contextColor(position)).Render("▰"))
+	}
+	return b.String()
+}
+
+// FormatTokens formats the tokens in a human readable way, like 110K or 1.2M.
+func FormatTokens(tokens int64) string {
+	var formatted string
+	switch {
+	case tokens >= 1_000_000:
+		formatted = fmt.Sprintf("%.1fM", float64(tokens)/1_000_000)
+	case tokens >= 1_000:
+		formatted = fmt.Sprintf("%.1fK", float64(tokens)/1_000)
+	default:
+		return fmt.Sprintf("%d", tokens)
 	}
-	return status
+	formatted = strings.Replace(formatted, ".0K", "K", 1)
+	return strings.Replace(formatted, ".0M", "M", 1)
 }
 
 func (m *statusCmp) infoMsg() string {
@@ -0,0 +1,72 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/tui/components/chat"
+)
+
+type testKeyMap struct{}
+
+func (testKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help"))}
+}
+
+func (k testKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+func TestUsageView(t *testing.T) {
+	t.Parallel()
+
+	m := NewStatusCmp().(*statusCmp)
+	m.SetKeyMap(testKeyMap{})
+	m.Update(tea.WindowSizeMsg{Width: 120})
+	require.Empty(t, m.usageView())
+
+	m.sessionID = "s"
+	usage := agent.Usage{
+		Cost:            1.234,
+		TurnTokens:      12_300,
+		ContextTokens:   60_000,
+		ContextWindow:   200_000,
+		InputTokens:     10_000,
+		CacheReadTokens: 8_000,
+	}
+	// The usage of other sessions is ignored.
+	m.Update(pubsub.Event[agent.AgentEvent]{Payload: agent.AgentEvent{Type: agent.AgentEventTypeUsage, SessionID: "other", Usage: usage}})
+	require.Empty(t, m.usageView())
+
+	m.Update(pubsub.Event[agent.AgentEvent]{Payload: agent.AgentEvent{Type: agent.AgentEventTypeUsage, SessionID: "s", Usage: usage}})
+	require.Equal(t, "$1.23 · 12.3K turn · ▰▰▱▱▱ 30% · 80% cached", ansi.Strip(m.usageView()))
+	require.Contains(t, ansi.Strip(m.View()), "80% cached")
+
+	// Summarizing the session empties its context.
+	m.Update(pubsub.Event[session.Session]{Payload: session.Session{ID: "s", Cost: 1.5}})
+	require.Equal(t, "$1.50 · 12.3K turn · ▱▱▱▱▱ 0% · 80% cached", ansi.Strip(m.usageView()))
+
+	// The route of the turn is shown when the routing is enabled.
+	usage.Route = "small draft ✓"
+	m.Update(pubsub.Event[agent.AgentEvent]{Payload: agent.AgentEvent{Type: agent.AgentEventTypeUsage, SessionID: "s", Usage: usage}})
+	require.Equal(t, "$1.23 · small draft ✓ · 12.3K turn · ▰▰▱▱▱ 30% · 80% cached", ansi.Strip(m.usageView()))
+
+	m.Update(chat.SessionClearedMsg{})
+	require.Empty(t, m.usageView())
+}
+
+func TestFormatTokens(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "999", FormatTokens(999))
+	require.Equal(t, "1K", FormatTokens(1_000))
+	require.Equal(t, "12.3K", FormatTokens(12_345))
+	require.Equal(t, "2M", FormatTokens(2_000_000))
+}
@@ -0,0 +1,231 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/dustin/go-humanize"
+)
+
+const ArtifactsDialogID dialogs.DialogID = "artifacts"
+
+// ArtifactsDialog interface for the dialog listing the artifacts of the
+// session.
+type ArtifactsDialog interface {
+	dialogs.DialogModel
+}
+
+type ArtifactsList = list.FilterableList[list.CompletionItem[artifact.Artifact]]
+
+type artifactsDialogCmp struct {
+	wWidth        int
+	wHeight       int
+	width         int
+	keyMap        KeyMap
+	artifactsList ArtifactsList
+	help          help.Model
+	service       artifact.Service
+}
+
+// NewArtifactsDialogCmp creates a new dialog to open the artifacts stored
+// for the session.
+func NewArtifactsDialogCmp(service artifact.Service, artifacts []artifact.Artifact) ArtifactsDialog {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	items := make([]list.CompletionItem[artifact.Artifact], len(artifacts))
+	for i, a := range artifacts {
+		items[i] = list.NewCompletionItem(
+			a.Name,
+			a,
+			list.WithCompletionID(a.ID),
+			list.WithCompletionShortcut(artifactSummary(a)),
+		)
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	artifactsList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Enter an artifact name"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &artifactsDialogCmp{
+		keyMap:        keyMap,
+		artifactsList: artifactsList,
+		help:          help,
+		service:       service,
+	}
+}
+
+// artifactSummary describes where the artifact comes from and its size.
+func artifactSummary(a artifact.Artifact) string {
+	summary := humanize.Bytes(uint64(a.Size))
+	if a.ToolName != "" {
+		summary = fmt.Sprintf("%s, %s", a.ToolName, summary)
+	}
+	return summary
+}
+
+func (s *artifactsDialogCmp) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	cmds = append(cmds, s.artifactsList.Init())
+	cmds = append(cmds, s.artifactsList.Focus())
+	return tea.Sequence(cmds...)
+}
+
+func (s *artifactsDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.wWidth = msg.Width
+		s.wHeight = msg.Height
+		s.width = min(120, s.wWidth-8)
+		s.artifactsList.SetInputWidth(s.listWidth() - 2)
+		return s, s.artifactsList.SetSize(s.listWidth(), s.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, s.keyMap.Select):
+			selectedItem := s.artifactsList.SelectedItem()
+			if selectedItem != nil {
+				a := (*selectedItem).Value()
+				return s, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					openArtifact(s.service, a),
+				)
+			}
+		case key.Matches(msg, s.keyMap.Close):
+			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := s.artifactsList.Update(msg)
+			s.artifactsList = u.(ArtifactsList)
+			return s, cmd
+		}
+	}
+	return s, nil
+}
+
+// plainCopyGrace is how long the decrypted copy of an artifact is kept after
+// the program opening it exits, the launchers of the viewers and of some
+// editors exiting before the file is read.
+const plainCopyGrace = 30 * time.Second
+
+// openArtifact opens text artifacts in the editor and everything else, like
+// images, with the default application of the system. Encrypted artifacts
+// are opened from a decrypted copy, removed once the program exits.
+func openArtifact(service artifact.Service, a artifact.Artifact) tea.Cmd {
+	path, remove, err := service.PlainPath(a)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	done := func() {
+		time.AfterFunc(plainCopyGrace, remove)
+	}
+	if !a.IsText() {
+		var c *exec.Cmd
+		switch runtime.GOOS {
+		case "darwin":
+			c = exec.CommandContext(context.TODO(), "open", path)
+		case "windows":
+			c = exec.CommandContext(context.TODO(), "rundll32", "url.dll,FileProtocolHandler", path)
+		default:
+			c = exec.CommandContext(context.TODO(), "xdg-open", path)
+		}
+		return func() tea.Msg {
+			if err := c.Start(); err != nil {
+				remove()
+				return util.ReportError(err)
+			}
+			go func() {
+				_ = c.Wait()
+				done()
+			}()
+			return nil
+		}
+	}
+
+	return util.OpenInEditorThen(path, 0, done)
+}
+
+func (s *artifactsDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	listView := s.artifactsList.View()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Artifacts", s.width-4)),
+		listView,
+		"",
+		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
+	)
+
+	return s.style().Render(content)
+}
+
+func (s *artifactsDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := s.artifactsList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = s.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (s *artifactsDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(s.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (s *artifactsDialogCmp) listHeight() int {
+	return s.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (s *artifactsDialogCmp) listWidth() int {
+	return s.width - 2 // 2 for the border
+}
+
+func (s *artifactsDialogCmp) Position() (int, int) {
+	row := s.wHeight/4 - 2 // just a bit above the center
+	col := s.wWidth / 2
+	col -= s.width / 2
+	return row, col
+}
+
+func (s *artifactsDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := s.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements ArtifactsDialog.
+func (s *artifactsDialogCmp) ID() dialogs.DialogID {
+	return ArtifactsDialogID
+}
@@ -0,0 +1,320 @@
+package blocks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/highlight"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+const (
+	BlocksDialogID dialogs.DialogID = "blocks"
+
+	maxDialogWidth = 80
+)
+
+// Block is a part of a message that can be copied or saved on its own.
+type Block struct {
+	// Language is the language of a code block, empty for the message.
+	Language string
+	// FileName is the file the block is saved to by default.
+	FileName string
+	Content  string
+	code     bool
+}
+
+// Title describes the block in the list.
+func (b Block) Title() string {
+	if !b.code {
+		return "Message"
+	}
+	language := b.Language
+	if language == "" {
+		language = "Code"
+	}
+	return language + " block"
+}
+
+// Extract returns the whole message followed by its code blocks.
+func Extract(content string) []Block {
+	blocks := []Block{{FileName: "message.md", Content: content}}
+
+	source := []byte(content)
+	doc := goldmark.New().Parser().Parse(text.NewReader(source))
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var info string
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock:
+			if n.Info != nil {
+				info = string(n.Info.Segment.Value(source))
+			}
+		case *ast.CodeBlock:
+		default:
+			return ast.WalkContinue, nil
+		}
+		var code strings.Builder
+		lines := n.Lines()
+		for i := range lines.Len() {
+			line := lines.At(i)
+			code.Write(line.Value(source))
+		}
+		language := highlight.FenceLanguage(info)
+		fileName := highlight.FenceFileName(info)
+		if fileName == "" {
+			fileName = fmt.Sprintf("snippet-%d%s", len(blocks), highlight.Extension(language))
+		}
+		blocks = append(blocks, Block{
+			Language: language,
+			FileName: fileName,
+			Content:  code.String(),
+			code:     true,
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	return blocks
+}
+
+// blockAt returns the index of the code block showing the line of the
+// rendered message, 0 for the message when the line is not part of one.
+func blockAt(blocks []Block, line string) int {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return 0
+	}
+	for i, b := range blocks {
+		if !b.code {
+			continue
+		}
+		for codeLine := range strings.SplitSeq(b.Content, "\n") {
+			if strings.TrimSpace(codeLine) == line {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// BlocksDialog lists the message and its code blocks, to copy them or save
+// them to files.
+type BlocksDialog interface {
+	dialogs.DialogModel
+}
+
+type blocksDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+
+	blocks   []Block
+	selected int
+
+	saving bool
+	// overwrite is the existing file the user was warned about, saving to it
+	// again replaces it.
+	overwrite string
+	input     textinput.Model
+
+	keyMap KeyMap
+	help   help.Model
+}
+
+// NewBlocksDialogCmp creates the dialog for the blocks of the message
+// content, with the code block showing the line of the rendered message
+// selected, if any.
+func NewBlocksDialogCmp(content, line string) BlocksDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	ti := textinput.New()
+	ti.Prompt = ""
+	ti.SetStyles(t.S().TextInput)
+	blocks := Extract(content)
+	return &blocksDialogCmp{
+		blocks:   blocks,
+		selected: blockAt(blocks, line),
+		input:    ti,
+		keyMap:   DefaultKeyMap(),
+		help:     h,
+	}
+}
+
+func (b *blocksDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (b *blocksDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		b.wWidth = msg.Width
+		b.wHeight = msg.Height
+		b.width = min(maxDialogWidth, b.wWidth-4)
+		b.input.SetWidth(b.width - 4)
+	case tea.KeyPressMsg:
+		if b.saving {
+			return b, b.updateSaving(msg)
+		}
+		switch {
+		case key.Matches(msg, b.keyMap.Next):
+			b.selected = min(b.selected+1, len(b.blocks)-1)
+		case key.Matches(msg, b.keyMap.Previous):
+			b.selected = max(b.selected-1, 0)
+		case key.Matches(msg, b.keyMap.Copy):
+			block := b.blocks[b.selected]
+			return b, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				copyToClipboard(block.Content, fmt.Sprintf("%s copied to clipboard", block.Title())),
+			)
+		case key.Matches(msg, b.keyMap.Save):
+			b.saving = true
+			b.overwrite = ""
+			b.input.SetValue(b.blocks[b.selected].FileName)
+			b.input.CursorEnd()
+			return b, b.input.Focus()
+		case key.Matches(msg, b.keyMap.Close):
+			return b, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+	}
+	return b, nil
+}
+
+// updateSaving handles the keys while the file to save the block to is
+// entered.
+func (b *blocksDialogCmp) updateSaving(msg tea.KeyPressMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, b.keyMap.Confirm):
+		path := strings.TrimSpace(b.input.Value())
+		if path == "" {
+			return util.ReportWarn("Enter the file to save the block to")
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(config.Get().WorkingDir(), path)
+		}
+		if _, err := os.Stat(path); err == nil && b.overwrite != path {
+			b.overwrite = path
+			return util.ReportWarn("The file already exists, press enter again to replace it")
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return util.ReportError(err)
+		}
+		if err := saveBlock(path, b.blocks[b.selected].Content); err != nil {
+			return util.ReportError(err)
+		}
+		return tea.Sequence(
+			util.CmdHandler(dialogs.CloseDialogMsg{}),
+			util.ReportInfo(fmt.Sprintf("Saved to %s", b.input.Value())),
+		)
+	case key.Matches(msg, b.keyMap.Close):
+		b.saving = false
+		b.input.Blur()
+		return nil
+	}
+	b.overwrite = ""
+	var cmd tea.Cmd
+	b.input, cmd = b.input.Update(msg)
+	return cmd
+}
+
+func saveBlock(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to save block: %w", err)
+	}
+	return nil
+}
+
+// copyToClipboard copies the text with OSC 52 and the native clipboard, for
+// compatibility with different terminal emulators and environments.
+func copyToClipboard(text, info string) tea.Cmd {
+	return tea.Sequence(
+		tea.SetClipboard(text),
+		func() tea.Msg {
+			_ = clipboard.WriteAll(text)
+			return nil
+		},
+		util.ReportInfo(info),
+	)
+}
+
+// content lists the blocks on a line each, with their size and their first
+// line.
+func (b *blocksDialogCmp) content() string {
+	t := styles.CurrentTheme()
+	width := b.width - 4
+	lines := make([]string, 0, len(b.blocks))
+	for i, block := range b.blocks {
+		lineCount := strings.Count(strings.TrimRight(block.Content, "\n"), "\n") + 1
+		title := fmt.Sprintf("%s · %d lines", block.Title(), lineCount)
+		if block.code {
+			title = fmt.Sprintf("%s · %s", title, block.FileName)
+		}
+		first, _, _ := strings.Cut(strings.TrimSpace(block.Content), "\n")
+		if i == b.selected {
+			line := ansi.Truncate(title+"  "+first, width, "…")
+			lines = append(lines, t.S().TextSelected.Width(width).Render(line))
+			continue
+		}
+		preview := ansi.Truncate(first, max(0, width-lipgloss.Width(title)-2), "…")
+		lines = append(lines, t.S().Text.Render(title)+"  "+t.S().Subtle.Render(preview))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (b *blocksDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	parts := []string{
+		t.S().Title.Render("Message Blocks"),
+		"",
+		b.content(),
+		"",
+	}
+	if b.saving {
+		parts = append(parts,
+			t.S().Text.Render("Save to"),
+			b.input.View(),
+			"",
+			b.help.View(savingKeyMap(b.keyMap)),
+		)
+	} else {
+		parts = append(parts, b.help.View(b.keyMap))
+	}
+	return t.S().Base.
+		Width(b.width).
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(lipgloss.JoinVertical(lipgloss.Left, parts...))
+}
+
+func (b *blocksDialogCmp) Position() (int, int) {
+	row := b.wHeight/2 - lipgloss.Height(b.View())/2
+	col := b.wWidth/2 - b.width/2
+	return row, col
+}
+
+func (b *blocksDialogCmp) ID() dialogs.DialogID {
+	return BlocksDialogID
+}
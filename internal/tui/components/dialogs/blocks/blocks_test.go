@@ -0,0 +1,52 @@
+package blocks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	content := "Here is the fix:\n\n" +
+		"```go:cmd/main.go\npackage main\n\nfunc main() {}\n```\n\n" +
+		"Then run:\n\n" +
+		"- the tests\n\n  ```sh\n  go test ./...\n  ```\n\n" +
+		"```\nplain\n```\n"
+
+	blocks := Extract(content)
+	require.Len(t, blocks, 4)
+
+	require.Equal(t, "Message", blocks[0].Title())
+	require.Equal(t, content, blocks[0].Content)
+	require.Equal(t, "message.md", blocks[0].FileName)
+
+	require.Equal(t, "Go block", blocks[1].Title())
+	require.Equal(t, "cmd/main.go", blocks[1].FileName)
+	require.Equal(t, "package main\n\nfunc main() {}\n", blocks[1].Content)
+
+	require.Equal(t, "Bash", blocks[2].Language)
+	require.Equal(t, "snippet-2.sh", blocks[2].FileName)
+	require.Equal(t, "go test ./...\n", blocks[2].Content)
+
+	require.Equal(t, "Code block", blocks[3].Title())
+	require.Equal(t, "snippet-3.txt", blocks[3].FileName)
+
+	require.Equal(t, 2, blockAt(blocks, "    go test ./...   "))
+	require.Equal(t, 1, blockAt(blocks, "  func main() {}"))
+	require.Equal(t, 0, blockAt(blocks, "Then run:"))
+	require.Equal(t, 0, blockAt(blocks, ""))
+}
+
+func TestSaveBlock(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cmd", "main.go")
+	require.NoError(t, saveBlock(path, "package main"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "package main\n", string(data))
+}
@@ -0,0 +1,94 @@
+package blocks
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
+)
+
+// KeyMap defines the keyboard bindings for the blocks dialog.
+type KeyMap struct {
+	Copy,
+	Save,
+	Next,
+	Previous,
+	Confirm,
+	Close key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return keymap.Apply("blocks", KeyMap{
+		Copy: key.NewBinding(
+			key.WithKeys("enter", "c", "y"),
+			key.WithHelp("enter", "copy"),
+		),
+		Save: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "save to file"),
+		),
+		Next: key.NewBinding(
+			key.WithKeys("down", "j", "ctrl+n"),
+			key.WithHelp("↓", "next block"),
+		),
+		Previous: key.NewBinding(
+			key.WithKeys("up", "k", "ctrl+p"),
+			key.WithHelp("↑", "previous block"),
+		),
+		Confirm: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "save"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "close"),
+		),
+	})
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Copy,
+		k.Save,
+		k.Next,
+		k.Previous,
+		k.Close,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.KeyBindings()}
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		key.NewBinding(
+			key.WithKeys("down", "up"),
+			key.WithHelp("↑↓", "choose"),
+		),
+		k.Copy,
+		k.Save,
+		k.Close,
+	}
+}
+
+// savingKeyMap is the help shown while the file to save a block to is
+// entered.
+type savingKeyMap KeyMap
+
+// FullHelp implements help.KeyMap.
+func (k savingKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// ShortHelp implements help.KeyMap.
+func (k savingKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		k.Confirm,
+		key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/charmbracelet/bubbles/v2/key"
 	"github.com/charmbracelet/bubbles/v2/textinput"
 	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/llm/agent"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
@@ -23,6 +24,14 @@ type ShowArgumentsDialogMsg struct {
 	CommandID string
 	Content   string
 	ArgNames  []string
+	// ArgDescriptions holds the descriptions of the arguments that have
+	// one, by name.
+	ArgDescriptions map[string]string
+	// OnSubmit runs the command with the arguments, when they aren't
+	// replaced in the content, e.g. for MCP prompts.
+	OnSubmit func(args map[string]string) tea.Cmd
+	// Options are passed on to the command run with the content.
+	Options agent.RunOptions
 }
 
 // CloseArgumentsDialogMsg is a message that is sent when the arguments dialog is closed.
@@ -49,16 +58,21 @@ type commandArgumentsDialogCmp struct {
 	commandID  string
 	content    string
 	argNames   []string
+	onSubmit   func(args map[string]string) tea.Cmd
+	options    agent.RunOptions
 	help       help.Model
 }
 
-func NewCommandArgumentsDialog(commandID, content string, argNames []string) CommandArgumentsDialog {
+func NewCommandArgumentsDialog(msg ShowArgumentsDialogMsg) CommandArgumentsDialog {
 	t := styles.CurrentTheme()
-	inputs := make([]textinput.Model, len(argNames))
+	inputs := make([]textinput.Model, len(msg.ArgNames))
 
-	for i, name := range argNames {
+	for i, name := range msg.ArgNames {
 		ti := textinput.New()
 		ti.Placeholder = fmt.Sprintf("Enter value for %s...", name)
+		if description := msg.ArgDescriptions[name]; description != "" {
+			ti.Placeholder = description
+		}
 		ti.SetWidth(40)
 		ti.SetVirtualCursor(false)
 		ti.Prompt = ""
@@ -77,9 +91,11 @@ func NewCommandArgumentsDialog(commandID, content string, argNames []string) Com
 	return &commandArgumentsDialogCmp{
 		inputs:     inputs,
 		keys:       DefaultArgumentsDialogKeyMap(),
-		commandID:  commandID,
-		content:    content,
-		argNames:   argNames,
+		commandID:  msg.CommandID,
+		content:    msg.Content,
+		argNames:   msg.ArgNames,
+		onSubmit:   msg.OnSubmit,
+		options:    msg.Options,
 		focusIndex: 0,
 		width:      60,
 		help:       help.New(),
@@ -101,6 +117,16 @@ func (c *commandArgumentsDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch {
 		case key.Matches(msg, c.keys.Confirm):
 			if c.focusIndex == len(c.inputs)-1 {
+				if c.onSubmit != nil {
+					args := make(map[string]string, len(c.argNames))
+					for i, name := range c.argNames {
+						args[name] = c.inputs[i].Value()
+					}
+					return c, tea.Sequence(
+						util.CmdHandler(dialogs.CloseDialogMsg{}),
+						c.onSubmit(args),
+					)
+				}
 				content := c.content
 				for i, name := range c.argNames {
 					value := c.inputs[i].Value()
@@ -111,6 +137,7 @@ func (c *commandArgumentsDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					util.CmdHandler(dialogs.CloseDialogMsg{}),
 					util.CmdHandler(CommandRunCustomMsg{
 						Content: content,
+						Options: c.options,
 					}),
 				)
 			}
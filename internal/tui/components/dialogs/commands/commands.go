@@ -67,9 +67,24 @@ type (
 	OpenFilePickerMsg     struct{}
 	ToggleHelpMsg         struct{}
 	ToggleCompactModeMsg  struct{}
+	ToggleDiffPaneMsg     struct{}
 	ToggleThinkingMsg     struct{}
 	OpenExternalEditorMsg struct{}
 	ToggleYoloModeMsg     struct{}
+	TogglePlanModeMsg     struct{}
+	OpenPlanMsg           struct{}
+	OpenQueueMsg          struct{}
+	OpenSessionFilesMsg   struct{}
+	OpenArtifactsMsg      struct{}
+	OpenTrashMsg          struct{}
+	OpenRewindMsg         struct{}
+	OpenEditPromptMsg     struct{}
+	OpenPinMsg            struct{}
+	OpenUnpinMsg          struct{}
+	OpenWorkspacesMsg     struct{}
+	OpenMCPResourcesMsg   struct{}
+	OpenMCPServersMsg     struct{}
+	OpenStatusMsg         struct{}
 	CompactMsg            struct {
 		SessionID string
 	}
@@ -111,7 +126,7 @@ func (c *commandDialogCmp) Init() tea.Cmd {
 	if err != nil {
 		return util.ReportError(err)
 	}
-	c.userCommands = commands
+	c.userCommands = append(commands, MCPPromptCommands()...)
 	return c.SetCommandType(c.commandType)
 }
 
@@ -276,6 +291,15 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				return util.CmdHandler(SwitchSessionsMsg{})
 			},
 		},
+		{
+			ID:          "switch_workspace",
+			Title:       "Switch Workspace",
+			Description: "Switch to the workspace of another project",
+			Shortcut:    "ctrl+x",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenWorkspacesMsg{})
+			},
+		},
 		{
 			ID:          "switch_model",
 			Title:       "Switch Model",
@@ -284,6 +308,7 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				return util.CmdHandler(SwitchModelMsg{})
 			},
 		},
+		StatusCommand(),
 	}
 
 	// Only show compact command if there's an active session
@@ -298,6 +323,31 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				})
 			},
 		})
+		commands = append(commands, Command{
+			ID:          "session_files",
+			Title:       "Session Files",
+			Description: "Jump to the files read or edited in this session",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenSessionFilesMsg{})
+			},
+		})
+		commands = append(commands, Command{
+			ID:          "artifacts",
+			Title:       "Open Artifacts",
+			Description: "Open the files and large outputs produced by tools in this session",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenArtifactsMsg{})
+			},
+		})
+		commands = append(commands, RewindCommand(), EditPromptCommand(), PinCommand(), UnpinCommand())
+		commands = append(commands, Command{
+			ID:          "trash",
+			Title:       "Restore Files",
+			Description: "Restore the files deleted or overwritten in this session",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenTrashMsg{})
+			},
+		})
 	}
 
 	// Only show thinking toggle for Anthropic models that can reason
@@ -306,7 +356,7 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 		providerCfg := cfg.GetProviderForModel(agentCfg.Model)
 		model := cfg.GetModelByType(agentCfg.Model)
 		if providerCfg != nil && model != nil &&
-			providerCfg.Type == catwalk.TypeAnthropic && model.CanReason {
+			(providerCfg.Type == catwalk.TypeAnthropic || providerCfg.Type == config.TypeAnthropicCompat) && model.CanReason {
 			selectedModel := cfg.Models[agentCfg.Model]
 			status := "Enable"
 			if selectedModel.Think {
@@ -333,6 +383,17 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 			},
 		})
 	}
+	if c.sessionID != "" {
+		commands = append(commands, Command{
+			ID:          "toggle_diff_pane",
+			Title:       "Toggle Diff Pane",
+			Description: "Show or hide the session diff next to the chat",
+			Shortcut:    "ctrl+l",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ToggleDiffPaneMsg{})
+			},
+		})
+	}
 	if c.sessionID != "" {
 		agentCfg := config.Get().Agents["coder"]
 		model := config.Get().GetModelByType(agentCfg.Model)
@@ -349,6 +410,25 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 		}
 	}
 
+	if len(config.Get().MCP) > 0 {
+		commands = append(commands, Command{
+			ID:          "mcp_resources",
+			Title:       "Attach MCP Resource",
+			Description: "Attach a resource of an MCP server to the prompt",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenMCPResourcesMsg{})
+			},
+		})
+		commands = append(commands, Command{
+			ID:          "mcp_servers",
+			Title:       "MCP Servers",
+			Description: "Show the state of the MCP servers and restart them",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenMCPServersMsg{})
+			},
+		})
+	}
+
 	// Add external editor command if $EDITOR is available
 	if os.Getenv("EDITOR") != "" {
 		commands = append(commands, Command{
@@ -371,6 +451,30 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				return util.CmdHandler(ToggleYoloModeMsg{})
 			},
 		},
+		{
+			ID:          "toggle_plan",
+			Title:       "Toggle Plan Mode",
+			Description: "Propose the file changes and commands for review instead of applying them",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(TogglePlanModeMsg{})
+			},
+		},
+		{
+			ID:          "review_plan",
+			Title:       "Review Plan",
+			Description: "Review the proposed actions and execute or discard them",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenPlanMsg{})
+			},
+		},
+		{
+			ID:          "queued_messages",
+			Title:       "Queued Messages",
+			Description: "Edit or remove the messages waiting for the agent to finish",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(OpenQueueMsg{})
+			},
+		},
 		{
 			ID:          "toggle_help",
 			Title:       "Toggle Help",
@@ -380,16 +484,7 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				return util.CmdHandler(ToggleHelpMsg{})
 			},
 		},
-		{
-			ID:          "init",
-			Title:       "Initialize Project",
-			Description: "Create/Update the CRUSH.md memory file",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(chat.SendMsg{
-					Text: prompt.Initialize(),
-				})
-			},
-		},
+		InitCommand(),
 		{
 			ID:          "quit",
 			Title:       "Quit",
@@ -402,6 +497,86 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 	}...)
 }
 
+// InitCommand asks the agent to analyze the project and write its CRUSH.md
+// context file, also run as the /init slash command.
+func InitCommand() Command {
+	return Command{
+		ID:          "init",
+		Title:       "Initialize Project",
+		Description: "Create/Update the CRUSH.md memory file",
+		Handler: func(cmd Command) tea.Cmd {
+			return util.CmdHandler(chat.SendMsg{
+				Text: prompt.Initialize(),
+			})
+		},
+	}
+}
+
+// RewindCommand rolls the session back to one of its prompts, also run as
+// the /rewind slash command.
+func RewindCommand() Command {
+	return Command{
+		ID:          "rewind",
+		Title:       "Rewind Session",
+		Description: "Roll the session and its file changes back to one of its prompts",
+		Handler: func(cmd Command) tea.Cmd {
+			return util.CmdHandler(OpenRewindMsg{})
+		},
+	}
+}
+
+// EditPromptCommand edits one of the prompts of the session to regenerate
+// what follows it, also run as the /edit slash command.
+func EditPromptCommand() Command {
+	return Command{
+		ID:          "edit",
+		Title:       "Edit Prompt",
+		Description: "Edit one of the session prompts and regenerate everything after it",
+		Handler: func(cmd Command) tea.Cmd {
+			return util.CmdHandler(OpenEditPromptMsg{})
+		},
+	}
+}
+
+// PinCommand pins a file or a message to the context of the session, also
+// run as the /pin slash command.
+func PinCommand() Command {
+	return Command{
+		ID:          "pin",
+		Title:       "Pin to Context",
+		Description: "Keep a file or a message in the context of every request, even after a summary",
+		Handler: func(cmd Command) tea.Cmd {
+			return util.CmdHandler(OpenPinMsg{})
+		},
+	}
+}
+
+// UnpinCommand removes one of the pins of the session, also run as the
+// /unpin slash command.
+func UnpinCommand() Command {
+	return Command{
+		ID:          "unpin",
+		Title:       "Unpin from Context",
+		Description: "Stop keeping a pinned file or message in the context",
+		Handler: func(cmd Command) tea.Cmd {
+			return util.CmdHandler(OpenUnpinMsg{})
+		},
+	}
+}
+
+// StatusCommand checks the providers, language servers and MCP servers,
+// also run as the /status slash command.
+func StatusCommand() Command {
+	return Command{
+		ID:          "status",
+		Title:       "Check Status",
+		Description: "Check the API keys and models of the providers, and the language and MCP servers",
+		Handler: func(cmd Command) tea.Cmd {
+			return util.CmdHandler(OpenStatusMsg{})
+		},
+	}
+}
+
 func (c *commandDialogCmp) ID() dialogs.DialogID {
 	return CommandsDialogID
 }
@@ -1,16 +1,21 @@
 package commands
 
 import (
+	"cmp"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
 	"github.com/charmbracelet/crush/internal/tui/util"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -20,6 +25,72 @@ const (
 
 var namedArgPattern = regexp.MustCompile(`\$([A-Z][A-Z0-9_]*)`)
 
+// frontMatterDelimiter opens and closes the front matter of a custom command.
+const frontMatterDelimiter = "---"
+
+// commandFrontMatter is the optional YAML header of a custom command, e.g.
+//
+//	---
+//	description: Review the changes of the branch
+//	args:
+//	  - name: FOCUS
+//	    description: What to look at first
+//	model: small
+//	allowed_tools: [view, grep, glob]
+//	---
+type commandFrontMatter struct {
+	Description string       `yaml:"description"`
+	Args        []commandArg `yaml:"args"`
+	// Model is the type of the model running the command, large or small.
+	Model        config.SelectedModelType `yaml:"model"`
+	AllowedTools []string                 `yaml:"allowed_tools"`
+}
+
+// commandArg is an argument of a custom command, given by its name alone or
+// with a description.
+type commandArg struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+func (a *commandArg) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		a.Name = node.Value
+		return nil
+	}
+	type plain commandArg
+	return node.Decode((*plain)(a))
+}
+
+// parseCommand splits the content of a custom command into its front matter
+// and the prompt.
+func parseCommand(content string) (commandFrontMatter, string, error) {
+	var fm commandFrontMatter
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(content, frontMatterDelimiter+"\n") {
+		return fm, content, nil
+	}
+	rest := content[len(frontMatterDelimiter)+1:]
+	header, body, found := strings.Cut(rest, "\n"+frontMatterDelimiter+"\n")
+	if !found {
+		if header, found = strings.CutSuffix(rest, "\n"+frontMatterDelimiter); !found {
+			return fm, "", fmt.Errorf("the front matter isn't closed by %s", frontMatterDelimiter)
+		}
+	}
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		return fm, "", fmt.Errorf("invalid front matter: %w", err)
+	}
+	switch fm.Model {
+	case "", config.SelectedModelTypeLarge, config.SelectedModelTypeSmall:
+	default:
+		return fm, "", fmt.Errorf("unknown model %q, use %s or %s", fm.Model, config.SelectedModelTypeLarge, config.SelectedModelTypeSmall)
+	}
+	for i, arg := range fm.Args {
+		fm.Args[i].Name = strings.ToUpper(strings.TrimPrefix(arg.Name, "$"))
+	}
+	return fm, strings.TrimLeft(body, "\n"), nil
+}
+
 type commandLoader struct {
 	sources []commandSource
 }
@@ -42,6 +113,28 @@ func LoadCustomCommands() ([]Command, error) {
 	return loader.loadAll()
 }
 
+// CustomCommandPath returns the markdown file of the custom command with the
+// ID, e.g. user:review or project:frontend:lint.
+func CustomCommandPath(cfg *config.Config, id string) (string, error) {
+	for _, source := range buildCommandSources(cfg) {
+		var found string
+		_ = filepath.WalkDir(source.path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !isMarkdownFile(d.Name()) {
+				return err
+			}
+			if buildCommandID(path, source.path, source.prefix) == id {
+				found = path
+				return fs.SkipAll
+			}
+			return nil
+		})
+		if found != "" {
+			return found, nil
+		}
+	}
+	return "", fmt.Errorf("custom command %q not found", id)
+}
+
 func buildCommandSources(cfg *config.Config) []commandSource {
 	var sources []commandSource
 
@@ -109,7 +202,8 @@ func (l *commandLoader) loadFromSource(source commandSource) ([]Command, error)
 
 		cmd, err := l.loadCommand(path, source.path, source.prefix)
 		if err != nil {
-			return nil // Skip invalid files
+			slog.Warn("Skipping invalid custom command", "path", path, "error", err)
+			return nil
 		}
 
 		commands = append(commands, cmd)
@@ -125,13 +219,18 @@ func (l *commandLoader) loadCommand(path, baseDir, prefix string) (Command, erro
 		return Command{}, err
 	}
 
+	fm, body, err := parseCommand(string(content))
+	if err != nil {
+		return Command{}, err
+	}
+
 	id := buildCommandID(path, baseDir, prefix)
 
 	return Command{
 		ID:          id,
 		Title:       id,
-		Description: fmt.Sprintf("Custom command from %s", filepath.Base(path)),
-		Handler:     createCommandHandler(id, string(content)),
+		Description: cmp.Or(fm.Description, fmt.Sprintf("Custom command from %s", filepath.Base(path))),
+		Handler:     createCommandHandler(id, body, fm),
 	}, nil
 }
 
@@ -148,20 +247,38 @@ func buildCommandID(path, baseDir, prefix string) string {
 	return prefix + strings.Join(parts, ":")
 }
 
-func createCommandHandler(id string, content string) func(Command) tea.Cmd {
+func createCommandHandler(id string, content string, fm commandFrontMatter) func(Command) tea.Cmd {
+	options := agent.RunOptions{
+		Model:        fm.Model,
+		AllowedTools: fm.AllowedTools,
+	}
 	return func(cmd Command) tea.Cmd {
-		args := extractArgNames(content)
+		// The arguments of the front matter come first, in their order.
+		var args []string
+		descriptions := make(map[string]string, len(fm.Args))
+		for _, arg := range fm.Args {
+			args = append(args, arg.Name)
+			descriptions[arg.Name] = arg.Description
+		}
+		for _, arg := range extractArgNames(content) {
+			if !slices.Contains(args, arg) {
+				args = append(args, arg)
+			}
+		}
 
 		if len(args) > 0 {
 			return util.CmdHandler(ShowArgumentsDialogMsg{
-				CommandID: id,
-				Content:   content,
-				ArgNames:  args,
+				CommandID:       id,
+				Content:         content,
+				ArgNames:        args,
+				ArgDescriptions: descriptions,
+				Options:         options,
 			})
 		}
 
 		return util.CmdHandler(CommandRunCustomMsg{
 			Content: content,
+			Options: options,
 		})
 	}
 }
@@ -199,4 +316,6 @@ func isMarkdownFile(name string) bool {
 
 type CommandRunCustomMsg struct {
 	Content string
+	// Options change the model and the tools running the command.
+	Options agent.RunOptions
 }
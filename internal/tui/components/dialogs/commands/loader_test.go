@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommand(t *testing.T) {
+	t.Parallel()
+
+	t.Run("without front matter", func(t *testing.T) {
+		t.Parallel()
+		fm, body, err := parseCommand("Review $FILE\n")
+		require.NoError(t, err)
+		require.Equal(t, commandFrontMatter{}, fm)
+		require.Equal(t, "Review $FILE\n", body)
+	})
+
+	t.Run("with front matter", func(t *testing.T) {
+		t.Parallel()
+		content := "---\r\n" +
+			"description: Review the changes\r\n" +
+			"args:\r\n" +
+			"  - focus\r\n" +
+			"  - name: $BRANCH\r\n" +
+			"    description: The branch to compare with\r\n" +
+			"model: small\r\n" +
+			"allowed_tools: [view, grep]\r\n" +
+			"---\r\n" +
+			"\r\n" +
+			"Review $BRANCH with a focus on $FOCUS.\r\n"
+		fm, body, err := parseCommand(content)
+		require.NoError(t, err)
+		require.Equal(t, "Review the changes", fm.Description)
+		require.Equal(t, []commandArg{
+			{Name: "FOCUS"},
+			{Name: "BRANCH", Description: "The branch to compare with"},
+		}, fm.Args)
+		require.Equal(t, config.SelectedModelTypeSmall, fm.Model)
+		require.Equal(t, []string{"view", "grep"}, fm.AllowedTools)
+		require.Equal(t, "Review $BRANCH with a focus on $FOCUS.\n", body)
+	})
+
+	t.Run("front matter only", func(t *testing.T) {
+		t.Parallel()
+		fm, body, err := parseCommand("---\ndescription: Nothing\n---")
+		require.NoError(t, err)
+		require.Equal(t, "Nothing", fm.Description)
+		require.Empty(t, body)
+	})
+
+	t.Run("unclosed front matter", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := parseCommand("---\ndescription: Nothing\n")
+		require.Error(t, err)
+	})
+
+	t.Run("unknown model", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := parseCommand("---\nmodel: gpt-4o\n---\nHello")
+		require.ErrorContains(t, err, "unknown model")
+	})
+}
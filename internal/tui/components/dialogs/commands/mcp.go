@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"cmp"
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+// MCPPromptCommands returns the prompts of the connected MCP servers as
+// commands.
+func MCPPromptCommands() []Command {
+	prompts := agent.MCPPrompts()
+	commands := make([]Command, 0, len(prompts))
+	for _, p := range prompts {
+		commands = append(commands, Command{
+			ID:          p.ID(),
+			Title:       p.ID(),
+			Description: cmp.Or(p.Description, "Prompt from the "+p.Server+" MCP server"),
+			Handler: func(Command) tea.Cmd {
+				return RunMCPPrompt(p)
+			},
+		})
+	}
+	return commands
+}
+
+// RunMCPPrompt asks for the arguments of the prompt, if it has any, then
+// gets it from its server and sends it.
+func RunMCPPrompt(p agent.MCPPrompt) tea.Cmd {
+	if len(p.Arguments) == 0 {
+		return getMCPPrompt(p, nil)
+	}
+
+	argNames := make([]string, len(p.Arguments))
+	descriptions := make(map[string]string, len(p.Arguments))
+	for i, arg := range p.Arguments {
+		argNames[i] = arg.Name
+		description := arg.Description
+		if arg.Required {
+			description = cmp.Or(description, arg.Name) + " (required)"
+		}
+		descriptions[arg.Name] = description
+	}
+	return util.CmdHandler(ShowArgumentsDialogMsg{
+		CommandID:       p.ID(),
+		ArgNames:        argNames,
+		ArgDescriptions: descriptions,
+		OnSubmit: func(args map[string]string) tea.Cmd {
+			// Optional arguments left empty are not sent.
+			for _, arg := range p.Arguments {
+				if !arg.Required && args[arg.Name] == "" {
+					delete(args, arg.Name)
+				}
+			}
+			return getMCPPrompt(p, args)
+		},
+	})
+}
+
+func getMCPPrompt(p agent.MCPPrompt, args map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		content, err := agent.GetMCPPrompt(context.Background(), p.Server, p.Name, args)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		return CommandRunCustomMsg{Content: content}
+	}
+}
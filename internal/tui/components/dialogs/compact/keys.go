@@ -2,6 +2,7 @@ package compact
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 // KeyMap defines the key bindings for the compact dialog.
@@ -15,7 +16,7 @@ type KeyMap struct {
 
 // DefaultKeyMap returns the default key bindings for the compact dialog.
 func DefaultKeyMap() KeyMap {
-	return KeyMap{
+	return keymap.Apply("compact", KeyMap{
 		ChangeSelection: key.NewBinding(
 			key.WithKeys("tab", "left", "right", "h", "l"),
 			key.WithHelp("tab/←/→", "toggle selection"),
@@ -36,7 +37,7 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "cancel"),
 		),
-	}
+	})
 }
 
 // KeyBindings implements layout.KeyMapProvider
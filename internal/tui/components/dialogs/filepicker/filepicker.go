@@ -5,12 +5,16 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/v2/filepicker"
 	"github.com/charmbracelet/bubbles/v2/help"
 	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/document"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
@@ -45,7 +49,12 @@ type model struct {
 	help            help.Model
 }
 
-var AllowedTypes = []string{".jpg", ".jpeg", ".png"}
+// ImageTypes are the extensions of the images that can be attached.
+var ImageTypes = []string{".jpg", ".jpeg", ".png"}
+
+// AllowedTypes are the extensions of the files that can be attached, the
+// images and the documents converted to text.
+var AllowedTypes = append(slices.Clone(ImageTypes), document.Extensions...)
 
 func NewFilePickerCmp(workingDir string) FilePicker {
 	t := styles.CurrentTheme()
@@ -142,10 +151,10 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return util.ReportError(fmt.Errorf("unable to read the image: %w", err))
 				}
 
-				mimeBufferSize := min(512, len(content))
-				mimeType := http.DetectContentType(content[:mimeBufferSize])
-				fileName := filepath.Base(path)
-				attachment := message.Attachment{FilePath: path, FileName: fileName, MimeType: mimeType, Content: content}
+				attachment, err := NewAttachment(path, content)
+				if err != nil {
+					return util.ReportError(err)
+				}
 				return FilePickedMsg{
 					Attachment: attachment,
 				}
@@ -162,7 +171,7 @@ func (m *model) View() string {
 
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
-		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Add Image", m.width-4)),
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Add Attachment", m.width-4)),
 		m.imagePreview(),
 		m.filePicker.View(),
 		t.S().Base.Width(m.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(m.help.View(m.keyMap)),
@@ -171,7 +180,7 @@ func (m *model) View() string {
 }
 
 func (m *model) currentImage() string {
-	for _, ext := range m.filePicker.AllowedTypes {
+	for _, ext := range ImageTypes {
 		if strings.HasSuffix(m.filePicker.HighlightedPath(), ext) {
 			return m.filePicker.HighlightedPath()
 		}
@@ -224,6 +233,28 @@ func (m *model) Position() (int, int) {
 	return row, col
 }
 
+// NewAttachment makes the attachment of the file. Documents are converted to
+// Markdown, as much of it as fits in the context window of the current model,
+// and images are refused when the model doesn't support them.
+func NewAttachment(path string, content []byte) (message.Attachment, error) {
+	var model *catwalk.Model
+	if cfg := config.Get(); cfg != nil {
+		model = cfg.GetModelByType(config.SelectedModelTypeLarge)
+	}
+	if document.Is(path) {
+		var contextWindow int64
+		if model != nil {
+			contextWindow = model.ContextWindow
+		}
+		return document.Attachment(path, content, document.MaxSize(contextWindow))
+	}
+	mimeType := http.DetectContentType(content[:min(512, len(content))])
+	if model != nil && !model.SupportsImages && strings.HasPrefix(mimeType, "image/") {
+		return message.Attachment{}, fmt.Errorf("images are not supported by the current model: %s", model.Name)
+	}
+	return message.Attachment{FilePath: path, FileName: filepath.Base(path), MimeType: mimeType, Content: content}, nil
+}
+
 func IsFileTooBig(filePath string, sizeLimit int64) (bool, error) {
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
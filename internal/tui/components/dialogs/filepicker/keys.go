@@ -2,6 +2,7 @@ package filepicker
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 // KeyMap defines keyboard bindings for dialog management.
@@ -15,7 +16,7 @@ type KeyMap struct {
 }
 
 func DefaultKeyMap() KeyMap {
-	return KeyMap{
+	return keymap.Apply("file_picker", KeyMap{
 		Select: key.NewBinding(
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "accept"),
@@ -41,7 +42,7 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "close/exit"),
 		),
-	}
+	})
 }
 
 // KeyBindings implements layout.KeyMapProvider
@@ -0,0 +1,174 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/doctor"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const HealthDialogID dialogs.DialogID = "health"
+
+// CheckFunc checks the providers, language servers and MCP servers.
+type CheckFunc func(ctx context.Context) []doctor.Check
+
+// checkedMsg carries the checks of a run, which is ignored once another one
+// started.
+type checkedMsg struct {
+	run    int
+	checks []doctor.Check
+}
+
+// HealthDialog interface for the dialog showing the checks of the
+// providers, language servers and MCP servers.
+type HealthDialog interface {
+	dialogs.DialogModel
+}
+
+type healthDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+	keyMap  KeyMap
+	help    help.Model
+	check   CheckFunc
+	run     int
+	checks  []doctor.Check
+	// checking is set until the checks of the run are done.
+	checking bool
+}
+
+// NewHealthDialogCmp creates a new dialog running the checks when it opens,
+// and again when asked.
+func NewHealthDialogCmp(check CheckFunc) HealthDialog {
+	t := styles.CurrentTheme()
+	help := help.New()
+	help.Styles = t.S().Help
+	return &healthDialogCmp{
+		keyMap: DefaultKeyMap(),
+		help:   help,
+		check:  check,
+	}
+}
+
+func (h *healthDialogCmp) Init() tea.Cmd {
+	return h.start()
+}
+
+// start runs the checks again.
+func (h *healthDialogCmp) start() tea.Cmd {
+	h.run++
+	h.checking = true
+	run, check := h.run, h.check
+	return func() tea.Msg {
+		return checkedMsg{run: run, checks: check(context.Background())}
+	}
+}
+
+func (h *healthDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		h.wWidth = msg.Width
+		h.wHeight = msg.Height
+		h.width = min(120, h.wWidth-8)
+	case checkedMsg:
+		if msg.run == h.run {
+			h.checks = msg.checks
+			h.checking = false
+		}
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, h.keyMap.Recheck):
+			if !h.checking {
+				return h, h.start()
+			}
+		case key.Matches(msg, h.keyMap.Close):
+			return h, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+	}
+	return h, nil
+}
+
+func (h *healthDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	body := t.S().Muted.Render("Checking the providers, language servers and MCP servers…")
+	if !h.checking {
+		body = h.renderChecks()
+	}
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Status", h.width-4)),
+		t.S().Base.Width(h.width-2).Padding(0, 1).Render(body),
+		"",
+		t.S().Base.Width(h.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(h.help.View(h.keyMap)),
+	)
+	return h.style().Render(content)
+}
+
+// renderChecks lists the checks with how to fix the failed ones, as many as
+// the window fits.
+func (h *healthDialogCmp) renderChecks() string {
+	t := styles.CurrentTheme()
+	if len(h.checks) == 0 {
+		return t.S().Muted.Render("Nothing to check")
+	}
+	var lines []string
+	for _, c := range h.checks {
+		icon := t.S().Base.Foreground(t.Success).Render(styles.CheckIcon)
+		switch c.Status {
+		case doctor.StatusWarning:
+			icon = t.S().Base.Foreground(t.Warning).Render(styles.WarningIcon)
+		case doctor.StatusFailed:
+			icon = t.S().Base.Foreground(t.Error).Render(styles.ErrorIcon)
+		}
+		detail := c.Detail
+		if c.LatencyMs > 0 && c.Kind != doctor.KindProvider {
+			detail += fmt.Sprintf(" (%s)", (time.Duration(c.LatencyMs) * time.Millisecond).String())
+		}
+		line := fmt.Sprintf("%s %s %s %s",
+			icon,
+			t.S().Subtle.Render(fmt.Sprintf("%-8s", c.Kind)),
+			c.Name,
+			t.S().Muted.Render(detail),
+		)
+		lines = append(lines, ansi.Truncate(line, h.width-4, "…"))
+		if c.Fix != "" {
+			lines = append(lines, t.S().Subtle.Render(ansi.Truncate("  → "+c.Fix, h.width-4, "…")))
+		}
+	}
+	if maxLines := h.wHeight/2 - 4; maxLines > 0 && len(lines) > maxLines {
+		lines = append(lines[:maxLines-1], t.S().Muted.Render(fmt.Sprintf("… %d more lines, run crush doctor to see them all", len(lines)-maxLines+1)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (h *healthDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(h.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (h *healthDialogCmp) Position() (int, int) {
+	row := h.wHeight/4 - 2 // just a bit above the center
+	col := h.wWidth / 2
+	col -= h.width / 2
+	return row, col
+}
+
+// ID implements HealthDialog.
+func (h *healthDialogCmp) ID() dialogs.DialogID {
+	return HealthDialogID
+}
@@ -0,0 +1,42 @@
+package health
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
+)
+
+type KeyMap struct {
+	Recheck,
+	Close key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return keymap.Apply("health", KeyMap{
+		Recheck: key.NewBinding(
+			key.WithKeys("r", "ctrl+r"),
+			key.WithHelp("r", "check again"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "close"),
+		),
+	})
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Recheck,
+		k.Close,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.KeyBindings()}
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return k.KeyBindings()
+}
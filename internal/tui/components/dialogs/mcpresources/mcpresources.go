@@ -0,0 +1,316 @@
+package mcpresources
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const MCPResourcesDialogID dialogs.DialogID = "mcp_resources"
+
+// MCPResourcesDialog interface for the dialog attaching MCP resources to the
+// prompt.
+type MCPResourcesDialog interface {
+	dialogs.DialogModel
+}
+
+type ResourcesList = list.FilterableList[list.CompletionItem[agent.MCPResource]]
+
+type mcpResourcesDialogCmp struct {
+	wWidth        int
+	wHeight       int
+	width         int
+	keyMap        KeyMap
+	resourcesList ResourcesList
+	help          help.Model
+
+	// template is the resource template being filled in, with an input for
+	// each of its variables.
+	template   *agent.MCPResource
+	varNames   []string
+	inputs     []textinput.Model
+	focusIndex int
+}
+
+// NewMCPResourcesDialogCmp creates a new dialog to attach the resources of
+// the MCP servers.
+func NewMCPResourcesDialogCmp(resources []agent.MCPResource) MCPResourcesDialog {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	items := make([]list.CompletionItem[agent.MCPResource], len(resources))
+	for i, r := range resources {
+		shortcut := r.Server
+		if r.Template {
+			shortcut += ", template"
+		}
+		items[i] = list.NewCompletionItem(
+			r.Name,
+			r,
+			list.WithCompletionID(r.Server+":"+r.URI),
+			list.WithCompletionShortcut(shortcut),
+		)
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	resourcesList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Enter a resource name"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &mcpResourcesDialogCmp{
+		keyMap:        keyMap,
+		resourcesList: resourcesList,
+		help:          help,
+	}
+}
+
+func (s *mcpResourcesDialogCmp) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	cmds = append(cmds, s.resourcesList.Init())
+	cmds = append(cmds, s.resourcesList.Focus())
+	return tea.Sequence(cmds...)
+}
+
+func (s *mcpResourcesDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.wWidth = msg.Width
+		s.wHeight = msg.Height
+		s.width = min(120, s.wWidth-8)
+		s.resourcesList.SetInputWidth(s.listWidth() - 2)
+		for i := range s.inputs {
+			s.inputs[i].SetWidth(s.listWidth() - 4)
+		}
+		return s, s.resourcesList.SetSize(s.listWidth(), s.listHeight())
+	case tea.KeyPressMsg:
+		if s.template != nil {
+			return s, s.updateTemplate(msg)
+		}
+		switch {
+		case key.Matches(msg, s.keyMap.Select):
+			selectedItem := s.resourcesList.SelectedItem()
+			if selectedItem == nil {
+				return s, nil
+			}
+			r := (*selectedItem).Value()
+			if !r.Template {
+				return s, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					attachResource(r.Server, r.URI),
+				)
+			}
+			return s, s.fillTemplate(r)
+		case key.Matches(msg, s.keyMap.Close):
+			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := s.resourcesList.Update(msg)
+			s.resourcesList = u.(ResourcesList)
+			return s, cmd
+		}
+	}
+	return s, nil
+}
+
+// fillTemplate asks for the variables of a resource template, templates
+// without any are attached as they are.
+func (s *mcpResourcesDialogCmp) fillTemplate(r agent.MCPResource) tea.Cmd {
+	varNames, err := r.Variables()
+	if err != nil {
+		return util.ReportError(err)
+	}
+	if len(varNames) == 0 {
+		return tea.Sequence(
+			util.CmdHandler(dialogs.CloseDialogMsg{}),
+			attachResource(r.Server, r.URI),
+		)
+	}
+
+	t := styles.CurrentTheme()
+	s.template = &r
+	s.varNames = varNames
+	s.inputs = make([]textinput.Model, len(varNames))
+	for i, name := range varNames {
+		ti := textinput.New()
+		ti.Placeholder = fmt.Sprintf("Enter value for %s...", name)
+		ti.SetWidth(s.listWidth() - 4)
+		ti.SetVirtualCursor(false)
+		ti.Prompt = ""
+		ti.SetStyles(t.S().TextInput)
+		s.inputs[i] = ti
+	}
+	s.focusIndex = 0
+	return s.inputs[0].Focus()
+}
+
+func (s *mcpResourcesDialogCmp) updateTemplate(msg tea.KeyPressMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, s.keyMap.Close):
+		s.template = nil
+		s.inputs = nil
+		return s.resourcesList.Focus()
+	case key.Matches(msg, s.keyMap.Select):
+		if s.focusIndex < len(s.inputs)-1 {
+			return s.focusInput(s.focusIndex + 1)
+		}
+		values := make(map[string]string, len(s.varNames))
+		for i, name := range s.varNames {
+			values[name] = s.inputs[i].Value()
+		}
+		uri, err := s.template.Expand(values)
+		if err != nil {
+			return util.ReportError(err)
+		}
+		return tea.Sequence(
+			util.CmdHandler(dialogs.CloseDialogMsg{}),
+			attachResource(s.template.Server, uri),
+		)
+	case key.Matches(msg, s.keyMap.Next):
+		return s.focusInput((s.focusIndex + 1) % len(s.inputs))
+	case key.Matches(msg, s.keyMap.Previous):
+		return s.focusInput((s.focusIndex - 1 + len(s.inputs)) % len(s.inputs))
+	default:
+		var cmd tea.Cmd
+		s.inputs[s.focusIndex], cmd = s.inputs[s.focusIndex].Update(msg)
+		return cmd
+	}
+}
+
+func (s *mcpResourcesDialogCmp) focusInput(i int) tea.Cmd {
+	s.inputs[s.focusIndex].Blur()
+	s.focusIndex = i
+	return s.inputs[s.focusIndex].Focus()
+}
+
+// attachResource reads the resource and adds it to the attachments of the
+// prompt. The resource is subscribed to, so the attachment is refreshed when
+// it changes before the prompt is sent.
+func attachResource(server, uri string) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		attachment, err := agent.ReadMCPResource(ctx, server, uri)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		if int64(len(attachment.Content)) > filepicker.MaxAttachmentSize {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("resource %s is too big to attach", uri)}
+		}
+		if err := agent.SubscribeMCPResource(ctx, server, uri); err != nil {
+			slog.Warn("failed to subscribe to mcp resource", "error", err, "name", server, "uri", uri)
+		}
+		return filepicker.FilePickedMsg{Attachment: attachment}
+	}
+}
+
+func (s *mcpResourcesDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	title := "MCP Resources"
+	body := s.resourcesList.View()
+	if s.template != nil {
+		title = s.template.Name
+		body = s.templateView()
+	}
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title(title, s.width-4)),
+		body,
+		"",
+		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
+	)
+
+	return s.style().Render(content)
+}
+
+func (s *mcpResourcesDialogCmp) templateView() string {
+	t := styles.CurrentTheme()
+	lines := []string{t.S().Subtle.PaddingLeft(1).Render(s.template.URI), ""}
+	for i, name := range s.varNames {
+		label := t.S().Text.PaddingLeft(1).Render(name)
+		if i == s.focusIndex {
+			label = t.S().Base.Foreground(t.Primary).Bold(true).PaddingLeft(1).Render(name)
+		}
+		lines = append(lines, label, t.S().Base.PaddingLeft(1).Render(s.inputs[i].View()), "")
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+func (s *mcpResourcesDialogCmp) Cursor() *tea.Cursor {
+	if s.template != nil {
+		cursor := s.inputs[s.focusIndex].Cursor()
+		if cursor != nil {
+			row, col := s.Position()
+			// Border, title, template and the labels and inputs above.
+			cursor.Y += row + 5 + s.focusIndex*3
+			cursor.X += col + 2
+		}
+		return cursor
+	}
+	if cursor, ok := s.resourcesList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = s.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (s *mcpResourcesDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(s.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (s *mcpResourcesDialogCmp) listHeight() int {
+	return s.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (s *mcpResourcesDialogCmp) listWidth() int {
+	return s.width - 2 // 2 for the border
+}
+
+func (s *mcpResourcesDialogCmp) Position() (int, int) {
+	row := s.wHeight/4 - 2 // just a bit above the center
+	col := s.wWidth / 2
+	col -= s.width / 2
+	return row, col
+}
+
+func (s *mcpResourcesDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := s.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements MCPResourcesDialog.
+func (s *mcpResourcesDialogCmp) ID() dialogs.DialogID {
+	return MCPResourcesDialogID
+}
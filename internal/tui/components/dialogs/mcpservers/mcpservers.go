@@ -0,0 +1,226 @@
+package mcpservers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const MCPServersDialogID dialogs.DialogID = "mcp_servers"
+
+// MCPServersDialog interface for the dialog listing the state of the MCP
+// servers.
+type MCPServersDialog interface {
+	dialogs.DialogModel
+}
+
+type ServersList = list.FilterableList[list.CompletionItem[string]]
+
+type mcpServersDialogCmp struct {
+	wWidth      int
+	wHeight     int
+	width       int
+	keyMap      KeyMap
+	serversList ServersList
+	help        help.Model
+}
+
+// NewMCPServersDialogCmp creates a new dialog listing the configured MCP
+// servers, it is refreshed as their state changes.
+func NewMCPServersDialogCmp() MCPServersDialog {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	serversList := list.NewFilterableList(
+		serverItems(),
+		list.WithFilterPlaceholder("Enter a server name"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &mcpServersDialogCmp{
+		keyMap:      keyMap,
+		serversList: serversList,
+		help:        help,
+	}
+}
+
+func serverItems() []list.CompletionItem[string] {
+	states := agent.GetMCPStates()
+	mcps := config.Get().MCP.Sorted()
+	items := make([]list.CompletionItem[string], len(mcps))
+	for i, m := range mcps {
+		items[i] = list.NewCompletionItem(
+			m.Name,
+			m.Name,
+			list.WithCompletionID(m.Name),
+			list.WithCompletionShortcut(serverSummary(m.MCP, states[m.Name])),
+		)
+	}
+	return items
+}
+
+// serverSummary describes the state of a server, with its tools and latency
+// once connected.
+func serverSummary(m config.MCPConfig, info agent.MCPClientInfo) string {
+	if m.Disabled {
+		return agent.MCPStateDisabled.String()
+	}
+	parts := []string{info.State.String()}
+	switch info.State {
+	case agent.MCPStateConnected:
+		parts = append(parts, fmt.Sprintf("%d tools", info.ToolCount))
+		if !info.CheckedAt.IsZero() {
+			parts = append(parts, info.Latency.Round(time.Millisecond).String())
+		}
+	case agent.MCPStateError:
+		if info.Error != nil {
+			parts = append(parts, info.Error.Error())
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (s *mcpServersDialogCmp) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	cmds = append(cmds, s.serversList.Init())
+	cmds = append(cmds, s.serversList.Focus())
+	return tea.Sequence(cmds...)
+}
+
+func (s *mcpServersDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.wWidth = msg.Width
+		s.wHeight = msg.Height
+		s.width = min(120, s.wWidth-8)
+		s.serversList.SetInputWidth(s.listWidth() - 2)
+		return s, s.serversList.SetSize(s.listWidth(), s.listHeight())
+	case pubsub.Event[agent.MCPEvent]:
+		if msg.Payload.Type != agent.MCPEventStateChanged && msg.Payload.Type != agent.MCPEventHealthChecked {
+			return s, nil
+		}
+		return s, s.refresh()
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, s.keyMap.Select):
+			selectedItem := s.serversList.SelectedItem()
+			if selectedItem != nil {
+				return s, restart((*selectedItem).Value())
+			}
+		case key.Matches(msg, s.keyMap.Close):
+			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := s.serversList.Update(msg)
+			s.serversList = u.(ServersList)
+			return s, cmd
+		}
+	}
+	return s, nil
+}
+
+// refresh lists the servers again, keeping the selected one.
+func (s *mcpServersDialogCmp) refresh() tea.Cmd {
+	var selected string
+	if item := s.serversList.SelectedItem(); item != nil {
+		selected = (*item).ID()
+	}
+	cmds := []tea.Cmd{s.serversList.SetItems(serverItems())}
+	if selected != "" {
+		cmds = append(cmds, s.serversList.SetSelected(selected))
+	}
+	return tea.Sequence(cmds...)
+}
+
+func restart(name string) tea.Cmd {
+	return func() tea.Msg {
+		if err := agent.RestartMCP(name); err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		return util.InfoMsg{Type: util.InfoTypeInfo, Msg: fmt.Sprintf("Restarting %s", name)}
+	}
+}
+
+func (s *mcpServersDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	listView := s.serversList.View()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("MCP Servers", s.width-4)),
+		listView,
+		"",
+		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
+	)
+
+	return s.style().Render(content)
+}
+
+func (s *mcpServersDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := s.serversList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = s.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (s *mcpServersDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(s.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (s *mcpServersDialogCmp) listHeight() int {
+	return s.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (s *mcpServersDialogCmp) listWidth() int {
+	return s.width - 2 // 2 for the border
+}
+
+func (s *mcpServersDialogCmp) Position() (int, int) {
+	row := s.wHeight/4 - 2 // just a bit above the center
+	col := s.wWidth / 2
+	col -= s.width / 2
+	return row, col
+}
+
+func (s *mcpServersDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := s.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements MCPServersDialog.
+func (s *mcpServersDialogCmp) ID() dialogs.DialogID {
+	return MCPServersDialogID
+}
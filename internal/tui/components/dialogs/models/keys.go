@@ -2,6 +2,7 @@ package models
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 type KeyMap struct {
@@ -16,7 +17,7 @@ type KeyMap struct {
 }
 
 func DefaultKeyMap() KeyMap {
-	return KeyMap{
+	return keymap.Apply("models", KeyMap{
 		Select: key.NewBinding(
 			key.WithKeys("enter", "ctrl+y"),
 			key.WithHelp("enter", "confirm"),
@@ -37,7 +38,7 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "cancel"),
 		),
-	}
+	})
 }
 
 // KeyBindings implements layout.KeyMapProvider
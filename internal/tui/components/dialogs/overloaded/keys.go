@@ -0,0 +1,43 @@
+package overloaded
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
+)
+
+// KeyMap defines the keyboard bindings for the overloaded dialog.
+type KeyMap struct {
+	Switch,
+	Close key.Binding
+}
+
+func DefaultKeymap() KeyMap {
+	return keymap.Apply("overloaded", KeyMap{
+		Switch: key.NewBinding(
+			key.WithKeys("y", "Y", "enter"),
+			key.WithHelp("y/enter", "switch"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("n", "N", "esc"),
+			key.WithHelp("n/esc", "keep waiting"),
+		),
+	})
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Switch,
+		k.Close,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.KeyBindings()}
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return k.KeyBindings()
+}
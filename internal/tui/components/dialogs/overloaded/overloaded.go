@@ -0,0 +1,119 @@
+package overloaded
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const (
+	OverloadedDialogID dialogs.DialogID = "overloaded"
+
+	dialogWidth = 60
+	// maxUpdateLength keeps long incident updates from filling the screen.
+	maxUpdateLength = 200
+)
+
+// OverloadedDialog tells that the provider stays overloaded, with the
+// incidents of its status page, and offers to switch to the fallback model.
+type OverloadedDialog interface {
+	dialogs.DialogModel
+}
+
+type overloadedDialogCmp struct {
+	wWidth  int
+	wHeight int
+
+	err      *provider.OverloadedError
+	fallback config.SelectedModel
+	keymap   KeyMap
+}
+
+func NewOverloadedDialogCmp(err *provider.OverloadedError, fallback config.SelectedModel) OverloadedDialog {
+	return &overloadedDialogCmp{
+		err:      err,
+		fallback: fallback,
+		keymap:   DefaultKeymap(),
+	}
+}
+
+func (o *overloadedDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (o *overloadedDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		o.wWidth = msg.Width
+		o.wHeight = msg.Height
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, o.keymap.Switch):
+			return o, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(models.ModelSelectedMsg{
+					Model:     o.fallback,
+					ModelType: config.SelectedModelTypeLarge,
+				}),
+			)
+		case key.Matches(msg, o.keymap.Close):
+			return o, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+	}
+	return o, nil
+}
+
+func (o *overloadedDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	baseStyle := t.S().Base.Width(dialogWidth)
+
+	lines := []string{
+		t.S().Title.Render(fmt.Sprintf("%s is overloaded", o.err.Provider)),
+		"",
+		fmt.Sprintf("The requests failed %d times in a row.", o.err.Attempts),
+	}
+	if status := o.err.Status; status != nil {
+		lines = append(lines, "", t.S().Subtle.Render("Status: ")+status.Description)
+		for _, i := range status.Incidents {
+			lines = append(lines, fmt.Sprintf("• %s (%s)", i.Name, i.Status))
+			if i.Update != "" {
+				update := strings.TrimSpace(i.Update)
+				if len(update) > maxUpdateLength {
+					update = update[:maxUpdateLength] + "…"
+				}
+				lines = append(lines, t.S().Muted.Render("  "+update))
+			}
+		}
+	}
+	lines = append(lines,
+		"",
+		fmt.Sprintf("Switch to %s (%s)?", o.fallback.Model, o.fallback.Provider),
+		"",
+		t.S().Muted.Render("y/enter switch • n/esc keep waiting"),
+	)
+
+	return baseStyle.
+		Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(baseStyle.Width(dialogWidth - 6).Render(strings.Join(lines, "\n")))
+}
+
+func (o *overloadedDialogCmp) Position() (int, int) {
+	row := o.wHeight/2 - lipgloss.Height(o.View())/2
+	col := o.wWidth/2 - dialogWidth/2
+	return row, col
+}
+
+func (o *overloadedDialogCmp) ID() dialogs.DialogID {
+	return OverloadedDialogID
+}
@@ -2,6 +2,7 @@ package permissions
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 type KeyMap struct {
@@ -11,7 +12,10 @@ type KeyMap struct {
 	Select,
 	Allow,
 	AllowSession,
+	AllowAlways,
 	Deny,
+	DenyWithFeedback,
+	CancelFeedback,
 	ToggleDiffMode,
 	ScrollDown,
 	ScrollUp key.Binding
@@ -20,7 +24,7 @@ type KeyMap struct {
 }
 
 func DefaultKeyMap() KeyMap {
-	return KeyMap{
+	return keymap.Apply("permissions", KeyMap{
 		Left: key.NewBinding(
 			key.WithKeys("left", "h"),
 			key.WithHelp("←", "previous"),
@@ -41,10 +45,22 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("s", "S", "ctrl+s"),
 			key.WithHelp("s", "allow session"),
 		),
+		AllowAlways: key.NewBinding(
+			key.WithKeys("w", "W"),
+			key.WithHelp("w", "always allow"),
+		),
 		Deny: key.NewBinding(
 			key.WithKeys("d", "D", "ctrl+d", "esc"),
 			key.WithHelp("d", "deny"),
 		),
+		DenyWithFeedback: key.NewBinding(
+			key.WithKeys("f", "F"),
+			key.WithHelp("f", "deny with feedback"),
+		),
+		CancelFeedback: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "back"),
+		),
 		Select: key.NewBinding(
 			key.WithKeys("enter", "ctrl+y"),
 			key.WithHelp("enter", "confirm"),
@@ -69,7 +85,7 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("shift+right", "L"),
 			key.WithHelp("shift+→", "scroll right"),
 		),
-	}
+	})
 }
 
 // KeyBindings implements layout.KeyMapProvider
@@ -81,7 +97,9 @@ func (k KeyMap) KeyBindings() []key.Binding {
 		k.Select,
 		k.Allow,
 		k.AllowSession,
+		k.AllowAlways,
 		k.Deny,
+		k.DenyWithFeedback,
 		k.ToggleDiffMode,
 		k.ScrollDown,
 		k.ScrollUp,
@@ -2,13 +2,16 @@ package permissions
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/v2/help"
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/textinput"
 	"github.com/charmbracelet/bubbles/v2/viewport"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/llm/agent"
 	"github.com/charmbracelet/crush/internal/llm/tools"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
@@ -25,7 +28,13 @@ type PermissionAction string
 const (
 	PermissionAllow           PermissionAction = "allow"
 	PermissionAllowForSession PermissionAction = "allow_session"
-	PermissionDeny            PermissionAction = "deny"
+	// PermissionAllowAlways allows the tool and action from now on, saving
+	// the rule in the configuration.
+	PermissionAllowAlways PermissionAction = "allow_always"
+	PermissionDeny        PermissionAction = "deny"
+	// PermissionDenyWithFeedback denies the permission, telling the agent
+	// what to do instead.
+	PermissionDenyWithFeedback PermissionAction = "deny_feedback"
 
 	PermissionsDialogID dialogs.DialogID = "permissions"
 )
@@ -34,6 +43,18 @@ const (
 type PermissionResponseMsg struct {
 	Permission permission.PermissionRequest
 	Action     PermissionAction
+	// Feedback is what the user tells the agent, for
+	// PermissionDenyWithFeedback.
+	Feedback string
+}
+
+// options are the actions of the buttons, in order.
+var options = []PermissionAction{
+	PermissionAllow,
+	PermissionAllowForSession,
+	PermissionAllowAlways,
+	PermissionDeny,
+	PermissionDenyWithFeedback,
 }
 
 // PermissionDialogCmp interface for permission dialog component
@@ -49,7 +70,11 @@ type permissionDialogCmp struct {
 	height          int
 	permission      permission.PermissionRequest
 	contentViewPort viewport.Model
-	selectedOption  int // 0: Allow, 1: Allow for session, 2: Deny
+	selectedOption  int // index in options
+
+	// The feedback is entered after choosing to deny with feedback.
+	enteringFeedback bool
+	feedback         textinput.Model
 
 	// Diff view state
 	defaultDiffSplitMode bool  // true for split, false for unified
@@ -76,8 +101,12 @@ func NewPermissionDialogCmp(permission permission.PermissionRequest, opts *Optio
 
 	// Create viewport for content
 	contentViewport := viewport.New()
+	feedback := textinput.New()
+	feedback.Placeholder = "Tell the agent what to do instead"
+	feedback.SetStyles(styles.CurrentTheme().S().TextInput)
 	return &permissionDialogCmp{
 		contentViewPort: contentViewport,
+		feedback:        feedback,
 		selectedOption:  0, // Default to "Allow"
 		permission:      permission,
 		diffSplitMode:   opts.isSplitMode(),
@@ -91,6 +120,9 @@ func (p *permissionDialogCmp) Init() tea.Cmd {
 }
 
 func (p *permissionDialogCmp) supportsDiffView() bool {
+	if _, ok := p.permission.Params.(tools.LSPEditPermissionsParams); ok {
+		return true
+	}
 	return p.permission.ToolName == tools.EditToolName || p.permission.ToolName == tools.WriteToolName || p.permission.ToolName == tools.MultiEditToolName
 }
 
@@ -104,30 +136,30 @@ func (p *permissionDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		p.contentDirty = true // Mark content as dirty on window resize
 		cmd := p.SetSize()
 		cmds = append(cmds, cmd)
+		p.feedback.SetWidth(p.width - 6)
 	case tea.KeyPressMsg:
+		if p.enteringFeedback {
+			return p, p.updateFeedback(msg)
+		}
 		switch {
 		case key.Matches(msg, p.keyMap.Right) || key.Matches(msg, p.keyMap.Tab):
-			p.selectedOption = (p.selectedOption + 1) % 3
+			p.selectedOption = (p.selectedOption + 1) % len(options)
 			return p, nil
 		case key.Matches(msg, p.keyMap.Left):
-			p.selectedOption = (p.selectedOption + 2) % 3
+			p.selectedOption = (p.selectedOption + len(options) - 1) % len(options)
 		case key.Matches(msg, p.keyMap.Select):
 			return p, p.selectCurrentOption()
 		case key.Matches(msg, p.keyMap.Allow):
-			return p, tea.Batch(
-				util.CmdHandler(dialogs.CloseDialogMsg{}),
-				util.CmdHandler(PermissionResponseMsg{Action: PermissionAllow, Permission: p.permission}),
-			)
+			return p, p.respond(PermissionAllow)
 		case key.Matches(msg, p.keyMap.AllowSession):
-			return p, tea.Batch(
-				util.CmdHandler(dialogs.CloseDialogMsg{}),
-				util.CmdHandler(PermissionResponseMsg{Action: PermissionAllowForSession, Permission: p.permission}),
-			)
+			return p, p.respond(PermissionAllowForSession)
+		case key.Matches(msg, p.keyMap.AllowAlways):
+			return p, p.respond(PermissionAllowAlways)
 		case key.Matches(msg, p.keyMap.Deny):
-			return p, tea.Batch(
-				util.CmdHandler(dialogs.CloseDialogMsg{}),
-				util.CmdHandler(PermissionResponseMsg{Action: PermissionDeny, Permission: p.permission}),
-			)
+			return p, p.respond(PermissionDeny)
+		case key.Matches(msg, p.keyMap.DenyWithFeedback):
+			p.selectedOption = slices.Index(options, PermissionDenyWithFeedback)
+			return p, p.startFeedback()
 		case key.Matches(msg, p.keyMap.ToggleDiffMode):
 			if p.supportsDiffView() {
 				if p.diffSplitMode == nil {
@@ -219,23 +251,49 @@ func (p *permissionDialogCmp) isMouseOverDialog(x, y int) bool {
 }
 
 func (p *permissionDialogCmp) selectCurrentOption() tea.Cmd {
-	var action PermissionAction
-
-	switch p.selectedOption {
-	case 0:
-		action = PermissionAllow
-	case 1:
-		action = PermissionAllowForSession
-	case 2:
-		action = PermissionDeny
+	action := options[p.selectedOption]
+	if action == PermissionDenyWithFeedback {
+		return p.startFeedback()
 	}
+	return p.respond(action)
+}
 
+// respond closes the dialog with the action.
+func (p *permissionDialogCmp) respond(action PermissionAction) tea.Cmd {
 	return tea.Batch(
 		util.CmdHandler(PermissionResponseMsg{Action: action, Permission: p.permission}),
 		util.CmdHandler(dialogs.CloseDialogMsg{}),
 	)
 }
 
+func (p *permissionDialogCmp) startFeedback() tea.Cmd {
+	p.enteringFeedback = true
+	return p.feedback.Focus()
+}
+
+// updateFeedback handles the keys while the feedback to the agent is
+// entered.
+func (p *permissionDialogCmp) updateFeedback(msg tea.KeyPressMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, p.keyMap.Select):
+		feedback := strings.TrimSpace(p.feedback.Value())
+		if feedback == "" {
+			return util.ReportWarn("Tell the agent what to do instead, or press esc to go back")
+		}
+		return tea.Batch(
+			util.CmdHandler(PermissionResponseMsg{Action: PermissionDenyWithFeedback, Permission: p.permission, Feedback: feedback}),
+			util.CmdHandler(dialogs.CloseDialogMsg{}),
+		)
+	case key.Matches(msg, p.keyMap.CancelFeedback):
+		p.enteringFeedback = false
+		p.feedback.Blur()
+		return nil
+	}
+	var cmd tea.Cmd
+	p.feedback, cmd = p.feedback.Update(msg)
+	return cmd
+}
+
 func (p *permissionDialogCmp) renderButtons() string {
 	t := styles.CurrentTheme()
 	baseStyle := t.S().Base
@@ -251,10 +309,20 @@ func (p *permissionDialogCmp) renderButtons() string {
 			UnderlineIndex: 10, // "S" in "Session"
 			Selected:       p.selectedOption == 1,
 		},
+		{
+			Text:           "Always Allow",
+			UnderlineIndex: 2, // "w"
+			Selected:       p.selectedOption == 2,
+		},
 		{
 			Text:           "Deny",
 			UnderlineIndex: 0, // "D"
-			Selected:       p.selectedOption == 2,
+			Selected:       p.selectedOption == 3,
+		},
+		{
+			Text:           "Deny with Feedback",
+			UnderlineIndex: 10, // "F" in "Feedback"
+			Selected:       p.selectedOption == 4,
 		},
 	}
 
@@ -302,6 +370,16 @@ func (p *permissionDialogCmp) renderHeader() string {
 	// Add tool-specific header information
 	switch p.permission.ToolName {
 	case tools.BashToolName:
+		if params, ok := p.permission.Params.(tools.BashPermissionsParams); ok {
+			rows := [][2]string{{"Directory", fsext.PrettyPath(params.WorkingDir)}}
+			if len(params.Env) > 0 {
+				rows = append(rows, [2]string{"Env", strings.Join(params.Env, " ")})
+			}
+			if params.Timeout > 0 {
+				rows = append(rows, [2]string{"Timeout", fmt.Sprintf("%ds", params.Timeout/1000)})
+			}
+			headerParts = append(headerParts, p.renderRows(rows)...)
+		}
 		headerParts = append(headerParts, t.S().Muted.Width(p.width).Render("Command"))
 	case tools.DownloadToolName:
 		params := p.permission.Params.(tools.DownloadPermissionsParams)
@@ -370,7 +448,38 @@ func (p *permissionDialogCmp) renderHeader() string {
 			),
 			baseStyle.Render(strings.Repeat(" ", p.width)),
 		)
+	case tools.RenameSymbolToolName, tools.CodeActionToolName, tools.FormatFileToolName:
+		var files []string
+		switch params := p.permission.Params.(type) {
+		case tools.LSPEditPermissionsParams:
+			for _, f := range params.Files {
+				files = append(files, fsext.PrettyPath(f.FilePath))
+			}
+		case tools.CodeActionCommandPermissionsParams:
+			files = append(files, fsext.PrettyPath(params.FilePath))
+		}
+		fileKey := t.S().Muted.Render("Files")
+		if len(files) == 1 {
+			fileKey = t.S().Muted.Render("File")
+		}
+		filePaths := t.S().Text.
+			Width(p.width - lipgloss.Width(fileKey)).
+			Render(fmt.Sprintf(" %s", strings.Join(files, ", ")))
+		headerParts = append(headerParts,
+			lipgloss.JoinHorizontal(
+				lipgloss.Left,
+				fileKey,
+				filePaths,
+			),
+			baseStyle.Render(strings.Repeat(" ", p.width)),
+		)
 	case tools.FetchToolName:
+		if params, ok := p.permission.Params.(tools.FetchPermissionsParams); ok {
+			headerParts = append(headerParts, p.renderRows([][2]string{
+				{"Method", params.Method},
+				{"Format", params.Format},
+			})...)
+		}
 		headerParts = append(headerParts, t.S().Muted.Width(p.width).Bold(true).Render("URL"))
 	case tools.ViewToolName:
 		params := p.permission.Params.(tools.ViewPermissionsParams)
@@ -386,6 +495,20 @@ func (p *permissionDialogCmp) renderHeader() string {
 			),
 			baseStyle.Render(strings.Repeat(" ", p.width)),
 		)
+	case agent.MCPSamplingToolName:
+		params := p.permission.Params.(agent.MCPSamplingPermissionsParams)
+		rows := [][2]string{
+			{"Server", params.Server},
+			{"Model", params.Model},
+		}
+		if prefs := samplingPreferences(params); prefs != "" {
+			rows = append(rows, [2]string{"Preferences", prefs})
+		}
+		if params.MaxTokens > 0 {
+			rows = append(rows, [2]string{"Max tokens", fmt.Sprintf("%d", params.MaxTokens)})
+		}
+		headerParts = append(headerParts, p.renderRows(rows)...)
+		headerParts = append(headerParts, t.S().Muted.Width(p.width).Render("Messages"))
 	case tools.LSToolName:
 		params := p.permission.Params.(tools.LSPermissionsParams)
 		pathKey := t.S().Muted.Render("Directory")
@@ -405,6 +528,23 @@ func (p *permissionDialogCmp) renderHeader() string {
 	return baseStyle.Render(lipgloss.JoinVertical(lipgloss.Left, headerParts...))
 }
 
+// renderRows renders the header rows of labels and values.
+func (p *permissionDialogCmp) renderRows(rows [][2]string) []string {
+	t := styles.CurrentTheme()
+	var parts []string
+	for _, row := range rows {
+		key := t.S().Muted.Render(row[0])
+		value := t.S().Text.
+			Width(p.width - lipgloss.Width(key)).
+			Render(fmt.Sprintf(" %s", row[1]))
+		parts = append(parts,
+			lipgloss.JoinHorizontal(lipgloss.Left, key, value),
+			t.S().Base.Render(strings.Repeat(" ", p.width)),
+		)
+	}
+	return parts
+}
+
 func (p *permissionDialogCmp) getOrGenerateContent() string {
 	// Return cached content if available and not dirty
 	if !p.contentDirty && p.cachedContent != "" {
@@ -424,12 +564,16 @@ func (p *permissionDialogCmp) getOrGenerateContent() string {
 		content = p.generateWriteContent()
 	case tools.MultiEditToolName:
 		content = p.generateMultiEditContent()
+	case tools.RenameSymbolToolName, tools.CodeActionToolName, tools.FormatFileToolName:
+		content = p.generateLSPEditContent()
 	case tools.FetchToolName:
 		content = p.generateFetchContent()
 	case tools.ViewToolName:
 		content = p.generateViewContent()
 	case tools.LSToolName:
 		content = p.generateLSContent()
+	case agent.MCPSamplingToolName:
+		content = p.generateSamplingContent()
 	default:
 		content = p.generateDefaultContent()
 	}
@@ -473,6 +617,31 @@ func (p *permissionDialogCmp) generateBashContent() string {
 	return ""
 }
 
+func (p *permissionDialogCmp) generateLSPEditContent() string {
+	pr, ok := p.permission.Params.(tools.LSPEditPermissionsParams)
+	if !ok || len(pr.Files) == 0 {
+		return p.generateDefaultContent()
+	}
+	name := fsext.PrettyPath(pr.Files[0].FilePath)
+	if len(pr.Files) > 1 {
+		name = fmt.Sprintf("%d files", len(pr.Files))
+	}
+	oldContent, newContent := tools.LSPEditContents(pr.Files)
+	formatter := core.DiffFormatter().
+		Before(name, oldContent).
+		After(name, newContent).
+		Height(p.contentViewPort.Height()).
+		Width(p.contentViewPort.Width()).
+		XOffset(p.diffXOffset).
+		YOffset(p.diffYOffset)
+	if p.useDiffSplitMode() {
+		formatter = formatter.Split()
+	} else {
+		formatter = formatter.Unified()
+	}
+	return formatter.String()
+}
+
 func (p *permissionDialogCmp) generateEditContent() string {
 	if pr, ok := p.permission.Params.(tools.EditPermissionsParams); ok {
 		formatter := core.DiffFormatter().
@@ -608,6 +777,55 @@ func (p *permissionDialogCmp) generateLSContent() string {
 	return ""
 }
 
+func (p *permissionDialogCmp) generateSamplingContent() string {
+	t := styles.CurrentTheme()
+	baseStyle := t.S().Base.Background(t.BgSubtle)
+	if pr, ok := p.permission.Params.(agent.MCPSamplingPermissionsParams); ok {
+		width := p.contentViewPort.Width()
+		var out []string
+		render := func(label, text string) {
+			out = append(out,
+				t.S().Base.Background(t.BgSubtle).Foreground(t.FgHalfMuted).Bold(true).
+					Width(width).Padding(0, 2).Render(label),
+				t.S().Muted.Foreground(t.FgBase).Background(t.BgSubtle).
+					Width(width).Padding(0, 2, 1, 2).Render(strings.TrimSpace(text)),
+			)
+		}
+		if pr.SystemPrompt != "" {
+			render("system", pr.SystemPrompt)
+		}
+		for _, msg := range pr.Messages {
+			render(msg.Role, msg.Content)
+		}
+		return baseStyle.
+			Width(width).
+			PaddingTop(1).
+			Render(strings.Join(out, "\n"))
+	}
+	return ""
+}
+
+// samplingPreferences describes the model preferences of a sampling request.
+func samplingPreferences(params agent.MCPSamplingPermissionsParams) string {
+	var prefs []string
+	if len(params.ModelHints) > 0 {
+		prefs = append(prefs, "hints "+strings.Join(params.ModelHints, ", "))
+	}
+	for _, pr := range []struct {
+		name  string
+		value float64
+	}{
+		{"cost", params.CostPriority},
+		{"speed", params.SpeedPriority},
+		{"intelligence", params.IntelligencePriority},
+	} {
+		if pr.value > 0 {
+			prefs = append(prefs, fmt.Sprintf("%s %.1f", pr.name, pr.value))
+		}
+	}
+	return strings.Join(prefs, "; ")
+}
+
 func (p *permissionDialogCmp) generateDefaultContent() string {
 	t := styles.CurrentTheme()
 	baseStyle := t.S().Base.Background(t.BgSubtle)
@@ -663,8 +881,19 @@ func (p *permissionDialogCmp) render() string {
 	title := core.Title("Permission Required", p.width-4)
 	// Render header
 	headerContent := p.renderHeader()
-	// Render buttons
+	// Render buttons, or the feedback input once chosen
 	buttons := p.renderButtons()
+	switch {
+	case p.enteringFeedback:
+		buttons = lipgloss.JoinVertical(lipgloss.Left,
+			t.S().Muted.Render("Deny and tell the agent (enter to send, esc to go back)"),
+			p.feedback.View(),
+		)
+	case options[p.selectedOption] == PermissionAllowAlways:
+		note := t.S().Muted.Width(p.width - 4).AlignHorizontal(lipgloss.Right).
+			Render(fmt.Sprintf("Allows %s from now on, saved in the global configuration", permission.Rule(p.permission)))
+		buttons = lipgloss.JoinVertical(lipgloss.Left, buttons, note)
+	}
 
 	p.contentViewPort.SetWidth(p.width - 4)
 
@@ -743,6 +972,9 @@ func (p *permissionDialogCmp) SetSize() tea.Cmd {
 	case tools.MultiEditToolName:
 		p.width = int(float64(p.wWidth) * 0.8)
 		p.height = int(float64(p.wHeight) * 0.8)
+	case tools.RenameSymbolToolName, tools.CodeActionToolName, tools.FormatFileToolName:
+		p.width = int(float64(p.wWidth) * 0.8)
+		p.height = int(float64(p.wHeight) * 0.8)
 	case tools.FetchToolName:
 		p.width = int(float64(p.wWidth) * 0.8)
 		p.height = int(float64(p.wHeight) * 0.3)
@@ -752,6 +984,9 @@ func (p *permissionDialogCmp) SetSize() tea.Cmd {
 	case tools.LSToolName:
 		p.width = int(float64(p.wWidth) * 0.8)
 		p.height = int(float64(p.wHeight) * 0.4)
+	case agent.MCPSamplingToolName:
+		p.width = int(float64(p.wWidth) * 0.8)
+		p.height = int(float64(p.wHeight) * 0.6)
 	default:
 		p.width = int(float64(p.wWidth) * 0.7)
 		p.height = int(float64(p.wHeight) * 0.5)
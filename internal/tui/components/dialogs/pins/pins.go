@@ -0,0 +1,217 @@
+package pins
+
+import (
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/pin"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/core/status"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const PinsDialogID dialogs.DialogID = "pins"
+
+// PinSelectedMsg asks to pin the file or message to the context of its
+// session.
+type PinSelectedMsg struct {
+	Pin pin.Pin
+}
+
+// UnpinSelectedMsg asks to unpin the pin.
+type UnpinSelectedMsg struct {
+	Pin pin.Pin
+}
+
+// PinsDialog interface for the dialog listing the files and messages of the
+// session to pin, or its pins to unpin.
+type PinsDialog interface {
+	dialogs.DialogModel
+}
+
+type PinsList = list.FilterableList[list.CompletionItem[pin.Pin]]
+
+type pinsDialogCmp struct {
+	wWidth   int
+	wHeight  int
+	width    int
+	keyMap   KeyMap
+	pinsList PinsList
+	help     help.Model
+	// unpin is set when the pins of the session are listed to be unpinned.
+	unpin bool
+}
+
+// NewPinDialogCmp creates a new dialog to pick the file or message of the
+// session to pin, among the candidates not pinned yet.
+func NewPinDialogCmp(candidates []pin.Pin) PinsDialog {
+	return newPinsDialogCmp(candidates, false)
+}
+
+// NewUnpinDialogCmp creates a new dialog to pick the pin to remove. Both
+// dialogs list the tokens the pins add to each request.
+func NewUnpinDialogCmp(pins []pin.Pin) PinsDialog {
+	return newPinsDialogCmp(pins, true)
+}
+
+func newPinsDialogCmp(pins []pin.Pin, unpin bool) *pinsDialogCmp {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	if unpin {
+		keyMap.Select.SetHelp("enter", "unpin")
+	}
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	items := make([]list.CompletionItem[pin.Pin], len(pins))
+	for i, p := range pins {
+		items[i] = list.NewCompletionItem(
+			Title(p),
+			p,
+			list.WithCompletionID(p.Kind+":"+p.Path+p.MessageID),
+			list.WithCompletionShortcut("~"+status.FormatTokens(int64(p.Tokens))+" tokens"),
+		)
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	pinsList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Enter a file or a message"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &pinsDialogCmp{
+		keyMap:   keyMap,
+		pinsList: pinsList,
+		help:     help,
+		unpin:    unpin,
+	}
+}
+
+// Title is how the pin is listed, the titles of the messages telling who
+// wrote them.
+func Title(p pin.Pin) string {
+	if p.Kind == pin.KindFile {
+		return "file: " + fsext.PrettyPath(p.Path)
+	}
+	return p.Title
+}
+
+func (s *pinsDialogCmp) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	cmds = append(cmds, s.pinsList.Init())
+	cmds = append(cmds, s.pinsList.Focus())
+	return tea.Sequence(cmds...)
+}
+
+func (s *pinsDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.wWidth = msg.Width
+		s.wHeight = msg.Height
+		s.width = min(120, s.wWidth-8)
+		s.pinsList.SetInputWidth(s.listWidth() - 2)
+		return s, s.pinsList.SetSize(s.listWidth(), s.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, s.keyMap.Select):
+			selectedItem := s.pinsList.SelectedItem()
+			if selectedItem != nil {
+				p := (*selectedItem).Value()
+				var selected tea.Msg = PinSelectedMsg{Pin: p}
+				if s.unpin {
+					selected = UnpinSelectedMsg{Pin: p}
+				}
+				return s, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					util.CmdHandler(selected),
+				)
+			}
+		case key.Matches(msg, s.keyMap.Close):
+			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := s.pinsList.Update(msg)
+			s.pinsList = u.(PinsList)
+			return s, cmd
+		}
+	}
+	return s, nil
+}
+
+func (s *pinsDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	listView := s.pinsList.View()
+	title := "Pin to Context"
+	if s.unpin {
+		title = "Unpin from Context"
+	}
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title(title, s.width-4)),
+		listView,
+		"",
+		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
+	)
+
+	return s.style().Render(content)
+}
+
+func (s *pinsDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := s.pinsList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = s.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (s *pinsDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(s.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (s *pinsDialogCmp) listHeight() int {
+	return s.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (s *pinsDialogCmp) listWidth() int {
+	return s.width - 2 // 2 for the border
+}
+
+func (s *pinsDialogCmp) Position() (int, int) {
+	row := s.wHeight/4 - 2 // just a bit above the center
+	col := s.wWidth / 2
+	col -= s.width / 2
+	return row, col
+}
+
+func (s *pinsDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := s.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements PinsDialog.
+func (s *pinsDialogCmp) ID() dialogs.DialogID {
+	return PinsDialogID
+}
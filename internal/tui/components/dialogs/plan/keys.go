@@ -0,0 +1,71 @@
+package plan
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
+)
+
+// KeyMap defines the keyboard bindings for the plan dialog.
+type KeyMap struct {
+	Execute,
+	Discard,
+	ScrollUp,
+	ScrollDown,
+	PageUp,
+	PageDown,
+	Close key.Binding
+}
+
+func DefaultKeymap() KeyMap {
+	return keymap.Apply("plan", KeyMap{
+		Execute: key.NewBinding(
+			key.WithKeys("enter", "x"),
+			key.WithHelp("enter/x", "execute"),
+		),
+		Discard: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "discard"),
+		),
+		ScrollUp: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑", "scroll up"),
+		),
+		ScrollDown: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓", "scroll down"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup"),
+			key.WithHelp("pgup", "page up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown"),
+			key.WithHelp("pgdown", "page down"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "close"),
+		),
+	})
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Execute,
+		k.Discard,
+		k.ScrollUp,
+		k.ScrollDown,
+		k.Close,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.KeyBindings()}
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return k.KeyBindings()
+}
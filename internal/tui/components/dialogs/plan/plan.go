@@ -0,0 +1,193 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const (
+	PlanDialogID dialogs.DialogID = "plan"
+
+	maxDialogWidth = 100
+)
+
+// PlanDialog shows the actions the agent proposed in plan mode, with their
+// diffs, and executes them on a single keypress.
+type PlanDialog interface {
+	dialogs.DialogModel
+}
+
+type planDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+
+	agent     agent.Service
+	sessionID string
+	actions   []agent.ProposedAction
+
+	viewport viewport.Model
+	keymap   KeyMap
+	help     help.Model
+}
+
+func NewPlanDialogCmp(coder agent.Service, sessionID string) PlanDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	return &planDialogCmp{
+		agent:     coder,
+		sessionID: sessionID,
+		actions:   coder.Plan(sessionID),
+		viewport:  viewport.New(),
+		keymap:    DefaultKeymap(),
+		help:      h,
+	}
+}
+
+func (p *planDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (p *planDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.wWidth = msg.Width
+		p.wHeight = msg.Height
+		p.setSize()
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, p.keymap.Execute):
+			if len(p.actions) == 0 {
+				return p, util.CmdHandler(dialogs.CloseDialogMsg{})
+			}
+			return p, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				p.execute(),
+			)
+		case key.Matches(msg, p.keymap.Discard):
+			p.agent.DiscardPlan(p.sessionID)
+			return p, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.ReportInfo("Plan discarded"),
+			)
+		case key.Matches(msg, p.keymap.ScrollUp):
+			p.viewport.ScrollUp(1)
+		case key.Matches(msg, p.keymap.ScrollDown):
+			p.viewport.ScrollDown(1)
+		case key.Matches(msg, p.keymap.PageUp):
+			p.viewport.PageUp()
+		case key.Matches(msg, p.keymap.PageDown):
+			p.viewport.PageDown()
+		case key.Matches(msg, p.keymap.Close):
+			return p, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+	}
+	return p, nil
+}
+
+// execute runs the plan, reporting the first failed action.
+func (p *planDialogCmp) execute() tea.Cmd {
+	coder, sessionID := p.agent, p.sessionID
+	return func() tea.Msg {
+		results, err := coder.ExecutePlan(context.Background(), sessionID)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		for _, r := range results {
+			if r.Err != nil {
+				return util.InfoMsg{
+					Type: util.InfoTypeError,
+					Msg:  fmt.Sprintf("Plan stopped at %s: %s", r.Action.Summary, r.Err),
+				}
+			}
+		}
+		return util.InfoMsg{
+			Type: util.InfoTypeInfo,
+			Msg:  fmt.Sprintf("Plan executed: %d actions applied", len(results)),
+		}
+	}
+}
+
+func (p *planDialogCmp) setSize() {
+	p.width = min(maxDialogWidth, p.wWidth-4)
+	// Room for the border, padding, title and help.
+	p.viewport.SetWidth(p.width - 4)
+	p.viewport.SetHeight(max(p.wHeight*3/4-8, 5))
+	p.viewport.SetContent(p.content())
+}
+
+// content lists the actions with their diffs.
+func (p *planDialogCmp) content() string {
+	t := styles.CurrentTheme()
+	if len(p.actions) == 0 {
+		return t.S().Muted.Render("No proposed actions. Enable plan mode and prompt the agent to record them.")
+	}
+
+	added := t.S().Base.Foreground(t.Success)
+	removed := t.S().Base.Foreground(t.Error)
+	var sb strings.Builder
+	for i, action := range p.actions {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(t.S().Text.Bold(true).Render(fmt.Sprintf("%d. %s", i+1, action.Summary)))
+		if action.Diff == "" {
+			continue
+		}
+		for line := range strings.SplitSeq(strings.TrimRight(action.Diff, "\n"), "\n") {
+			sb.WriteString("\n")
+			switch {
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "@@"):
+				sb.WriteString(t.S().Subtle.Render(line))
+			case strings.HasPrefix(line, "+"):
+				sb.WriteString(added.Render(line))
+			case strings.HasPrefix(line, "-"):
+				sb.WriteString(removed.Render(line))
+			default:
+				sb.WriteString(t.S().Muted.Render(line))
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (p *planDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	title := t.S().Title.Render(fmt.Sprintf("Proposed plan (%d actions)", len(p.actions)))
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		p.viewport.View(),
+		"",
+		p.help.View(p.keymap),
+	)
+	return t.S().Base.
+		Width(p.width).
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+func (p *planDialogCmp) Position() (int, int) {
+	row := p.wHeight/2 - lipgloss.Height(p.View())/2
+	col := p.wWidth/2 - p.width/2
+	return row, col
+}
+
+func (p *planDialogCmp) ID() dialogs.DialogID {
+	return PlanDialogID
+}
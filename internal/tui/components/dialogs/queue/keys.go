@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
+)
+
+// KeyMap defines the keyboard bindings for the queue dialog.
+type KeyMap struct {
+	Edit,
+	Remove,
+	Next,
+	Previous,
+	Save,
+	Newline,
+	Close key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return keymap.Apply("queue", KeyMap{
+		Edit: key.NewBinding(
+			key.WithKeys("enter", "e"),
+			key.WithHelp("enter", "edit"),
+		),
+		Remove: key.NewBinding(
+			key.WithKeys("d", "delete", "backspace"),
+			key.WithHelp("d", "remove"),
+		),
+		Next: key.NewBinding(
+			key.WithKeys("down", "j", "ctrl+n"),
+			key.WithHelp("↓", "next item"),
+		),
+		Previous: key.NewBinding(
+			key.WithKeys("up", "k", "ctrl+p"),
+			key.WithHelp("↑", "previous item"),
+		),
+		Save: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "save"),
+		),
+		Newline: key.NewBinding(
+			key.WithKeys("shift+enter", "ctrl+j"),
+			key.WithHelp("ctrl+j", "newline"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "close"),
+		),
+	})
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Edit,
+		k.Remove,
+		k.Next,
+		k.Previous,
+		k.Close,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.KeyBindings()}
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		key.NewBinding(
+			key.WithKeys("down", "up"),
+			key.WithHelp("↑↓", "choose"),
+		),
+		k.Edit,
+		k.Remove,
+		k.Close,
+	}
+}
+
+// editingKeyMap is the help shown while a queued message is edited.
+type editingKeyMap KeyMap
+
+// FullHelp implements help.KeyMap.
+func (k editingKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+// ShortHelp implements help.KeyMap.
+func (k editingKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		k.Save,
+		k.Newline,
+		key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+	}
+}
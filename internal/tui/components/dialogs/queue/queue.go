@@ -0,0 +1,239 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/textarea"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	QueueDialogID dialogs.DialogID = "queue"
+
+	maxDialogWidth = 80
+	editorHeight   = 5
+	// refreshInterval is how often the dialog reads the queue again, as the
+	// agent takes the messages out of it when it sends them.
+	refreshInterval = 500 * time.Millisecond
+)
+
+// QueueDialog lists the messages queued while the agent is working, to edit
+// or remove them before they are sent.
+type QueueDialog interface {
+	dialogs.DialogModel
+}
+
+type refreshMsg struct{}
+
+type queueDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+
+	agent     agent.Service
+	sessionID string
+	prompts   []string
+	selected  int
+
+	editing   bool
+	editIndex int
+	textarea  *textarea.Model
+
+	keyMap KeyMap
+	help   help.Model
+}
+
+func NewQueueDialogCmp(coder agent.Service, sessionID string) QueueDialog {
+	t := styles.CurrentTheme()
+	h := help.New()
+	h.Styles = t.S().Help
+	ta := textarea.New()
+	ta.SetStyles(t.S().TextArea)
+	ta.ShowLineNumbers = false
+	ta.CharLimit = -1
+	ta.Prompt = ""
+	ta.SetHeight(editorHeight)
+	return &queueDialogCmp{
+		agent:     coder,
+		sessionID: sessionID,
+		prompts:   coder.QueuedPromptList(sessionID),
+		textarea:  ta,
+		keyMap:    DefaultKeyMap(),
+		help:      h,
+	}
+}
+
+func (q *queueDialogCmp) Init() tea.Cmd {
+	return refresh()
+}
+
+func refresh() tea.Cmd {
+	return tea.Tick(refreshInterval, func(time.Time) tea.Msg {
+		return refreshMsg{}
+	})
+}
+
+func (q *queueDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		q.wWidth = msg.Width
+		q.wHeight = msg.Height
+		q.width = min(maxDialogWidth, q.wWidth-4)
+		q.textarea.SetWidth(q.width - 4)
+	case refreshMsg:
+		q.reload()
+		if q.editing && q.editIndex >= len(q.prompts) {
+			q.editing = false
+			return q, util.ReportWarn("The queued message was sent while editing it")
+		}
+		if len(q.prompts) == 0 && !q.editing {
+			return q, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+		return q, refresh()
+	case tea.KeyPressMsg:
+		if q.editing {
+			return q, q.updateEditing(msg)
+		}
+		switch {
+		case key.Matches(msg, q.keyMap.Next):
+			q.selected = min(q.selected+1, len(q.prompts)-1)
+		case key.Matches(msg, q.keyMap.Previous):
+			q.selected = max(q.selected-1, 0)
+		case key.Matches(msg, q.keyMap.Edit):
+			if q.selected < len(q.prompts) {
+				q.editing = true
+				q.editIndex = q.selected
+				q.textarea.SetValue(q.prompts[q.selected])
+				q.textarea.MoveToEnd()
+				return q, q.textarea.Focus()
+			}
+		case key.Matches(msg, q.keyMap.Remove):
+			if _, ok := q.agent.RemoveQueuedPrompt(q.sessionID, q.selected); !ok {
+				return q, util.ReportWarn("The queued message was already sent")
+			}
+			q.reload()
+			if len(q.prompts) == 0 {
+				return q, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					util.ReportInfo("Queue cleared"),
+				)
+			}
+		case key.Matches(msg, q.keyMap.Close):
+			return q, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+	}
+	return q, nil
+}
+
+// updateEditing handles the keys while a queued message is edited, saving it
+// in place so that it keeps its turn in the queue.
+func (q *queueDialogCmp) updateEditing(msg tea.KeyPressMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, q.keyMap.Save):
+		q.editing = false
+		q.textarea.Blur()
+		content := strings.TrimSpace(q.textarea.Value())
+		var ok bool
+		if content == "" {
+			_, ok = q.agent.RemoveQueuedPrompt(q.sessionID, q.editIndex)
+		} else {
+			ok = q.agent.UpdateQueuedPrompt(q.sessionID, q.editIndex, content)
+		}
+		q.reload()
+		if !ok {
+			return util.ReportWarn("The queued message was already sent")
+		}
+		return nil
+	case key.Matches(msg, q.keyMap.Newline):
+		q.textarea.InsertRune('\n')
+		return nil
+	case key.Matches(msg, q.keyMap.Close):
+		q.editing = false
+		q.textarea.Blur()
+		return nil
+	}
+	var cmd tea.Cmd
+	q.textarea, cmd = q.textarea.Update(msg)
+	return cmd
+}
+
+func (q *queueDialogCmp) reload() {
+	q.prompts = q.agent.QueuedPromptList(q.sessionID)
+	q.selected = max(min(q.selected, len(q.prompts)-1), 0)
+}
+
+// content lists the queued messages on a line each, the edited one in a text
+// area.
+func (q *queueDialogCmp) content() string {
+	t := styles.CurrentTheme()
+	if len(q.prompts) == 0 {
+		return t.S().Muted.Render("No queued messages.")
+	}
+	width := q.width - 4
+	lines := make([]string, 0, len(q.prompts))
+	for i, prompt := range q.prompts {
+		if q.editing && i == q.editIndex {
+			lines = append(lines, q.textarea.View())
+			continue
+		}
+		first, _, multiline := strings.Cut(prompt, "\n")
+		line := fmt.Sprintf("%d. %s", i+1, first)
+		if multiline {
+			line += " …"
+		}
+		line = ansi.Truncate(line, width, "…")
+		if i == q.selected && !q.editing {
+			lines = append(lines, t.S().TextSelected.Width(width).Render(line))
+		} else {
+			lines = append(lines, t.S().Text.Render(line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (q *queueDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	title := t.S().Title.Render(fmt.Sprintf("Queued Messages (%d)", len(q.prompts)))
+	var helpView string
+	if q.editing {
+		helpView = q.help.View(editingKeyMap(q.keyMap))
+	} else {
+		helpView = q.help.View(q.keyMap)
+	}
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		q.content(),
+		"",
+		t.S().Muted.Render("Queued messages are sent once the agent finishes its current step."),
+		"",
+		helpView,
+	)
+	return t.S().Base.
+		Width(q.width).
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+func (q *queueDialogCmp) Position() (int, int) {
+	row := q.wHeight/2 - lipgloss.Height(q.View())/2
+	col := q.wWidth/2 - q.width/2
+	return row, col
+}
+
+func (q *queueDialogCmp) ID() dialogs.DialogID {
+	return QueueDialogID
+}
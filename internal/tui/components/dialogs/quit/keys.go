@@ -2,6 +2,7 @@ package quit
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 // KeyMap defines the keyboard bindings for the quit dialog.
@@ -15,7 +16,7 @@ type KeyMap struct {
 }
 
 func DefaultKeymap() KeyMap {
-	return KeyMap{
+	return keymap.Apply("quit", KeyMap{
 		LeftRight: key.NewBinding(
 			key.WithKeys("left", "right"),
 			key.WithHelp("←/→", "switch options"),
@@ -40,7 +41,7 @@ func DefaultKeymap() KeyMap {
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "cancel"),
 		),
-	}
+	})
 }
 
 // KeyBindings implements layout.KeyMapProvider
@@ -0,0 +1,206 @@
+package restore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/trash"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/dustin/go-humanize"
+)
+
+const RestoreDialogID dialogs.DialogID = "restore"
+
+// RestoreDialog interface for the dialog listing the files deleted or
+// overwritten in the session.
+type RestoreDialog interface {
+	dialogs.DialogModel
+}
+
+type EntriesList = list.FilterableList[list.CompletionItem[trash.Entry]]
+
+type restoreDialogCmp struct {
+	wWidth      int
+	wHeight     int
+	width       int
+	keyMap      KeyMap
+	trashBin    trash.Service
+	entriesList EntriesList
+	help        help.Model
+}
+
+// NewRestoreDialogCmp creates a new dialog to restore the files in the trash
+// of the session.
+func NewRestoreDialogCmp(trashBin trash.Service, entries []trash.Entry) RestoreDialog {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	items := make([]list.CompletionItem[trash.Entry], len(entries))
+	for i, e := range entries {
+		items[i] = list.NewCompletionItem(
+			fsext.PrettyPath(e.Path),
+			e,
+			list.WithCompletionID(e.ID),
+			list.WithCompletionShortcut(entrySummary(e)),
+		)
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	entriesList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Enter a file name"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &restoreDialogCmp{
+		keyMap:      keyMap,
+		trashBin:    trashBin,
+		entriesList: entriesList,
+		help:        help,
+	}
+}
+
+// entrySummary describes how and when the file was put in the trash.
+func entrySummary(e trash.Entry) string {
+	return fmt.Sprintf(
+		"%s by %s %s, %s",
+		e.Reason,
+		e.ToolName,
+		humanize.Time(time.Unix(e.TrashedAt, 0)),
+		humanize.Bytes(uint64(e.Size)),
+	)
+}
+
+func (s *restoreDialogCmp) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	cmds = append(cmds, s.entriesList.Init())
+	cmds = append(cmds, s.entriesList.Focus())
+	return tea.Sequence(cmds...)
+}
+
+func (s *restoreDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.wWidth = msg.Width
+		s.wHeight = msg.Height
+		s.width = min(120, s.wWidth-8)
+		s.entriesList.SetInputWidth(s.listWidth() - 2)
+		return s, s.entriesList.SetSize(s.listWidth(), s.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, s.keyMap.Select):
+			selectedItem := s.entriesList.SelectedItem()
+			if selectedItem != nil {
+				e := (*selectedItem).Value()
+				return s, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					s.restore(e),
+				)
+			}
+		case key.Matches(msg, s.keyMap.Close):
+			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := s.entriesList.Update(msg)
+			s.entriesList = u.(EntriesList)
+			return s, cmd
+		}
+	}
+	return s, nil
+}
+
+// restore puts the file back where it was. Overwritten files replace their
+// current version, which stays in the history of the session, deleted files
+// are only restored when nothing took their place.
+func (s *restoreDialogCmp) restore(e trash.Entry) tea.Cmd {
+	return func() tea.Msg {
+		_, err := s.trashBin.Restore(e.ID, e.Reason == trash.ReasonOverwritten)
+		if errors.Is(err, trash.ErrExists) {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: fmt.Sprintf("%s exists, move it first to restore it", fsext.PrettyPath(e.Path))}
+		}
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		return util.InfoMsg{Type: util.InfoTypeInfo, Msg: fmt.Sprintf("Restored %s", fsext.PrettyPath(e.Path))}
+	}
+}
+
+func (s *restoreDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	listView := s.entriesList.View()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Trash", s.width-4)),
+		listView,
+		"",
+		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
+	)
+
+	return s.style().Render(content)
+}
+
+func (s *restoreDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := s.entriesList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = s.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (s *restoreDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(s.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (s *restoreDialogCmp) listHeight() int {
+	return s.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (s *restoreDialogCmp) listWidth() int {
+	return s.width - 2 // 2 for the border
+}
+
+func (s *restoreDialogCmp) Position() (int, int) {
+	row := s.wHeight/4 - 2 // just a bit above the center
+	col := s.wWidth / 2
+	col -= s.width / 2
+	return row, col
+}
+
+func (s *restoreDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := s.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements RestoreDialog.
+func (s *restoreDialogCmp) ID() dialogs.DialogID {
+	return RestoreDialogID
+}
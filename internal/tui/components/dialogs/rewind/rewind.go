@@ -0,0 +1,219 @@
+package rewind
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/dustin/go-humanize"
+)
+
+const RewindDialogID dialogs.DialogID = "rewind"
+
+// RewindSelectedMsg asks to rewind the session to the prompt of the message.
+type RewindSelectedMsg struct {
+	SessionID string
+	MessageID string
+}
+
+// EditSelectedMsg asks to edit the prompt, to regenerate the session from it.
+type EditSelectedMsg struct {
+	Prompt message.Message
+}
+
+// RewindDialog interface for the dialog listing the prompts of the session
+// it can be rewound to.
+type RewindDialog interface {
+	dialogs.DialogModel
+}
+
+type PromptsList = list.FilterableList[list.CompletionItem[message.Message]]
+
+type rewindDialogCmp struct {
+	wWidth      int
+	wHeight     int
+	width       int
+	keyMap      KeyMap
+	promptsList PromptsList
+	help        help.Model
+	// edit is set when the selected prompt is edited rather than rewound to.
+	edit bool
+}
+
+// NewRewindDialogCmp creates a new dialog to rewind the session to one of
+// its prompts, given in chronological order and listed latest first.
+func NewRewindDialogCmp(prompts []message.Message) RewindDialog {
+	return newRewindDialogCmp(prompts, false)
+}
+
+// NewEditDialogCmp creates a new dialog to pick the prompt of the session to
+// edit, listed like NewRewindDialogCmp does.
+func NewEditDialogCmp(prompts []message.Message) RewindDialog {
+	return newRewindDialogCmp(prompts, true)
+}
+
+func newRewindDialogCmp(prompts []message.Message, edit bool) *rewindDialogCmp {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	if edit {
+		keyMap.Select.SetHelp("enter", "edit")
+	}
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	items := make([]list.CompletionItem[message.Message], len(prompts))
+	for i, msg := range prompts {
+		items[len(prompts)-1-i] = list.NewCompletionItem(
+			promptTitle(msg),
+			msg,
+			list.WithCompletionID(msg.ID),
+			list.WithCompletionShortcut(humanize.Time(time.Unix(msg.CreatedAt, 0))),
+		)
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	promptsList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Enter a prompt"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &rewindDialogCmp{
+		keyMap:      keyMap,
+		promptsList: promptsList,
+		help:        help,
+		edit:        edit,
+	}
+}
+
+// promptTitle is the first line of the prompt.
+func promptTitle(msg message.Message) string {
+	text := strings.TrimSpace(msg.Content().Text)
+	if line, _, ok := strings.Cut(text, "\n"); ok {
+		return line + " …"
+	}
+	return text
+}
+
+func (s *rewindDialogCmp) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	cmds = append(cmds, s.promptsList.Init())
+	cmds = append(cmds, s.promptsList.Focus())
+	return tea.Sequence(cmds...)
+}
+
+func (s *rewindDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.wWidth = msg.Width
+		s.wHeight = msg.Height
+		s.width = min(120, s.wWidth-8)
+		s.promptsList.SetInputWidth(s.listWidth() - 2)
+		return s, s.promptsList.SetSize(s.listWidth(), s.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, s.keyMap.Select):
+			selectedItem := s.promptsList.SelectedItem()
+			if selectedItem != nil {
+				prompt := (*selectedItem).Value()
+				var selected tea.Msg = RewindSelectedMsg{SessionID: prompt.SessionID, MessageID: prompt.ID}
+				if s.edit {
+					selected = EditSelectedMsg{Prompt: prompt}
+				}
+				return s, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					util.CmdHandler(selected),
+				)
+			}
+		case key.Matches(msg, s.keyMap.Close):
+			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := s.promptsList.Update(msg)
+			s.promptsList = u.(PromptsList)
+			return s, cmd
+		}
+	}
+	return s, nil
+}
+
+func (s *rewindDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	listView := s.promptsList.View()
+	title := "Rewind to Prompt"
+	if s.edit {
+		title = "Edit Prompt"
+	}
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title(title, s.width-4)),
+		listView,
+		"",
+		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
+	)
+
+	return s.style().Render(content)
+}
+
+func (s *rewindDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := s.promptsList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = s.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (s *rewindDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(s.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (s *rewindDialogCmp) listHeight() int {
+	return s.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (s *rewindDialogCmp) listWidth() int {
+	return s.width - 2 // 2 for the border
+}
+
+func (s *rewindDialogCmp) Position() (int, int) {
+	row := s.wHeight/4 - 2 // just a bit above the center
+	col := s.wWidth / 2
+	col -= s.width / 2
+	return row, col
+}
+
+func (s *rewindDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := s.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements RewindDialog.
+func (s *rewindDialogCmp) ID() dialogs.DialogID {
+	return RewindDialogID
+}
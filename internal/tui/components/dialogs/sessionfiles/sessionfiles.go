@@ -0,0 +1,186 @@
+package sessionfiles
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const SessionFilesDialogID dialogs.DialogID = "session_files"
+
+// SessionFilesDialog interface for the dialog listing the files touched in
+// the session.
+type SessionFilesDialog interface {
+	dialogs.DialogModel
+}
+
+type FilesList = list.FilterableList[list.CompletionItem[history.TouchedFile]]
+
+type sessionFilesDialogCmp struct {
+	wWidth    int
+	wHeight   int
+	width     int
+	keyMap    KeyMap
+	filesList FilesList
+	help      help.Model
+}
+
+// NewSessionFilesDialogCmp creates a new dialog to jump to the files touched
+// in the session.
+func NewSessionFilesDialogCmp(files []history.TouchedFile) SessionFilesDialog {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	items := make([]list.CompletionItem[history.TouchedFile], len(files))
+	for i, file := range files {
+		items[i] = list.NewCompletionItem(
+			fsext.PrettyPath(file.Path),
+			file,
+			list.WithCompletionID(file.Path),
+			list.WithCompletionShortcut(touchedSummary(file)),
+		)
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	filesList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Enter a file name"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &sessionFilesDialogCmp{
+		keyMap:    keyMap,
+		filesList: filesList,
+		help:      help,
+	}
+}
+
+// touchedSummary describes how often the file was read and edited.
+func touchedSummary(file history.TouchedFile) string {
+	summary := fmt.Sprintf("read %d", file.ReadCount)
+	if file.WriteCount > 0 {
+		summary += fmt.Sprintf(", edited %d", file.WriteCount)
+	}
+	if file.LastLine > 0 {
+		summary += fmt.Sprintf(" :%d", file.LastLine)
+	}
+	return summary
+}
+
+func (s *sessionFilesDialogCmp) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	cmds = append(cmds, s.filesList.Init())
+	cmds = append(cmds, s.filesList.Focus())
+	return tea.Sequence(cmds...)
+}
+
+func (s *sessionFilesDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.wWidth = msg.Width
+		s.wHeight = msg.Height
+		s.width = min(120, s.wWidth-8)
+		s.filesList.SetInputWidth(s.listWidth() - 2)
+		return s, s.filesList.SetSize(s.listWidth(), s.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, s.keyMap.Select):
+			selectedItem := s.filesList.SelectedItem()
+			if selectedItem != nil {
+				file := (*selectedItem).Value()
+				return s, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					util.OpenInEditor(file.Path, int(file.LastLine)),
+				)
+			}
+		case key.Matches(msg, s.keyMap.Close):
+			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := s.filesList.Update(msg)
+			s.filesList = u.(FilesList)
+			return s, cmd
+		}
+	}
+	return s, nil
+}
+
+func (s *sessionFilesDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	listView := s.filesList.View()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Session Files", s.width-4)),
+		listView,
+		"",
+		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
+	)
+
+	return s.style().Render(content)
+}
+
+func (s *sessionFilesDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := s.filesList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = s.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (s *sessionFilesDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(s.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (s *sessionFilesDialogCmp) listHeight() int {
+	return s.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (s *sessionFilesDialogCmp) listWidth() int {
+	return s.width - 2 // 2 for the border
+}
+
+func (s *sessionFilesDialogCmp) Position() (int, int) {
+	row := s.wHeight/4 - 2 // just a bit above the center
+	col := s.wWidth / 2
+	col -= s.width / 2
+	return row, col
+}
+
+func (s *sessionFilesDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := s.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements SessionFilesDialog.
+func (s *sessionFilesDialogCmp) ID() dialogs.DialogID {
+	return SessionFilesDialogID
+}
@@ -0,0 +1,187 @@
+package workspaces
+
+import (
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/projects"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/dustin/go-humanize"
+)
+
+const WorkspacesDialogID dialogs.DialogID = "workspaces"
+
+// WorkspaceSelectedMsg asks to switch to the workspace of the project.
+type WorkspaceSelectedMsg struct {
+	Project projects.Project
+}
+
+// WorkspacesDialog interface for the dialog switching between the projects
+// crush ran in.
+type WorkspacesDialog interface {
+	dialogs.DialogModel
+}
+
+type WorkspacesList = list.FilterableList[list.CompletionItem[projects.Project]]
+
+type workspacesDialogCmp struct {
+	wWidth         int
+	wHeight        int
+	width          int
+	keyMap         KeyMap
+	workspacesList WorkspacesList
+	help           help.Model
+}
+
+// NewWorkspacesDialogCmp creates a new dialog listing the projects, the
+// workspace in the current directory first.
+func NewWorkspacesDialogCmp(current string, all []projects.Project) WorkspacesDialog {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	items := make([]list.CompletionItem[projects.Project], 0, len(all))
+	for _, p := range all {
+		shortcut := humanize.Time(p.LastUsed)
+		if p.Path == current {
+			shortcut = "current"
+		}
+		item := list.NewCompletionItem(
+			fsext.PrettyPath(p.Path),
+			p,
+			list.WithCompletionID(p.DataDir),
+			list.WithCompletionShortcut(shortcut),
+		)
+		if p.Path == current {
+			items = append([]list.CompletionItem[projects.Project]{item}, items...)
+		} else {
+			items = append(items, item)
+		}
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	workspacesList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Enter a project path"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &workspacesDialogCmp{
+		keyMap:         keyMap,
+		workspacesList: workspacesList,
+		help:           help,
+	}
+}
+
+func (s *workspacesDialogCmp) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	cmds = append(cmds, s.workspacesList.Init())
+	cmds = append(cmds, s.workspacesList.Focus())
+	return tea.Sequence(cmds...)
+}
+
+func (s *workspacesDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.wWidth = msg.Width
+		s.wHeight = msg.Height
+		s.width = min(120, s.wWidth-8)
+		s.workspacesList.SetInputWidth(s.listWidth() - 2)
+		return s, s.workspacesList.SetSize(s.listWidth(), s.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, s.keyMap.Select):
+			selectedItem := s.workspacesList.SelectedItem()
+			if selectedItem != nil {
+				p := (*selectedItem).Value()
+				return s, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					util.CmdHandler(WorkspaceSelectedMsg{Project: p}),
+				)
+			}
+		case key.Matches(msg, s.keyMap.Close):
+			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := s.workspacesList.Update(msg)
+			s.workspacesList = u.(WorkspacesList)
+			return s, cmd
+		}
+	}
+	return s, nil
+}
+
+func (s *workspacesDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	listView := s.workspacesList.View()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Switch Workspace", s.width-4)),
+		listView,
+		"",
+		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
+	)
+
+	return s.style().Render(content)
+}
+
+func (s *workspacesDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := s.workspacesList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = s.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (s *workspacesDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(s.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (s *workspacesDialogCmp) listHeight() int {
+	return s.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (s *workspacesDialogCmp) listWidth() int {
+	return s.width - 2 // 2 for the border
+}
+
+func (s *workspacesDialogCmp) Position() (int, int) {
+	row := s.wHeight/4 - 2 // just a bit above the center
+	col := s.wWidth / 2
+	col -= s.width / 2
+	return row, col
+}
+
+func (s *workspacesDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := s.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements WorkspacesDialog.
+func (s *workspacesDialogCmp) ID() dialogs.DialogID {
+	return WorkspacesDialogID
+}
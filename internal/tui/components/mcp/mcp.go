@@ -2,6 +2,8 @@ package mcp
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss/v2"
 
@@ -65,10 +67,20 @@ func RenderMCPList(opts RenderOptions) []string {
 			case agent.MCPStateStarting:
 				icon = t.ItemBusyIcon
 				description = t.S().Subtle.Render("starting...")
+			case agent.MCPStateAuthorizing:
+				icon = t.ItemBusyIcon
+				description = t.S().Subtle.Render("authorize in browser...")
 			case agent.MCPStateConnected:
 				icon = t.ItemOnlineIcon
+				var extra []string
 				if state.ToolCount > 0 {
-					extraContent = t.S().Subtle.Render(fmt.Sprintf("%d tools", state.ToolCount))
+					extra = append(extra, fmt.Sprintf("%d tools", state.ToolCount))
+				}
+				if !state.CheckedAt.IsZero() {
+					extra = append(extra, state.Latency.Round(time.Millisecond).String())
+				}
+				if len(extra) > 0 {
+					extraContent = t.S().Subtle.Render(strings.Join(extra, " · "))
 				}
 			case agent.MCPStateError:
 				icon = t.ItemErrorIcon
@@ -2,6 +2,7 @@ package list
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 type KeyMap struct {
@@ -18,7 +19,7 @@ type KeyMap struct {
 }
 
 func DefaultKeyMap() KeyMap {
-	return KeyMap{
+	return keymap.Apply("list", KeyMap{
 		Down: key.NewBinding(
 			key.WithKeys("down", "ctrl+j", "ctrl+n", "j"),
 			key.WithHelp("↓", "down"),
@@ -59,7 +60,7 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("G", "end"),
 			key.WithHelp("G", "end"),
 		),
-	}
+	})
 }
 
 func (k KeyMap) KeyBindings() []key.Binding {
@@ -57,6 +57,11 @@ type List[T Item] interface {
 	SelectParagraph(col, line int)
 	GetSelectedText(paddingLeft int) string
 	HasSelection() bool
+	// LineAt returns the plain text of the line shown at the row of the view.
+	LineAt(line int) string
+	// ItemAt returns the item shown at the row of the view.
+	ItemAt(line int) (T, bool)
+	AtTop() bool
 }
 
 type direction int
@@ -993,6 +998,12 @@ func (l *list[T]) GoToBottom() tea.Cmd {
 	return l.render()
 }
 
+// AtTop implements List.
+func (l *list[T]) AtTop() bool {
+	start, _ := l.viewPosition()
+	return start == 0
+}
+
 // GoToTop implements List.
 func (l *list[T]) GoToTop() tea.Cmd {
 	l.offset = 0
@@ -1331,14 +1342,22 @@ func (l *list[T]) SelectionClear() {
 	l.selectionActive = false
 }
 
-func (l *list[T]) findWordBoundaries(col, line int) (startCol, endCol int) {
+// renderedLine returns the plain text of the rendered line shown at the row
+// of the view.
+func (l *list[T]) renderedLine(line int) (string, bool) {
 	lines := strings.Split(l.rendered, "\n")
-	for i, l := range lines {
-		lines[i] = ansi.Strip(l)
+	line = l.contentLine(line, len(lines))
+	if line < 0 || line >= len(lines) {
+		return "", false
 	}
+	return ansi.Strip(lines[line]), true
+}
 
-	if l.direction == DirectionBackward && len(lines) > l.height {
-		line = ((len(lines) - 1) - l.height) + line + 1
+// contentLine returns the line of the rendered content shown at the row of
+// the view.
+func (l *list[T]) contentLine(line, lineCount int) int {
+	if l.direction == DirectionBackward && lineCount > l.height {
+		line = ((lineCount - 1) - l.height) + line + 1
 	}
 
 	if l.offset > 0 {
@@ -1348,12 +1367,34 @@ func (l *list[T]) findWordBoundaries(col, line int) (startCol, endCol int) {
 			line += l.offset
 		}
 	}
+	return line
+}
 
-	if line < 0 || line >= len(lines) {
+// ItemAt implements List.
+func (l *list[T]) ItemAt(line int) (T, bool) {
+	var zero T
+	line = l.contentLine(line, lipgloss.Height(l.rendered))
+	for item := range l.items.Seq() {
+		rItem, ok := l.renderedItems.Get(item.ID())
+		if ok && line >= rItem.start && line <= rItem.end {
+			return item, true
+		}
+	}
+	return zero, false
+}
+
+// LineAt implements List.
+func (l *list[T]) LineAt(line int) string {
+	text, _ := l.renderedLine(line)
+	return text
+}
+
+func (l *list[T]) findWordBoundaries(col, line int) (startCol, endCol int) {
+	currentLine, ok := l.renderedLine(line)
+	if !ok {
 		return 0, 0
 	}
 
-	currentLine := lines[line]
 	gr := uniseg.NewGraphemes(currentLine)
 	startCol = -1
 	upTo := col
@@ -3,6 +3,8 @@ package highlight
 import (
 	"bytes"
 	"image/color"
+	"path/filepath"
+	"strings"
 
 	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/formatters"
@@ -52,3 +54,60 @@ func SyntaxHighlight(source, fileName string, bg color.Color) (string, error) {
 	err = f.Format(&buf, s, it)
 	return buf.String(), err
 }
+
+// FenceLanguage returns the name of the language of a markdown code fence
+// from its info string, like "go", "go title=main.go", "go:main.go", "{.go}"
+// or "main.go". It is empty when there is no lexer for it, leaving the
+// language to be detected from the code.
+func FenceLanguage(info string) string {
+	fields := strings.Fields(strings.Trim(strings.TrimSpace(info), "{}"))
+	if len(fields) == 0 {
+		return ""
+	}
+	name := strings.TrimPrefix(fields[0], ".")
+	candidates := []string{name}
+	if lang, path, ok := strings.Cut(name, ":"); ok {
+		candidates = []string{lang, filepath.Base(path)}
+	} else if strings.Contains(name, "/") {
+		candidates = []string{filepath.Base(name)}
+	}
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if l := lexers.Get(candidate); l != nil {
+			return l.Config().Name
+		}
+	}
+	return ""
+}
+
+// FenceFileName returns the file name a code fence info string gives, like
+// "main.go" in "go:main.go" or "cmd/main.go", empty when it names none.
+func FenceFileName(info string) string {
+	fields := strings.Fields(strings.Trim(strings.TrimSpace(info), "{}"))
+	if len(fields) == 0 {
+		return ""
+	}
+	name := strings.TrimPrefix(fields[0], ".")
+	if _, path, ok := strings.Cut(name, ":"); ok {
+		return path
+	}
+	if strings.ContainsAny(name, "./") && lexers.Get(name) != nil {
+		return name
+	}
+	return ""
+}
+
+// Extension returns the usual file extension of the language, ".txt" when
+// it has none.
+func Extension(language string) string {
+	if l := lexers.Get(language); l != nil {
+		for _, pattern := range l.Config().Filenames {
+			if ext := strings.TrimPrefix(pattern, "*"); strings.HasPrefix(ext, ".") && !strings.ContainsAny(ext, "*?[") {
+				return ext
+			}
+		}
+	}
+	return ".txt"
+}
@@ -0,0 +1,49 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFenceLanguage(t *testing.T) {
+	t.Parallel()
+
+	for info, want := range map[string]string{
+		"go":                   "Go",
+		"Python":               "Python",
+		"go title=main.go":     "Go",
+		"go:main.go":           "Go",
+		":internal/app/app.go": "Go",
+		"{.rust}":              "Rust",
+		"main.py":              "Python",
+		"src/app.ts":           "TypeScript",
+		"":                     "",
+		"not-a-language":       "",
+	} {
+		require.Equal(t, want, FenceLanguage(info), info)
+	}
+}
+
+func TestFenceFileName(t *testing.T) {
+	t.Parallel()
+
+	for info, want := range map[string]string{
+		"go":                "",
+		"go:main.go":        "main.go",
+		"main.go":           "main.go",
+		"cmd/crush/main.go": "cmd/crush/main.go",
+		"go title=main.go":  "",
+		"":                  "",
+	} {
+		require.Equal(t, want, FenceFileName(info), info)
+	}
+}
+
+func TestExtension(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, ".go", Extension("Go"))
+	require.Equal(t, ".py", Extension("python"))
+	require.Equal(t, ".txt", Extension(""))
+}
@@ -0,0 +1,83 @@
+// Package keymap applies the key bindings configured in options.tui.keymap
+// over the default ones of the TUI.
+//
+// Every binding is an action named after its key map and its field in snake
+// case, like app.quit, chat.new_session or list.half_page_down. An action
+// configured with no keys is disabled.
+package keymap
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// Apply returns the key map with the keys configured for the actions of its
+// key.Binding fields, scope naming the key map.
+func Apply[T any](scope string, keyMap T) T {
+	bindings := configured()
+	if len(bindings) == 0 {
+		return keyMap
+	}
+	v := reflect.ValueOf(&keyMap).Elem()
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if !field.IsExported() || field.Type != reflect.TypeFor[key.Binding]() {
+			continue
+		}
+		b := v.Field(i).Addr().Interface().(*key.Binding)
+		if keys, ok := bindings[scope+"."+snakeCase(field.Name)]; ok {
+			*b = rebind(*b, keys)
+		}
+	}
+	return keyMap
+}
+
+// Binding returns the binding with the keys configured for the action.
+func Binding(action string, b key.Binding) key.Binding {
+	if keys, ok := configured()[action]; ok {
+		return rebind(b, keys)
+	}
+	return b
+}
+
+// Vim reports whether the vim-style modal editing is enabled.
+func Vim() bool {
+	cfg := config.Get()
+	return cfg != nil && cfg.Options != nil && cfg.Options.TUI != nil && cfg.Options.TUI.Keymap.Vim
+}
+
+func configured() map[string][]string {
+	cfg := config.Get()
+	if cfg == nil || cfg.Options == nil || cfg.Options.TUI == nil {
+		return nil
+	}
+	return cfg.Options.TUI.Keymap.Bindings
+}
+
+func rebind(b key.Binding, keys []string) key.Binding {
+	if len(keys) == 0 {
+		b.SetEnabled(false)
+		return b
+	}
+	b.SetKeys(keys...)
+	b.SetHelp(keys[0], b.Help().Desc)
+	return b
+}
+
+func snakeCase(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
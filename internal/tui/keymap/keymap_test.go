@@ -0,0 +1,63 @@
+package keymap
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type testKeyMap struct {
+	NewSession key.Binding
+	Quit       key.Binding
+	Help       key.Binding
+}
+
+func defaultTestKeyMap() testKeyMap {
+	return testKeyMap{
+		NewSession: key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("ctrl+n", "new session")),
+		Quit:       key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit")),
+		Help:       key.NewBinding(key.WithKeys("ctrl+g"), key.WithHelp("ctrl+g", "more")),
+	}
+}
+
+func TestApply(t *testing.T) {
+	config.Set(&config.Config{Options: &config.Options{TUI: &config.TUIOptions{
+		Keymap: config.KeymapOptions{Bindings: map[string][]string{
+			"test.new_session": {"alt+n", "ctrl+t"},
+			"test.quit":        {},
+		}},
+	}}})
+	t.Cleanup(func() { config.Set(nil) })
+
+	km := Apply("test", defaultTestKeyMap())
+
+	require.Equal(t, []string{"alt+n", "ctrl+t"}, km.NewSession.Keys())
+	require.Equal(t, "alt+n", km.NewSession.Help().Key)
+	require.Equal(t, "new session", km.NewSession.Help().Desc)
+	require.True(t, key.Matches(tea.KeyPressMsg{Code: 't', Mod: tea.ModCtrl}, km.NewSession))
+
+	require.False(t, km.Quit.Enabled())
+	require.False(t, key.Matches(tea.KeyPressMsg{Code: 'c', Mod: tea.ModCtrl}, km.Quit))
+
+	require.Equal(t, []string{"ctrl+g"}, km.Help.Keys())
+
+	b := Binding("test.quit", defaultTestKeyMap().Quit)
+	require.False(t, b.Enabled())
+}
+
+func TestApplyWithoutConfig(t *testing.T) {
+	config.Set(nil)
+	km := Apply("test", defaultTestKeyMap())
+	require.Equal(t, []string{"ctrl+n"}, km.NewSession.Keys())
+	require.False(t, Vim())
+}
+
+func TestSnakeCase(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "new_session", snakeCase("NewSession"))
+	require.Equal(t, "down_one_item", snakeCase("DownOneItem"))
+	require.Equal(t, "quit", snakeCase("Quit"))
+}
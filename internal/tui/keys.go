@@ -2,20 +2,22 @@ package tui
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 type KeyMap struct {
-	Quit     key.Binding
-	Help     key.Binding
-	Commands key.Binding
-	Suspend  key.Binding
-	Sessions key.Binding
+	Quit       key.Binding
+	Help       key.Binding
+	Commands   key.Binding
+	Suspend    key.Binding
+	Sessions   key.Binding
+	Workspaces key.Binding
 
 	pageBindings []key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
-	return KeyMap{
+	return keymap.Apply("app", KeyMap{
 		Quit: key.NewBinding(
 			key.WithKeys("ctrl+c"),
 			key.WithHelp("ctrl+c", "quit"),
@@ -36,5 +38,9 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+s"),
 			key.WithHelp("ctrl+s", "sessions"),
 		),
-	}
+		Workspaces: key.NewBinding(
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "workspaces"),
+		),
+	})
 }
@@ -12,23 +12,29 @@ import (
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/llm/tools"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pin"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/tui/components/anim"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
+	"github.com/charmbracelet/crush/internal/tui/components/chat/diffpane"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/editor"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/header"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/messages"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/sidebar"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/splash"
+	"github.com/charmbracelet/crush/internal/tui/components/checklist"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 	"github.com/charmbracelet/crush/internal/tui/page"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
@@ -51,6 +57,7 @@ const (
 	PanelTypeChat   PanelType = "chat"
 	PanelTypeEditor PanelType = "editor"
 	PanelTypeSplash PanelType = "splash"
+	PanelTypeDiff   PanelType = "diff"
 )
 
 const (
@@ -60,6 +67,8 @@ const (
 	SideBarWidth                = 31  // Width of the sidebar
 	SideBarDetailsPadding       = 1   // Padding for the sidebar details section
 	HeaderHeight                = 1   // Height of the header
+	DiffPaneMinWidth            = 40  // Minimum width of the diff pane and of the messages next to it
+	DiffPanePercent             = 45  // Share of the chat area taken by the diff pane
 
 	// Layout constants for borders and padding
 	BorderWidth        = 1 // Width of component borders
@@ -105,9 +114,11 @@ type chatPage struct {
 	chat    chat.MessageListCmp
 	editor  editor.Editor
 	splash  splash.Splash
+	diff    diffpane.DiffPane
 
 	// Simple state flags
 	showingDetails   bool
+	showDiffPane     bool
 	isCanceling      bool
 	splashFullScreen bool
 	isOnboarding     bool
@@ -119,10 +130,11 @@ func New(app *app.App) ChatPage {
 		app:         app,
 		keyMap:      DefaultKeyMap(),
 		header:      header.New(app.LSPClients),
-		sidebar:     sidebar.New(app.History, app.LSPClients, false),
+		sidebar:     sidebar.New(app.History, app.Pins, app.LSPClients, false),
 		chat:        chat.New(app),
 		editor:      editor.New(app),
 		splash:      splash.New(),
+		diff:        diffpane.New(app.History),
 		focusedPane: PanelTypeSplash,
 	}
 }
@@ -133,6 +145,7 @@ func (p *chatPage) Init() tea.Cmd {
 	p.compact = compact
 	p.forceCompact = compact
 	p.sidebar.SetCompactMode(p.compact)
+	p.showDiffPane = cfg.Options.TUI.DiffPane
 
 	// Set splash state based on config
 	if !config.HasInitialDataConfig() {
@@ -175,6 +188,11 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			p.chat = u.(chat.MessageListCmp)
 			return p, cmd
 		}
+		if p.isMouseOverDiffPane(msg.X, msg.Y) {
+			u, cmd := p.diff.Update(msg)
+			p.diff = u.(diffpane.DiffPane)
+			return p, cmd
+		}
 		return p, nil
 	case tea.MouseClickMsg:
 		if p.isOnboarding {
@@ -183,14 +201,13 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if p.compact {
 			msg.Y -= 1
 		}
-		if p.isMouseOverChat(msg.X, msg.Y) {
-			p.focusedPane = PanelTypeChat
-			p.chat.Focus()
-			p.editor.Blur()
-		} else {
-			p.focusedPane = PanelTypeEditor
-			p.editor.Focus()
-			p.chat.Blur()
+		switch {
+		case p.isMouseOverChat(msg.X, msg.Y):
+			p.setFocus(PanelTypeChat)
+		case p.isMouseOverDiffPane(msg.X, msg.Y):
+			p.setFocus(PanelTypeDiff)
+		default:
+			p.setFocus(PanelTypeEditor)
 		}
 		u, cmd := p.chat.Update(msg)
 		p.chat = u.(chat.MessageListCmp)
@@ -233,10 +250,39 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		u, cmd := p.editor.Update(msg)
 		p.editor = u.(editor.Editor)
 		return p, cmd
+	case chat.OpenFileMsg:
+		if p.diffPaneWidth() > 0 && p.diff.ShowFile(msg.Path, msg.Line) {
+			return p, nil
+		}
+		return p, util.OpenInEditor(msg.Path, msg.Line)
+	case chat.OpenURLMsg:
+		return p, util.OpenURL(msg.URL)
 	case chat.SendMsg:
+		if msg.EditMessageID != "" {
+			return p, p.editPrompt(msg)
+		}
 		return p, p.sendMessage(msg.Text, msg.Attachments)
+	case chat.EditPromptMsg:
+		if msg.Prompt.SessionID != p.session.ID {
+			return p, nil
+		}
+		p.setFocus(PanelTypeEditor)
+		u, cmd := p.editor.Update(msg)
+		p.editor = u.(editor.Editor)
+		return p, cmd
 	case chat.SessionSelectedMsg:
 		return p, p.setSession(msg)
+	case chat.SessionRewoundMsg:
+		if msg.Session.ID != p.session.ID {
+			return p, nil
+		}
+		u, cmd := p.chat.Update(msg)
+		p.chat = u.(chat.MessageListCmp)
+		cmds = append(cmds, cmd)
+		u, cmd = p.editor.Update(editor.OpenEditorMsg{Text: msg.Prompt})
+		p.editor = u.(editor.Editor)
+		cmds = append(cmds, cmd, p.loadPlan(msg.Session.ID))
+		return p, tea.Batch(cmds...)
 	case splash.SubmitAPIKeyMsg:
 		u, cmd := p.splash.Update(msg)
 		p.splash = u.(splash.Splash)
@@ -253,6 +299,8 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmd = p.updateCompactConfig(false)
 		}
 		return p, tea.Batch(p.SetSize(p.width, p.height), cmd)
+	case commands.ToggleDiffPaneMsg:
+		return p, p.toggleDiffPane()
 	case commands.ToggleThinkingMsg:
 		return p, p.toggleThinking()
 	case commands.OpenExternalEditorMsg:
@@ -277,8 +325,12 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		u, cmd = p.chat.Update(msg)
 		p.chat = u.(chat.MessageListCmp)
 		cmds = append(cmds, cmd)
+		cmds = append(cmds, p.diff.SetSession(session.Session{}))
 		return p, tea.Batch(cmds...)
 	case filepicker.FilePickedMsg,
+		editor.ResourceRefreshedMsg,
+		editor.SymbolCompletionsMsg,
+		pubsub.Event[agent.MCPEvent],
 		completions.CompletionsClosedMsg,
 		completions.SelectCompletionMsg:
 		u, cmd := p.editor.Update(msg)
@@ -296,6 +348,14 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case pubsub.Event[message.Message],
 		anim.StepMsg,
 		spinner.TickMsg:
+		if event, ok := msg.(pubsub.Event[message.Message]); ok && event.Payload.SessionID == p.session.ID {
+			if steps, ok := checklist.FromMessage(event.Payload); ok {
+				cmds = append(cmds, util.CmdHandler(checklist.PlanUpdatedMsg{
+					SessionID: event.Payload.SessionID,
+					Steps:     steps,
+				}))
+			}
+		}
 		if p.focusedPane == PanelTypeSplash {
 			u, cmd := p.splash.Update(msg)
 			p.splash = u.(splash.Splash)
@@ -307,28 +367,73 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		return p, tea.Batch(cmds...)
-	case commands.ToggleYoloModeMsg:
+	case app.ConfigReloadedMsg:
+		if !msg.Changed("options.tui") {
+			return p, nil
+		}
+		p.keyMap = DefaultKeyMap()
+		tuiOptions := config.Get().Options.TUI
+		p.forceCompact = tuiOptions.CompactMode
+		if p.forceCompact {
+			p.setCompactMode(true)
+		} else {
+			p.handleCompactMode(p.width, p.height)
+		}
+		p.showDiffPane = tuiOptions.DiffPane
+		if !p.showDiffPane && p.focusedPane == PanelTypeDiff {
+			p.setFocus(PanelTypeEditor)
+		}
+		u, cmd := p.editor.Update(msg)
+		p.editor = u.(editor.Editor)
+		cmds = append(cmds, cmd)
+		u, cmd = p.diff.Update(msg)
+		p.diff = u.(diffpane.DiffPane)
+		cmds = append(cmds, cmd, p.SetSize(p.width, p.height))
+		return p, tea.Batch(cmds...)
+	case commands.ToggleYoloModeMsg, commands.TogglePlanModeMsg:
 		// update the editor style
 		u, cmd := p.editor.Update(msg)
 		p.editor = u.(editor.Editor)
 		return p, cmd
-	case pubsub.Event[history.File], sidebar.SessionFilesMsg:
+	case pubsub.Event[history.File]:
 		u, cmd := p.sidebar.Update(msg)
 		p.sidebar = u.(sidebar.Sidebar)
 		cmds = append(cmds, cmd)
+		u, cmd = p.diff.Update(msg)
+		p.diff = u.(diffpane.DiffPane)
+		cmds = append(cmds, cmd)
 		return p, tea.Batch(cmds...)
-	case pubsub.Event[permission.PermissionNotification]:
+	case checklist.PlanUpdatedMsg:
+		u, cmd := p.header.Update(msg)
+		p.header = u.(header.Header)
+		cmds = append(cmds, cmd)
+		u, cmd = p.sidebar.Update(msg)
+		p.sidebar = u.(sidebar.Sidebar)
+		cmds = append(cmds, cmd)
+		return p, tea.Batch(cmds...)
+	case sidebar.SessionFilesMsg, sidebar.SessionPinsMsg, pubsub.Event[pin.Pin]:
+		u, cmd := p.sidebar.Update(msg)
+		p.sidebar = u.(sidebar.Sidebar)
+		cmds = append(cmds, cmd)
+		return p, tea.Batch(cmds...)
+	case pubsub.Event[permission.PermissionNotification], pubsub.Event[tools.ToolOutput]:
 		u, cmd := p.chat.Update(msg)
 		p.chat = u.(chat.MessageListCmp)
 		cmds = append(cmds, cmd)
 		return p, tea.Batch(cmds...)
 
 	case commands.CommandRunCustomMsg:
-		if p.app.CoderAgent.IsBusy() {
+		// The command is queued when the session is busy, without its
+		// options as the queued prompts share the running turn.
+		if p.app.CoderAgent.IsSessionBusy(p.session.ID) && (msg.Options.Model != "" || msg.Options.AllowedTools != nil) {
+			return p, util.ReportWarn("Agent is busy, please wait before executing a command with a model or allowed tools...")
+		}
+		if p.app.CoderAgent.IsBusy() && !p.app.CoderAgent.IsSessionBusy(p.session.ID) {
 			return p, util.ReportWarn("Agent is busy, please wait before executing a command...")
 		}
 
-		cmd := p.sendMessage(msg.Content, nil)
+		ctx := agent.WithRunOptions(context.Background(), msg.Options)
+		cmd := p.sendMessageContext(ctx, msg.Content, nil)
 		if cmd != nil {
 			return p, cmd
 		}
@@ -364,13 +469,9 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return p, p.newSession()
 		case key.Matches(msg, p.keyMap.AddAttachment):
-			agentCfg := config.Get().Agents["coder"]
-			model := config.Get().GetModelByType(agentCfg.Model)
-			if model.SupportsImages {
-				return p, util.CmdHandler(commands.OpenFilePickerMsg{})
-			} else {
-				return p, util.ReportWarn("File attachments are not supported by the current model: " + model.Name)
-			}
+			// Documents are converted to text for any model, images are
+			// refused when picked if the model doesn't support them.
+			return p, util.CmdHandler(commands.OpenFilePickerMsg{})
 		case key.Matches(msg, p.keyMap.Tab):
 			if p.session.ID == "" {
 				u, cmd := p.splash.Update(msg)
@@ -386,6 +487,11 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, p.keyMap.Details):
 			p.toggleDetails()
 			return p, nil
+		case key.Matches(msg, p.keyMap.DiffPane):
+			return p, p.toggleDiffPane()
+		case p.focusedPane == PanelTypeChat && keymap.Vim() && key.Matches(msg, p.keyMap.Insert):
+			p.setFocus(PanelTypeEditor)
+			return p, nil
 		}
 
 		switch p.focusedPane {
@@ -401,6 +507,10 @@ func (p *chatPage) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			u, cmd := p.splash.Update(msg)
 			p.splash = u.(splash.Splash)
 			cmds = append(cmds, cmd)
+		case PanelTypeDiff:
+			u, cmd := p.diff.Update(msg)
+			p.diff = u.(diffpane.DiffPane)
+			cmds = append(cmds, cmd)
 		}
 	case tea.PasteMsg:
 		switch p.focusedPane {
@@ -458,6 +568,13 @@ func (p *chatPage) View() string {
 		}
 	} else {
 		messagesView := p.chat.View()
+		if p.diffPaneWidth() > 0 {
+			messagesView = lipgloss.JoinHorizontal(
+				lipgloss.Top,
+				messagesView,
+				p.diff.View(),
+			)
+		}
 		editorView := p.editor.View()
 		if p.compact {
 			headerView := p.header.View()
@@ -588,14 +705,17 @@ func (p *chatPage) SetSize(width, height int) tea.Cmd {
 			cmds = append(cmds, p.editor.SetPosition(0, height-EditorHeight))
 		}
 	} else {
+		diffWidth := p.diffPaneWidth()
 		if p.compact {
-			cmds = append(cmds, p.chat.SetSize(width, height-EditorHeight-HeaderHeight))
+			cmds = append(cmds, p.chat.SetSize(width-diffWidth, height-EditorHeight-HeaderHeight))
+			cmds = append(cmds, p.diff.SetSize(diffWidth, height-EditorHeight-HeaderHeight))
 			p.detailsWidth = width - DetailsPositioning
 			cmds = append(cmds, p.sidebar.SetSize(p.detailsWidth-LeftRightBorders, p.detailsHeight-TopBottomBorders))
 			cmds = append(cmds, p.editor.SetSize(width, EditorHeight))
 			cmds = append(cmds, p.header.SetWidth(width-BorderWidth))
 		} else {
-			cmds = append(cmds, p.chat.SetSize(width-SideBarWidth, height-EditorHeight))
+			cmds = append(cmds, p.chat.SetSize(width-SideBarWidth-diffWidth, height-EditorHeight))
+			cmds = append(cmds, p.diff.SetSize(diffWidth, height-EditorHeight))
 			cmds = append(cmds, p.editor.SetSize(width, EditorHeight))
 			cmds = append(cmds, p.sidebar.SetSize(SideBarWidth, height-EditorHeight))
 		}
@@ -610,9 +730,7 @@ func (p *chatPage) newSession() tea.Cmd {
 	}
 
 	p.session = session.Session{}
-	p.focusedPane = PanelTypeEditor
-	p.editor.Focus()
-	p.chat.Blur()
+	p.setFocus(PanelTypeEditor)
 	p.isCanceling = false
 	return tea.Batch(
 		util.CmdHandler(chat.SessionClearedMsg{}),
@@ -631,25 +749,103 @@ func (p *chatPage) setSession(session session.Session) tea.Cmd {
 	cmds = append(cmds, p.SetSize(p.width, p.height))
 	cmds = append(cmds, p.chat.SetSession(session))
 	cmds = append(cmds, p.sidebar.SetSession(session))
+	cmds = append(cmds, p.diff.SetSession(session))
 	cmds = append(cmds, p.header.SetSession(session))
 	cmds = append(cmds, p.editor.SetSession(session))
+	cmds = append(cmds, p.loadPlan(session.ID))
 
 	return tea.Sequence(cmds...)
 }
 
+// loadPlan reads the last plan the agent made in the session, to show its
+// progress when the session is opened.
+func (p *chatPage) loadPlan(sessionID string) tea.Cmd {
+	return func() tea.Msg {
+		msgs, err := p.app.Messages.List(context.Background(), sessionID)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		steps, _ := checklist.Latest(msgs)
+		return checklist.PlanUpdatedMsg{SessionID: sessionID, Steps: steps}
+	}
+}
+
 func (p *chatPage) changeFocus() {
 	if p.session.ID == "" {
 		return
 	}
 	switch p.focusedPane {
 	case PanelTypeChat:
-		p.focusedPane = PanelTypeEditor
-		p.editor.Focus()
-		p.chat.Blur()
+		if p.diffPaneWidth() > 0 {
+			p.setFocus(PanelTypeDiff)
+		} else {
+			p.setFocus(PanelTypeEditor)
+		}
 	case PanelTypeEditor:
-		p.focusedPane = PanelTypeChat
+		p.setFocus(PanelTypeChat)
+	case PanelTypeDiff:
+		p.setFocus(PanelTypeEditor)
+	}
+}
+
+// setFocus focuses one of the panes of a session, blurring the others.
+func (p *chatPage) setFocus(pane PanelType) {
+	p.focusedPane = pane
+	p.chat.Blur()
+	p.editor.Blur()
+	p.diff.Blur()
+	switch pane {
+	case PanelTypeChat:
 		p.chat.Focus()
-		p.editor.Blur()
+	case PanelTypeEditor:
+		p.editor.Focus()
+	case PanelTypeDiff:
+		p.diff.Focus()
+	}
+}
+
+// diffPaneWidth returns the width of the diff pane, or 0 when it is hidden or
+// the window is too narrow for it.
+func (p *chatPage) diffPaneWidth() int {
+	if !p.showDiffPane || p.session.ID == "" {
+		return 0
+	}
+	available := p.width
+	if !p.compact {
+		available -= SideBarWidth
+	}
+	width := max(DiffPaneMinWidth, available*DiffPanePercent/100)
+	if available-width < DiffPaneMinWidth {
+		return 0
+	}
+	return width
+}
+
+func (p *chatPage) toggleDiffPane() tea.Cmd {
+	if p.session.ID == "" {
+		return nil
+	}
+	p.showDiffPane = !p.showDiffPane
+	if !p.showDiffPane && p.focusedPane == PanelTypeDiff {
+		p.setFocus(PanelTypeEditor)
+	}
+	var cmd tea.Cmd
+	if p.showDiffPane && p.diffPaneWidth() == 0 {
+		cmd = util.ReportWarn("The window is too narrow for the diff pane")
+	}
+	return tea.Batch(p.SetSize(p.width, p.height), cmd, p.updateDiffPaneConfig(p.showDiffPane))
+}
+
+func (p *chatPage) updateDiffPaneConfig(show bool) tea.Cmd {
+	return func() tea.Msg {
+		err := config.Get().SetDiffPane(show)
+		if err != nil {
+			return util.InfoMsg{
+				Type: util.InfoTypeError,
+				Msg:  "Failed to update diff pane configuration: " + err.Error(),
+			}
+		}
+		return nil
 	}
 }
 
@@ -686,6 +882,33 @@ func (p *chatPage) toggleDetails() {
 }
 
 func (p *chatPage) sendMessage(text string, attachments []message.Attachment) tea.Cmd {
+	return p.sendMessageContext(context.Background(), text, attachments)
+}
+
+// editPrompt sends the message in place of the prompt it edits, archiving the
+// conversation after the prompt before regenerating it.
+func (p *chatPage) editPrompt(msg chat.SendMsg) tea.Cmd {
+	result, err := p.app.EditPrompt(context.Background(), p.session.ID, msg.EditMessageID, msg.Text, msg.Attachments...)
+	if err != nil && result.Branch.ID == "" {
+		return util.ReportError(err)
+	}
+	sess, getErr := p.app.Sessions.Get(context.Background(), p.session.ID)
+	if getErr != nil {
+		return util.ReportError(getErr)
+	}
+	u, cmd := p.chat.Update(chat.SessionRewoundMsg{Session: sess})
+	p.chat = u.(chat.MessageListCmp)
+	cmds := []tea.Cmd{cmd, p.loadPlan(sess.ID)}
+	if err != nil {
+		return tea.Batch(append(cmds, util.ReportError(err))...)
+	}
+	return tea.Batch(append(cmds,
+		p.chat.GoToBottom(),
+		util.ReportInfo("Regenerating from the edited prompt, the previous conversation is archived in "+result.Branch.ID),
+	)...)
+}
+
+func (p *chatPage) sendMessageContext(ctx context.Context, text string, attachments []message.Attachment) tea.Cmd {
 	session := p.session
 	var cmds []tea.Cmd
 	if p.session.ID == "" {
@@ -699,10 +922,16 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 	if p.app.CoderAgent == nil {
 		return util.ReportError(fmt.Errorf("coder agent is not initialized"))
 	}
-	_, err := p.app.CoderAgent.Run(context.Background(), session.ID, text, attachments...)
+	queued := p.app.CoderAgent.IsSessionBusy(session.ID)
+	_, err := p.app.CoderAgent.Run(ctx, session.ID, text, attachments...)
 	if err != nil {
 		return util.ReportError(err)
 	}
+	if queued && len(attachments) > 0 {
+		cmds = append(cmds, util.ReportWarn("Message queued without its attachments"))
+	} else if queued {
+		cmds = append(cmds, util.ReportInfo("Message queued, open Queued Messages to edit it"))
+	}
 	cmds = append(cmds, p.chat.GoToBottom())
 	return tea.Batch(cmds...)
 }
@@ -726,22 +955,30 @@ func (p *chatPage) Bindings() []key.Binding {
 	switch p.focusedPane {
 	case PanelTypeChat:
 		bindings = append([]key.Binding{
-			key.NewBinding(
+			keymap.Binding("chat.tab", key.NewBinding(
 				key.WithKeys("tab"),
 				key.WithHelp("tab", "focus editor"),
-			),
+			)),
 		}, bindings...)
 		bindings = append(bindings, p.chat.Bindings()...)
 	case PanelTypeEditor:
 		bindings = append([]key.Binding{
-			key.NewBinding(
+			keymap.Binding("chat.tab", key.NewBinding(
 				key.WithKeys("tab"),
 				key.WithHelp("tab", "focus chat"),
-			),
+			)),
 		}, bindings...)
 		bindings = append(bindings, p.editor.Bindings()...)
 	case PanelTypeSplash:
 		bindings = append(bindings, p.splash.Bindings()...)
+	case PanelTypeDiff:
+		bindings = append([]key.Binding{
+			keymap.Binding("chat.tab", key.NewBinding(
+				key.WithKeys("tab"),
+				key.WithHelp("tab", "focus editor"),
+			)),
+		}, bindings...)
+		bindings = append(bindings, p.diff.Bindings()...)
 	}
 
 	return bindings
@@ -764,10 +1001,10 @@ func (p *chatPage) Help() help.KeyMap {
 				key.WithHelp("enter", "accept"),
 			),
 			// Quit
-			key.NewBinding(
+			keymap.Binding("app.quit", key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("ctrl+c", "quit"),
-			),
+			)),
 		)
 		// keep them the same
 		for _, v := range shortList {
@@ -792,10 +1029,10 @@ func (p *chatPage) Help() help.KeyMap {
 		}
 		shortList = append(shortList,
 			// Quit
-			key.NewBinding(
+			keymap.Binding("app.quit", key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("ctrl+c", "quit"),
-			),
+			)),
 		)
 		// keep them the same
 		for _, v := range shortList {
@@ -803,10 +1040,10 @@ func (p *chatPage) Help() help.KeyMap {
 		}
 	case p.isProjectInit:
 		shortList = append(shortList,
-			key.NewBinding(
+			keymap.Binding("app.quit", key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("ctrl+c", "quit"),
-			),
+			)),
 		)
 		// keep them the same
 		for _, v := range shortList {
@@ -815,10 +1052,10 @@ func (p *chatPage) Help() help.KeyMap {
 	default:
 		if p.editor.IsCompletionsOpen() {
 			shortList = append(shortList,
-				key.NewBinding(
+				keymap.Binding("chat.tab", key.NewBinding(
 					key.WithKeys("tab", "enter"),
 					key.WithHelp("tab/enter", "complete"),
-				),
+				)),
 				key.NewBinding(
 					key.WithKeys("esc"),
 					key.WithHelp("esc", "cancel"),
@@ -860,40 +1097,48 @@ func (p *chatPage) Help() help.KeyMap {
 		globalBindings := []key.Binding{}
 		// we are in a session
 		if p.session.ID != "" {
-			tabKey := key.NewBinding(
+			tabKey := keymap.Binding("chat.tab", key.NewBinding(
 				key.WithKeys("tab"),
 				key.WithHelp("tab", "focus chat"),
-			)
-			if p.focusedPane == PanelTypeChat {
-				tabKey = key.NewBinding(
+			))
+			switch {
+			case p.focusedPane == PanelTypeChat && p.diffPaneWidth() > 0:
+				tabKey = keymap.Binding("chat.tab", key.NewBinding(
+					key.WithKeys("tab"),
+					key.WithHelp("tab", "focus diff"),
+				))
+			case p.focusedPane == PanelTypeChat, p.focusedPane == PanelTypeDiff:
+				tabKey = keymap.Binding("chat.tab", key.NewBinding(
 					key.WithKeys("tab"),
 					key.WithHelp("tab", "focus editor"),
-				)
+				))
 			}
 			shortList = append(shortList, tabKey)
 			globalBindings = append(globalBindings, tabKey)
 		}
-		commandsBinding := key.NewBinding(
+		commandsBinding := keymap.Binding("app.commands", key.NewBinding(
 			key.WithKeys("ctrl+p"),
 			key.WithHelp("ctrl+p", "commands"),
-		)
-		helpBinding := key.NewBinding(
+		))
+		helpBinding := keymap.Binding("app.help", key.NewBinding(
 			key.WithKeys("ctrl+g"),
 			key.WithHelp("ctrl+g", "more"),
-		)
+		))
 		globalBindings = append(globalBindings, commandsBinding)
 		globalBindings = append(globalBindings,
-			key.NewBinding(
+			keymap.Binding("app.sessions", key.NewBinding(
 				key.WithKeys("ctrl+s"),
 				key.WithHelp("ctrl+s", "sessions"),
-			),
+			)),
 		)
 		if p.session.ID != "" {
 			globalBindings = append(globalBindings,
-				key.NewBinding(
+				keymap.Binding("chat.new_session", key.NewBinding(
 					key.WithKeys("ctrl+n"),
 					key.WithHelp("ctrl+n", "new sessions"),
-				))
+				)),
+				p.keyMap.DiffPane,
+			)
 		}
 		shortList = append(shortList,
 			// Commands
@@ -949,17 +1194,24 @@ func (p *chatPage) Help() help.KeyMap {
 				},
 				[]key.Binding{
 					messages.CopyKey,
+					messages.BlocksKey,
+					messages.ToggleThinkingKey,
+					messages.ScrollOutputKey,
 					messages.ClearSelectionKey,
 				},
 			)
+		case PanelTypeDiff:
+			bindings := p.diff.Bindings()
+			shortList = append(shortList, bindings[:3]...)
+			fullList = append(fullList, bindings[:3], bindings[3:])
 		case PanelTypeEditor:
-			newLineBinding := key.NewBinding(
+			newLineBinding := keymap.Binding("editor.newline", key.NewBinding(
 				key.WithKeys("shift+enter", "ctrl+j"),
 				// "ctrl+j" is a common keybinding for newline in many editors. If
 				// the terminal supports "shift+enter", we substitute the help text
 				// to reflect that.
 				key.WithHelp("ctrl+j", "newline"),
-			)
+			))
 			if p.keyboardEnhancements.SupportsKeyDisambiguation() {
 				newLineBinding.SetHelp("shift+enter", newLineBinding.Help().Desc)
 			}
@@ -967,18 +1219,18 @@ func (p *chatPage) Help() help.KeyMap {
 			fullList = append(fullList,
 				[]key.Binding{
 					newLineBinding,
-					key.NewBinding(
+					keymap.Binding("chat.add_attachment", key.NewBinding(
 						key.WithKeys("ctrl+f"),
 						key.WithHelp("ctrl+f", "add image"),
-					),
-					key.NewBinding(
+					)),
+					keymap.Binding("editor.add_file", key.NewBinding(
 						key.WithKeys("/"),
 						key.WithHelp("/", "add file"),
-					),
-					key.NewBinding(
+					)),
+					keymap.Binding("editor.open_editor", key.NewBinding(
 						key.WithKeys("ctrl+o"),
 						key.WithHelp("ctrl+o", "open editor"),
-					),
+					)),
 				})
 
 			if p.editor.HasAttachments() {
@@ -1000,18 +1252,18 @@ func (p *chatPage) Help() help.KeyMap {
 		}
 		shortList = append(shortList,
 			// Quit
-			key.NewBinding(
+			keymap.Binding("app.quit", key.NewBinding(
 				key.WithKeys("ctrl+c"),
 				key.WithHelp("ctrl+c", "quit"),
-			),
+			)),
 			// Help
 			helpBinding,
 		)
 		fullList = append(fullList, []key.Binding{
-			key.NewBinding(
+			keymap.Binding("app.help", key.NewBinding(
 				key.WithKeys("ctrl+g"),
 				key.WithHelp("ctrl+g", "less"),
-			),
+			)),
 		})
 	}
 
@@ -1045,7 +1297,26 @@ func (p *chatPage) isMouseOverChat(x, y int) bool {
 		chatWidth = p.width - SideBarWidth
 		chatHeight = p.height - EditorHeight
 	}
+	chatWidth -= p.diffPaneWidth()
 
 	// Check if mouse coordinates are within chat bounds
 	return x >= chatX && x < chatX+chatWidth && y >= chatY && y < chatY+chatHeight
 }
+
+// isMouseOverDiffPane checks if the given mouse coordinates are within the diff
+// pane, right of the chat area.
+func (p *chatPage) isMouseOverDiffPane(x, y int) bool {
+	width := p.diffPaneWidth()
+	if width == 0 {
+		return false
+	}
+	paneX, paneY := p.width-width, 0
+	height := p.height - EditorHeight
+	if p.compact {
+		paneY = HeaderHeight
+		height -= HeaderHeight
+	} else {
+		paneX -= SideBarWidth
+	}
+	return x >= paneX && x < paneX+width && y >= paneY && y < paneY+height
+}
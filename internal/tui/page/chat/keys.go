@@ -2,6 +2,7 @@ package chat
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/keymap"
 )
 
 type KeyMap struct {
@@ -10,10 +11,13 @@ type KeyMap struct {
 	Cancel        key.Binding
 	Tab           key.Binding
 	Details       key.Binding
+	DiffPane      key.Binding
+	// Insert focuses the editor from the chat when vim mode is enabled.
+	Insert key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
-	return KeyMap{
+	return keymap.Apply("chat", KeyMap{
 		NewSession: key.NewBinding(
 			key.WithKeys("ctrl+n"),
 			key.WithHelp("ctrl+n", "new session"),
@@ -34,5 +38,13 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+d"),
 			key.WithHelp("ctrl+d", "toggle details"),
 		),
-	}
+		DiffPane: key.NewBinding(
+			key.WithKeys("ctrl+l"),
+			key.WithHelp("ctrl+l", "toggle diff pane"),
+		),
+		Insert: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "focus editor"),
+		),
+	})
 }
@@ -10,6 +10,7 @@ const (
 	LoadingIcon  string = "⟳"
 	DocumentIcon string = "🖼"
 	ModelIcon    string = "◇"
+	PinIcon      string = "◆"
 
 	// Tool call icons
 	ToolPending string = "●"
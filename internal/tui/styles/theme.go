@@ -648,6 +648,22 @@ func ApplyBoldForegroundGrad(input string, color1, color2 color.Color) string {
 	return o.String()
 }
 
+// GradientColor returns the color at the position, between 0 and 1, of a
+// gradient going through the given stops. Blending is done in Hcl to stay in
+// gamut.
+func GradientColor(position float64, stops ...color.Color) color.Color {
+	if len(stops) < 2 {
+		return nil
+	}
+	position = max(0, min(1, position))
+	segments := len(stops) - 1
+	scaled := position * float64(segments)
+	i := min(int(scaled), segments-1)
+	c1, _ := colorful.MakeColor(stops[i])
+	c2, _ := colorful.MakeColor(stops[i+1])
+	return c1.BlendHcl(c2, scaled-float64(i)).Clamped()
+}
+
 // blendColors returns a slice of colors blended between the given keys.
 // Blending is done in Hcl to stay in gamut.
 func blendColors(size int, stops ...color.Color) []color.Color {
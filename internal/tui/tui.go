@@ -2,7 +2,9 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,8 +13,13 @@ import (
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/projects"
 	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
 	cmpChat "github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/splash"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
@@ -20,13 +27,25 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/components/core/status"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/artifacts"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/compact"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/health"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/mcpresources"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/mcpservers"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/overloaded"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/permissions"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/pins"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/plan"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/queue"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/quit"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/restore"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/rewind"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/sessionfiles"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/sessions"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/workspaces"
 	"github.com/charmbracelet/crush/internal/tui/page"
 	"github.com/charmbracelet/crush/internal/tui/page/chat"
 	"github.com/charmbracelet/crush/internal/tui/styles"
@@ -49,6 +68,12 @@ func MouseEventFilter(m tea.Model, msg tea.Msg) tea.Msg {
 	return msg
 }
 
+// sessionRewoundMsg carries the outcome of rewinding a session.
+type sessionRewoundMsg struct {
+	session session.Session
+	rewind  app.Rewind
+}
+
 // appModel represents the main application model that manages pages, dialogs, and UI state.
 type appModel struct {
 	wWidth, wHeight int // Window dimensions
@@ -72,6 +97,9 @@ type appModel struct {
 
 	// Chat Page Specific
 	selectedSessionID string // The ID of the currently selected session
+
+	// switchTo is the workspace to switch to once the TUI quits.
+	switchTo *projects.Project
 }
 
 // Init initializes the application model and returns initial commands.
@@ -84,7 +112,11 @@ func (a appModel) Init() tea.Cmd {
 	cmd = a.status.Init()
 	cmds = append(cmds, cmd)
 
-	cmds = append(cmds, tea.EnableMouseAllMotion)
+	if a.app.Config().Mouse() {
+		cmds = append(cmds, tea.EnableMouseAllMotion)
+	}
+
+	a.app.PrewarmAgent()
 
 	return tea.Batch(cmds...)
 }
@@ -127,11 +159,7 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case commands.ShowArgumentsDialogMsg:
 		return a, util.CmdHandler(
 			dialogs.OpenDialogMsg{
-				Model: commands.NewCommandArgumentsDialog(
-					msg.CommandID,
-					msg.Content,
-					msg.ArgNames,
-				),
+				Model: commands.NewCommandArgumentsDialog(msg),
 			},
 		)
 	// Page change messages
@@ -147,6 +175,9 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Session
 	case cmpChat.SessionSelectedMsg:
+		if msg.ID != a.selectedSessionID {
+			a.app.PrewarmAgent()
+		}
 		a.selectedSessionID = msg.ID
 	case cmpChat.SessionClearedMsg:
 		a.selectedSessionID = ""
@@ -159,6 +190,195 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+	case commands.OpenSessionFilesMsg:
+		if a.selectedSessionID == "" {
+			return a, nil
+		}
+		return a, func() tea.Msg {
+			files, err := a.app.History.ListTouched(context.Background(), a.selectedSessionID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			if len(files) == 0 {
+				return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "No files touched in this session yet"}
+			}
+			return dialogs.OpenDialogMsg{
+				Model: sessionfiles.NewSessionFilesDialogCmp(files),
+			}
+		}
+
+	case commands.OpenArtifactsMsg:
+		if a.selectedSessionID == "" {
+			return a, nil
+		}
+		return a, func() tea.Msg {
+			list, err := a.app.Artifacts.List(a.selectedSessionID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			if len(list) == 0 {
+				return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "No artifacts in this session yet"}
+			}
+			return dialogs.OpenDialogMsg{
+				Model: artifacts.NewArtifactsDialogCmp(a.app.Artifacts, list),
+			}
+		}
+
+	case commands.OpenWorkspacesMsg:
+		return a, a.openWorkspaces()
+
+	case workspaces.WorkspaceSelectedMsg:
+		if msg.Project.Path == a.app.Config().WorkingDir() {
+			return a, nil
+		}
+		if a.app.CoderAgent != nil && a.app.CoderAgent.IsBusy() {
+			return a, util.ReportWarn("Agent is busy, please wait...")
+		}
+		a.switchTo = &msg.Project
+		return a, tea.Quit
+
+	case commands.OpenTrashMsg:
+		if a.selectedSessionID == "" {
+			return a, nil
+		}
+		return a, func() tea.Msg {
+			entries, err := a.app.Trash.List(a.selectedSessionID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			if len(entries) == 0 {
+				return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "No files deleted or overwritten in this session yet"}
+			}
+			return dialogs.OpenDialogMsg{
+				Model: restore.NewRestoreDialogCmp(a.app.Trash, entries),
+			}
+		}
+
+	case commands.OpenRewindMsg:
+		if a.selectedSessionID == "" {
+			return a, util.ReportInfo("Send a prompt first to rewind the session")
+		}
+		return a, a.openPromptsDialog("No prompts to rewind to in this session", rewind.NewRewindDialogCmp)
+
+	case commands.OpenEditPromptMsg:
+		if a.selectedSessionID == "" {
+			return a, util.ReportInfo("Send a prompt first to edit it")
+		}
+		if a.app.CoderAgent != nil && a.app.CoderAgent.IsSessionBusy(a.selectedSessionID) {
+			return a, util.ReportWarn("Agent is working, cancel it first to edit a prompt")
+		}
+		return a, a.openPromptsDialog("No prompts to edit in this session", rewind.NewEditDialogCmp)
+
+	case commands.OpenPinMsg:
+		if a.selectedSessionID == "" {
+			return a, util.ReportInfo("Send a prompt first to pin files or messages")
+		}
+		sessionID := a.selectedSessionID
+		return a, func() tea.Msg {
+			candidates, err := a.app.PinCandidates(context.Background(), sessionID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			if len(candidates) == 0 {
+				return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "No files or messages to pin in this session"}
+			}
+			return dialogs.OpenDialogMsg{
+				Model: pins.NewPinDialogCmp(candidates),
+			}
+		}
+
+	case commands.OpenUnpinMsg:
+		if a.selectedSessionID == "" {
+			return a, nil
+		}
+		sessionID := a.selectedSessionID
+		return a, func() tea.Msg {
+			pinned, err := a.app.Pins.List(sessionID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			if len(pinned) == 0 {
+				return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Nothing is pinned in this session"}
+			}
+			return dialogs.OpenDialogMsg{
+				Model: pins.NewUnpinDialogCmp(pinned),
+			}
+		}
+
+	case pins.PinSelectedMsg:
+		return a, func() tea.Msg {
+			pinned, err := a.app.Pin(context.Background(), msg.Pin)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return util.InfoMsg{
+				Type: util.InfoTypeInfo,
+				Msg:  fmt.Sprintf("Pinned %s, about %s tokens added to each request", pins.Title(pinned), status.FormatTokens(int64(pinned.Tokens))),
+			}
+		}
+
+	case pins.UnpinSelectedMsg:
+		return a, func() tea.Msg {
+			if _, err := a.app.Pins.Unpin(msg.Pin.SessionID, msg.Pin.ID); err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Unpinned " + pins.Title(msg.Pin)}
+		}
+
+	case rewind.EditSelectedMsg:
+		return a, util.CmdHandler(cmpChat.EditPromptMsg{Prompt: msg.Prompt})
+
+	case rewind.RewindSelectedMsg:
+		return a, func() tea.Msg {
+			result, err := a.app.Rewind(context.Background(), msg.SessionID, msg.MessageID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			sess, err := a.app.Sessions.Get(context.Background(), msg.SessionID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return sessionRewoundMsg{session: sess, rewind: result}
+		}
+
+	case sessionRewoundMsg:
+		info := "Rewound the session"
+		if len(msg.rewind.Files) > 0 {
+			info += fmt.Sprintf(" and restored %d files", len(msg.rewind.Files))
+		}
+		return a, tea.Batch(
+			util.CmdHandler(cmpChat.SessionRewoundMsg{Session: msg.session, Prompt: msg.rewind.Prompt}),
+			util.ReportInfo(info+", the discarded messages are archived in "+msg.rewind.Branch.ID),
+		)
+
+	case commands.OpenMCPResourcesMsg:
+		return a, func() tea.Msg {
+			resources, err := agent.ListMCPResources(context.Background())
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			if len(resources) == 0 {
+				return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "No MCP resources available"}
+			}
+			return dialogs.OpenDialogMsg{
+				Model: mcpresources.NewMCPResourcesDialogCmp(resources),
+			}
+		}
+
+	case commands.OpenMCPServersMsg:
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: mcpservers.NewMCPServersDialogCmp(),
+			},
+		)
+
+	case commands.OpenStatusMsg:
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: health.NewHealthDialogCmp(a.app.Doctor),
+			},
+		)
+
 	case commands.SwitchModelMsg:
 		return a, util.CmdHandler(
 			dialogs.OpenDialogMsg{
@@ -176,6 +396,28 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		})
 	case commands.ToggleYoloModeMsg:
 		a.app.Permissions.SetSkipRequests(!a.app.Permissions.SkipRequests())
+	case commands.TogglePlanModeMsg:
+		enabled := !a.app.CoderAgent.PlanMode()
+		a.app.CoderAgent.SetPlanMode(enabled)
+		if enabled {
+			cmds = append(cmds, util.ReportInfo("Plan mode on: changes are proposed for review"))
+		} else {
+			cmds = append(cmds, util.ReportInfo("Plan mode off"))
+		}
+	case commands.OpenPlanMsg:
+		if a.selectedSessionID == "" {
+			return a, util.ReportInfo("No proposed plan in this session yet")
+		}
+		return a, util.CmdHandler(dialogs.OpenDialogMsg{
+			Model: plan.NewPlanDialogCmp(a.app.CoderAgent, a.selectedSessionID),
+		})
+	case commands.OpenQueueMsg:
+		if a.selectedSessionID == "" || a.app.CoderAgent.QueuedPrompts(a.selectedSessionID) == 0 {
+			return a, util.ReportInfo("No queued messages")
+		}
+		return a, util.CmdHandler(dialogs.OpenDialogMsg{
+			Model: queue.NewQueueDialogCmp(a.app.CoderAgent, a.selectedSessionID),
+		})
 	case commands.ToggleHelpMsg:
 		a.status.ToggleFullHelp()
 		a.showingFullHelp = !a.showingFullHelp
@@ -207,8 +449,8 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, util.CmdHandler(dialogs.OpenDialogMsg{
 			Model: filepicker.NewFilePickerCmp(a.app.Config().WorkingDir()),
 		})
-	// Permissions
-	case pubsub.Event[permission.PermissionNotification]:
+	// Permissions, and the output of the running tools
+	case pubsub.Event[permission.PermissionNotification], pubsub.Event[tools.ToolOutput]:
 		item, ok := a.pages[a.currentPage]
 		if !ok {
 			return a, nil
@@ -230,14 +472,28 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.app.Permissions.Grant(msg.Permission)
 		case permissions.PermissionAllowForSession:
 			a.app.Permissions.GrantPersistent(msg.Permission)
+		case permissions.PermissionAllowAlways:
+			rule := a.app.Permissions.GrantAlways(msg.Permission)
+			if err := config.Get().AllowTool(rule); err != nil {
+				return a, util.ReportError(err)
+			}
+			return a, util.ReportInfo(fmt.Sprintf("%s is allowed from now on", rule))
 		case permissions.PermissionDeny:
 			a.app.Permissions.Deny(msg.Permission)
+		case permissions.PermissionDenyWithFeedback:
+			a.app.Permissions.DenyWithFeedback(msg.Permission, msg.Feedback)
 		}
 		return a, nil
 	// Agent Events
 	case pubsub.Event[agent.AgentEvent]:
 		payload := msg.Payload
 
+		s, _ := a.status.Update(msg)
+		a.status = s.(status.StatusCmp)
+		if payload.Type == agent.AgentEventTypeUsage {
+			return a, nil
+		}
+
 		// Forward agent events to dialogs
 		if a.dialog.HasDialogs() && a.dialog.ActiveDialogID() == compact.CompactDialogID {
 			u, dialogCmd := a.dialog.Update(payload)
@@ -245,6 +501,25 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, dialogCmd)
 		}
 
+		// Offer the fallback model when the provider stays overloaded
+		var overloadedErr *provider.OverloadedError
+		if payload.Type == agent.AgentEventTypeError && errors.As(payload.Error, &overloadedErr) {
+			if fallback := a.overloadFallback(); fallback != nil {
+				cmds = append(cmds, util.CmdHandler(dialogs.OpenDialogMsg{
+					Model: overloaded.NewOverloadedDialogCmp(overloadedErr, *fallback),
+				}))
+			}
+		}
+
+		// Show the proposed plan once the agent is done, to execute it on a
+		// single keypress
+		if payload.Done && payload.Type == agent.AgentEventTypeResponse && a.app.CoderAgent.PlanMode() &&
+			payload.Message.SessionID == a.selectedSessionID && len(a.app.CoderAgent.Plan(a.selectedSessionID)) > 0 {
+			cmds = append(cmds, util.CmdHandler(dialogs.OpenDialogMsg{
+				Model: plan.NewPlanDialogCmp(a.app.CoderAgent, a.selectedSessionID),
+			}))
+		}
+
 		// Handle auto-compact logic
 		if payload.Done && payload.Type == agent.AgentEventTypeResponse && a.selectedSessionID != "" {
 			// Get current session to check token usage
@@ -262,6 +537,25 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		return a, tea.Batch(cmds...)
+	case app.ConfigReloadedMsg:
+		if msg.Err != nil {
+			return a, util.ReportWarn("Configuration not reloaded: " + msg.Err.Error())
+		}
+		for id, item := range a.pages {
+			updated, pageCmd := item.Update(msg)
+			a.pages[id] = updated.(util.Model)
+			cmds = append(cmds, pageCmd)
+		}
+		if msg.Changed("options.tui") {
+			keyMap := DefaultKeyMap()
+			if chatPage, ok := a.pages[chat.ChatPageID].(chat.ChatPage); ok {
+				keyMap.pageBindings = chatPage.Bindings()
+			}
+			a.keyMap = keyMap
+		}
+		cmds = append(cmds, reportConfigChanges(msg.Changes))
+		return a, tea.Batch(cmds...)
+
 	case splash.OnboardingCompleteMsg:
 		item, ok := a.pages[a.currentPage]
 		if !ok {
@@ -329,6 +623,20 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return a, tea.Batch(cmds...)
 }
 
+// overloadFallback returns the fallback model of overloaded providers, when
+// it is configured and isn't the current large model.
+func (a *appModel) overloadFallback() *config.SelectedModel {
+	opts := config.Get().Options.Overload
+	if opts == nil || opts.Fallback == nil || opts.Fallback.Model == "" {
+		return nil
+	}
+	current := config.Get().Models[config.SelectedModelTypeLarge]
+	if current.Model == opts.Fallback.Model && current.Provider == opts.Fallback.Provider {
+		return nil
+	}
+	return opts.Fallback
+}
+
 // handleWindowResize processes window resize events and updates all components.
 func (a *appModel) handleWindowResize(width, height int) tea.Cmd {
 	var cmds []tea.Cmd
@@ -431,6 +739,14 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 			},
 		)
 		return tea.Sequence(cmds...)
+	case key.Matches(msg, a.keyMap.Workspaces):
+		if a.dialog.ActiveDialogID() == workspaces.WorkspacesDialogID {
+			return util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+		if a.dialog.HasDialogs() {
+			return nil
+		}
+		return a.openWorkspaces()
 	case key.Matches(msg, a.keyMap.Suspend):
 		if a.app.CoderAgent != nil && a.app.CoderAgent.IsBusy() {
 			return util.ReportWarn("Agent is busy, please wait...")
@@ -448,6 +764,34 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 	}
 }
 
+// openWorkspaces opens the dialog switching to the workspace of another
+// project.
+func (a *appModel) openWorkspaces() tea.Cmd {
+	return func() tea.Msg {
+		all, err := projects.List()
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		return dialogs.OpenDialogMsg{
+			Model: workspaces.NewWorkspacesDialogCmp(a.app.Config().WorkingDir(), all),
+		}
+	}
+}
+
+// SwitchWorkspace returns the project whose workspace was selected, when the
+// TUI quit to switch to it.
+func (a *appModel) SwitchWorkspace() (projects.Project, bool) {
+	if a.switchTo == nil {
+		return projects.Project{}, false
+	}
+	return *a.switchTo, true
+}
+
+// SessionID returns the ID of the selected session.
+func (a *appModel) SessionID() string {
+	return a.selectedSessionID
+}
+
 // moveToPage handles navigation between different pages in the application.
 func (a *appModel) moveToPage(pageID page.PageID) tea.Cmd {
 	if a.app.CoderAgent.IsBusy() {
@@ -550,6 +894,27 @@ func (a *appModel) View() tea.View {
 	return view
 }
 
+// reportConfigChanges tells which sections of the reloaded configuration
+// were applied, and which ones need crush to restart.
+func reportConfigChanges(changes []config.Change) tea.Cmd {
+	var applied, restart []string
+	for _, change := range changes {
+		if change.Restart {
+			restart = append(restart, change.Section)
+		} else {
+			applied = append(applied, change.Section)
+		}
+	}
+	if len(restart) == 0 {
+		return util.ReportInfo("Configuration reloaded: " + strings.Join(applied, ", "))
+	}
+	msg := "Restart crush to apply " + strings.Join(restart, ", ")
+	if len(applied) > 0 {
+		msg = "Configuration reloaded: " + strings.Join(applied, ", ") + ". " + msg
+	}
+	return util.ReportWarn(msg)
+}
+
 // New creates and initializes a new TUI application model.
 func New(app *app.App) tea.Model {
 	chatPage := chat.New(app)
@@ -573,3 +938,24 @@ func New(app *app.App) tea.Model {
 
 	return model
 }
+
+// openPromptsDialog opens the dialog listing the prompts of the selected
+// session, reporting empty when there are none.
+func (a *appModel) openPromptsDialog(empty string, newDialog func([]message.Message) rewind.RewindDialog) tea.Cmd {
+	sessionID := a.selectedSessionID
+	return func() tea.Msg {
+		msgs, err := a.app.Messages.List(context.Background(), sessionID)
+		if err != nil {
+			return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+		}
+		prompts := slices.DeleteFunc(msgs, func(msg message.Message) bool {
+			return msg.Role != message.User
+		})
+		if len(prompts) == 0 {
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: empty}
+		}
+		return dialogs.OpenDialogMsg{
+			Model: newDialog(prompts),
+		}
+	}
+}
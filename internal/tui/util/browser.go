@@ -0,0 +1,30 @@
+package util
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// OpenURL opens the URL with the default application of the system, the
+// browser for web pages.
+func OpenURL(url string) tea.Cmd {
+	return func() tea.Msg {
+		var c *exec.Cmd
+		switch runtime.GOOS {
+		case "darwin":
+			c = exec.CommandContext(context.TODO(), "open", url)
+		case "windows":
+			c = exec.CommandContext(context.TODO(), "rundll32", "url.dll,FileProtocolHandler", url)
+		default:
+			c = exec.CommandContext(context.TODO(), "xdg-open", url)
+		}
+		if err := c.Start(); err != nil {
+			return InfoMsg{Type: InfoTypeError, Msg: err.Error()}
+		}
+		go func() { _ = c.Wait() }()
+		return nil
+	}
+}
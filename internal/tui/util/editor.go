@@ -0,0 +1,60 @@
+package util
+
+import (
+	"context"
+	"os"
+	"os/exec"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/editor"
+)
+
+// OpenInEditor opens the file at the line, 0 for none, in the editor of the
+// user. Terminal editors suspend the TUI until they exit.
+func OpenInEditor(path string, line int) tea.Cmd {
+	return OpenInEditorThen(path, line, nil)
+}
+
+// OpenInEditorThen opens the file like OpenInEditor, calling done once the
+// editor exits.
+func OpenInEditorThen(path string, line int, done func()) tea.Cmd {
+	if done == nil {
+		done = func() {}
+	}
+	var opts *config.EditorOptions
+	if cfg := config.Get(); cfg != nil && cfg.Options != nil {
+		opts = cfg.Options.Editor
+	}
+	e, err := editor.Open(opts, path, line, 1)
+	if err != nil {
+		done()
+		return ReportError(err)
+	}
+
+	c := exec.CommandContext(context.TODO(), e.Args[0], e.Args[1:]...)
+	if !e.Terminal {
+		return func() tea.Msg {
+			if err := c.Start(); err != nil {
+				done()
+				return ReportError(err)
+			}
+			go func() {
+				_ = c.Wait()
+				done()
+			}()
+			return nil
+		}
+	}
+
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		done()
+		if err != nil {
+			return ReportError(err)
+		}
+		return nil
+	})
+}